@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.CertificateCheckOptions
+
+	flags.StringVarP(&options.Host, "host", "H", "", "the host to read the certificates from with a TLS handshake")
+	flags.IntVarP(&options.Port, "port", "p", 443, "the port of the host")
+	flags.StringVarP(&options.File, "file", "f", "", "a PEM file to read the certificates from instead of a host")
+	flags.StringVarP(&options.Warning, "warning", "w", "30:", "the warning threshold, the range of days the certificates may have left")
+	flags.StringVarP(&options.Critical, "critical", "c", "14:", "the critical threshold, the range of days the certificates may have left")
+	flags.BoolVarP(&options.VerifyHostname, "verify_hostname", "", false, "check the certificate is valid for --host")
+
+	initcmd.SetRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		options.Timeout = *initcmd.TimeoutSeconds()
+		options.TimeoutExit = initcmd.TimeoutExit()
+
+		return nagiosfoundation.RunCertificateCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_certificate",
+		Short: "Check the expiry of a TLS certificate.",
+		Long: `Reads the certificate chain of --host on --port with a TLS handshake, or from
+the PEM file given with --file, and checks the days left until the soonest
+expiry of the certificates in the chain. A CRITICAL response is issued when
+the days left are outside the --critical range, a WARNING response when they
+are outside the --warning range and an OK response otherwise. The ranges are
+Nagios ranges such as "30:", alerting with fewer than 30 days left, and a
+plain number such as 30 is the least number of days.
+
+A certificate in the chain that has expired or is not yet valid issues a
+CRITICAL response, as does a certificate not valid for --host when
+--verify_hostname is given. A host that cannot be reached within --timeout
+seconds issues an UNKNOWN response.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// The time given to the handshake is also the global --timeout,
+	// so the flag is bound to it with the check's own shorthand.
+	rootCmd.Flags().IntVarP(initcmd.TimeoutSeconds(), "timeout", "t", 10, "the number of seconds to wait for the connection and handshake")
+
+	// Accept the single dash -host of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.CommandCheckOptions
+
+	flags.StringVarP(&options.Command, "cmd", "", "", "the command to run")
+	flags.StringSliceVarP(&options.Args, "args", "", nil, "the arguments of the command, separated by commas or given with --args for each")
+	flags.StringVarP(&options.Extract, "extract", "e", "", "a regular expression finding the value in the output, its first group or the whole match, such as \"depth: (\\d+)\"")
+	flags.StringVarP(&options.Warning, "warning", "w", "", "the warning threshold of the value")
+	flags.StringVarP(&options.Critical, "critical", "c", "", "the critical threshold of the value")
+	flags.StringVarP(&options.MetricName, "metric_name", "m", "value", "the name of the metric generated by this check")
+
+	initcmd.SetRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		options.Deadline = initcmd.Deadline()
+		options.TimeoutExit = initcmd.TimeoutExit()
+
+		return nagiosfoundation.RunCommandCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_command",
+		Short: "Check the number output by a command.",
+		Long: `Runs the command given with --cmd and its --args, reads a number from its
+standard output and checks it against the --warning and --critical thresholds,
+to turn the output of a tool such as a vendor CLI printing a queue depth into a
+check. The value is found in the output with the --extract regular expression,
+the first group of the expression or the whole match, such as "depth: (\d+)".
+Without --extract the whole output must be the number. The value is output as
+perfdata.
+
+The command is run directly rather than by a shell. A command that cannot be
+run, exits with a non-zero code or does not output a number issues an UNKNOWN
+response. A command still running when the --timeout passes is killed and
+issues an UNKNOWN response.
+
+The --warning and --critical thresholds are Nagios ranges, such as "10" to alert
+above 10 and "5:" to alert below 5.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// The flags are not parsed again as the other checks do,
+			// which would repeat the --args.
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -cmd of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
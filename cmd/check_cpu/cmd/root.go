@@ -7,12 +7,28 @@ import (
 	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var warning, critical, interval int
+	var metricName string
+
+	flags.IntVarP(&warning, "warning", "w", 85, "the average cpu threshold to issue a warning alert")
+	flags.IntVarP(&critical, "critical", "c", 95, "the average cpu threshold to issue a critical alert")
+	flags.IntVarP(&interval, "interval", "i", 1, "the number of seconds to sample the cpu usage over")
+	flags.StringVarP(&metricName, "metric_name", "m", "pct_processor_time", "the name of the metric generated by this check")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunCPUCheck(warning, critical, metricName, interval)
+	}
+}
+
 // Execute runs the root command
 func Execute() {
-	var warning, critical int
-	var metricName string
+	var check func() nagiosfoundation.CheckResult
 
 	var rootCmd = &cobra.Command{
 		Use:   "check_cpu",
@@ -20,21 +36,22 @@ func Execute() {
 		Long: `Perform a CPU check by getting the usage percentage and if
 above --critical percentage, issue a CRITICAL response, else if it is
 above --warning percentage, issue a WARNING response. If it's below both
-of these, an OK response is issued.`,
+of these, an OK response is issued. The usage is sampled over --interval
+seconds.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.ParseFlags(os.Args)
-			msg, retval := nagiosfoundation.CheckCPU(warning, critical, metricName)
+			result := initcmd.RunCheck(check)
 
-			fmt.Println(msg)
-			os.Exit(retval)
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
 		},
 	}
 
 	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
 
-	rootCmd.Flags().IntVarP(&warning, "warning", "w", 85, "the average cpu threshold to issue a warning alert")
-	rootCmd.Flags().IntVarP(&critical, "critical", "c", 95, "the average cpu threshold to issue a critical alert")
-	rootCmd.Flags().StringVarP(&metricName, "metric_name", "m", "pct_processor_time", "the name of the metric generated by this check")
+	check = NewCheck(rootCmd.Flags())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
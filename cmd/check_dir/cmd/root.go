@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.DirCheckOptions
+
+	flags.StringVarP(&options.Path, "path", "p", "", "the directory whose entries are counted")
+	flags.StringVarP(&options.Pattern, "pattern", "", "", "a globbing pattern the names of the entries counted must match, such as *.msg")
+	flags.BoolVarP(&options.Recursive, "recursive", "r", false, "count the entries of the subdirectories as well")
+	flags.DurationVarP(&options.OlderThan, "older_than", "", 0, "only count the entries last modified longer ago than this, such as 1h")
+	flags.StringVarP(&options.Warning, "warning", "w", "", "the warning threshold, the number of entries")
+	flags.StringVarP(&options.Critical, "critical", "c", "", "the critical threshold, the number of entries")
+	flags.BoolVarP(&options.MissingOK, "missing_ok", "", false, "report a directory that does not exist as OK with no entries rather than UNKNOWN")
+	flags.StringVarP(&options.MetricName, "metric_name", "m", "entries", "the name of the metric generated by this check")
+
+	initcmd.SetRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunDirCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_dir",
+		Short: "Check the number of entries in a directory.",
+		Long: `Counts the entries of the directory at --path other than its subdirectories,
+or with --recursive the entries of its whole tree, and checks the count against
+the --warning and --critical thresholds, to catch a spool or queue directory
+accumulating files. Only the entries whose names match --pattern, a globbing
+pattern such as *.msg, are counted, and with --older_than only those last
+modified longer ago than the given time, such as 1h, so that only stale
+entries are counted. A directory that does not exist issues an UNKNOWN
+response, or with --missing_ok an OK response counting no entries.
+
+The --warning and --critical thresholds are Nagios ranges, such as "100" to
+alert above 100 entries and "0" to alert on any entry.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -path of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
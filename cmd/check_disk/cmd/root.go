@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var path, warning, critical, metricName string
+	var inodes bool
+
+	flags.StringVarP(&path, "path", "p", "", "the path on the filesystem to check (default \"/\" on Linux, \"C:\\\" on Windows)")
+	flags.StringVarP(&warning, "warning", "w", "85%", "the space used to issue a warning alert, as a percentage or size")
+	flags.StringVarP(&critical, "critical", "c", "95%", "the space used to issue a critical alert, as a percentage or size")
+	flags.BoolVarP(&inodes, "inodes", "i", false, "check the inodes used instead of space")
+	flags.StringVarP(&metricName, "metric_name", "m", "", "the prefix of the metrics generated by this check (default \"disk\", or \"inodes\" with --inodes)")
+
+	initcmd.SetRelativeRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunDiskCheck(path, warning, critical, metricName, inodes)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_disk",
+		Short: "Determine if the disk space used exceeds a threshold.",
+		Long: `Determines the space used on the filesystem holding --path and if over the
+--critical threshold issue a CRITICAL response, then check if over the
+--warning threshold, issue a WARNING response. Otherwise, an OK response is
+issued. The thresholds are a percentage of the filesystem such as 90% or an
+amount such as 2G. With --inodes the inodes used are checked instead, which
+is Linux only.
+
+A bind mount or overlay reports the usage of the filesystem it shares with
+other mounts, which is named in the response.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
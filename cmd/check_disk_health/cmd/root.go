@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.DiskHealthCheckOptions
+
+	flags.StringVarP(&options.Device, "device", "d", "", "the device checked, such as /dev/sda")
+	flags.StringVarP(&options.DeviceType, "device_type", "", "", "the type of the device passed to smartctl -d, such as sat or megaraid,0")
+	flags.StringVarP(&options.ReallocatedWarning, "reallocated_warning", "", "", "the warning threshold of the reallocated sectors")
+	flags.StringVarP(&options.ReallocatedCritical, "reallocated_critical", "", "", "the critical threshold of the reallocated sectors")
+	flags.StringVarP(&options.PendingWarning, "pending_warning", "", "", "the warning threshold of the sectors pending reallocation")
+	flags.StringVarP(&options.PendingCritical, "pending_critical", "", "", "the critical threshold of the sectors pending reallocation")
+	flags.StringVarP(&options.TemperatureWarning, "temperature_warning", "", "", "the warning threshold of the temperature in degrees Celsius")
+	flags.StringVarP(&options.TemperatureCritical, "temperature_critical", "", "", "the critical threshold of the temperature in degrees Celsius")
+	flags.StringVarP(&options.Smartctl, "smartctl", "", "smartctl", "the smartctl command run, found in the PATH unless a path is given")
+	flags.StringVarP(&options.MetricName, "metric_name", "m", "smart", "the prefix of the perfdata labels")
+
+	initcmd.SetRangeFlags(flags, "reallocated_warning", "reallocated_critical", "pending_warning", "pending_critical", "temperature_warning", "temperature_critical")
+
+	return func() nagiosfoundation.CheckResult {
+		options.Deadline = initcmd.Deadline()
+		options.TimeoutExit = initcmd.TimeoutExit()
+
+		return nagiosfoundation.RunDiskHealthCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_disk_health",
+		Short: "Check the SMART health of a disk.",
+		Long: `Runs smartctl -H -A for the --device and checks its overall SMART health
+assessment, issuing a CRITICAL response when the device reports it is failing.
+The reallocated sectors, the sectors pending reallocation and the temperature
+are checked against the --reallocated_warning, --reallocated_critical,
+--pending_warning, --pending_critical, --temperature_warning and
+--temperature_critical thresholds when the device reports them, and output as
+perfdata. Thresholds not given are not checked.
+
+smartctl needs root to read a device. A smartctl that cannot be run or cannot
+open the device, such as for the lack of privileges, issues an UNKNOWN response,
+as does a smartctl still running when the --timeout passes, which is killed.
+This check is Linux only.
+
+The thresholds are Nagios ranges, such as "0" to alert on any reallocated
+sector.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -device of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
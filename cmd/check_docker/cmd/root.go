@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.DockerCheckOptions
+
+	flags.StringVarP(&options.Name, "name", "n", "", "the name or ID of the container")
+	flags.StringVarP(&options.Socket, "socket", "s", "/var/run/docker.sock", "the unix socket of the Docker Engine API")
+	flags.StringVarP(&options.MetricName, "metric_name", "m", "restarts", "the name of the metric generated by this check")
+
+	return func() nagiosfoundation.CheckResult {
+		options.Timeout = *initcmd.TimeoutSeconds()
+		options.TimeoutExit = initcmd.TimeoutExit()
+
+		return nagiosfoundation.RunDockerCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_docker",
+		Short: "Check a Docker container is running and healthy.",
+		Long: `Checks the Docker container of --name, given as its name or ID, is running and,
+when it has a healthcheck, is healthy, inspecting it with the Docker Engine
+API on the unix socket of --socket. A container that is not running, such as
+exited, paused or restarting, is unhealthy or does not exist issues a CRITICAL
+response, and one whose healthcheck is still starting a WARNING response. A
+socket that cannot be connected to, such as one the user of the check is not
+allowed to, issues an UNKNOWN response. The number of times the container has
+been restarted is output as perfdata. This check is Linux only.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -name and -socket of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
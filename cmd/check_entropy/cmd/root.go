@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var warning, critical int
+
+	flags.IntVarP(&warning, "warning", "w", 200, "the available entropy threshold to issue a warning alert")
+	flags.IntVarP(&critical, "critical", "c", 100, "the available entropy threshold to issue a critical alert")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunEntropyCheck(warning, critical)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_entropy",
+		Short: "Determine if available kernel entropy is below a threshold.",
+		Long: `Reads the available kernel entropy from /proc/sys/kernel/random/entropy_avail
+and if below the --critical threshold issue a CRITICAL response, then check if
+below the --warning threshold, issue a WARNING response. Otherwise, an OK
+response is issued.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var path, checkType, warning, critical, metricName string
+
+	flags.StringVarP(&path, "path", "p", "", "the path or globbing pattern of the file to check")
+	flags.StringVarP(&checkType, "check_type", "t", "exists", "Supported types are \"exists\", \"age\" and \"size\"")
+	flags.StringVarP(&warning, "warning", "w", "", "the warning threshold, the seconds since the file was modified for \"age\" or the bytes in the file for \"size\"")
+	flags.StringVarP(&critical, "critical", "c", "", "the critical threshold, the seconds since the file was modified for \"age\" or the bytes in the file for \"size\"")
+	flags.StringVarP(&metricName, "metric_name", "m", "", "the name of the metric generated by this check (default \"files\", \"age\" or \"size\" by type)")
+
+	initcmd.SetRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunFileCheck(path, checkType, warning, critical, metricName)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_file",
+		Short: "Check a file exists and is not stale or the wrong size.",
+		Long: `Checks the file at --path, which may be a globbing pattern in which case the
+newest matching file is checked. The "exists" type issues a CRITICAL response
+when no file matches. The "age" type checks the seconds since the file was
+modified and the "size" type checks the size of the file in bytes against the
+--warning and --critical thresholds. For these a CRITICAL response is issued
+when no file matches.
+
+The --warning and --critical thresholds are Nagios ranges, such as "3600" to
+alert above 3600, "1024:" to alert below 1024 and "1024:4096" to alert
+outside 1024 to 4096.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -5,31 +5,48 @@ import (
 	"os"
 
 	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-// Execute runs the root command
-func Execute(apiCheckFileExists func(string, bool) (string, int)) int {
-	var exitCode int
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with apiCheckFileExists and their
+// values.
+func NewCheck(flags *pflag.FlagSet, apiCheckFileExists func(string, bool) nagiosfoundation.CheckResult) func() nagiosfoundation.CheckResult {
 	var pattern string
 	var negate bool
 
+	flags.StringVarP(&pattern, "pattern", "p", "", "Filepath or globbing pattern to check for one or more existing files")
+	flags.BoolVarP(&negate, "negate", "n", false, "Asserts filepath or globbing pattern should NOT match any existing file")
+
+	return func() nagiosfoundation.CheckResult {
+		return apiCheckFileExists(pattern, negate)
+	}
+}
+
+// Execute runs the root command
+func Execute(apiCheckFileExists func(string, bool) nagiosfoundation.CheckResult) int {
+	var check func() nagiosfoundation.CheckResult
+	var exitCode int
+
 	var rootCmd = &cobra.Command{
 		Use:   "check_file_exists",
 		Short: "Check for the existence of one or more files matching specific filepath or globbing patterns.",
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.ParseFlags(os.Args)
-			msg, retval := apiCheckFileExists(pattern, negate)
+			result := initcmd.RunCheck(check)
 
-			fmt.Println(msg)
-			exitCode = retval
+			initcmd.PrintResult(result)
+			exitCode = result.Code
 		},
 	}
 
 	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
 
-	rootCmd.Flags().StringVarP(&pattern, "pattern", "p", "", "Filepath or globbing pattern to check for one or more existing files")
-	rootCmd.Flags().BoolVarP(&negate, "negate", "n", false, "Asserts filepath or globbing pattern should NOT match any existing file")
+	check = NewCheck(rootCmd.Flags(), apiCheckFileExists)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stdout, err)
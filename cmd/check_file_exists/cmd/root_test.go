@@ -3,6 +3,8 @@ package cmd
 import (
 	"os"
 	"testing"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 )
 
 func TestCheckFileExistsCmd(t *testing.T) {
@@ -32,8 +34,8 @@ func TestCheckFileExistsCmd(t *testing.T) {
 		},
 	}
 
-	apiCheckFileExists := func(pattern string, negate bool) (string, int) {
-		return "Test Message", expectedExitCode
+	apiCheckFileExists := func(pattern string, negate bool) nagiosfoundation.CheckResult {
+		return nagiosfoundation.NewCheckResult("CheckFileExists", nagiosfoundation.State(expectedExitCode), "Test Message")
 	}
 
 	savedArgs := os.Args
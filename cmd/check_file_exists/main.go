@@ -8,5 +8,5 @@ import (
 )
 
 func main() {
-	os.Exit(cmd.Execute(nagiosfoundation.CheckFileExists))
+	os.Exit(cmd.Execute(nagiosfoundation.RunFileExistsCheck))
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.HeartbeatCheckOptions
+
+	flags.StringVarP(&options.Path, "path", "p", "", "the heartbeat file touched or written by the job")
+	flags.StringVarP(&options.ContentFormat, "content_format", "f", "mtime", "where the time of the heartbeat is read from, \"mtime\" for the modification time of the file, or \"rfc3339\" or \"unix\" for a timestamp in the file")
+	flags.StringVarP(&options.Warning, "warning", "w", "", "the warning threshold of the age of the heartbeat in seconds")
+	flags.StringVarP(&options.Critical, "critical", "c", "", "the critical threshold of the age of the heartbeat in seconds")
+	flags.StringVarP(&options.MetricName, "metric_name", "m", "age", "the name of the metric generated by this check")
+
+	initcmd.SetFlagValues(flags, "content_format", "mtime", "rfc3339", "unix")
+	initcmd.SetRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunHeartbeatCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_heartbeat",
+		Short: "Check a job is still reporting with a heartbeat file.",
+		Long: `Checks the age of the heartbeat file at --path, touched or written by a job
+on each successful cycle, against the --warning and --critical thresholds in
+seconds. The time of the heartbeat is the modification time of the file, or
+with --content_format a timestamp on the first line of the file, "rfc3339"
+such as "2019-06-04T15:04:05Z" or "unix" such as "1559660645", for a job that
+records the time of its last success rather than touching the file. A
+heartbeat that does not exist or cannot be parsed issues a CRITICAL response,
+as the job is not reporting. The age is output as perfdata.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -content_format, -warning and -critical
+	// of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
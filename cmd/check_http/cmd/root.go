@@ -5,37 +5,73 @@ import (
 	"os"
 
 	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with apiCheckHTTP and their values.
+func NewCheck(flags *pflag.FlagSet, apiCheckHTTP func(nagiosfoundation.HTTPCheckOptions) nagiosfoundation.CheckResult) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.HTTPCheckOptions
+
+	flags.StringVarP(&options.URL, "url", "u", "http://127.0.0.1", "the URL to check")
+	flags.BoolVarP(&options.Redirect, "redirect", "r", false, "follow redirects?")
+	flags.StringVarP(&options.Format, "format", "f", "", "The expected response format: json")
+	flags.StringVarP(&options.Path, "path", "p", "", "The path in the return value data to test against the expected value")
+	flags.StringVarP(&options.ExpectedValue, "expectedValue", "e", "", "The expected response data value")
+	flags.StringVarP(&options.Expression, "expression", "", "", "Expression to evaluate against response data value")
+	flags.StringVarP(&options.ExpectedStatus, "expected_status", "s", "", "the expected status codes, such as 200 or 2xx, separated by commas")
+	flags.StringVarP(&options.ExpectString, "expect_string", "", "", "a string the response body must contain")
+	flags.StringVarP(&options.Warning, "warning", "w", "", "the response time in seconds to issue a warning alert")
+	flags.StringVarP(&options.Critical, "critical", "c", "", "the response time in seconds to issue a critical alert")
+	flags.BoolVarP(&options.Insecure, "insecure", "k", false, "do not verify the TLS certificate of the server")
+	flags.StringVarP(&options.ClientCert, "client_cert", "", "", "the PEM file of the client certificate presented to a server requiring mutual TLS")
+	flags.StringVarP(&options.ClientKey, "client_key", "", "", "the PEM file of the key of the client certificate, by default read from the --client_cert file")
+	flags.StringVarP(&options.CAFile, "ca_file", "", "", "a PEM file of the CA certificates trusted to sign the certificate of the server, such as a private CA")
+	flags.IntVarP(&options.IPVersion, "ip_version", "", 0, "the IP version connected with, 4 or 6, or 0 for either")
+	flags.StringVarP(&options.Addresses, "addresses", "", "any", "the addresses of a host resolving to several that must accept a connection, \"any\" or \"all\"")
+
+	initcmd.SetFlagValues(flags, "ip_version", "0", "4", "6")
+	initcmd.SetFlagValues(flags, "addresses", "any", "all")
+	initcmd.SetRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		options.Timeout = *initcmd.TimeoutSeconds()
+		options.TimeoutExit = initcmd.TimeoutExit()
+
+		return apiCheckHTTP(options)
+	}
+}
+
 // Execute runs the root command
-func Execute(apiCheckHTTP func(string, bool, int, string, string, string, string) (string, int)) int {
-	var redirect bool
-	var exitCode, timeout int
-	var url, format, path, expectedValue, expression string
+func Execute(apiCheckHTTP func(nagiosfoundation.HTTPCheckOptions) nagiosfoundation.CheckResult) int {
+	var check func() nagiosfoundation.CheckResult
+	var exitCode int
 
 	var rootCmd = &cobra.Command{
 		Use:   "check_http",
 		Short: "Check the response code of an http request.",
-		Long:  `Perform an HTTP get request and assert whether it is OK, warning or critical.`,
+		Long: `Perform an HTTP get request and assert whether it is OK, warning or critical
+by the status code, the content of the response and the response time.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.ParseFlags(os.Args)
-			msg, retval := apiCheckHTTP(url, redirect, timeout, format, path, expectedValue, expression)
+			result := initcmd.RunCheck(check)
 
-			fmt.Println(msg)
-			exitCode = retval
+			initcmd.PrintResult(result)
+			exitCode = result.Code
 		},
 	}
 
 	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags(), apiCheckHTTP)
 
-	rootCmd.Flags().StringVarP(&url, "url", "u", "http://127.0.0.1", "the URL to check")
-	rootCmd.Flags().BoolVarP(&redirect, "redirect", "r", false, "follow redirects?")
-	rootCmd.Flags().IntVarP(&timeout, "timeout", "t", 15, "timeout in seconds")
-	rootCmd.Flags().StringVarP(&format, "format", "f", "", "The expected response format: json")
-	rootCmd.Flags().StringVarP(&path, "path", "p", "", "The path in the return value data to test against the expected value")
-	rootCmd.Flags().StringVarP(&expectedValue, "expectedValue", "e", "", "The expected response data value")
-	rootCmd.Flags().StringVarP(&expression, "expression", "", "", "Expression to evaluate against response data value")
+	// The request timeout is also the global --timeout, so the flag
+	// is bound to it with the check's own shorthand and default.
+	rootCmd.Flags().IntVarP(initcmd.TimeoutSeconds(), "timeout", "t", 15, "timeout in seconds")
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stdout, err)
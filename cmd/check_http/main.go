@@ -8,5 +8,5 @@ import (
 )
 
 func main() {
-	os.Exit(cmd.Execute(nagiosfoundation.CheckHTTP))
+	os.Exit(cmd.Execute(nagiosfoundation.RunHTTPCheck))
 }
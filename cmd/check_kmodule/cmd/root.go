@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var name string
+	var minRefCount, minSize int
+
+	const nameFlag = "name"
+	flags.StringVarP(&name, nameFlag, "n", "", "kernel module name")
+	cobra.MarkFlagRequired(flags, nameFlag)
+	flags.IntVarP(&minRefCount, "min_refcount", "r", -1, "the minimum reference count of the module, below which a warning is issued")
+	flags.IntVarP(&minSize, "min_size", "s", -1, "the minimum size in bytes of the module, below which a warning is issued")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunKernelModuleCheck(name, minRefCount, minSize)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_kmodule",
+		Short: "Determine if a kernel module is loaded.",
+		Long: `Perform a check for a kernel module by name using /proc/modules. If the
+module is not loaded, a CRITICAL response is issued. If --min_refcount or
+--min_size is given and the loaded module falls below it, a WARNING response
+is issued. Otherwise, an OK response is issued.
+
+The --name (-n) option is always required.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var warning, critical, metricName string
+	var perCPU bool
+
+	flags.StringVarP(&warning, "warning", "w", "", "the warning threshold, a load or a 1,5,15 minute triplet")
+	flags.StringVarP(&critical, "critical", "c", "", "the critical threshold, a load or a 1,5,15 minute triplet")
+	flags.BoolVarP(&perCPU, "per_cpu", "r", false, "divide the load averages by the number of CPUs")
+	flags.StringVarP(&metricName, "metric_name", "m", "load", "the name of the metric prefixed to each period in the perfdata")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunLoadCheck(warning, critical, metricName, perCPU)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_load",
+		Short: "Check the system load average.",
+		Long: `Reads the 1, 5 and 15 minute load averages and issues a CRITICAL response if
+any is over its --critical threshold, a WARNING response if any is over its
+--warning threshold and an OK response otherwise.
+
+A threshold is a single load applied to all three averages, such as 4, or a
+comma separated triplet for the 1, 5 and 15 minute averages, such as 15,10,5,
+as taken by the classic check_load. An average left empty in a triplet is not
+checked. With --per_cpu the averages are divided by the number of CPUs so the
+same thresholds suit hosts of any size.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
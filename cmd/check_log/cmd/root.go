@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.LogCheckOptions
+
+	flags.StringVarP(&options.File, "file", "f", "", "the log file scanned for matching lines")
+	flags.StringVarP(&options.Pattern, "pattern", "p", "", "a regular expression the lines counted must match, such as \"ERROR|FATAL\"")
+	flags.StringVarP(&options.StateDir, "state_dir", "", "", "the directory the offset read up to is saved in between runs")
+	flags.StringVarP(&options.Warning, "warning", "w", "0", "the warning threshold, the number of matching lines since the last run")
+	flags.StringVarP(&options.Critical, "critical", "c", "", "the critical threshold, the number of matching lines since the last run")
+	flags.IntVarP(&options.MaxLines, "max_lines", "", 10, "the number of matching lines output")
+	flags.StringVarP(&options.MetricName, "metric_name", "m", "matches", "the name of the metric generated by this check")
+
+	initcmd.SetRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunLogCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_log",
+		Short: "Check a log file for lines matching a pattern.",
+		Long: `Counts the lines of the log --file matching the --pattern regular expression
+written since the last run and checks the count against the --warning and
+--critical thresholds, the warning defaulting to 0 to alert on any matching
+line. The offset read up to is saved in --state_dir, so each run reads only the
+lines written since the run before it, and the first run starts from the end
+of the log. A log that has been rotated, replaced by a new file or smaller than
+the offset, is read from its start. Up to --max_lines of the matching lines are
+output after the result for context.
+
+The --warning and --critical thresholds are Nagios ranges, such as "0" to alert
+on any matching line and "10" to alert above 10.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -file of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
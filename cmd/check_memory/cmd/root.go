@@ -7,33 +7,60 @@ import (
 	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var warning, critical, metricName string
+	var swap bool
+
+	flags.StringVarP(&warning, "warning", "w", "85%", "the memory used to issue a warning alert, as a percentage or size")
+	flags.StringVarP(&critical, "critical", "c", "95%", "the memory used to issue a critical alert, as a percentage or size")
+	flags.BoolVarP(&swap, "swap", "s", false, "check the swap used instead of physical memory")
+	flags.StringVarP(&metricName, "metric_name", "m", "available_memory_percent", "the name of the metric generated by this check")
+
+	initcmd.SetRelativeRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		var checkType string
+		if swap {
+			checkType = "swap"
+		}
+
+		return nagiosfoundation.RunMemoryCheck(checkType, warning, critical, metricName)
+	}
+}
+
 // Execute runs the root command
 func Execute() {
-	var warning, critical int
-	var metricName string
+	var check func() nagiosfoundation.CheckResult
 
 	var rootCmd = &cobra.Command{
 		Use:   "check_memory",
 		Short: "Determine if memory used exceeds percentage threshold.",
-		Long: `Determines the percentage of memory used and if over the --critical
-threshold issue a CRITICAL response, then check if over the --warning threshold,
-issue a WARNING response. Otherwise, an OK response is issued.`,
+		Long: `Determines the memory used and if over the --critical threshold issue a
+CRITICAL response, then check if over the --warning threshold, issue a WARNING
+response. Otherwise, an OK response is issued. The thresholds are Nagios ranges
+of a percentage of the memory such as 90% or an amount such as 6G, and a plain
+number such as 90 is a percentage. The memory available for reclaiming, such as
+buffers and cache, is not counted as used. With --swap the swap used is checked
+instead.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.ParseFlags(os.Args)
-			msg, retval := nagiosfoundation.CheckMemory("", warning, critical, metricName)
+			result := initcmd.RunCheck(check)
 
-			fmt.Println(msg)
-			os.Exit(retval)
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
 		},
 	}
 
 	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
 
-	rootCmd.Flags().IntVarP(&warning, "warning", "w", 85, "the memory threshold to issue a warning alert")
-	rootCmd.Flags().IntVarP(&critical, "critical", "c", 95, "the memory threshold to issue a critical alert")
-	rootCmd.Flags().StringVarP(&metricName, "metric_name", "m", "available_memory_percent", "the name of the metric generated by this check")
+	check = NewCheck(rootCmd.Flags())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
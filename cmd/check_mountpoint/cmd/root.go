@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.MountpointCheckOptions
+
+	flags.StringVarP(&options.Path, "path", "p", "", "the path that must be a mount point, such as /data")
+	flags.StringVarP(&options.FsType, "fstype", "t", "", "the filesystem type the path must be mounted with, such as xfs")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunMountpointCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_mountpoint",
+		Short: "Check a path is a mount point.",
+		Long: `Checks that the --path is a mount point, its device differing from that of its
+parent directory or the path listed in /proc/mounts, so an application writing
+to a volume that failed to mount is caught before it fills the filesystem
+below. With --fstype the filesystem mounted at the path must also be of the
+type, such as xfs. A path that is not a mount point, does not exist or is
+mounted with another type issues a CRITICAL response. This check is Linux
+only.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -path of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// checkInfo describes a check type and its options for list-checks.
+type checkInfo struct {
+	Type    string     `json:"type"`
+	Command string     `json:"command"`
+	Flags   []flagInfo `json:"flags"`
+}
+
+// flagInfo describes an option of a check type, with the values it
+// accepts when they are a fixed set.
+type flagInfo struct {
+	Name      string   `json:"name"`
+	Shorthand string   `json:"shorthand,omitempty"`
+	Type      string   `json:"type"`
+	Default   string   `json:"default"`
+	Usage     string   `json:"usage"`
+	Required  bool     `json:"required"`
+	Values    []string `json:"values,omitempty"`
+}
+
+// listCheckInfo returns the check types in order of name, each with
+// its options read from the flags the check registers. The checks are
+// only set up, none is run.
+func listCheckInfo() []checkInfo {
+	names := make([]string, 0, len(checkTypes))
+	for name := range checkTypes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	checks := make([]checkInfo, 0, len(names))
+
+	for _, name := range names {
+		flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+		checkTypes[name](flags)
+
+		check := checkInfo{Type: name, Command: "check_" + name, Flags: []flagInfo{}}
+
+		flags.VisitAll(func(flag *pflag.Flag) {
+			_, required := flag.Annotations[cobra.BashCompOneRequiredFlag]
+
+			check.Flags = append(check.Flags, flagInfo{
+				Name:      flag.Name,
+				Shorthand: flag.Shorthand,
+				Type:      flag.Value.Type(),
+				Default:   flag.DefValue,
+				Usage:     flag.Usage,
+				Required:  required,
+				Values:    flag.Annotations[initcmd.FlagValuesAnnotation],
+			})
+		})
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// printCheckInfo writes the check types as a JSON object of a checks
+// list.
+func printCheckInfo(w io.Writer, checks []checkInfo) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(struct {
+		Checks []checkInfo `json:"checks"`
+	}{checks})
+}
+
+// addListChecksCommand adds the list-checks subcommand to the root
+// command, listing the check types and their options as JSON.
+func addListChecksCommand(cmd *cobra.Command) {
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list-checks",
+		Short: "List the check types and their options as JSON",
+		Long: `List the check types that may be given in a --spec as JSON, each with the
+command it runs as and its options, the name, shorthand, type, default, usage,
+whether it is required and, when the option takes a fixed set of values such as
+the --type of check_process, the values. Nothing is checked, so tooling
+generating the check configuration can read the capabilities of the binary.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := printCheckInfo(os.Stdout, listCheckInfo()); err != nil {
+				os.Exit(1)
+			}
+		},
+	})
+}
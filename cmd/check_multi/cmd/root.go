@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	certificate "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_certificate/cmd"
+	command "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_command/cmd"
+	cpu "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_cpu/cmd"
+	dir "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_dir/cmd"
+	disk "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_disk/cmd"
+	diskhealth "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_disk_health/cmd"
+	docker "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_docker/cmd"
+	entropy "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_entropy/cmd"
+	file "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_file/cmd"
+	fileexists "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_file_exists/cmd"
+	heartbeat "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_heartbeat/cmd"
+	http "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_http/cmd"
+	kmodule "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_kmodule/cmd"
+	load "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_load/cmd"
+	log "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_log/cmd"
+	memory "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_memory/cmd"
+	mountpoint "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_mountpoint/cmd"
+	netif "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_netif/cmd"
+	ntp "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_ntp/cmd"
+	performancecounter "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_performance_counter/cmd"
+	ping "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_ping/cmd"
+	portrange "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_port_range/cmd"
+	process "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_process/cmd"
+	service "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_service/cmd"
+	swap "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_swap/cmd"
+	systemd "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_systemd/cmd"
+	tcp "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_tcp/cmd"
+	uptime "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_uptime/cmd"
+	usergroup "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_user_group/cmd"
+	users "github.com/ncr-devops-platform/nagiosfoundation/cmd/check_users/cmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const checkTypeKey = "type"
+
+// checkTypes are the checks that may be run, by the name of their
+// command without the check_ prefix.
+var checkTypes = map[string]func(*pflag.FlagSet) func() nagiosfoundation.CheckResult{
+	"certificate": certificate.NewCheck,
+	"command":     command.NewCheck,
+	"cpu":         cpu.NewCheck,
+	"dir":         dir.NewCheck,
+	"disk":        disk.NewCheck,
+	"disk_health": diskhealth.NewCheck,
+	"docker":      docker.NewCheck,
+	"entropy":     entropy.NewCheck,
+	"file":        file.NewCheck,
+	"file_exists": func(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+		return fileexists.NewCheck(flags, nagiosfoundation.RunFileExistsCheck)
+	},
+	"heartbeat": heartbeat.NewCheck,
+	"http": func(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+		return http.NewCheck(flags, nagiosfoundation.RunHTTPCheck)
+	},
+	"kmodule":             kmodule.NewCheck,
+	"load":                load.NewCheck,
+	"log":                 log.NewCheck,
+	"memory":              memory.NewCheck,
+	"mountpoint":          mountpoint.NewCheck,
+	"netif":               netif.NewCheck,
+	"ntp":                 ntp.NewCheck,
+	"performance_counter": performancecounter.NewCheck,
+	"ping":                ping.NewCheck,
+	"port_range":          portrange.NewCheck,
+	"process":             process.NewCheck,
+	"service":             service.NewCheck,
+	"swap":                swap.NewCheck,
+	"systemd":             systemd.NewCheck,
+	"tcp":                 tcp.NewCheck,
+	"uptime":              uptime.NewCheck,
+	"user_group":          usergroup.NewCheck,
+	"users":               users.NewCheck,
+}
+
+func checkTypeNames() string {
+	names := make([]string, 0, len(checkTypes))
+	for name := range checkTypes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// newSubCheck returns the function running the check described by
+// the spec, its type and the values of the flags of the check
+// command. The check_ prefix of the type is optional.
+func newSubCheck(spec map[string]string) (func() nagiosfoundation.CheckResult, error) {
+	checkType := strings.TrimPrefix(spec[checkTypeKey], "check_")
+	if checkType == "" {
+		return nil, fmt.Errorf("no %s given", checkTypeKey)
+	}
+
+	newCheck, ok := checkTypes[checkType]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q, the types are %s", checkType, checkTypeNames())
+	}
+
+	flags := pflag.NewFlagSet(checkType, pflag.ContinueOnError)
+	check := newCheck(flags)
+
+	// The keys are set in order so an error names the same key on
+	// every run.
+	keys := make([]string, 0, len(spec))
+	for key := range spec {
+		if key != checkTypeKey {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if flags.Lookup(key) == nil {
+			return nil, fmt.Errorf("unknown option %q of type %s", key, checkType)
+		}
+
+		if err := flags.Set(key, spec[key]); err != nil {
+			return nil, fmt.Errorf("invalid value for option %q: %s", key, err)
+		}
+	}
+
+	var missing []string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if _, required := flag.Annotations[cobra.BashCompOneRequiredFlag]; required && !flag.Changed {
+			missing = append(missing, flag.Name)
+		}
+	})
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("option %q of type %s is required", missing[0], checkType)
+	}
+
+	return check, nil
+}
+
+// newSubChecks returns the functions running the checks listed in
+// the spec file.
+func newSubChecks(specPath string) ([]func() nagiosfoundation.CheckResult, error) {
+	specs, err := initcmd.ReadCheckList(specPath)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]func() nagiosfoundation.CheckResult, len(specs))
+
+	for i, spec := range specs {
+		if checks[i], err = newSubCheck(spec); err != nil {
+			return nil, fmt.Errorf("Check %d in %s: %s", i+1, specPath, err)
+		}
+	}
+
+	return checks, nil
+}
+
+// Execute runs the root command
+func Execute() {
+	var specPath string
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_multi",
+		Short: "Run several checks at once and report the worst result.",
+		Long: `Runs the checks listed in the YAML file given with --spec at once, in a single
+process, and issues the worst of their results, CRITICAL then WARNING then
+UNKNOWN then OK. The output is a summary counting the checks in each state
+followed by the result of each check on a line of its own, with the perfdata of
+all of the checks.
+
+Each check is given as its type, the name of the check command without the
+check_ prefix, and the flags of the command as keys, such as
+
+  checks:
+    - type: process
+      name: sshd
+    - type: disk
+      path: /
+      warning: 80%
+
+The checks share the --timeout of check_multi, so a check not complete in time
+is UNKNOWN while the results of the others are still issued. An invalid spec
+issues an UNKNOWN response without running any of the checks.
+
+The types are ` + checkTypeNames() + `.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(func() nagiosfoundation.CheckResult {
+				checks, err := newSubChecks(specPath)
+				if err != nil {
+					return nagiosfoundation.UnknownResult("CheckMulti", err.Error())
+				}
+
+				return nagiosfoundation.RunMultiCheck(checks, initcmd.Deadline())
+			})
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	addListChecksCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	const specFlag = "spec"
+	rootCmd.Flags().StringVarP(&specPath, specFlag, "s", "", "the YAML file listing the checks to run")
+	rootCmd.MarkFlagRequired(specFlag)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewSubCheck(t *testing.T) {
+	type testItem struct {
+		description string
+		spec        map[string]string
+		expectedErr string
+	}
+
+	testList := []testItem{
+		{"No type", map[string]string{"name": "sshd"}, "no type given"},
+		{"Unknown type", map[string]string{"type": "printer"}, "unknown type \"printer\", the types are certificate, command, cpu, dir,"},
+		{"Unknown option", map[string]string{"type": "process", "name": "sshd", "nme": "cron"}, "unknown option \"nme\" of type process"},
+		{"Invalid value", map[string]string{"type": "cpu", "warning": "high"}, "invalid value for option \"warning\""},
+		{"Required option", map[string]string{"type": "tcp", "host": "db01"}, "option \"port\" of type tcp is required"},
+	}
+
+	for _, i := range testList {
+		if _, err := newSubCheck(i.spec); err == nil || !strings.Contains(err.Error(), i.expectedErr) {
+			t.Errorf("%s: Expected Error: %s, Actual Error: %v", i.description, i.expectedErr, err)
+		}
+	}
+
+	for _, checkType := range []string{"file_exists", "check_file_exists"} {
+		check, err := newSubCheck(map[string]string{"type": checkType, "pattern": "/nonexistent/*.lock", "negate": "true"})
+		if err != nil {
+			t.Fatalf("newSubCheck() returned an error on a valid %s spec: %s", checkType, err)
+		}
+
+		if msg, code := check().Output(); code != 0 || !strings.Contains(msg, "CheckFileExists OK") {
+			t.Errorf("newSubCheck() should run the check with the options of the spec, returned %d, %s", code, msg)
+		}
+	}
+}
+
+func TestListChecks(t *testing.T) {
+	checks := listCheckInfo()
+	if len(checks) != len(checkTypes) || checks[0].Type != "certificate" {
+		t.Fatalf("listCheckInfo() should list every check type in order, got %d starting with %+v", len(checks), checks[0])
+	}
+
+	var buf bytes.Buffer
+	if err := printCheckInfo(&buf, checks); err != nil {
+		t.Fatalf("printCheckInfo() returned an error: %s", err)
+	}
+
+	var listed struct {
+		Checks []checkInfo `json:"checks"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &listed); err != nil {
+		t.Fatalf("printCheckInfo() should write JSON: %s", err)
+	}
+
+	flags := make(map[string]flagInfo)
+	for _, check := range listed.Checks {
+		for _, flag := range check.Flags {
+			flags[check.Command+" --"+flag.Name] = flag
+		}
+	}
+
+	if flag := flags["check_process --type"]; flag.Shorthand != "t" || flag.Default != "running" || !reflect.DeepEqual(flag.Values[:2], []string{"running", "notrunning"}) {
+		t.Errorf("The --type of check_process should be listed with its shorthand, default and types: %+v", flag)
+	}
+
+	if flag := flags["check_tcp --port"]; !flag.Required || flag.Type != "int" {
+		t.Errorf("The --port of check_tcp should be listed as a required int: %+v", flag)
+	}
+
+	if flag := flags["check_cpu --warning"]; flag.Required || flag.Values != nil {
+		t.Errorf("The --warning of check_cpu should be listed as optional without values: %+v", flag)
+	}
+}
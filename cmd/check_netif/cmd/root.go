@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.NetifCheckOptions
+
+	flags.StringVarP(&options.Interface, "interface", "i", "", "the name of the network interface, such as eth0")
+	flags.DurationVarP(&options.Interval, "interval", "", time.Second, "the time the counters are sampled over, such as 1s or 500ms")
+	flags.StringVarP(&options.Direction, "direction", "d", "both", "the direction checked against the thresholds: rx, tx or both")
+	flags.StringVarP(&options.Warning, "warning", "w", "", "the warning threshold, the bytes per second in each direction checked")
+	flags.StringVarP(&options.Critical, "critical", "c", "", "the critical threshold, the bytes per second in each direction checked")
+	flags.StringVarP(&options.ErrorsWarning, "errors_warning", "", "", "the warning threshold of the errors and drops per second")
+	flags.StringVarP(&options.ErrorsCritical, "errors_critical", "", "", "the critical threshold of the errors and drops per second")
+	flags.StringVarP(&options.MetricName, "metric_name", "m", "netif", "the prefix of the perfdata labels")
+
+	initcmd.SetFlagValues(flags, "direction", "rx", "tx", "both")
+	initcmd.SetRangeFlags(flags, "warning", "critical", "errors_warning", "errors_critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunNetifCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_netif",
+		Short: "Check the throughput of a network interface.",
+		Long: `Reads the counters of the network --interface from /proc/net/dev twice,
+--interval apart, and checks the bytes per second received and sent against the
+--warning and --critical thresholds, for the --direction rx, tx or both. The
+errors and drops per second are checked against the --errors_warning and
+--errors_critical thresholds. A counter lower than before has wrapped, at 2^32
+for the 32-bit counters of many drivers, or was reset. An interface that does
+not exist issues an UNKNOWN response. This check is Linux only.
+
+The thresholds are Nagios ranges, such as "12500000" to alert above 100Mbit/s.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -interface of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var server, warning, critical string
+	var port int
+
+	const serverFlag = "server"
+	flags.StringVarP(&server, serverFlag, "H", "", "the NTP server to query")
+	cobra.MarkFlagRequired(flags, serverFlag)
+	flags.IntVarP(&port, "port", "p", 123, "the port of the NTP server")
+	flags.StringVarP(&warning, "warning", "w", "60", "the warning threshold of the offset in seconds")
+	flags.StringVarP(&critical, "critical", "c", "120", "the critical threshold of the offset in seconds")
+
+	initcmd.SetRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunNTPCheck(server, port, *initcmd.TimeoutSeconds(), warning, critical, initcmd.TimeoutExit())
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_ntp",
+		Short: "Check the offset of the local clock from an NTP server.",
+		Long: `Queries the NTP server given with --server using SNTP and compares the absolute
+offset of the local clock from the server, in seconds, against the --warning
+and --critical thresholds. A CRITICAL response is issued when the offset is
+outside the critical threshold, a WARNING response when it is outside the
+warning threshold and an OK response otherwise.
+
+A server that does not reply within --timeout seconds, or that replies its
+own clock is not synchronized, issues an UNKNOWN response, as nothing can be
+said of the local clock.
+
+The --warning and --critical thresholds are Nagios ranges, such as "0.5" to
+alert on an offset above half a second.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// The time given to the reply is also the global --timeout, so
+	// the flag is bound to it with the check's own shorthand.
+	rootCmd.Flags().IntVarP(initcmd.TimeoutSeconds(), "timeout", "t", 10, "the number of seconds to wait for the reply")
+
+	// Accept the single dash -server of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
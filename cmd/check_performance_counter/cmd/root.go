@@ -7,15 +7,37 @@ import (
 	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-// Execute runs the root command
-func Execute() {
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
 	var greaterThan bool
 	var warning, critical float64
 	var pollingAttempts, pollingDelay int
 	var metricName, counterName string
 
+	const counterNameFlag = "counter_name"
+	flags.StringVarP(&counterName, counterNameFlag, "n", "", "the name of the performance counter to check")
+	cobra.MarkFlagRequired(flags, counterNameFlag)
+	flags.StringVarP(&metricName, "metric_name", "m", "", "the name of the metric generated by this check")
+	flags.Float64VarP(&warning, "warning", "w", 0, "the threshold to issue a warning alert")
+	flags.Float64VarP(&critical, "critical", "c", 0, "the threshold to issue a critical alert")
+	flags.BoolVarP(&greaterThan, "greater_than", "g", false, "issue warnings if the metric is greater than the expected thresholds (default false)")
+	flags.IntVarP(&pollingAttempts, "polling_attempts", "a", 2, "the number of times to fetch and average the performance counter")
+	flags.IntVarP(&pollingDelay, "polling_delay", "d", 1, "the number of seconds to delay between polling attempts")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunPerformanceCounterCheck(warning, critical, greaterThan, pollingAttempts,
+			pollingDelay, metricName, counterName)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
 	var rootCmd = &cobra.Command{
 		Use:   "check_performance_counter",
 		Short: "Retrieve and compare values on a performance counter.",
@@ -29,25 +51,18 @@ this check to emit an OK response when using these defaults.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.ParseFlags(os.Args)
 
-			msg, retval := nagiosfoundation.CheckPerformanceCounter(warning, critical, greaterThan, pollingAttempts,
-				pollingDelay, metricName, counterName)
+			result := initcmd.RunCheck(check)
 
-			fmt.Println(msg)
-			os.Exit(retval)
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
 		},
 	}
 
 	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
 
-	const counterNameFlag = "counter_name"
-	rootCmd.Flags().StringVarP(&counterName, counterNameFlag, "n", "", "the name of the performance counter to check")
-	rootCmd.MarkFlagRequired(counterNameFlag)
-	rootCmd.Flags().StringVarP(&metricName, "metric_name", "m", "", "the name of the metric generated by this check")
-	rootCmd.Flags().Float64VarP(&warning, "warning", "w", 0, "the threshold to issue a warning alert")
-	rootCmd.Flags().Float64VarP(&critical, "critical", "c", 0, "the threshold to issue a critical alert")
-	rootCmd.Flags().BoolVarP(&greaterThan, "greater_than", "g", false, "issue warnings if the metric is greater than the expected thresholds (default false)")
-	rootCmd.Flags().IntVarP(&pollingAttempts, "polling_attempts", "a", 2, "the number of times to fetch and average the performance counter")
-	rootCmd.Flags().IntVarP(&pollingDelay, "polling_delay", "d", 1, "the number of seconds to delay between polling attempts")
+	check = NewCheck(rootCmd.Flags())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.PingCheckOptions
+
+	const hostFlag = "host"
+	flags.StringVarP(&options.Host, hostFlag, "H", "", "the host to ping, a host name or an IPv4 or IPv6 address")
+	cobra.MarkFlagRequired(flags, hostFlag)
+	flags.IntVarP(&options.Count, "count", "p", 5, "the number of echo requests to send to each address")
+	flags.StringVarP(&options.Warning, "warning", "w", "100.0,20%", "the warning threshold as <rta>,<pl>%, the round trip average in milliseconds and the packet loss")
+	flags.StringVarP(&options.Critical, "critical", "c", "500.0,60%", "the critical threshold as <rta>,<pl>%, the round trip average in milliseconds and the packet loss")
+	flags.IntVarP(&options.IPVersion, "ip_version", "", 0, "the IP version pinged, 4 or 6, or 0 for either")
+	flags.StringVarP(&options.Addresses, "addresses", "", "any", "the addresses of a host resolving to several that must answer, \"any\" or \"all\"")
+
+	initcmd.SetFlagValues(flags, "ip_version", "0", "4", "6")
+	initcmd.SetFlagValues(flags, "addresses", "any", "all")
+
+	return func() nagiosfoundation.CheckResult {
+		options.Timeout = *initcmd.TimeoutSeconds()
+
+		return nagiosfoundation.RunPingCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_ping",
+		Short: "Check a host answers ICMP echo requests.",
+		Long: `Sends --count ICMP echo requests to --host and compares the round trip
+average and packet loss against the --warning and --critical thresholds, given
+as <rta>,<pl>% such as 100.0,20% as in the classic check_ping. A CRITICAL
+response is issued when either reaches the critical threshold, a WARNING
+response when either reaches the warning threshold and an OK response
+otherwise.
+
+A host name is resolved to its addresses of the --ip_version, the IPv4
+addresses first, which are pinged in turn until one answers, or with
+--addresses all each is pinged and the worst result is issued. The time taken
+resolving the host is output as the dns_time perfdata.
+
+A raw ICMP socket, needing root or CAP_NET_RAW, is used when it can be opened.
+Otherwise an unprivileged ICMP socket is used, which Linux allows for the
+groups in the net.ipv4.ping_group_range sysctl. If neither can be opened an
+UNKNOWN response is issued.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// The time given to the echo requests is also the global
+	// --timeout, so the flag is bound to it with the check's own
+	// shorthand.
+	rootCmd.Flags().IntVarP(initcmd.TimeoutSeconds(), "timeout", "t", 10, "the number of seconds to wait for all of the replies")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
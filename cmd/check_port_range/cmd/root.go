@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.PortRangeCheckOptions
+
+	flags.StringVarP(&options.Ports, "ports", "p", "", "the TCP ports that must be listening, a comma separated list of ports and ranges such as \"9000-9010,9200\"")
+	flags.StringVarP(&options.Warning, "warning", "w", "", "the warning threshold of the number of ports not listening, or of the percentage of the ports such as \"10%\"")
+	flags.StringVarP(&options.Critical, "critical", "c", "", "the critical threshold of the number of ports not listening, or of the percentage of the ports (default \"0\" without --warning)")
+	flags.StringVarP(&options.MetricName, "metric_name", "m", "missing", "the name of the metric generated by this check")
+
+	initcmd.SetRelativeRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunPortRangeCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_port_range",
+		Short: "Check a set of TCP ports are listening.",
+		Long: `Checks every TCP port of --ports, a comma separated list of ports and ranges
+such as "9000-9010,9200", is listening, reading the state of the sockets from
+/proc/net/tcp and /proc/net/tcp6 rather than connecting to each port. The
+ports not listening are listed in the result, and their number is checked
+against the --warning and --critical thresholds, which may be percentages of
+the ports checked such as "10%", or is CRITICAL from a single port without
+either. This check is Linux only.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -ports, -warning and -critical of the
+	// classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
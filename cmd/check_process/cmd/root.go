@@ -3,40 +3,158 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.ProcessCheckOptions
+	var target string
+
+	flags.StringArrayVarP(&options.Names, "name", "n", nil, "process name, repeated or separated by commas to check several processes with the \"running\" type, or the parent process name for the \"zombie\" type")
+	flags.StringVarP(&options.CheckType, "type", "t", "running", "Supported types are \"running\", \"notrunning\", \"wxmappings\", \"logactive\", \"cgroupcount\", \"count\", \"memory\", \"uptime\", \"threads\", \"fds\", \"zombie\", \"listening\", \"cpu\" and \"restarted\"")
+	flags.StringVarP(&options.MetricName, "metric_name", "m", "process_state", "the name of the metric generated by this check")
+	flags.StringVarP(&options.LogPath, "log_path", "l", "", "the path of the log the process writes, used by the \"logactive\" type")
+	flags.StringVarP(&options.Warning, "warning", "w", "", "the warning threshold, the seconds since the log was written for \"logactive\" (default 300), the range of instances for \"count\", the megabytes of memory for \"memory\", the seconds running for \"uptime\", the number of threads for \"threads\", the number, or with --of_limit the percentage of the limit, of open files for \"fds\", the number of zombie processes for \"zombie\" (default 0) or the percentage of CPU used for \"cpu\"")
+	flags.StringVarP(&options.Critical, "critical", "c", "", "the critical threshold, the seconds since the log was written for \"logactive\" (default 900), the range of instances for \"count\", the megabytes of memory for \"memory\", the seconds running for \"uptime\", the number of threads for \"threads\", the number, or with --of_limit the percentage of the limit, of open files for \"fds\", the number of zombie processes for \"zombie\" or the percentage of CPU used for \"cpu\"")
+	flags.IntVarP(&options.Port, "port", "p", 0, "the TCP port the process should be listening on, used by the \"listening\" type")
+	flags.IntVarP(&options.MinCount, "min_count", "", 1, "the minimum number of processes expected in each cgroup, used by the \"cgroupcount\" type")
+	flags.IntVarP(&options.MaxCount, "max_count", "", 0, "the maximum number of processes expected in each cgroup, 0 for no maximum, used by the \"cgroupcount\" type")
+
+	flags.StringVarP(&options.PidNamespace, "pid_ns", "", "", "only check processes in this PID namespace, given as a /proc/<pid>/ns/pid path, a PID or a container ID")
+
+	flags.StringVarP(&options.MatchCmdline, "match_cmdline", "", "", "only check processes with a command line containing this text")
+	flags.StringVarP(&options.ExePath, "exe_path", "", "", "only check processes running the executable at this path, or under it for a path ending in \"/\"")
+	flags.StringVarP(&options.User, "user", "u", "", "only check processes owned by this user, given as a user name or UID")
+	flags.IntVarP(&options.Ppid, "ppid", "", 0, "only check the children of the process with this PID, such as the workers of a supervisor")
+	flags.IntVarP(&options.Pgid, "pgid", "", 0, "only check the processes in the process group with this ID")
+	flags.BoolVarP(&options.Regex, "regex", "", false, "match --name, --match_cmdline and --exe_path as regular expressions")
+	flags.BoolVarP(&options.ShowPids, "show_pids", "", false, "list the PIDs of the processes matched in the result of the \"running\" and \"count\" types, also listed with --verbose")
+	flags.BoolVarP(&options.ExpectSingle, "expect_single", "", false, "expect exactly one instance of the process with the \"running\" type, WARNING with the PIDs when there are several")
+	flags.StringVarP(&options.Select, "select", "", "oldest", "the process checked by the \"uptime\" type when several match, \"oldest\" or \"youngest\"")
+	flags.BoolVarP(&options.PerProcess, "per_process", "", false, "check the threads, open files or CPU usage of each process rather than their total, used by the \"threads\", \"fds\" and \"cpu\" types")
+	flags.BoolVarP(&options.OfLimit, "of_limit", "", false, "check the open files as a percentage of the soft limit on open files, used by the \"fds\" type")
+	flags.DurationVarP(&options.Interval, "interval", "", time.Second, "the time the CPU usage is sampled over, such as 1s or 500ms, used by the \"cpu\" type")
+	flags.BoolVarP(&options.OfCPUs, "of_cpus", "", false, "check the CPU usage as a percentage of all of the CPUs rather than of one CPU, used by the \"cpu\" type")
+	flags.BoolVarP(&options.Delta, "delta", "", false, "check the change in the count since the previous run against the thresholds, used by the \"count\" type with --state_dir")
+	flags.StringVarP(&options.StateDir, "state_dir", "", "", "the directory the state of the check is saved in between runs, such as the previous count for --delta or the processes for the \"restarted\" type")
+	flags.StringVarP(&options.MissingState, "negate_on_missing", "", "", "the state reported when the process is not running, \"ok\", \"warning\", \"critical\" or \"unknown\", rather than that of the type")
+	flags.StringVarP(&options.ProcfsRoot, "procfs_root", "", "/proc", "the directory the proc filesystem is read from")
+	flags.IntVarP(&options.ContainerPid, "container_pid", "", 0, "check the processes in the container with this init process PID on the host, read from /proc/<pid>/root/proc")
+	flags.IntVarP(&options.Concurrency, "concurrency", "", 0, "the number of process names read at once while scanning the proc filesystem, 0 for the number of CPUs")
+	flags.StringVarP(&target, "target", "", "", "the check options as a list of key=value entries separated by semicolons")
+
+	initcmd.SetFlagValues(flags, "type", nagiosfoundation.ProcessCheckTypes()...)
+	initcmd.SetFlagValues(flags, "select", "oldest", "youngest")
+	initcmd.SetRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunProcessCheckWithTarget(target, options)
+	}
+}
+
 // Execute runs the root command
 func Execute() {
-	var name, checkType, metricName string
+	var check func() nagiosfoundation.CheckResult
 
 	var rootCmd = &cobra.Command{
 		Use:   "check_process",
 		Short: "Determine if a process is running.",
 		Long: `Perform a check for a process by name to determine if the process
 is running or not running. The default is to check for a running process.
+The "wxmappings" type checks the process for memory mappings that are both
+writable and executable. The "logactive" type checks the process has the
+log given with --log_path open and that the log was written to within the
+--warning and --critical number of seconds. The "cgroupcount" type counts the
+process in each cgroup and checks every count is within --min_count and
+--max_count. The "count" type counts the running instances of the process and
+checks the count against the --warning and --critical thresholds, or with
+--delta checks the change in the count since the previous run, saved in
+--state_dir, so a sudden spike or drop alerts. The "memory" type totals the
+resident memory of the processes and checks the total in megabytes against
+the --warning and --critical thresholds. The "uptime" type checks the seconds
+the --select oldest or youngest process has been running against the
+--warning and --critical thresholds. The "threads"
+type totals the threads of the processes, or with --per_process counts the
+threads of each, and checks the count against the --warning and --critical
+thresholds. The "fds" type likewise totals or, with --per_process, counts for
+each process the open file descriptors in /proc/<pid>/fd, and with --of_limit
+checks them as a percentage of the soft limit on open files. The "zombie"
+type counts the processes in the zombie state, read from /proc/<pid>/stat,
+whose parent matches --name, or every zombie without --name, and checks the
+count against the --warning (default 0) and --critical thresholds, naming
+the zombies and their parents when a threshold trips. The "listening" type
+checks the process has a socket listening on the TCP --port, read from the
+socket inodes in /proc/<pid>/fd and the listening sockets of /proc/net/tcp and
+tcp6, and is CRITICAL when the process is running but the port is not bound,
+as with a started but wedged service. The "cpu" type samples the user and
+system time of the processes in /proc/<pid>/stat twice, --interval apart, and
+checks the percentage of one CPU used, the total or with --per_process that of
+each process, against the --warning and --critical thresholds, with --of_cpus
+dividing it by the number of CPUs so that 100% is every CPU busy. The
+"restarted" type saves the PID and start time of each process in --state_dir
+and is WARNING when they changed since the previous run. On Linux, --pid_ns
+scopes any type to the processes in one PID namespace such as a single
+container, --match_cmdline to the processes with a command line containing the
+given text, --exe_path to the processes running the executable at the path or
+under a path ending in "/", --user to the processes owned by the user and
+--ppid and --pgid to the children of a process and the processes of a process
+group. With --regex, --name, --match_cmdline and --exe_path are regular
+expressions. Also on Linux, --procfs_root reads the proc filesystem from
+somewhere other than /proc, such as the host /proc mounted inside a container,
+and --concurrency sets the number of process names read at once, defaulting to
+the number of CPUs.
+
+When the process is not running, the "running" type and most others are
+CRITICAL while types such as "threads" with nothing to count are UNKNOWN.
+--negate_on_missing selects the state reported instead, such as "ok" for an
+optional daemon, so absence can be told apart from failure.
+
+The --warning and --critical thresholds are Nagios ranges, such as "10" to
+alert above 10, "5:" to alert below 5, "5:10" to alert outside 5 to 10 and
+"@5:10" to alert inside 5 to 10.
+
+The options may instead be given together with --target as a list of
+key=value entries separated by semicolons, using the flag names as keys with
+warn and crit short for warning and critical, for example
+--target 'name=java;type=logactive;log_path=/var/log/app.log;warn=60'.
+Entries in --target override the flags.
 
-The --name (-n) option is always required.
+Several processes may be checked at once by the "running" type by repeating
+--name or giving the names separated by commas, as in --name sshd,cron. The
+processes are listed once for all the names and the check is CRITICAL when
+any of them is not running, naming those that are not.
+
+With --expect_single the "running" type expects exactly one instance of a
+singleton daemon and is WARNING, listing the PIDs, when several are running.
+
+The --name (-n) option, or a name in --target, is required by every type
+but "zombie".
 ` + getHelpOsConstrained(),
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.ParseFlags(os.Args)
-			msg, retcode := nagiosfoundation.CheckProcess(name, checkType, metricName)
+			result := initcmd.RunCheck(check)
 
-			fmt.Println(msg)
-			os.Exit(retcode)
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
 		},
 	}
 
 	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
 
-	const nameFlag = "name"
-	rootCmd.Flags().StringVarP(&name, nameFlag, "n", "", "process name")
-	rootCmd.MarkFlagRequired(nameFlag)
-	rootCmd.Flags().StringVarP(&checkType, "type", "t", "running", "Supported types are \"running\" and \"notrunning\"")
-	rootCmd.Flags().StringVarP(&metricName, "metric_name", "m", "process_state", "the name of the metric generated by this check")
+	// Accept the -name and -type of the flag package check_process
+	// was first written with.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -7,17 +7,37 @@ import (
 	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 const serviceManagerFlag = "manager"
 const currentStateWantedFlag = "current_state"
 
-var state, user, manager string
-var currentStateWanted bool
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.ServiceCheckOptions
+
+	const nameFlag = "name"
+	flags.StringVarP(&options.Name, nameFlag, "n", "", "service name")
+	cobra.MarkFlagRequired(flags, nameFlag)
+
+	flags.StringVarP(&options.MatchBy, "match_by", "", "name", "match --name against the service name, \"name\", or on Windows its display name, \"display\"")
+	flags.StringVarP(&options.MissingState, "negate_on_missing", "", "", "the state reported when the service is not installed, \"ok\", \"warning\", \"critical\" or \"unknown\" (default \"unknown\")")
+	flags.DurationVarP(&options.Grace, "grace", "", 0, "the time to wait before checking a stopped service once more, such as 30s")
+
+	addFlagsOsConstrained(flags, &options)
+
+	return func() nagiosfoundation.CheckResult {
+		options.Deadline = initcmd.Deadline()
+
+		return nagiosfoundation.RunServiceCheck(options)
+	}
+}
 
 // Execute runs the root command
 func Execute() {
-	var name string
+	var check func() nagiosfoundation.CheckResult
 
 	var rootCmd = &cobra.Command{
 		Use:   "check_service",
@@ -27,20 +47,18 @@ given and the --name (-n) option is always required.` + getHelpOsConstrained(),
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.ParseFlags(os.Args)
 
-			msg, retcode := nagiosfoundation.CheckService(name, state, user, currentStateWanted, manager)
+			result := initcmd.RunCheck(check)
 
-			fmt.Println(msg)
-			os.Exit(retcode)
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
 		},
 	}
 
 	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
 
-	const nameFlag = "name"
-	rootCmd.Flags().StringVarP(&name, nameFlag, "n", "", "service name")
-	rootCmd.MarkFlagRequired(nameFlag)
-
-	addFlagsOsConstrained(rootCmd)
+	check = NewCheck(rootCmd.Flags())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
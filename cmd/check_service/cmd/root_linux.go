@@ -2,18 +2,21 @@
 
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/pflag"
+)
 
 func getHelpOsConstrained() string {
 	return `
 
-For Linux, the only check done is for a running state. Both the --name (-n) and
--manager (-m) options must be specified and the service is only checked
-to see if it is running.
+For Linux, the only check done is for a running state. The --name (-n) option
+must be specified and the service is only checked to see if it is running. The
+--manager (-m) defaults to the service manager the host was booted with.
 `
 }
 
-func addFlagsOsConstrained(cmd *cobra.Command) {
-	cmd.Flags().BoolVarP(&currentStateWanted, currentStateWantedFlag, "c", false, "output the service state in nagios output")
-	cmd.Flags().StringVarP(&manager, serviceManagerFlag, "m", "systemd", "the name of local service manager. Allowed options are: systemd")
+func addFlagsOsConstrained(flags *pflag.FlagSet, options *nagiosfoundation.ServiceCheckOptions) {
+	flags.BoolVarP(&options.CurrentStateWanted, currentStateWantedFlag, "c", false, "output the service state in nagios output")
+	flags.StringVarP(&options.Manager, serviceManagerFlag, "m", "", "the name of local service manager. Allowed options are: systemd, sysvinit. Detected when not given")
 }
@@ -2,7 +2,10 @@
 
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/pflag"
+)
 
 func getHelpOsConstrained() string {
 	return `
@@ -16,12 +19,18 @@ Some examples:
     Checks for the service in the running state and running as user.
   check_service.exe --name audiosrv --user "NT AUTHORITY\LocalService"
     Checks for the service to exist and would be run as user.
+  check_service.exe --name audiosrv --state running --start_type auto
+    Checks for the service in the running state and set to start automatically.
+  check_service.exe --name audiosrv --state running --recovery
+    Checks for the service in the running state and restarted when it fails.
 `
 }
 
-func addFlagsOsConstrained(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&state, "state", "s", "", "the desired state of the service")
-	cmd.Flags().StringVarP(&user, "user", "u", "", "the user the service should run as")
-	cmd.Flags().BoolVarP(&currentStateWanted, currentStateWantedFlag, "c", false, "output the Windows service state in nagios output")
-	cmd.Flags().StringVarP(&manager, serviceManagerFlag, "m", "wmi", "Service manager. Allowed options are: \"wmi\" and \"svcmgr\"")
+func addFlagsOsConstrained(flags *pflag.FlagSet, options *nagiosfoundation.ServiceCheckOptions) {
+	flags.StringVarP(&options.State, "state", "s", "", "the desired state of the service")
+	flags.StringVarP(&options.User, "user", "u", "", "the user the service should run as")
+	flags.StringVarP(&options.StartType, "start_type", "t", "", "the start type the service should have, such as auto, manual or disabled")
+	flags.BoolVarP(&options.Recovery, "recovery", "", false, "warn unless the service is restarted on its first and second failure")
+	flags.BoolVarP(&options.CurrentStateWanted, currentStateWantedFlag, "c", false, "output the Windows service state in nagios output")
+	flags.StringVarP(&options.Manager, serviceManagerFlag, "m", "wmi", "Service manager. Allowed options are: \"wmi\" and \"svcmgr\"")
 }
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var warning, critical, metricName string
+
+	flags.StringVarP(&warning, "warning", "w", "85%", "the warning threshold of the swap used, as a percentage such as 85% or an amount such as 2G")
+	flags.StringVarP(&critical, "critical", "c", "95%", "the critical threshold of the swap used, as a percentage such as 95% or an amount such as 4G")
+	flags.StringVarP(&metricName, "metric_name", "m", "swap", "the prefix of the perfdata labels")
+
+	initcmd.SetRelativeRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunSwapCheck(warning, critical, metricName)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_swap",
+		Short: "Check the swap used.",
+		Long: `Determines the swap used, read from SwapTotal and SwapFree of /proc/meminfo on
+Linux and from the page file on Windows, and compares it against the --warning
+and --critical thresholds. A CRITICAL response is issued when the swap used is
+over the critical threshold, a WARNING response when it is over the warning
+threshold and an OK response otherwise.
+
+The thresholds are a percentage of the total swap such as "85%", or an amount
+such as "2G" or "512MB", where the suffixes are powers of 1024. A host without
+swap configured issues an OK response saying so.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -warning of the classic plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var name, state string
+
+	flags.StringVarP(&name, "name", "n", "", "the unit to check, such as nginx or nginx.service, or none to check for failed units")
+	flags.StringVarP(&state, "state", "s", "active", "the expected ActiveState, optionally with the SubState such as active/running")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunSystemdCheck(name, state)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_systemd",
+		Short: "Check the state of a systemd unit.",
+		Long: `Reads the ActiveState and SubState of the --name unit and issues an OK
+response if the unit is in the --state given, such as active or active/running.
+A unit still activating towards an expected active state issues a WARNING
+response. Any other state, such as failed, issues a CRITICAL response.
+
+Without --name, a CRITICAL response is issued if any unit has failed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
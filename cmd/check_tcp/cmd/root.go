@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.TCPCheckOptions
+
+	const portFlag = "port"
+	flags.StringVarP(&options.Host, "host", "H", "127.0.0.1", "the host to connect to, a host name or an IPv4 or IPv6 address")
+	flags.IntVarP(&options.Port, portFlag, "p", 0, "the port to connect to")
+	cobra.MarkFlagRequired(flags, portFlag)
+	flags.StringVarP(&options.Send, "send", "s", "", "the string to send once connected")
+	flags.StringVarP(&options.Expect, "expect", "e", "", "the string the response must contain")
+	flags.IntVarP(&options.IPVersion, "ip_version", "", 0, "the IP version connected with, 4 or 6, or 0 for either")
+	flags.StringVarP(&options.Addresses, "addresses", "", "any", "the addresses of a host resolving to several that must accept the connection, \"any\" or \"all\"")
+
+	initcmd.SetFlagValues(flags, "ip_version", "0", "4", "6")
+	initcmd.SetFlagValues(flags, "addresses", "any", "all")
+
+	return func() nagiosfoundation.CheckResult {
+		options.Timeout = *initcmd.TimeoutSeconds()
+		options.TimeoutExit = initcmd.TimeoutExit()
+
+		return nagiosfoundation.RunTCPCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_tcp",
+		Short: "Check a TCP port accepts connections.",
+		Long: `Connects to --port on --host and issues a CRITICAL response if the connection
+fails or does not complete within --timeout seconds. Otherwise an OK response
+is issued with the time taken.
+
+For a basic check that the service behind the port is alive, --send writes a
+string once connected, which may contain \r, \n and \t escapes, and --expect
+issues a CRITICAL response unless the response contains the expected string.
+A service that sends a banner, such as SSH, can be checked with --expect alone.
+
+A host name is resolved to its addresses of the --ip_version, and connected to
+in turn until one accepts the connection, or with --addresses all each must
+accept it. A host that cannot be resolved issues an UNKNOWN response. The time
+taken resolving the host is output as the dns_time perfdata.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// The connect timeout is also the global --timeout, so the flag
+	// is bound to it with the check's own shorthand.
+	rootCmd.Flags().IntVarP(initcmd.TimeoutSeconds(), "timeout", "t", 10, "the number of seconds to wait for the connection and response")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,9 @@
+package main
+
+import (
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/check_tcp/cmd"
+)
+
+func main() {
+	cmd.Execute()
+}
@@ -3,38 +3,56 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"time"
 
 	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var warning, critical, metricName string
+
+	flags.StringVarP(&warning, "warning", "w", "15m:72h", "the range of uptime outside of which to issue a warning alert")
+	flags.StringVarP(&critical, "critical", "c", "168h", "the range of uptime outside of which to issue a critical alert")
+	flags.StringVarP(&metricName, "metric_name", "m", "current_sytem_uptime", "the name of the metric generated by this check")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunUptimeCheck(warning, critical, metricName)
+	}
+}
+
 // Execute runs the root command
 func Execute() {
-	var warning, critical time.Duration
-	var metricName string
+	var check func() nagiosfoundation.CheckResult
 
 	var rootCmd = &cobra.Command{
 		Use:   "check_uptime",
-		Short: "Determine if system uptime used exceeds time threshold.",
-		Long: `Determines the system uptime in seconds and if over the --critical
-threshold issue a CRITICAL response, then check if over the --warning threshold,
-issue a WARNING response. Otherwise, an OK response is issued.`,
+		Short: "Determine if the system uptime is within the time thresholds.",
+		Long: `Determines the time since the system booted and if outside the --critical
+range issue a CRITICAL response, then check if outside the --warning range,
+issue a WARNING response. Otherwise, an OK response is issued.
+
+The ranges are Nagios ranges with durations such as 15m, 72h or 7d as the
+bounds. "15m:" alerts when the system has been up less than 15 minutes, to
+catch an unexpected reboot, "72h" alerts when it has been up more than 72
+hours, such as a host overdue for patching, and "15m:72h" on either.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.ParseFlags(os.Args)
-			msg, retval := nagiosfoundation.CheckUptime("", warning, critical, metricName)
+			result := initcmd.RunCheck(check)
 
-			fmt.Println(msg)
-			os.Exit(retval)
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
 		},
 	}
 
 	initcmd.AddVersionCommand(rootCmd)
-	
-	rootCmd.Flags().DurationVarP(&warning, "warning", "w", time.Duration(72*time.Hour), "The uptime threshold to issue a warning alert, default is 72h")
-	rootCmd.Flags().DurationVarP(&critical, "critical", "c", time.Duration(168*time.Hour), "The uptime threshold to issue a critical alert, default is 1 week (168h)")
-	rootCmd.Flags().StringVarP(&metricName, "metric_name", "m", "current_sytem_uptime", "the name of the metric generated by this check")
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -7,11 +7,25 @@ import (
 	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var user, group string
+
+	flags.StringVarP(&user, "user", "u", "", "user name")
+	flags.StringVarP(&group, "group", "g", "", "group name")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunUserGroupCheck(user, group)
+	}
+}
+
 // Execute runs the root command
 func Execute() {
-	var user, group string
+	var check func() nagiosfoundation.CheckResult
 
 	var rootCmd = &cobra.Command{
 		Use:   "check_user_group",
@@ -27,21 +41,22 @@ belongs to a group. At least one flag must be provided.
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.ParseFlags(os.Args)
 
-			if user == "" && group == "" {
+			if cmd.Flags().Lookup("user").Value.String() == "" && cmd.Flags().Lookup("group").Value.String() == "" {
 				cmd.Help()
 			} else {
-				msg, retval := nagiosfoundation.CheckUserGroup(user, group)
+				result := initcmd.RunCheck(check)
 
-				fmt.Println(msg)
-				os.Exit(retval)
+				initcmd.PrintResult(result)
+				os.Exit(result.Code)
 			}
 		},
 	}
 
 	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
 
-	rootCmd.Flags().StringVarP(&user, "user", "u", "", "user name")
-	rootCmd.Flags().StringVarP(&group, "group", "g", "", "group name")
+	check = NewCheck(rootCmd.Flags())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
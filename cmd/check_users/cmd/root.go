@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCheck adds the flags of the check to flags and returns the
+// function running the check with their values.
+func NewCheck(flags *pflag.FlagSet) func() nagiosfoundation.CheckResult {
+	var options nagiosfoundation.UsersCheckOptions
+
+	flags.StringVarP(&options.Warning, "warning", "w", "", "the warning threshold of the number of sessions logged in")
+	flags.StringVarP(&options.Critical, "critical", "c", "", "the critical threshold of the number of sessions logged in")
+	flags.StringVarP(&options.Utmp, "utmp", "", "/var/run/utmp", "the utmp file the sessions are read from")
+	flags.StringVarP(&options.MetricName, "metric_name", "m", "users", "the name of the metric generated by this check")
+
+	initcmd.SetRangeFlags(flags, "warning", "critical")
+
+	return func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RunUsersCheck(options)
+	}
+}
+
+// Execute runs the root command
+func Execute() {
+	var check func() nagiosfoundation.CheckResult
+
+	var rootCmd = &cobra.Command{
+		Use:   "check_users",
+		Short: "Check the number of users logged in.",
+		Long: `Counts the login sessions read from the USER_PROCESS records of --utmp,
+/var/run/utmp by default, and checks the count against the --warning and
+--critical thresholds, such as --critical 0 to alert on any interactive login
+to a production host. The sessions are listed with their terminal line and
+the remote host. A utmp that cannot be read issues an UNKNOWN response rather
+than a count of zero. A utmp not in the record layout of glibc is read by
+running who instead. This check is Linux only.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.ParseFlags(os.Args)
+			result := initcmd.RunCheck(check)
+
+			initcmd.PrintResult(result)
+			os.Exit(result.Code)
+		},
+	}
+
+	initcmd.AddVersionCommand(rootCmd)
+	initcmd.AddSelftestCommand(rootCmd)
+	initcmd.AddGlobalFlags(rootCmd)
+
+	check = NewCheck(rootCmd.Flags())
+
+	// Accept the single dash -warning and -critical of the classic
+	// plugins.
+	os.Args = initcmd.NormalizeSingleDashFlags(rootCmd, os.Args)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,224 @@
+package initcmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// The config file selected with the --config flag.
+var configPath string
+
+// configSeparators are the separators between the key and value in
+// each supported config file format, by file extension.
+var configSeparators = map[string]string{
+	".yaml": ":",
+	".yml":  ":",
+	".toml": "=",
+}
+
+// applyConfig sets the flags of the command from the config file
+// selected with the --config flag. Flags given on the command line
+// override the config file.
+func applyConfig(cmd *cobra.Command) error {
+	if configPath == "" {
+		return nil
+	}
+
+	values, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range values {
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil {
+			return fmt.Errorf("Unknown key %q in config file %s", key, configPath)
+		}
+
+		if flag.Changed {
+			continue
+		}
+
+		if err := cmd.Flags().Set(key, value); err != nil {
+			return fmt.Errorf("Invalid value for key %q in config file %s: %s", key, configPath, err)
+		}
+
+		flagSources[key] = "config file " + configPath
+	}
+
+	return nil
+}
+
+// readConfig reads the flag values from a YAML or TOML config file,
+// the format chosen by the file extension.
+func readConfig(path string) (map[string]string, error) {
+	separator, ok := configSeparators[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported config file %s. Supported extensions are .yaml, .yml and .toml", path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := parseConfig(string(data), separator)
+	if err != nil {
+		return nil, fmt.Errorf("Config file %s: %s", path, err)
+	}
+
+	return values, nil
+}
+
+// parseConfig parses a config file of one key and value per line,
+// separated by separator, such as "warning: 10" in YAML or
+// "warning = 10" in TOML. As the command line flags all take a single
+// value, only this flat subset of YAML and TOML is supported. Values
+// may be quoted and lines and values may end with a # comment.
+func parseConfig(data, separator string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for i, line := range strings.Split(data, "\n") {
+		lineNumber := i + 1
+
+		line = strings.TrimSpace(line)
+		if line == "" || line == "---" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyValue := strings.SplitN(line, separator, 2)
+		if len(keyValue) != 2 || strings.TrimSpace(keyValue[0]) == "" {
+			return nil, fmt.Errorf("line %d is not a key%s value pair, only flat files are supported", lineNumber, separator)
+		}
+
+		key := strings.TrimSpace(keyValue[0])
+		value, err := parseConfigValue(strings.TrimSpace(keyValue[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+
+		if _, ok := values[key]; ok {
+			return nil, fmt.Errorf("line %d: key %q given more than once", lineNumber, key)
+		}
+
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// parseConfigValue removes the quotes or trailing comment from a
+// config value.
+func parseConfigValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		end := 1
+		for end < len(value) && value[end] != '"' {
+			if value[end] == '\\' {
+				end++
+			}
+			end++
+		}
+
+		if end >= len(value) {
+			return "", fmt.Errorf("unterminated quote in %s", value)
+		}
+
+		return strconv.Unquote(value[:end+1])
+	case strings.HasPrefix(value, "'"):
+		end := strings.Index(value[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quote in %s", value)
+		}
+
+		return value[1 : end+1], nil
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+
+	return value, nil
+}
+
+// ReadCheckList reads a YAML list of checks, each a flat map of keys
+// and values, such as the sub-checks run by check_multi:
+//
+//	checks:
+//	  - type: process
+//	    name: sshd
+//	  - type: disk
+//	    warning: 80
+//
+// The list may be under a checks key or at the top of the file. As
+// with a --config file only this subset of YAML is supported, a value
+// taking a single line.
+func ReadCheckList(path string) ([]map[string]string, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".yaml" && ext != ".yml" {
+		return nil, fmt.Errorf("Unsupported check list %s. Supported extensions are .yaml and .yml", path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	checks, err := parseCheckList(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("Check list %s: %s", path, err)
+	}
+
+	return checks, nil
+}
+
+// parseCheckList parses a YAML list of flat maps. A line starting
+// with "- " starts the next map and the indented lines after it add
+// to the map.
+func parseCheckList(data string) ([]map[string]string, error) {
+	var checks []map[string]string
+
+	for i, line := range strings.Split(data, "\n") {
+		lineNumber := i + 1
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if trimmed == "checks:" && len(checks) == 0 && !strings.HasPrefix(line, " ") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") {
+			checks = append(checks, make(map[string]string))
+			trimmed = strings.TrimSpace(trimmed[2:])
+		} else if len(checks) == 0 || !strings.HasPrefix(line, " ") {
+			return nil, fmt.Errorf("line %d is not in a list of checks, each starting with \"- \"", lineNumber)
+		}
+
+		keyValue := strings.SplitN(trimmed, ":", 2)
+		if len(keyValue) != 2 || strings.TrimSpace(keyValue[0]) == "" {
+			return nil, fmt.Errorf("line %d is not a key: value pair, only flat checks are supported", lineNumber)
+		}
+
+		key := strings.TrimSpace(keyValue[0])
+		value, err := parseConfigValue(strings.TrimSpace(keyValue[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+
+		check := checks[len(checks)-1]
+		if _, ok := check[key]; ok {
+			return nil, fmt.Errorf("line %d: key %q given more than once", lineNumber, key)
+		}
+
+		check[key] = value
+	}
+
+	return checks, nil
+}
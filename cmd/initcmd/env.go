@@ -0,0 +1,49 @@
+package initcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envVarName returns the environment variable setting a flag of the
+// command, the command and flag names upper cased and joined by an
+// underscore with dashes replaced, such as CHECK_PROCESS_NAME for the
+// --name flag of check_process.
+func envVarName(commandName, flagName string) string {
+	name := commandName + "_" + flagName
+
+	return strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// applyEnv sets each flag of the command not given on the command line
+// from its environment variable, if set. As the flags set are then
+// changed, they also override the config file.
+func applyEnv(cmd *cobra.Command) error {
+	var err error
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "help" {
+			return
+		}
+
+		envVar := envVarName(cmd.Name(), flag.Name)
+
+		value, ok := os.LookupEnv(envVar)
+		if !ok {
+			return
+		}
+
+		if setErr := cmd.Flags().Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("Invalid value for environment variable %s: %s", envVar, setErr)
+			return
+		}
+
+		flagSources[flag.Name] = "environment " + envVar
+	})
+
+	return err
+}
@@ -0,0 +1,122 @@
+package initcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+)
+
+// The exit codes the WARNING, CRITICAL and UNKNOWN results are
+// reported as, selected with the --map_warning_to, --map_critical_to
+// and --map_unknown_to flags. Empty keeps the exit code.
+var mapWarningTo string
+var mapCriticalTo string
+var mapUnknownTo string
+
+// The state an UNKNOWN result is escalated to, selected with the
+// --unknown_as flag, "unknown", "warning" or "critical". It is the
+// --map_unknown_to of the states alone, for setups paging on CRITICAL
+// only that would rather see an UNKNOWN as a WARNING.
+var unknownAs string
+
+// unknownAsStates are the states an UNKNOWN result may be escalated to
+// with --unknown_as.
+var unknownAsStates = []string{"unknown", "warning", "critical"}
+
+// exitCodeStates are the state names a result may be mapped to.
+var exitCodeStates = map[string]int{
+	"ok":       0,
+	"warning":  1,
+	"critical": 2,
+	"unknown":  3,
+}
+
+// addExitCodeMap adds the --map_warning_to, --map_critical_to and
+// --map_unknown_to flags to the root command.
+func addExitCodeMap(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&mapWarningTo, "map_warning_to", "", "report a WARNING result as this state, ok, warning, critical or unknown, or as this exit code from 0 to 255")
+	cmd.PersistentFlags().StringVar(&mapCriticalTo, "map_critical_to", "", "report a CRITICAL result as this state, ok, warning, critical or unknown, or as this exit code from 0 to 255")
+	cmd.PersistentFlags().StringVar(&mapUnknownTo, "map_unknown_to", "", "report an UNKNOWN result as this state, ok, warning, critical or unknown, or as this exit code from 0 to 255")
+	cmd.PersistentFlags().StringVar(&unknownAs, "unknown_as", "unknown", "report an UNKNOWN result as unknown, warning or critical")
+}
+
+// validateUnknownAs checks --unknown_as is one of unknownAsStates and
+// is not given along with --map_unknown_to, which would contradict it.
+func validateUnknownAs(unknownAs, mapUnknownTo string) error {
+	valid := false
+	for _, state := range unknownAsStates {
+		valid = valid || strings.ToLower(unknownAs) == state
+	}
+
+	if !valid {
+		return fmt.Errorf("Invalid --unknown_as %q. Valid states are \"unknown\", \"warning\" and \"critical\"", unknownAs)
+	}
+
+	if mapUnknownTo != "" && strings.ToLower(unknownAs) != "unknown" {
+		return fmt.Errorf("--unknown_as and --map_unknown_to may not both be given")
+	}
+
+	return nil
+}
+
+// parseExitCode returns the exit code of a state name such as
+// "warning", or of an exit code such as "1". Empty is -1, keeping the
+// exit code.
+func parseExitCode(value string) (int, error) {
+	if value == "" {
+		return -1, nil
+	}
+
+	if code, ok := exitCodeStates[strings.ToLower(value)]; ok {
+		return code, nil
+	}
+
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 0 || code > 255 {
+		return 0, fmt.Errorf("Invalid exit code mapping %q. Valid mappings are \"ok\", \"warning\", \"critical\", \"unknown\" and exit codes from 0 to 255", value)
+	}
+
+	return code, nil
+}
+
+func validateExitCodeMap(mappings ...string) error {
+	for _, mapping := range mappings {
+		if _, err := parseExitCode(mapping); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mapExitCode returns the result of a check with the
+// --map_warning_to, --map_critical_to, --map_unknown_to and
+// --unknown_as flags applied, changing the status of the result along
+// with the exit code. It is applied once to the final result, after
+// --invert and --retries, so a mapped result is neither inverted nor
+// retried.
+func mapExitCode(result nagiosfoundation.CheckResult) nagiosfoundation.CheckResult {
+	var mapping string
+
+	switch result.Code {
+	case 1:
+		mapping = mapWarningTo
+	case 2:
+		mapping = mapCriticalTo
+	case 3:
+		mapping = mapUnknownTo
+		if mapping == "" {
+			mapping = unknownAs
+		}
+	}
+
+	code, err := parseExitCode(mapping)
+	if err != nil || code < 0 {
+		return result
+	}
+
+	return result.Remap(code)
+}
@@ -0,0 +1,71 @@
+package initcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Set with the --explain flag to print the options rather than run
+// the check.
+var explain bool
+
+// flagSources is where each flag not given on the command line was
+// set from, such as a config file, by flag name.
+var flagSources = make(map[string]string)
+
+// addExplain adds the --explain flag to the root command and wraps
+// its Run so that with the flag the options of the check are printed
+// and the command exits 0 without running the check.
+func addExplain(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&explain, "explain", false, "print the options of the check, with where each was set, rather than run it")
+
+	run := cmd.Run
+	if run == nil {
+		return
+	}
+
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		if explain {
+			fmt.Print(explainFlags(cmd))
+			os.Exit(0)
+		}
+
+		run(cmd, args)
+	}
+}
+
+// explainFlags returns the value of each flag of the command, sorted
+// by name, with whether it is the default, was given on the command
+// line or was set from a config file.
+func explainFlags(cmd *cobra.Command) string {
+	var explanation strings.Builder
+
+	explanation.WriteString(cmd.Name() + " options:\n")
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "help" || flag.Name == "explain" {
+			return
+		}
+
+		value := flag.Value.String()
+		if flag.Value.Type() == "string" {
+			value = fmt.Sprintf("%q", value)
+		}
+
+		source := "default"
+		if flag.Changed {
+			source = "command line"
+			if s, ok := flagSources[flag.Name]; ok {
+				source = s
+			}
+		}
+
+		explanation.WriteString(fmt.Sprintf("  %s = %s (%s)\n", flag.Name, value, source))
+	})
+
+	return explanation.String()
+}
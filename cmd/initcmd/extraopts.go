@@ -0,0 +1,192 @@
+package initcmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The section and ini file selected with the --extra-opts flag, such
+// as "check_process@/etc/nagios/plugins.ini".
+var extraOpts string
+
+// extraOptsDefault is the value of an --extra-opts given without one,
+// reading the section named after the command from the default file.
+const extraOptsDefault = "@"
+
+// defaultExtraOptsFiles are the ini files searched for the section of
+// an --extra-opts given without a file, the same files searched by the
+// plugins of the monitoring-plugins suite.
+var defaultExtraOptsFiles = []string{
+	"/etc/nagios/plugins.ini",
+	"/usr/local/nagios/etc/plugins.ini",
+	"/usr/local/etc/nagios/plugins.ini",
+	"/etc/opt/nagios/plugins.ini",
+	"/etc/nagios-plugins.ini",
+	"/usr/local/etc/nagios-plugins.ini",
+	"/etc/opt/nagios-plugins.ini",
+}
+
+// iniOption is a key of an ini section, with its value if it has one.
+type iniOption struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+func addExtraOpts(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&extraOpts, "extra-opts", "", "read flags from a section of an ini file, as in --extra-opts=section@file, overridden by the flags given")
+	cmd.PersistentFlags().Lookup("extra-opts").NoOptDefVal = extraOptsDefault
+}
+
+// splitExtraOpts returns the section and file of an --extra-opts
+// value of the form section@file. The section defaults to the name of
+// the command and an empty file is searched for in the default files.
+func splitExtraOpts(opts, commandName string) (string, string) {
+	section, path := opts, ""
+
+	if i := strings.LastIndex(opts, "@"); i >= 0 {
+		section, path = opts[:i], opts[i+1:]
+	}
+
+	if section == "" {
+		section = commandName
+	}
+
+	return section, path
+}
+
+// findExtraOptsFile returns the first of the default ini files that
+// exists.
+func findExtraOptsFile() (string, error) {
+	for _, path := range defaultExtraOptsFiles {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("No ini file for --extra-opts found in %s", strings.Join(defaultExtraOptsFiles, ", "))
+}
+
+// applyExtraOpts sets the flags of the command from the ini section
+// selected with the --extra-opts flag, each key of the section the
+// name of a flag. Flags given on the command line override the
+// section. A key given more than once is set once for each value, so
+// a flag taking a list of values may be repeated as on the command
+// line.
+func applyExtraOpts(cmd *cobra.Command) error {
+	if extraOpts == "" {
+		return nil
+	}
+
+	section, path := splitExtraOpts(extraOpts, cmd.Name())
+	if path == "" {
+		var err error
+
+		if path, err = findExtraOptsFile(); err != nil {
+			return err
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	options, err := parseIniSection(string(data), section)
+	if err != nil {
+		return fmt.Errorf("Ini file %s: %s", path, err)
+	}
+
+	given := make(map[string]bool)
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		given[flag.Name] = true
+	})
+
+	for _, option := range options {
+		flag := cmd.Flags().Lookup(option.key)
+		if flag == nil {
+			return fmt.Errorf("Unknown key %q in section [%s] of ini file %s", option.key, section, path)
+		}
+
+		if given[option.key] {
+			continue
+		}
+
+		value := option.value
+		if !option.hasValue {
+			if flag.NoOptDefVal == "" {
+				return fmt.Errorf("Key %q in section [%s] of ini file %s needs a value", option.key, section, path)
+			}
+
+			value = flag.NoOptDefVal
+		}
+
+		if err := cmd.Flags().Set(option.key, value); err != nil {
+			return fmt.Errorf("Invalid value for key %q in section [%s] of ini file %s: %s", option.key, section, path, err)
+		}
+
+		flagSources[option.key] = fmt.Sprintf("extra-opts [%s] of %s", section, path)
+	}
+
+	return nil
+}
+
+// parseIniSection returns the options of the section of an ini file,
+// one "key=value" per line, or a key alone for a flag without a value,
+// such as "verbose". Lines starting with # or ; are comments. The
+// sections of the same name are read as one.
+func parseIniSection(data, section string) ([]iniOption, error) {
+	var options []iniOption
+	var current string
+	found := false
+
+	for i, line := range strings.Split(data, "\n") {
+		lineNumber := i + 1
+
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section name %s", lineNumber, line)
+			}
+
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			if current == section {
+				found = true
+			}
+
+			continue
+		}
+
+		if current != section {
+			continue
+		}
+
+		keyValue := strings.SplitN(line, "=", 2)
+
+		option := iniOption{key: strings.TrimSpace(keyValue[0])}
+		if option.key == "" {
+			return nil, fmt.Errorf("line %d is not a key=value pair", lineNumber)
+		}
+
+		if len(keyValue) == 2 {
+			option.value, option.hasValue = strings.TrimSpace(keyValue[1]), true
+		}
+
+		options = append(options, option)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("section [%s] not found", section)
+	}
+
+	return options, nil
+}
@@ -1,6 +1,7 @@
 package initcmd
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // The command name and version are injected into
@@ -16,6 +18,11 @@ import (
 // See godel/config/dist-plugin.yml
 var cmdName string
 var cmdVersion string
+var cmdCommit string
+var cmdBuildDate string
+
+// The --json flag of the version command.
+var versionJSON bool
 
 // SetFlagIfNotProvided sets a command line flag if it wasn't
 // provided. This overcomes a command line flag in library
@@ -52,19 +59,47 @@ func SetDefaultGlogStderr() {
 func GetVersion() string {
 	const unknown = "<unknown>"
 
-	if cmdName == "" {
-		cmdName = unknown
+	name, version := cmdName, cmdVersion
+
+	if name == "" {
+		name = unknown
 	}
 
-	if cmdVersion == "" {
-		cmdVersion = unknown
+	if version == "" {
+		version = unknown
 	}
 
-	version := cmdName + " version " + cmdVersion + " " + runtime.GOOS + "/" + runtime.GOARCH
+	version = name + " version " + version + " " + runtime.GOOS + "/" + runtime.GOARCH
 
 	return version
 }
 
+// GetVersionJSON returns the executable version, commit and build
+// date as JSON. Those not injected at build time are "unknown".
+func GetVersionJSON() string {
+	const unknown = "unknown"
+
+	valueOrUnknown := func(value string) string {
+		if value == "" {
+			return unknown
+		}
+
+		return value
+	}
+
+	version, _ := json.Marshal(struct {
+		Version   string `json:"version"`
+		Commit    string `json:"commit"`
+		BuildDate string `json:"buildDate"`
+	}{
+		Version:   valueOrUnknown(cmdVersion),
+		Commit:    valueOrUnknown(cmdCommit),
+		BuildDate: valueOrUnknown(cmdBuildDate),
+	})
+
+	return string(version)
+}
+
 // ShowVersion checks for "version" to be the only argument
 // and if true, writes the version to the io.Writer passed
 // in.
@@ -93,14 +128,71 @@ func CheckExecutableVersion() {
 	}
 }
 
-// AddVersionCommand adds the version command via Cobra
+// AddVersionCommand adds the version command via Cobra, along with
+// a --version flag printing the same version.
 func AddVersionCommand(cmd *cobra.Command) {
-	cmd.AddCommand(&cobra.Command{
+	cmd.Version = GetVersion()
+	cmd.SetVersionTemplate("{{.Version}}\n")
+
+	versionCmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print the version",
-		Long:  "Print the version and OS/arch.",
+		Long:  "Print the version and OS/arch, or with --json the version, commit and build date as JSON.",
 		Run: func(cmd *cobra.Command, args []string) {
+			if versionJSON {
+				fmt.Println(GetVersionJSON())
+				return
+			}
+
 			ShowVersion(os.Stdout)
 		},
-	})
+	}
+
+	versionCmd.Flags().BoolVar(&versionJSON, "json", false, "print the version, commit and build date as JSON")
+
+	cmd.AddCommand(versionCmd)
+}
+
+// NormalizeSingleDashFlags returns the arguments with the long flags of
+// the command given with a single dash, such as -name, rewritten with
+// two dashes. The flag package of Go accepts either, so commands
+// migrated from it keep working with existing configurations, where
+// Cobra would otherwise take -name as -n with the value "ame". Only
+// arguments naming a long flag of the command are rewritten, so
+// shorthand flags such as -n or -vn are unchanged, as is everything
+// after a "--" argument.
+func NormalizeSingleDashFlags(cmd *cobra.Command, args []string) []string {
+	cmd.InitDefaultHelpFlag()
+	cmd.InitDefaultVersionFlag()
+
+	normalized := make([]string, len(args))
+	copy(normalized, args)
+
+	for i, arg := range normalized {
+		if arg == "--" {
+			break
+		}
+
+		if len(arg) < 3 || arg[0] != '-' || arg[1] == '-' {
+			continue
+		}
+
+		name := strings.SplitN(arg[1:], "=", 2)[0]
+		if cmd.Flags().Lookup(name) != nil || cmd.PersistentFlags().Lookup(name) != nil {
+			normalized[i] = "-" + arg
+		}
+	}
+
+	return normalized
+}
+
+// FlagValuesAnnotation is the annotation of a flag listing the values
+// it accepts, set with SetFlagValues().
+const FlagValuesAnnotation = "nagiosfoundation_values"
+
+// SetFlagValues annotates the flag with the values it accepts, such as
+// the check types of check_process, for tooling listing the options of
+// the checks.
+func SetFlagValues(flags *pflag.FlagSet, name string, values ...string) {
+	flags.SetAnnotation(name, FlagValuesAnnotation, values)
 }
@@ -1,12 +1,19 @@
 package initcmd
 
 import (
+	"bytes"
+	"errors"
 	"flag"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
 	"github.com/spf13/cobra"
 )
 
@@ -94,5 +101,910 @@ func TestVersion(t *testing.T) {
 		t.Error("version command did not load into Cobra")
 	}
 
+	expectedResult = `{"version":"unknown","commit":"unknown","buildDate":"unknown"}`
+	if version := GetVersionJSON(); version != expectedResult {
+		t.Errorf("Version JSON returned is not correct. Expected result: %s Actual Result: %s", expectedResult, version)
+	}
+
+	cmdVersion = "1.2.0"
+	cmdCommit = "a023d8a"
+	cmdBuildDate = "2019-06-04T15:04:05Z"
+	expectedResult = `{"version":"1.2.0","commit":"a023d8a","buildDate":"2019-06-04T15:04:05Z"}`
+	if version := GetVersionJSON(); version != expectedResult {
+		t.Errorf("Version JSON returned is not correct. Expected result: %s Actual Result: %s", expectedResult, version)
+	}
+
+	cmdVersion, cmdCommit, cmdBuildDate = "", "", ""
+
+	if cmdList[0].Flags().Lookup("json") == nil {
+		t.Error("version command does not have the --json flag")
+	}
+
+	if testCmd.Version != "<unknown> version <unknown> "+runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("AddVersionCommand() should set the version printed by --version: %s", testCmd.Version)
+	}
+
 	os.Args = savedArgs
 }
+
+func TestNormalizeSingleDashFlags(t *testing.T) {
+	testCmd := &cobra.Command{Use: "check_process"}
+	testCmd.Flags().StringP("name", "n", "", "")
+	testCmd.Flags().StringP("type", "t", "", "")
+	testCmd.Flags().BoolP("verbose", "v", false, "")
+	testCmd.PersistentFlags().Int("timeout", 10, "")
+
+	args := []string{"check_process", "-name", "nginx", "-type=running", "-timeout", "5", "-n", "sshd", "-vn", "cron",
+		"--name", "bash", "-help", "--", "-name"}
+	expected := []string{"check_process", "--name", "nginx", "--type=running", "--timeout", "5", "-n", "sshd", "-vn", "cron",
+		"--name", "bash", "--help", "--", "-name"}
+
+	normalized := NormalizeSingleDashFlags(testCmd, args)
+	if strings.Join(normalized, " ") != strings.Join(expected, " ") {
+		t.Errorf("NormalizeSingleDashFlags() Expected: %v, Actual: %v", expected, normalized)
+	}
+
+	if args[1] != "-name" {
+		t.Error("NormalizeSingleDashFlags() should not change the arguments passed to it")
+	}
+}
+
+func TestFormatResult(t *testing.T) {
+	savedOutputFormat := outputFormat
+
+	msg := "CheckCPU WARNING - value = 87.500000 | cpu=87.5%;85;95"
+
+	outputFormat = outputFormatText
+	if output := FormatResult(nagiosfoundation.ParseCheckResult(msg, 1)); output != msg {
+		t.Errorf("FormatResult() text output should be the message unchanged: %s", output)
+	}
+
+	outputFormat = outputFormatJSON
+	expected := `{"check":"CheckCPU","status":"WARNING","code":1,"message":"value = 87.500000","perfdata":[{"label":"cpu","value":87.5,"uom":"%","warning":"85","critical":"95"}]}`
+	if output := FormatResult(nagiosfoundation.ParseCheckResult(msg, 1)); output != expected {
+		t.Errorf("FormatResult() json output. Expected: %s, Actual: %s", expected, output)
+	}
+
+	label = "web-pod-3"
+	expected = `{"label":"web-pod-3","check":"CheckCPU","status":"WARNING","code":1,"message":"value = 87.500000","perfdata":[{"label":"cpu","value":87.5,"uom":"%","warning":"85","critical":"95"}]}`
+	if output := FormatResult(nagiosfoundation.ParseCheckResult(msg, 1)); output != expected {
+		t.Errorf("FormatResult() labelled json output. Expected: %s, Actual: %s", expected, output)
+	}
+
+	outputFormat = outputFormatText
+	if output := FormatResult(nagiosfoundation.ParseCheckResult(msg, 1)); output != "[web-pod-3] "+msg {
+		t.Errorf("FormatResult() should prefix the text output with the label: %s", output)
+	}
+
+	if output := FormatResult(nagiosfoundation.OKResult("CheckProcess", "Process bash is running")); output != "[web-pod-3] CheckProcess OK - Process bash is running" {
+		t.Errorf("FormatResult() should prefix the text output of a result with the label: %s", output)
+	}
+
+	label = ""
+
+	// A pipe in the message is kept in the JSON output, while in the
+	// text output it does not start the perfdata.
+	piped := nagiosfoundation.WarningResult("CheckLog", "ERROR disk full | retrying", nagiosfoundation.PerfData{Label: "matches", Value: 1})
+	if output := FormatResult(piped); output != "CheckLog WARNING - ERROR disk full ¦ retrying | matches=1" {
+		t.Errorf("FormatResult() text output should not start the perfdata in the message: %s", output)
+	}
+
+	outputFormat = outputFormatJSON
+	if output := FormatResult(piped); !strings.Contains(output, `"message":"ERROR disk full | retrying","perfdata":[{"label":"matches","value":1}]`) {
+		t.Errorf("FormatResult() json output should keep the message as it is: %s", output)
+	}
+	outputFormat = outputFormatText
+
+	// The text output is cut to the --max_output_length, keeping the
+	// perfdata, while the JSON output is kept whole.
+	long := "CheckLog WARNING - 2 lines matching ERROR\n" + strings.Repeat("ERROR disk full\n", 20) + "ERROR done | matches=21"
+	maxOutputLength = 80
+	if output := FormatResult(nagiosfoundation.ParseCheckResult(long, 1)); len(output) > 80 || !strings.HasSuffix(output, "...(truncated) | matches=21") {
+		t.Errorf("FormatResult() should cut the text output to the maximum length: %q", output)
+	}
+
+	outputFormat = outputFormatJSON
+	if output := FormatResult(nagiosfoundation.ParseCheckResult(long, 1)); !strings.Contains(output, "ERROR done") {
+		t.Errorf("FormatResult() should not cut the json output: %s", output)
+	}
+
+	outputFormat = outputFormatText
+	maxOutputLength = 0
+	if output := FormatResult(nagiosfoundation.ParseCheckResult(long, 1)); output != long {
+		t.Errorf("FormatResult() should not cut the output without a maximum length: %q", output)
+	}
+
+	maxOutputLength = defaultMaxOutputLength
+
+	for _, format := range []string{"text", "json", "JSON"} {
+		if err := validateOutputFormat(format); err != nil {
+			t.Errorf("Output format %s should be valid: %s", format, err)
+		}
+	}
+
+	if err := validateOutputFormat("xml"); err == nil {
+		t.Error("Output format xml should not be valid")
+	}
+
+	testCmd := &cobra.Command{}
+	AddGlobalFlags(testCmd)
+	if testCmd.PersistentFlags().Lookup("output") == nil {
+		t.Error("output flag did not load into Cobra")
+	}
+
+	if testCmd.PersistentFlags().ShorthandLookup("v") == nil {
+		t.Error("verbose flag did not load into Cobra")
+	}
+
+	if testCmd.PersistentFlags().Lookup("invert") == nil {
+		t.Error("invert flag did not load into Cobra")
+	}
+
+	outputFormat = savedOutputFormat
+}
+
+func TestResultSink(t *testing.T) {
+	savedResultSink := resultSink
+
+	for _, sink := range []string{"stdout", "syslog", "file:/var/spool/nagios/results"} {
+		if err := validateResultSink(sink); err != nil {
+			t.Errorf("Result sink %s should be valid: %s", sink, err)
+		}
+	}
+
+	for _, sink := range []string{"stderr", "file:", "/tmp/results"} {
+		if err := validateResultSink(sink); err == nil {
+			t.Errorf("Result sink %s should not be valid", sink)
+		}
+	}
+
+	severities := map[int]syslogSeverity{0: syslogSeverityInfo, 1: syslogSeverityWarning, 2: syslogSeverityErr, 3: syslogSeverityErr}
+	for retcode, expected := range severities {
+		if severity := resultSeverity(retcode); severity != expected {
+			t.Errorf("resultSeverity(%d) Expected: %d, Actual: %d", retcode, expected, severity)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "result-sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "results")
+	resultSink = resultSinkFile + path
+
+	PrintResult(nagiosfoundation.ParseCheckResult("CheckProcess OK - Process bash is running", 0))
+	PrintResult(nagiosfoundation.ParseCheckResult("CheckProcess CRITICAL - Process nginx is not running", 2))
+
+	data, err := ioutil.ReadFile(path)
+	expected := "CheckProcess OK - Process bash is running\nCheckProcess CRITICAL - Process nginx is not running\n"
+	if err != nil || string(data) != expected {
+		t.Errorf("PrintResult() should append each result to the file. Expected: %q, Actual: %q, Error: %v", expected, data, err)
+	}
+
+	if err := writeResult("CheckProcess OK", 0); err != nil {
+		t.Errorf("writeResult() should write to the file: %s", err)
+	}
+
+	resultSink = resultSinkFile + filepath.Join(dir, "missing", "results")
+	if err := writeResult("CheckProcess OK", 0); err == nil {
+		t.Error("writeResult() should return an error when the file cannot be opened")
+	}
+
+	resultSink = savedResultSink
+}
+
+func TestQuiet(t *testing.T) {
+	savedResultSink := resultSink
+
+	dir, err := ioutil.TempDir("", "quiet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "results")
+	resultSink = resultSinkFile + path
+	quiet = true
+
+	PrintResult(nagiosfoundation.ParseCheckResult("CheckProcess OK - Process bash is running", 0))
+	PrintResult(nagiosfoundation.ParseCheckResult("CheckProcess WARNING - Process nginx has 3 instances", 1))
+	PrintResult(nagiosfoundation.ParseCheckResult("CheckProcess UNKNOWN - Could not read the processes", 3))
+
+	data, err := ioutil.ReadFile(path)
+	expected := "CheckProcess WARNING - Process nginx has 3 instances\nCheckProcess UNKNOWN - Could not read the processes\n"
+	if err != nil || string(data) != expected {
+		t.Errorf("PrintResult() with --quiet should only write results that are not OK. Expected: %q, Actual: %q, Error: %v", expected, data, err)
+	}
+
+	quiet = false
+	resultSink = savedResultSink
+}
+
+func TestPerfdataOnly(t *testing.T) {
+	savedResultSink := resultSink
+
+	perfdataOnly = true
+	label = "web-pod-3"
+
+	msg := "CheckDisk WARNING - Disk used on / is 85.00% | disk_used=85B;80;95;0;100 'disk used pct'=85%;80;95;0;100"
+	expected := "disk_used=85B;80;95;0;100 'disk used pct'=85%;80;95;0;100"
+	if output := FormatResult(nagiosfoundation.ParseCheckResult(msg, 1)); output != expected {
+		t.Errorf("FormatResult() with --perfdata_only should output the perfdata alone. Expected: %s, Actual: %s", expected, output)
+	}
+
+	if output := FormatResult(nagiosfoundation.ParseCheckResult("CheckService OK - sshd in a running state", 0)); output != "" {
+		t.Errorf("FormatResult() with --perfdata_only should output nothing without perfdata: %q", output)
+	}
+
+	dir, err := ioutil.TempDir("", "perfdata-only")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "results")
+	resultSink = resultSinkFile + path
+
+	PrintResult(nagiosfoundation.ParseCheckResult("CheckService OK - sshd in a running state", 0))
+	PrintResult(nagiosfoundation.ParseCheckResult("CheckProcess CRITICAL - Process nginx is not running | process_state=2", 2))
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil || string(data) != "process_state=2\n" {
+		t.Errorf("PrintResult() with --perfdata_only should only write the results with perfdata: %q, Error: %v", data, err)
+	}
+
+	perfdataOnly = false
+	label = ""
+	resultSink = savedResultSink
+}
+
+func TestFinalResult(t *testing.T) {
+	msg := "CheckFileExists OK - /tmp/lock exists"
+
+	if output, code := finalResult(nagiosfoundation.ParseCheckResult(msg, 0)).Output(); output != msg || code != 0 {
+		t.Errorf("finalResult() should not change the result without --invert: %s %d", output, code)
+	}
+
+	invert = true
+	if output, code := finalResult(nagiosfoundation.ParseCheckResult(msg, 0)).Output(); output != "CheckFileExists CRITICAL - /tmp/lock exists" || code != 2 {
+		t.Errorf("finalResult() should invert the result with --invert: %s %d", output, code)
+	}
+	invert = false
+}
+
+func TestExitCodeMap(t *testing.T) {
+	msg := "CheckTcp CRITICAL - Connection to 127.0.0.1:5432 failed"
+
+	if output, code := mapExitCode(nagiosfoundation.ParseCheckResult(msg, 2)).Output(); output != msg || code != 2 {
+		t.Errorf("mapExitCode() should not change the result without a mapping: %s %d", output, code)
+	}
+
+	mapCriticalTo = "warning"
+	if output, code := mapExitCode(nagiosfoundation.ParseCheckResult(msg, 2)).Output(); output != "CheckTcp WARNING - Connection to 127.0.0.1:5432 failed" || code != 1 {
+		t.Errorf("mapExitCode() should map CRITICAL to WARNING: %s %d", output, code)
+	}
+
+	if output, code := mapExitCode(nagiosfoundation.ParseCheckResult("CheckTcp OK - Connected to 127.0.0.1:5432", 0)).Output(); code != 0 || output != "CheckTcp OK - Connected to 127.0.0.1:5432" {
+		t.Errorf("mapExitCode() should not map an OK result: %s %d", output, code)
+	}
+	mapCriticalTo = ""
+
+	mapUnknownTo = "4"
+	if output, code := mapExitCode(nagiosfoundation.ParseCheckResult("CheckTcp UNKNOWN - timed out after 10s", 3)).Output(); output != "CheckTcp UNKNOWN - timed out after 10s" || code != 4 {
+		t.Errorf("mapExitCode() should map UNKNOWN to exit code 4 keeping the status text: %s %d", output, code)
+	}
+	mapUnknownTo = ""
+
+	savedUnknownAs := unknownAs
+	unknownAs = "warning"
+	if output, code := mapExitCode(nagiosfoundation.ParseCheckResult("CheckTcp UNKNOWN - timed out after 10s", 3)).Output(); output != "CheckTcp WARNING - timed out after 10s" || code != 1 {
+		t.Errorf("mapExitCode() should escalate UNKNOWN to WARNING with --unknown_as keeping the description: %s %d", output, code)
+	}
+
+	if output, code := mapExitCode(nagiosfoundation.ParseCheckResult(msg, 2)).Output(); output != msg || code != 2 {
+		t.Errorf("--unknown_as should only change an UNKNOWN result: %s %d", output, code)
+	}
+	unknownAs = savedUnknownAs
+
+	for _, state := range []string{"unknown", "Warning", "critical"} {
+		if err := validateUnknownAs(state, ""); err != nil {
+			t.Errorf("--unknown_as %q should be valid: %s", state, err)
+		}
+	}
+
+	if err := validateUnknownAs("ok", ""); err == nil {
+		t.Error("--unknown_as should not hide an UNKNOWN result as OK")
+	}
+
+	if err := validateUnknownAs("critical", "4"); err == nil {
+		t.Error("--unknown_as should not be given along with --map_unknown_to")
+	}
+
+	if err := validateUnknownAs("unknown", "4"); err != nil {
+		t.Errorf("The default --unknown_as should allow --map_unknown_to: %s", err)
+	}
+
+	for _, mapping := range []string{"", "OK", "critical", "0", "255"} {
+		if err := validateExitCodeMap(mapping); err != nil {
+			t.Errorf("Exit code mapping %q should be valid: %s", mapping, err)
+		}
+	}
+
+	for _, mapping := range []string{"fatal", "-1", "256"} {
+		if err := validateExitCodeMap(mapping); err == nil {
+			t.Errorf("Exit code mapping %q should not be valid", mapping)
+		}
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	savedRetries, savedRetryInterval := retries, retryInterval
+	retries, retryInterval = 3, time.Second
+
+	clock := time.Date(2019, 6, 4, 15, 4, 5, 0, time.UTC)
+	now := func() time.Time { return clock }
+	sleep := func(d time.Duration) { clock = clock.Add(d) }
+
+	results := []int{2, 1, 0, 2}
+	runs := 0
+	check := func() nagiosfoundation.CheckResult {
+		retcode := results[runs]
+		runs++
+		clock = clock.Add(100 * time.Millisecond)
+
+		return nagiosfoundation.NewCheckResult("CheckTcp", nagiosfoundation.State(retcode), "attempt")
+	}
+
+	if msg, code := runCheck(check, sleep, now).Output(); code != 0 || runs != 3 || msg != "CheckTcp OK - attempt" {
+		t.Errorf("runCheck() should retry until OK. Code: %d, Runs: %d, Msg: %s", code, runs, msg)
+	}
+
+	results, runs = []int{2, 2, 2, 2, 2}, 0
+	if msg, code := runCheck(check, sleep, now).Output(); code != 2 || runs != 4 || msg != "CheckTcp CRITICAL - attempt" {
+		t.Errorf("runCheck() should return the last result after the retries. Code: %d, Runs: %d, Msg: %s", code, runs, msg)
+	}
+
+	// With 2s left, one retry fits but a second would pass the
+	// deadline.
+	results, runs = []int{2, 2, 2, 2}, 0
+	deadline = clock.Add(2 * time.Second)
+	if _, code := runCheck(check, sleep, now).Output(); code != 2 || runs != 2 {
+		t.Errorf("runCheck() should not retry past the deadline. Code: %d, Runs: %d", code, runs)
+	}
+	deadline = time.Time{}
+
+	retries = 0
+	results, runs = []int{2, 0}, 0
+	if _, code := runCheck(check, sleep, now).Output(); code != 2 || runs != 1 {
+		t.Errorf("runCheck() should not retry without --retries. Code: %d, Runs: %d", code, runs)
+	}
+
+	savedCommandName := commandName
+	commandName = "check_process"
+
+	msg, code := RunCheck(func() nagiosfoundation.CheckResult { panic("nil process list") }).Output()
+	if code != 3 || msg != "CheckProcess UNKNOWN - The check failed unexpectedly: nil process list" {
+		t.Errorf("RunCheck() should return UNKNOWN for a check that panics. Code: %d, Msg: %s", code, msg)
+	}
+	commandName = savedCommandName
+
+	if validateRetries(-1, time.Second) == nil || validateRetries(1, -time.Second) == nil || validateRetries(1, 0) != nil {
+		t.Error("validateRetries() should accept only retries and intervals of 0 or more")
+	}
+
+	retries, retryInterval = savedRetries, savedRetryInterval
+}
+
+func TestTimeout(t *testing.T) {
+	if !runWithTimeout(time.Second, func() {}) {
+		t.Error("runWithTimeout() should complete work finishing before the timeout")
+	}
+
+	release := make(chan struct{})
+	if runWithTimeout(10*time.Millisecond, func() { <-release }) {
+		t.Error("runWithTimeout() should time out work not finishing before the timeout")
+	}
+	close(release)
+
+	expected := "CheckProcess UNKNOWN - timed out after 10s"
+	if msg := timeoutResult("check_process", nagiosfoundation.StateUnknown, 10).String(); msg != expected {
+		t.Errorf("timeoutResult(). Expected: %s, Actual: %s", expected, msg)
+	}
+
+	testCmd := &cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+	AddGlobalFlags(testCmd)
+	if testCmd.PersistentFlags().Lookup("timeout") == nil {
+		t.Error("timeout flag did not load into Cobra")
+	}
+
+	if testCmd.PersistentFlags().Lookup("timeout_exit") == nil {
+		t.Error("timeout_exit flag did not load into Cobra")
+	}
+
+	for _, state := range []string{"", "unknown", "Critical", "warning"} {
+		if err := validateTimeoutExit(state); err != nil {
+			t.Errorf("validateTimeoutExit(%q) should accept the state: %s", state, err)
+		}
+	}
+
+	if err := validateTimeoutExit("ok"); err == nil {
+		t.Error("validateTimeoutExit() should reject a state that is not unknown, critical or warning")
+	}
+
+	if *TimeoutSeconds() != defaultTimeoutSeconds {
+		t.Errorf("TimeoutSeconds() should default to %d seconds", defaultTimeoutSeconds)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	yaml := `---
+# check_process defaults
+name: java
+type: count   # instances
+warning: "2:4"
+critical: '1:'
+match_cmdline: "com.acme.\"Order\" Worker"
+`
+
+	values, err := parseConfig(yaml, ":")
+	if err != nil {
+		t.Fatalf("parseConfig() returned an error on valid YAML: %s", err)
+	}
+
+	expected := map[string]string{
+		"name":          "java",
+		"type":          "count",
+		"warning":       "2:4",
+		"critical":      "1:",
+		"match_cmdline": `com.acme."Order" Worker`,
+	}
+
+	for key, value := range expected {
+		if values[key] != value {
+			t.Errorf("parseConfig() YAML key %s. Expected: %s, Actual: %s", key, value, values[key])
+		}
+	}
+
+	values, err = parseConfig("timeout = 30\nurl = \"http://example.com/#top\"\n", "=")
+	if err != nil || values["timeout"] != "30" || values["url"] != "http://example.com/#top" {
+		t.Errorf("parseConfig() did not parse valid TOML: %v, Error: %v", values, err)
+	}
+
+	for _, invalid := range []string{"[check_process]\nname = java", "name = java\nname = bash", "name = \"java"} {
+		if _, err := parseConfig(invalid, "="); err == nil {
+			t.Errorf("parseConfig() should not parse %q", invalid)
+		}
+	}
+}
+
+func TestParseCheckList(t *testing.T) {
+	yaml := `---
+# checks run by check_multi
+checks:
+  - type: process
+    name: sshd   # the daemon
+  - type: disk
+    warning: "80"
+
+  - type: http
+    url: "http://example.com/#top"
+`
+
+	checks, err := parseCheckList(yaml)
+	expected := []map[string]string{
+		{"type": "process", "name": "sshd"},
+		{"type": "disk", "warning": "80"},
+		{"type": "http", "url": "http://example.com/#top"},
+	}
+
+	if err != nil || !reflect.DeepEqual(checks, expected) {
+		t.Errorf("parseCheckList() Expected: %v, Actual: %v, Error: %v", expected, checks, err)
+	}
+
+	if checks, err = parseCheckList("- type: cpu\n- type: load\n"); err != nil || len(checks) != 2 {
+		t.Errorf("parseCheckList() should parse a list at the top of the file: %v, Error: %v", checks, err)
+	}
+
+	for _, invalid := range []string{"type: cpu", "checks:\n  type: cpu", "- type: cpu\n  type: load", "- type: cpu\nname: bash", "- type: cpu\n  warning", "- type: \"cpu"} {
+		if _, err := parseCheckList(invalid); err == nil {
+			t.Errorf("parseCheckList() should not parse %q", invalid)
+		}
+	}
+
+	if _, err := ReadCheckList("checks.toml"); err == nil || !strings.Contains(err.Error(), "Unsupported check list") {
+		t.Errorf("ReadCheckList() should only read YAML: %v", err)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	savedConfigPath := configPath
+
+	dir, err := ioutil.TempDir("", "initcmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var name, checkType string
+
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "check_test"}
+		cmd.Flags().StringVar(&name, "name", "", "")
+		cmd.Flags().StringVar(&checkType, "type", "running", "")
+
+		return cmd
+	}
+
+	configPath = filepath.Join(dir, "check.yaml")
+	if err := ioutil.WriteFile(configPath, []byte("name: java\ntype: count\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newCmd()
+	cmd.ParseFlags([]string{"--type", "memory"})
+	if err := applyConfig(cmd); err != nil {
+		t.Fatalf("applyConfig() returned an error: %s", err)
+	}
+
+	if name != "java" || checkType != "memory" {
+		t.Errorf("applyConfig() should set flags not given on the command line, name: %s, type: %s", name, checkType)
+	}
+
+	if err := ioutil.WriteFile(configPath, []byte("nmae: java\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyConfig(newCmd()); err == nil || !strings.Contains(err.Error(), "Unknown key") {
+		t.Errorf("applyConfig() should reject unknown keys, returned %v", err)
+	}
+
+	configPath = filepath.Join(dir, "check.ini")
+	if err := applyConfig(newCmd()); err == nil {
+		t.Error("applyConfig() should reject unsupported config files")
+	}
+
+	configPath = savedConfigPath
+}
+
+func TestValidateConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "initcmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var name, warning, critical, disk, missing string
+	var port int
+
+	cmd := &cobra.Command{Use: "check_test"}
+	cmd.Flags().StringVar(&name, "name", "", "")
+	cmd.Flags().IntVar(&port, "port", 0, "")
+	cmd.Flags().StringVar(&warning, "warning", "", "")
+	cmd.Flags().StringVar(&critical, "critical", "", "")
+	cmd.Flags().StringVar(&disk, "disk_warning", "85%", "")
+	cmd.Flags().StringVar(&missing, "missing_warning", "", "")
+	cmd.PersistentFlags().StringVar(&label, "label", "", "")
+	SetRangeFlags(cmd.Flags(), "warning", "critical")
+	SetRelativeRangeFlags(cmd.Flags(), "missing_warning")
+
+	testList := []struct {
+		description string
+		file        string
+		data        string
+		expected    []string
+	}{
+		{"Valid", "check.yaml", "name: java\nport: 8080\nwarning: \"@5:10\"\ncritical: ''\ndisk_warning: 90%\nmissing_warning: 10%\nlabel: web-1\n", nil},
+		{"Percentages", "check.yaml", "warning: 10%\nmissing_warning: \"5:120%\"\n", []string{
+			`Invalid threshold for key "missing_warning": Invalid range "5:120%", end is not a percentage from 0% to 100%`,
+			`Invalid threshold for key "warning": Invalid range "10%", end is not a number`,
+		}},
+		{"Problems sorted by key", "check.toml", "warnign = 5\ncritical = \"10:5x\"\nport = http\nname = java\n", []string{
+			`Invalid threshold for key "critical": Invalid range "10:5x", end is not a number`,
+			`Invalid value for key "port": strconv.ParseInt: parsing "http": invalid syntax`,
+			`Unknown key "warnign"`,
+		}},
+		{"Malformed", "check.yaml", "name java\n", []string{"Config file " + filepath.Join(dir, "check.yaml") + ": line 1 is not a key: value pair, only flat files are supported"}},
+		{"Unsupported", "check.ini", "name=java\n", []string{"Unsupported config file " + filepath.Join(dir, "check.ini") + ". Supported extensions are .yaml, .yml and .toml"}},
+	}
+
+	for _, i := range testList {
+		path := filepath.Join(dir, i.file)
+		if err := ioutil.WriteFile(path, []byte(i.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if problems := validateConfig(cmd, path); !reflect.DeepEqual(problems, i.expected) {
+			t.Errorf("%s: Expected: %q, Actual: %q", i.description, i.expected, problems)
+		}
+	}
+
+	if problems := validateConfig(cmd, ""); len(problems) != 1 || !strings.Contains(problems[0], "A config file must be given") {
+		t.Errorf("validateConfig() should require a config file, returned %q", problems)
+	}
+
+	var out bytes.Buffer
+	if code := printConfigProblems(&out, "check.yaml", []string{`Unknown key "warnign"`}); code != 1 || out.String() != "FAIL Unknown key \"warnign\"\n" {
+		t.Errorf("printConfigProblems() Expected: 1 FAIL line, Actual: %d %q", code, out.String())
+	}
+
+	out.Reset()
+	if code := printConfigProblems(&out, "check.yaml", nil); code != 0 || out.String() != "OK   config file check.yaml\n" {
+		t.Errorf("printConfigProblems() Expected: 0 OK line, Actual: %d %q", code, out.String())
+	}
+
+	label = ""
+}
+
+func TestParseIniSection(t *testing.T) {
+	data := `# shared settings
+[check_process]
+name = sshd
+
+[java_workers]
+; the order workers
+name=java
+match_cmdline=com.acme.OrderWorker
+verbose
+args=-v
+args=status
+
+[check_process]
+type=running
+`
+
+	expected := []iniOption{
+		{key: "name", value: "java", hasValue: true},
+		{key: "match_cmdline", value: "com.acme.OrderWorker", hasValue: true},
+		{key: "verbose"},
+		{key: "args", value: "-v", hasValue: true},
+		{key: "args", value: "status", hasValue: true},
+	}
+
+	if options, err := parseIniSection(data, "java_workers"); err != nil || !reflect.DeepEqual(options, expected) {
+		t.Errorf("parseIniSection() Expected: %v, Actual: %v, Error: %v", expected, options, err)
+	}
+
+	expected = []iniOption{{key: "name", value: "sshd", hasValue: true}, {key: "type", value: "running", hasValue: true}}
+	if options, err := parseIniSection(data, "check_process"); err != nil || !reflect.DeepEqual(options, expected) {
+		t.Errorf("parseIniSection() should read the sections of the same name as one. Expected: %v, Actual: %v, Error: %v", expected, options, err)
+	}
+
+	errorList := []struct {
+		description string
+		data        string
+		expectedErr string
+	}{
+		{"Missing section", data, "section [check_disk] not found"},
+		{"Unterminated section", "[check_disk\nname=sda\n", "line 1: unterminated section name"},
+		{"Empty key", "[check_disk]\n=sda\n", "line 2 is not a key=value pair"},
+	}
+
+	for _, i := range errorList {
+		if _, err := parseIniSection(i.data, "check_disk"); err == nil || !strings.Contains(err.Error(), i.expectedErr) {
+			t.Errorf("%s: Expected Error: %s, Actual Error: %v", i.description, i.expectedErr, err)
+		}
+	}
+
+	splitList := []struct {
+		opts            string
+		expectedSection string
+		expectedPath    string
+	}{
+		{"java_workers@/etc/nagios/plugins.ini", "java_workers", "/etc/nagios/plugins.ini"},
+		{"@/etc/nagios/plugins.ini", "check_process", "/etc/nagios/plugins.ini"},
+		{"java_workers", "java_workers", ""},
+		{extraOptsDefault, "check_process", ""},
+	}
+
+	for _, i := range splitList {
+		if section, path := splitExtraOpts(i.opts, "check_process"); section != i.expectedSection || path != i.expectedPath {
+			t.Errorf("splitExtraOpts(%q) Expected: %s %s, Actual: %s %s", i.opts, i.expectedSection, i.expectedPath, section, path)
+		}
+	}
+}
+
+func TestApplyExtraOpts(t *testing.T) {
+	savedExtraOpts := extraOpts
+	savedFlagSources := flagSources
+	flagSources = make(map[string]string)
+
+	dir, err := ioutil.TempDir("", "initcmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var name, checkType string
+	var args []string
+	var dryRun bool
+
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "check_test"}
+		cmd.Flags().StringVar(&name, "name", "", "")
+		cmd.Flags().StringVar(&checkType, "type", "running", "")
+		cmd.Flags().StringSliceVar(&args, "args", nil, "")
+		cmd.Flags().BoolVar(&dryRun, "dry_run", false, "")
+
+		return cmd
+	}
+
+	path := filepath.Join(dir, "plugins.ini")
+	data := "[check_test]\nname=java\ntype=count\nargs=-v\nargs=status\ndry_run\n\n[bad]\nnmae=java\n\n[no_value]\nname\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	extraOpts = "@" + path
+	cmd := newCmd()
+	cmd.ParseFlags([]string{"--type", "memory"})
+	if err := applyExtraOpts(cmd); err != nil {
+		t.Fatalf("applyExtraOpts() returned an error: %s", err)
+	}
+
+	if name != "java" || checkType != "memory" || strings.Join(args, " ") != "-v status" || !dryRun {
+		t.Errorf("applyExtraOpts() should set flags not given on the command line, name: %s, type: %s, args: %v, dry_run: %t", name, checkType, args, dryRun)
+	}
+
+	if expected := "extra-opts [check_test] of " + path; flagSources["name"] != expected {
+		t.Errorf("applyExtraOpts() should record the section a flag was set from. Expected: %s, Actual: %s", expected, flagSources["name"])
+	}
+
+	errorList := []struct {
+		opts        string
+		expectedErr string
+	}{
+		{"bad@" + path, "Unknown key \"nmae\" in section [bad]"},
+		{"no_value@" + path, "Key \"name\" in section [no_value]"},
+		{"missing@" + path, "section [missing] not found"},
+		{"check_test@" + filepath.Join(dir, "missing.ini"), "missing.ini"},
+	}
+
+	for _, i := range errorList {
+		extraOpts = i.opts
+		if err := applyExtraOpts(newCmd()); err == nil || !strings.Contains(err.Error(), i.expectedErr) {
+			t.Errorf("applyExtraOpts() with %s Expected Error: %s, Actual Error: %v", i.opts, i.expectedErr, err)
+		}
+	}
+
+	extraOpts = savedExtraOpts
+	flagSources = savedFlagSources
+}
+
+func TestExplainFlags(t *testing.T) {
+	var name, warning, critical string
+
+	testCmd := &cobra.Command{Use: "check_test", Run: func(cmd *cobra.Command, args []string) {}}
+	testCmd.Flags().StringVar(&name, "name", "", "")
+	testCmd.Flags().StringVar(&warning, "warning", "", "")
+	testCmd.Flags().StringVar(&critical, "critical", "90", "")
+
+	testCmd.ParseFlags([]string{"--name", "java", "--warning", "80"})
+	savedFlagSources := flagSources
+	flagSources = map[string]string{"warning": "config file /etc/check.yaml"}
+
+	expected := `check_test options:
+  critical = "90" (default)
+  name = "java" (command line)
+  warning = "80" (config file /etc/check.yaml)
+`
+	if explanation := explainFlags(testCmd); explanation != expected {
+		t.Errorf("explainFlags() Expected: %s, Actual: %s", expected, explanation)
+	}
+
+	flagSources = savedFlagSources
+}
+
+func TestApplyEnv(t *testing.T) {
+	savedFlagSources := flagSources
+	flagSources = make(map[string]string)
+
+	if envVar := envVarName("check_http", "timeout_exit"); envVar != "CHECK_HTTP_TIMEOUT_EXIT" {
+		t.Errorf("envVarName() should upper case the names: %s", envVar)
+	}
+
+	if envVar := envVarName("check_process", "extra-opts"); envVar != "CHECK_PROCESS_EXTRA_OPTS" {
+		t.Errorf("envVarName() should replace dashes: %s", envVar)
+	}
+
+	var name, checkType string
+	var minCount int
+
+	cmd := &cobra.Command{Use: "check_test"}
+	cmd.Flags().StringVar(&name, "name", "", "")
+	cmd.Flags().StringVar(&checkType, "type", "running", "")
+	cmd.Flags().IntVar(&minCount, "min_count", 1, "")
+
+	os.Setenv("CHECK_TEST_NAME", "java")
+	os.Setenv("CHECK_TEST_TYPE", "count")
+	defer os.Unsetenv("CHECK_TEST_NAME")
+	defer os.Unsetenv("CHECK_TEST_TYPE")
+
+	cmd.ParseFlags([]string{"--type", "memory"})
+	if err := applyEnv(cmd); err != nil {
+		t.Fatalf("applyEnv() returned an error: %s", err)
+	}
+
+	if name != "java" || checkType != "memory" || minCount != 1 {
+		t.Errorf("applyEnv() should set flags not given on the command line, name: %s, type: %s, min_count: %d", name, checkType, minCount)
+	}
+
+	if flagSources["name"] != "environment CHECK_TEST_NAME" {
+		t.Errorf("applyEnv() should record the environment variable a flag was set from: %s", flagSources["name"])
+	}
+
+	os.Setenv("CHECK_TEST_MIN_COUNT", "many")
+	defer os.Unsetenv("CHECK_TEST_MIN_COUNT")
+
+	if err := applyEnv(cmd); err == nil || !strings.Contains(err.Error(), "CHECK_TEST_MIN_COUNT") {
+		t.Errorf("applyEnv() should reject invalid values, returned %v", err)
+	}
+
+	flagSources = savedFlagSources
+}
+
+func TestVerbosityLevel(t *testing.T) {
+	var level verbosityLevel
+
+	cmd := &cobra.Command{Use: "check_test"}
+	cmd.Flags().VarP(&level, "verbose", "v", "")
+	cmd.Flags().Lookup("verbose").NoOptDefVal = verbosityIncrement
+
+	testList := []struct {
+		args     []string
+		expected verbosityLevel
+	}{
+		{[]string{}, 0},
+		{[]string{"-v"}, 1},
+		{[]string{"-vvv"}, 3},
+		{[]string{"-v", "--verbose"}, 2},
+		{[]string{"--verbose=true"}, 1},
+		{[]string{"--verbose=2"}, 2},
+	}
+
+	for _, i := range testList {
+		level = 0
+		cmd.Flags().Parse(i.args)
+
+		if level != i.expected {
+			t.Errorf("%v: Expected Verbosity: %d, Actual Verbosity: %d", i.args, i.expected, level)
+		}
+	}
+
+	// A config file may still set the flag as the bool it was.
+	if err := level.Set("false"); err != nil || level != 0 {
+		t.Errorf("Set(\"false\") should turn off the verbosity, Verbosity: %d, Error: %v", level, err)
+	}
+
+	for _, value := range []string{"many", "-1"} {
+		if err := level.Set(value); err == nil {
+			t.Errorf("Set(%q) should be rejected", value)
+		}
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	var out bytes.Buffer
+
+	code := printSelfTest(&out, []nagiosfoundation.Capability{
+		{Name: "read /proc"},
+		{Name: "open the service manager", Err: errors.New("permission denied")},
+	})
+
+	expected := "OK   read /proc\nFAIL open the service manager: permission denied\n"
+	if code != 1 || out.String() != expected {
+		t.Errorf("printSelfTest() Expected: %d %q, Actual: %d %q", 1, expected, code, out.String())
+	}
+
+	out.Reset()
+	if code := printSelfTest(&out, []nagiosfoundation.Capability{{Name: "read /proc"}}); code != 0 {
+		t.Errorf("printSelfTest() should exit 0 when every capability is available, returned %d", code)
+	}
+
+	testCmd := &cobra.Command{Use: "check_test"}
+	AddSelftestCommand(testCmd)
+	if found, _, err := testCmd.Find([]string{"preflight"}); err != nil || found.Name() != "selftest" {
+		t.Errorf("selftest command should be added with the preflight alias: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package initcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+
+	// defaultMaxOutputLength keeps the text output well within the
+	// 8KB Nagios reads of the output of a plugin.
+	defaultMaxOutputLength = 4096
+)
+
+// The output format selected with the --output flag.
+var outputFormat = outputFormatText
+
+// The name of the root command, such as check_process, naming the
+// check in the results made for it.
+var commandName string
+
+// Set with the --verbose flag to log diagnostic messages to stderr,
+// and given more than once to add detail to the result.
+var verbose verbosityLevel
+
+// Set with the --invert flag to swap the OK and CRITICAL results.
+var invert bool
+
+// Set with the --label flag to tell apart the results of the same
+// check on many hosts, such as the pod a check runs in.
+var label string
+
+// Set with the --quiet flag to write only the results that are not OK.
+var quiet bool
+
+// Set with the --max_output_length flag to cut the text output short,
+// keeping its perfdata, rather than leave Nagios to cut it.
+var maxOutputLength = defaultMaxOutputLength
+
+// Set with the --perfdata_only flag to write only the perfdata of the
+// results, for collectors scraping the metrics.
+var perfdataOnly bool
+
+// AddGlobalFlags adds the flags supported by every check command
+// to the root command.
+func AddGlobalFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputFormatText, "the output format: text or json")
+	cmd.PersistentFlags().StringVar(&configPath, "config", "", "a YAML or TOML file of flag values, overridden by the flags given")
+	cmd.PersistentFlags().VarP(&verbose, "verbose", "v", "log diagnostic messages to stderr, with -vv adding a line to the result for each item matched and -vvv the raw values read")
+	cmd.PersistentFlags().Lookup("verbose").NoOptDefVal = verbosityIncrement
+	cmd.PersistentFlags().BoolVar(&invert, "invert", false, "return CRITICAL when the check would return OK and OK when it would return CRITICAL")
+	cmd.PersistentFlags().StringVar(&label, "label", "", "a label such as the host or pod name to prefix the result with, as in [web-pod-3]")
+	cmd.PersistentFlags().BoolVar(&perfdataOnly, "perfdata_only", false, "write only the perfdata of the result, without the status and description, and nothing without perfdata")
+	cmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "write nothing when the result is OK, only WARNING, CRITICAL and UNKNOWN results")
+	cmd.PersistentFlags().IntVar(&maxOutputLength, "max_output_length", defaultMaxOutputLength, "the most bytes of text output written, the description cut short keeping the perfdata, or 0 for no limit")
+	addRetries(cmd)
+	addTimeout(cmd)
+	addExplain(cmd)
+	addResultSink(cmd)
+	addExitCodeMap(cmd)
+	addExtraOpts(cmd)
+	addValidateConfigCommand(cmd)
+
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		// Subcommands such as version have none of the check flags.
+		if !cmd.HasParent() {
+			commandName = cmd.Name()
+
+			if err := applyEnv(cmd); err != nil {
+				return err
+			}
+
+			if err := applyExtraOpts(cmd); err != nil {
+				return err
+			}
+
+			if err := applyConfig(cmd); err != nil {
+				return err
+			}
+		}
+
+		nagiosfoundation.SetVerbosity(int(verbose))
+
+		if err := validateRetries(retries, retryInterval); err != nil {
+			return err
+		}
+
+		if err := validateResultSink(resultSink); err != nil {
+			return err
+		}
+
+		if err := validateExitCodeMap(mapWarningTo, mapCriticalTo, mapUnknownTo); err != nil {
+			return err
+		}
+
+		if err := validateUnknownAs(unknownAs, mapUnknownTo); err != nil {
+			return err
+		}
+
+		if err := validateTimeoutExit(timeoutExit); err != nil {
+			return err
+		}
+
+		if maxOutputLength < 0 {
+			return fmt.Errorf("Invalid maximum output length (%d). The length must be 0 or more", maxOutputLength)
+		}
+
+		if perfdataOnly && strings.ToLower(outputFormat) != outputFormatText {
+			return fmt.Errorf("--perfdata_only is only supported with the text output format")
+		}
+
+		return validateOutputFormat(outputFormat)
+	}
+}
+
+func validateOutputFormat(format string) error {
+	switch strings.ToLower(format) {
+	case outputFormatText, outputFormatJSON:
+		return nil
+	}
+
+	return fmt.Errorf("Invalid output format %q. Valid formats are \"text\" and \"json\"", format)
+}
+
+// finalResult returns the result of a check with the --invert flag
+// applied.
+func finalResult(result nagiosfoundation.CheckResult) nagiosfoundation.CheckResult {
+	if invert {
+		return result.Invert()
+	}
+
+	return result
+}
+
+// FormatResult returns the result of a check in the output format
+// selected with the --output flag. The text format is the plain text
+// Nagios output prefixed with the --label, cut to the
+// --max_output_length. With --perfdata_only it is the perfdata alone.
+func FormatResult(result nagiosfoundation.CheckResult) string {
+	if perfdataOnly {
+		return perfdataText(result)
+	}
+
+	return formatCheckResult(result, outputFormat)
+}
+
+func formatCheckResult(result nagiosfoundation.CheckResult, format string) string {
+	if strings.ToLower(format) == outputFormatJSON {
+		if output, err := labelJSON(result); err == nil {
+			return output
+		}
+	}
+
+	return nagiosfoundation.TruncateResult(labelText(result.String()), maxOutputLength)
+}
+
+// perfdataText returns the perfdata of the result in the plain text
+// Nagios format, without the leading pipe, or empty when the result
+// has none. The --label is left out so the metrics parse as they are.
+func perfdataText(result nagiosfoundation.CheckResult) string {
+	return result.PerfData.String()
+}
+
+// labelText prefixes the text output of a check with the --label in
+// brackets, such as "[web-pod-3] CheckProcess OK - ...". Without a
+// label the output is unchanged.
+func labelText(output string) string {
+	if label == "" {
+		return output
+	}
+
+	return "[" + label + "] " + output
+}
+
+// labelJSON renders the result as JSON with the --label as the label
+// key. Without a label the output is that of CheckResult.JSON().
+func labelJSON(result nagiosfoundation.CheckResult) (string, error) {
+	if label == "" {
+		return result.JSON()
+	}
+
+	if result.PerfData == nil {
+		result.PerfData = nagiosfoundation.PerfDataSet{}
+	}
+
+	data, err := json.Marshal(struct {
+		Label string `json:"label"`
+		nagiosfoundation.CheckResult
+	}{label, result})
+
+	return string(data), err
+}
@@ -0,0 +1,72 @@
+package initcmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+)
+
+const defaultRetryInterval = time.Second
+
+// The number of times a check not returning OK is run again, selected
+// with the --retries flag, and the time waited before each retry,
+// selected with the --retry_interval flag.
+var retries int
+var retryInterval = defaultRetryInterval
+
+// addRetries adds the --retries and --retry_interval flags to the
+// root command.
+func addRetries(cmd *cobra.Command) {
+	cmd.PersistentFlags().IntVar(&retries, "retries", 0, "the number of times to run the check again when it does not return OK")
+	cmd.PersistentFlags().DurationVar(&retryInterval, "retry_interval", defaultRetryInterval, "the time to wait before each retry, such as 500ms or 2s")
+}
+
+func validateRetries(retries int, interval time.Duration) error {
+	if retries < 0 {
+		return fmt.Errorf("Invalid retries (%d). The retries must be 0 or more", retries)
+	}
+
+	if interval < 0 {
+		return fmt.Errorf("Invalid retry interval (%s). The interval must be 0 or more", interval)
+	}
+
+	return nil
+}
+
+// RunCheck runs the check and returns its result with the global
+// flags applied, such as --invert and --map_critical_to. While the
+// result is not OK the check is run again, up to --retries times, to
+// ride out a transient failure such as a dropped connection, and the
+// result of the last run is returned. A retry is not made when,
+// taking as long as the run before it, it would not complete before
+// the --timeout passes, so the last result is returned rather than
+// the timeout. A check that panics returns UNKNOWN, with the stack
+// written to stderr with --verbose.
+func RunCheck(check func() nagiosfoundation.CheckResult) nagiosfoundation.CheckResult {
+	recovered := func() nagiosfoundation.CheckResult {
+		return nagiosfoundation.RecoverCheckResult(checkName(commandName), check)
+	}
+
+	return mapExitCode(runCheck(recovered, time.Sleep, time.Now))
+}
+
+func runCheck(check func() nagiosfoundation.CheckResult, sleep func(time.Duration), now func() time.Time) nagiosfoundation.CheckResult {
+	start := now()
+	result := finalResult(check())
+
+	for attempt := 0; attempt < retries && result.Code != 0; attempt++ {
+		finished := now()
+		if !deadline.IsZero() && finished.Add(retryInterval+finished.Sub(start)).After(deadline) {
+			break
+		}
+
+		sleep(retryInterval)
+
+		start = now()
+		result = finalResult(check())
+	}
+
+	return result
+}
@@ -0,0 +1,45 @@
+package initcmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+)
+
+// AddSelftestCommand adds the selftest subcommand to the root command,
+// testing the capabilities of the host the checks rely on.
+func AddSelftestCommand(cmd *cobra.Command) {
+	cmd.AddCommand(&cobra.Command{
+		Use:     "selftest",
+		Aliases: []string{"preflight"},
+		Short:   "Test the capabilities of the host the checks rely on",
+		Long: `Test the capabilities of the host the checks rely on, such as reading /proc,
+resolving the current user and opening the service manager, without running a
+check. Each capability is reported as OK or FAIL, and the command exits 0 only
+when every capability is available, to find a permission or packaging problem
+in a deployment before its checks return UNKNOWN.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			os.Exit(printSelfTest(os.Stdout, nagiosfoundation.SelfTest()))
+		},
+	})
+}
+
+// printSelfTest writes a line for each capability, OK or FAIL with the
+// error, and returns the exit code, 1 when a capability failed.
+func printSelfTest(w io.Writer, capabilities []nagiosfoundation.Capability) int {
+	exitCode := 0
+
+	for _, capability := range capabilities {
+		if capability.Err != nil {
+			fmt.Fprintf(w, "FAIL %s: %s\n", capability.Name, capability.Err)
+			exitCode = 1
+		} else {
+			fmt.Fprintf(w, "OK   %s\n", capability.Name)
+		}
+	}
+
+	return exitCode
+}
@@ -0,0 +1,112 @@
+package initcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+)
+
+const (
+	resultSinkStdout = "stdout"
+	resultSinkSyslog = "syslog"
+	resultSinkFile   = "file:"
+)
+
+// The syslog severities the results are logged with, the levels of
+// RFC 5424.
+type syslogSeverity int
+
+const (
+	syslogSeverityErr     syslogSeverity = 3
+	syslogSeverityWarning syslogSeverity = 4
+	syslogSeverityInfo    syslogSeverity = 6
+)
+
+// The destination of the result selected with the --result_sink flag.
+var resultSink = resultSinkStdout
+
+func addResultSink(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&resultSink, "result_sink", resultSinkStdout, "where the result is written: stdout, syslog or file:<path> to append to a file")
+}
+
+func validateResultSink(sink string) error {
+	switch {
+	case sink == resultSinkStdout, sink == resultSinkSyslog:
+		return nil
+	case strings.HasPrefix(sink, resultSinkFile) && len(sink) > len(resultSinkFile):
+		return nil
+	}
+
+	return fmt.Errorf("Invalid result sink %q. Valid sinks are \"stdout\", \"syslog\" and \"file:<path>\"", sink)
+}
+
+// resultSeverity returns the syslog severity of a return code. An
+// UNKNOWN result is an error, as the check could not be made.
+func resultSeverity(retcode int) syslogSeverity {
+	switch retcode {
+	case 0:
+		return syslogSeverityInfo
+	case 1:
+		return syslogSeverityWarning
+	}
+
+	return syslogSeverityErr
+}
+
+// appendResult appends the output as a line to the file, creating it
+// if needed, such as the Nagios command file read for passive checks.
+func appendResult(path, output string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintln(file, output); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// writeResult writes the output of a check with the return code to
+// the --result_sink.
+func writeResult(output string, retcode int) error {
+	switch {
+	case resultSink == resultSinkSyslog:
+		return writeSyslogOsConstrained(resultSeverity(retcode), output)
+	case strings.HasPrefix(resultSink, resultSinkFile):
+		return appendResult(strings.TrimPrefix(resultSink, resultSinkFile), output)
+	}
+
+	_, err := fmt.Println(output)
+
+	return err
+}
+
+// PrintResult writes the result of a check in the output format
+// selected with the --output flag to the --result_sink, standard
+// output unless another is selected. A result that cannot be written
+// to the sink is written to standard output instead, with the error on
+// standard error, so that it is not lost. With --quiet an OK result, a
+// return code of 0, is not written, and with --perfdata_only a result
+// without perfdata is not written. The return code is left for the
+// command to exit with, as for an active check.
+func PrintResult(result nagiosfoundation.CheckResult) {
+	if quiet && result.Code == 0 {
+		return
+	}
+
+	output := FormatResult(result)
+	if perfdataOnly && output == "" {
+		return
+	}
+
+	if err := writeResult(output, result.Code); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write the result to %s: %s\n", resultSink, err)
+		fmt.Println(output)
+	}
+}
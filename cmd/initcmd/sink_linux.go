@@ -0,0 +1,21 @@
+// +build !windows
+
+package initcmd
+
+import (
+	"log/syslog"
+)
+
+// writeSyslogOsConstrained logs the output to the local syslog with
+// the severity, tagged with the command name.
+func writeSyslogOsConstrained(severity syslogSeverity, output string) error {
+	writer, err := syslog.New(syslog.Priority(severity)|syslog.LOG_USER, "")
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	_, err = writer.Write([]byte(output))
+
+	return err
+}
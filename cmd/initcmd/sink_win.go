@@ -0,0 +1,11 @@
+// +build windows
+
+package initcmd
+
+import (
+	"errors"
+)
+
+func writeSyslogOsConstrained(severity syslogSeverity, output string) error {
+	return errors.New("syslog is not supported on Windows")
+}
@@ -0,0 +1,129 @@
+package initcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultTimeoutSeconds = 10
+
+	// timeoutGrace is the time past the deadline given to a check
+	// that enforces the timeout itself, such as check_http, so that
+	// it reports its own result rather than the generic timeout.
+	timeoutGrace = 500 * time.Millisecond
+)
+
+// The number of seconds selected with the --timeout flag.
+var timeoutSeconds = defaultTimeoutSeconds
+
+// The state selected with the --timeout_exit flag to issue when the
+// check does not complete in time.
+var timeoutExit string
+
+// The time the --timeout of the running check passes, the zero time
+// until the check starts.
+var deadline time.Time
+
+// TimeoutSeconds returns the value of the --timeout flag. A command
+// also using the timeout for its own work can bind its flag to it.
+func TimeoutSeconds() *int {
+	return &timeoutSeconds
+}
+
+// TimeoutExit returns the value of the --timeout_exit flag, for a
+// check enforcing the timeout itself to issue the same state when it
+// does not complete in time.
+func TimeoutExit() string {
+	return timeoutExit
+}
+
+// Deadline returns the time the --timeout of the running check
+// passes, for a check waiting within its timeout such as for a grace
+// period. It is the zero time until the check starts.
+func Deadline() time.Time {
+	return deadline
+}
+
+// addTimeout adds the --timeout and --timeout_exit flags to the root
+// command and wraps its Run so the check is abandoned with a result in
+// the state of --timeout_exit, UNKNOWN by default, when it does not
+// complete in time.
+func addTimeout(cmd *cobra.Command) {
+	cmd.PersistentFlags().IntVar(&timeoutSeconds, "timeout", defaultTimeoutSeconds, "the number of seconds to wait for the check to complete")
+	cmd.PersistentFlags().StringVar(&timeoutExit, "timeout_exit", "", "the state to issue when the check does not complete in time: unknown, critical or warning")
+
+	run := cmd.Run
+	if run == nil {
+		return
+	}
+
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		timeout := time.Duration(timeoutSeconds) * time.Second
+		deadline = time.Now().Add(timeout)
+
+		if !runWithTimeout(timeout+timeoutGrace, func() { run(cmd, args) }) {
+			code, _, _ := nagiosfoundation.TimeoutStatus(timeoutExit)
+			result := mapExitCode(timeoutResult(cmd.Name(), nagiosfoundation.State(code), timeoutSeconds))
+
+			PrintResult(result)
+			os.Exit(result.Code)
+		}
+	}
+}
+
+// runWithTimeout runs the work in a goroutine and returns false if
+// it does not complete within the timeout.
+func runWithTimeout(timeout time.Duration, work func()) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		work()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// validateTimeoutExit returns an error for a --timeout_exit that is
+// not one of the states TimeoutStatus() accepts.
+func validateTimeoutExit(timeoutExit string) error {
+	if _, _, err := nagiosfoundation.TimeoutStatus(timeoutExit); err != nil {
+		return fmt.Errorf("Invalid --timeout_exit %q. Valid states are \"unknown\", \"critical\" and \"warning\"", timeoutExit)
+	}
+
+	return nil
+}
+
+// timeoutResult returns the result in the state of a check that timed
+// out, with the check named after the command.
+func timeoutResult(commandName string, state nagiosfoundation.State, seconds int) nagiosfoundation.CheckResult {
+	return nagiosfoundation.NewCheckResult(checkName(commandName), state, fmt.Sprintf("timed out after %ds", seconds))
+}
+
+// checkName returns the name of the check run by the command, such as
+// CheckProcess for check_process.
+func checkName(commandName string) string {
+	var name strings.Builder
+
+	for _, word := range strings.Split(commandName, "_") {
+		if word != "" {
+			name.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+
+	return name.String()
+}
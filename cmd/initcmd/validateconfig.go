@@ -0,0 +1,136 @@
+package initcmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/app/nagiosfoundation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// FlagRangeAnnotation is the annotation of a flag taking a threshold
+// in the Nagios range format, such as "10:20", set with
+// SetRangeFlags().
+const FlagRangeAnnotation = "nagiosfoundation_range"
+
+// SetRangeFlags annotates the flags as taking a threshold in the
+// Nagios range format, so that the validate-config command parses
+// their values. The thresholds of other formats, such as the load
+// triplets of check_load, are left unannotated.
+func SetRangeFlags(flags *pflag.FlagSet, names ...string) {
+	for _, name := range names {
+		flags.SetAnnotation(name, FlagRangeAnnotation, []string{"true"})
+	}
+}
+
+// SetRelativeRangeFlags annotates the flags as taking a threshold in
+// the Nagios range format whose bounds may be percentages of a total
+// or sizes, such as "10%:" or "2G", so that the validate-config
+// command parses their values with
+// nagiosfoundation.ParseRelativeRange().
+func SetRelativeRangeFlags(flags *pflag.FlagSet, names ...string) {
+	for _, name := range names {
+		flags.SetAnnotation(name, FlagRangeAnnotation, []string{"relative"})
+	}
+}
+
+// addValidateConfigCommand adds the validate-config subcommand to the
+// root command, validating a config file for the flags of the root
+// command without running the check.
+func addValidateConfigCommand(cmd *cobra.Command) {
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate-config [file]",
+		Short: "Validate a config file without running the check",
+		Long: `Validate a config file of flag values, given as the argument or with --config,
+without running the check. Each key must be a flag of the check, each value
+must be valid for its flag and each threshold in the Nagios range format must
+parse. Each problem is reported on a line of its own and the command exits 0
+only when there are none, to catch a typo in a config file before it is rolled
+out and shows up as a confusing UNKNOWN.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			path := configPath
+			if len(args) > 0 {
+				path = args[0]
+			}
+
+			os.Exit(printConfigProblems(os.Stdout, path, validateConfig(cmd, path)))
+		},
+	})
+}
+
+// validateConfig returns the problems of the config file for the flags
+// of the root command, sorted by key: the keys that are not flags, the
+// values the flags reject and the thresholds that are not Nagios
+// ranges. A file that cannot be read or parsed is a single problem.
+func validateConfig(cmd *cobra.Command, path string) []string {
+	if path == "" {
+		return []string{"A config file must be given, as the argument or with --config"}
+	}
+
+	values, err := readConfig(path)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var problems []string
+
+	for _, key := range keys {
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil {
+			flag = cmd.PersistentFlags().Lookup(key)
+		}
+
+		if flag == nil {
+			problems = append(problems, fmt.Sprintf("Unknown key %q", key))
+			continue
+		}
+
+		value := values[key]
+		if err := flag.Value.Set(value); err != nil {
+			problems = append(problems, fmt.Sprintf("Invalid value for key %q: %s", key, err))
+			continue
+		}
+
+		if annotation, ok := flag.Annotations[FlagRangeAnnotation]; ok && value != "" {
+			var err error
+			if len(annotation) > 0 && annotation[0] == "relative" {
+				_, err = nagiosfoundation.ParseRelativeRange(value)
+			} else {
+				_, err = nagiosfoundation.ParseRange(value)
+			}
+
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("Invalid threshold for key %q: %s", key, err))
+			}
+		}
+	}
+
+	return problems
+}
+
+// printConfigProblems writes a line for each problem of the config
+// file, or an OK line when there are none, and returns the exit code,
+// 1 when there are problems.
+func printConfigProblems(w io.Writer, path string, problems []string) int {
+	if len(problems) == 0 {
+		fmt.Fprintf(w, "OK   config file %s\n", path)
+		return 0
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintf(w, "FAIL %s\n", problem)
+	}
+
+	return 1
+}
@@ -0,0 +1,47 @@
+package initcmd
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// verbosityLevel is the value of the --verbose flag, the number of
+// times it is given, so -vv and -vvv add detail as with the classic
+// plugins. As the flag was a bool, "true" and "false" are also taken,
+// such as "verbose: true" in a config file, as levels 1 and 0.
+type verbosityLevel int
+
+// verbosityIncrement is the value of a --verbose given without one,
+// adding a level.
+const verbosityIncrement = "+1"
+
+func (v *verbosityLevel) String() string {
+	return strconv.Itoa(int(*v))
+}
+
+func (v *verbosityLevel) Set(value string) error {
+	switch value {
+	case verbosityIncrement:
+		*v++
+		return nil
+	case "true":
+		*v = 1
+		return nil
+	case "false":
+		*v = 0
+		return nil
+	}
+
+	level, err := strconv.Atoi(value)
+	if err != nil || level < 0 {
+		return fmt.Errorf("Invalid verbosity %q. The verbosity is a level of 0 or more, true or false", value)
+	}
+
+	*v = verbosityLevel(level)
+
+	return nil
+}
+
+func (v *verbosityLevel) Type() string {
+	return "count"
+}
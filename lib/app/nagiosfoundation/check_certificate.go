@@ -0,0 +1,258 @@
+package nagiosfoundation
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const checkCertificateName = "CheckCertificate"
+
+// CertificateCheckOptions contains the options for a certificate check.
+type CertificateCheckOptions struct {
+	// The host and port to read the certificate chain from with a TLS
+	// handshake. The host is also the server name sent in the
+	// handshake.
+	Host string
+	Port int
+
+	// A PEM file to read the certificate chain from instead of a host,
+	// with the leaf certificate first.
+	File string
+
+	// The warning and critical thresholds, Nagios ranges of the
+	// number of days the certificates may have left before they
+	// expire, such as "30:". A plain number such as "30" is the least
+	// number of days, as the thresholds were before ranges were
+	// accepted.
+	Warning  string
+	Critical string
+
+	// Checks the leaf certificate is valid for Host.
+	VerifyHostname bool
+
+	// The seconds to wait for the connection and handshake.
+	Timeout int
+
+	// The state to issue when the connection and handshake do not
+	// complete in time: "unknown", "critical" or "warning". Defaults to
+	// "unknown".
+	TimeoutExit string
+}
+
+// dialCertificates returns the certificate chain sent by the server
+// in a TLS handshake. The chain is not verified, as an expired or
+// untrusted certificate is what the check reports on.
+func dialCertificates(host string, port, timeout int) ([]*x509.Certificate, error) {
+	dialer := &net.Dialer{Timeout: time.Duration(timeout) * time.Second}
+	dialer.Deadline = time.Now().Add(dialer.Timeout)
+
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(port)),
+		&tls.Config{ServerName: host, InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates, nil
+}
+
+// parsePEMCertificates returns the certificates in the PEM data, in
+// the order they are given. Blocks other than certificates, such as a
+// private key, are skipped.
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("No certificates found")
+	}
+
+	return certificates, nil
+}
+
+// readCertificates returns the certificate chain of the options, read
+// from the file or from the host.
+func readCertificates(options CertificateCheckOptions) ([]*x509.Certificate, error) {
+	if options.File != "" {
+		data, err := ioutil.ReadFile(options.File)
+		if err != nil {
+			return nil, err
+		}
+
+		return parsePEMCertificates(data)
+	}
+
+	return dialCertificates(options.Host, options.Port, options.Timeout)
+}
+
+// describeCertificate names a certificate by the common name of its
+// subject, or by the whole subject when it has no common name.
+func describeCertificate(certificate *x509.Certificate) string {
+	if certificate.Subject.CommonName != "" {
+		return certificate.Subject.CommonName
+	}
+
+	return certificate.Subject.String()
+}
+
+// certificateThreshold returns the threshold as the range of at least
+// that many days when it is a plain number, so that "30" still alerts
+// with fewer than 30 days left rather than with more.
+func certificateThreshold(threshold string) string {
+	text := strings.TrimSpace(threshold)
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return text + ":"
+	}
+
+	return threshold
+}
+
+// runCertificateCheck performs the check of
+// CheckCertificateWithHandler() and returns its result.
+func runCertificateCheck(options CertificateCheckOptions,
+	read func(CertificateCheckOptions) ([]*x509.Certificate, error), now func() time.Time) CheckResult {
+	source := options.File
+
+	switch {
+	case options.File != "" && options.Host != "":
+		return UnknownResult(checkCertificateName, "Only one of a host or a file may be specified.")
+	case options.File == "" && options.Host == "":
+		return UnknownResult(checkCertificateName, "A host or a file must be specified.")
+	case options.File == "":
+		if options.Port < 1 || options.Port > 65535 {
+			return UnknownResult(checkCertificateName, fmt.Sprintf("Invalid port (%d). The port must be from 1 to 65535.", options.Port))
+		}
+
+		if options.Timeout < 1 {
+			return UnknownResult(checkCertificateName, fmt.Sprintf("Invalid timeout (%d). The timeout must be at least 1 second.", options.Timeout))
+		}
+
+		source = net.JoinHostPort(options.Host, strconv.Itoa(options.Port))
+	}
+
+	if options.VerifyHostname && options.Host == "" {
+		return UnknownResult(checkCertificateName, "The hostname can only be verified for a host.")
+	}
+
+	if err := checkTimeoutExit(options.TimeoutExit); err != nil {
+		return UnknownResult(checkCertificateName, err.Error())
+	}
+
+	thresholds, err := ParseThresholds(certificateThreshold(options.Warning), certificateThreshold(options.Critical))
+	if err != nil {
+		return UnknownResult(checkCertificateName, err.Error())
+	}
+
+	certificates, err := read(options)
+	if err != nil {
+		if IsErrorKind(classifyError(err), ErrTimeout) {
+			return timeoutResult(checkCertificateName, options.TimeoutExit,
+				fmt.Sprintf("Could not read the certificates of %s: %s", source, err))
+		}
+
+		return UnknownResult(checkCertificateName, fmt.Sprintf("Could not read the certificates of %s: %s", source, err))
+	}
+
+	if len(certificates) == 0 {
+		return UnknownResult(checkCertificateName, fmt.Sprintf("No certificates from %s", source))
+	}
+
+	leaf := certificates[0]
+	checkTime := now()
+
+	if options.VerifyHostname {
+		if err := leaf.VerifyHostname(options.Host); err != nil {
+			return CriticalResult(checkCertificateName, fmt.Sprintf("Certificate %s of %s: %s", describeCertificate(leaf), source, err))
+		}
+	}
+
+	soonest := leaf
+	for _, certificate := range certificates {
+		switch {
+		case checkTime.After(certificate.NotAfter):
+			return CriticalResult(checkCertificateName, fmt.Sprintf("Certificate %s of %s expired on %s",
+				describeCertificate(certificate), source, certificate.NotAfter.UTC().Format(time.RFC3339)))
+		case checkTime.Before(certificate.NotBefore):
+			return CriticalResult(checkCertificateName, fmt.Sprintf("Certificate %s of %s is not valid until %s",
+				describeCertificate(certificate), source, certificate.NotBefore.UTC().Format(time.RFC3339)))
+		}
+
+		if certificate.NotAfter.Before(soonest.NotAfter) {
+			soonest = certificate
+		}
+	}
+
+	days := int(soonest.NotAfter.Sub(checkTime) / (24 * time.Hour))
+
+	checkInfo := fmt.Sprintf("Certificate %s of %s expires in %d days on %s",
+		describeCertificate(soonest), source, days, soonest.NotAfter.UTC().Format(time.RFC3339))
+	if soonest != leaf {
+		checkInfo += fmt.Sprintf(", the soonest in the chain of %s", describeCertificate(leaf))
+	}
+
+	state, tripped := thresholds.Status(float64(days))
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected %s days)", tripped.Expected())
+	}
+
+	return NewCheckResult(checkCertificateName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: "days",
+		Value: float64(days),
+	}))
+}
+
+// CheckCertificateWithHandler reads the certificate chain with the
+// handler and emits a critical response if any certificate in the
+// chain has expired or is not yet valid at the time returned by now,
+// or with options.VerifyHostname if the leaf certificate is not valid
+// for options.Host. Otherwise the days left until the soonest expiry
+// in the chain are compared against the warning and critical
+// thresholds, emitting a critical response with the days left outside
+// the critical range, such as fewer than 14 days with "14:", a
+// warning response with the days left outside the warning range and
+// a good response otherwise. A connection or
+// handshake not completing in options.Timeout emits a response in the
+// state of options.TimeoutExit. The days left are output as perfdata.
+func CheckCertificateWithHandler(options CertificateCheckOptions,
+	read func(CertificateCheckOptions) ([]*x509.Certificate, error), now func() time.Time) (string, int) {
+	return runCertificateCheck(options, read, now).Output()
+}
+
+// RunCertificateCheck performs the certificate check of
+// CheckCertificate() and returns its result, without output.
+func RunCertificateCheck(options CertificateCheckOptions) CheckResult {
+	return runCertificateCheck(options, readCertificates, time.Now)
+}
+
+// CheckCertificate executes CheckCertificateWithHandler(), passing it
+// a handler reading the certificates from the file or the host and
+// time.Now().
+//
+// Returns are those of CheckCertificateWithHandler()
+func CheckCertificate(options CertificateCheckOptions) (string, int) {
+	return RunCertificateCheck(options).Output()
+}
@@ -0,0 +1,158 @@
+package nagiosfoundation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCertificate creates a self-signed certificate for the name,
+// valid from notBefore to notAfter.
+func testCertificate(t *testing.T, name string, notBefore, notAfter time.Time) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certificate
+}
+
+func TestCheckCertificate(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	leaf := testCertificate(t, "www.example.com", now.Add(-30*day), now.Add(90*day))
+	intermediate := testCertificate(t, "Example CA", now.Add(-30*day), now.Add(20*day))
+	expired := testCertificate(t, "www.example.com", now.Add(-30*day), now.Add(-day))
+	future := testCertificate(t, "www.example.com", now.Add(day), now.Add(90*day))
+
+	chain := func(certificates ...*x509.Certificate) func(CertificateCheckOptions) ([]*x509.Certificate, error) {
+		return func(CertificateCheckOptions) ([]*x509.Certificate, error) {
+			return certificates, nil
+		}
+	}
+
+	readError := func(CertificateCheckOptions) ([]*x509.Certificate, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	readTimeout := func(CertificateCheckOptions) ([]*x509.Certificate, error) {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}
+	}
+
+	host := CertificateCheckOptions{Host: "www.example.com", Port: 443, Warning: "30:", Critical: "14:", Timeout: 10}
+
+	type testItem struct {
+		description  string
+		options      CertificateCheckOptions
+		read         func(CertificateCheckOptions) ([]*x509.Certificate, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Valid", host, chain(leaf), statusCodeOK,
+			"CheckCertificate OK - Certificate www.example.com of www.example.com:443 expires in 90 days on 2021-05-30T12:00:00Z | days=90;30:;14:"},
+		{"Chain expiring", host, chain(leaf, intermediate), statusCodeWarning,
+			"Certificate Example CA of www.example.com:443 expires in 20 days on 2021-03-21T12:00:00Z, the soonest in the chain of www.example.com (expected at least 30 days) | days=20;30:;14:"},
+		{"Critical", CertificateCheckOptions{Host: "www.example.com", Port: 443, Warning: "120", Critical: "100", Timeout: 10}, chain(leaf), statusCodeCritical,
+			"(expected at least 100 days) | days=90;120:;100:"},
+		{"Too long", CertificateCheckOptions{Host: "www.example.com", Port: 443, Warning: "30:60", Critical: "14:", Timeout: 10}, chain(leaf), statusCodeWarning,
+			"(expected 30-60 days) | days=90;30:60;14:"},
+		{"Invalid threshold", CertificateCheckOptions{Host: "www.example.com", Port: 443, Warning: "soon", Timeout: 10}, chain(leaf), statusCodeUnknown, "Invalid range \"soon\""},
+		{"Expired", host, chain(expired), statusCodeCritical, "Certificate www.example.com of www.example.com:443 expired on 2021-02-28T12:00:00Z"},
+		{"Expired in chain", host, chain(leaf, expired), statusCodeCritical, "expired on"},
+		{"Not yet valid", host, chain(future), statusCodeCritical, "is not valid until 2021-03-02T12:00:00Z"},
+		{"Hostname matches", CertificateCheckOptions{Host: "www.example.com", Port: 443, Warning: "30:", Critical: "14:", Timeout: 10, VerifyHostname: true}, chain(leaf), statusCodeOK, "expires in 90 days"},
+		{"Hostname mismatch", CertificateCheckOptions{Host: "mail.example.com", Port: 443, Warning: "30:", Critical: "14:", Timeout: 10, VerifyHostname: true}, chain(leaf), statusCodeCritical,
+			"Certificate www.example.com of mail.example.com:443: x509: certificate is valid for www.example.com, not mail.example.com"},
+		{"File", CertificateCheckOptions{File: "/etc/ssl/site.pem", Warning: "30:", Critical: "14:"}, chain(leaf), statusCodeOK, "of /etc/ssl/site.pem expires in 90 days"},
+		{"Read error", host, readError, statusCodeUnknown, "Could not read the certificates of www.example.com:443: connection refused"},
+		{"Read timeout", host, readTimeout, statusCodeUnknown, "CheckCertificate UNKNOWN - Could not read the certificates of www.example.com:443"},
+		{"Read timeout exit", CertificateCheckOptions{Host: "www.example.com", Port: 443, Timeout: 10, TimeoutExit: "critical"}, readTimeout, statusCodeCritical,
+			"CheckCertificate CRITICAL - Could not read the certificates of www.example.com:443"},
+		{"Invalid timeout exit", CertificateCheckOptions{Host: "www.example.com", Port: 443, Timeout: 10, TimeoutExit: "ok"}, chain(leaf), statusCodeUnknown, "The timeout exit (--timeout_exit)"},
+		{"No certificates", host, chain(), statusCodeUnknown, "No certificates from www.example.com:443"},
+		{"No host", CertificateCheckOptions{Port: 443, Timeout: 10}, chain(leaf), statusCodeUnknown, "A host or a file must be specified."},
+		{"Host and file", CertificateCheckOptions{Host: "www.example.com", File: "/etc/ssl/site.pem", Port: 443, Timeout: 10}, chain(leaf), statusCodeUnknown, "Only one of"},
+		{"Invalid port", CertificateCheckOptions{Host: "www.example.com", Timeout: 10}, chain(leaf), statusCodeUnknown, "Invalid port (0)"},
+		{"Verify a file", CertificateCheckOptions{File: "/etc/ssl/site.pem", VerifyHostname: true}, chain(leaf), statusCodeUnknown, "The hostname can only be verified for a host."},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckCertificateWithHandler(i.options, i.read, func() time.Time { return now })
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+func TestReadCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	certificates, err := readCertificates(CertificateCheckOptions{Host: host, Port: portNumber, Timeout: 5})
+	if err != nil || len(certificates) == 0 || !certificates[0].Equal(server.Certificate()) {
+		t.Errorf("readCertificates() should return the certificate of the server, returned %d certificates, Error: %v", len(certificates), err)
+	}
+
+	file, err := ioutil.TempFile("", "certificate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	pem.Encode(file, &pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")})
+	pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	file.Close()
+
+	certificates, err = readCertificates(CertificateCheckOptions{File: file.Name()})
+	if err != nil || len(certificates) != 2 {
+		t.Errorf("readCertificates() should return the certificates of the file, returned %d certificates, Error: %v", len(certificates), err)
+	}
+
+	if _, err = parsePEMCertificates([]byte("not a certificate")); err == nil {
+		t.Error("parsePEMCertificates() should return an error without certificates")
+	}
+}
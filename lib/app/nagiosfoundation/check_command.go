@@ -0,0 +1,182 @@
+package nagiosfoundation
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const checkCommandName = "CheckCommand"
+
+// CommandCheckOptions contains the options for a command check.
+type CommandCheckOptions struct {
+	// The command to run and its arguments. The command is run
+	// directly rather than by a shell.
+	Command string
+	Args    []string
+
+	// A regular expression finding the value in the standard output
+	// of the command, such as "depth: (\d+)". The first group of the
+	// expression is the value, or the whole match without a group.
+	// Empty takes the whole output as the value.
+	Extract string
+
+	// The warning and critical thresholds, Nagios ranges of the value.
+	// An empty threshold is not checked.
+	Warning  string
+	Critical string
+
+	// The name of the metric in the nagios output. Defaults to
+	// "value".
+	MetricName string
+
+	// The time the command is killed when it has not exited, the zero
+	// time to wait for the command however long it takes.
+	Deadline time.Time
+
+	// The state to issue when the command does not complete in time:
+	// "unknown", "critical" or "warning". Defaults to "unknown".
+	TimeoutExit string
+}
+
+// runCommand runs the command with the arguments and returns its
+// standard output, killing the command when the context is done.
+// When the command exits with a non-zero code, the error includes
+// what it wrote to standard error.
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		err = fmt.Errorf("%s: %s", err, strings.Replace(strings.TrimSpace(string(exitErr.Stderr)), "\n", " ", -1))
+	}
+
+	return out, err
+}
+
+// extractCommandValue returns the value found in the output of a
+// command by the expression, its first group or the whole match, or
+// the whole output without an expression. The output is trimmed of
+// its trailing newline first, so that $ matches the end of the last
+// line.
+func extractCommandValue(out string, extract *regexp.Regexp) (float64, error) {
+	text := strings.TrimSpace(out)
+
+	if extract != nil {
+		match := extract.FindStringSubmatch(text)
+		if match == nil {
+			return 0, fmt.Errorf("No value matching %s in the output", extract)
+		}
+
+		text = match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil {
+		return 0, fmt.Errorf("The output %q is not a number", text)
+	}
+
+	return value, nil
+}
+
+// runCommandCheck performs the check of CheckCommandWithHandler() and
+// returns its result.
+func runCommandCheck(options CommandCheckOptions,
+	run func(context.Context, string, ...string) ([]byte, error)) CheckResult {
+	if options.Command == "" {
+		return UnknownResult(checkCommandName, "A command must be specified.")
+	}
+
+	var extract *regexp.Regexp
+	if options.Extract != "" {
+		var err error
+		if extract, err = regexp.Compile(options.Extract); err != nil {
+			return UnknownResult(checkCommandName, fmt.Sprintf("Invalid extract expression %q: %s", options.Extract, err))
+		}
+	}
+
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkCommandName, err.Error())
+	}
+
+	if err := checkTimeoutExit(options.TimeoutExit); err != nil {
+		return UnknownResult(checkCommandName, err.Error())
+	}
+
+	metricName := options.MetricName
+	if metricName == "" {
+		metricName = "value"
+	}
+
+	ctx := context.Background()
+	if !options.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, options.Deadline)
+		defer cancel()
+	}
+
+	debugLog.Printf("Running %s %s", options.Command, strings.Join(options.Args, " "))
+	out, err := run(ctx, options.Command, options.Args...)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = classifyError(ctx.Err())
+	}
+
+	switch {
+	case IsErrorKind(err, ErrTimeout):
+		return timeoutResult(checkCommandName, options.TimeoutExit, fmt.Sprintf("Command %s did not complete in time and was killed", options.Command))
+	case err != nil:
+		return UnknownResult(checkCommandName, fmt.Sprintf("Command %s failed: %s", options.Command, err))
+	}
+
+	value, err := extractCommandValue(string(out), extract)
+	if err != nil {
+		return UnknownResult(checkCommandName, fmt.Sprintf("Could not read a value from command %s: %s", options.Command, err))
+	}
+
+	checkInfo := fmt.Sprintf("Command %s returned %s", options.Command, strconv.FormatFloat(value, 'f', -1, 64))
+
+	state, tripped := thresholds.Status(value)
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+	}
+
+	return NewCheckResult(checkCommandName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: metricName,
+		Value: value,
+	}))
+}
+
+// CheckCommandWithHandler runs options.Command with options.Args
+// using run, reads a number from its standard output, found by the
+// options.Extract expression or the whole output, and compares it
+// against the options.Warning and options.Critical thresholds, to turn
+// the output of a tool such as a vendor CLI printing a queue depth
+// into a check. A command that cannot be run, exits with a non-zero
+// code or does not output a number emits an unknown response, as does
+// a command still running when options.Deadline passes, which is
+// killed, unless options.TimeoutExit selects another state. The value
+// is output as perfdata.
+func CheckCommandWithHandler(options CommandCheckOptions,
+	run func(context.Context, string, ...string) ([]byte, error)) (string, int) {
+	return runCommandCheck(options, run).Output()
+}
+
+// RunCommandCheck performs the command check of CheckCommand() and
+// returns its result, without output.
+func RunCommandCheck(options CommandCheckOptions) CheckResult {
+	return runCommandCheck(options, runCommand)
+}
+
+// CheckCommand executes CheckCommandWithHandler(), passing it a
+// function running the command with exec.CommandContext().
+//
+// Returns are those of CheckCommandWithHandler()
+func CheckCommand(options CommandCheckOptions) (string, int) {
+	return RunCommandCheck(options).Output()
+}
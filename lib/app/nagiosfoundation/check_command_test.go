@@ -0,0 +1,97 @@
+package nagiosfoundation
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckCommand(t *testing.T) {
+	output := func(out string, err error) func(context.Context, string, ...string) ([]byte, error) {
+		return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte(out), err
+		}
+	}
+
+	type testItem struct {
+		description  string
+		options      CommandCheckOptions
+		run          func(context.Context, string, ...string) ([]byte, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Whole output", CommandCheckOptions{Command: "queuectl", Warning: "10"}, output("7\n", nil), statusCodeOK,
+			"CheckCommand OK - Command queuectl returned 7 | value=7;10"},
+		{"Extract group", CommandCheckOptions{Command: "queuectl", Extract: `depth: (\d+)`, Warning: "10", Critical: "20", MetricName: "depth"},
+			output("queue: orders\ndepth: 15\n", nil), statusCodeWarning,
+			"CheckCommand WARNING - Command queuectl returned 15 (expected 0-10) | depth=15;10;20"},
+		{"Extract match", CommandCheckOptions{Command: "queuectl", Extract: `[0-9.]+`, Critical: "2"}, output("load 2.5 of 4", nil), statusCodeCritical,
+			"CheckCommand CRITICAL - Command queuectl returned 2.5 (expected 0-2) | value=2.5;;2"},
+		{"Extract at end", CommandCheckOptions{Command: "queuectl", Extract: `\d+$`}, output("orders 3 of 12\n", nil), statusCodeOK,
+			"CheckCommand OK - Command queuectl returned 12 | value=12"},
+		{"No match", CommandCheckOptions{Command: "queuectl", Extract: `depth: (\d+)`}, output("queue: orders", nil), statusCodeUnknown,
+			"Could not read a value from command queuectl: No value matching depth: (\\d+) in the output"},
+		{"Not a number", CommandCheckOptions{Command: "queuectl"}, output("empty", nil), statusCodeUnknown,
+			"The output \"empty\" is not a number"},
+		{"Command fails", CommandCheckOptions{Command: "queuectl"}, output("", fmt.Errorf("exit status 1: no such queue")), statusCodeUnknown,
+			"CheckCommand UNKNOWN - Command queuectl failed: exit status 1: no such queue"},
+		{"No command", CommandCheckOptions{}, output("", nil), statusCodeUnknown, "A command must be specified"},
+		{"Bad expression", CommandCheckOptions{Command: "queuectl", Extract: "("}, output("", nil), statusCodeUnknown, "Invalid extract expression"},
+		{"Invalid threshold", CommandCheckOptions{Command: "queuectl", Warning: "many"}, output("", nil), statusCodeUnknown, "Invalid range"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckCommandWithHandler(i.options, i.run)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	hung := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	options := CommandCheckOptions{Command: "queuectl", Deadline: time.Now().Add(10 * time.Millisecond)}
+	if msg, code := CheckCommandWithHandler(options, hung); code != statusCodeUnknown || !strings.Contains(msg, "did not complete in time") {
+		t.Errorf("CheckCommandWithHandler() should report a command running past the deadline as UNKNOWN: %d %s", code, msg)
+	}
+
+	options = CommandCheckOptions{Command: "queuectl", Deadline: time.Now().Add(10 * time.Millisecond), TimeoutExit: "critical"}
+	if msg, code := CheckCommandWithHandler(options, hung); code != statusCodeCritical || !strings.Contains(msg, "CheckCommand CRITICAL - Command queuectl did not complete in time") {
+		t.Errorf("CheckCommandWithHandler() should report a command running past the deadline in the timeout exit: %d %s", code, msg)
+	}
+
+	options = CommandCheckOptions{Command: "queuectl", TimeoutExit: "ok"}
+	if msg, code := CheckCommandWithHandler(options, hung); code != statusCodeUnknown || !strings.Contains(msg, "The timeout exit (--timeout_exit)") {
+		t.Errorf("CheckCommandWithHandler() should be UNKNOWN on an invalid timeout exit: %d %s", code, msg)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	if msg, code := CheckCommand(CommandCheckOptions{Command: "echo", Args: []string{"42"}}); code != statusCodeOK || !strings.Contains(msg, "returned 42") {
+		t.Errorf("CheckCommand() should run the command, returned %d: %s", code, msg)
+	}
+
+	if msg, code := CheckCommand(CommandCheckOptions{Command: "false"}); code != statusCodeUnknown || !strings.Contains(msg, "exit status 1") {
+		t.Errorf("CheckCommand() should return UNKNOWN for a command exiting with an error, returned %d: %s", code, msg)
+	}
+
+	start := time.Now()
+	options = CommandCheckOptions{Command: "sleep", Args: []string{"10"}, Deadline: start.Add(100 * time.Millisecond)}
+	if msg, code := CheckCommand(options); code != statusCodeUnknown || time.Since(start) > 5*time.Second {
+		t.Errorf("CheckCommand() should kill a command running past the deadline, returned %d after %s: %s", code, time.Since(start), msg)
+	}
+}
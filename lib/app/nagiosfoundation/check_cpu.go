@@ -4,21 +4,18 @@ package nagiosfoundation
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"strconv"
 
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/cpu"
-	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/nagiosformatters"
 )
 
-// CheckCPUWithHandler gets the CPU load then emits a critical response
-// if it's above the critical argument, a warning if it's above
-// warning argument and good response for everything else.
-//
-// Returns are a response message and response code.
-func CheckCPUWithHandler(warning, critical int, metricName string, cpuHandler func() (float64, error)) (string, int) {
+// runCPUCheck performs the check of CheckCPUWithHandler() and returns
+// its result.
+func runCPUCheck(warning, critical int, metricName string, cpuHandler func() (float64, error)) CheckResult {
 	const checkName = "CheckAVGCPULoad"
 
-	var msg string
-	var retcode int
 	var value float64
 	var err error
 
@@ -28,14 +25,36 @@ func CheckCPUWithHandler(warning, critical int, metricName string, cpuHandler fu
 		value, err = cpuHandler()
 	}
 
-	if err == nil {
-		msg, retcode = nagiosformatters.GreaterFormatNagiosCheck(checkName, value, float64(warning), float64(critical), metricName)
-	} else {
-		msg, _ = resultMessage(checkName, statusTextCritical, err.Error())
-		retcode = 2
+	if err != nil {
+		return CriticalResult(checkName, err.Error())
+	}
+
+	state := StateOK
+	switch {
+	case value > float64(critical):
+		state = StateCritical
+	case value > float64(warning):
+		state = StateWarning
 	}
 
-	return msg, retcode
+	return NewCheckResult(checkName, state, fmt.Sprintf("value = %f", value), PerfData{
+		Label:    metricName,
+		Value:    math.Round(value*100) / 100,
+		UOM:      "%",
+		Warning:  strconv.Itoa(warning),
+		Critical: strconv.Itoa(critical),
+		Min:      "0",
+		Max:      "100",
+	})
+}
+
+// CheckCPUWithHandler gets the CPU load then emits a critical response
+// if it's above the critical argument, a warning if it's above
+// warning argument and good response for everything else.
+//
+// Returns are a response message and response code.
+func CheckCPUWithHandler(warning, critical int, metricName string, cpuHandler func() (float64, error)) (string, int) {
+	return runCPUCheck(warning, critical, metricName, cpuHandler).Output()
 }
 
 // CheckCPU executes CheckCPUWithHandler(), passing it the OS
@@ -44,3 +63,17 @@ func CheckCPUWithHandler(warning, critical int, metricName string, cpuHandler fu
 func CheckCPU(warning, critical int, metricName string) (string, int) {
 	return CheckCPUWithHandler(warning, critical, metricName, cpu.GetCPULoad)
 }
+
+// RunCPUCheck performs the CPU check of CheckCPUWithInterval() and
+// returns its result, without output.
+func RunCPUCheck(warning, critical int, metricName string, interval int) CheckResult {
+	return runCPUCheck(warning, critical, metricName, func() (float64, error) {
+		return cpu.GetCPULoadWithInterval(interval)
+	})
+}
+
+// CheckCPUWithInterval executes CheckCPUWithHandler(), sampling the
+// CPU load over interval seconds.
+func CheckCPUWithInterval(warning, critical int, metricName string, interval int) (string, int) {
+	return RunCPUCheck(warning, critical, metricName, interval).Output()
+}
@@ -2,6 +2,7 @@ package nagiosfoundation
 
 import (
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -22,6 +23,17 @@ func TestCheckCpu(t *testing.T) {
 		t.Error("CheckCPUWithHandler() failed with valid returns from service")
 	}
 
+	if !strings.HasSuffix(msg, "| pct_processor_time=0.5%;85;95;0;100") {
+		t.Errorf("CheckCPUWithHandler() perfdata malformed: %s", msg)
+	}
+
+	testReturnHigh := func() (float64, error) { return 90.126, nil }
+	msg, retcode = CheckCPUWithHandler(85, 95, "cpu", testReturnHigh)
+
+	if retcode != 1 || !strings.Contains(msg, "WARNING") || !strings.HasSuffix(msg, "| cpu=90.13%;85;95;0;100") {
+		t.Errorf("CheckCPUWithHandler() failed with usage above warning: %s", msg)
+	}
+
 	msg, retcode = CheckCPUWithHandler(85, 95, "pct_processor_time", testReturnError)
 
 	if retcode != 2 || msg == "" {
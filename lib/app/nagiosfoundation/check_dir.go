@@ -0,0 +1,199 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const checkDirName = "CheckDir"
+
+// DirCheckOptions contains the options for a directory check.
+type DirCheckOptions struct {
+	// The directory whose entries are counted.
+	Path string
+
+	// A pattern as accepted by filepath.Match the name of an entry
+	// must match to be counted, such as "*.msg". Empty counts every
+	// entry.
+	Pattern string
+
+	// Counts the entries of the subdirectories of Path as well.
+	Recursive bool
+
+	// Counts only the entries last modified longer ago than this, the
+	// stale entries. Zero counts every entry.
+	OlderThan time.Duration
+
+	// The warning and critical thresholds, Nagios ranges of the number
+	// of entries. An empty threshold is not checked.
+	Warning  string
+	Critical string
+
+	// Reports a missing directory as OK with no entries rather than
+	// UNKNOWN, such as a spool directory only created when needed.
+	MissingOK bool
+
+	// The name of the metric in the nagios output. Defaults to
+	// "entries".
+	MetricName string
+}
+
+// dirEntryMatcher reports whether an entry is counted, matching the
+// pattern and modified before the cutoff.
+type dirEntryMatcher struct {
+	pattern string
+	cutoff  time.Time
+}
+
+func (m dirEntryMatcher) matches(info os.FileInfo) bool {
+	if m.pattern != "" {
+		if matched, _ := filepath.Match(m.pattern, info.Name()); !matched {
+			return false
+		}
+	}
+
+	return m.cutoff.IsZero() || info.ModTime().Before(m.cutoff)
+}
+
+// countDirEntries counts the entries of the directory the matcher
+// matches. Directories are not counted, though with recursive the
+// entries within them are. An entry removed while the directory is
+// read is not counted.
+func countDirEntries(path string, recursive bool, matcher dirEntryMatcher) (int, error) {
+	count := 0
+
+	if !recursive {
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() && matcher.matches(entry) {
+				count++
+			}
+		}
+
+		return count, nil
+	}
+
+	err := filepath.Walk(path, func(walkPath string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && walkPath != path {
+			debugLog.Printf("Skipping %s, it has been removed", walkPath)
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && matcher.matches(info) {
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// runDirCheck performs the check of CheckDirWithHandler() and returns
+// its result.
+func runDirCheck(options DirCheckOptions, now func() time.Time) CheckResult {
+	if options.Path == "" {
+		return UnknownResult(checkDirName, "A path must be specified.")
+	}
+
+	if _, err := filepath.Match(options.Pattern, ""); err != nil {
+		return UnknownResult(checkDirName, fmt.Sprintf("Invalid pattern %q: %s", options.Pattern, err))
+	}
+
+	if options.OlderThan < 0 {
+		return UnknownResult(checkDirName, fmt.Sprintf("Invalid age (%s). The age may not be negative.", options.OlderThan))
+	}
+
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkDirName, err.Error())
+	}
+
+	metricName := options.MetricName
+	if metricName == "" {
+		metricName = "entries"
+	}
+
+	info, err := os.Stat(options.Path)
+
+	switch {
+	case os.IsNotExist(err) && options.MissingOK:
+		return OKResult(checkDirName, fmt.Sprintf("Directory %s does not exist", options.Path),
+			thresholds.Metric(PerfData{Label: metricName, Value: 0, Min: "0"}))
+	case os.IsNotExist(err):
+		return UnknownResult(checkDirName, fmt.Sprintf("Directory %s does not exist", options.Path))
+	case err != nil:
+		return UnknownResult(checkDirName, fmt.Sprintf("Could not read directory %s: %s", options.Path, err))
+	case !info.IsDir():
+		return UnknownResult(checkDirName, fmt.Sprintf("%s is not a directory", options.Path))
+	}
+
+	matcher := dirEntryMatcher{pattern: options.Pattern}
+	if options.OlderThan > 0 {
+		matcher.cutoff = now().Add(-options.OlderThan)
+	}
+
+	count, err := countDirEntries(options.Path, options.Recursive, matcher)
+	if err != nil {
+		return UnknownResult(checkDirName, fmt.Sprintf("Could not read directory %s: %s", options.Path, err))
+	}
+
+	checkInfo := fmt.Sprintf("%d entries in %s", count, options.Path)
+	if options.Recursive {
+		checkInfo += " and its subdirectories"
+	}
+
+	if options.Pattern != "" {
+		checkInfo += fmt.Sprintf(" matching %s", options.Pattern)
+	}
+
+	if options.OlderThan > 0 {
+		checkInfo += fmt.Sprintf(" older than %s", options.OlderThan)
+	}
+
+	state, tripped := thresholds.Status(float64(count))
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+	}
+
+	return NewCheckResult(checkDirName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: metricName,
+		Value: float64(count),
+		Min:   "0",
+	}))
+}
+
+// CheckDirWithHandler counts the entries of the directory options.Path
+// other than subdirectories, those with options.Recursive of its whole
+// tree, and compares the count against the options.Warning and
+// options.Critical thresholds, to catch a spool or queue directory
+// accumulating files. Only the entries whose names match
+// options.Pattern and, with options.OlderThan, those last modified
+// longer ago than that as of now are counted. A directory that does
+// not exist emits an unknown response, or a good response counting
+// no entries with options.MissingOK. The count is output as perfdata.
+func CheckDirWithHandler(options DirCheckOptions, now func() time.Time) (string, int) {
+	return runDirCheck(options, now).Output()
+}
+
+// RunDirCheck performs the directory check of CheckDir() and returns
+// its result, without output.
+func RunDirCheck(options DirCheckOptions) CheckResult {
+	return runDirCheck(options, time.Now)
+}
+
+// CheckDir executes CheckDirWithHandler(), passing it time.Now().
+//
+// Returns are those of CheckDirWithHandler()
+func CheckDir(options DirCheckOptions) (string, int) {
+	return RunDirCheck(options).Output()
+}
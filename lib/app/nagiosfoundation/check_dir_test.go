@@ -0,0 +1,92 @@
+package nagiosfoundation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+
+	if err := os.MkdirAll(filepath.Join(dir, "deferred", "retry"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []struct {
+		name string
+		age  time.Duration
+	}{
+		{"1.msg", 3 * time.Hour},
+		{"2.msg", 10 * time.Minute},
+		{"3.tmp", 5 * time.Hour},
+		{filepath.Join("deferred", "4.msg"), 2 * time.Hour},
+		{filepath.Join("deferred", "retry", "5.msg"), time.Minute},
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.name)
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, now, now.Add(-entry.age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file := filepath.Join(dir, "1.msg")
+	missing := filepath.Join(dir, "missing")
+
+	type testItem struct {
+		description  string
+		options      DirCheckOptions
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"All entries", DirCheckOptions{Path: dir}, statusCodeOK, "3 entries in " + dir + " | entries=3;;;0"},
+		{"Matching pattern", DirCheckOptions{Path: dir, Pattern: "*.msg", Warning: "1"}, statusCodeWarning,
+			"2 entries in " + dir + " matching *.msg (expected 0-1) | entries=2;1;;0"},
+		{"Recursive", DirCheckOptions{Path: dir, Pattern: "*.msg", Recursive: true, Warning: "3", Critical: "10"}, statusCodeWarning,
+			"4 entries in " + dir + " and its subdirectories matching *.msg (expected 0-3) | entries=4;3;10;0"},
+		{"Older than", DirCheckOptions{Path: dir, Recursive: true, OlderThan: time.Hour, Critical: "2", MetricName: "stale"}, statusCodeCritical,
+			"3 entries in " + dir + " and its subdirectories older than 1h0m0s (expected 0-2) | stale=3;;2;0"},
+		{"Older than with pattern", DirCheckOptions{Path: dir, Pattern: "*.msg", OlderThan: time.Hour, Warning: "1"}, statusCodeOK,
+			"1 entries in " + dir + " matching *.msg older than 1h0m0s | entries=1;1;;0"},
+		{"Missing", DirCheckOptions{Path: missing}, statusCodeUnknown, "Directory " + missing + " does not exist"},
+		{"Missing OK", DirCheckOptions{Path: missing, MissingOK: true, Warning: "10"}, statusCodeOK,
+			"Directory " + missing + " does not exist | entries=0;10;;0"},
+		{"Not a directory", DirCheckOptions{Path: file}, statusCodeUnknown, file + " is not a directory"},
+		{"No path", DirCheckOptions{}, statusCodeUnknown, "A path must be specified"},
+		{"Bad pattern", DirCheckOptions{Path: dir, Pattern: "["}, statusCodeUnknown, "Invalid pattern"},
+		{"Negative age", DirCheckOptions{Path: dir, OlderThan: -time.Hour}, statusCodeUnknown, "The age may not be negative"},
+		{"Invalid threshold", DirCheckOptions{Path: dir, Warning: "many"}, statusCodeUnknown, "Invalid range"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckDirWithHandler(i.options, func() time.Time { return now })
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	if msg, code := CheckDir(DirCheckOptions{Path: dir}); code != statusCodeOK {
+		t.Errorf("CheckDir() should count the entries, returned %d: %s", code, msg)
+	}
+}
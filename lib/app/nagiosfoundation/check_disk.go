@@ -0,0 +1,136 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/disk"
+)
+
+const checkDiskName = "CheckDisk"
+
+// describeMount describes the mount the check ran against. Bind
+// mounts and overlays report the usage of a filesystem shared with
+// other mounts, so the filesystem is named to make clear which usage
+// is being reported.
+func describeMount(mount disk.Mount) string {
+	switch {
+	case mount.IsBind():
+		return fmt.Sprintf("bind mount of %s on %s %s (device %s)", mount.Root, mount.FSType, mount.Source, mount.Device)
+	case mount.FSType == "overlay":
+		return fmt.Sprintf("overlay mounted at %s, usage is of the upper layer filesystem", mount.MountPoint)
+	default:
+		return fmt.Sprintf("%s %s mounted at %s", mount.FSType, mount.Source, mount.MountPoint)
+	}
+}
+
+// diskThresholds returns the thresholds resolved against the total,
+// as whole amounts to compare the amount used against and as
+// percentages of two decimals for the percentage used, which are
+// rounded as in the perfdata.
+func diskThresholds(thresholds RelativeThresholds, total uint64) (Thresholds, Thresholds) {
+	return thresholds.Resolve(float64(total)).round(0), thresholds.Percentages(float64(total)).round(2)
+}
+
+// runDiskCheck performs the check of CheckDiskWithHandlers() and
+// returns its result.
+func runDiskCheck(path, warning, critical, metricName string, inodes bool,
+	usageHandler func(string) (disk.Usage, error), mountHandler func(string) (disk.Mount, error)) CheckResult {
+	if path == "" {
+		path = disk.DefaultPath
+	}
+
+	kind, unit, uom, label := "Disk", "bytes", "B", "disk"
+	if inodes {
+		kind, unit, uom, label = "Inodes", "inodes", "", "inodes"
+	}
+
+	if metricName == "" {
+		metricName = label
+	}
+
+	relativeThresholds, err := ParseRelativeThresholds(warning, critical)
+	if err != nil {
+		return UnknownResult(checkDiskName, err.Error())
+	}
+
+	if usageHandler == nil {
+		return UnknownResult(checkDiskName, "No disk usage service")
+	}
+
+	usage, err := usageHandler(path)
+	if err != nil {
+		return UnknownResult(checkDiskName, fmt.Sprintf("Could not determine %s used on %s: %s", strings.ToLower(kind), path, err))
+	}
+
+	if usage.Total == 0 {
+		return OKResult(checkDiskName, fmt.Sprintf("No %s reported on %s", unit, path))
+	}
+
+	thresholds, percentThresholds := diskThresholds(relativeThresholds, usage.Total)
+	state, _ := thresholds.Status(float64(usage.Used))
+
+	usedPercentage := float64(usage.Used) / float64(usage.Total) * 100
+
+	desc := fmt.Sprintf("%s used on %s is %.2f%% (%d of %d %s)", kind, path, usedPercentage, usage.Used, usage.Total, unit)
+	if mountHandler != nil {
+		if mount, err := mountHandler(path); err == nil {
+			desc += ", " + describeMount(mount)
+		} else {
+			debugLog.Printf("Could not determine the mount holding %s: %s", path, err)
+		}
+	}
+
+	return NewCheckResult(checkDiskName, state, desc,
+		thresholds.Metric(PerfData{
+			Label: metricName + "_used",
+			Value: float64(usage.Used),
+			UOM:   uom,
+			Min:   "0",
+			Max:   strconv.FormatUint(usage.Total, 10),
+		}),
+		percentThresholds.Metric(PerfData{
+			Label: metricName + "_used_pct",
+			Value: math.Round(usedPercentage*100) / 100,
+			UOM:   "%",
+			Min:   "0",
+			Max:   "100",
+		}),
+		PerfData{Label: metricName + "_total", Value: float64(usage.Total), UOM: uom, Min: "0"},
+	)
+}
+
+// CheckDiskWithHandlers determines the space, or with inodes set the
+// inodes, used on the filesystem holding path and emits a critical
+// response if it's over the critical threshold, a warning response if
+// it's over the warning threshold and a good response otherwise. The
+// thresholds are Nagios ranges of a percentage such as "90%" or an
+// amount such as "2G", as parsed by ParseRelativeRange(). The
+// mountHandler describes the mount in the response and may fail or be
+// nil, in which case the mount is not described.
+func CheckDiskWithHandlers(path, warning, critical, metricName string, inodes bool,
+	usageHandler func(string) (disk.Usage, error), mountHandler func(string) (disk.Mount, error)) (string, int) {
+	return runDiskCheck(path, warning, critical, metricName, inodes, usageHandler, mountHandler).Output()
+}
+
+// RunDiskCheck performs the disk check of CheckDisk() and returns its
+// result, without output.
+func RunDiskCheck(path, warning, critical, metricName string, inodes bool) CheckResult {
+	usageHandler := disk.GetUsage
+	if inodes {
+		usageHandler = disk.GetInodeUsage
+	}
+
+	return runDiskCheck(path, warning, critical, metricName, inodes, usageHandler, disk.GetMount)
+}
+
+// CheckDisk executes CheckDiskWithHandlers(), passing it the OS
+// constrained disk.GetInodeUsage() function when inodes is set,
+// otherwise disk.GetUsage(), along with disk.GetMount().
+//
+// Returns are those of CheckDiskWithHandlers()
+func CheckDisk(path, warning, critical, metricName string, inodes bool) (string, int) {
+	return RunDiskCheck(path, warning, critical, metricName, inodes).Output()
+}
@@ -0,0 +1,329 @@
+package nagiosfoundation
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const checkDiskHealthName = "CheckDiskHealth"
+
+const defaultSmartctl = "smartctl"
+
+// The bits of the exit code of smartctl telling it could not read the
+// health of the device, as opposed to the bits describing the health
+// read.
+const (
+	smartctlExitCommandLine = 1 << 0
+	smartctlExitOpenFailed  = 1 << 1
+)
+
+// DiskHealthCheckOptions contains the options for a disk health check.
+type DiskHealthCheckOptions struct {
+	// The device checked, such as "/dev/sda".
+	Device string
+
+	// The type of the device passed to smartctl with -d, such as
+	// "sat" or "megaraid,0". Empty lets smartctl detect the type.
+	DeviceType string
+
+	// The warning and critical thresholds, Nagios ranges of the
+	// reallocated sectors, the sectors pending reallocation and the
+	// temperature in degrees Celsius. An empty threshold is not
+	// checked.
+	ReallocatedWarning  string
+	ReallocatedCritical string
+	PendingWarning      string
+	PendingCritical     string
+	TemperatureWarning  string
+	TemperatureCritical string
+
+	// The smartctl command run. Defaults to "smartctl" found in the
+	// PATH.
+	Smartctl string
+
+	// The prefix of the perfdata labels. Defaults to "smart".
+	MetricName string
+
+	// The time smartctl is killed when it has not exited, the zero
+	// time to wait however long it takes.
+	Deadline time.Time
+
+	// The state to issue when smartctl does not complete in time:
+	// "unknown", "critical" or "warning". Defaults to "unknown".
+	TimeoutExit string
+}
+
+// smartHealth is the health of a device read from smartctl. The
+// values not reported for the device, such as the reallocated sectors
+// of most NVMe devices, are -1.
+type smartHealth struct {
+	// The overall health assessment, such as "PASSED", "FAILED!" or
+	// "OK". Empty when not reported.
+	assessment string
+
+	reallocated int64
+	pending     int64
+	temperature int64
+}
+
+// passed reports whether the overall health assessment is good.
+func (h smartHealth) passed() bool {
+	return h.assessment == "PASSED" || h.assessment == "OK"
+}
+
+// parseSmartRaw returns the leading number of a raw value of smartctl,
+// such as 35 for the temperature "35 (Min/Max 20/45)".
+func parseSmartRaw(raw string) (int64, bool) {
+	end := 0
+	for end < len(raw) && raw[end] >= '0' && raw[end] <= '9' {
+		end++
+	}
+
+	value, err := strconv.ParseInt(raw[:end], 10, 64)
+
+	return value, err == nil
+}
+
+// parseSmartctl returns the health of a device from the output of
+// smartctl -H -A, covering the formats of ATA, NVMe and SCSI devices.
+// The ATA attributes are read from the table of attributes, a line
+// for each of the ID, name, flags, normalized values and the raw
+// value, such as
+// "  5 Reallocated_Sector_Ct   0x0033   100   100   010    Pre-fail  Always       -       0",
+// and the NVMe and SCSI values from the lines of the name and value,
+// such as "Temperature:   35 Celsius".
+func parseSmartctl(data string) smartHealth {
+	health := smartHealth{reallocated: -1, pending: -1, temperature: -1}
+
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.Index(line, ":"); i >= 0 {
+			name, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+
+			switch name {
+			case "SMART overall-health self-assessment test result", "SMART Health Status":
+				health.assessment = value
+			case "Temperature", "Current Drive Temperature":
+				if temperature, ok := parseSmartRaw(value); ok {
+					health.temperature = temperature
+				}
+			case "Elements in grown defect list":
+				if reallocated, ok := parseSmartRaw(value); ok {
+					health.reallocated = reallocated
+				}
+			}
+
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+
+		raw, ok := parseSmartRaw(fields[9])
+		if !ok {
+			continue
+		}
+
+		switch fields[0] {
+		case "5":
+			health.reallocated = raw
+		case "197":
+			health.pending = raw
+		case "194":
+			health.temperature = raw
+		case "190":
+			// The airflow temperature is read when the drive has no
+			// temperature of its own.
+			if health.temperature < 0 {
+				health.temperature = raw
+			}
+		}
+	}
+
+	return health
+}
+
+// smartctlError returns the line of the output of smartctl telling
+// why it could not read the device, such as
+// "Smartctl open device: /dev/sda failed: Permission denied", or the
+// last line of the output.
+func smartctlError(data string) string {
+	var last string
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.Contains(line, "failed:") {
+			return line
+		}
+
+		if line != "" {
+			last = line
+		}
+	}
+
+	return last
+}
+
+// runCommandExitCode runs the command with the arguments and returns
+// its standard output and exit code, killing the command when the
+// context is done. Unlike runCommand() a non-zero exit code is not an
+// error, for commands such as smartctl telling their result with the
+// exit code.
+func runCommandExitCode(ctx context.Context, name string, args ...string) ([]byte, int, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && ctx.Err() == nil {
+		return out, exitErr.ExitCode(), nil
+	}
+
+	return out, 0, err
+}
+
+// runDiskHealthCheck performs the check of CheckDiskHealthWithHandler()
+// and returns its result.
+func runDiskHealthCheck(options DiskHealthCheckOptions,
+	run func(context.Context, string, ...string) ([]byte, int, error)) CheckResult {
+	if options.Device == "" {
+		return UnknownResult(checkDiskHealthName, "A device must be specified.")
+	}
+
+	reallocatedThresholds, err := ParseThresholds(options.ReallocatedWarning, options.ReallocatedCritical)
+	if err != nil {
+		return UnknownResult(checkDiskHealthName, err.Error())
+	}
+
+	pendingThresholds, err := ParseThresholds(options.PendingWarning, options.PendingCritical)
+	if err != nil {
+		return UnknownResult(checkDiskHealthName, err.Error())
+	}
+
+	temperatureThresholds, err := ParseThresholds(options.TemperatureWarning, options.TemperatureCritical)
+	if err != nil {
+		return UnknownResult(checkDiskHealthName, err.Error())
+	}
+
+	if err := checkTimeoutExit(options.TimeoutExit); err != nil {
+		return UnknownResult(checkDiskHealthName, err.Error())
+	}
+
+	smartctl := options.Smartctl
+	if smartctl == "" {
+		smartctl = defaultSmartctl
+	}
+
+	metricName := options.MetricName
+	if metricName == "" {
+		metricName = "smart"
+	}
+
+	args := []string{"-H", "-A"}
+	if options.DeviceType != "" {
+		args = append(args, "-d", options.DeviceType)
+	}
+
+	args = append(args, options.Device)
+
+	ctx := context.Background()
+	if !options.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, options.Deadline)
+		defer cancel()
+	}
+
+	debugLog.Printf("Running %s %s", smartctl, strings.Join(args, " "))
+	out, exitCode, err := run(ctx, smartctl, args...)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = classifyError(ctx.Err())
+	}
+
+	switch {
+	case IsErrorKind(err, ErrTimeout):
+		return timeoutResult(checkDiskHealthName, options.TimeoutExit, fmt.Sprintf("%s did not complete in time and was killed", smartctl))
+	case err != nil:
+		return UnknownResult(checkDiskHealthName, fmt.Sprintf("Could not run %s: %s", smartctl, err))
+	case exitCode&(smartctlExitCommandLine|smartctlExitOpenFailed) != 0:
+		return UnknownResult(checkDiskHealthName, fmt.Sprintf("Could not read the health of device %s, %s exited with %d: %s",
+			options.Device, smartctl, exitCode, smartctlError(string(out))))
+	}
+
+	health := parseSmartctl(string(out))
+	if health.assessment == "" {
+		return UnknownResult(checkDiskHealthName, fmt.Sprintf("No health assessment of device %s in the output of %s: %s",
+			options.Device, smartctl, smartctlError(string(out))))
+	}
+
+	state := StateOK
+	if !health.passed() {
+		state = StateCritical
+	}
+
+	details := []string{"health " + health.assessment}
+	var tripped []string
+	var perfData []PerfData
+
+	check := func(value int64, desc, label string, thresholds Thresholds) {
+		if value < 0 {
+			return
+		}
+
+		details = append(details, fmt.Sprintf("%d %s", value, desc))
+		perfData = append(perfData, thresholds.Metric(PerfData{Label: metricName + "_" + label, Value: float64(value), Min: "0"}))
+
+		s, r := thresholds.Status(float64(value))
+		if s == StateOK {
+			return
+		}
+
+		if s == StateCritical || state == StateOK {
+			state = s
+		}
+
+		tripped = append(tripped, fmt.Sprintf("%d %s, expected %s", value, desc, r.Expected()))
+	}
+
+	check(health.reallocated, "reallocated sectors", "reallocated", reallocatedThresholds)
+	check(health.pending, "pending sectors", "pending", pendingThresholds)
+	check(health.temperature, "C", "temperature", temperatureThresholds)
+
+	checkInfo := fmt.Sprintf("Device %s %s", options.Device, strings.Join(details, ", "))
+	if len(tripped) > 0 {
+		checkInfo += " (" + strings.Join(tripped, ", ") + ")"
+	}
+
+	return NewCheckResult(checkDiskHealthName, state, checkInfo, perfData...)
+}
+
+// CheckDiskHealthWithHandler runs smartctl -H -A for options.Device
+// using run and checks its overall health assessment, emitting a
+// critical response when the device reports it is failing, for an
+// alert before the disk fails. The reallocated sectors, the sectors
+// pending reallocation and the temperature are compared against their
+// thresholds in options, when the device reports them. As smartctl
+// needs root to read a device, a smartctl that cannot be run or that
+// cannot open the device, such as for the lack of privileges, emits an
+// unknown response rather than a critical one, as does a smartctl
+// still running when options.Deadline passes, which is killed, unless
+// options.TimeoutExit selects another state. The values read are
+// output as perfdata.
+func CheckDiskHealthWithHandler(options DiskHealthCheckOptions,
+	run func(context.Context, string, ...string) ([]byte, int, error)) (string, int) {
+	return runDiskHealthCheck(options, run).Output()
+}
+
+// RunDiskHealthCheck performs the disk health check of
+// CheckDiskHealth() and returns its result, without output.
+func RunDiskHealthCheck(options DiskHealthCheckOptions) CheckResult {
+	return runDiskHealthCheck(options, runCommandExitCode)
+}
+
+// CheckDiskHealth executes CheckDiskHealthWithHandler(), passing it a
+// function running smartctl with exec.CommandContext().
+//
+// Returns are those of CheckDiskHealthWithHandler()
+func CheckDiskHealth(options DiskHealthCheckOptions) (string, int) {
+	return RunDiskHealthCheck(options).Output()
+}
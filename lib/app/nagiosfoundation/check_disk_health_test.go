@@ -0,0 +1,140 @@
+package nagiosfoundation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSmartctlATA = `smartctl 7.1 2019-12-30 r5022 [x86_64-linux-5.4.0] (local build)
+
+=== START OF READ SMART DATA SECTION ===
+SMART overall-health self-assessment test result: PASSED
+
+SMART Attributes Data Structure revision number: 16
+Vendor Specific SMART Attributes with Thresholds:
+ID# ATTRIBUTE_NAME          FLAG     VALUE WORST THRESH TYPE      UPDATED  WHEN_FAILED RAW_VALUE
+  5 Reallocated_Sector_Ct   0x0033   100   100   010    Pre-fail  Always       -       8
+  9 Power_On_Hours          0x0032   091   091   000    Old_age   Always       -       39812
+190 Airflow_Temperature_Cel 0x0022   062   048   045    Old_age   Always       -       38
+194 Temperature_Celsius     0x0022   036   052   000    Old_age   Always       -       36 (Min/Max 20/52)
+197 Current_Pending_Sector  0x0012   100   100   000    Old_age   Always       -       0
+`
+
+const testSmartctlNVMe = `=== START OF SMART DATA SECTION ===
+SMART overall-health self-assessment test result: PASSED
+
+SMART/Health Information (NVMe Log 0x02)
+Critical Warning:                   0x00
+Temperature:                        41 Celsius
+Available Spare:                    100%
+`
+
+const testSmartctlSCSI = `=== START OF READ SMART DATA SECTION ===
+SMART Health Status: OK
+
+Current Drive Temperature:     30 C
+Drive Trip Temperature:        65 C
+Elements in grown defect list: 2
+`
+
+func TestParseSmartctl(t *testing.T) {
+	testList := []struct {
+		description string
+		data        string
+		expected    smartHealth
+	}{
+		{"ATA", testSmartctlATA, smartHealth{assessment: "PASSED", reallocated: 8, pending: 0, temperature: 36}},
+		{"NVMe", testSmartctlNVMe, smartHealth{assessment: "PASSED", reallocated: -1, pending: -1, temperature: 41}},
+		{"SCSI", testSmartctlSCSI, smartHealth{assessment: "OK", reallocated: 2, pending: -1, temperature: 30}},
+		{"Airflow temperature alone", "190 Airflow_Temperature_Cel 0x0022 062 048 045 Old_age Always - 38",
+			smartHealth{reallocated: -1, pending: -1, temperature: 38}},
+	}
+
+	for _, i := range testList {
+		if health := parseSmartctl(i.data); !reflect.DeepEqual(health, i.expected) {
+			t.Errorf("%s: Expected: %+v, Actual: %+v", i.description, i.expected, health)
+		}
+	}
+}
+
+func TestCheckDiskHealth(t *testing.T) {
+	type testItem struct {
+		description  string
+		options      DiskHealthCheckOptions
+		out          string
+		exitCode     int
+		err          error
+		expectedCode int
+		expectedMsg  string
+	}
+
+	failing := strings.Replace(testSmartctlATA, "PASSED", "FAILED!", 1)
+
+	testList := []testItem{
+		{"Healthy", DiskHealthCheckOptions{Device: "/dev/sda"}, testSmartctlATA, 0, nil, statusCodeOK,
+			"CheckDiskHealth OK - Device /dev/sda health PASSED, 8 reallocated sectors, 0 pending sectors, 36 C | smart_reallocated=8;;;0 smart_pending=0;;;0 smart_temperature=36;;;0"},
+		{"Failing", DiskHealthCheckOptions{Device: "/dev/sda"}, failing, 8, nil, statusCodeCritical, "Device /dev/sda health FAILED!, 8 reallocated"},
+		{"Reallocated over the warning", DiskHealthCheckOptions{Device: "/dev/sda", ReallocatedWarning: "0", ReallocatedCritical: "100"},
+			testSmartctlATA, 0, nil, statusCodeWarning, "(8 reallocated sectors, expected 0) | smart_reallocated=8;0;100;0"},
+		{"Hot", DiskHealthCheckOptions{Device: "/dev/sda", TemperatureWarning: "30", TemperatureCritical: "35", MetricName: "sda"},
+			testSmartctlATA, 0, nil, statusCodeCritical, "(36 C, expected 0-35) | sda_reallocated"},
+		{"NVMe has no sectors", DiskHealthCheckOptions{Device: "/dev/nvme0", PendingCritical: "0"}, testSmartctlNVMe, 0, nil, statusCodeOK,
+			"Device /dev/nvme0 health PASSED, 41 C | smart_temperature=41;;;0"},
+		{"Attributes below their thresholds", DiskHealthCheckOptions{Device: "/dev/sda"}, testSmartctlATA, 32, nil, statusCodeOK, "health PASSED"},
+		{"Permission denied", DiskHealthCheckOptions{Device: "/dev/sda"},
+			"smartctl 7.1\n\nSmartctl open device: /dev/sda failed: Permission denied\n", 2, nil, statusCodeUnknown,
+			"CheckDiskHealth UNKNOWN - Could not read the health of device /dev/sda, smartctl exited with 2: Smartctl open device: /dev/sda failed: Permission denied"},
+		{"Not installed", DiskHealthCheckOptions{Device: "/dev/sda"}, "", 0, errors.New(`exec: "smartctl": executable file not found in $PATH`),
+			statusCodeUnknown, "Could not run smartctl: exec: \"smartctl\": executable file not found"},
+		{"No assessment", DiskHealthCheckOptions{Device: "/dev/sda"}, "SMART support is: Unavailable\n", 4, nil, statusCodeUnknown,
+			"No health assessment of device /dev/sda in the output of smartctl: SMART support is: Unavailable"},
+		{"No device", DiskHealthCheckOptions{}, "", 0, nil, statusCodeUnknown, "A device must be specified"},
+		{"Invalid threshold", DiskHealthCheckOptions{Device: "/dev/sda", TemperatureWarning: "hot"}, "", 0, nil, statusCodeUnknown, "Invalid range"},
+	}
+
+	for _, i := range testList {
+		run := func(ctx context.Context, name string, args ...string) ([]byte, int, error) {
+			return []byte(i.out), i.exitCode, i.err
+		}
+
+		msg, code := CheckDiskHealthWithHandler(i.options, run)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	var ranArgs []string
+	run := func(ctx context.Context, name string, args ...string) ([]byte, int, error) {
+		ranArgs = append([]string{name}, args...)
+		return []byte(testSmartctlATA), 0, nil
+	}
+
+	CheckDiskHealthWithHandler(DiskHealthCheckOptions{Device: "/dev/sdb", DeviceType: "megaraid,0", Smartctl: "/usr/sbin/smartctl"}, run)
+	if expected := []string{"/usr/sbin/smartctl", "-H", "-A", "-d", "megaraid,0", "/dev/sdb"}; !reflect.DeepEqual(ranArgs, expected) {
+		t.Errorf("CheckDiskHealthWithHandler() Expected Command: %v, Actual Command: %v", expected, ranArgs)
+	}
+
+	timedOut := func(ctx context.Context, name string, args ...string) ([]byte, int, error) {
+		<-ctx.Done()
+		return nil, 0, ctx.Err()
+	}
+
+	options := DiskHealthCheckOptions{Device: "/dev/sda", Deadline: time.Now().Add(10 * time.Millisecond)}
+	if msg, code := CheckDiskHealthWithHandler(options, timedOut); code != statusCodeUnknown || !strings.Contains(msg, "did not complete in time") {
+		t.Errorf("CheckDiskHealthWithHandler() should return UNKNOWN when smartctl times out: %d %s", code, msg)
+	}
+
+	options.Deadline, options.TimeoutExit = time.Now().Add(10*time.Millisecond), "warning"
+	if msg, code := CheckDiskHealthWithHandler(options, timedOut); code != statusCodeWarning || !strings.Contains(msg, "did not complete in time") {
+		t.Errorf("CheckDiskHealthWithHandler() should return the timeout exit when smartctl times out: %d %s", code, msg)
+	}
+}
@@ -0,0 +1,74 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/disk"
+)
+
+func TestCheckDisk(t *testing.T) {
+	usage := func(used, total uint64) func(string) (disk.Usage, error) {
+		return func(string) (disk.Usage, error) {
+			return disk.Usage{Used: used, Total: total}, nil
+		}
+	}
+
+	mount := func(string) (disk.Mount, error) {
+		return disk.Mount{Device: "8:17", Root: "/exports/web", MountPoint: "/srv/web", FSType: "xfs", Source: "/dev/sdb1"}, nil
+	}
+
+	type testItem struct {
+		description  string
+		warning      string
+		critical     string
+		inodes       bool
+		usage        func(string) (disk.Usage, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"OK by percent", "85%", "95%", false, usage(500, 1000), statusCodeOK,
+			"CheckDisk OK - Disk used on /srv/web is 50.00% (500 of 1000 bytes), bind mount of /exports/web on xfs /dev/sdb1 (device 8:17) | disk_used=500B;850;950;0;1000 disk_used_pct=50%;85;95;0;100 disk_total=1000B;;;0"},
+		{"Warning by percent", "85%", "95%", false, usage(900, 1000), statusCodeWarning, "disk_used_pct=90%;85;95;0;100"},
+		{"Critical by size", "1K", "2K", false, usage(3072, 4096), statusCodeCritical, "disk_used=3072B;1024;2048;0;4096 disk_used_pct=75%;25;50;0;100"},
+		{"Warning by percent under size", "80%", "950", false, usage(900, 1000), statusCodeWarning,
+			"CheckDisk WARNING - Disk used on /srv/web is 90.00% (900 of 1000 bytes)"},
+		{"Mixed thresholds perfdata", "80%", "950", false, usage(900, 1000), statusCodeWarning, "disk_used=900B;800;950;0;1000 disk_used_pct=90%;80;95;0;100"},
+		{"Critical by size over percent", "99%", "2K", false, usage(3072, 4096), statusCodeCritical, "disk_used=3072B;4055;2048;0;4096 disk_used_pct=75%;99;50;0;100"},
+		{"Inodes", "50%", "90%", true, usage(60, 100), statusCodeWarning, "Inodes used on /srv/web is 60.00% (60 of 100 inodes)"},
+		{"Inode perfdata", "50%", "90%", true, usage(60, 100), statusCodeWarning, "inodes_used=60;50;90;0;100"},
+		{"No inodes", "50%", "90%", true, usage(0, 0), statusCodeOK, "No inodes reported on /srv/web"},
+		{"Invalid threshold", "lots", "95%", false, usage(1, 2), statusCodeUnknown, "Invalid range \"lots\", end is not a number, a percentage such as 90% or a size such as 2G"},
+		{"Range of free space", "~:50%", "10%:", false, usage(900, 1000), statusCodeWarning, "disk_used=900B;~:500;100:;0;1000 disk_used_pct=90%;~:50;10:;0;100"},
+		{"No critical threshold", "85%", "", false, usage(990, 1000), statusCodeWarning, "disk_used=990B;850;;0;1000 disk_used_pct=99%;85;;0;100"},
+		{"Usage error", "85%", "95%", false, func(string) (disk.Usage, error) { return disk.Usage{}, errors.New("no such file or directory") }, statusCodeUnknown, "no such file or directory"},
+		{"No usage service", "85%", "95%", false, nil, statusCodeUnknown, "No disk usage service"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckDiskWithHandlers("/srv/web", i.warning, i.critical, "", i.inodes, i.usage, mount)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	overlay := func(string) (disk.Mount, error) {
+		return disk.Mount{Device: "0:45", Root: "/", MountPoint: "/", FSType: "overlay", Source: "overlay"}, nil
+	}
+
+	if msg, _ := CheckDiskWithHandlers("", "85%", "95%", "root", false, usage(1, 2), overlay); !strings.Contains(msg, "usage is of the upper layer filesystem | root_used=") {
+		t.Errorf("CheckDiskWithHandlers() should describe an overlay: %s", msg)
+	}
+
+	if msg, _ := CheckDiskWithHandlers("/", "85%", "95%", "", false, usage(1, 2), nil); strings.Contains(msg, "mounted") {
+		t.Errorf("CheckDiskWithHandlers() should not describe the mount without a mount service: %s", msg)
+	}
+}
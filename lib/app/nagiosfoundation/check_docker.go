@@ -0,0 +1,232 @@
+package nagiosfoundation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	checkDockerName     = "CheckDocker"
+	defaultDockerSocket = "/var/run/docker.sock"
+
+	// The most of a response of the Docker Engine read, well over the
+	// inspection of a container.
+	maxDockerResponse = 1 << 20
+)
+
+// DockerCheckOptions contains the options for a check of the state of
+// a Docker container.
+type DockerCheckOptions struct {
+	// The name or ID of the container.
+	Name string
+
+	// The unix socket of the Docker Engine API. Defaults to
+	// /var/run/docker.sock.
+	Socket string
+
+	// The number of seconds to wait for the Docker Engine. Defaults to
+	// 10.
+	Timeout int
+
+	// The name of the metric of the restarts of the container in the
+	// nagios output. Defaults to "restarts".
+	MetricName string
+
+	// The state to issue when the Docker Engine does not respond in
+	// time: "unknown", "critical" or "warning". Defaults to "unknown".
+	TimeoutExit string
+}
+
+// dockerContainer is the part of the inspection of a container by the
+// Docker Engine API the check reads.
+type dockerContainer struct {
+	RestartCount int
+	State        struct {
+		Status     string
+		Running    bool
+		Paused     bool
+		Restarting bool
+		ExitCode   int
+		Health     *struct {
+			Status        string
+			FailingStreak int
+		}
+	}
+}
+
+// dockerEngineGetter returns a function getting a path of the Docker
+// Engine API through the unix socket, returning the status code and
+// the body of the response.
+func dockerEngineGetter(socket string, timeout time.Duration) func(string) (int, []byte, error) {
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	return func(path string) (int, []byte, error) {
+		// The host is not used, the connection is to the socket.
+		response, err := client.Get("http://docker" + path)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxDockerResponse))
+
+		return response.StatusCode, body, err
+	}
+}
+
+// dockerErrorMessage returns the message of an error response of the
+// Docker Engine API, or the status when the body has none.
+func dockerErrorMessage(status int, body []byte) string {
+	var response struct{ Message string }
+	if json.Unmarshal(body, &response) == nil && response.Message != "" {
+		return response.Message
+	}
+
+	return fmt.Sprintf("status %d", status)
+}
+
+// runDockerCheck performs the check of CheckDockerWithHandlers() and
+// returns its result.
+func runDockerCheck(options DockerCheckOptions, get func(string) (int, []byte, error)) CheckResult {
+	name := strings.TrimPrefix(options.Name, "/")
+	if name == "" {
+		return UnknownResult(checkDockerName, "A container name must be given")
+	}
+
+	socket := options.Socket
+	if socket == "" {
+		socket = defaultDockerSocket
+	}
+
+	metricName := options.MetricName
+	if metricName == "" {
+		metricName = "restarts"
+	}
+
+	if err := checkTimeoutExit(options.TimeoutExit); err != nil {
+		return UnknownResult(checkDockerName, err.Error())
+	}
+
+	status, body, err := get("/containers/" + url.PathEscape(name) + "/json")
+	if err != nil {
+		err = classifyError(err)
+
+		// The url.Error only adds the URL of the socket, which is not
+		// the address connected to.
+		cause := err
+		if checkErr, ok := err.(*CheckError); ok {
+			cause = checkErr.Err
+		}
+		if urlErr, ok := cause.(*url.Error); ok {
+			cause = urlErr.Err
+		}
+
+		message := fmt.Sprintf("Could not query the Docker Engine at %s: %s", socket, cause)
+		if IsErrorKind(err, ErrTimeout) {
+			return timeoutResult(checkDockerName, options.TimeoutExit, message)
+		}
+
+		return errorResult(checkDockerName, message, err)
+	}
+
+	switch {
+	case status == http.StatusNotFound:
+		return errorResult(checkDockerName, fmt.Sprintf("Container %s does not exist", name),
+			&CheckError{Kind: ErrTargetNotFound, Err: fmt.Errorf("%s", dockerErrorMessage(status, body))})
+	case status != http.StatusOK:
+		return UnknownResult(checkDockerName, fmt.Sprintf("Could not inspect container %s: %s", name, dockerErrorMessage(status, body)))
+	}
+
+	var container dockerContainer
+	if err := json.Unmarshal(body, &container); err != nil {
+		return UnknownResult(checkDockerName, fmt.Sprintf("Could not parse the inspection of container %s: %s", name, err))
+	}
+
+	metric := PerfData{Label: metricName, Value: float64(container.RestartCount), Min: "0"}
+	state := container.State
+
+	if !state.Running || state.Paused || state.Restarting {
+		desc := fmt.Sprintf("Container %s is %s", name, state.Status)
+		if state.Status == "exited" {
+			desc += fmt.Sprintf(" (exit code %d)", state.ExitCode)
+		}
+
+		return CriticalResult(checkDockerName, desc, metric)
+	}
+
+	// A container without a healthcheck has no health, or the status
+	// "none" with older engines.
+	health := ""
+	if state.Health != nil && state.Health.Status != "none" {
+		health = state.Health.Status
+	}
+
+	switch health {
+	case "":
+		return OKResult(checkDockerName, fmt.Sprintf("Container %s is running", name), metric)
+	case "healthy":
+		return OKResult(checkDockerName, fmt.Sprintf("Container %s is running and healthy", name), metric)
+	case "starting":
+		return WarningResult(checkDockerName, fmt.Sprintf("Container %s is running, its healthcheck is starting", name), metric)
+	case "unhealthy":
+		return CriticalResult(checkDockerName, fmt.Sprintf("Container %s is running and unhealthy, failing %d healthchecks in a row",
+			name, state.Health.FailingStreak), metric)
+	}
+
+	return UnknownResult(checkDockerName, fmt.Sprintf("Container %s is running with the unknown health %s", name, health), metric)
+}
+
+// CheckDockerWithHandlers checks the Docker container of options.Name
+// is running and, when it has a healthcheck, is healthy, reading its
+// state from the Docker Engine API with get. A container running and
+// healthy, or without a healthcheck, emits a good response. A
+// container whose healthcheck is still starting emits a warning
+// response, as it may yet become healthy. A container that is not
+// running, such as exited, paused or restarting, is unhealthy or does
+// not exist emits a critical response. The Docker Engine not being
+// reachable, such as a socket the user of the check may not connect
+// to, emits an unknown response, and not responding in time a
+// response in the state of options.TimeoutExit. The number of times
+// the container has been restarted is output as perfdata.
+func CheckDockerWithHandlers(options DockerCheckOptions, get func(string) (int, []byte, error)) (string, int) {
+	return runDockerCheck(options, get).Output()
+}
+
+// RunDockerCheck performs the container check of CheckDocker() and
+// returns its result, without output.
+func RunDockerCheck(options DockerCheckOptions) CheckResult {
+	socket := options.Socket
+	if socket == "" {
+		socket = defaultDockerSocket
+	}
+
+	timeout := options.Timeout
+	if timeout < 1 {
+		timeout = 10
+	}
+
+	return runDockerCheck(options, dockerEngineGetter(socket, time.Duration(timeout)*time.Second))
+}
+
+// CheckDocker executes CheckDockerWithHandlers(), reading the state of
+// the container from the Docker Engine API on options.Socket.
+//
+// Returns are those of CheckDockerWithHandlers()
+func CheckDocker(options DockerCheckOptions) (string, int) {
+	return RunDockerCheck(options).Output()
+}
@@ -0,0 +1,141 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckDocker(t *testing.T) {
+	inspection := func(state string, restarts int) string {
+		return `{"Id": "4f66ad9a0b2e", "Name": "/web", "RestartCount": ` + strconv.Itoa(restarts) + `, "State": ` + state + `}`
+	}
+
+	running := `{"Status": "running", "Running": true, "Paused": false, "Restarting": false, "ExitCode": 0}`
+	health := func(status string, failing int) string {
+		return `{"Status": "running", "Running": true, "Health": {"Status": "` + status + `", "FailingStreak": ` + strconv.Itoa(failing) + `, "Log": []}}`
+	}
+
+	get := func(status int, body string, err error) func(string) (int, []byte, error) {
+		return func(path string) (int, []byte, error) {
+			if path != "/containers/web/json" {
+				return http.StatusNotFound, []byte(`{"message": "page not found"}`), nil
+			}
+
+			return status, []byte(body), err
+		}
+	}
+
+	socketErr := &net.OpError{Op: "dial", Net: "unix", Err: os.NewSyscallError("connect", os.ErrPermission)}
+
+	type testItem struct {
+		description  string
+		name         string
+		get          func(string) (int, []byte, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Running", "web", get(http.StatusOK, inspection(running, 2), nil), statusCodeOK,
+			"CheckDocker OK - Container web is running | restarts=2;;;0"},
+		{"Name with a slash", "/web", get(http.StatusOK, inspection(running, 0), nil), statusCodeOK, "Container web is running"},
+		{"Healthy", "web", get(http.StatusOK, inspection(health("healthy", 0), 0), nil), statusCodeOK, "Container web is running and healthy"},
+		{"Health starting", "web", get(http.StatusOK, inspection(health("starting", 0), 0), nil), statusCodeWarning,
+			"CheckDocker WARNING - Container web is running, its healthcheck is starting"},
+		{"Unhealthy", "web", get(http.StatusOK, inspection(health("unhealthy", 3), 1), nil), statusCodeCritical,
+			"CheckDocker CRITICAL - Container web is running and unhealthy, failing 3 healthchecks in a row | restarts=1;;;0"},
+		{"No healthcheck", "web", get(http.StatusOK, inspection(health("none", 0), 0), nil), statusCodeOK, "Container web is running |"},
+		{"Exited", "web", get(http.StatusOK, inspection(`{"Status": "exited", "Running": false, "ExitCode": 137}`, 5), nil), statusCodeCritical,
+			"CheckDocker CRITICAL - Container web is exited (exit code 137) | restarts=5;;;0"},
+		{"Paused", "web", get(http.StatusOK, inspection(`{"Status": "paused", "Running": true, "Paused": true}`, 0), nil), statusCodeCritical,
+			"Container web is paused"},
+		{"Restarting", "web", get(http.StatusOK, inspection(`{"Status": "restarting", "Running": true, "Restarting": true}`, 4), nil), statusCodeCritical,
+			"Container web is restarting"},
+		{"Not found", "db", get(http.StatusOK, "", nil), statusCodeCritical, "CheckDocker CRITICAL - Container db does not exist"},
+		{"Engine error", "web", get(http.StatusInternalServerError, `{"message": "client version 1.99 is too new"}`, nil), statusCodeUnknown,
+			"Could not inspect container web: client version 1.99 is too new"},
+		{"Engine error without a message", "web", get(http.StatusBadGateway, "", nil), statusCodeUnknown, "Could not inspect container web: status 502"},
+		{"Socket denied", "web", get(0, "", socketErr), statusCodeUnknown,
+			"CheckDocker UNKNOWN - Could not query the Docker Engine at /var/run/docker.sock: dial unix: connect: permission denied"},
+		{"Engine down", "web", get(0, "", errors.New("connection refused")), statusCodeUnknown, "Could not query the Docker Engine"},
+		{"Malformed inspection", "web", get(http.StatusOK, "{", nil), statusCodeUnknown, "Could not parse the inspection of container web"},
+		{"No name", "", get(http.StatusOK, inspection(running, 0), nil), statusCodeUnknown, "A container name must be given"},
+	}
+
+	timedOut := &url.Error{Op: "Get", URL: "http://docker/containers/web/json", Err: &net.OpError{Op: "dial", Net: "unix", Err: timeoutError{}}}
+
+	for _, i := range testList {
+		msg, code := CheckDockerWithHandlers(DockerCheckOptions{Name: i.name}, i.get)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	if msg, _ := CheckDockerWithHandlers(DockerCheckOptions{Name: "web", MetricName: "web_restarts"}, get(http.StatusOK, inspection(running, 0), nil)); !strings.Contains(msg, "| web_restarts=0") {
+		t.Errorf("CheckDockerWithHandlers() should name the metric: %s", msg)
+	}
+
+	if msg, code := CheckDockerWithHandlers(DockerCheckOptions{Name: "web"}, get(0, "", timedOut)); code != statusCodeUnknown || !strings.Contains(msg, "Could not query the Docker Engine") {
+		t.Errorf("CheckDockerWithHandlers() should be UNKNOWN when the Docker Engine times out: %d %s", code, msg)
+	}
+
+	if msg, code := CheckDockerWithHandlers(DockerCheckOptions{Name: "web", TimeoutExit: "critical"}, get(0, "", timedOut)); code != statusCodeCritical || !strings.Contains(msg, "CheckDocker CRITICAL - Could not query the Docker Engine") {
+		t.Errorf("CheckDockerWithHandlers() should issue the timeout exit when the Docker Engine times out: %d %s", code, msg)
+	}
+}
+
+func TestDockerEngineGetter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The Docker Engine socket is a unix socket")
+	}
+
+	dir, err := ioutil.TempDir("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/containers/web%20app/json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(`{"RestartCount": 1, "State": {"Status": "running", "Running": true}}`))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	msg, code := CheckDockerWithHandlers(DockerCheckOptions{Name: "web app"}, dockerEngineGetter(socket, time.Second))
+	if code != statusCodeOK || !strings.Contains(msg, "Container web app is running | restarts=1;;;0") {
+		t.Errorf("CheckDockerWithHandlers() through the socket incorrect: %d %s", code, msg)
+	}
+
+	msg, code = CheckDocker(DockerCheckOptions{Name: "web", Socket: filepath.Join(dir, "missing.sock")})
+	if code != statusCodeUnknown || !strings.Contains(msg, "Could not query the Docker Engine at "+filepath.Join(dir, "missing.sock")) {
+		t.Errorf("CheckDocker() of a missing socket incorrect: %d %s", code, msg)
+	}
+}
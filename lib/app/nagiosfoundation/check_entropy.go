@@ -0,0 +1,72 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+const checkEntropyName = "CheckEntropy"
+
+const entropyAvailFile = "/proc/sys/kernel/random/entropy_avail"
+
+// runEntropyCheck performs the check of CheckEntropyWithHandler() and
+// returns its result.
+func runEntropyCheck(warning, critical int, readFile func(string) ([]byte, error)) CheckResult {
+	var entropy int
+	var err error
+
+	if readFile == nil {
+		err = errors.New("No read file service")
+	} else {
+		var data []byte
+		if data, err = readFile(entropyAvailFile); err == nil {
+			entropy, err = strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				err = fmt.Errorf("Could not parse available entropy: %s", err)
+			}
+		}
+	}
+
+	if err != nil {
+		return UnknownResult(checkEntropyName, err.Error())
+	}
+
+	state := StateOK
+	switch {
+	case entropy < critical:
+		state = StateCritical
+	case entropy < warning:
+		state = StateWarning
+	}
+
+	return NewCheckResult(checkEntropyName, state, fmt.Sprintf("%d bits of entropy available", entropy), PerfData{
+		Label:    "entropy",
+		Value:    float64(entropy),
+		Warning:  fmt.Sprintf("%d:", warning),
+		Critical: fmt.Sprintf("%d:", critical),
+		Min:      "0",
+	})
+}
+
+// CheckEntropyWithHandler reads the available kernel entropy using the
+// readFile function and emits a critical response if it's below the
+// critical argument, a warning response if it's below the warning
+// argument, and good response otherwise.
+func CheckEntropyWithHandler(warning, critical int, readFile func(string) ([]byte, error)) (string, int) {
+	return runEntropyCheck(warning, critical, readFile).Output()
+}
+
+// RunEntropyCheck performs the entropy check of CheckEntropy() and
+// returns its result, without output.
+func RunEntropyCheck(warning, critical int) CheckResult {
+	return runEntropyCheck(warning, critical, ioutil.ReadFile)
+}
+
+// CheckEntropy executes CheckEntropyWithHandler(), reading the
+// available entropy from /proc/sys/kernel/random/entropy_avail.
+func CheckEntropy(warning, critical int) (string, int) {
+	return RunEntropyCheck(warning, critical).Output()
+}
@@ -0,0 +1,48 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckEntropy(t *testing.T) {
+	readEntropy := func(value string) func(string) ([]byte, error) {
+		return func(string) ([]byte, error) {
+			return []byte(value), nil
+		}
+	}
+
+	readError := func(string) ([]byte, error) {
+		return nil, errors.New("read error")
+	}
+
+	type testItem struct {
+		description  string
+		readFile     func(string) ([]byte, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Entropy above thresholds", readEntropy("3754\n"), statusCodeOK, "entropy=3754;200:;100:;0"},
+		{"Entropy below warning", readEntropy("150\n"), statusCodeWarning, "150 bits of entropy available | entropy=150;200:;100:;0"},
+		{"Entropy below critical", readEntropy("42\n"), statusCodeCritical, statusTextCritical},
+		{"Entropy at warning", readEntropy("200"), statusCodeOK, statusTextOK},
+		{"Unparseable entropy", readEntropy("lots\n"), statusCodeUnknown, "Could not parse"},
+		{"Read error", readError, statusCodeUnknown, "read error"},
+		{"No read service", nil, statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckEntropyWithHandler(200, 100, i.readFile)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const checkFileName = "CheckFile"
+
+// fileCheckTypes lists the supported check types.
+var fileCheckTypes = []string{"exists", "age", "size"}
+
+// fileCheckMetricNames are the default metric names of each check
+// type.
+var fileCheckMetricNames = map[string]string{
+	"exists": "files",
+	"age":    "age",
+	"size":   "size",
+}
+
+// newestFile returns the most recently modified of the files
+// matching pattern, or nil when none match, along with the number
+// of files matching. Directories are not files and do not match.
+func newestFile(pattern string, glob func(string) ([]string, error), stat func(string) (os.FileInfo, error)) (string, os.FileInfo, int, error) {
+	matches, err := glob(pattern)
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	var newestPath string
+	var newest os.FileInfo
+	count := 0
+
+	for _, match := range matches {
+		// A file removed between matching and reading its details
+		// no longer matches.
+		info, err := stat(match)
+		if err != nil {
+			debugLog.Printf("Skipping %s: %s", match, err)
+			continue
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		count++
+
+		if newest == nil || info.ModTime().After(newest.ModTime()) {
+			newestPath, newest = match, info
+		}
+	}
+
+	return newestPath, newest, count, nil
+}
+
+// runFileCheck performs the check of CheckFileWithHandlers() and
+// returns its result.
+func runFileCheck(path, checkType, warning, critical, metricName string,
+	glob func(string) ([]string, error), stat func(string) (os.FileInfo, error)) CheckResult {
+	checkType = strings.ToLower(checkType)
+	if checkType == "" {
+		checkType = "exists"
+	}
+
+	if path == "" {
+		return CriticalResult(checkFileName, "A path must be specified.")
+	}
+
+	defaultMetricName, ok := fileCheckMetricNames[checkType]
+	if !ok {
+		return CriticalResult(checkFileName, fmt.Sprintf("Invalid check type (%s). Only \"%s\" are supported.",
+			checkType, strings.Join(fileCheckTypes, "\", \"")))
+	}
+
+	if metricName == "" {
+		metricName = defaultMetricName
+	}
+
+	newestPath, newest, count, err := newestFile(path, glob, stat)
+	if err != nil {
+		return UnknownResult(checkFileName, fmt.Sprintf("Error matching path %s: %s", path, err))
+	}
+
+	if newest == nil {
+		return CriticalResult(checkFileName, fmt.Sprintf("No file matches %s", path))
+	}
+
+	if checkType == "exists" {
+		return OKResult(checkFileName, fmt.Sprintf("%d files match %s, the newest is %s", count, path, newestPath),
+			PerfData{Label: metricName, Value: float64(count), Min: "0"})
+	}
+
+	var value float64
+	var checkInfo, uom string
+
+	if checkType == "age" {
+		seconds := int64(time.Since(newest.ModTime()).Seconds())
+		value, uom = float64(seconds), "s"
+		checkInfo = fmt.Sprintf("File %s was modified %ds ago", newestPath, seconds)
+	} else {
+		value, uom = float64(newest.Size()), "B"
+		checkInfo = fmt.Sprintf("File %s is %d bytes", newestPath, newest.Size())
+	}
+
+	thresholds, err := ParseThresholds(warning, critical)
+	if err != nil {
+		return UnknownResult(checkFileName, err.Error())
+	}
+
+	state, tripped := thresholds.Status(value)
+
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+	}
+
+	return NewCheckResult(checkFileName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: metricName,
+		Value: value,
+		UOM:   uom,
+		Min:   "0",
+	}))
+}
+
+// CheckFileWithHandlers checks the newest file matching the path,
+// which may be a pattern as accepted by filepath.Glob. The checkType
+// "exists" issues a critical response when no file matches. The
+// checkType "age" compares the seconds since the file was modified
+// and "size" compares the size of the file in bytes against the
+// warning and critical thresholds, which are Nagios ranges. For
+// these a critical response is issued when no file matches.
+func CheckFileWithHandlers(path, checkType, warning, critical, metricName string,
+	glob func(string) ([]string, error), stat func(string) (os.FileInfo, error)) (string, int) {
+	return runFileCheck(path, checkType, warning, critical, metricName, glob, stat).Output()
+}
+
+// RunFileCheck performs the file check of CheckFile() and returns its
+// result, without output.
+func RunFileCheck(path, checkType, warning, critical, metricName string) CheckResult {
+	return runFileCheck(path, checkType, warning, critical, metricName, filepath.Glob, os.Stat)
+}
+
+// CheckFile executes CheckFileWithHandlers(), passing it
+// filepath.Glob() and os.Stat().
+//
+// Returns are those of CheckFileWithHandlers()
+func CheckFile(path, checkType, warning, critical, metricName string) (string, int) {
+	return RunFileCheck(path, checkType, warning, critical, metricName).Output()
+}
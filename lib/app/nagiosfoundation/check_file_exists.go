@@ -6,36 +6,25 @@ import (
 	"strconv"
 )
 
-// CheckFileExists tests the assertion that one or more files matching specified pattern should or should not exist.
-func CheckFileExists(pattern string, negate bool) (string, int) {
-	var msg string
-	var retCode int
-	var checkStateText, msgString string
+// RunFileExistsCheck performs the check of CheckFileExists() and
+// returns its result, without output.
+func RunFileExistsCheck(pattern string, negate bool) CheckResult {
+	const checkName = "CheckFileExists"
 
 	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return UnknownResult(checkName, fmt.Sprintf("Error matching pattern %s: %s", pattern, err))
+	}
 
-	switch {
-	case err != nil:
-		checkStateText = statusTextUnknown
-		msgString = fmt.Sprintf("Error matching pattern %s: %s", pattern, err)
-		retCode = 3
-	default:
-		matchCount := len(matches)
-
-		switch {
-		case (matchCount == 0 && negate == false) ||
-			(matchCount > 0 && negate == true):
-			checkStateText = statusTextCritical
-			retCode = 2
-		case (matchCount == 0 && negate == true) ||
-			(matchCount > 0 && negate == false):
-			checkStateText = statusTextOK
-			retCode = 0
-		}
-
-		msgString = fmt.Sprintf("%s files matched pattern %s", strconv.Itoa(len(matches)), pattern)
+	state := StateOK
+	if (len(matches) == 0) != negate {
+		state = StateCritical
 	}
 
-	msg, _ = resultMessage("CheckFileExists", checkStateText, msgString)
-	return msg, retCode
+	return NewCheckResult(checkName, state, fmt.Sprintf("%s files matched pattern %s", strconv.Itoa(len(matches)), pattern))
+}
+
+// CheckFileExists tests the assertion that one or more files matching specified pattern should or should not exist.
+func CheckFileExists(pattern string, negate bool) (string, int) {
+	return RunFileExistsCheck(pattern, negate).Output()
 }
@@ -0,0 +1,94 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	files := []struct {
+		name string
+		size int
+		age  time.Duration
+	}{
+		{"backup-1.tar", 100, 3 * time.Hour},
+		{"backup-2.tar", 2048, 30 * time.Minute},
+		{"backup-3.tar", 10, 2 * time.Hour},
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := ioutil.WriteFile(path, make([]byte, f.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, now, now.Add(-f.age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "backup-9.tar"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	pattern := filepath.Join(dir, "backup-*.tar")
+	newest := filepath.Join(dir, "backup-2.tar")
+
+	type testItem struct {
+		description  string
+		path         string
+		checkType    string
+		warning      string
+		critical     string
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Exists", pattern, "exists", "", "", statusCodeOK, "3 files match " + pattern + ", the newest is " + newest + " | files=3;;;0"},
+		{"Exists by default", newest, "", "", "", statusCodeOK, "1 files match"},
+		{"Missing", filepath.Join(dir, "missing"), "exists", "", "", statusCodeCritical, "No file matches"},
+		{"Age of newest", pattern, "age", "3600", "7200", statusCodeOK, "File " + newest + " was modified 1800s ago | age=1800s;3600;7200;0"},
+		{"Age over warning", pattern, "age", "600", "7200", statusCodeWarning, "(expected 0-600)"},
+		{"Age over critical", pattern, "AGE", "60", "600", statusCodeCritical, "age="},
+		{"Age of missing file", filepath.Join(dir, "missing-*"), "age", "60", "600", statusCodeCritical, "No file matches"},
+		{"Size of newest", pattern, "size", "1024:", "1:", statusCodeOK, "File " + newest + " is 2048 bytes | size=2048B;1024:;1:;0"},
+		{"Size too small", pattern, "size", "4096:", "1:", statusCodeWarning, "(expected at least 4096)"},
+		{"Invalid threshold", pattern, "size", "big", "", statusCodeUnknown, "Invalid range"},
+		{"Invalid check type", pattern, "owner", "", "", statusCodeCritical, "Invalid check type (owner)"},
+		{"No path", "", "exists", "", "", statusCodeCritical, "A path must be specified"},
+		{"Bad pattern", filepath.Join(dir, "["), "exists", "", "", statusCodeUnknown, "Error matching path"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckFile(i.path, i.checkType, i.warning, i.critical, "")
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	failingStat := func(string) (os.FileInfo, error) {
+		return nil, errors.New("permission denied")
+	}
+
+	if _, code := CheckFileWithHandlers(pattern, "age", "60", "600", "", filepath.Glob, failingStat); code != statusCodeCritical {
+		t.Errorf("CheckFileWithHandlers() should not match files it cannot read, returned %d", code)
+	}
+}
@@ -0,0 +1,169 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const checkHeartbeatName = "CheckHeartbeat"
+
+// heartbeatFormats lists the supported formats of a heartbeat, the
+// modification time of the file or a timestamp in its content.
+var heartbeatFormats = []string{"mtime", "rfc3339", "unix"}
+
+// HeartbeatCheckOptions contains the options for a heartbeat check.
+type HeartbeatCheckOptions struct {
+	// The heartbeat file, touched or written by a job on each
+	// successful cycle.
+	Path string
+
+	// Where the time of the last heartbeat is read from, "mtime" for
+	// the modification time of the file, "rfc3339" for a timestamp
+	// such as "2019-06-04T15:04:05Z" or "unix" for the seconds since
+	// the epoch, such as "1559660645", on the first line of the file.
+	// Defaults to "mtime".
+	ContentFormat string
+
+	// The warning and critical thresholds, Nagios ranges of the age
+	// of the heartbeat in seconds. An empty threshold is not checked.
+	Warning  string
+	Critical string
+
+	// The name of the metric in the nagios output. Defaults to "age".
+	MetricName string
+}
+
+// parseHeartbeat returns the time of the heartbeat in the content of a
+// heartbeat file, the first line of the content in the format,
+// "rfc3339" or "unix".
+func parseHeartbeat(content []byte, format string) (time.Time, error) {
+	text := strings.TrimSpace(strings.SplitN(string(content), "\n", 2)[0])
+	if text == "" {
+		return time.Time{}, fmt.Errorf("no timestamp in the file")
+	}
+
+	if format == "rfc3339" {
+		return time.Parse(time.RFC3339, text)
+	}
+
+	seconds, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a unix time", text)
+	}
+
+	// A float holds a unix time to well under a microsecond, so the
+	// fraction is read to the millisecond.
+	whole, fraction := math.Modf(seconds)
+
+	return time.Unix(int64(whole), int64(math.Round(fraction*1000))*int64(time.Millisecond)), nil
+}
+
+// runHeartbeatCheck performs the check of CheckHeartbeatWithHandlers()
+// and returns its result.
+func runHeartbeatCheck(options HeartbeatCheckOptions, stat func(string) (os.FileInfo, error),
+	readFile func(string) ([]byte, error), now func() time.Time) CheckResult {
+	if options.Path == "" {
+		return UnknownResult(checkHeartbeatName, "A path must be specified.")
+	}
+
+	format := strings.ToLower(options.ContentFormat)
+	if format == "" {
+		format = "mtime"
+	}
+
+	switch format {
+	case "mtime", "rfc3339", "unix":
+	default:
+		return UnknownResult(checkHeartbeatName, fmt.Sprintf("Invalid content format (%s). Only \"%s\" are supported.",
+			options.ContentFormat, strings.Join(heartbeatFormats, "\", \"")))
+	}
+
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkHeartbeatName, err.Error())
+	}
+
+	metricName := options.MetricName
+	if metricName == "" {
+		metricName = "age"
+	}
+
+	var heartbeat time.Time
+
+	if format == "mtime" {
+		var info os.FileInfo
+		if info, err = stat(options.Path); err == nil {
+			heartbeat = info.ModTime()
+		}
+	} else {
+		var content []byte
+		if content, err = readFile(options.Path); err == nil {
+			if heartbeat, err = parseHeartbeat(content, format); err != nil {
+				return CriticalResult(checkHeartbeatName,
+					fmt.Sprintf("Could not parse the heartbeat %s as %s: %s", options.Path, format, err))
+			}
+		}
+	}
+
+	if os.IsNotExist(err) {
+		err = &CheckError{Kind: ErrTargetNotFound, Err: fmt.Errorf("Heartbeat %s does not exist", options.Path)}
+	} else if err != nil {
+		err = wrapError(err, "Could not read heartbeat %s: %s", options.Path, err)
+	}
+
+	if err != nil {
+		return errorResult(checkHeartbeatName, err.Error(), err)
+	}
+
+	seconds := int64(now().Sub(heartbeat).Seconds())
+	state, tripped := thresholds.Status(float64(seconds))
+
+	var checkInfo string
+	if seconds < 0 {
+		checkInfo = fmt.Sprintf("Heartbeat %s is dated %ds in the future", options.Path, -seconds)
+	} else {
+		checkInfo = fmt.Sprintf("Heartbeat %s is %ds old", options.Path, seconds)
+	}
+
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+	}
+
+	return NewCheckResult(checkHeartbeatName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: metricName,
+		Value: float64(seconds),
+		UOM:   "s",
+	}))
+}
+
+// CheckHeartbeatWithHandlers compares the age of the heartbeat of a
+// job at options.Path as of now against the options.Warning and
+// options.Critical thresholds, catching a job that stopped reporting.
+// The time of the heartbeat is the modification time of the file read
+// with stat, or with options.ContentFormat a timestamp in its content
+// read with readFile. A heartbeat that does not exist or cannot be
+// parsed emits a critical response, as the job is not reporting. The
+// age in seconds is output as perfdata.
+func CheckHeartbeatWithHandlers(options HeartbeatCheckOptions, stat func(string) (os.FileInfo, error),
+	readFile func(string) ([]byte, error), now func() time.Time) (string, int) {
+	return runHeartbeatCheck(options, stat, readFile, now).Output()
+}
+
+// RunHeartbeatCheck performs the heartbeat check of CheckHeartbeat()
+// and returns its result, without output.
+func RunHeartbeatCheck(options HeartbeatCheckOptions) CheckResult {
+	return runHeartbeatCheck(options, os.Stat, ioutil.ReadFile, time.Now)
+}
+
+// CheckHeartbeat executes CheckHeartbeatWithHandlers(), passing it
+// os.Stat(), ioutil.ReadFile() and time.Now().
+//
+// Returns are those of CheckHeartbeatWithHandlers()
+func CheckHeartbeat(options HeartbeatCheckOptions) (string, int) {
+	return RunHeartbeatCheck(options).Output()
+}
@@ -0,0 +1,101 @@
+package nagiosfoundation
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHeartbeat(t *testing.T) {
+	type testItem struct {
+		description string
+		content     string
+		format      string
+		expected    time.Time
+		expectError bool
+	}
+
+	testList := []testItem{
+		{"RFC3339", "2019-06-04T15:04:05Z\n", "rfc3339", time.Date(2019, 6, 4, 15, 4, 5, 0, time.UTC), false},
+		{"RFC3339 with offset", "2019-06-04T17:04:05.5+02:00", "rfc3339", time.Date(2019, 6, 4, 15, 4, 5, 500000000, time.UTC), false},
+		{"Unix time", " 1559660645 \nrun 42 ok\n", "unix", time.Unix(1559660645, 0), false},
+		{"Fractional unix time", "1559660645.1", "unix", time.Unix(1559660645, 100000000), false},
+		{"Unix time as RFC3339", "1559660645", "rfc3339", time.Time{}, true},
+		{"Garbage unix time", "yesterday", "unix", time.Time{}, true},
+		{"Empty file", "\n", "unix", time.Time{}, true},
+	}
+
+	for _, i := range testList {
+		heartbeat, err := parseHeartbeat([]byte(i.content), i.format)
+
+		if (err != nil) != i.expectError {
+			t.Errorf("%s: Expected Error: %t, Actual Error: %v", i.description, i.expectError, err)
+		}
+
+		if err == nil && !heartbeat.Equal(i.expected) {
+			t.Errorf("%s: Expected: %s, Actual: %s", i.description, i.expected, heartbeat)
+		}
+	}
+}
+
+func TestCheckHeartbeat(t *testing.T) {
+	now := time.Date(2019, 6, 4, 16, 0, 0, 0, time.UTC)
+
+	type testItem struct {
+		description  string
+		options      HeartbeatCheckOptions
+		modTime      time.Time
+		content      string
+		err          error
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Fresh mtime", HeartbeatCheckOptions{Path: "/run/job.hb", Warning: "600", Critical: "1800"}, now.Add(-42 * time.Second), "", nil, statusCodeOK,
+			"CheckHeartbeat OK - Heartbeat /run/job.hb is 42s old | age=42s;600;1800"},
+		{"Stale mtime", HeartbeatCheckOptions{Path: "/run/job.hb", Warning: "600", Critical: "1800"}, now.Add(-20 * time.Minute), "", nil, statusCodeWarning,
+			"Heartbeat /run/job.hb is 1200s old (expected 0-600)"},
+		{"RFC3339 content", HeartbeatCheckOptions{Path: "/run/job.hb", ContentFormat: "RFC3339", Critical: "1800", MetricName: "job_age"}, now, "2019-06-04T15:00:00Z\n", nil, statusCodeCritical,
+			"Heartbeat /run/job.hb is 3600s old (expected 0-1800) | job_age=3600s;;1800"},
+		{"Unix content", HeartbeatCheckOptions{Path: "/run/job.hb", ContentFormat: "unix", Warning: "600"}, now, "1559663940", nil, statusCodeOK,
+			"is 60s old"},
+		{"Future heartbeat", HeartbeatCheckOptions{Path: "/run/job.hb", ContentFormat: "unix", Warning: "600"}, now, "1559664030", nil, statusCodeWarning,
+			"Heartbeat /run/job.hb is dated 30s in the future (expected 0-600) | age=-30s;600"},
+		{"Missing heartbeat", HeartbeatCheckOptions{Path: "/run/job.hb", ContentFormat: "unix"}, now, "", os.ErrNotExist, statusCodeCritical,
+			"Heartbeat /run/job.hb does not exist"},
+		{"Missing mtime heartbeat", HeartbeatCheckOptions{Path: "/run/job.hb"}, now, "", os.ErrNotExist, statusCodeCritical,
+			"Heartbeat /run/job.hb does not exist"},
+		{"Unparseable heartbeat", HeartbeatCheckOptions{Path: "/run/job.hb", ContentFormat: "rfc3339"}, now, "last run: ok", nil, statusCodeCritical,
+			"Could not parse the heartbeat /run/job.hb as rfc3339"},
+		{"Unreadable heartbeat", HeartbeatCheckOptions{Path: "/run/job.hb"}, now, "", os.ErrPermission, statusCodeUnknown,
+			"Could not read heartbeat /run/job.hb: permission denied"},
+		{"Invalid format", HeartbeatCheckOptions{Path: "/run/job.hb", ContentFormat: "iso"}, now, "", nil, statusCodeUnknown,
+			"Invalid content format (iso)"},
+		{"Invalid threshold", HeartbeatCheckOptions{Path: "/run/job.hb", Warning: "old"}, now, "", nil, statusCodeUnknown, "Invalid range"},
+		{"No path", HeartbeatCheckOptions{}, now, "", nil, statusCodeUnknown, "A path must be specified"},
+	}
+
+	for _, i := range testList {
+		stat := func(string) (os.FileInfo, error) {
+			if i.err != nil {
+				return nil, i.err
+			}
+
+			return testModTimeFileInfo{modTime: i.modTime}, nil
+		}
+
+		readFile := func(string) ([]byte, error) { return []byte(i.content), i.err }
+
+		msg, code := CheckHeartbeatWithHandlers(i.options, stat, readFile, func() time.Time { return now })
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+	}
+}
@@ -1,11 +1,19 @@
 package nagiosfoundation
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/PaesslerAG/gval"
@@ -95,32 +103,358 @@ func evaluateExpression(actualValue interface{}, expression, path string) (int,
 	return retCode, responseStateText, checkMsg
 }
 
+// HTTPCheckOptions are the options of CheckHTTPWithOptions().
+type HTTPCheckOptions struct {
+	// The URL to request.
+	URL string
+
+	// Follow redirects and treat a redirect response as OK.
+	Redirect bool
+
+	// The seconds to wait for the response. A request that does not
+	// complete in time reports the state selected by TimeoutExit.
+	Timeout     int
+	TimeoutExit string
+
+	// The expected response format, such as "json", and the path
+	// in the response to compare against the expected value or
+	// expression.
+	Format        string
+	Path          string
+	ExpectedValue string
+	Expression    string
+
+	// The expected status codes, a comma separated list of codes
+	// such as "200" or classes such as "2xx". When empty a status
+	// of 400 or over is critical and 300 to 399 a warning.
+	ExpectedStatus string
+
+	// A string the response body must contain.
+	ExpectString string
+
+	// The warning and critical thresholds of the response time in
+	// seconds as Nagios ranges.
+	Warning  string
+	Critical string
+
+	// Skip verifying the TLS certificate of the server.
+	Insecure bool
+
+	// The PEM files of the client certificate and its key presented
+	// to a server requiring mutual TLS. Without ClientKey the key is
+	// read from the ClientCert file.
+	ClientCert string
+	ClientKey  string
+
+	// A PEM file of the CA certificates trusted to sign the
+	// certificate of the server, such as a private CA, in place of the
+	// CAs of the system.
+	CAFile string
+
+	// The IP version connected with, 4 or 6, or 0 for either.
+	IPVersion int
+
+	// The addresses of a host resolving to several that must accept
+	// a connection, "any" for one of them, the default, or "all".
+	Addresses string
+}
+
+// httpDialer connects the requests of a check, resolving the host
+// itself so the time taken resolving it is known and the addresses
+// can be limited to an IP version.
+type httpDialer struct {
+	ipVersion int
+	mode      string
+	lookup    func(string) ([]net.IP, error)
+	dial      func(context.Context, string, string) (net.Conn, error)
+
+	mutex   sync.Mutex
+	dnsTime time.Duration
+}
+
+// DialContext resolves the host of the address and connects to one of
+// its addresses, trying each in turn, or with the "all" mode connects
+// to every address, failing unless each accepts the connection, and
+// returns the first connection.
+func (d *httpDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, elapsed, err := resolveAddresses(host, d.ipVersion, d.lookup)
+
+	d.mutex.Lock()
+	d.dnsTime += elapsed
+	d.mutex.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if d.ipVersion != 0 {
+		network = fmt.Sprintf("tcp%d", d.ipVersion)
+	}
+
+	var conns []net.Conn
+	for _, ip := range ips {
+		conn, dialErr := d.dial(ctx, network, net.JoinHostPort(ip.String(), port))
+		if dialErr != nil {
+			err = dialErr
+
+			if d.mode == addressesAll {
+				break
+			}
+
+			continue
+		}
+
+		conns = append(conns, conn)
+		if d.mode == addressesAny {
+			return conn, nil
+		}
+	}
+
+	if d.mode == addressesAll && err != nil || len(conns) == 0 {
+		for _, conn := range conns {
+			conn.Close()
+		}
+
+		return nil, err
+	}
+
+	for _, conn := range conns[1:] {
+		conn.Close()
+	}
+
+	return conns[0], nil
+}
+
+// resolveTime returns the total time taken resolving hosts.
+func (d *httpDialer) resolveTime() time.Duration {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.dnsTime
+}
+
+// httpTLSConfig returns the TLS configuration of the request for the
+// options, or nil to use that of the default transport.
+func httpTLSConfig(options HTTPCheckOptions) (*tls.Config, error) {
+	if !options.Insecure && options.ClientCert == "" && options.ClientKey == "" && options.CAFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: options.Insecure}
+
+	if options.ClientKey != "" && options.ClientCert == "" {
+		return nil, fmt.Errorf("The client key (--client_key) is given without a client certificate (--client_cert)")
+	}
+
+	if options.ClientCert != "" {
+		keyFile := options.ClientKey
+		if keyFile == "" {
+			keyFile = options.ClientCert
+		}
+
+		certificate, err := tls.LoadX509KeyPair(options.ClientCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("The client certificate (--client_cert) could not be loaded: %s", err)
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	if options.CAFile != "" {
+		data, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("The CA file (--ca_file) could not be read: %s", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("The CA file (--ca_file) %s contains no PEM certificates", options.CAFile)
+		}
+
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// matchesStatus reports whether the status matches one of the
+// expected statuses, a comma separated list of status codes such as
+// "200" or classes such as "2xx".
+func matchesStatus(status int, expected string) (bool, error) {
+	matched := false
+
+	for _, item := range strings.Split(expected, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+
+		if len(item) == 3 && strings.HasSuffix(item, "xx") && item[0] >= '1' && item[0] <= '5' {
+			if status/100 == int(item[0]-'0') {
+				matched = true
+			}
+
+			continue
+		}
+
+		code, err := strconv.Atoi(item)
+		if err != nil || code < 100 || code > 599 {
+			return false, fmt.Errorf("\"%s\" is not a status code such as 200 or a class such as 2xx", item)
+		}
+
+		if status == code {
+			matched = true
+		}
+	}
+
+	return matched, nil
+}
+
+// isDNSError reports whether the request failed resolving the host.
+func isDNSError(err error) bool {
+	for {
+		switch e := err.(type) {
+		case *net.DNSError:
+			return true
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		default:
+			return false
+		}
+	}
+}
+
+// isTLSError reports whether the request failed the TLS handshake,
+// such as the certificate of the server not being trusted or the
+// server rejecting the client certificate.
+func isTLSError(err error) bool {
+	for {
+		switch e := err.(type) {
+		case x509.UnknownAuthorityError, x509.CertificateInvalidError, x509.HostnameError, tls.RecordHeaderError:
+			return true
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		case nil:
+			return false
+		default:
+			// The alerts sent by the server and most handshake errors
+			// are not exported, so they are known by their text.
+			return strings.HasPrefix(e.Error(), "tls: ") || strings.HasPrefix(e.Error(), "remote error: tls: ")
+		}
+	}
+}
+
 // CheckHTTP attempts an HTTP request against the provided url, reporting the HTTP response code and overall request state.
-func CheckHTTP(url string, redirect bool, timeout int, format, path, expectedValue, expression string) (string, int) {
+// A request that does not complete within timeout seconds reports the state selected by timeoutExit.
+func CheckHTTP(url string, redirect bool, timeout int, format, path, expectedValue, expression, timeoutExit string) (string, int) {
+	return CheckHTTPWithOptions(HTTPCheckOptions{
+		URL:           url,
+		Redirect:      redirect,
+		Timeout:       timeout,
+		TimeoutExit:   timeoutExit,
+		Format:        format,
+		Path:          path,
+		ExpectedValue: expectedValue,
+		Expression:    expression,
+	})
+}
+
+// CheckHTTPWithOptions attempts an HTTP request against the URL in the options, reporting the HTTP response code,
+// response time and overall request state. A host that cannot be resolved is unknown rather than critical, and a failed
+// TLS handshake is critical, with the TLS error logged as a diagnostic message.
+func CheckHTTPWithOptions(options HTTPCheckOptions) (string, int) {
+	return RunHTTPCheck(options).Output()
+}
+
+// RunHTTPCheck performs the request of CheckHTTPWithOptions() and
+// returns its result, without output.
+func RunHTTPCheck(options HTTPCheckOptions) CheckResult {
 	const checkName = "CheckHttp"
-	var retCode int
-	var msg string
+
+	url, format, path, timeout := options.URL, options.Format, options.Path, options.Timeout
 
 	acceptText, err := getAcceptText(format)
 	if err != nil {
-		msg, _ = resultMessage(checkName, statusTextCritical, fmt.Sprintf("The format (--format) \"%s\" is not valid. The only valid value is \"json\".", format))
+		return CriticalResult(checkName, fmt.Sprintf("The format (--format) \"%s\" is not valid. The only valid value is \"json\".", format))
+	}
+
+	if err := checkTimeoutExit(options.TimeoutExit); err != nil {
+		return CriticalResult(checkName, err.Error())
+	}
+
+	if options.ExpectedStatus != "" {
+		if _, err := matchesStatus(http.StatusOK, options.ExpectedStatus); err != nil {
+			return CriticalResult(checkName, fmt.Sprintf("The expected status (--expected_status) %s.", err))
+		}
+	}
+
+	tlsConfig, err := httpTLSConfig(options)
+	if err != nil {
+		return CriticalResult(checkName, err.Error()+".")
+	}
 
-		return msg, 2
+	if err := checkIPVersion(options.IPVersion); err != nil {
+		return UnknownResult(checkName, err.Error())
 	}
 
-	status, body, _ := statusCode(url, timeout, acceptText)
+	mode, err := parseAddressesMode(options.Addresses)
+	if err != nil {
+		return UnknownResult(checkName, err.Error())
+	}
+
+	dialer := &httpDialer{ipVersion: options.IPVersion, mode: mode, lookup: net.LookupIP, dial: (&net.Dialer{}).DialContext}
+
+	// The time taken resolving the host is reported on its own rather
+	// than as part of the response time.
+	start := time.Now()
+	status, body, err := statusCode(url, timeout, acceptText, options.Redirect, tlsConfig, dialer.DialContext)
+	dnsTime := dialer.resolveTime()
+	elapsed := (time.Since(start) - dnsTime).Seconds()
 
-	retCode, responseStateText := evaluateStatusCode(status, redirect)
+	if err != nil {
+		debugLog.Printf("Request to %s failed: %s", url, err)
+	}
+
+	if err == context.DeadlineExceeded {
+		return timeoutResult(checkName, options.TimeoutExit, fmt.Sprintf("Url %s timed out after %ds", url, timeout))
+	}
+
+	if isDNSError(err) {
+		return UnknownResult(checkName, fmt.Sprintf("Url %s could not be resolved: %s", url, err))
+	}
+
+	if isTLSError(err) {
+		return CriticalResult(checkName, fmt.Sprintf("Url %s failed the TLS handshake, see --verbose for the TLS error", url))
+	}
+
+	retCode, responseStateText := evaluateStatusCode(status, options.Redirect)
 	responseCode := strconv.Itoa(status)
 
 	var checkMsg = ""
+	if status != -1 && options.ExpectedStatus != "" {
+		if matched, _ := matchesStatus(status, options.ExpectedStatus); matched {
+			retCode = 0
+			responseStateText = statusTextOK
+		} else {
+			retCode = 2
+			responseStateText = statusTextCritical
+			checkMsg = fmt.Sprintf(", expected %s", options.ExpectedStatus)
+		}
+	}
+
 	if retCode == 0 && len(format) > 0 && len(path) > 0 {
 		var queryValue string
 
 		switch {
 		case format == "json":
-			expectedValueLen := len(expectedValue)
-			expressionLen := len(expression)
+			expectedValueLen := len(options.ExpectedValue)
+			expressionLen := len(options.Expression)
 
 			value := gojsonq.New().JSONString(body).Find(path)
 
@@ -134,9 +468,9 @@ func CheckHTTP(url string, redirect bool, timeout int, format, path, expectedVal
 				checkMsg = fmt.Sprintf(". Both --expectedValue and --expression given but only one is used")
 			} else if expectedValueLen > 0 {
 				queryValue = fmt.Sprintf("%v", value)
-				retCode, responseStateText, checkMsg = evaluateExpectedValue(queryValue, expectedValue, path)
+				retCode, responseStateText, checkMsg = evaluateExpectedValue(queryValue, options.ExpectedValue, path)
 			} else if expressionLen > 0 {
-				retCode, responseStateText, checkMsg = evaluateExpression(value, expression, path)
+				retCode, responseStateText, checkMsg = evaluateExpression(value, options.Expression, path)
 			} else {
 				retCode = 2
 				responseStateText = statusTextCritical
@@ -145,29 +479,99 @@ func CheckHTTP(url string, redirect bool, timeout int, format, path, expectedVal
 		}
 	}
 
-	msg, _ = resultMessage(checkName, responseStateText, fmt.Sprintf("Url %s responded with %s%s", url, responseCode, checkMsg))
+	if retCode == 0 && options.ExpectString != "" {
+		if strings.Contains(body, options.ExpectString) {
+			checkMsg += fmt.Sprintf(". The response contains %q", options.ExpectString)
+		} else {
+			retCode = 2
+			responseStateText = statusTextCritical
+			checkMsg += fmt.Sprintf(". The response does not contain %q", options.ExpectString)
+		}
+	}
 
-	return msg, retCode
+	if status == -1 {
+		return CheckResult{
+			Name:    checkName,
+			Status:  responseStateText,
+			Code:    retCode,
+			Message: fmt.Sprintf("Url %s responded with %s%s", url, responseCode, checkMsg),
+		}
+	}
+
+	// A slow response only matters once the response is otherwise
+	// good.
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if retCode == 0 {
+		if err != nil {
+			return UnknownResult(checkName, err.Error())
+		}
+
+		if state, tripped := thresholds.Status(elapsed); state != StateOK {
+			retCode = state.ExitCode()
+			responseStateText = state.String()
+			checkMsg += fmt.Sprintf(". The response time of %.3fs is outside %s", elapsed, tripped.Expected())
+		}
+	}
+
+	perfData := thresholds.Metric(PerfData{
+		Label: "time",
+		Value: math.Round(elapsed*1000) / 1000,
+		UOM:   "s",
+		Min:   "0",
+	})
+
+	return NewCheckResult(checkName, State(retCode), fmt.Sprintf("Url %s responded with %s in %.3fs%s", url, responseCode, elapsed, checkMsg),
+		perfData, dnsTimeMetric(dnsTime))
 }
 
-func statusCode(url string, timeout int, accept string) (int, string, error) {
-	http.DefaultClient.Timeout = time.Duration(timeout) * time.Second
+// statusCode performs the request and returns the response status code
+// and body, connecting with dial. If the request does not complete
+// within timeout seconds the returned error is
+// context.DeadlineExceeded. A nil tlsConfig uses the default TLS
+// configuration.
+func statusCode(url string, timeout int, accept string, redirect bool, tlsConfig *tls.Config,
+	dial func(context.Context, string, string) (net.Conn, error)) (int, string, error) {
+	var transport http.RoundTripper = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		DialContext:     dial,
+		TLSClientConfig: tlsConfig,
+	}
+
+	// Unless redirects are followed the transport is used directly
+	// so they are not. The client timeout would not apply, so the
+	// timeout is placed on the request context instead.
+	roundTrip := transport.RoundTrip
+	if redirect {
+		roundTrip = (&http.Client{Transport: transport}).Do
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
 
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return -1, "", err
 	}
 
+	request = request.WithContext(ctx)
 	request.Header.Set("accept", accept)
 
-	response, err := http.DefaultTransport.RoundTrip(request)
+	response, err := roundTrip(request)
 	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = ctx.Err()
+		}
+
 		return -1, "", err
 	}
 	defer response.Body.Close()
 
 	body, readErr := ioutil.ReadAll(response.Body)
 	if readErr != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			readErr = ctx.Err()
+		}
+
 		return -1, "", readErr
 	}
 
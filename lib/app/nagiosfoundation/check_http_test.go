@@ -1,10 +1,28 @@
 package nagiosfoundation
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCheckHTTP(t *testing.T) {
@@ -24,63 +42,63 @@ func TestCheckHTTP(t *testing.T) {
 
 	// Code 200
 	httpStatus = http.StatusOK
-	_, code := CheckHTTP(httpServer.URL, false, 1, format, path, expectedValue, "")
+	_, code := CheckHTTP(httpServer.URL, false, 1, format, path, expectedValue, "", "")
 	if code != 0 {
 		t.Error("CheckHTTP() should return code of 0 when on OK (200) response")
 	}
 
 	// Code 400
 	httpStatus = http.StatusBadRequest
-	_, code = CheckHTTP(httpServer.URL, false, 1, format, path, expectedValue, "")
+	_, code = CheckHTTP(httpServer.URL, false, 1, format, path, expectedValue, "", "")
 	if code != 2 {
 		t.Error("CheckHTTP() should return code of 2 when on bad request (400) response")
 	}
 
 	// Code 300
 	httpStatus = http.StatusMultipleChoices
-	_, code = CheckHTTP(httpServer.URL, false, 1, format, path, expectedValue, "")
+	_, code = CheckHTTP(httpServer.URL, false, 1, format, path, expectedValue, "", "")
 	if code != 1 {
 		t.Error("CheckHTTP() should return code of 2 when on multiple choices (300) response")
 	}
 
 	// Code 300 with redirect on
 	httpStatus = http.StatusMultipleChoices
-	_, code = CheckHTTP(httpServer.URL, true, 1, format, path, expectedValue, "")
+	_, code = CheckHTTP(httpServer.URL, true, 1, format, path, expectedValue, "", "")
 	if code != 0 {
 		t.Error("CheckHTTP() should return code of 0 when on multiple choices (300) with redirect response")
 	}
 
 	// Code 200 with format json and a match on expected value
 	httpStatus = http.StatusOK
-	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", idValueString, "")
+	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", idValueString, "", "")
 	if code != 0 {
 		t.Error("CheckHTTP() should return code of 0 when json path matches expected value")
 	}
 
 	// Code 200 with format json and failed match on expected value
 	httpStatus = http.StatusOK
-	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", "failmatch", "")
+	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", "failmatch", "", "")
 	if code != 2 {
 		t.Error("CheckHTTP() should return code of 2 when json path does not match expected value")
 	}
 
 	// Code 200 with format json and expression true
 	httpStatus = http.StatusOK
-	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", "", "!= \""+idValueString+"\"")
+	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", "", "!= \""+idValueString+"\"", "")
 	if code != 2 {
 		t.Error("CheckHTTP() should return code of 2 when json path causes expression to return false")
 	}
 
 	// Code 200 with format json and no expected value or expression
 	httpStatus = http.StatusOK
-	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", "", "")
+	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", "", "", "")
 	if code != 2 {
 		t.Error("CheckHTTP() should return code of 2 with json path but no expected value or expression")
 	}
 
 	// Code 200 with format json and but both expected value and expression given
 	httpStatus = http.StatusOK
-	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", "expectedvalue", "expression")
+	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", "expectedvalue", "expression", "")
 	if code != 2 {
 		t.Error("CheckHTTP() should return code of 2 with json path but no expected value or expression")
 	}
@@ -88,7 +106,7 @@ func TestCheckHTTP(t *testing.T) {
 	// Code 200 with format json and expression true using integer
 	responseBody = `{"id":` + idValueString + `}`
 	httpStatus = http.StatusOK
-	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", "", "== "+idValueString)
+	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "id", "", "== "+idValueString, "")
 	if code != 0 {
 		t.Errorf("CheckHTTP() should return code of 0 with json path but and comparison to int %d", idValue)
 	}
@@ -96,7 +114,7 @@ func TestCheckHTTP(t *testing.T) {
 	// Invalid format
 	responseBody = `{"id":` + idValueString + `}`
 	httpStatus = http.StatusOK
-	_, code = CheckHTTP(httpServer.URL, false, 1, "invalidformat", "id", "", "== "+idValueString)
+	_, code = CheckHTTP(httpServer.URL, false, 1, "invalidformat", "id", "", "== "+idValueString, "")
 	if code != 2 {
 		t.Errorf("CheckHTTP() should return code of 2 when given an invalid format")
 	}
@@ -104,7 +122,7 @@ func TestCheckHTTP(t *testing.T) {
 	// Invalid path
 	responseBody = `{"id":` + idValueString + `}`
 	httpStatus = http.StatusOK
-	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "invalidpath", "", "== "+idValueString)
+	_, code = CheckHTTP(httpServer.URL, false, 1, "json", "invalidpath", "", "== "+idValueString, "")
 	if code != 2 {
 		t.Errorf("CheckHTTP() should return code of 2 when given an invalid path")
 	}
@@ -114,14 +132,14 @@ func TestCheckHTTP(t *testing.T) {
 
 	// No server for connection
 	httpStatus = http.StatusOK
-	_, code = CheckHTTP(httpServer.URL, false, 1, format, path, expectedValue, "")
+	_, code = CheckHTTP(httpServer.URL, false, 1, format, path, expectedValue, "", "")
 	if code != 2 {
 		t.Error("CheckHTTP() should return code of 2 when no server is available")
 	}
 
 	// Invalid URL
 	httpStatus = http.StatusOK
-	_, code = CheckHTTP("invalid%url", false, 1, format, path, expectedValue, "")
+	_, code = CheckHTTP("invalid%url", false, 1, format, path, expectedValue, "", "")
 	if code != 2 {
 		t.Error("CheckHTTP() should return code of 2 when given an unparseable URL")
 	}
@@ -295,3 +313,291 @@ func TestEvaluateExpression(t *testing.T) {
 		t.Errorf("evaluateExpression() returned actual text of %s when expecting %s", actualText, expectedText)
 	}
 }
+
+func TestCheckHTTPTimeout(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer httpServer.Close()
+
+	type testItem struct {
+		description  string
+		timeoutExit  string
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Default timeout exit", "", statusCodeUnknown, statusTextUnknown + " - Url " + httpServer.URL + " timed out after 1s"},
+		{"Critical timeout exit", "critical", statusCodeCritical, statusTextCritical},
+		{"Warning timeout exit", "WARNING", statusCodeWarning, statusTextWarning},
+		{"Invalid timeout exit", "ok", statusCodeCritical, "--timeout_exit"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckHTTP(httpServer.URL, false, 1, "", "", "", "", i.timeoutExit)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+func TestCheckHTTPWithOptions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Status: healthy"))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	mux.HandleFunc("/teapot", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	tlsServer := httptest.NewTLSServer(mux)
+	defer tlsServer.Close()
+
+	type testItem struct {
+		description  string
+		options      HTTPCheckOptions
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"OK with response time", HTTPCheckOptions{URL: httpServer.URL}, 0, "| time="},
+		{"Redirect not followed", HTTPCheckOptions{URL: httpServer.URL + "/moved"}, 1, "responded with 302"},
+		{"Redirect followed", HTTPCheckOptions{URL: httpServer.URL + "/moved", Redirect: true}, 0, "responded with 200"},
+		{"Expected status class", HTTPCheckOptions{URL: httpServer.URL + "/teapot", ExpectedStatus: "2xx,418"}, 0, "responded with 418"},
+		{"Unexpected status", HTTPCheckOptions{URL: httpServer.URL, ExpectedStatus: "204"}, 2, "responded with 200 in"},
+		{"Invalid expected status", HTTPCheckOptions{URL: httpServer.URL, ExpectedStatus: "2x"}, 2, "--expected_status"},
+		{"Body contains string", HTTPCheckOptions{URL: httpServer.URL, ExpectString: "healthy"}, 0, `contains "healthy"`},
+		{"Body missing string", HTTPCheckOptions{URL: httpServer.URL, ExpectString: "degraded"}, 2, `does not contain "degraded"`},
+		{"Response time warning", HTTPCheckOptions{URL: httpServer.URL, Warning: "0.000001", Critical: "10"}, 1, ";0.000001;10;0"},
+		{"Response time critical", HTTPCheckOptions{URL: httpServer.URL, Critical: "0.000001"}, 2, "is outside"},
+		{"Invalid response time threshold", HTTPCheckOptions{URL: httpServer.URL, Warning: "fast"}, 3, ""},
+		{"Unverified certificate", HTTPCheckOptions{URL: tlsServer.URL, Timeout: 1}, 2, "CheckHttp CRITICAL - Url " + tlsServer.URL + " failed the TLS handshake"},
+		{"Insecure", HTTPCheckOptions{URL: tlsServer.URL, Insecure: true}, 0, "responded with 200"},
+		{"DNS time", HTTPCheckOptions{URL: httpServer.URL}, 0, " dns_time=0s;;;0"},
+		{"IPv4", HTTPCheckOptions{URL: httpServer.URL, IPVersion: 4, Addresses: "all"}, 0, "responded with 200"},
+		{"IPv6 of an IPv4 address", HTTPCheckOptions{URL: httpServer.URL, IPVersion: 6}, 3, "could not be resolved: lookup 127.0.0.1: no IPv6 address"},
+		{"Invalid IP version", HTTPCheckOptions{URL: httpServer.URL, IPVersion: 5}, 3, "Invalid IP version (5)"},
+	}
+
+	for _, i := range testList {
+		if i.options.Timeout == 0 {
+			i.options.Timeout = 1
+		}
+
+		msg, code := CheckHTTPWithOptions(i.options)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+// writeTestClientCertificate writes a self-signed client certificate
+// and its key to PEM files in dir, returning their paths along with
+// the certificate.
+func writeTestClientCertificate(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nagios"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile, certificate
+}
+
+func TestCheckHTTPClientCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkhttp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile, clientCertificate := writeTestClientCertificate(t, dir)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCertificate)
+
+	tlsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Status: healthy"))
+	}))
+	tlsServer.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The certificate and key in a single file.
+	bundleFile := filepath.Join(dir, "bundle.pem")
+	certData, _ := ioutil.ReadFile(certFile)
+	keyData, _ := ioutil.ReadFile(keyFile)
+	if err := ioutil.WriteFile(bundleFile, append(certData, keyData...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	type testItem struct {
+		description  string
+		options      HTTPCheckOptions
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Client certificate", HTTPCheckOptions{ClientCert: certFile, ClientKey: keyFile, CAFile: caFile}, 0, "responded with 200"},
+		{"Certificate and key in one file", HTTPCheckOptions{ClientCert: bundleFile, CAFile: caFile}, 0, "responded with 200"},
+		{"Client certificate with insecure", HTTPCheckOptions{ClientCert: certFile, ClientKey: keyFile, Insecure: true}, 0, "responded with 200"},
+		{"No client certificate", HTTPCheckOptions{CAFile: caFile}, 2, "failed the TLS handshake"},
+		{"Server not trusted", HTTPCheckOptions{ClientCert: certFile, ClientKey: keyFile}, 2, "failed the TLS handshake"},
+		{"Key without certificate", HTTPCheckOptions{ClientKey: keyFile}, 2, "given without a client certificate"},
+		{"Missing certificate", HTTPCheckOptions{ClientCert: filepath.Join(dir, "missing.pem")}, 2, "The client certificate (--client_cert) could not be loaded"},
+		{"Missing CA file", HTTPCheckOptions{CAFile: filepath.Join(dir, "missing.pem")}, 2, "The CA file (--ca_file) could not be read"},
+		{"CA file without certificates", HTTPCheckOptions{CAFile: keyFile}, 2, "contains no PEM certificates"},
+	}
+
+	for _, i := range testList {
+		i.options.URL = tlsServer.URL
+		i.options.Timeout = 1
+
+		msg, code := CheckHTTPWithOptions(i.options)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+func TestIsDNSError(t *testing.T) {
+	dnsError := &net.DNSError{Err: "no such host", Name: "nosuchhost.invalid"}
+
+	if !isDNSError(&url.Error{Op: "Get", URL: "http://nosuchhost.invalid", Err: &net.OpError{Op: "dial", Err: dnsError}}) {
+		t.Error("isDNSError() should find the DNS error within a client error")
+	}
+
+	if isDNSError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}) {
+		t.Error("isDNSError() should be false for a refused connection")
+	}
+
+	if isDNSError(nil) {
+		t.Error("isDNSError() should be false without an error")
+	}
+}
+
+func TestHTTPDialer(t *testing.T) {
+	lookup := func(host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("192.0.2.10"), net.ParseIP("2001:db8::10")}, nil
+	}
+
+	var dialed []string
+	dial := func(refused ...string) func(context.Context, string, string) (net.Conn, error) {
+		return func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = append(dialed, network+" "+address)
+
+			for _, r := range refused {
+				if address == r {
+					return nil, errors.New("connection refused")
+				}
+			}
+
+			client, server := net.Pipe()
+			server.Close()
+
+			return client, nil
+		}
+	}
+
+	type testItem struct {
+		description    string
+		dialer         *httpDialer
+		expectedErr    string
+		expectedDialed []string
+	}
+
+	testList := []testItem{
+		{"First address", &httpDialer{mode: addressesAny, lookup: lookup, dial: dial()}, "", []string{"tcp 192.0.2.10:443"}},
+		{"Second address", &httpDialer{mode: addressesAny, lookup: lookup, dial: dial("192.0.2.10:443")}, "",
+			[]string{"tcp 192.0.2.10:443", "tcp [2001:db8::10]:443"}},
+		{"No address", &httpDialer{mode: addressesAny, lookup: lookup, dial: dial("192.0.2.10:443", "[2001:db8::10]:443")}, "connection refused",
+			[]string{"tcp 192.0.2.10:443", "tcp [2001:db8::10]:443"}},
+		{"All addresses", &httpDialer{mode: addressesAll, lookup: lookup, dial: dial()}, "", []string{"tcp 192.0.2.10:443", "tcp [2001:db8::10]:443"}},
+		{"All addresses with one down", &httpDialer{mode: addressesAll, lookup: lookup, dial: dial("[2001:db8::10]:443")}, "connection refused",
+			[]string{"tcp 192.0.2.10:443", "tcp [2001:db8::10]:443"}},
+		{"IPv6", &httpDialer{ipVersion: 6, mode: addressesAll, lookup: lookup, dial: dial()}, "", []string{"tcp6 [2001:db8::10]:443"}},
+	}
+
+	for _, i := range testList {
+		dialed = nil
+
+		conn, err := i.dialer.DialContext(context.Background(), "tcp", "www.example.com:443")
+		if i.expectedErr == "" && err != nil {
+			t.Errorf("%s: Expected no error, Actual: %s", i.description, err)
+		} else if i.expectedErr != "" && (err == nil || !strings.Contains(err.Error(), i.expectedErr)) {
+			t.Errorf("%s: Expected Error: %s, Actual: %v", i.description, i.expectedErr, err)
+		}
+
+		if conn != nil {
+			conn.Close()
+		}
+
+		if !reflect.DeepEqual(dialed, i.expectedDialed) {
+			t.Errorf("%s: Expected Dialed: %v, Actual Dialed: %v", i.description, i.expectedDialed, dialed)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const checkKernelModuleName = "CheckKernelModule"
+
+const procModulesFile = "/proc/modules"
+
+// sysModuleDir holds a directory for each module known to the kernel,
+// including the modules built into the kernel, which are not listed
+// in /proc/modules.
+const sysModuleDir = "/sys/module"
+
+// unknownRefCount is the reference count of a module listed with a
+// reference count of "-", such as a module that cannot be unloaded.
+const unknownRefCount = -1
+
+type kernelModule struct {
+	name     string
+	size     uint64
+	refCount int
+}
+
+// parseKernelModules parses the contents of /proc/modules into a map of
+// modules keyed by module name. Each line in the file has the format of
+// "name size refcount dependencies state address" and only the first
+// three fields are used. A reference count of "-" is unknownRefCount.
+func parseKernelModules(data string) (map[string]kernelModule, error) {
+	modules := make(map[string]kernelModule)
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("Could not parse module entry: %s", line)
+		}
+
+		size, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse size of module %s: %s", fields[0], err)
+		}
+
+		refCount := unknownRefCount
+		if fields[2] != "-" {
+			if refCount, err = strconv.Atoi(fields[2]); err != nil {
+				return nil, fmt.Errorf("Could not parse reference count of module %s: %s", fields[0], err)
+			}
+		}
+
+		modules[fields[0]] = kernelModule{
+			name:     fields[0],
+			size:     size,
+			refCount: refCount,
+		}
+	}
+
+	return modules, nil
+}
+
+// runKernelModuleCheck performs the check of
+// CheckKernelModuleWithHandlers() and returns its result.
+func runKernelModuleCheck(name string, minRefCount, minSize int, readFile func(string) ([]byte, error),
+	stat func(string) (os.FileInfo, error)) CheckResult {
+	var modules map[string]kernelModule
+	var err error
+
+	if name == "" {
+		err = errors.New("A kernel module name must be specified")
+	} else if readFile == nil {
+		err = errors.New("No read file service")
+	} else {
+		var data []byte
+		if data, err = readFile(procModulesFile); err == nil {
+			modules, err = parseKernelModules(string(data))
+		}
+	}
+
+	if err != nil {
+		return UnknownResult(checkKernelModuleName, err.Error())
+	}
+
+	module, loaded := modules[name]
+
+	if !loaded && stat != nil {
+		if _, err := stat(filepath.Join(sysModuleDir, name)); err == nil {
+			return OKResult(checkKernelModuleName, fmt.Sprintf("Module %s is built into the kernel", name))
+		}
+	}
+
+	var state State
+	var desc string
+
+	switch {
+	case !loaded:
+		state = StateCritical
+		desc = fmt.Sprintf("Module %s is not loaded", name)
+	case minRefCount >= 0 && module.refCount != unknownRefCount && module.refCount < minRefCount:
+		state = StateWarning
+		desc = fmt.Sprintf("Module %s is loaded with reference count %d, expected at least %d",
+			name, module.refCount, minRefCount)
+	case minSize >= 0 && module.size < uint64(minSize):
+		state = StateWarning
+		desc = fmt.Sprintf("Module %s is loaded with size %d, expected at least %d",
+			name, module.size, minSize)
+	default:
+		state = StateOK
+		desc = fmt.Sprintf("Module %s is loaded", name)
+	}
+
+	if !loaded {
+		return NewCheckResult(checkKernelModuleName, state, desc)
+	}
+
+	refCount := float64(module.refCount)
+	if module.refCount == unknownRefCount {
+		refCount = math.NaN()
+	}
+
+	return NewCheckResult(checkKernelModuleName, state, desc,
+		PerfData{Label: "refcount", Value: refCount},
+		PerfData{Label: "size", Value: float64(module.size), UOM: "B"})
+}
+
+// CheckKernelModuleWithHandler checks for a kernel module to be loaded,
+// reading the loaded modules using the readFile function. If the module
+// is not loaded, a CRITICAL response is issued. A module that is loaded
+// but has a reference count below minRefCount or a size below minSize
+// issues a WARNING response. Set minRefCount or minSize below zero to
+// skip them. A module with an unknown reference count, listed as "-",
+// is not compared against minRefCount and its reference count is
+// output as "U".
+func CheckKernelModuleWithHandler(name string, minRefCount, minSize int, readFile func(string) ([]byte, error)) (string, int) {
+	return runKernelModuleCheck(name, minRefCount, minSize, readFile, nil).Output()
+}
+
+// CheckKernelModuleWithHandlers checks the kernel module as
+// CheckKernelModuleWithHandler() does, and when it is not loaded
+// looks for the module in /sys/module using the stat function. A
+// module found there is built into the kernel and issues an OK
+// response, without perfdata as it has no size or reference count.
+func CheckKernelModuleWithHandlers(name string, minRefCount, minSize int, readFile func(string) ([]byte, error),
+	stat func(string) (os.FileInfo, error)) (string, int) {
+	return runKernelModuleCheck(name, minRefCount, minSize, readFile, stat).Output()
+}
+
+// RunKernelModuleCheck performs the kernel module check of
+// CheckKernelModule() and returns its result, without output.
+func RunKernelModuleCheck(name string, minRefCount, minSize int) CheckResult {
+	return runKernelModuleCheck(name, minRefCount, minSize, ioutil.ReadFile, os.Stat)
+}
+
+// CheckKernelModule executes CheckKernelModuleWithHandlers(), reading
+// the loaded modules from /proc/modules and the modules built into
+// the kernel from /sys/module.
+func CheckKernelModule(name string, minRefCount, minSize int) (string, int) {
+	return RunKernelModuleCheck(name, minRefCount, minSize).Output()
+}
@@ -0,0 +1,101 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testProcModules = `nf_conntrack 139264 5 xt_conntrack,nf_nat,xt_MASQUERADE, Live 0x0000000000000000
+e1000e 262144 0 - Live 0x0000000000000000
+libcrc32c 16384 3 nf_conntrack,nf_nat,btrfs, Live 0x0000000000000000
+vboxdrv 495616 - - Live 0x0000000000000000
+`
+
+func TestParseKernelModules(t *testing.T) {
+	modules, err := parseKernelModules(testProcModules)
+	if err != nil {
+		t.Fatalf("parseKernelModules() returned an error on valid data: %s", err)
+	}
+
+	if len(modules) != 4 {
+		t.Errorf("parseKernelModules() should have returned 4 modules, returned %d", len(modules))
+	}
+
+	module := modules["nf_conntrack"]
+	if module.size != 139264 || module.refCount != 5 {
+		t.Errorf("parseKernelModules() parsed nf_conntrack incorrectly: %+v", module)
+	}
+
+	if module = modules["vboxdrv"]; module.refCount != unknownRefCount {
+		t.Errorf("parseKernelModules() should parse a reference count of - as unknown: %+v", module)
+	}
+
+	if _, err = parseKernelModules("e1000e 262144\n"); err == nil {
+		t.Error("parseKernelModules() should return an error on a short entry")
+	}
+
+	if _, err = parseKernelModules("e1000e size 0 - Live 0x0\n"); err == nil {
+		t.Error("parseKernelModules() should return an error on an invalid size")
+	}
+
+	if _, err = parseKernelModules("e1000e 262144 refs - Live 0x0\n"); err == nil {
+		t.Error("parseKernelModules() should return an error on an invalid reference count")
+	}
+}
+
+func TestCheckKernelModule(t *testing.T) {
+	readValid := func(string) ([]byte, error) {
+		return []byte(testProcModules), nil
+	}
+
+	readError := func(string) ([]byte, error) {
+		return nil, errors.New("read error")
+	}
+
+	statBuiltin := func(path string) (os.FileInfo, error) {
+		if path == "/sys/module/ext4" {
+			return nil, nil
+		}
+
+		return nil, os.ErrNotExist
+	}
+
+	type testItem struct {
+		description  string
+		name         string
+		minRefCount  int
+		minSize      int
+		readFile     func(string) ([]byte, error)
+		stat         func(string) (os.FileInfo, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Module loaded", "nf_conntrack", -1, -1, readValid, nil, statusCodeOK, "refcount=5 size=139264B"},
+		{"Module not loaded", "nvidia", -1, -1, readValid, nil, statusCodeCritical, "is not loaded"},
+		{"Reference count met", "nf_conntrack", 5, -1, readValid, nil, statusCodeOK, statusTextOK},
+		{"Reference count too low", "e1000e", 1, -1, readValid, nil, statusCodeWarning, "reference count 0"},
+		{"Size too small", "libcrc32c", -1, 32768, readValid, nil, statusCodeWarning, "size 16384"},
+		{"Unknown reference count", "vboxdrv", 1, -1, readValid, nil, statusCodeOK, "refcount=U size=495616B"},
+		{"Built into the kernel", "ext4", 1, 32768, readValid, statBuiltin, statusCodeOK, "Module ext4 is built into the kernel"},
+		{"Neither loaded nor built in", "nvidia", -1, -1, readValid, statBuiltin, statusCodeCritical, "is not loaded"},
+		{"No module name", "", -1, -1, readValid, nil, statusCodeUnknown, "must be specified"},
+		{"No read service", "e1000e", -1, -1, nil, nil, statusCodeUnknown, statusTextUnknown},
+		{"Read error", "e1000e", -1, -1, readError, nil, statusCodeUnknown, "read error"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckKernelModuleWithHandlers(i.name, i.minRefCount, i.minSize, i.readFile, i.stat)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/load"
+)
+
+const checkLoadName = "CheckLoad"
+
+// loadPeriods are the minutes each load average is taken over.
+var loadPeriods = []int{1, 5, 15}
+
+// parseLoadThresholds parses a threshold for each load average. The
+// threshold is a single load applied to all three averages, such as
+// "4", or a triplet for the 1, 5 and 15 minute averages as taken by
+// the classic check_load, such as "15,10,5". An empty threshold is
+// not checked.
+func parseLoadThresholds(threshold string) ([]string, error) {
+	thresholds := strings.Split(threshold, ",")
+
+	switch len(thresholds) {
+	case 1:
+		thresholds = []string{thresholds[0], thresholds[0], thresholds[0]}
+	case len(loadPeriods):
+	default:
+		return nil, fmt.Errorf("Invalid threshold %q, expected a load such as 4 or a triplet such as 15,10,5", threshold)
+	}
+
+	for i, t := range thresholds {
+		thresholds[i] = strings.TrimSpace(t)
+
+		if thresholds[i] == "" {
+			continue
+		}
+
+		if value, err := strconv.ParseFloat(thresholds[i], 64); err != nil || value < 0 {
+			return nil, fmt.Errorf("Invalid threshold %q, expected a load such as 4 or a triplet such as 15,10,5", threshold)
+		}
+	}
+
+	return thresholds, nil
+}
+
+// isOverLoadThreshold reports whether the load is over the threshold,
+// which is not checked when empty.
+func isOverLoadThreshold(load float64, threshold string) bool {
+	if threshold == "" {
+		return false
+	}
+
+	value, _ := strconv.ParseFloat(threshold, 64)
+
+	return load > value
+}
+
+func formatLoad(load float64) string {
+	return strconv.FormatFloat(math.Round(load*100)/100, 'f', 2, 64)
+}
+
+// runLoadCheck performs the check of CheckLoadWithHandlers() and
+// returns its result.
+func runLoadCheck(warning, critical, metricName string, perCPU bool,
+	loadHandler func() (load.Average, error), cpuCountHandler func() (int, error)) CheckResult {
+	if metricName == "" {
+		metricName = "load"
+	}
+
+	warnings, err := parseLoadThresholds(warning)
+	if err != nil {
+		return UnknownResult(checkLoadName, err.Error())
+	}
+
+	criticals, err := parseLoadThresholds(critical)
+	if err != nil {
+		return UnknownResult(checkLoadName, err.Error())
+	}
+
+	if loadHandler == nil {
+		return UnknownResult(checkLoadName, "No load average service")
+	}
+
+	average, err := loadHandler()
+	if err != nil {
+		return UnknownResult(checkLoadName, fmt.Sprintf("Could not read the load average: %s", err))
+	}
+
+	averages := []float64{average.One, average.Five, average.Fifteen}
+
+	desc := "Load average"
+
+	if perCPU {
+		if cpuCountHandler == nil {
+			return UnknownResult(checkLoadName, "No CPU count service")
+		}
+
+		cpus, err := cpuCountHandler()
+		if err != nil {
+			return UnknownResult(checkLoadName, fmt.Sprintf("Could not count the CPUs: %s", err))
+		}
+
+		for i := range averages {
+			averages[i] /= float64(cpus)
+		}
+
+		desc = fmt.Sprintf("Load average per CPU of %d CPUs", cpus)
+	}
+
+	state := StateOK
+	var tripped []string
+	perfData := make([]PerfData, 0, len(loadPeriods))
+
+	for i, minutes := range loadPeriods {
+		label := fmt.Sprintf("%s%d", metricName, minutes)
+
+		switch {
+		case isOverLoadThreshold(averages[i], criticals[i]):
+			state = StateCritical
+			tripped = append(tripped, fmt.Sprintf("%s over critical threshold %s", label, criticals[i]))
+		case isOverLoadThreshold(averages[i], warnings[i]):
+			if state == StateOK {
+				state = StateWarning
+			}
+			tripped = append(tripped, fmt.Sprintf("%s over warning threshold %s", label, warnings[i]))
+		}
+
+		perfData = append(perfData, PerfData{
+			Label:    label,
+			Value:    math.Round(averages[i]*100) / 100,
+			Warning:  warnings[i],
+			Critical: criticals[i],
+			Min:      "0",
+		})
+	}
+
+	desc = fmt.Sprintf("%s is %s, %s, %s", desc, formatLoad(averages[0]), formatLoad(averages[1]), formatLoad(averages[2]))
+	if len(tripped) > 0 {
+		desc += " (" + strings.Join(tripped, ", ") + ")"
+	}
+
+	return NewCheckResult(checkLoadName, state, desc, perfData...)
+}
+
+// CheckLoadWithHandlers gets the 1, 5 and 15 minute load averages
+// then emits a critical response if any is over its critical
+// threshold, a warning response if any is over its warning threshold
+// and a good response otherwise. With perCPU set the averages are
+// divided by the number of CPUs, so the same thresholds suit hosts of
+// any size. Each average is output as perfdata.
+func CheckLoadWithHandlers(warning, critical, metricName string, perCPU bool,
+	loadHandler func() (load.Average, error), cpuCountHandler func() (int, error)) (string, int) {
+	return runLoadCheck(warning, critical, metricName, perCPU, loadHandler, cpuCountHandler).Output()
+}
+
+// RunLoadCheck performs the load check of CheckLoad() and returns its
+// result, without output.
+func RunLoadCheck(warning, critical, metricName string, perCPU bool) CheckResult {
+	return runLoadCheck(warning, critical, metricName, perCPU, load.GetLoadAverage, load.GetCPUCount)
+}
+
+// CheckLoad executes CheckLoadWithHandlers(), passing it the OS
+// constrained load.GetLoadAverage() and load.GetCPUCount() functions.
+//
+// Returns are those of CheckLoadWithHandlers()
+func CheckLoad(warning, critical, metricName string, perCPU bool) (string, int) {
+	return RunLoadCheck(warning, critical, metricName, perCPU).Output()
+}
@@ -0,0 +1,65 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/load"
+)
+
+func TestCheckLoad(t *testing.T) {
+	loadHandler := func() (load.Average, error) {
+		return load.Average{One: 6, Five: 3.5, Fifteen: 1.25}, nil
+	}
+
+	cpuCountHandler := func() (int, error) {
+		return 4, nil
+	}
+
+	type testItem struct {
+		description  string
+		warning      string
+		critical     string
+		perCPU       bool
+		loadHandler  func() (load.Average, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Below thresholds", "10", "20", false, loadHandler, statusCodeOK,
+			"Load average is 6.00, 3.50, 1.25 | load1=6;10;20;0 load5=3.5;10;20;0 load15=1.25;10;20;0"},
+		{"Triplet warning", "5,4,3", "10,8,6", false, loadHandler, statusCodeWarning, "(load1 over warning threshold 5)"},
+		{"Triplet critical", "5,3,1", "10,8,1", false, loadHandler, statusCodeCritical,
+			"(load1 over warning threshold 5, load5 over warning threshold 3, load15 over critical threshold 1)"},
+		{"Only 15 minute checked", ",,1", ",,2", false, loadHandler, statusCodeWarning, "load1=6;;;0"},
+		{"Per CPU", "1", "2", true, loadHandler, statusCodeWarning, "Load average per CPU of 4 CPUs is 1.50, 0.88, 0.31 (load1 over warning threshold 1)"},
+		{"No thresholds", "", "", false, loadHandler, statusCodeOK, "Load average is"},
+		{"Invalid triplet", "5,4", "10", false, loadHandler, statusCodeUnknown, "Invalid threshold \"5,4\""},
+		{"Invalid threshold", "5", "high", false, loadHandler, statusCodeUnknown, "Invalid threshold \"high\""},
+		{"Load error", "5", "10", false, func() (load.Average, error) {
+			return load.Average{}, errors.New("not supported")
+		}, statusCodeUnknown, "Could not read the load average: not supported"},
+		{"No load service", "5", "10", false, nil, statusCodeUnknown, "No load average service"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckLoadWithHandlers(i.warning, i.critical, "", i.perCPU, i.loadHandler, cpuCountHandler)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	msg, code := CheckLoadWithHandlers("1", "2", "", true, loadHandler, func() (int, error) {
+		return 0, errors.New("no cpuinfo")
+	})
+	if code != statusCodeUnknown || !strings.Contains(msg, "Could not count the CPUs") {
+		t.Errorf("CheckLoadWithHandlers() should be UNKNOWN when the CPUs cannot be counted: %s", msg)
+	}
+}
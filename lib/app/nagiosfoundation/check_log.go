@@ -0,0 +1,277 @@
+package nagiosfoundation
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const checkLogName = "CheckLog"
+
+const (
+	// defaultLogWarning alerts on any matching line when no warning
+	// threshold is given.
+	defaultLogWarning = "0"
+
+	// defaultLogMaxLines is the number of matching lines output when
+	// no cap is given.
+	defaultLogMaxLines = 10
+
+	// maxLogLineLength is the number of characters of a matching line
+	// kept in the output.
+	maxLogLineLength = 200
+
+	// maxLogStateKeyName is the number of characters of the name of
+	// the log kept in the key its offset is saved as.
+	maxLogStateKeyName = 64
+)
+
+// LogCheckOptions contains the options for a log check.
+type LogCheckOptions struct {
+	// The log file scanned for matching lines.
+	File string
+
+	// A regular expression the lines counted must match, such as
+	// "ERROR|FATAL".
+	Pattern string
+
+	// The directory the offset read up to is saved in between runs.
+	StateDir string
+
+	// The warning and critical thresholds, Nagios ranges of the number
+	// of matching lines since the last run. The warning defaults to 0,
+	// alerting on any matching line.
+	Warning  string
+	Critical string
+
+	// The number of matching lines output, defaulting to 10. Zero
+	// outputs the default, and the count covers every matching line.
+	MaxLines int
+
+	// The name of the metric in the nagios output. Defaults to
+	// "matches".
+	MetricName string
+}
+
+// logState is the position a log check read up to on its previous
+// run, with the ID of the file read, to tell a rotated log from the
+// one read before.
+type logState struct {
+	Offset int64     `json:"offset"`
+	FileID uint64    `json:"file_id"`
+	Time   time.Time `json:"time"`
+}
+
+// logStateKey is the key the offset of a log check is saved as, told
+// apart by a hash of the file and the pattern, as keys holding them
+// collide once their punctuation is replaced in the file name and may
+// be longer than a file name may be. The shortened name of the log is
+// kept so the state files can be told apart by eye.
+func logStateKey(options LogCheckOptions) string {
+	name := filepath.Base(options.File)
+	if runes := []rune(name); len(runes) > maxLogStateKeyName {
+		name = string(runes[:maxLogStateKeyName])
+	}
+
+	return fmt.Sprintf("check_log_%s_%x", name, sha256.Sum256([]byte(options.File+"\x00"+options.Pattern)))
+}
+
+// logLineText returns a matching line as output, shortened to
+// maxLogLineLength characters.
+func logLineText(line string) string {
+	if runes := []rune(line); len(runes) > maxLogLineLength {
+		line = string(runes[:maxLogLineLength]) + "..."
+	}
+
+	return line
+}
+
+// scanLog reads the lines of the log from offset and returns the
+// number matching the pattern, up to maxLines of them and the offset
+// after the last line read. A last line without a newline, still
+// being written, is not read, so it is matched whole on the next run.
+func scanLog(log io.ReadSeeker, offset int64, pattern *regexp.Regexp, maxLines int) (int, []string, int64, error) {
+	if _, err := log.Seek(offset, io.SeekStart); err != nil {
+		return 0, nil, offset, err
+	}
+
+	reader := bufio.NewReader(log)
+	count := 0
+	var lines []string
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, nil, offset, err
+		}
+
+		offset += int64(len(line))
+		line = strings.TrimRight(line, "\r\n")
+
+		if pattern.MatchString(line) {
+			count++
+
+			if len(lines) < maxLines {
+				lines = append(lines, logLineText(line))
+			}
+		}
+	}
+
+	return count, lines, offset, nil
+}
+
+// runLogCheck performs the check of CheckLogWithHandler() and returns
+// its result.
+func runLogCheck(options LogCheckOptions, store stateStore, fileID func(os.FileInfo) uint64) CheckResult {
+	if options.File == "" {
+		return UnknownResult(checkLogName, "A log file must be specified.")
+	}
+
+	if options.Pattern == "" {
+		return UnknownResult(checkLogName, "A pattern must be specified.")
+	}
+
+	if options.StateDir == "" {
+		return UnknownResult(checkLogName, "A state directory must be specified to save the offset read up to.")
+	}
+
+	if options.MaxLines < 0 {
+		return UnknownResult(checkLogName, fmt.Sprintf("Invalid maximum lines (%d). The maximum may not be negative.", options.MaxLines))
+	}
+
+	pattern, err := regexp.Compile(options.Pattern)
+	if err != nil {
+		return UnknownResult(checkLogName, fmt.Sprintf("Invalid regular expression %q: %s", options.Pattern, err))
+	}
+
+	warning := options.Warning
+	if warning == "" {
+		warning = defaultLogWarning
+	}
+
+	thresholds, err := ParseThresholds(warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkLogName, err.Error())
+	}
+
+	maxLines := options.MaxLines
+	if maxLines == 0 {
+		maxLines = defaultLogMaxLines
+	}
+
+	metricName := options.MetricName
+	if metricName == "" {
+		metricName = "matches"
+	}
+
+	log, err := os.Open(options.File)
+	if os.IsNotExist(err) {
+		return UnknownResult(checkLogName, fmt.Sprintf("Log %s does not exist", options.File))
+	} else if err != nil {
+		return UnknownResult(checkLogName, fmt.Sprintf("Could not open log %s: %s", options.File, err))
+	}
+	defer log.Close()
+
+	info, err := log.Stat()
+	if err != nil {
+		return UnknownResult(checkLogName, fmt.Sprintf("Could not read log %s: %s", options.File, err))
+	}
+
+	key := logStateKey(options)
+
+	var previous logState
+	ok, err := store.loadJSON(key, &previous)
+	if err != nil {
+		return UnknownResult(checkLogName, fmt.Sprintf("Could not read the offset of log %s from %s: %s", options.File, store.dir, err))
+	}
+
+	current := logState{Offset: info.Size(), FileID: fileID(info), Time: store.now()}
+	metric := PerfData{Label: metricName, Min: "0"}
+
+	if !ok {
+		if err := store.saveJSON(key, current); err != nil {
+			return UnknownResult(checkLogName, fmt.Sprintf("Could not save the offset of log %s in %s: %s", options.File, store.dir, err))
+		}
+
+		return OKResult(checkLogName, fmt.Sprintf("No previous offset of log %s, the lines written from now on are checked", options.File),
+			thresholds.Metric(metric))
+	}
+
+	rotated := previous.FileID != current.FileID || info.Size() < previous.Offset
+
+	offset := previous.Offset
+	if rotated {
+		debugLog.Printf("Log %s has been rotated, reading it from the start", options.File)
+		offset = 0
+	}
+
+	count, lines, offset, err := scanLog(log, offset, pattern, maxLines)
+	if err != nil {
+		return UnknownResult(checkLogName, fmt.Sprintf("Could not read log %s: %s", options.File, err))
+	}
+
+	current.Offset = offset
+	if err := store.saveJSON(key, current); err != nil {
+		return UnknownResult(checkLogName, fmt.Sprintf("Could not save the offset of log %s in %s: %s", options.File, store.dir, err))
+	}
+
+	checkInfo := fmt.Sprintf("%d lines matching %s in %s since the last run", count, options.Pattern, options.File)
+	if rotated {
+		checkInfo += ", the log has been rotated"
+	}
+
+	state, tripped := thresholds.Status(float64(count))
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+	}
+
+	if len(lines) > 0 {
+		checkInfo += "\n" + strings.Join(lines, "\n")
+	}
+
+	if count > len(lines) {
+		checkInfo += fmt.Sprintf("\n... and %d more", count-len(lines))
+	}
+
+	metric.Value = float64(count)
+
+	return NewCheckResult(checkLogName, state, checkInfo, thresholds.Metric(metric))
+}
+
+// CheckLogWithHandler counts the lines of the log options.File
+// matching the options.Pattern expression written since the last run
+// and compares the count against the options.Warning threshold,
+// defaulting to 0, and the options.Critical threshold. The offset read
+// up to is saved to the store, so each run reads only the lines
+// written since the run before it. The first run has no offset and
+// starts from the end of the log, so the lines written before the
+// check was set up are not counted. A log whose file ID, read with
+// fileID, has changed or that is smaller than the offset has been
+// rotated and is read from its start. Up to options.MaxLines of the
+// matching lines are output after the result, and the count is output
+// as perfdata.
+func CheckLogWithHandler(options LogCheckOptions, store stateStore, fileID func(os.FileInfo) uint64) (string, int) {
+	return runLogCheck(options, store, fileID).Output()
+}
+
+// RunLogCheck performs the log check of CheckLog() and returns its
+// result, without output.
+func RunLogCheck(options LogCheckOptions) CheckResult {
+	return runLogCheck(options, newStateStore(options.StateDir), getFileIDOsConstrained)
+}
+
+// CheckLog executes CheckLogWithHandler(), saving the offset in
+// options.StateDir and telling a rotated log by its inode on Linux.
+//
+// Returns are those of CheckLogWithHandler()
+func CheckLog(options LogCheckOptions) (string, int) {
+	return RunLogCheck(options).Output()
+}
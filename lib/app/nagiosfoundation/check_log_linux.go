@@ -0,0 +1,18 @@
+// +build !windows
+
+package nagiosfoundation
+
+import (
+	"os"
+	"syscall"
+)
+
+// getFileIDOsConstrained returns the inode of the file, which changes
+// when a log is rotated by moving it aside and creating a new file.
+func getFileIDOsConstrained(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+
+	return 0
+}
@@ -0,0 +1,138 @@
+package nagiosfoundation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checklog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logFile := filepath.Join(dir, "app.log")
+	store := stateStore{dir: filepath.Join(dir, "state"), now: func() time.Time { return time.Date(2019, 6, 4, 15, 4, 5, 0, time.UTC) }}
+
+	inode := uint64(1)
+	fileID := func(os.FileInfo) uint64 { return inode }
+
+	appendLog := func(text string) {
+		log, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := log.WriteString(text); err != nil {
+			t.Fatal(err)
+		}
+
+		log.Close()
+	}
+
+	options := LogCheckOptions{File: logFile, Pattern: "ERROR|FATAL", StateDir: store.dir, Critical: "3", MaxLines: 2}
+
+	appendLog("INFO started\nERROR before the first run\n")
+
+	type testItem struct {
+		description  string
+		before       func()
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"First run starts at the end", func() {}, statusCodeOK,
+			"CheckLog OK - No previous offset of log " + logFile + ", the lines written from now on are checked | matches=0;0;3;0"},
+		{"No new lines", func() {}, statusCodeOK,
+			"CheckLog OK - 0 lines matching ERROR¦FATAL in " + logFile + " since the last run | matches=0;0;3;0"},
+		{"New matching line", func() { appendLog("INFO request\nERROR disk full | retrying\n") }, statusCodeWarning,
+			"CheckLog WARNING - 1 lines matching ERROR¦FATAL in " + logFile + " since the last run (expected 0)\nERROR disk full ¦ retrying | matches=1;0;3;0"},
+		{"Lines over the cap", func() { appendLog("ERROR one\nFATAL two\nINFO three\nERROR four\nERROR five\n") }, statusCodeCritical,
+			"4 lines matching ERROR¦FATAL in " + logFile + " since the last run (expected 0-3)\nERROR one\nFATAL two\n... and 2 more | matches=4"},
+		{"Partial line is left for the next run", func() { appendLog("ERROR half") }, statusCodeOK, "0 lines matching"},
+		{"Partial line completed", func() { appendLog(" written\n") }, statusCodeWarning, "1 lines matching ERROR¦FATAL in " + logFile + " since the last run (expected 0)\nERROR half written |"},
+		{"Rotated by shrinking", func() { os.Remove(logFile); appendLog("ERROR after rotation\n") }, statusCodeWarning,
+			"1 lines matching ERROR¦FATAL in " + logFile + " since the last run, the log has been rotated (expected 0)\nERROR after rotation |"},
+		{"Rotated to a new file", func() {
+			os.Remove(logFile)
+			appendLog("INFO a longer first line of the new log\nINFO a longer second line of the new log\nFATAL rotated\n")
+			inode = 2
+		}, statusCodeWarning, "1 lines matching ERROR¦FATAL in " + logFile + " since the last run, the log has been rotated (expected 0)\nFATAL rotated |"},
+	}
+
+	for _, i := range testList {
+		i.before()
+		msg, code := CheckLogWithHandler(options, store, fileID)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	appendLog("ERROR quota exceeded | retrying\n")
+	if result := runLogCheck(options, store, fileID); !strings.HasSuffix(result.Message, "\nERROR quota exceeded | retrying") {
+		t.Errorf("runLogCheck() should keep the pipe of a matching line in the result: %q", result.Message)
+	}
+
+	invalidList := []struct {
+		description string
+		options     LogCheckOptions
+		expectedMsg string
+	}{
+		{"No file", LogCheckOptions{Pattern: "ERROR", StateDir: store.dir}, "A log file must be specified"},
+		{"No pattern", LogCheckOptions{File: logFile, StateDir: store.dir}, "A pattern must be specified"},
+		{"No state directory", LogCheckOptions{File: logFile, Pattern: "ERROR"}, "A state directory must be specified"},
+		{"Negative maximum", LogCheckOptions{File: logFile, Pattern: "ERROR", StateDir: store.dir, MaxLines: -1}, "may not be negative"},
+		{"Bad pattern", LogCheckOptions{File: logFile, Pattern: "(", StateDir: store.dir}, "Invalid regular expression"},
+		{"Invalid threshold", LogCheckOptions{File: logFile, Pattern: "ERROR", StateDir: store.dir, Critical: "many"}, "Invalid range"},
+		{"Missing log", LogCheckOptions{File: filepath.Join(dir, "missing.log"), Pattern: "ERROR", StateDir: store.dir}, "does not exist"},
+	}
+
+	for _, i := range invalidList {
+		if msg, code := CheckLogWithHandler(i.options, store, fileID); code != statusCodeUnknown || !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected UNKNOWN with %s, Actual: %d %s", i.description, i.expectedMsg, code, msg)
+		}
+	}
+
+	if line := logLineText(strings.Repeat("x", 300)); len(line) != maxLogLineLength+3 {
+		t.Errorf("logLineText() should shorten a long line to %d characters, got %d", maxLogLineLength, len(line))
+	}
+
+	if msg, code := CheckLog(LogCheckOptions{File: logFile, Pattern: "ERROR", StateDir: store.dir}); code != statusCodeOK {
+		t.Errorf("CheckLog() should start a new check at the end of the log, returned %d: %s", code, msg)
+	}
+}
+
+func TestLogStateKey(t *testing.T) {
+	store := newStateStore("/var/tmp/nagiosfoundation")
+	path := func(file, pattern string) string {
+		return store.path(logStateKey(LogCheckOptions{File: file, Pattern: pattern}))
+	}
+
+	if path("/var/log/app.log", "a|b") == path("/var/log/app.log", "a_b") {
+		t.Error("logStateKey() should tell apart patterns made the same in the state file name")
+	}
+
+	if path("/var/log/a/b.log", "ERROR") == path("/var/log/a_b.log", "ERROR") {
+		t.Error("logStateKey() should tell apart files made the same in the state file name")
+	}
+
+	name := filepath.Base(path("/var/log/"+strings.Repeat("x", 300)+".log", strings.Repeat("ERROR|", 100)))
+	if len(name) > 255 {
+		t.Errorf("logStateKey() should keep the state file name to 255 characters, got %d", len(name))
+	}
+
+	if !strings.HasPrefix(filepath.Base(path("/var/log/app.log", "ERROR")), "check_log_app.log_") {
+		t.Errorf("logStateKey() should keep the name of the log, got %s", path("/var/log/app.log", "ERROR"))
+	}
+}
@@ -0,0 +1,14 @@
+// +build windows
+
+package nagiosfoundation
+
+import (
+	"os"
+)
+
+// getFileIDOsConstrained returns 0, as the file information on
+// Windows has no inode, so a rotated log is only noticed when the new
+// file is smaller than the offset read up to.
+func getFileIDOsConstrained(info os.FileInfo) uint64 {
+	return 0
+}
@@ -2,48 +2,117 @@ package nagiosfoundation
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/memory"
-	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/nagiosformatters"
 )
 
-// CheckMemoryWithHandler determines the percentage of
-// memory used and emits a critical response if it's over
-// the critical argument, a warning response if it's over the
-// warning argument, and good response otherwise.
-func CheckMemoryWithHandler(checkType string, warning, critical int, metricName string, memoryHandler func() uint64) (string, int) {
+// memoryThreshold returns the threshold with each bound that is a
+// plain number as a percentage, the form of the whole-number
+// percentages the thresholds of check_memory were, so that "85" and
+// "10:85" are still percentages rather than bytes. Bounds with a
+// unit, such as "6G", and the infinite "~" are left as they are.
+func memoryThreshold(threshold string) string {
+	text := strings.TrimSpace(threshold)
+
+	prefix := ""
+	if strings.HasPrefix(text, "@") {
+		prefix, text = "@", text[1:]
+	}
+
+	bounds := strings.SplitN(text, ":", 2)
+	for i, bound := range bounds {
+		if _, err := strconv.ParseFloat(bound, 64); err == nil {
+			bounds[i] = bound + "%"
+		}
+	}
+
+	return prefix + strings.Join(bounds, ":")
+}
+
+// runMemoryCheck performs the check of CheckMemoryUsageWithHandler()
+// and returns its result.
+func runMemoryCheck(checkType, warning, critical, metricName string, usageHandler func() (uint64, uint64, error)) CheckResult {
 	const checkName = "CheckMemory"
 
-	var msg string
-	var retcode int
-	var usedMemoryPercentage uint64
+	var used, total uint64
 	var err error
 
-	if memoryHandler == nil {
-		err = errors.New("No used memory percentage service")
-	} else {
-		usedMemoryPercentage = memoryHandler()
-
-		if usedMemoryPercentage == 0 {
-			err = errors.New("Failed to determine used memory percentage")
-		}
+	kind, labelPrefix := "Memory", "memory"
+	if checkType == "swap" {
+		kind, labelPrefix = "Swap", "swap"
 	}
 
+	relativeThresholds, err := ParseRelativeThresholds(memoryThreshold(warning), memoryThreshold(critical))
 	if err != nil {
-		msg, _ = resultMessage(checkName, statusTextCritical, err.Error())
-		retcode = 2
+		return UnknownResult(checkName, err.Error())
+	}
+
+	if usageHandler == nil {
+		err = errors.New("No memory usage service")
 	} else {
-		msg, retcode = nagiosformatters.GreaterFormatNagiosCheck(checkName, float64(usedMemoryPercentage), float64(warning), float64(critical), metricName)
+		used, total, err = usageHandler()
+	}
+
+	if err != nil {
+		return CriticalResult(checkName, err.Error())
+	}
+
+	if total == 0 {
+		return OKResult(checkName, fmt.Sprintf("No %s configured", labelPrefix))
+	}
+
+	thresholds, percentThresholds := diskThresholds(relativeThresholds, total)
+	state, _ := thresholds.Status(float64(used))
+
+	usedPercentage := float64(used) / float64(total) * 100
+
+	return NewCheckResult(checkName, state,
+		fmt.Sprintf("%s used is %.2f%% (%d of %d bytes)", kind, usedPercentage, used, total),
+		percentThresholds.Metric(PerfData{
+			Label: metricName,
+			Value: math.Round(usedPercentage*100) / 100,
+			UOM:   "%",
+			Min:   "0",
+			Max:   "100",
+		}),
+		thresholds.Metric(PerfData{Label: labelPrefix + "_used", Value: float64(used), UOM: "B", Min: "0", Max: strconv.FormatUint(total, 10)}),
+		PerfData{Label: labelPrefix + "_total", Value: float64(total), UOM: "B", Min: "0"},
+	)
+}
+
+// CheckMemoryUsageWithHandler determines the memory used from the used
+// and total amounts returned by usageHandler and emits a critical
+// response if it's over the critical argument, a warning response if
+// it's over the warning argument, and good response otherwise. As with
+// CheckDisk the thresholds are Nagios ranges of a percentage such as
+// "85%" or an amount such as "6G", with a plain number such as "85" a
+// percentage, also as a bound of a range such as "10:85". The checkType
+// of "swap" describes the usage as swap, anything else as physical
+// memory. Having no swap configured is a good response.
+func CheckMemoryUsageWithHandler(checkType, warning, critical, metricName string, usageHandler func() (uint64, uint64, error)) (string, int) {
+	return runMemoryCheck(checkType, warning, critical, metricName, usageHandler).Output()
+}
+
+// RunMemoryCheck performs the memory check of CheckMemory() and returns
+// its result, without output.
+func RunMemoryCheck(checkType, warning, critical, metricName string) CheckResult {
+	usageHandler := memory.GetMemoryUsage
+	if checkType == "swap" {
+		usageHandler = memory.GetSwapUsage
 	}
 
-	return msg, retcode
+	return runMemoryCheck(checkType, warning, critical, metricName, usageHandler)
 }
 
-// CheckMemory executes CheckMemoryWithHandler(),
-// passing it the OS constranted GetFreeMemory() function, prints
-// the returned message and exits with the returned exit code.
+// CheckMemory executes CheckMemoryUsageWithHandler(), passing it the
+// OS constrained GetSwapUsage() function when the checkType is
+// "swap", otherwise GetMemoryUsage().
 //
-// Returns are those of CheckMemoryWithHandler()
-func CheckMemory(checkType string, warning, critical int, metricName string) (string, int) {
-	return CheckMemoryWithHandler(checkType, warning, critical, metricName, memory.GetUsedMemoryPercentage)
+// Returns are those of CheckMemoryUsageWithHandler()
+func CheckMemory(checkType, warning, critical, metricName string) (string, int) {
+	return RunMemoryCheck(checkType, warning, critical, metricName).Output()
 }
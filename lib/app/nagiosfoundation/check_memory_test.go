@@ -1,43 +1,55 @@
 package nagiosfoundation
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
-func TestCheckMemory(t *testing.T) {
-	testReturnValid := func() uint64 { return uint64(50) }
-	testReturnZero := func() uint64 { return uint64(0) }
-
-	// No "get memory" service passed
-	msg, retcode := CheckMemoryWithHandler("", 85, 95, "available_memory_percent", nil)
-
-	if retcode != 2 || msg == "" {
-		t.Error("CheckMemoryWithHandler() failed to handle nil service")
+func TestCheckMemoryUsage(t *testing.T) {
+	usage := func(used, total uint64, err error) func() (uint64, uint64, error) {
+		return func() (uint64, uint64, error) { return used, total, err }
 	}
 
-	// Valid memory service with flag defaults
-	msg, retcode = CheckMemoryWithHandler("", 85, 95, "available_memory_percent", testReturnValid)
-
-	if retcode != 0 || msg == "" {
-		t.Error("CheckMemoryWithHandler() failed with valid GetFreeMemory() call")
+	type testItem struct {
+		description  string
+		checkType    string
+		warning      string
+		critical     string
+		handler      func() (uint64, uint64, error)
+		expectedCode int
+		expectedMsg  string
 	}
 
-	// Valid memory service but service returns error
-	msg, retcode = CheckMemoryWithHandler("", 85, 95, "available_memory_percent", testReturnZero)
-
-	if retcode != 2 || msg == "" {
-		t.Error("CheckMemoryWithHandler() failed with valid GetFreeMemory() call")
+	testList := []testItem{
+		{"Memory below warning", "", "85", "95", usage(4096, 8192, nil), statusCodeOK,
+			"Memory used is 50.00% (4096 of 8192 bytes) | pct=50%;85;95;0;100 memory_used=4096B;6963;7782;0;8192 memory_total=8192B;;;0"},
+		{"Memory above warning", "", "40", "95", usage(4096, 8192, nil), statusCodeWarning, "CheckMemory WARNING"},
+		{"Memory above warning by percent", "", "40%", "95", usage(4096, 8192, nil), statusCodeWarning, "pct=50%;40;95;0;100"},
+		{"Memory above warning by size", "", "3K", "95", usage(4096, 8192, nil), statusCodeWarning, "pct=50%;37.5;95;0;100 memory_used=4096B;3072;7782;0;8192"},
+		{"Memory below a range", "", "6K:", "95", usage(4096, 8192, nil), statusCodeWarning, "pct=50%;75:;95;0;100"},
+		{"Memory above critical", "", "40", "95", usage(8000, 8192, nil), statusCodeCritical, "CheckMemory CRITICAL"},
+		{"Memory above a lower critical", "", "85", "45", usage(4096, 8192, nil), statusCodeCritical, "pct=50%;85;45;0;100"},
+		{"Memory within a range", "", "10:85", "95", usage(4096, 8192, nil), statusCodeOK, "pct=50%;10:85;95;0;100"},
+		{"Memory inside an inverted range", "", "@40:60", "95", usage(4096, 8192, nil), statusCodeWarning, "pct=50%;@40:60;95;0;100"},
+		{"Memory above an unbounded range", "", "~:40", "95", usage(4096, 8192, nil), statusCodeWarning, "pct=50%;~:40;95;0;100"},
+		{"Memory below a percentage range", "", "60:", "95", usage(4096, 8192, nil), statusCodeWarning, "pct=50%;60:;95;0;100"},
+		{"Swap usage", "swap", "85", "95", usage(1024, 4096, nil), statusCodeOK, "| pct=25%;85;95;0;100 swap_used=1024B;3482;3891;0;4096 swap_total=4096B;;;0"},
+		{"No swap configured", "swap", "85", "95", usage(0, 0, nil), statusCodeOK, "No swap configured"},
+		{"Invalid threshold", "", "lots", "95", usage(4096, 8192, nil), statusCodeUnknown, "Invalid range \"lots\""},
+		{"Usage error", "", "85", "95", usage(0, 0, errors.New("read failure")), statusCodeCritical, "read failure"},
+		{"No usage service", "", "85", "95", nil, statusCodeCritical, "No memory usage service"},
 	}
 
-	msg, retcode = CheckMemoryWithHandler("", 40, 95, "available_memory_percent", testReturnValid)
-
-	if retcode != 1 || msg == "" {
-		t.Error("CheckMemoryWithHandler() should have emitted WARNING")
-	}
+	for _, i := range testList {
+		msg, code := CheckMemoryUsageWithHandler(i.checkType, i.warning, i.critical, "pct", i.handler)
 
-	msg, retcode = CheckMemoryWithHandler("", 85, 45, "available_memory_percent", testReturnValid)
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
 
-	if retcode != 2 || msg == "" {
-		t.Error("CheckMemoryWithHandler() should have emitted CRITICAL")
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
 	}
 }
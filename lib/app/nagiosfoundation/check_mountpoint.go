@@ -0,0 +1,189 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const checkMountpointName = "CheckMountpoint"
+
+const mountsFile = "/proc/mounts"
+
+// MountpointCheckOptions contains the options for a mount point check.
+type MountpointCheckOptions struct {
+	// The path that must be a mount point, such as "/data".
+	Path string
+
+	// The filesystem type the path must be mounted with, such as
+	// "xfs", not checked when empty.
+	FsType string
+}
+
+// mountEntry is a filesystem mounted, a line of /proc/mounts.
+type mountEntry struct {
+	device     string
+	mountPoint string
+	fsType     string
+}
+
+// unescapeMountField returns a field of /proc/mounts with the octal
+// escapes of the kernel replaced, such as "\040" for a space.
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var unescaped strings.Builder
+
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+4 <= len(field) {
+			if value, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				unescaped.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+
+		unescaped.WriteByte(field[i])
+	}
+
+	return unescaped.String()
+}
+
+// parseMounts returns the filesystems mounted from the content of
+// /proc/mounts, a line for each of the device, the mount point, the
+// filesystem type, the options and two numbers, such as
+// "/dev/sdb1 /data xfs rw,relatime 0 0". Lines too short are skipped.
+func parseMounts(data string) []mountEntry {
+	var mounts []mountEntry
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		mounts = append(mounts, mountEntry{
+			device:     unescapeMountField(fields[0]),
+			mountPoint: unescapeMountField(fields[1]),
+			fsType:     fields[2],
+		})
+	}
+
+	return mounts
+}
+
+// findMount returns the filesystem mounted at the path. A path mounted
+// over more than once is listed once for each, and the last listed is
+// the one seen.
+func findMount(mounts []mountEntry, path string) (mountEntry, bool) {
+	for i := len(mounts) - 1; i >= 0; i-- {
+		if mounts[i].mountPoint == path {
+			return mounts[i], true
+		}
+	}
+
+	return mountEntry{}, false
+}
+
+// runMountpointCheck performs the check of
+// CheckMountpointWithHandlers() and returns its result.
+func runMountpointCheck(options MountpointCheckOptions, stat func(string) (os.FileInfo, error),
+	deviceID func(os.FileInfo) (uint64, bool), readFile func(string) ([]byte, error)) CheckResult {
+	if options.Path == "" {
+		return UnknownResult(checkMountpointName, "A path must be specified.")
+	}
+
+	path := filepath.Clean(options.Path)
+
+	info, err := stat(path)
+	if os.IsNotExist(err) {
+		return CriticalResult(checkMountpointName, fmt.Sprintf("Path %s does not exist", path))
+	} else if err != nil {
+		return UnknownResult(checkMountpointName, fmt.Sprintf("Could not read path %s: %s", path, err))
+	}
+
+	device, ok := deviceID(info)
+	if !ok {
+		return UnknownResult(checkMountpointName, "The device of a path is not available on this OS")
+	}
+
+	parent := filepath.Dir(path)
+
+	parentInfo, err := stat(parent)
+	if err != nil {
+		return UnknownResult(checkMountpointName, fmt.Sprintf("Could not read the parent %s of path %s: %s", parent, path, err))
+	}
+
+	parentDevice, _ := deviceID(parentInfo)
+
+	var mounts []mountEntry
+	data, err := readFile(mountsFile)
+	if err != nil && options.FsType != "" {
+		return UnknownResult(checkMountpointName, fmt.Sprintf("Could not read %s: %s", mountsFile, err))
+	} else if err != nil {
+		debugLog.Printf("Could not read %s, checking the device of %s alone: %s", mountsFile, path, err)
+	} else {
+		mounts = parseMounts(string(data))
+	}
+
+	mount, listed := findMount(mounts, path)
+
+	// The root is always a mount point, though it shares its device
+	// with its parent, itself.
+	if path != parent && device == parentDevice && !listed {
+		return CriticalResult(checkMountpointName, fmt.Sprintf("Path %s is not a mount point, it is on the filesystem of %s", path, parent))
+	}
+
+	if !listed {
+		if options.FsType != "" {
+			return CriticalResult(checkMountpointName, fmt.Sprintf("Path %s is a mount point not listed in %s, expected a %s filesystem",
+				path, mountsFile, options.FsType))
+		}
+
+		return OKResult(checkMountpointName, fmt.Sprintf("Path %s is a mount point", path))
+	}
+
+	checkInfo := fmt.Sprintf("Path %s is a mount point, %s %s", path, mount.fsType, mount.device)
+
+	if options.FsType != "" && mount.fsType != options.FsType {
+		return CriticalResult(checkMountpointName, fmt.Sprintf("%s (expected %s)", checkInfo, options.FsType))
+	}
+
+	return OKResult(checkMountpointName, checkInfo)
+}
+
+// CheckMountpointWithHandlers checks that options.Path is a mount
+// point, so an application writing to a volume that failed to mount
+// is caught before it fills the filesystem below it. The path is a
+// mount point when the ID of its device, read from the file
+// information returned by stat with deviceID, differs from that of
+// its parent directory, or when it is listed in /proc/mounts, read
+// with readFile, as a bind mount of a directory of the same
+// filesystem. With options.FsType the filesystem mounted at the path,
+// the last of those listed in /proc/mounts, must also be of the type.
+// A path that is not a mount point, does not exist or is mounted with
+// another type emits a critical response.
+func CheckMountpointWithHandlers(options MountpointCheckOptions, stat func(string) (os.FileInfo, error),
+	deviceID func(os.FileInfo) (uint64, bool), readFile func(string) ([]byte, error)) (string, int) {
+	return runMountpointCheck(options, stat, deviceID, readFile).Output()
+}
+
+// RunMountpointCheck performs the mount point check of
+// CheckMountpoint() and returns its result, without output.
+func RunMountpointCheck(options MountpointCheckOptions) CheckResult {
+	return runMountpointCheck(options, os.Stat, getDeviceIDOsConstrained, ioutil.ReadFile)
+}
+
+// CheckMountpoint executes CheckMountpointWithHandlers(), reading the
+// device of the path with the OS and the filesystems mounted from
+// /proc/mounts.
+//
+// Returns are those of CheckMountpointWithHandlers()
+func CheckMountpoint(options MountpointCheckOptions) (string, int) {
+	return RunMountpointCheck(options).Output()
+}
@@ -0,0 +1,19 @@
+// +build !windows
+
+package nagiosfoundation
+
+import (
+	"os"
+	"syscall"
+)
+
+// getDeviceIDOsConstrained returns the ID of the device holding the
+// file, which differs from that of its parent directory when the file
+// is a mount point.
+func getDeviceIDOsConstrained(info os.FileInfo) (uint64, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Dev), true
+	}
+
+	return 0, false
+}
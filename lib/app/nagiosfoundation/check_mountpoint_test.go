@@ -0,0 +1,109 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testMounts = `/dev/vda1 / ext4 rw,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+/dev/sdb1 /data xfs rw,relatime 0 0
+/dev/vda1 /srv/bind ext4 rw,relatime 0 0
+/dev/sdc1 /mnt/my\040disk ext4 rw,relatime 0 0
+`
+
+func TestParseMounts(t *testing.T) {
+	mounts := parseMounts(testMounts + "short line\ntmpfs /data tmpfs rw 0 0\n")
+	if len(mounts) != 6 {
+		t.Fatalf("parseMounts() Expected: 6 mounts, Actual: %d", len(mounts))
+	}
+
+	if expected := (mountEntry{device: "/dev/sdc1", mountPoint: "/mnt/my disk", fsType: "ext4"}); mounts[4] != expected {
+		t.Errorf("parseMounts() should unescape the fields, Expected: %+v, Actual: %+v", expected, mounts[4])
+	}
+
+	if mount, ok := findMount(mounts, "/data"); !ok || mount.fsType != "tmpfs" {
+		t.Errorf("findMount() should return the last mount of a path, Actual: %+v, Found: %t", mount, ok)
+	}
+
+	if unescaped := unescapeMountField(`a\134b\04`); unescaped != `a\b\04` {
+		t.Errorf("unescapeMountField() Expected: %q, Actual: %q", `a\b\04`, unescaped)
+	}
+}
+
+func TestCheckMountpoint(t *testing.T) {
+	devices := map[string]uint64{
+		"/": 1, "/data": 2, "/srv": 1, "/srv/bind": 1, "/srv/app": 1, "/mnt": 1, "/mnt/usb": 3,
+	}
+
+	stat := func(path string) (os.FileInfo, error) {
+		if path == "/secret" {
+			return nil, os.ErrPermission
+		}
+
+		if _, ok := devices[path]; !ok {
+			return nil, os.ErrNotExist
+		}
+
+		return testPidFileInfo{name: path}, nil
+	}
+
+	deviceID := func(info os.FileInfo) (uint64, bool) {
+		return devices[info.Name()], true
+	}
+
+	readMounts := func(string) ([]byte, error) {
+		return []byte(testMounts), nil
+	}
+
+	readError := func(string) ([]byte, error) {
+		return nil, errors.New("permission denied")
+	}
+
+	type testItem struct {
+		description  string
+		options      MountpointCheckOptions
+		readFile     func(string) ([]byte, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Mounted", MountpointCheckOptions{Path: "/data"}, readMounts, statusCodeOK, "CheckMountpoint OK - Path /data is a mount point, xfs /dev/sdb1"},
+		{"Trailing slash", MountpointCheckOptions{Path: "/data/"}, readMounts, statusCodeOK, "Path /data is a mount point"},
+		{"Not mounted", MountpointCheckOptions{Path: "/srv/app"}, readMounts, statusCodeCritical,
+			"CheckMountpoint CRITICAL - Path /srv/app is not a mount point, it is on the filesystem of /srv"},
+		{"Bind mount of the same device", MountpointCheckOptions{Path: "/srv/bind", FsType: "ext4"}, readMounts, statusCodeOK,
+			"Path /srv/bind is a mount point, ext4 /dev/vda1"},
+		{"Root", MountpointCheckOptions{Path: "/", FsType: "ext4"}, readMounts, statusCodeOK, "Path / is a mount point, ext4 /dev/vda1"},
+		{"Wrong type", MountpointCheckOptions{Path: "/data", FsType: "ext4"}, readMounts, statusCodeCritical,
+			"Path /data is a mount point, xfs /dev/sdb1 (expected ext4)"},
+		{"Missing path", MountpointCheckOptions{Path: "/backup"}, readMounts, statusCodeCritical, "Path /backup does not exist"},
+		{"Unreadable path", MountpointCheckOptions{Path: "/secret"}, readMounts, statusCodeUnknown, "Could not read path /secret"},
+		{"No path", MountpointCheckOptions{}, readMounts, statusCodeUnknown, "A path must be specified"},
+		{"Device alone", MountpointCheckOptions{Path: "/mnt/usb"}, readError, statusCodeOK, "CheckMountpoint OK - Path /mnt/usb is a mount point"},
+		{"Not listed with a type", MountpointCheckOptions{Path: "/mnt/usb", FsType: "vfat"}, readMounts, statusCodeCritical,
+			"Path /mnt/usb is a mount point not listed in /proc/mounts, expected a vfat filesystem"},
+		{"Type without mounts", MountpointCheckOptions{Path: "/data", FsType: "xfs"}, readError, statusCodeUnknown,
+			"Could not read /proc/mounts: permission denied"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckMountpointWithHandlers(i.options, stat, deviceID, i.readFile)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	noDevice := func(os.FileInfo) (uint64, bool) { return 0, false }
+	if msg, code := CheckMountpointWithHandlers(MountpointCheckOptions{Path: "/data"}, stat, noDevice, readMounts); code != statusCodeUnknown {
+		t.Errorf("CheckMountpointWithHandlers() should return UNKNOWN without a device ID: %d %s", code, msg)
+	}
+}
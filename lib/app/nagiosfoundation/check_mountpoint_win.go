@@ -0,0 +1,13 @@
+// +build windows
+
+package nagiosfoundation
+
+import (
+	"os"
+)
+
+// getDeviceIDOsConstrained returns false, as the file information on
+// Windows has no device ID.
+func getDeviceIDOsConstrained(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,140 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const checkMultiName = "CheckMulti"
+
+// multiStateOrder orders the states from the worst, the order the
+// aggregate state is chosen in. UNKNOWN is better than WARNING as the
+// check could not tell there is a problem, while OK is the best.
+var multiStateOrder = []State{StateCritical, StateWarning, StateUnknown, StateOK}
+
+// worseState reports whether state a is worse than state b.
+func worseState(a, b State) bool {
+	for _, state := range multiStateOrder {
+		switch state {
+		case a:
+			return a != b
+		case b:
+			return false
+		}
+	}
+
+	return false
+}
+
+// multiDetail returns the line describing the result of one check,
+// its name, status and message without the perfdata, which is output
+// together for all of the checks.
+func multiDetail(result CheckResult) string {
+	detail := result.Status
+	if result.Name != "" {
+		detail = result.Name + " " + detail
+	}
+
+	if result.Message != "" {
+		detail += " - " + result.Message
+	}
+
+	return detail
+}
+
+// RunMultiCheck runs the checks at once and emits the worst of their
+// states, CRITICAL then WARNING then UNKNOWN then OK, with a summary
+// counting the checks in each state followed by a line describing the
+// result of each check, in the order given. The perfdata of all of
+// the checks is output together. A check not complete by the
+// deadline is UNKNOWN, so a hung check does not hide the results of
+// the others, as is a check that panics. A zero deadline waits for
+// every check.
+func RunMultiCheck(checks []func() CheckResult, deadline time.Time) CheckResult {
+	if len(checks) == 0 {
+		return UnknownResult(checkMultiName, "No checks to run")
+	}
+
+	type indexedResult struct {
+		index  int
+		result CheckResult
+	}
+
+	done := make(chan indexedResult, len(checks))
+
+	for i, check := range checks {
+		// A panic in the goroutine of a check is not recovered by the
+		// command, so each check recovers its own.
+		go func(i int, check func() CheckResult) {
+			done <- indexedResult{i, RecoverCheckResult(checkMultiName, check)}
+		}(i, check)
+	}
+
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	results := make([]*CheckResult, len(checks))
+
+	for remaining := len(checks); remaining > 0; remaining-- {
+		select {
+		case r := <-done:
+			results[r.index] = &r.result
+		case <-expired:
+			remaining = 0
+		}
+	}
+
+	state := StateOK
+	counts := make(map[State]int)
+	details := make([]string, len(checks))
+	var perfData PerfDataSet
+
+	for i, result := range results {
+		if result == nil {
+			timedOut := UnknownResult(checkMultiName, fmt.Sprintf("Check %d did not complete in time", i+1))
+			result = &timedOut
+		}
+
+		if worseState(result.State(), state) {
+			state = result.State()
+		}
+
+		counts[result.State()]++
+		details[i] = multiDetail(*result)
+		perfData.Append(result.PerfData...)
+	}
+
+	var summary []string
+	for _, s := range multiStateOrder {
+		if counts[s] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+
+	checkInfo := fmt.Sprintf("%d checks, %s\n%s", len(checks), strings.Join(summary, ", "), strings.Join(details, "\n"))
+
+	return NewCheckResult(checkMultiName, state, checkInfo, perfData...)
+}
+
+// CheckMulti executes RunMultiCheck() with checks returning the plain
+// text output and exit code, read back as their results.
+//
+// Returns are the output and exit code of the result of
+// RunMultiCheck()
+func CheckMulti(checks []func() (string, int), deadline time.Time) (string, int) {
+	resultChecks := make([]func() CheckResult, len(checks))
+
+	for i, check := range checks {
+		check := check
+		resultChecks[i] = func() CheckResult {
+			return ParseCheckResult(check())
+		}
+	}
+
+	return RunMultiCheck(resultChecks, deadline).Output()
+}
@@ -0,0 +1,89 @@
+package nagiosfoundation
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckMulti(t *testing.T) {
+	result := func(result CheckResult) func() (string, int) {
+		return func() (string, int) {
+			return result.Output()
+		}
+	}
+
+	sshd := result(OKResult("CheckProcess", "Process sshd is running", PerfData{Label: "sshd", Value: 0}))
+	disk := result(WarningResult("CheckDisk", "/ is 85% used", PerfData{Label: "root", Value: 85, UOM: "%", Warning: "80", Critical: "90"}))
+	nginx := result(CriticalResult("CheckProcess", "Process nginx is not running", PerfData{Label: "nginx", Value: 2}))
+	ntp := result(UnknownResult("CheckNtp", "No response from pool.ntp.org:123 within 10s"))
+
+	type testItem struct {
+		description  string
+		checks       []func() (string, int)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"All OK", []func() (string, int){sshd, sshd}, statusCodeOK,
+			"CheckMulti OK - 2 checks, 2 OK\nCheckProcess OK - Process sshd is running\nCheckProcess OK - Process sshd is running | sshd=0 sshd=0"},
+		{"Worst is critical", []func() (string, int){sshd, disk, nginx, ntp}, statusCodeCritical,
+			"CheckMulti CRITICAL - 4 checks, 1 CRITICAL, 1 WARNING, 1 UNKNOWN, 1 OK\n" +
+				"CheckProcess OK - Process sshd is running\n" +
+				"CheckDisk WARNING - / is 85% used\n" +
+				"CheckProcess CRITICAL - Process nginx is not running\n" +
+				"CheckNtp UNKNOWN - No response from pool.ntp.org:123 within 10s | sshd=0 root=85%;80;90 nginx=2"},
+		{"Warning worse than unknown", []func() (string, int){ntp, disk}, statusCodeWarning, "CheckMulti WARNING - 2 checks, 1 WARNING, 1 UNKNOWN"},
+		{"Unknown worse than OK", []func() (string, int){sshd, ntp}, statusCodeUnknown, "CheckMulti UNKNOWN - 2 checks, 1 UNKNOWN, 1 OK"},
+		{"Not Nagios output", []func() (string, int){func() (string, int) { return "flag provided but not defined", 3 }}, statusCodeUnknown,
+			"1 UNKNOWN\nUNKNOWN - flag provided but not defined"},
+		{"Check panics", []func() (string, int){sshd, func() (string, int) { panic("nil process list") }}, statusCodeUnknown,
+			"CheckProcess OK - Process sshd is running\nCheckMulti UNKNOWN - The check failed unexpectedly: nil process list"},
+		{"No checks", nil, statusCodeUnknown, "No checks to run"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckMulti(i.checks, time.Time{})
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	hung := make(chan struct{})
+	defer close(hung)
+
+	msg, code := CheckMulti([]func() (string, int){sshd, func() (string, int) {
+		<-hung
+		return sshd()
+	}}, time.Now().Add(50*time.Millisecond))
+
+	if code != statusCodeUnknown || !strings.Contains(msg, "CheckProcess OK - Process sshd is running\nCheckMulti UNKNOWN - Check 2 did not complete in time") {
+		t.Errorf("CheckMulti() should report a check not complete by the deadline as UNKNOWN: %s", msg)
+	}
+}
+
+func TestRunMultiCheck(t *testing.T) {
+	logCheck := func() CheckResult {
+		return WarningResult("CheckLog", "1 lines matching ERROR\nERROR disk full | retrying", PerfData{Label: "matches", Value: 1})
+	}
+
+	result := RunMultiCheck([]func() CheckResult{logCheck}, time.Time{})
+
+	if result.State() != StateWarning || !strings.HasSuffix(result.Message, "ERROR disk full | retrying") {
+		t.Errorf("RunMultiCheck() should keep the message of each check as it is: %+v", result)
+	}
+
+	if len(result.PerfData) != 1 || result.PerfData[0].Label != "matches" {
+		t.Errorf("RunMultiCheck() should not read the message of a check as perfdata: %+v", result.PerfData)
+	}
+
+	if msg, _ := result.Output(); !strings.HasSuffix(msg, "ERROR disk full ¦ retrying | matches=1") {
+		t.Errorf("RunMultiCheck() text output should not start the perfdata in a message: %q", msg)
+	}
+}
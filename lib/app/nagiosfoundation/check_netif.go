@@ -0,0 +1,297 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const checkNetifName = "CheckNetif"
+
+const netDevFile = "/proc/net/dev"
+
+const (
+	// defaultNetifInterval is the time the counters of an interface
+	// are sampled over when no interval is given.
+	defaultNetifInterval = time.Second
+
+	netifDirectionRx   = "rx"
+	netifDirectionTx   = "tx"
+	netifDirectionBoth = "both"
+)
+
+// NetifCheckOptions contains the options for a network interface check.
+type NetifCheckOptions struct {
+	// The name of the interface, such as "eth0".
+	Interface string
+
+	// The time the counters are sampled over. Defaults to 1s.
+	Interval time.Duration
+
+	// The direction checked against the thresholds, "rx" for the
+	// bytes received, "tx" for the bytes sent or "both", the default,
+	// checking each.
+	Direction string
+
+	// The warning and critical thresholds, Nagios ranges of the bytes
+	// per second in each direction checked.
+	Warning  string
+	Critical string
+
+	// The warning and critical thresholds of the errors and drops per
+	// second in the directions checked, not checked when empty.
+	ErrorsWarning  string
+	ErrorsCritical string
+
+	// The prefix of the perfdata labels. Defaults to "netif".
+	MetricName string
+}
+
+// netifCounters are the counters of an interface in /proc/net/dev.
+type netifCounters struct {
+	rxBytes, rxErrors, rxDrops uint64
+	txBytes, txErrors, txDrops uint64
+}
+
+// String returns the counters as read, such as "rx_bytes=1234
+// rx_errors=0 ...", for the raw values of a verbose result.
+func (c netifCounters) String() string {
+	return fmt.Sprintf("rx_bytes=%d rx_errors=%d rx_drops=%d tx_bytes=%d tx_errors=%d tx_drops=%d",
+		c.rxBytes, c.rxErrors, c.rxDrops, c.txBytes, c.txErrors, c.txDrops)
+}
+
+// parseNetDev returns the counters of the interface from the content
+// of /proc/net/dev, a line for each interface of its name and 16
+// counters, the first 8 received and the last 8 sent, such as
+// "  eth0: 1234 10 0 0 0 0 0 0 5678 12 0 0 0 0 0 0". The bool is false
+// when the interface is not listed.
+func parseNetDev(data, name string) (netifCounters, bool, error) {
+	for _, line := range strings.Split(data, "\n") {
+		nameCounters := strings.SplitN(line, ":", 2)
+		if len(nameCounters) != 2 || strings.TrimSpace(nameCounters[0]) != name {
+			continue
+		}
+
+		fields := strings.Fields(nameCounters[1])
+		if len(fields) < 16 {
+			return netifCounters{}, true, fmt.Errorf("Expected 16 counters for interface %s, found %d", name, len(fields))
+		}
+
+		values := make([]uint64, 16)
+		for i := range values {
+			value, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return netifCounters{}, true, fmt.Errorf("Invalid counter %q for interface %s", fields[i], name)
+			}
+
+			values[i] = value
+		}
+
+		return netifCounters{
+			rxBytes: values[0], rxErrors: values[2], rxDrops: values[3],
+			txBytes: values[8], txErrors: values[10], txDrops: values[11],
+		}, true, nil
+	}
+
+	return netifCounters{}, false, nil
+}
+
+// counterDelta returns the increase of a counter from previous to
+// current. A counter lower than before has either wrapped or been
+// reset. As many drivers keep 32-bit counters, a counter that was
+// below 2^32 is taken to have wrapped at 2^32, while a larger one is
+// taken to have been reset, such as by the interface being recreated,
+// and to have counted current since.
+func counterDelta(previous, current uint64) uint64 {
+	switch {
+	case current >= previous:
+		return current - previous
+	case previous <= math.MaxUint32:
+		return math.MaxUint32 - previous + current + 1
+	}
+
+	return current
+}
+
+// readNetifCounters reads the counters of the interface with readFile.
+func readNetifCounters(readFile func(string) ([]byte, error), name string) (netifCounters, error) {
+	data, err := readFile(netDevFile)
+	if err != nil {
+		return netifCounters{}, fmt.Errorf("Could not read %s: %s", netDevFile, err)
+	}
+
+	counters, found, err := parseNetDev(string(data), name)
+	if err != nil {
+		return netifCounters{}, err
+	} else if !found {
+		return netifCounters{}, fmt.Errorf("Interface %s not found in %s", name, netDevFile)
+	}
+
+	return counters, nil
+}
+
+// runNetifCheck performs the check of CheckNetifWithHandlers() and
+// returns its result.
+func runNetifCheck(options NetifCheckOptions, readFile func(string) ([]byte, error),
+	sleep func(time.Duration), now func() time.Time) CheckResult {
+	if options.Interface == "" {
+		return UnknownResult(checkNetifName, "An interface must be specified.")
+	}
+
+	direction := strings.ToLower(options.Direction)
+	switch direction {
+	case "":
+		direction = netifDirectionBoth
+	case netifDirectionRx, netifDirectionTx, netifDirectionBoth:
+	default:
+		return UnknownResult(checkNetifName, fmt.Sprintf("Invalid direction %q. Valid directions are \"rx\", \"tx\" and \"both\"", options.Direction))
+	}
+
+	interval := options.Interval
+	if interval == 0 {
+		interval = defaultNetifInterval
+	} else if interval < 0 {
+		return UnknownResult(checkNetifName, fmt.Sprintf("Invalid interval %s. The interval may not be negative.", interval))
+	}
+
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkNetifName, err.Error())
+	}
+
+	errorThresholds, err := ParseThresholds(options.ErrorsWarning, options.ErrorsCritical)
+	if err != nil {
+		return UnknownResult(checkNetifName, err.Error())
+	}
+
+	metricName := options.MetricName
+	if metricName == "" {
+		metricName = "netif"
+	}
+
+	before, err := readNetifCounters(readFile, options.Interface)
+	if err != nil {
+		return UnknownResult(checkNetifName, err.Error())
+	}
+
+	start := now()
+	sleep(interval)
+
+	after, err := readNetifCounters(readFile, options.Interface)
+	if err != nil {
+		return UnknownResult(checkNetifName, err.Error())
+	}
+
+	seconds := now().Sub(start).Seconds()
+	if seconds <= 0 {
+		seconds = interval.Seconds()
+	}
+
+	rate := func(previous, current uint64) float64 {
+		return math.Round(float64(counterDelta(previous, current))/seconds*100) / 100
+	}
+
+	rx := rate(before.rxBytes, after.rxBytes)
+	tx := rate(before.txBytes, after.txBytes)
+
+	var errorCount uint64
+	if direction != netifDirectionTx {
+		errorCount += counterDelta(before.rxErrors, after.rxErrors) + counterDelta(before.rxDrops, after.rxDrops)
+	}
+
+	if direction != netifDirectionRx {
+		errorCount += counterDelta(before.txErrors, after.txErrors) + counterDelta(before.txDrops, after.txDrops)
+	}
+
+	errorRate := math.Round(float64(errorCount)/seconds*100) / 100
+
+	state := StateOK
+	var tripped []string
+
+	check := func(desc string, value float64, t Thresholds) {
+		s, r := t.Status(value)
+		if s == StateOK {
+			return
+		}
+
+		if s == StateCritical || state == StateOK {
+			state = s
+		}
+
+		tripped = append(tripped, fmt.Sprintf("%s, expected %s", desc, r.Expected()))
+	}
+
+	if direction != netifDirectionTx {
+		check(fmt.Sprintf("received %s bytes/s", formatRangeBound(rx)), rx, thresholds)
+	}
+
+	if direction != netifDirectionRx {
+		check(fmt.Sprintf("sent %s bytes/s", formatRangeBound(tx)), tx, thresholds)
+	}
+
+	check(fmt.Sprintf("%s errors and drops/s", formatRangeBound(errorRate)), errorRate, errorThresholds)
+
+	checkInfo := fmt.Sprintf("Interface %s received %s and sent %s bytes/s with %s errors and drops/s over %s",
+		options.Interface, formatRangeBound(rx), formatRangeBound(tx), formatRangeBound(errorRate), interval)
+	if len(tripped) > 0 {
+		checkInfo += " (" + strings.Join(tripped, ", ") + ")"
+	}
+
+	checkInfo += verboseLines(VerbosityBreakdown,
+		fmt.Sprintf("Received %s bytes/s, %s errors/s, %s drops/s", formatRangeBound(rx),
+			formatRangeBound(rate(before.rxErrors, after.rxErrors)), formatRangeBound(rate(before.rxDrops, after.rxDrops))),
+		fmt.Sprintf("Sent %s bytes/s, %s errors/s, %s drops/s", formatRangeBound(tx),
+			formatRangeBound(rate(before.txErrors, after.txErrors)), formatRangeBound(rate(before.txDrops, after.txDrops))))
+	checkInfo += verboseLines(VerbosityRaw,
+		fmt.Sprintf("%s before: %s", netDevFile, before),
+		fmt.Sprintf("%s after %.3fs: %s", netDevFile, seconds, after))
+
+	rxMetric := PerfData{Label: metricName + "_rx", Value: rx, Min: "0"}
+	txMetric := PerfData{Label: metricName + "_tx", Value: tx, Min: "0"}
+
+	if direction != netifDirectionTx {
+		rxMetric = thresholds.Metric(rxMetric)
+	}
+
+	if direction != netifDirectionRx {
+		txMetric = thresholds.Metric(txMetric)
+	}
+
+	return NewCheckResult(checkNetifName, state, checkInfo, rxMetric, txMetric,
+		errorThresholds.Metric(PerfData{Label: metricName + "_errors", Value: errorRate, Min: "0"}))
+}
+
+// CheckNetifWithHandlers samples the counters of the options.Interface
+// interface from /proc/net/dev, read with readFile, twice,
+// options.Interval apart waiting with sleep, and compares the bytes
+// per second received and sent against the options.Warning and
+// options.Critical thresholds, for the directions selected by
+// options.Direction. The rates are taken over the time passed between
+// the samples measured with now, as sleep may wait longer. The errors
+// and drops per second of the directions checked are compared against
+// options.ErrorsWarning and options.ErrorsCritical. A counter wrapping
+// or reset between the samples is handled by counterDelta(). The rates
+// are output as perfdata. From VerbosityBreakdown the rates of each
+// direction are added on lines of their own, and from VerbosityRaw the
+// counters of both samples.
+func CheckNetifWithHandlers(options NetifCheckOptions, readFile func(string) ([]byte, error),
+	sleep func(time.Duration), now func() time.Time) (string, int) {
+	return runNetifCheck(options, readFile, sleep, now).Output()
+}
+
+// RunNetifCheck performs the interface check of CheckNetif() and
+// returns its result, without output.
+func RunNetifCheck(options NetifCheckOptions) CheckResult {
+	return runNetifCheck(options, ioutil.ReadFile, time.Sleep, time.Now)
+}
+
+// CheckNetif executes CheckNetifWithHandlers(), reading the counters
+// from /proc/net/dev.
+//
+// Returns are those of CheckNetifWithHandlers()
+func CheckNetif(options NetifCheckOptions) (string, int) {
+	return RunNetifCheck(options).Output()
+}
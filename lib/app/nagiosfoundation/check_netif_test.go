@@ -0,0 +1,159 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testNetDevHeader = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    9000      90    0    0    0     0          0         0     9000      90    0    0    0     0       0          0
+`
+
+func testNetDev(rxBytes, rxErrors, txBytes, txDrops uint64) string {
+	return testNetDevHeader + fmt.Sprintf("  eth0:%d 100 %d 0 0 0 0 0 %d 100 0 %d 0 0 0 0\n", rxBytes, rxErrors, txBytes, txDrops)
+}
+
+func TestParseNetDev(t *testing.T) {
+	counters, found, err := parseNetDev(testNetDev(1234, 2, 5678, 3), "eth0")
+	expected := netifCounters{rxBytes: 1234, rxErrors: 2, txBytes: 5678, txDrops: 3}
+	if err != nil || !found || counters != expected {
+		t.Errorf("parseNetDev() Expected: %+v, Actual: %+v, Found: %t, Error: %v", expected, counters, found, err)
+	}
+
+	if _, found, err := parseNetDev(testNetDevHeader, "eth9"); found || err != nil {
+		t.Errorf("parseNetDev() should not find a missing interface, Found: %t, Error: %v", found, err)
+	}
+
+	if _, _, err := parseNetDev("  eth0: 1 2 3\n", "eth0"); err == nil || !strings.Contains(err.Error(), "Expected 16 counters") {
+		t.Errorf("parseNetDev() should reject a short line, Error: %v", err)
+	}
+}
+
+func TestCounterDelta(t *testing.T) {
+	testList := []struct {
+		description string
+		previous    uint64
+		current     uint64
+		expected    uint64
+	}{
+		{"Increase", 1000, 1500, 500},
+		{"No change", 1000, 1000, 0},
+		{"32-bit wrap", math.MaxUint32 - 99, 100, 200},
+		{"Reset", 1 << 40, 100, 100},
+	}
+
+	for _, i := range testList {
+		if delta := counterDelta(i.previous, i.current); delta != i.expected {
+			t.Errorf("%s: Expected: %d, Actual: %d", i.description, i.expected, delta)
+		}
+	}
+}
+
+func TestCheckNetif(t *testing.T) {
+	type testItem struct {
+		description  string
+		options      NetifCheckOptions
+		before       string
+		after        string
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Under the thresholds", NetifCheckOptions{Interface: "eth0", Warning: "1000", Critical: "2000"},
+			testNetDev(1000, 0, 5000, 0), testNetDev(1500, 0, 5800, 0), statusCodeOK,
+			"CheckNetif OK - Interface eth0 received 500 and sent 800 bytes/s with 0 errors and drops/s over 1s | netif_rx=500;1000;2000;0 netif_tx=800;1000;2000;0 netif_errors=0;;;0"},
+		{"Sent over the warning", NetifCheckOptions{Interface: "eth0", Warning: "700", Critical: "2000"},
+			testNetDev(1000, 0, 5000, 0), testNetDev(1500, 0, 5800, 0), statusCodeWarning,
+			"Interface eth0 received 500 and sent 800 bytes/s with 0 errors and drops/s over 1s (sent 800 bytes/s, expected 0-700)"},
+		{"Only received checked", NetifCheckOptions{Interface: "eth0", Direction: "rx", Warning: "700"},
+			testNetDev(1000, 0, 5000, 0), testNetDev(1500, 0, 5800, 0), statusCodeOK, "netif_rx=500;700;;0 netif_tx=800;;;0"},
+		{"Critical wins", NetifCheckOptions{Interface: "eth0", Warning: "100", Critical: "600", MetricName: "eth0"},
+			testNetDev(1000, 0, 5000, 0), testNetDev(1500, 0, 5800, 0), statusCodeCritical, "(received 500 bytes/s, expected 0-100, sent 800 bytes/s, expected 0-600) | eth0_rx"},
+		{"Errors and drops", NetifCheckOptions{Interface: "eth0", ErrorsCritical: "1"},
+			testNetDev(1000, 0, 5000, 0), testNetDev(1000, 4, 5000, 2), statusCodeCritical, "with 6 errors and drops/s over 1s (6 errors and drops/s, expected 0-1)"},
+		{"Drops sent not counted for rx", NetifCheckOptions{Interface: "eth0", Direction: "rx", ErrorsCritical: "1"},
+			testNetDev(1000, 0, 5000, 0), testNetDev(1000, 0, 5000, 6), statusCodeOK, "netif_errors=0;;1;0"},
+		{"Wrapped counter", NetifCheckOptions{Interface: "eth0"},
+			testNetDev(math.MaxUint32-199, 0, 5000, 0), testNetDev(300, 0, 5000, 0), statusCodeOK, "received 500 and sent 0 bytes/s"},
+		{"Missing interface", NetifCheckOptions{Interface: "eth9"}, testNetDevHeader, testNetDevHeader, statusCodeUnknown,
+			"CheckNetif UNKNOWN - Interface eth9 not found in /proc/net/dev"},
+		{"Interface removed", NetifCheckOptions{Interface: "eth0"}, testNetDev(1000, 0, 5000, 0), testNetDevHeader, statusCodeUnknown,
+			"Interface eth0 not found"},
+		{"No interface", NetifCheckOptions{}, "", "", statusCodeUnknown, "An interface must be specified"},
+		{"Invalid direction", NetifCheckOptions{Interface: "eth0", Direction: "up"}, "", "", statusCodeUnknown, "Invalid direction \"up\""},
+		{"Negative interval", NetifCheckOptions{Interface: "eth0", Interval: -time.Second}, "", "", statusCodeUnknown, "may not be negative"},
+		{"Invalid threshold", NetifCheckOptions{Interface: "eth0", ErrorsWarning: "many"}, "", "", statusCodeUnknown, "Invalid range"},
+	}
+
+	for _, i := range testList {
+		reads := []string{i.before, i.after}
+		readFile := func(string) ([]byte, error) {
+			data := reads[0]
+			reads = reads[1:]
+
+			return []byte(data), nil
+		}
+
+		clock := time.Date(2019, 6, 4, 15, 4, 5, 0, time.UTC)
+		var slept time.Duration
+		sleep := func(d time.Duration) {
+			slept = d
+			clock = clock.Add(d)
+		}
+
+		msg, code := CheckNetifWithHandlers(i.options, readFile, sleep, func() time.Time { return clock })
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+
+		if code != statusCodeUnknown && slept != defaultNetifInterval {
+			t.Errorf("%s: Expected a sleep of %s, Actual: %s", i.description, defaultNetifInterval, slept)
+		}
+	}
+
+	// A sleep waiting longer than the interval lowers the rates.
+	reads := []string{testNetDev(1000, 0, 5000, 0), testNetDev(2000, 0, 6600, 0)}
+	readFile := func(string) ([]byte, error) {
+		data := reads[0]
+		reads = reads[1:]
+
+		return []byte(data), nil
+	}
+
+	clock := time.Now()
+	sleep := func(d time.Duration) { clock = clock.Add(2 * d) }
+	options := NetifCheckOptions{Interface: "eth0", Interval: 500 * time.Millisecond}
+	if msg, _ := CheckNetifWithHandlers(options, readFile, sleep, func() time.Time { return clock }); !strings.Contains(msg, "received 1000 and sent 1600 bytes/s") {
+		t.Errorf("CheckNetifWithHandlers() should take the rates over the time passed: %s", msg)
+	}
+
+	// From -vv each direction is on a line of its own, and from -vvv
+	// the counters of both samples.
+	reads = []string{testNetDev(1000, 0, 5000, 0), testNetDev(1500, 2, 5800, 1)}
+	SetVerbosity(VerbosityRaw)
+	msg, _ := CheckNetifWithHandlers(NetifCheckOptions{Interface: "eth0"}, readFile, func(d time.Duration) { clock = clock.Add(d) }, func() time.Time { return clock })
+	SetVerbosity(0)
+
+	expectedLines := "over 1s\nReceived 500 bytes/s, 2 errors/s, 0 drops/s\nSent 800 bytes/s, 0 errors/s, 1 drops/s\n" +
+		"/proc/net/dev before: rx_bytes=1000 rx_errors=0 rx_drops=0 tx_bytes=5000 tx_errors=0 tx_drops=0\n" +
+		"/proc/net/dev after 1.000s: rx_bytes=1500 rx_errors=2 rx_drops=0 tx_bytes=5800 tx_errors=0 tx_drops=1 |"
+	if !strings.Contains(msg, expectedLines) {
+		t.Errorf("CheckNetifWithHandlers() should add the rates and counters when verbose, Expected: %q, Actual: %q", expectedLines, msg)
+	}
+
+	readError := func(string) ([]byte, error) { return nil, fmt.Errorf("no such file") }
+	if msg, code := CheckNetifWithHandlers(NetifCheckOptions{Interface: "eth0"}, readError, func(time.Duration) {}, time.Now); code != statusCodeUnknown ||
+		!strings.Contains(msg, "Could not read /proc/net/dev: no such file") {
+		t.Errorf("CheckNetifWithHandlers() should return UNKNOWN when /proc/net/dev cannot be read: %d %s", code, msg)
+	}
+}
@@ -0,0 +1,203 @@
+package nagiosfoundation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+)
+
+const checkNTPName = "CheckNtp"
+
+// sntpPacketLength is the length of an SNTP packet without the
+// optional extension fields and authenticator.
+const sntpPacketLength = 48
+
+// ntpEpochOffset is the seconds from the NTP epoch of 1900 to the Unix
+// epoch of 1970.
+const ntpEpochOffset = 2208988800
+
+// The SNTP header fields used, the leap indicator, version and mode
+// packed in the first byte. A leap indicator of 3 is a server whose
+// clock is not synchronized.
+const (
+	sntpVersion           = 4
+	sntpModeClient        = 3
+	sntpModeServer        = 4
+	sntpLeapNotInSync     = 3
+	sntpStratumKissODeath = 0
+)
+
+// toNTPTime returns the 64 bit NTP timestamp of t, seconds since 1900
+// and a binary fraction of a second.
+func toNTPTime(t time.Time) uint64 {
+	nanos := uint64(t.UnixNano()) + ntpEpochOffset*uint64(time.Second)
+	seconds := nanos / uint64(time.Second)
+	fraction := (nanos % uint64(time.Second)) << 32 / uint64(time.Second)
+
+	return seconds<<32 | fraction
+}
+
+// fromNTPTime returns the time of a 64 bit NTP timestamp.
+func fromNTPTime(timestamp uint64) time.Time {
+	seconds := int64(timestamp>>32) - ntpEpochOffset
+	nanos := int64((timestamp & 0xffffffff) * uint64(time.Second) >> 32)
+
+	return time.Unix(seconds, nanos)
+}
+
+// newSNTPRequest returns a client request carrying the time it is
+// sent as its transmit timestamp, which the server returns as the
+// originate timestamp of its reply.
+func newSNTPRequest(sent time.Time) []byte {
+	request := make([]byte, sntpPacketLength)
+	request[0] = sntpVersion<<3 | sntpModeClient
+	binary.BigEndian.PutUint64(request[40:], toNTPTime(sent))
+
+	return request
+}
+
+// parseSNTPResponse checks the reply is the server's answer to the
+// request and returns the offset of the local clock from the server,
+// computed from the times the request was sent and the reply received
+// by the local clock and the times the server received the request
+// and sent the reply by its clock. A positive offset means the local
+// clock is behind the server.
+func parseSNTPResponse(request, response []byte, sent, received time.Time) (time.Duration, error) {
+	if len(response) < sntpPacketLength {
+		return 0, fmt.Errorf("Reply too short, %d bytes", len(response))
+	}
+
+	if mode := response[0] & 0x7; mode != sntpModeServer {
+		return 0, fmt.Errorf("Reply is not from a server, mode %d", mode)
+	}
+
+	if !bytes.Equal(response[24:32], request[40:48]) {
+		return 0, fmt.Errorf("Reply does not answer the request sent")
+	}
+
+	if stratum := response[1]; stratum == sntpStratumKissODeath {
+		return 0, fmt.Errorf("Server refused the request with kiss code %q", bytes.TrimRight(response[12:16], "\x00"))
+	}
+
+	if leap := response[0] >> 6; leap == sntpLeapNotInSync {
+		return 0, fmt.Errorf("Server clock is not synchronized")
+	}
+
+	serverReceived := fromNTPTime(binary.BigEndian.Uint64(response[32:]))
+	serverSent := fromNTPTime(binary.BigEndian.Uint64(response[40:]))
+
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+// sntpQuery sends an SNTP request to the address over UDP and returns
+// the offset of the local clock from the server, waiting up to
+// timeout for the reply.
+func sntpQuery(address string, timeout time.Duration) (time.Duration, error) {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	sent := time.Now()
+	conn.SetDeadline(sent.Add(timeout))
+
+	request := newSNTPRequest(sent)
+	if _, err := conn.Write(request); err != nil {
+		return 0, err
+	}
+
+	response := make([]byte, 1024)
+	n, err := conn.Read(response)
+	if err != nil {
+		return 0, err
+	}
+
+	return parseSNTPResponse(request, response[:n], sent, time.Now())
+}
+
+// runNTPCheck performs the check of CheckNTPWithHandler() and returns
+// its result.
+func runNTPCheck(server string, port, timeout int, warning, critical, timeoutExit string,
+	query func(string, time.Duration) (time.Duration, error)) CheckResult {
+	if server == "" {
+		return UnknownResult(checkNTPName, "A server must be specified.")
+	}
+
+	if port < 1 || port > 65535 {
+		return UnknownResult(checkNTPName, fmt.Sprintf("Invalid port (%d). The port must be from 1 to 65535.", port))
+	}
+
+	if timeout < 1 {
+		return UnknownResult(checkNTPName, fmt.Sprintf("Invalid timeout (%d). The timeout must be at least 1 second.", timeout))
+	}
+
+	thresholds, err := ParseThresholds(warning, critical)
+	if err != nil {
+		return UnknownResult(checkNTPName, err.Error())
+	}
+
+	if err := checkTimeoutExit(timeoutExit); err != nil {
+		return UnknownResult(checkNTPName, err.Error())
+	}
+
+	address := net.JoinHostPort(server, strconv.Itoa(port))
+
+	offset, err := query(address, time.Duration(timeout)*time.Second)
+	if err != nil {
+		if isTimeout(err) {
+			return timeoutResult(checkNTPName, timeoutExit, fmt.Sprintf("No response from %s within %ds", address, timeout))
+		}
+
+		return UnknownResult(checkNTPName, fmt.Sprintf("Could not query %s: %s", address, err))
+	}
+
+	seconds := math.Round(offset.Seconds()*1e6) / 1e6
+
+	state, tripped := thresholds.Status(math.Abs(seconds))
+
+	checkInfo := fmt.Sprintf("Clock offset is %.6f seconds from %s", seconds, server)
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+	}
+
+	return NewCheckResult(checkNTPName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: "offset",
+		Value: seconds,
+		UOM:   "s",
+	}))
+}
+
+// CheckNTPWithHandler queries the NTP server on port with the handler
+// using SNTP, allowing timeout seconds for the reply, and compares
+// the absolute offset of the local clock in seconds against the
+// warning and critical thresholds. A critical response is emitted
+// when the offset is outside the critical threshold, a warning
+// response when it is outside the warning threshold and a good
+// response otherwise. A server that replies that it cannot be trusted
+// emits an unknown response as nothing can be said of the local clock,
+// as does one that does not reply unless timeoutExit selects another
+// state, as with TimeoutStatus(). The offset is output as perfdata in
+// seconds, with its sign.
+func CheckNTPWithHandler(server string, port, timeout int, warning, critical, timeoutExit string,
+	query func(string, time.Duration) (time.Duration, error)) (string, int) {
+	return runNTPCheck(server, port, timeout, warning, critical, timeoutExit, query).Output()
+}
+
+// RunNTPCheck performs the NTP check of CheckNTP() and returns its
+// result, without output.
+func RunNTPCheck(server string, port, timeout int, warning, critical, timeoutExit string) CheckResult {
+	return runNTPCheck(server, port, timeout, warning, critical, timeoutExit, sntpQuery)
+}
+
+// CheckNTP executes CheckNTPWithHandler(), passing it a handler
+// querying the server with SNTP over UDP.
+//
+// Returns are those of CheckNTPWithHandler()
+func CheckNTP(server string, port, timeout int, warning, critical, timeoutExit string) (string, int) {
+	return RunNTPCheck(server, port, timeout, warning, critical, timeoutExit).Output()
+}
@@ -0,0 +1,180 @@
+package nagiosfoundation
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sntpTestResponse returns the reply of a server whose clock is ahead
+// of the local clock by skew to the request, with the first byte and
+// stratum given.
+func sntpTestResponse(request []byte, skew time.Duration, first, stratum byte) []byte {
+	response := make([]byte, sntpPacketLength)
+	response[0] = first
+	response[1] = stratum
+	copy(response[24:32], request[40:48])
+
+	now := time.Now().Add(skew)
+	binary.BigEndian.PutUint64(response[32:], toNTPTime(now))
+	binary.BigEndian.PutUint64(response[40:], toNTPTime(now))
+
+	return response
+}
+
+// sntpTestServer answers SNTP requests on a local port with the reply
+// to each request, until the connection is closed.
+func sntpTestServer(t *testing.T, reply func([]byte) []byte) (net.PacketConn, string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listening failed: %s", err)
+	}
+
+	go func() {
+		buffer := make([]byte, 1024)
+
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+
+			if response := reply(buffer[:n]); response != nil {
+				conn.WriteTo(response, addr)
+			}
+		}
+	}()
+
+	return conn, conn.LocalAddr().String()
+}
+
+func TestNTPTime(t *testing.T) {
+	now := time.Unix(1700000000, 123456789)
+
+	if got := fromNTPTime(toNTPTime(now)); got.Sub(now) > time.Microsecond || now.Sub(got) > time.Microsecond {
+		t.Errorf("fromNTPTime(toNTPTime()) should return the time given, expected %s, got %s", now, got)
+	}
+
+	if seconds := toNTPTime(time.Unix(0, 0)) >> 32; seconds != ntpEpochOffset {
+		t.Errorf("toNTPTime() of the Unix epoch should be %d seconds, got %d", uint64(ntpEpochOffset), seconds)
+	}
+}
+
+func TestSNTPQuery(t *testing.T) {
+	server, address := sntpTestServer(t, func(request []byte) []byte {
+		return sntpTestResponse(request, 5*time.Second, sntpVersion<<3|sntpModeServer, 2)
+	})
+	defer server.Close()
+
+	offset, err := sntpQuery(address, time.Second)
+	if err != nil || offset < 4900*time.Millisecond || offset > 5100*time.Millisecond {
+		t.Errorf("sntpQuery() should return an offset of 5s, returned %s, Error: %v", offset, err)
+	}
+
+	silent, silentAddress := sntpTestServer(t, func([]byte) []byte {
+		return nil
+	})
+	defer silent.Close()
+
+	if _, err = sntpQuery(silentAddress, 100*time.Millisecond); !isTimeout(err) {
+		t.Errorf("sntpQuery() should time out when the server does not reply, returned %v", err)
+	}
+
+	sent := time.Now()
+	request := newSNTPRequest(sent)
+
+	type testItem struct {
+		description string
+		response    []byte
+		expectedErr string
+	}
+
+	kiss := sntpTestResponse(request, 0, sntpVersion<<3|sntpModeServer, 0)
+	copy(kiss[12:16], "RATE")
+
+	stale := sntpTestResponse(request, 0, sntpVersion<<3|sntpModeServer, 2)
+	stale[31]++
+
+	testList := []testItem{
+		{"Too short", []byte{0x24, 2}, "Reply too short"},
+		{"Client mode", sntpTestResponse(request, 0, sntpVersion<<3|sntpModeClient, 2), "Reply is not from a server"},
+		{"Stale reply", stale, "Reply does not answer the request sent"},
+		{"Kiss of death", kiss, "Server refused the request with kiss code \"RATE\""},
+		{"Not synchronized", sntpTestResponse(request, 0, sntpLeapNotInSync<<6|sntpVersion<<3|sntpModeServer, 2), "Server clock is not synchronized"},
+	}
+
+	for _, i := range testList {
+		if _, err := parseSNTPResponse(request, i.response, sent, time.Now()); err == nil || !strings.Contains(err.Error(), i.expectedErr) {
+			t.Errorf("%s: Expected Error: %s, Actual Error: %v", i.description, i.expectedErr, err)
+		}
+	}
+}
+
+func TestCheckNTP(t *testing.T) {
+	offset := func(offset time.Duration) func(string, time.Duration) (time.Duration, error) {
+		return func(string, time.Duration) (time.Duration, error) {
+			return offset, nil
+		}
+	}
+
+	type testItem struct {
+		description  string
+		server       string
+		port         int
+		timeout      int
+		query        func(string, time.Duration) (time.Duration, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"In sync", "pool.ntp.org", 123, 10, offset(1234 * time.Microsecond), statusCodeOK,
+			"CheckNtp OK - Clock offset is 0.001234 seconds from pool.ntp.org | offset=0.001234s;0.5;1"},
+		{"Behind over warning", "pool.ntp.org", 123, 10, offset(-700 * time.Millisecond), statusCodeWarning,
+			"Clock offset is -0.700000 seconds from pool.ntp.org (expected 0-0.5) | offset=-0.7s;0.5;1"},
+		{"Ahead over critical", "pool.ntp.org", 123, 10, offset(3 * time.Second), statusCodeCritical, "(expected 0-1)"},
+		{"No response", "pool.ntp.org", 123, 10, func(string, time.Duration) (time.Duration, error) {
+			return 0, timeoutError{}
+		}, statusCodeUnknown, "No response from pool.ntp.org:123 within 10s"},
+		{"Refused", "pool.ntp.org", 123, 10, func(string, time.Duration) (time.Duration, error) {
+			return 0, errors.New("Server clock is not synchronized")
+		}, statusCodeUnknown, "Could not query pool.ntp.org:123: Server clock is not synchronized"},
+		{"No server", "", 123, 10, offset(0), statusCodeUnknown, "A server must be specified."},
+		{"Invalid port", "pool.ntp.org", 0, 10, offset(0), statusCodeUnknown, "Invalid port (0)"},
+		{"Invalid timeout", "pool.ntp.org", 123, 0, offset(0), statusCodeUnknown, "Invalid timeout (0)"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckNTPWithHandler(i.server, i.port, i.timeout, "0.5", "1", "", i.query)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	msg, code := CheckNTPWithHandler("pool.ntp.org", 123, 10, "high", "1", "", offset(0))
+	if code != statusCodeUnknown {
+		t.Errorf("CheckNTPWithHandler() should be UNKNOWN on an invalid threshold: %s", msg)
+	}
+
+	noResponse := func(string, time.Duration) (time.Duration, error) {
+		return 0, timeoutError{}
+	}
+
+	msg, code = CheckNTPWithHandler("pool.ntp.org", 123, 10, "0.5", "1", "critical", noResponse)
+	if code != statusCodeCritical || !strings.Contains(msg, "CheckNtp CRITICAL - No response from pool.ntp.org:123 within 10s") {
+		t.Errorf("CheckNTPWithHandler() should issue the timeout exit on no response: %d %s", code, msg)
+	}
+
+	msg, code = CheckNTPWithHandler("pool.ntp.org", 123, 10, "0.5", "1", "ok", offset(0))
+	if code != statusCodeUnknown || !strings.Contains(msg, `The timeout exit (--timeout_exit) "ok" is not valid`) {
+		t.Errorf("CheckNTPWithHandler() should be UNKNOWN on an invalid timeout exit: %d %s", code, msg)
+	}
+}
@@ -4,20 +4,12 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/nagiosformatters"
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/perfcounters"
 )
 
-// CheckPerformanceCounterWithHandler fetches a performance counter
-// specified with the counterName parameter. It then performs checks
-// against the value based on the threshold test specified along with
-// the warning and critical thresholds.
-//
-// Returns are a message stating the results of the check and a return
-// value from the check.
-func CheckPerformanceCounterWithHandler(warning, critical float64, greaterThan bool, pollingAttempts, pollingDelay int, metricName, counterName string, perfCounterHandler func(string, int, int) (perfcounters.PerformanceCounter, error)) (string, int) {
-	var msg string
-	var retcode int
+// runPerformanceCounterCheck performs the check of
+// CheckPerformanceCounterWithHandler() and returns its result.
+func runPerformanceCounterCheck(warning, critical float64, greaterThan bool, pollingAttempts, pollingDelay int, metricName, counterName string, perfCounterHandler func(string, int, int) (perfcounters.PerformanceCounter, error)) CheckResult {
 	var counter perfcounters.PerformanceCounter
 	var err error
 
@@ -27,16 +19,31 @@ func CheckPerformanceCounterWithHandler(warning, critical float64, greaterThan b
 		counter, err = perfCounterHandler(counterName, pollingAttempts, pollingDelay)
 	}
 
-	if err == nil {
-		if greaterThan {
-			msg, retcode = nagiosformatters.GreaterFormatNagiosCheck(counterName, counter.Value, warning, critical, metricName)
-		} else {
-			msg, retcode = nagiosformatters.LesserFormatNagiosCheck(counterName, counter.Value, warning, critical, metricName)
-		}
-	} else {
-		msg = fmt.Sprintf("%s CRITICAL - %s", counterName, err)
-		retcode = 2
+	if err != nil {
+		return CriticalResult(counterName, err.Error())
+	}
+
+	value := counter.Value
+
+	state := StateOK
+	switch {
+	case greaterThan && value > critical, !greaterThan && value < critical:
+		state = StateCritical
+	case greaterThan && value > warning, !greaterThan && value < warning:
+		state = StateWarning
 	}
 
-	return msg, retcode
+	return NewCheckResult(counterName, state, fmt.Sprintf("value = %f", value),
+		PerfData{Label: metricName, Value: value})
+}
+
+// CheckPerformanceCounterWithHandler fetches a performance counter
+// specified with the counterName parameter. It then performs checks
+// against the value based on the threshold test specified along with
+// the warning and critical thresholds.
+//
+// Returns are a message stating the results of the check and a return
+// value from the check.
+func CheckPerformanceCounterWithHandler(warning, critical float64, greaterThan bool, pollingAttempts, pollingDelay int, metricName, counterName string, perfCounterHandler func(string, int, int) (perfcounters.PerformanceCounter, error)) (string, int) {
+	return runPerformanceCounterCheck(warning, critical, greaterThan, pollingAttempts, pollingDelay, metricName, counterName, perfCounterHandler).Output()
 }
@@ -4,11 +4,17 @@ import (
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/perfcounters"
 )
 
+// RunPerformanceCounterCheck performs the performance counter check of
+// CheckPerformanceCounter() and returns its result, without output.
+func RunPerformanceCounterCheck(warning, critical float64, greaterThan bool, pollingAttempts, pollingDelay int, metricName, counterName string) CheckResult {
+	return runPerformanceCounterCheck(warning,
+		critical, greaterThan, pollingAttempts, pollingDelay,
+		metricName, counterName, perfcounters.ReadPerformanceCounter)
+}
+
 // CheckPerformanceCounter executes CheckPerformanceCounterWitHandler(),
 // passing it the OS constrained ReadPerformanceCounter() function, prints
 // the returned message and exits with the returned exit code.
 func CheckPerformanceCounter(warning, critical float64, greaterThan bool, pollingAttempts, pollingDelay int, metricName, counterName string) (string, int) {
-	return CheckPerformanceCounterWithHandler(warning,
-		critical, greaterThan, pollingAttempts, pollingDelay,
-		metricName, counterName, perfcounters.ReadPerformanceCounter)
+	return RunPerformanceCounterCheck(warning, critical, greaterThan, pollingAttempts, pollingDelay, metricName, counterName).Output()
 }
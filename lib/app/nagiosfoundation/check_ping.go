@@ -0,0 +1,409 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const checkPingName = "CheckPing"
+
+// The IANA protocol numbers of ICMP and ICMPv6, used to parse replies.
+const (
+	protocolICMP   = 1
+	protocolICMPv6 = 58
+)
+
+// pingStats are the results of the echo requests sent to a host.
+type pingStats struct {
+	sent      int
+	received  int
+	totalTime time.Duration
+}
+
+// packetLoss returns the percentage of echo requests not answered.
+func (s pingStats) packetLoss() int {
+	if s.sent == 0 {
+		return 100
+	}
+
+	return (s.sent - s.received) * 100 / s.sent
+}
+
+// roundTripAverage returns the average round trip time in
+// milliseconds of the echo requests answered.
+func (s pingStats) roundTripAverage() float64 {
+	if s.received == 0 {
+		return 0
+	}
+
+	return float64(s.totalTime) / float64(s.received) / float64(time.Millisecond)
+}
+
+// pingThreshold is a threshold of the classic check_ping, a round trip
+// average in milliseconds and a packet loss percentage.
+type pingThreshold struct {
+	rta float64
+	pl  int
+}
+
+// parsePingThreshold parses a threshold in the classic check_ping
+// format of "<rta>,<pl>%", such as "100.0,20%".
+func parsePingThreshold(threshold string) (pingThreshold, error) {
+	fields := strings.Split(threshold, ",")
+	if len(fields) != 2 {
+		return pingThreshold{}, fmt.Errorf("Invalid threshold %q, expected <rta>,<pl>%% such as 100.0,20%%", threshold)
+	}
+
+	rta, err := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+	if err != nil || rta < 0 {
+		return pingThreshold{}, fmt.Errorf("Invalid round trip average in threshold %q", threshold)
+	}
+
+	pl, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(fields[1]), "%"))
+	if err != nil || pl < 0 || pl > 100 {
+		return pingThreshold{}, fmt.Errorf("Invalid packet loss in threshold %q, it must be from 0%% to 100%%", threshold)
+	}
+
+	return pingThreshold{rta: rta, pl: pl}, nil
+}
+
+// exceeds reports whether the results reach the threshold. As in the
+// classic check_ping, a round trip average or packet loss equal to
+// the threshold reaches it.
+func (t pingThreshold) exceeds(stats pingStats) bool {
+	return stats.packetLoss() >= t.pl || (stats.received > 0 && stats.roundTripAverage() >= t.rta)
+}
+
+// listenICMP opens an ICMP socket for the network of the address. A
+// raw socket needing root or CAP_NET_RAW is tried first, then an
+// unprivileged datagram socket, which Linux allows for the groups in
+// net.ipv4.ping_group_range. The address to send to is returned in
+// the form the socket takes.
+func listenICMP(ip net.IP) (*icmp.PacketConn, net.Addr, error) {
+	rawNetwork, udpNetwork, listenAddress := "ip4:icmp", "udp4", "0.0.0.0"
+	if ip.To4() == nil {
+		rawNetwork, udpNetwork, listenAddress = "ip6:ipv6-icmp", "udp6", "::"
+	}
+
+	conn, rawErr := icmp.ListenPacket(rawNetwork, listenAddress)
+	if rawErr == nil {
+		return conn, &net.IPAddr{IP: ip}, nil
+	}
+
+	conn, udpErr := icmp.ListenPacket(udpNetwork, listenAddress)
+	if udpErr == nil {
+		return conn, &net.UDPAddr{IP: ip}, nil
+	}
+
+	return nil, nil, fmt.Errorf("raw socket: %s, unprivileged socket: %s", rawErr, udpErr)
+}
+
+// icmpPing sends count echo requests to the address one at a time,
+// waiting up to timeout for each reply.
+func icmpPing(ip net.IP, count int, timeout time.Duration) (pingStats, error) {
+	var stats pingStats
+
+	conn, dst, err := listenICMP(ip)
+	if err != nil {
+		return stats, fmt.Errorf("Could not open an ICMP socket, which needs root, CAP_NET_RAW or a group in net.ipv4.ping_group_range (%s)", err)
+	}
+	defer conn.Close()
+
+	var requestType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	protocol := protocolICMP
+	if ip.To4() == nil {
+		requestType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+		protocol = protocolICMPv6
+	}
+
+	// A raw socket receives every echo reply to the host, so replies
+	// are matched by ID. The kernel replaces the ID of an unprivileged
+	// echo request with the port of the socket and only delivers its
+	// own replies, so those are matched by the sequence number alone.
+	id := os.Getpid() & 0xffff
+	_, raw := dst.(*net.IPAddr)
+	reply := make([]byte, 1500)
+
+	for seq := 1; seq <= count; seq++ {
+		request, err := (&icmp.Message{
+			Type: requestType,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte(checkPingName)},
+		}).Marshal(nil)
+		if err != nil {
+			return stats, err
+		}
+
+		start := time.Now()
+		if _, err := conn.WriteTo(request, dst); err != nil {
+			return stats, fmt.Errorf("Could not send an echo request: %s", err)
+		}
+		stats.sent++
+
+		conn.SetReadDeadline(start.Add(timeout))
+
+		for {
+			n, _, err := conn.ReadFrom(reply)
+			if err != nil {
+				// Timed out, the request is lost.
+				break
+			}
+
+			message, err := icmp.ParseMessage(protocol, reply[:n])
+			if err != nil || message.Type != replyType {
+				continue
+			}
+
+			if echo, ok := message.Body.(*icmp.Echo); ok && echo.Seq == seq && (!raw || echo.ID == id) {
+				stats.received++
+				stats.totalTime += time.Since(start)
+				break
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// PingCheckOptions are the options of CheckPingWithOptions().
+type PingCheckOptions struct {
+	// The host to ping, a host name or an IPv4 or IPv6 address.
+	Host string
+
+	// The number of echo requests sent to each address pinged.
+	Count int
+
+	// The seconds to wait for all of the replies.
+	Timeout int
+
+	// The warning and critical thresholds in the classic check_ping
+	// format of "<rta>,<pl>%".
+	Warning  string
+	Critical string
+
+	// The IP version pinged, 4 or 6, or 0 for either.
+	IPVersion int
+
+	// The addresses of a host resolving to several that must answer,
+	// "any" for one of them, the default, or "all".
+	Addresses string
+}
+
+// pingResult is the result of the echo requests sent to one address
+// of a host.
+type pingResult struct {
+	ip    net.IP
+	stats pingStats
+	state State
+}
+
+// runPingCheck performs the check of CheckPingWithHandlers() and
+// returns its result.
+func runPingCheck(options PingCheckOptions, lookup func(string) ([]net.IP, error),
+	ping func(net.IP, int, time.Duration) (pingStats, error)) CheckResult {
+	host, count, timeout := options.Host, options.Count, options.Timeout
+
+	if host == "" {
+		return UnknownResult(checkPingName, "A host must be specified.")
+	}
+
+	if count < 1 {
+		return UnknownResult(checkPingName, fmt.Sprintf("Invalid count (%d). At least 1 packet must be sent.", count))
+	}
+
+	if timeout < 1 {
+		return UnknownResult(checkPingName, fmt.Sprintf("Invalid timeout (%d). The timeout must be at least 1 second.", timeout))
+	}
+
+	if err := checkIPVersion(options.IPVersion); err != nil {
+		return UnknownResult(checkPingName, err.Error())
+	}
+
+	mode, err := parseAddressesMode(options.Addresses)
+	if err != nil {
+		return UnknownResult(checkPingName, err.Error())
+	}
+
+	warningThreshold, err := parsePingThreshold(options.Warning)
+	if err != nil {
+		return UnknownResult(checkPingName, err.Error())
+	}
+
+	criticalThreshold, err := parsePingThreshold(options.Critical)
+	if err != nil {
+		return UnknownResult(checkPingName, err.Error())
+	}
+
+	ips, dnsTime, err := resolveAddresses(host, options.IPVersion, lookup)
+	if err != nil {
+		return UnknownResult(checkPingName, fmt.Sprintf("Could not resolve host %s: %s", host, err))
+	}
+
+	sort.SliceStable(ips, func(i, j int) bool { return ips[i].To4() != nil && ips[j].To4() == nil })
+
+	packetTimeout := time.Duration(timeout) * time.Second / time.Duration(count*len(ips))
+
+	var results []pingResult
+	var pingErrors []string
+	worst := 0
+
+	for _, ip := range ips {
+		stats, err := ping(ip, count, packetTimeout)
+		if err != nil {
+			if mode != addressesAny {
+				return UnknownResult(checkPingName, err.Error())
+			}
+
+			// Another address may still reply, such as the IPv6
+			// address of a host whose IPv4 address has no route.
+			debugLog.Printf("Could not ping %s: %s", ip, err)
+			pingErrors = append(pingErrors, err.Error())
+			continue
+		}
+
+		result := pingResult{ip: ip, stats: stats, state: StateOK}
+		switch {
+		case criticalThreshold.exceeds(stats):
+			result.state = StateCritical
+		case warningThreshold.exceeds(stats):
+			result.state = StateWarning
+		}
+
+		results = append(results, result)
+		if mode == addressesAny {
+			worst = len(results) - 1
+
+			if stats.received > 0 {
+				break
+			}
+		} else if worseState(result.state, results[worst].state) {
+			worst = len(results) - 1
+		}
+	}
+
+	if len(results) == 0 {
+		return UnknownResult(checkPingName, strings.Join(pingErrors, ", "))
+	}
+
+	// A host with several addresses names the address of each result.
+	describe := func(result pingResult) string {
+		target := host
+		if len(ips) > 1 {
+			target = fmt.Sprintf("%s (%s)", host, result.ip)
+		}
+
+		if result.stats.received == 0 {
+			return fmt.Sprintf("Packet loss = 100%%, no replies from %s", target)
+		}
+
+		return fmt.Sprintf("Packet loss = %d%%, RTA = %.2f ms to %s", result.stats.packetLoss(),
+			math.Round(result.stats.roundTripAverage()*1000)/1000, target)
+	}
+
+	var descs []string
+	if mode == addressesAll {
+		for _, result := range results {
+			descs = append(descs, describe(result))
+		}
+	} else {
+		descs = append(descs, describe(results[worst]))
+	}
+
+	stats := results[worst].stats
+	rta := math.Round(stats.roundTripAverage()*1000) / 1000
+	pl := stats.packetLoss()
+
+	perfData := []PerfData{{
+		Label:    "pl",
+		Value:    float64(pl),
+		UOM:      "%",
+		Warning:  strconv.Itoa(warningThreshold.pl),
+		Critical: strconv.Itoa(criticalThreshold.pl),
+		Min:      "0",
+	}}
+
+	// Without replies there is no round trip time to report.
+	if stats.received > 0 {
+		perfData = append([]PerfData{{
+			Label:    "rta",
+			Value:    rta,
+			UOM:      "ms",
+			Warning:  fmt.Sprintf("%f", warningThreshold.rta),
+			Critical: fmt.Sprintf("%f", criticalThreshold.rta),
+			Min:      "0.000000",
+		}}, perfData...)
+	}
+
+	perfData = append(perfData, dnsTimeMetric(dnsTime))
+
+	return NewCheckResult(checkPingName, results[worst].state, strings.Join(descs, "; "), perfData...)
+}
+
+// CheckPingWithHandlers sends Count echo requests to the host of the
+// options with the ping handler, allowing Timeout seconds in all, and
+// compares the round trip average and packet loss against the Warning
+// and Critical thresholds, given in the classic check_ping format of
+// "<rta>,<pl>%". A critical response is emitted when either reaches
+// the critical threshold, a warning response when either reaches the
+// warning threshold and a good response otherwise. The host is
+// resolved with lookup to its addresses of the IPVersion, the IPv4
+// addresses first. With several addresses they are pinged in turn
+// until one answers, or with Addresses "all" each is pinged and the
+// worst state is emitted, the timeout shared between them. The round
+// trip average and packet loss, of the address in the worst state,
+// are output as the rta and pl perfdata of the classic check_ping,
+// followed by the time taken resolving the host.
+func CheckPingWithHandlers(options PingCheckOptions, lookup func(string) ([]net.IP, error),
+	ping func(net.IP, int, time.Duration) (pingStats, error)) (string, int) {
+	return runPingCheck(options, lookup, ping).Output()
+}
+
+// CheckPingWithHandler executes CheckPingWithHandlers() with the host,
+// count, timeout and thresholds given, resolving the host to a single
+// address with resolve.
+//
+// Returns are those of CheckPingWithHandlers()
+func CheckPingWithHandler(host string, count, timeout int, warning, critical string,
+	resolve func(string) (net.IP, error), ping func(net.IP, int, time.Duration) (pingStats, error)) (string, int) {
+	lookup := func(host string) ([]net.IP, error) {
+		ip, err := resolve(host)
+		if err != nil {
+			return nil, err
+		}
+
+		return []net.IP{ip}, nil
+	}
+
+	return CheckPingWithHandlers(PingCheckOptions{Host: host, Count: count, Timeout: timeout, Warning: warning, Critical: critical}, lookup, ping)
+}
+
+// RunPingCheck performs the ping check of CheckPingWithOptions() and
+// returns its result, without output.
+func RunPingCheck(options PingCheckOptions) CheckResult {
+	return runPingCheck(options, net.LookupIP, icmpPing)
+}
+
+// CheckPingWithOptions executes CheckPingWithHandlers(), passing it
+// handlers resolving the host and sending ICMP echo requests.
+//
+// Returns are those of CheckPingWithHandlers()
+func CheckPingWithOptions(options PingCheckOptions) (string, int) {
+	return RunPingCheck(options).Output()
+}
+
+// CheckPing executes CheckPingWithOptions() with the host, count,
+// timeout and thresholds given.
+//
+// Returns are those of CheckPingWithHandlers()
+func CheckPing(host string, count, timeout int, warning, critical string) (string, int) {
+	return CheckPingWithOptions(PingCheckOptions{Host: host, Count: count, Timeout: timeout, Warning: warning, Critical: critical})
+}
@@ -0,0 +1,154 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckPing(t *testing.T) {
+	resolve := func(host string) (net.IP, error) {
+		if host == "nosuchhost" {
+			return nil, errors.New("no such host")
+		}
+
+		return net.ParseIP("192.0.2.10"), nil
+	}
+
+	ping := func(received int, rtt time.Duration) func(net.IP, int, time.Duration) (pingStats, error) {
+		return func(ip net.IP, count int, timeout time.Duration) (pingStats, error) {
+			if timeout != 2*time.Second {
+				t.Errorf("Each of the 5 packets should wait 2s of the 10s timeout, not %s", timeout)
+			}
+
+			return pingStats{sent: count, received: received, totalTime: time.Duration(received) * rtt}, nil
+		}
+	}
+
+	type testItem struct {
+		description  string
+		host         string
+		warning      string
+		ping         func(net.IP, int, time.Duration) (pingStats, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"OK", "db01", "100,20%", ping(5, 1500*time.Microsecond), statusCodeOK,
+			"CheckPing OK - Packet loss = 0%, RTA = 1.50 ms to db01 | rta=1.5ms;100.000000;500.000000;0.000000 pl=0%;20;60;0"},
+		{"Warning on round trip", "db01", "100,20%", ping(5, 150*time.Millisecond), statusCodeWarning, "RTA = 150.00 ms"},
+		{"Warning on packet loss", "db01", "100,20%", ping(4, time.Millisecond), statusCodeWarning, "Packet loss = 20%"},
+		{"Critical on round trip", "db01", "100,20%", ping(5, 600*time.Millisecond), statusCodeCritical, "RTA = 600.00 ms"},
+		{"No replies", "db01", "100,20%", ping(0, 0), statusCodeCritical, "Packet loss = 100%, no replies from db01 | pl=100%;20;60;0"},
+		{"Packet loss without percent", "db01", "100,20", ping(5, time.Millisecond), statusCodeOK, "pl=0%;20;60;0"},
+		{"Invalid threshold", "db01", "100", ping(5, time.Millisecond), statusCodeUnknown, "Invalid threshold \"100\""},
+		{"Invalid packet loss", "db01", "100,120%", ping(5, time.Millisecond), statusCodeUnknown, "Invalid packet loss"},
+		{"No host", "", "100,20%", ping(5, time.Millisecond), statusCodeUnknown, "A host must be specified."},
+		{"Unresolved host", "nosuchhost", "100,20%", ping(5, time.Millisecond), statusCodeUnknown, "Could not resolve host nosuchhost"},
+		{"No ICMP socket", "db01", "100,20%", func(net.IP, int, time.Duration) (pingStats, error) {
+			return pingStats{}, errors.New("Could not open an ICMP socket")
+		}, statusCodeUnknown, "CheckPing UNKNOWN - Could not open an ICMP socket"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckPingWithHandler(i.host, 5, 10, i.warning, "500,60%", resolve, i.ping)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+func TestCheckPingAddresses(t *testing.T) {
+	lookup := func(host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("2001:db8::10"), net.ParseIP("192.0.2.10")}, nil
+	}
+
+	var pinged []string
+	ping := func(answering ...string) func(net.IP, int, time.Duration) (pingStats, error) {
+		return func(ip net.IP, count int, timeout time.Duration) (pingStats, error) {
+			pinged = append(pinged, fmt.Sprintf("%s %s", ip, timeout))
+
+			for _, a := range answering {
+				if ip.String() == a {
+					return pingStats{sent: count, received: count, totalTime: time.Duration(count) * time.Millisecond}, nil
+				}
+			}
+
+			return pingStats{sent: count}, nil
+		}
+	}
+
+	failing := func(failed []string, next func(net.IP, int, time.Duration) (pingStats, error)) func(net.IP, int, time.Duration) (pingStats, error) {
+		return func(ip net.IP, count int, timeout time.Duration) (pingStats, error) {
+			for _, f := range failed {
+				if ip.String() == f {
+					pinged = append(pinged, fmt.Sprintf("%s %s", ip, timeout))
+					return pingStats{}, fmt.Errorf("no route to %s", ip)
+				}
+			}
+
+			return next(ip, count, timeout)
+		}
+	}
+
+	type testItem struct {
+		description    string
+		options        PingCheckOptions
+		ping           func(net.IP, int, time.Duration) (pingStats, error)
+		expectedCode   int
+		expectedMsg    string
+		expectedPinged []string
+	}
+
+	testList := []testItem{
+		{"IPv4 first", PingCheckOptions{Host: "db01"}, ping("192.0.2.10", "2001:db8::10"), statusCodeOK,
+			"CheckPing OK - Packet loss = 0%, RTA = 1.00 ms to db01 (192.0.2.10) | rta=1ms", []string{"192.0.2.10 1s"}},
+		{"IPv4 not answering", PingCheckOptions{Host: "db01"}, ping("2001:db8::10"), statusCodeOK,
+			"Packet loss = 0%, RTA = 1.00 ms to db01 (2001:db8::10)", []string{"192.0.2.10 1s", "2001:db8::10 1s"}},
+		{"No address answering", PingCheckOptions{Host: "db01"}, ping(), statusCodeCritical,
+			"Packet loss = 100%, no replies from db01 (2001:db8::10) | pl=100%;20;60;0 dns_time=", []string{"192.0.2.10 1s", "2001:db8::10 1s"}},
+		{"IPv4 failing", PingCheckOptions{Host: "db01"}, failing([]string{"192.0.2.10"}, ping("2001:db8::10")), statusCodeOK,
+			"CheckPing OK - Packet loss = 0%, RTA = 1.00 ms to db01 (2001:db8::10)", []string{"192.0.2.10 1s", "2001:db8::10 1s"}},
+		{"Every address failing", PingCheckOptions{Host: "db01"}, failing([]string{"192.0.2.10", "2001:db8::10"}, ping()), statusCodeUnknown,
+			"CheckPing UNKNOWN - no route to 192.0.2.10, no route to 2001:db8::10", []string{"192.0.2.10 1s", "2001:db8::10 1s"}},
+		{"All addresses with one failing", PingCheckOptions{Host: "db01", Addresses: "all"}, failing([]string{"2001:db8::10"}, ping("192.0.2.10")), statusCodeUnknown,
+			"CheckPing UNKNOWN - no route to 2001:db8::10", []string{"192.0.2.10 1s", "2001:db8::10 1s"}},
+		{"All addresses", PingCheckOptions{Host: "db01", Addresses: "all"}, ping("192.0.2.10"), statusCodeCritical,
+			"CheckPing CRITICAL - Packet loss = 0%, RTA = 1.00 ms to db01 (192.0.2.10); Packet loss = 100%, no replies from db01 (2001:db8::10) | pl=100%",
+			[]string{"192.0.2.10 1s", "2001:db8::10 1s"}},
+		{"IPv6 only", PingCheckOptions{Host: "db01", IPVersion: 6}, ping("2001:db8::10"), statusCodeOK,
+			"RTA = 1.00 ms to db01 |", []string{"2001:db8::10 2s"}},
+		{"IPv6 literal", PingCheckOptions{Host: "2001:db8::20"}, ping("2001:db8::20"), statusCodeOK,
+			"RTA = 1.00 ms to 2001:db8::20 |", []string{"2001:db8::20 2s"}},
+		{"Invalid IP version", PingCheckOptions{Host: "db01", IPVersion: 5}, ping(), statusCodeUnknown, "Invalid IP version (5)", nil},
+	}
+
+	for _, i := range testList {
+		pinged = nil
+
+		i.options.Count, i.options.Timeout, i.options.Warning, i.options.Critical = 5, 10, "100,20%", "500,60%"
+		msg, code := CheckPingWithHandlers(i.options, lookup, i.ping)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+
+		if !reflect.DeepEqual(pinged, i.expectedPinged) {
+			t.Errorf("%s: Expected Pinged: %v, Actual Pinged: %v", i.description, i.expectedPinged, pinged)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const checkPortRangeName = "CheckPortRange"
+
+// PortRangeCheckOptions contains the options for a check of the TCP
+// ports listening on the host.
+type PortRangeCheckOptions struct {
+	// The ports that must be listening, a comma separated list of
+	// ports and ranges of ports such as "9000-9010,9200".
+	Ports string
+
+	// The warning and critical thresholds, Nagios ranges of the number
+	// of the ports that are not listening. The bounds may also be
+	// percentages of the ports checked, such as "10%". Without either,
+	// any port not listening is critical.
+	Warning  string
+	Critical string
+
+	// The name of the metric in the nagios output. Defaults to
+	// "missing".
+	MetricName string
+}
+
+// parsePortSpec returns the ports of a comma separated list of ports
+// and ranges of ports such as "9000-9010,9200", in ascending order and
+// each once.
+func parsePortSpec(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+	var ports []int
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(item, "-", 2)
+		first, err := parsePort(bounds[0])
+		last := first
+
+		if err == nil && len(bounds) == 2 {
+			last, err = parsePort(bounds[1])
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ports %q: %s", item, err)
+		} else if last < first {
+			return nil, fmt.Errorf("Invalid ports %q: the range ends before it starts", item)
+		}
+
+		for port := first; port <= last; port++ {
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("No ports given")
+	}
+
+	sort.Ints(ports)
+
+	return ports, nil
+}
+
+// parsePort returns the TCP port of the text, from 1 to 65535.
+func parsePort(text string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("%q is not a port from 1 to 65535", strings.TrimSpace(text))
+	}
+
+	return port, nil
+}
+
+// portsText returns the ports, in ascending order, as listed in a
+// result with consecutive ports joined in a range, such as
+// "9003-9005, 9007".
+func portsText(ports []int) string {
+	var listed []string
+
+	for i := 0; i < len(ports); {
+		j := i
+		for j+1 < len(ports) && ports[j+1] == ports[j]+1 {
+			j++
+		}
+
+		if j == i {
+			listed = append(listed, strconv.Itoa(ports[i]))
+		} else {
+			listed = append(listed, fmt.Sprintf("%d-%d", ports[i], ports[j]))
+		}
+
+		i = j + 1
+	}
+
+	return strings.Join(listed, ", ")
+}
+
+// runPortRangeCheck performs the check of CheckPortRangeWithHandlers()
+// and returns its result.
+func runPortRangeCheck(options PortRangeCheckOptions, readFile func(string) ([]byte, error)) CheckResult {
+	ports, err := parsePortSpec(options.Ports)
+	if err != nil {
+		return UnknownResult(checkPortRangeName, err.Error())
+	}
+
+	if options.Warning == "" && options.Critical == "" {
+		options.Critical = "0"
+	}
+
+	relativeThresholds, err := ParseRelativeThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkPortRangeName, err.Error())
+	}
+
+	thresholds := relativeThresholds.Resolve(float64(len(ports)))
+
+	metricName := options.MetricName
+	if metricName == "" {
+		metricName = "missing"
+	}
+
+	listening := make(map[int]bool)
+
+	for _, netFile := range []string{"tcp", "tcp6"} {
+		path := defaultProcRoot + "/net/" + netFile
+
+		// tcp6 is missing when IPv6 is disabled.
+		data, err := readFile(path)
+		if netFile == "tcp6" && os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return errorResult(checkPortRangeName, fmt.Sprintf("Could not read the sockets from %s: %s", path, err), classifyError(err))
+		}
+
+		for _, port := range parseNetTCPListeners(string(data)) {
+			listening[port] = true
+		}
+	}
+
+	var missing []int
+	for _, port := range ports {
+		if !listening[port] {
+			missing = append(missing, port)
+		}
+	}
+
+	state, tripped := thresholds.Status(float64(len(missing)))
+
+	var checkInfo string
+	if len(missing) == 0 {
+		checkInfo = fmt.Sprintf("All %d ports of %s are listening", len(ports), portsText(ports))
+	} else {
+		checkInfo = fmt.Sprintf("%d of %d ports not listening", len(missing), len(ports))
+
+		if state != StateOK {
+			checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+		}
+
+		checkInfo += ": " + portsText(missing)
+	}
+
+	return NewCheckResult(checkPortRangeName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: metricName,
+		Value: float64(len(missing)),
+		Min:   "0",
+		Max:   strconv.Itoa(len(ports)),
+	}))
+}
+
+// CheckPortRangeWithHandlers checks every TCP port of options.Ports is
+// listening on the host, reading the state of the sockets from
+// /proc/net/tcp and /proc/net/tcp6 with readFile rather than
+// connecting to each port. The number of ports not listening is
+// compared against the options.Warning and options.Critical
+// thresholds, with percentages resolved against the number of ports
+// checked, or is critical from one port without either, and the ports
+// not listening are listed in the result. The sockets are those
+// of the network namespace of the check, so a check run in a container
+// sees the ports of the container. The number of ports not listening
+// is output as perfdata, with the thresholds resolved to numbers of
+// ports.
+func CheckPortRangeWithHandlers(options PortRangeCheckOptions, readFile func(string) ([]byte, error)) (string, int) {
+	return runPortRangeCheck(options, readFile).Output()
+}
+
+// RunPortRangeCheck performs the port range check of CheckPortRange()
+// and returns its result, without output.
+func RunPortRangeCheck(options PortRangeCheckOptions) CheckResult {
+	return runPortRangeCheck(options, ioutil.ReadFile)
+}
+
+// CheckPortRange executes CheckPortRangeWithHandlers(), reading the
+// sockets with ioutil.ReadFile().
+//
+// Returns are those of CheckPortRangeWithHandlers()
+func CheckPortRange(options PortRangeCheckOptions) (string, int) {
+	return RunPortRangeCheck(options).Output()
+}
@@ -0,0 +1,108 @@
+package nagiosfoundation
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePortSpec(t *testing.T) {
+	type testItem struct {
+		spec        string
+		expected    []int
+		expectedErr string
+	}
+
+	testList := []testItem{
+		{"9000-9003,9200", []int{9000, 9001, 9002, 9003, 9200}, ""},
+		{" 443, 80 ,80-81 ", []int{80, 81, 443}, ""},
+		{"22", []int{22}, ""},
+		{"9010-9000", nil, "the range ends before it starts"},
+		{"0-10", nil, `"0" is not a port from 1 to 65535`},
+		{"8080,http", nil, `Invalid ports "http"`},
+		{"65536", nil, "is not a port"},
+		{" , ", nil, "No ports given"},
+	}
+
+	for _, i := range testList {
+		ports, err := parsePortSpec(i.spec)
+
+		if i.expectedErr == "" && (err != nil || !reflect.DeepEqual(ports, i.expected)) {
+			t.Errorf("parsePortSpec(%q) Expected: %v, Actual: %v, Error: %v", i.spec, i.expected, ports, err)
+		} else if i.expectedErr != "" && (err == nil || !strings.Contains(err.Error(), i.expectedErr)) {
+			t.Errorf("parsePortSpec(%q) Expected Error: %q, Actual Error: %v", i.spec, i.expectedErr, err)
+		}
+	}
+
+	if text := portsText([]int{80, 9003, 9004, 9005, 9007, 9008}); text != "80, 9003-9005, 9007-9008" {
+		t.Errorf("portsText() incorrect: %s", text)
+	}
+}
+
+func TestCheckPortRange(t *testing.T) {
+	header := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	tcp := header +
+		"   0: 00000000:2328 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 31001 1 0000000000000000 100 0 0 10 0\n" +
+		"   1: 00000000:2329 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 31002 1 0000000000000000 100 0 0 10 0\n" +
+		"   2: 0100007F:232B 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 31003 1 0000000000000000 100 0 0 10 0\n" +
+		"   3: 0100007F:232A 0100007F:9C40 01 00000000:00000000 00:00000000 00000000  1000        0 31004 1 0000000000000000 100 0 0 10 0\n"
+	tcp6 := header +
+		"   0: 00000000000000000000000000000000:232A 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 31005 1 0000000000000000 100 0 0 10 0\n"
+
+	readFile := func(files map[string]string) func(string) ([]byte, error) {
+		return func(path string) ([]byte, error) {
+			if data, ok := files[path]; ok {
+				return []byte(data), nil
+			}
+
+			return nil, os.ErrNotExist
+		}
+	}
+
+	both := map[string]string{"/proc/net/tcp": tcp, "/proc/net/tcp6": tcp6}
+
+	type testItem struct {
+		description  string
+		options      PortRangeCheckOptions
+		files        map[string]string
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"All listening", PortRangeCheckOptions{Ports: "9000-9003"}, both, statusCodeOK,
+			"CheckPortRange OK - All 4 ports of 9000-9003 are listening | missing=0;;0;0;4"},
+		{"Missing ports", PortRangeCheckOptions{Ports: "9000-9006,9010"}, both, statusCodeCritical,
+			"CheckPortRange CRITICAL - 4 of 8 ports not listening (expected 0): 9004-9006, 9010 | missing=4;;0;0;8"},
+		{"Missing under the thresholds", PortRangeCheckOptions{Ports: "9000-9005", Warning: "2", Critical: "4", MetricName: "down"}, both, statusCodeOK,
+			"CheckPortRange OK - 2 of 6 ports not listening: 9004-9005 | down=2;2;4;0;6"},
+		{"Missing over the warning", PortRangeCheckOptions{Ports: "9000-9005", Warning: "1"}, both, statusCodeWarning,
+			"2 of 6 ports not listening (expected 0-1): 9004-9005"},
+		{"Missing over a percentage", PortRangeCheckOptions{Ports: "9000-9009", Warning: "10%", Critical: "60%"}, both, statusCodeWarning,
+			"CheckPortRange WARNING - 6 of 10 ports not listening (expected 0-1): 9004-9009 | missing=6;1;6;0;10"},
+		{"Missing under a percentage", PortRangeCheckOptions{Ports: "9000-9004", Critical: "20%"}, both, statusCodeOK,
+			"1 of 5 ports not listening: 9004 | missing=1;;1;0;5"},
+		{"Connected socket not listening", PortRangeCheckOptions{Ports: "9002"}, map[string]string{"/proc/net/tcp": tcp}, statusCodeCritical,
+			"1 of 1 ports not listening (expected 0): 9002"},
+		{"No tcp6", PortRangeCheckOptions{Ports: "9000,9001"}, map[string]string{"/proc/net/tcp": tcp}, statusCodeOK,
+			"All 2 ports of 9000-9001 are listening"},
+		{"No tcp", PortRangeCheckOptions{Ports: "9000"}, map[string]string{}, statusCodeUnknown,
+			"Could not read the sockets from /proc/net/tcp"},
+		{"Invalid ports", PortRangeCheckOptions{Ports: "9000-"}, both, statusCodeUnknown, `Invalid ports "9000-"`},
+		{"Invalid threshold", PortRangeCheckOptions{Ports: "9000", Warning: "few"}, both, statusCodeUnknown, "Invalid range"},
+		{"Invalid percentage", PortRangeCheckOptions{Ports: "9000", Critical: "150%"}, both, statusCodeUnknown, "not a percentage from 0% to 100%"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckPortRangeWithHandlers(i.options, readFile(i.files))
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+	}
+}
@@ -5,14 +5,46 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/user"
+	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 const checkProcessName = "CheckProcess"
 
-func getPidNameWithHandler(readFile func(string) ([]byte, error), pid int) (string, error) {
-	procFile := fmt.Sprintf("/proc/%d/stat", pid)
+// defaultProcRoot is where the proc filesystem is mounted.
+const defaultProcRoot = "/proc"
+
+// errProcessNotRunning is the error of the details of a process that
+// is not running, of the kind ErrTargetNotFound.
+var errProcessNotRunning error = &CheckError{Kind: ErrTargetNotFound, Err: errors.New("Process not running")}
+
+// processExited reports whether an error reading the details of a
+// process listed in the proc filesystem is from the process having
+// exited since, as a process exits between the scan listing it and its
+// files being read. Its files are then gone, or a file opened before
+// it exited fails the read with ESRCH. Other errors, such as a
+// permission denied, are not from the process exiting.
+func processExited(err error) bool {
+	if os.IsNotExist(err) {
+		return true
+	}
+
+	if pathErr, ok := err.(*os.PathError); ok {
+		err = pathErr.Err
+	}
+
+	return err == syscall.ESRCH
+}
+
+func getPidNameWithHandler(readFile func(string) ([]byte, error), procRoot string, pid int) (string, error) {
+	procFile := fmt.Sprintf("%s/%d/stat", procRoot, pid)
 	procDataBytes, err := readFile(procFile)
 	if err != nil {
 		return "", err
@@ -32,26 +64,239 @@ func getPidNameWithHandler(readFile func(string) ([]byte, error), pid int) (stri
 	return procName, nil
 }
 
+// parseStatParent returns the parent PID and the process group ID of
+// a process, fields 4 and 5 of /proc/<pid>/stat. As with the start
+// time, the fields are counted from the last closing parenthesis of
+// the process name.
+func parseStatParent(data string) (int, int, error) {
+	nameEnd := strings.LastIndex(data, ")")
+	if nameEnd < 0 {
+		return 0, 0, fmt.Errorf("Could not parse process stat")
+	}
+
+	fields := strings.Fields(data[nameEnd+1:])
+	if len(fields) < 3 {
+		return 0, 0, fmt.Errorf("Could not parse process parent, too few stat fields")
+	}
+
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("Could not parse process parent: %s", err)
+	}
+
+	pgid, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, 0, fmt.Errorf("Could not parse process group: %s", err)
+	}
+
+	return ppid, pgid, nil
+}
+
 func getPidName(pid int) (string, error) {
-	return getPidNameWithHandler(ioutil.ReadFile, pid)
+	return getPidNameWithHandler(ioutil.ReadFile, defaultProcRoot, pid)
 }
 
 type processByNameHandlers struct {
 	open       func(string) (*os.File, error)
 	close      func(*os.File) error
 	readDir    func(*os.File, int) ([]os.FileInfo, error)
-	getPidName func(readFile func(string) ([]byte, error), pid int) (string, error)
+	getPidName func(readFile func(string) ([]byte, error), procRoot string, pid int) (string, error)
 	readFile   func(string) ([]byte, error)
+	listDir    func(string) ([]string, error)
+	readLink   func(string) (string, error)
+	stat       func(string) (os.FileInfo, error)
+
+	// When set, only processes in this PID namespace match. It is
+	// the path of a namespace link such as /proc/<pid>/ns/pid, the
+	// PID of a process in the namespace or a container ID.
+	pidNamespace string
+
+	// When set, only processes with a command line containing
+	// this text match.
+	matchCmdline string
+
+	// When set, only processes running this executable match, the
+	// target of the /proc/<pid>/exe link read with readLink. A path
+	// ending in "/" matches the executables under it.
+	exePath string
+
+	// When set, only processes owned by this user, a user name or
+	// UID, match. The owner is the owner of the /proc/<pid>
+	// directory, read with getPidUID, and the user name is resolved
+	// to a UID with lookupUID.
+	user      string
+	getPidUID func(stat func(string) (os.FileInfo, error), procRoot string, pid int) (string, error)
+	lookupUID func(string) (string, error)
+
+	// When set, only the children of the process with this PID, or
+	// the processes in the process group with this ID, match. Both
+	// are read from /proc/<pid>/stat.
+	ppid int
+	pgid int
+
+	// When set, the process name, matchCmdline and exePath are
+	// regular expressions matched against the process name, command
+	// line and executable.
+	regex bool
+
+	// Where the proc filesystem is read from, such as a host /proc
+	// mounted at /host/proc inside a container. Defaults to /proc.
+	procRoot string
+
+	// The number of process names read at once during a scan. Up to
+	// 1 reads them one at a time.
+	concurrency int
+}
+
+// procDir returns the directory the proc filesystem is read from.
+func (svc processByNameHandlers) procDir() string {
+	if svc.procRoot == "" {
+		return defaultProcRoot
+	}
+
+	return strings.TrimSuffix(svc.procRoot, "/")
+}
+
+// newTextMatcher returns a function reporting if text matches the
+// pattern. When regex is set, the pattern is a regular expression,
+// otherwise the text matches when equal reports it matches the
+// pattern.
+func newTextMatcher(pattern string, regex bool, equal func(text, pattern string) bool) (func(string) bool, error) {
+	if !regex {
+		return func(text string) bool { return equal(text, pattern) }, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid regular expression %q: %s", pattern, err)
+	}
+
+	return re.MatchString, nil
+}
+
+func equalText(text, pattern string) bool {
+	return text == pattern
+}
+
+// getPidCmdlineWithHandler returns the command line of a process from
+// /proc/<pid>/cmdline, where the arguments are separated and ended by
+// NUL characters, with the arguments joined by spaces.
+func getPidCmdlineWithHandler(readFile func(string) ([]byte, error), procRoot string, pid int) (string, error) {
+	cmdline, err := readFile(fmt.Sprintf("%s/%d/cmdline", procRoot, pid))
+	if err != nil {
+		return "", err
+	}
+
+	args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+
+	return strings.Join(args, " "), nil
+}
+
+// resolvePidNamespace returns the namespace the pidNamespace setting
+// refers to. A setting that is neither a PID nor a path is taken as a
+// container ID and resolved through the first process with the ID in
+// its cgroup, as container runtimes name the container cgroup after
+// the container ID.
+func resolvePidNamespace(svc processByNameHandlers, procEntries []os.FileInfo) (string, error) {
+	link := svc.pidNamespace
+
+	if _, err := strconv.Atoi(svc.pidNamespace); err == nil {
+		link = fmt.Sprintf("%s/%s/ns/pid", svc.procDir(), svc.pidNamespace)
+	} else if !strings.HasPrefix(svc.pidNamespace, "/") {
+		link = ""
+
+		for _, procEntry := range procEntries {
+			pid, err := strconv.Atoi(procEntry.Name())
+			if err != nil || !procEntry.IsDir() {
+				continue
+			}
+
+			cgroup, err := svc.readFile(fmt.Sprintf("%s/%d/cgroup", svc.procDir(), pid))
+			if err == nil && strings.Contains(string(cgroup), svc.pidNamespace) {
+				link = fmt.Sprintf("%s/%d/ns/pid", svc.procDir(), pid)
+				break
+			}
+		}
+
+		if link == "" {
+			return "", fmt.Errorf("Could not find a process in container %s", svc.pidNamespace)
+		}
+	}
+
+	namespace, err := svc.readLink(link)
+	if err != nil {
+		return "", wrapError(err, "Could not read PID namespace %s: %s", svc.pidNamespace, err)
+	}
+
+	return namespace, nil
 }
 
 func getProcessesByNameWithHandlers(svc processByNameHandlers, name string) ([]os.FileInfo, error) {
+	return findProcessesByNameWithHandlers(svc, name, 0)
+}
+
+// getProcessesRunningWithHandlers reports which of the named processes
+// are running, from a single scan of the proc filesystem that stops
+// once a process is found for every name.
+func getProcessesRunningWithHandlers(svc processByNameHandlers, names []string) (map[string]bool, error) {
+	matchingEntries, err := findProcessesByNamesWithHandlers(svc, names, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	running := make(map[string]bool, len(names))
+	for _, name := range names {
+		running[name] = len(matchingEntries[name]) > 0
+	}
+
+	return running, nil
+}
+
+// findProcessesByNameWithHandlers returns up to limit of the processes
+// matching name, stopping the scan of the proc filesystem once limit
+// are found. A limit of 0 returns every matching process. A process
+// exiting during the scan is skipped, while any other error reading
+// the name of a process is returned as the processes found would not
+// be complete.
+func findProcessesByNameWithHandlers(svc processByNameHandlers, name string, limit int) ([]os.FileInfo, error) {
+	matchingEntries, err := findProcessesByNamesWithHandlers(svc, []string{name}, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return matchingEntries[name], nil
+}
+
+// findProcessesByNamesWithHandlers classifies the processes by the
+// names they match in a single scan of the proc filesystem, so checking
+// several names costs no more than checking one. Up to limit processes
+// are returned for each name, with the scan stopping once every name
+// has limit, and a limit of 0 returns every matching process. Every
+// name is in the returned map, with no processes when none match. The
+// process names are read with up to svc.concurrency reads at once,
+// and the processes returned are the same as for a scan reading them
+// one at a time. A proc filesystem or a process name that may not be
+// read, such as with the proc filesystem mounted with hidepid, is an
+// error of the kind ErrPermission.
+func findProcessesByNamesWithHandlers(svc processByNameHandlers, names []string, limit int) (map[string][]os.FileInfo, error) {
 	var errorReturn error
-	matchingEntries := make([]os.FileInfo, 0)
+	matchingEntries := make(map[string][]os.FileInfo, len(names))
+	matchNames := make([]func(string) bool, len(names))
 
-	dir, err := svc.open("/proc")
+	for i, name := range names {
+		matchName, err := newTextMatcher(name, svc.regex, equalText)
+		if err != nil {
+			return nil, err
+		}
+
+		matchNames[i] = matchName
+		matchingEntries[name] = make([]os.FileInfo, 0)
+	}
+
+	dir, err := svc.open(svc.procDir())
 	if err != nil {
 		matchingEntries = nil
-		errorReturn = err
+		errorReturn = classifyError(err)
 	}
 
 	defer svc.close(dir)
@@ -62,25 +307,84 @@ func getProcessesByNameWithHandlers(svc processByNameHandlers, name string) ([]o
 
 		if err != nil {
 			matchingEntries = nil
-			errorReturn = err
+			errorReturn = classifyError(err)
 		}
 	}
 
+	var filters []processFilter
 	if errorReturn == nil {
-		for _, procEntry := range procEntries {
-			// Skip entries that aren't directories
-			if !procEntry.IsDir() {
+		filters, err = newProcessFilters(svc, procEntries)
+
+		if err != nil {
+			matchingEntries = nil
+			errorReturn = err
+		}
+	}
+
+	var pidEntries []os.FileInfo
+	var pids []int
+
+	for _, procEntry := range procEntries {
+		// Skip entries that aren't directories
+		if !procEntry.IsDir() {
+			continue
+		}
+
+		// Skip entries that aren't numbers
+		if pid, err := strconv.Atoi(procEntry.Name()); err == nil {
+			pidEntries = append(pidEntries, procEntry)
+			pids = append(pids, pid)
+		}
+	}
+
+	// The names are read a batch at a time, so a scan stopping at a
+	// limit reads at most a batch more than it needs. Read one at a
+	// time, the scan stops at the process completing the limit.
+	batchSize := len(pids)
+	if limit > 0 {
+		batchSize = 1
+		if svc.concurrency > 1 {
+			batchSize = svc.concurrency * pidNamesPerWorker
+		}
+	}
+
+scan:
+	for start := 0; errorReturn == nil && start < len(pids); start += batchSize {
+		end := start + batchSize
+		if end > len(pids) {
+			end = len(pids)
+		}
+
+		procNames, nameErrs := readPidNames(svc, pids[start:end])
+
+		for i, pid := range pids[start:end] {
+			procEntry, procName, err := pidEntries[start+i], procNames[i], nameErrs[i]
+			if processExited(err) {
+				debugLog.Printf("Skipping process %d, it has exited", pid)
 				continue
+			} else if err != nil {
+				matchingEntries = nil
+				errorReturn = wrapError(err, "Could not read the name of process %d: %s", pid, err)
+				break
 			}
 
-			// Skip entries that aren't numbers
-			pid, err := strconv.Atoi(procEntry.Name())
-			if err != nil {
+			var matchedNames []string
+			for i, name := range names {
+				if (limit == 0 || len(matchingEntries[name]) < limit) && matchNames[i](procName) {
+					matchedNames = append(matchedNames, name)
+				}
+			}
+
+			if len(matchedNames) == 0 || !matchesProcessFilters(filters, pid) {
 				continue
 			}
 
-			if procName, _ := svc.getPidName(svc.readFile, pid); procName == name {
-				matchingEntries = append(matchingEntries, procEntry)
+			for _, name := range matchedNames {
+				matchingEntries[name] = append(matchingEntries[name], procEntry)
+			}
+
+			if limit > 0 && allProcessesFound(matchingEntries, limit) {
+				break scan
 			}
 		}
 	}
@@ -88,8 +392,197 @@ func getProcessesByNameWithHandlers(svc processByNameHandlers, name string) ([]o
 	return matchingEntries, errorReturn
 }
 
-func getProcessesByName(name string) ([]os.FileInfo, error) {
-	svc := processByNameHandlers{
+// processFilter reports whether a process matches one of the
+// criteria of a scan other than its name, such as its owner.
+type processFilter func(pid int) bool
+
+// newProcessFilters returns a filter for each of the criteria set in
+// svc other than the name. A process is counted only when it matches
+// every filter, so the criteria given together narrow the processes
+// counted. A process whose details cannot be read does not match.
+func newProcessFilters(svc processByNameHandlers, procEntries []os.FileInfo) ([]processFilter, error) {
+	var filters []processFilter
+
+	// Processes are in the namespace when their namespace link
+	// resolves to the same namespace, such as "pid:[4026531836]".
+	if svc.pidNamespace != "" {
+		namespace, err := resolvePidNamespace(svc, procEntries)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, func(pid int) bool {
+			pidNs, err := svc.readLink(fmt.Sprintf("%s/%d/ns/pid", svc.procDir(), pid))
+			if err != nil {
+				debugLog.Printf("Skipping process %d, could not read its PID namespace: %s", pid, err)
+			}
+
+			return pidNs == namespace
+		})
+	}
+
+	if svc.matchCmdline != "" {
+		matchCmdline, err := newTextMatcher(svc.matchCmdline, svc.regex, strings.Contains)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, func(pid int) bool {
+			cmdline, err := getPidCmdlineWithHandler(svc.readFile, svc.procDir(), pid)
+			if err != nil {
+				debugLog.Printf("Skipping process %d, could not read its command line: %s", pid, err)
+				return false
+			}
+
+			return matchCmdline(cmdline)
+		})
+	}
+
+	if svc.exePath != "" {
+		matchExe, err := newTextMatcher(svc.exePath, svc.regex, exePathMatches)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, func(pid int) bool {
+			exe, err := svc.readLink(fmt.Sprintf("%s/%d/exe", svc.procDir(), pid))
+			if err != nil {
+				debugLog.Printf("Skipping process %d, could not read its executable: %s", pid, err)
+				return false
+			}
+
+			return matchExe(strings.TrimSuffix(exe, deletedExeSuffix))
+		})
+	}
+
+	if svc.user != "" {
+		uid, err := svc.lookupUID(svc.user)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, func(pid int) bool {
+			pidUID, err := svc.getPidUID(svc.stat, svc.procDir(), pid)
+			if err != nil {
+				debugLog.Printf("Skipping process %d, could not read its owner: %s", pid, err)
+			}
+
+			return pidUID == uid
+		})
+	}
+
+	if svc.ppid != 0 || svc.pgid != 0 {
+		filters = append(filters, func(pid int) bool {
+			stat, err := svc.readFile(fmt.Sprintf("%s/%d/stat", svc.procDir(), pid))
+			if err != nil {
+				debugLog.Printf("Skipping process %d, could not read its parent: %s", pid, err)
+				return false
+			}
+
+			ppid, pgid, err := parseStatParent(string(stat))
+			if err != nil {
+				debugLog.Printf("Skipping process %d, %s", pid, err)
+				return false
+			}
+
+			return (svc.ppid == 0 || ppid == svc.ppid) && (svc.pgid == 0 || pgid == svc.pgid)
+		})
+	}
+
+	return filters, nil
+}
+
+// deletedExeSuffix is appended to the target of /proc/<pid>/exe when
+// the executable has been deleted or replaced since the process
+// started, such as by a package upgrade.
+const deletedExeSuffix = " (deleted)"
+
+// exePathMatches reports whether the executable is the path, or is
+// under it for a path ending in "/".
+func exePathMatches(exe, path string) bool {
+	if strings.HasSuffix(path, "/") {
+		return strings.HasPrefix(exe, path)
+	}
+
+	return exe == path
+}
+
+// matchesProcessFilters reports whether the process matches every one
+// of the filters, reading no more of its details once one fails.
+func matchesProcessFilters(filters []processFilter, pid int) bool {
+	for _, filter := range filters {
+		if !filter(pid) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pidNamesPerWorker is the number of process names read by each
+// worker in a batch of a scan that may stop early.
+const pidNamesPerWorker = 64
+
+// readPidNames reads the name of each of the processes, with up to
+// svc.concurrency reads at once. The names, or the errors reading
+// them, are returned in the order of pids, so the processes found do
+// not depend on the order the reads complete.
+func readPidNames(svc processByNameHandlers, pids []int) ([]string, []error) {
+	names := make([]string, len(pids))
+	errs := make([]error, len(pids))
+
+	workers := svc.concurrency
+	if workers > len(pids) {
+		workers = len(pids)
+	}
+
+	if workers <= 1 {
+		for i, pid := range pids {
+			names[i], errs[i] = svc.getPidName(svc.readFile, svc.procDir(), pid)
+		}
+
+		return names, errs
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range next {
+				names[i], errs[i] = svc.getPidName(svc.readFile, svc.procDir(), pids[i])
+			}
+		}()
+	}
+
+	for i := range pids {
+		next <- i
+	}
+
+	close(next)
+	wg.Wait()
+
+	return names, errs
+}
+
+// allProcessesFound reports whether limit processes have been found
+// for every name.
+func allProcessesFound(matchingEntries map[string][]os.FileInfo, limit int) bool {
+	for _, entries := range matchingEntries {
+		if len(entries) < limit {
+			return false
+		}
+	}
+
+	return true
+}
+
+func getProcessByNameHandlers() processByNameHandlers {
+	return processByNameHandlers{
 		open: os.Open,
 		close: func(f *os.File) error {
 			return f.Close()
@@ -99,9 +592,148 @@ func getProcessesByName(name string) ([]os.FileInfo, error) {
 		},
 		getPidName: getPidNameWithHandler,
 		readFile:   ioutil.ReadFile,
+		listDir:    readDirNames,
+		readLink:   os.Readlink,
+		stat:       os.Stat,
+		getPidUID:  getPidUIDWithHandler,
+		lookupUID:  lookupUID,
+	}
+}
+
+// lookupUID returns the UID of the user named, or the UID itself
+// when given one such as "1000". An unknown user is an error, as
+// checking for processes owned by a user that does not exist would
+// find none.
+func lookupUID(name string) (string, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u.Uid, nil
+	}
+
+	if _, convErr := strconv.ParseUint(name, 10, 32); convErr == nil {
+		return name, nil
+	}
+
+	if _, ok := err.(user.UnknownUserError); ok {
+		return "", fmt.Errorf("Unknown user %s", name)
+	}
+
+	return "", fmt.Errorf("Could not look up user %s: %s", name, err)
+}
+
+// readDirNames returns the names of the entries in the named directory.
+func readDirNames(name string) ([]string, error) {
+	dir, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	return dir.Readdirnames(0)
+}
+
+// memoryMapping is a single region of memory mapped into a process
+// as listed in /proc/<pid>/maps.
+type memoryMapping struct {
+	pid         int
+	address     string
+	permissions string
+	path        string
+}
+
+func (m memoryMapping) isWritableExecutable() bool {
+	return len(m.permissions) >= 3 && m.permissions[1] == 'w' && m.permissions[2] == 'x'
+}
+
+func (m memoryMapping) String() string {
+	path := m.path
+	if path == "" {
+		path = "[anonymous]"
+	}
+
+	return fmt.Sprintf("%d:%s %s %s", m.pid, m.address, m.permissions, path)
+}
+
+// parseMemoryMaps parses the contents of /proc/<pid>/maps. Each line
+// has the format of "address perms offset dev inode pathname" where
+// the pathname is optional and may contain spaces.
+func parseMemoryMaps(pid int, data string) ([]memoryMapping, error) {
+	mappings := make([]memoryMapping, 0)
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("Could not parse memory mapping: %s", line)
+		}
+
+		mapping := memoryMapping{
+			pid:         pid,
+			address:     fields[0],
+			permissions: fields[1],
+		}
+
+		if len(fields) > 5 {
+			mapping.path = strings.Join(fields[5:], " ")
+		}
+
+		mappings = append(mappings, mapping)
 	}
 
-	return getProcessesByNameWithHandlers(svc, name)
+	return mappings, nil
+}
+
+// getWritableExecutableMappingsWithHandlers finds the processes matching
+// name and returns the memory mappings in those processes that are both
+// writable and executable. A process exiting while it is read is
+// skipped. Returns errProcessNotRunning if no process matches or every
+// process has exited.
+func getWritableExecutableMappingsWithHandlers(svc processByNameHandlers, name string) ([]memoryMapping, error) {
+	processEntries, err := getProcessesByNameWithHandlers(svc, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(processEntries) == 0 {
+		return nil, errProcessNotRunning
+	}
+
+	wxMappings := make([]memoryMapping, 0)
+	read := 0
+
+	for _, processEntry := range processEntries {
+		pid, _ := strconv.Atoi(processEntry.Name())
+
+		data, err := svc.readFile(fmt.Sprintf("%s/%d/maps", svc.procDir(), pid))
+		if processExited(err) {
+			debugLog.Printf("Skipping process %d, it has exited", pid)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		read++
+
+		mappings, err := parseMemoryMaps(pid, string(data))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mapping := range mappings {
+			if mapping.isWritableExecutable() {
+				wxMappings = append(wxMappings, mapping)
+			}
+		}
+	}
+
+	if read == 0 {
+		return nil, errProcessNotRunning
+	}
+
+	return wxMappings, nil
 }
 
 // ProcessService is an interface required by ProcessCheck.
@@ -113,10 +745,183 @@ type ProcessService interface {
 	IsProcessRunning(string) bool
 }
 
-type processHandler struct{}
+// processRunningService is implemented by a ProcessService that can
+// also report the process state could not be determined, such as when
+// the process list cannot be read, rather than the process not
+// running.
+type processRunningService interface {
+	ProcessRunning(string) (bool, error)
+}
+
+// processesRunningService is implemented by a ProcessService that can
+// report the state of several processes at once, reading the process
+// list once rather than once for each process.
+type processesRunningService interface {
+	ProcessesRunning([]string) (map[string]bool, error)
+}
+
+// processPidsService is implemented by a ProcessService that can also
+// list the PIDs of the running instances of the named process.
+type processPidsService interface {
+	ProcessPids(string) ([]int, error)
+}
+
+// processDetailsService is implemented by a ProcessService that can
+// also describe the running instances of the named process.
+type processDetailsService interface {
+	ProcessDetails(string) ([]ProcessInfo, error)
+}
+
+// processMappingService is implemented by a ProcessService that can
+// also inspect the memory mappings of the named process.
+type processMappingService interface {
+	WritableExecutableMappings(string) ([]memoryMapping, error)
+}
+
+// processHandler is the ProcessService interrogating the OS.
+type processHandler struct {
+	// Limits the processes to those in this PID namespace, with
+	// a command line containing matchCmdline, running exePath, owned
+	// by user and children of ppid or in the process group pgid. See
+	// processByNameHandlers.
+	pidNamespace string
+	matchCmdline string
+	exePath      string
+	user         string
+	ppid         int
+	pgid         int
+	regex        bool
+	procRoot     string
+	concurrency  int
+
+	// Lists the processes of the OS, chosen by newProcessHandler().
+	inspector ProcessInspector
+}
+
+// newProcessHandler returns the processHandler for the options with
+// the ProcessInspector of the OS.
+func newProcessHandler(options ProcessCheckOptions) *processHandler {
+	p := &processHandler{
+		pidNamespace: options.PidNamespace,
+		matchCmdline: options.MatchCmdline,
+		exePath:      options.ExePath,
+		user:         options.User,
+		ppid:         options.Ppid,
+		pgid:         options.Pgid,
+		regex:        options.Regex,
+		procRoot:     options.ProcfsRoot,
+		concurrency:  options.Concurrency,
+	}
+
+	if p.concurrency < 1 {
+		p.concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	p.inspector = newProcessInspectorOsConstrained(*p)
+
+	return p
+}
+
+// procHandlers returns the handlers for reading process information
+// from the proc filesystem, scoped by the processHandler settings.
+func (p processHandler) procHandlers() processByNameHandlers {
+	svc := getProcessByNameHandlers()
+	svc.pidNamespace = p.pidNamespace
+	svc.matchCmdline = p.matchCmdline
+	svc.exePath = p.exePath
+	svc.user = p.user
+	svc.ppid = p.ppid
+	svc.pgid = p.pgid
+	svc.regex = p.regex
+	svc.procRoot = p.procRoot
+	svc.concurrency = p.concurrency
+
+	return svc
+}
 
 func (p processHandler) IsProcessRunning(name string) bool {
-	return isProcessRunningOsConstrained(name)
+	running, err := p.ProcessRunning(name)
+	if err != nil {
+		debugLog.Printf("Could not list the processes named %s: %s", name, err)
+	}
+
+	return running
+}
+
+func (p processHandler) ProcessRunning(name string) (bool, error) {
+	// Only whether the process is running matters, so the scan stops
+	// at the first match.
+	processes, err := p.inspector.List(name, 1)
+
+	return len(processes) > 0, err
+}
+
+func (p processHandler) ProcessesRunning(names []string) (map[string]bool, error) {
+	return getProcessesRunningOsConstrained(p, names)
+}
+
+func (p processHandler) ProcessPids(name string) ([]int, error) {
+	processes, err := p.inspector.List(name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make([]int, len(processes))
+	for i, process := range processes {
+		pids[i] = process.PID
+	}
+
+	return pids, nil
+}
+
+func (p processHandler) ProcessDetails(name string) ([]ProcessInfo, error) {
+	return p.inspector.List(name, 0)
+}
+
+func (p processHandler) WritableExecutableMappings(name string) ([]memoryMapping, error) {
+	return getWritableExecutableMappingsOsConstrained(p, name)
+}
+
+func (p processHandler) LogAge(name, logPath string) (time.Duration, error) {
+	return getLogAgeOsConstrained(p, name, logPath)
+}
+
+func (p processHandler) CgroupCounts(name string) (map[string]int, error) {
+	return getCgroupCountsOsConstrained(p, name)
+}
+
+func (p processHandler) ProcessCount(name string) (int, error) {
+	processes, err := p.inspector.List(name, 0)
+
+	return len(processes), err
+}
+
+func (p processHandler) ProcessMemory(name string) (uint64, int, error) {
+	return processMemory(p.inspector, name)
+}
+
+func (p processHandler) ProcessAges(name string) ([]time.Duration, error) {
+	return processAges(p.inspector, name, time.Now())
+}
+
+func (p processHandler) ProcessThreads(name string) ([]ProcessInfo, error) {
+	return processThreads(p.inspector, name)
+}
+
+func (p processHandler) ProcessFds(name string) ([]processFds, error) {
+	return getProcessFdsOsConstrained(p, name)
+}
+
+func (p processHandler) ProcessZombies(name string) ([]processZombie, error) {
+	return getProcessZombiesOsConstrained(p, name)
+}
+
+func (p processHandler) ProcessListeningPorts(name string) ([]int, error) {
+	return getProcessListeningPortsOsConstrained(p, name)
+}
+
+func (p processHandler) ProcessCPU(name string, interval time.Duration) ([]processCPU, error) {
+	return getProcessCPUOsConstrained(p, name, interval)
 }
 
 // ProcessCheck is used to encapsulate a named process
@@ -127,105 +932,675 @@ type ProcessCheck struct {
 	ProcessName string
 
 	ProcessCheckHandler ProcessService
+
+	// The state reported when the process is not running, set from
+	// ProcessCheckOptions.MissingState. Empty keeps the state of the
+	// check type.
+	missingState string
+
+	// Lists the PIDs of the processes matched in the result of the
+	// "running" and "count" checks, set from
+	// ProcessCheckOptions.ShowPids.
+	showPids bool
+}
+
+// maxListedPids is the number of PIDs listed in the result of a check
+// showing the PIDs of the processes matched.
+const maxListedPids = 10
+
+// pids returns the PIDs of the running instances of the process, in
+// ascending order. The bool is false when the PIDs are not to be
+// shown, neither with showPids nor verbose messages enabled, or the
+// service cannot list them.
+func (p ProcessCheck) pids() ([]int, bool, error) {
+	pidsService, ok := p.ProcessCheckHandler.(processPidsService)
+	if !(p.showPids || verboseEnabled) || !ok {
+		return nil, false, nil
+	}
+
+	pids, err := pidsService.ProcessPids(p.ProcessName)
+	if err != nil {
+		return nil, true, err
+	}
+
+	sort.Ints(pids)
+
+	return pids, true, nil
+}
+
+// pidsText returns the PIDs as listed in a result, such as
+// " (pids: 123, 456)", with up to maxListedPids of them and "..."
+// after a list cut short.
+func pidsText(pids []int) string {
+	listed := make([]string, 0, maxListedPids+1)
+
+	for i, pid := range pids {
+		if i == maxListedPids {
+			listed = append(listed, "...")
+			break
+		}
+
+		listed = append(listed, strconv.Itoa(pid))
+	}
+
+	return fmt.Sprintf(" (pids: %s)", strings.Join(listed, ", "))
+}
+
+// maxVerboseProcesses is the number of processes described on lines
+// of their own in a verbose result.
+const maxVerboseProcesses = 50
+
+// verboseProcesses returns the lines describing the running instances
+// of the process in a verbose result, in ascending order of PID. From
+// VerbosityBreakdown each process has a line of its PID and name, such
+// as "pid 1234 worker", and from VerbosityRaw the values read for it
+// are added. Up to maxVerboseProcesses are described. Empty below
+// VerbosityBreakdown or when the service cannot describe them.
+func (p ProcessCheck) verboseProcesses() string {
+	detailsService, ok := p.ProcessCheckHandler.(processDetailsService)
+	if verbosity < VerbosityBreakdown || !ok {
+		return ""
+	}
+
+	processes, err := detailsService.ProcessDetails(p.ProcessName)
+	if err != nil {
+		debugLog.Printf("Could not describe the processes of %s: %s", p.ProcessName, err)
+		return ""
+	}
+
+	sort.Slice(processes, func(i, j int) bool { return processes[i].PID < processes[j].PID })
+
+	var lines []string
+	for i, process := range processes {
+		if i == maxVerboseProcesses {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(processes)-i))
+			break
+		}
+
+		line := fmt.Sprintf("pid %d %s", process.PID, process.Name)
+		if verbosity >= VerbosityRaw {
+			line += fmt.Sprintf(", rss=%d threads=%d start=%s", process.RSS, process.Threads, process.StartTime.Format(time.RFC3339))
+		}
+
+		lines = append(lines, line)
+	}
+
+	return verboseLines(VerbosityBreakdown, lines...)
+}
+
+// notRunningResult returns the result of a check finding the process
+// is not running, in the state of the check type unless another was
+// selected with ProcessCheckOptions.MissingState.
+func (p ProcessCheck) notRunningResult(state State, checkInfo string, perfData ...PerfData) CheckResult {
+	if missing, err := ParseState(p.missingState); err == nil {
+		state = missing
+	}
+
+	return NewCheckResult(checkProcessName, state, checkInfo, perfData...)
 }
 
 // IsProcessRunning interrogates the OS for the named
 // process to check if it's running. Note this function
 // calls IsProcessRunning in the injected service and
-// in this implementation will ultimately call an OS
-// constrained function.
+// in this implementation will ultimately list the
+// processes with the ProcessInspector of the OS.
 func (p ProcessCheck) IsProcessRunning() bool {
 	return p.ProcessCheckHandler.IsProcessRunning(p.ProcessName)
 }
 
-func checkRunning(processCheck ProcessCheck, metricName string, invert bool) (string, int) {
-	var msg string
-	var retcode int
-	var responseStateText string
-	var checkInfo string
+func checkRunning(processCheck ProcessCheck, metricName string) CheckResult {
+	running := false
 
-	result := processCheck.IsProcessRunning()
-	if result != invert {
-		retcode = statusCodeOK
-		responseStateText = statusTextOK
-	} else {
-		retcode = statusCodeCritical
-		responseStateText = statusTextCritical
+	pids, listed, err := processCheck.pids()
+	if err != nil {
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not determine if process %s is running: %s", processCheck.ProcessName, err), err)
 	}
 
-	nagiosOutput := metricName + "="
-	if result == true {
-		checkInfo = ""
-		nagiosOutput = nagiosOutput + strconv.Itoa(statusCodeOK)
+	if listed {
+		running = len(pids) > 0
+	} else if runningService, ok := processCheck.ProcessCheckHandler.(processRunningService); ok {
+		var err error
+		if running, err = runningService.ProcessRunning(processCheck.ProcessName); err != nil {
+			return errorResult(checkProcessName,
+				fmt.Sprintf("Could not determine if process %s is running: %s", processCheck.ProcessName, err), err)
+		}
 	} else {
-		checkInfo = "not "
-		nagiosOutput = nagiosOutput + strconv.Itoa(statusCodeCritical)
+		running = processCheck.IsProcessRunning()
+	}
+
+	// The metric is the state of the process rather than of the
+	// check, so a process that is not running is always 2, even
+	// when the check is inverted or reports another missing state.
+	if !running {
+		return processCheck.notRunningResult(StateCritical,
+			fmt.Sprintf("Process %s is not running", processCheck.ProcessName),
+			PerfData{Label: metricName, Value: statusCodeCritical})
+	}
+
+	checkInfo := fmt.Sprintf("Process %s is running", processCheck.ProcessName)
+	if listed {
+		checkInfo += pidsText(pids)
+	}
+
+	checkInfo += processCheck.verboseProcesses()
+
+	return OKResult(checkProcessName, checkInfo,
+		PerfData{Label: metricName, Value: statusCodeOK})
+}
+
+// checkRunningSingle checks exactly one instance of the named process
+// is running, emitting a warning response listing the PIDs when there
+// are several, such as a stale daemon left running or a restart that
+// started a second instance, and a critical response when there are
+// none. The state of the process is output as perfdata, 1 when
+// duplicated.
+func checkRunningSingle(processCheck ProcessCheck, metricName string) CheckResult {
+	pidsService, ok := processCheck.ProcessCheckHandler.(processPidsService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Process PIDs are not available from the process service")
+	}
+
+	pids, err := pidsService.ProcessPids(processCheck.ProcessName)
+	if err != nil {
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not determine if process %s is running: %s", processCheck.ProcessName, err), err)
+	}
+
+	sort.Ints(pids)
+
+	if len(pids) == 0 {
+		return processCheck.notRunningResult(StateCritical,
+			fmt.Sprintf("Process %s is not running", processCheck.ProcessName),
+			PerfData{Label: metricName, Value: statusCodeCritical})
+	}
+
+	state := StateOK
+	checkInfo := fmt.Sprintf("Process %s is running", processCheck.ProcessName)
+	if len(pids) > 1 {
+		state = StateWarning
+		checkInfo = fmt.Sprintf("%d instances of %s running, expected one", len(pids), processCheck.ProcessName)
 	}
 
-	msg, _ = resultMessage(checkProcessName, responseStateText,
-		fmt.Sprintf("Process %s is %srunning", processCheck.ProcessName, checkInfo),
-		nagiosOutput)
+	checkInfo += pidsText(pids) + processCheck.verboseProcesses()
 
-	return msg, retcode
+	return NewCheckResult(checkProcessName, state, checkInfo,
+		PerfData{Label: metricName, Value: float64(state.ExitCode())})
+}
+
+// processesRunning reports which of the named processes are running,
+// with a single read of the process list when the service supports it.
+func processesRunning(processService ProcessService, names []string) (map[string]bool, error) {
+	if runningService, ok := processService.(processesRunningService); ok {
+		return runningService.ProcessesRunning(names)
+	}
+
+	running := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		if runningService, ok := processService.(processRunningService); ok {
+			var err error
+			if running[name], err = runningService.ProcessRunning(name); err != nil {
+				return nil, err
+			}
+		} else {
+			running[name] = processService.IsProcessRunning(name)
+		}
+	}
+
+	return running, nil
+}
+
+// checkRunningNames checks that each of the named processes is
+// running, emitting a critical response, or the missing state of the
+// check, listing the processes that are not running if there are any,
+// otherwise a good response. The state of each process is output as
+// perfdata named after the metric and the process.
+func checkRunningNames(processCheck ProcessCheck, names []string, metricName string) CheckResult {
+	running, err := processesRunning(processCheck.ProcessCheckHandler, names)
+	if err != nil {
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not determine if processes %s are running: %s", strings.Join(names, ", "), err), err)
+	}
+
+	var notRunning []string
+	perfData := make([]PerfData, 0, len(names))
+
+	for _, name := range names {
+		metric := PerfData{Label: metricName + "_" + cgroupLabel(name), Value: statusCodeOK}
+		if !running[name] {
+			notRunning = append(notRunning, name)
+			metric.Value = statusCodeCritical
+		}
+
+		perfData = append(perfData, metric)
+	}
+
+	if len(notRunning) > 0 {
+		return processCheck.notRunningResult(StateCritical,
+			fmt.Sprintf("%d of %d processes are not running: %s", len(notRunning), len(names), strings.Join(notRunning, ", ")),
+			perfData...)
+	}
+
+	return OKResult(checkProcessName,
+		fmt.Sprintf("All %d processes are running: %s", len(names), strings.Join(names, ", ")),
+		perfData...)
+}
+
+func checkWritableExecutable(processCheck ProcessCheck, metricName string) CheckResult {
+	mappingService, ok := processCheck.ProcessCheckHandler.(processMappingService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Memory mappings are not available from the process service")
+	}
+
+	mappings, err := mappingService.WritableExecutableMappings(processCheck.ProcessName)
+
+	switch {
+	case err == errProcessNotRunning:
+		return processCheck.notRunningResult(StateCritical, fmt.Sprintf("Process %s is not running", processCheck.ProcessName))
+	case err != nil:
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not read memory mappings of process %s: %s", processCheck.ProcessName, err), err)
+	}
+
+	metric := PerfData{Label: metricName, Value: float64(len(mappings))}
+
+	if len(mappings) > 0 {
+		regions := make([]string, len(mappings))
+		for i, mapping := range mappings {
+			regions[i] = mapping.String()
+		}
+
+		return WarningResult(checkProcessName,
+			fmt.Sprintf("Process %s has %d writable and executable memory mappings: %s",
+				processCheck.ProcessName, len(mappings), strings.Join(regions, ", ")),
+			metric)
+	}
+
+	return OKResult(checkProcessName,
+		fmt.Sprintf("Process %s has no writable and executable memory mappings", processCheck.ProcessName),
+		metric)
+}
+
+// ProcessCheckOptions contains the options for a process check.
+// Only the options relevant to the check type need to be populated.
+type ProcessCheckOptions struct {
+	// The name of the process to check. Unless Regex is set, several
+	// names may be given separated by commas. The "zombie" check
+	// takes the name of the parent process and counts every zombie
+	// when the name is empty.
+	Name string
+
+	// Further names of processes to check along with Name. Only the
+	// "running" check takes several names, checking them all with a
+	// single read of the process list.
+	Names []string
+
+	// The type of check to perform. See processCheckTypes.
+	CheckType string
+
+	// The name of the metric in the nagios output.
+	MetricName string
+
+	// The path of the log file the process should be writing.
+	// Used by the "logactive" check.
+	LogPath string
+
+	// The warning and critical thresholds. Used by the
+	// "logactive" check as the log age in seconds, defaulting to
+	// 300 and 900, by the "count" check as the range of instances
+	// by the "memory" check as the resident memory in megabytes, by
+	// the "uptime" check as the seconds the process has run, by the
+	// "threads" check as the number of threads, by the "fds"
+	// check as the number of open files, by the "zombie" check as
+	// the number of zombie processes, warning on any by default, and
+	// by the "cpu" check as the percentage of CPU used.
+	Warning  string
+	Critical string
+
+	// The TCP port the process should be listening on. Used by the
+	// "listening" check.
+	Port int
+
+	// The minimum and maximum number of processes expected. Used
+	// by the "cgroupcount" check for the count in each cgroup. A
+	// MaxCount of zero means there is no maximum.
+	MinCount int
+	MaxCount int
+
+	// Limits the check to processes in a PID namespace, given as
+	// a namespace link path, the PID of a process in the namespace
+	// or a container ID. Linux only.
+	PidNamespace string
+
+	// Limits the check to processes with a command line containing
+	// this text, for telling apart processes with the same name
+	// such as java workers. Linux only.
+	MatchCmdline string
+
+	// Limits the check to processes running the executable at this
+	// path, the target of /proc/<pid>/exe, for telling apart the
+	// processes of two installs of a program. A path ending in "/"
+	// matches the executables under it, such as "/opt/app/bin/". A
+	// process still running an executable deleted since it started,
+	// such as by an upgrade, matches the path it was started from.
+	// Linux only.
+	ExePath string
+
+	// Limits the check to processes owned by this user, given as a
+	// user name or UID. An unknown user returns UNKNOWN. Linux only.
+	User string
+
+	// Limits the check to the children of the process with this PID,
+	// such as the workers of a supervisor, or to the processes in the
+	// process group with this ID. Zero does not limit the check.
+	// Linux only.
+	Ppid int
+	Pgid int
+
+	// Treats Name, MatchCmdline and ExePath as regular expressions.
+	Regex bool
+
+	// Lists the PIDs of the processes matched, up to 10 of them, in
+	// the result of the "running" and "count" checks, such as
+	// "Process worker is running (pids: 123, 456)". The PIDs are also
+	// listed with verbose messages enabled by SetVerbose().
+	ShowPids bool
+
+	// Selects the "oldest" or "youngest" process for the "uptime"
+	// check when several match. Defaults to "oldest".
+	Select string
+
+	// Checks the thread or open file count or the CPU usage of each
+	// process rather than the total of the processes for the
+	// "threads", "fds" and "cpu" checks.
+	PerProcess bool
+
+	// The time the CPU usage is sampled over for the "cpu" check.
+	// Defaults to a second. Linux only.
+	Interval time.Duration
+
+	// Takes the CPU usage of the "cpu" check as a percentage of all of
+	// the CPUs rather than of one CPU.
+	OfCPUs bool
+
+	// The state reported when the process is not running, "ok",
+	// "warning", "critical" or "unknown", such as ok for an optional
+	// daemon. Empty keeps the state of the check type, CRITICAL for
+	// "running" and UNKNOWN for the types such as "threads" that have
+	// nothing to count. Applies to the result before any inversion,
+	// so "notrunning" inverts it.
+	MissingState string
+
+	// Compares the change in the count of the "count" check since the
+	// previous run, rather than the count, against the thresholds.
+	// The count is saved in StateDir, which must be given.
+	Delta bool
+
+	// Expects exactly one instance of the process for the "running"
+	// check, a singleton daemon that misbehaves when duplicated, such as
+	// a scheduler running every job twice. More than one instance is a
+	// warning listing their PIDs.
+	ExpectSingle bool
+
+	// The directory the state of a check is saved in between runs,
+	// such as the count of the previous run for Delta or the start
+	// times of the processes for the "restarted" check.
+	StateDir string
+
+	// Takes the thresholds of the "fds" check as a percentage of the
+	// soft limit on open files. Linux only.
+	OfLimit bool
+
+	// The directory the proc filesystem is read from, such as a host
+	// /proc mounted at /host/proc inside a container. Defaults to
+	// /proc. Linux only.
+	ProcfsRoot string
+
+	// Checks the processes in the PID namespace of a container, given
+	// the PID on the host of the init process of the container. The
+	// processes are read from /proc/<pid>/root/proc, the proc
+	// filesystem mounted in the container, under ProcfsRoot. Zero
+	// checks the processes of ProcfsRoot. Linux only.
+	ContainerPid int
+
+	// The number of process names read at once while scanning the
+	// proc filesystem, speeding up the scan of a host with many
+	// processes. Defaults to GOMAXPROCS. Linux only.
+	Concurrency int
+}
+
+// processCheckTypes lists the supported check types.
+var processCheckTypes = []string{"running", "notrunning", "wxmappings", "logactive", "cgroupcount", "count", "memory", "uptime", "threads", "fds", "zombie", "listening", "cpu", "restarted"}
+
+// processNames returns the names of the processes to check, Name and
+// Names with comma-separated names split unless they are regular
+// expressions. Each name is returned once.
+func processNames(options ProcessCheckOptions) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, name := range append([]string{options.Name}, options.Names...) {
+		parts := []string{name}
+		if !options.Regex {
+			parts = strings.Split(name, ",")
+		}
+
+		for _, part := range parts {
+			if part = strings.TrimSpace(part); part != "" && !seen[part] {
+				names = append(names, part)
+				seen[part] = true
+			}
+		}
+	}
+
+	return names
+}
+
+// ProcessCheckTypes returns the check types supported by the process
+// check, such as "running" and "count".
+func ProcessCheckTypes() []string {
+	return append([]string(nil), processCheckTypes...)
+}
+
+func isProcessCheckType(checkType string) bool {
+	for _, t := range processCheckTypes {
+		if t == checkType {
+			return true
+		}
+	}
+
+	return false
 }
 
 // checkProcessWithService provides a way to inject a custom
 // service for interrogating the OS for the named process.
 // This is mainly used for testing but can also be used for any
 // application wishing to override the normal interrogations.
-func checkProcessWithService(name, checkType, metricName string, processService ProcessService) (string, int) {
+func checkProcessWithService(options ProcessCheckOptions, processService ProcessService) CheckResult {
+	names := processNames(options)
+
 	pc := ProcessCheck{
-		ProcessName:         name,
 		ProcessCheckHandler: processService,
+		missingState:        options.MissingState,
+		showPids:            options.ShowPids,
 	}
 
-	var msg string
-	var retcode int
+	if len(names) > 0 {
+		pc.ProcessName = names[0]
+	}
+
+	var result CheckResult
 
-	switch checkType {
+	switch options.CheckType {
 	case "running":
-		msg, retcode = checkRunning(pc, metricName, false)
+		if len(names) > 1 {
+			result = checkRunningNames(pc, names, options.MetricName)
+			break
+		}
+
+		if options.ExpectSingle {
+			result = checkRunningSingle(pc, options.MetricName)
+			break
+		}
+
+		result = checkRunning(pc, options.MetricName)
 	case "notrunning":
-		msg, retcode = checkRunning(pc, metricName, true)
+		// Kept as an alias of running with --invert.
+		result = checkRunning(pc, options.MetricName).Invert()
+	case "wxmappings":
+		result = checkWritableExecutable(pc, options.MetricName)
+	case "logactive":
+		result = checkLogActive(pc, options)
+	case "cgroupcount":
+		result = checkCgroupCount(pc, options)
+	case "count":
+		result = checkCount(pc, options)
+	case "memory":
+		result = checkMemory(pc, options)
+	case "uptime":
+		result = checkProcessUptime(pc, options)
+	case "threads":
+		result = checkThreads(pc, options)
+	case "fds":
+		result = checkFds(pc, options)
+	case "zombie":
+		result = checkZombies(pc, options)
+	case "listening":
+		result = checkListening(pc, options)
+	case "cpu":
+		result = checkProcessCPU(pc, options)
+	case "restarted":
+		result = checkRestarted(pc, options, newStateStore(options.StateDir))
 	default:
-		msg = fmt.Sprintf("Invalid check type: %s", checkType)
-		retcode = statusCodeCritical
+		result = CriticalResult(checkProcessName, fmt.Sprintf("Invalid check type: %s", options.CheckType))
 	}
 
-	return msg, retcode
+	return result
 }
 
 // checkProcessCmd will interrogate the OS for details on
 // a named process. The details of the interrogation
 // depend on the check type.
-func checkProcessCmd(name, checkType, metricName string, checkProcess func(string, string, string, ProcessService) (string, int), processService ProcessService) (string, int) {
+func checkProcessCmd(options ProcessCheckOptions, checkProcess func(ProcessCheckOptions, ProcessService) CheckResult, processService ProcessService) CheckResult {
 	var invalidParametersMsg string
-	var msg string
-	var retcode int
 
-	checkType = strings.ToLower(checkType)
+	options.CheckType = strings.ToLower(options.CheckType)
+	names := processNames(options)
 
-	if name == "" {
+	if len(names) == 0 && options.CheckType != "zombie" {
 		invalidParametersMsg = invalidParametersMsg +
 			"A process name must be specified."
-	} else if checkType != "running" && checkType != "notrunning" {
+	} else if !isProcessCheckType(options.CheckType) {
+		invalidParametersMsg = invalidParametersMsg +
+			fmt.Sprintf("Invalid check type (%s). Only \"%s\" are supported.",
+				options.CheckType, strings.Join(processCheckTypes, "\", \""))
+	} else if len(names) > 1 && options.CheckType != "running" {
+		invalidParametersMsg = invalidParametersMsg +
+			fmt.Sprintf("Several process names are only supported by the \"running\" type, not %s.", options.CheckType)
+	} else if options.CheckType == "logactive" && options.LogPath == "" {
+		invalidParametersMsg = invalidParametersMsg +
+			"A log path must be specified for the logactive check."
+	} else if options.CheckType == "listening" && (options.Port < 1 || options.Port > 65535) {
+		invalidParametersMsg = invalidParametersMsg +
+			fmt.Sprintf("A port from 1 to 65535 must be specified for the listening check, not %d.", options.Port)
+	} else if options.CheckType == "cpu" && options.Interval < 0 {
+		invalidParametersMsg = invalidParametersMsg +
+			fmt.Sprintf("The interval of the cpu check may not be negative, not %s.", options.Interval)
+	} else if options.ContainerPid < 0 {
 		invalidParametersMsg = invalidParametersMsg +
-			fmt.Sprintf("Invalid check type (%s). Only \"running\" and \"notrunning\" are supported.",
-				checkType)
+			fmt.Sprintf("The container PID may not be negative, not %d.", options.ContainerPid)
+	} else if options.Ppid < 0 || options.Pgid < 0 {
+		invalidParametersMsg = invalidParametersMsg +
+			fmt.Sprintf("The parent PID and process group ID may not be negative, not %d and %d.", options.Ppid, options.Pgid)
+	} else if options.Delta && options.CheckType != "count" {
+		invalidParametersMsg = invalidParametersMsg +
+			fmt.Sprintf("The delta mode is only supported by the \"count\" type, not %s.", options.CheckType)
+	} else if options.Delta && options.StateDir == "" {
+		invalidParametersMsg = invalidParametersMsg +
+			"A state directory must be specified for the delta mode of the count check."
+	} else if options.ExpectSingle && options.CheckType != "running" {
+		invalidParametersMsg = invalidParametersMsg +
+			fmt.Sprintf("The expect single mode is only supported by the \"running\" type, not %s.", options.CheckType)
+	} else if options.ExpectSingle && len(names) > 1 {
+		invalidParametersMsg = invalidParametersMsg +
+			"The expect single mode takes a single process name."
+	} else if options.CheckType == "restarted" && options.StateDir == "" {
+		invalidParametersMsg = invalidParametersMsg +
+			"A state directory must be specified for the restarted check."
+	}
+
+	if options.MissingState != "" {
+		if _, err := ParseState(options.MissingState); err != nil {
+			return UnknownResult(checkProcessName, fmt.Sprintf("Invalid missing state: %s", err))
+		}
+	}
+
+	if invalidParametersMsg == "" && options.Regex {
+		for _, pattern := range append(names, options.MatchCmdline, options.ExePath) {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return UnknownResult(checkProcessName, fmt.Sprintf("Invalid regular expression %q: %s", pattern, err))
+			}
+		}
 	}
 
 	if invalidParametersMsg != "" {
-		msg, _ = resultMessage(checkProcessName, statusTextCritical, invalidParametersMsg)
-		retcode = statusCodeCritical
-	} else {
-		msg, retcode = checkProcess(name, checkType, metricName, processService)
+		return CriticalResult(checkProcessName, invalidParametersMsg)
+	}
+
+	return checkProcess(options, processService)
+}
+
+// containerProcRoot returns the proc filesystem of the container with
+// the init process pid, as mounted in the container and read through
+// the root of the process under procRoot. A root that cannot be read,
+// such as without the privileges to read the root of another process,
+// or without a proc filesystem mounted, is an error.
+func containerProcRoot(procRoot string, pid int, stat func(string) (os.FileInfo, error)) (string, error) {
+	if procRoot == "" {
+		procRoot = defaultProcRoot
+	}
+
+	root := fmt.Sprintf("%s/%d/root/proc", strings.TrimSuffix(procRoot, "/"), pid)
+
+	if _, err := stat(root + "/uptime"); err != nil {
+		return "", fmt.Errorf("Could not access the processes of container process %d in %s: %s", pid, root, err)
+	}
+
+	return root, nil
+}
+
+// CheckProcessWithOptions performs the process check described
+// by options. With options.ContainerPid the processes are read from
+// the proc filesystem of the container, and a container that cannot
+// be accessed returns UNKNOWN.
+func CheckProcessWithOptions(options ProcessCheckOptions) (string, int) {
+	return RunProcessCheck(options).Output()
+}
+
+// RunProcessCheck performs the process check described by options
+// and returns the result, for programs embedding the check or
+// running several checks in one process. Nothing is output and the
+// program does not exit.
+func RunProcessCheck(options ProcessCheckOptions) CheckResult {
+	if options.ContainerPid > 0 {
+		procRoot, err := containerProcRoot(options.ProcfsRoot, options.ContainerPid, os.Stat)
+		if err != nil {
+			return UnknownResult(checkProcessName, err.Error())
+		}
+
+		options.ProcfsRoot = procRoot
 	}
 
-	return msg, retcode
+	return checkProcessCmd(options, checkProcessWithService, newProcessHandler(options))
 }
 
 // CheckProcess finds a process by name to determine
 // if it is running or not running.
 func CheckProcess(name, checkType, metricName string) (string, int) {
-	return checkProcessCmd(name, checkType, metricName, checkProcessWithService, new(processHandler))
+	return CheckProcessWithOptions(ProcessCheckOptions{
+		Name:       name,
+		CheckType:  checkType,
+		MetricName: metricName,
+	})
 }
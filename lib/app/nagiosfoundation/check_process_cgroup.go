@@ -0,0 +1,165 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// processCgroupService is implemented by a ProcessService that can
+// also count the named processes in each cgroup.
+type processCgroupService interface {
+	CgroupCounts(string) (map[string]int, error)
+}
+
+// parseCgroup returns the cgroup path from the contents of
+// /proc/<pid>/cgroup. Each line has the format of
+// "hierarchy-id:controllers:path". The unified (cgroup v2) hierarchy
+// is preferred, followed by the systemd hierarchy, then the first
+// hierarchy listed.
+func parseCgroup(data string) (string, error) {
+	var first, systemd string
+
+	for _, line := range strings.Split(data, "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			return "", fmt.Errorf("Could not parse cgroup entry: %s", line)
+		}
+
+		if fields[0] == "0" && fields[1] == "" {
+			return fields[2], nil
+		}
+
+		if fields[1] == "name=systemd" {
+			systemd = fields[2]
+		}
+
+		if first == "" {
+			first = fields[2]
+		}
+	}
+
+	if systemd != "" {
+		return systemd, nil
+	}
+
+	if first == "" {
+		return "", fmt.Errorf("No cgroup found")
+	}
+
+	return first, nil
+}
+
+func getPidCgroupWithHandler(readFile func(string) ([]byte, error), procRoot string, pid int) (string, error) {
+	data, err := readFile(fmt.Sprintf("%s/%d/cgroup", procRoot, pid))
+	if err != nil {
+		return "", err
+	}
+
+	return parseCgroup(string(data))
+}
+
+// getCgroupCountsWithHandlers counts the processes matching name in
+// each cgroup, keyed by cgroup path. A process exiting while it is
+// read is not counted.
+func getCgroupCountsWithHandlers(svc processByNameHandlers, name string) (map[string]int, error) {
+	processEntries, err := getProcessesByNameWithHandlers(svc, name)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+
+	for _, processEntry := range processEntries {
+		pid, _ := strconv.Atoi(processEntry.Name())
+
+		cgroup, err := getPidCgroupWithHandler(svc.readFile, svc.procDir(), pid)
+		if processExited(err) {
+			debugLog.Printf("Skipping process %d, it has exited", pid)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		counts[cgroup]++
+	}
+
+	return counts, nil
+}
+
+var cgroupLabelExp = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// cgroupLabel turns a cgroup path into a perfdata label safe
+// value by replacing runs of unsafe characters with underscores.
+func cgroupLabel(cgroup string) string {
+	label := strings.Trim(cgroupLabelExp.ReplaceAllString(cgroup, "_"), "_")
+	if label == "" {
+		label = "root"
+	}
+
+	return label
+}
+
+// checkCgroupCount counts the named processes in each cgroup and
+// issues a CRITICAL response if any cgroup has fewer than
+// options.MinCount or more than options.MaxCount processes. A
+// MaxCount of zero means there is no maximum.
+func checkCgroupCount(processCheck ProcessCheck, options ProcessCheckOptions) CheckResult {
+	cgroupService, ok := processCheck.ProcessCheckHandler.(processCgroupService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Cgroups are not available from the process service")
+	}
+
+	counts, err := cgroupService.CgroupCounts(processCheck.ProcessName)
+	if err != nil {
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not count process %s by cgroup: %s", processCheck.ProcessName, err), err)
+	}
+
+	if len(counts) == 0 {
+		return processCheck.notRunningResult(StateCritical,
+			fmt.Sprintf("Process %s is not running in any cgroup", processCheck.ProcessName))
+	}
+
+	cgroups := make([]string, 0, len(counts))
+	for cgroup := range counts {
+		cgroups = append(cgroups, cgroup)
+	}
+	sort.Strings(cgroups)
+
+	outOfRange := make([]string, 0)
+	perfData := make([]PerfData, 0, len(cgroups))
+
+	for _, cgroup := range cgroups {
+		count := counts[cgroup]
+
+		if count < options.MinCount || (options.MaxCount > 0 && count > options.MaxCount) {
+			outOfRange = append(outOfRange, fmt.Sprintf("%s (%d)", cgroup, count))
+		}
+
+		perfData = append(perfData, PerfData{
+			Label: options.MetricName + "_" + cgroupLabel(cgroup),
+			Value: float64(count),
+			Min:   "0",
+		})
+	}
+
+	// A host with many containers can produce more perfdata than
+	// Nagios accepts so only the metrics that fit are output.
+	if len(outOfRange) > 0 {
+		return CriticalResult(checkProcessName,
+			fmt.Sprintf("Process %s count out of range in %d of %d cgroups: %s",
+				processCheck.ProcessName, len(outOfRange), len(cgroups), strings.Join(outOfRange, ", ")),
+			perfData...)
+	}
+
+	return OKResult(checkProcessName,
+		fmt.Sprintf("Process %s count in range in %d cgroups", processCheck.ProcessName, len(cgroups)),
+		perfData...)
+}
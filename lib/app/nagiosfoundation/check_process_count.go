@@ -0,0 +1,105 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+)
+
+// processCountService is implemented by a ProcessService that can
+// also count the running instances of the named process.
+type processCountService interface {
+	ProcessCount(string) (int, error)
+}
+
+// checkCount counts the running instances of the named process
+// and compares the count against the options.Warning and
+// options.Critical ranges. An empty threshold is not checked. With
+// options.Delta the change in the count since the last run is
+// compared instead, see checkCountDelta. Otherwise with ShowPids the
+// PIDs of the processes counted are listed, and the verbose levels add
+// a line for each process.
+func checkCount(processCheck ProcessCheck, options ProcessCheckOptions) CheckResult {
+	countService, ok := processCheck.ProcessCheckHandler.(processCountService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Process counts are not available from the process service")
+	}
+
+	pids, listed, err := processCheck.pids()
+
+	count := len(pids)
+	if !listed {
+		count, err = countService.ProcessCount(processCheck.ProcessName)
+	}
+
+	if err != nil {
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not count instances of process %s: %s", processCheck.ProcessName, err), err)
+	}
+
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkProcessName, err.Error())
+	}
+
+	if options.Delta {
+		return checkCountDelta(processCheck, options, count, thresholds, newStateStore(options.StateDir))
+	}
+
+	state, tripped := thresholds.Status(float64(count))
+
+	checkInfo := fmt.Sprintf("%d instances of %s running", count, processCheck.ProcessName)
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+	}
+
+	if listed && count > 0 {
+		checkInfo += pidsText(pids)
+	}
+
+	checkInfo += processCheck.verboseProcesses()
+
+	return NewCheckResult(checkProcessName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: options.MetricName,
+		Value: float64(count),
+		Min:   "0",
+	}))
+}
+
+// countStateKey is the key the count of a delta check is saved as,
+// told apart by the process name and the metric name.
+func countStateKey(processCheck ProcessCheck, options ProcessCheckOptions) string {
+	return fmt.Sprintf("check_process_count_%s_%s", processCheck.ProcessName, options.MetricName)
+}
+
+// checkCountDelta saves the count to the store and compares the size
+// of the change from the count saved by the previous run against the
+// thresholds, in either direction, catching a sudden spike or drop in
+// an otherwise acceptable count. The first run has no count to
+// compare and emits a good response. The count and the change are
+// output as perfdata.
+func checkCountDelta(processCheck ProcessCheck, options ProcessCheckOptions, count int, thresholds Thresholds, store stateStore) CheckResult {
+	previous, ok, err := store.swap(countStateKey(processCheck, options), float64(count))
+	if err != nil {
+		return errorResult(checkProcessName, fmt.Sprintf("Could not save the process count in %s: %s", store.dir, err), err)
+	}
+
+	checkInfo := fmt.Sprintf("%d instances of %s running", count, processCheck.ProcessName)
+	countMetric := PerfData{Label: options.MetricName, Value: float64(count), Min: "0"}
+
+	if !ok {
+		return OKResult(checkProcessName, checkInfo+", no previous count to compare", countMetric)
+	}
+
+	delta := count - int(previous.Value)
+	state, tripped := thresholds.Status(math.Abs(float64(delta)))
+
+	checkInfo += fmt.Sprintf(", changed by %+d in %d seconds", delta, int(store.now().Sub(previous.Time).Seconds()))
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected a change of %s)", tripped.Expected())
+	}
+
+	return NewCheckResult(checkProcessName, state, checkInfo, countMetric, thresholds.Metric(PerfData{
+		Label: options.MetricName + "_delta",
+		Value: float64(delta),
+	}))
+}
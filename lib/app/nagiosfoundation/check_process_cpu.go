@@ -0,0 +1,273 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultCPUInterval is the time the "cpu" check samples the CPU
+// time of the processes over when no interval is given.
+const defaultCPUInterval = time.Second
+
+// processCPU is the CPU used by a process over the sampling interval
+// as a percentage of one CPU, so a process keeping two cores busy
+// uses 200%.
+type processCPU struct {
+	pid     int
+	percent float64
+}
+
+// processCPUService is implemented by a ProcessService that can also
+// sample the CPU used by each of the named processes over an
+// interval.
+type processCPUService interface {
+	ProcessCPU(string, time.Duration) ([]processCPU, error)
+}
+
+// parseStatCPUTime returns the CPU time of a process in clock ticks,
+// the sum of the user time and system time of fields 14 and 15 of
+// /proc/<pid>/stat, along with its start time to tell a process from
+// another given the same PID. As with the start time, the fields are
+// counted from the last closing parenthesis of the process name.
+func parseStatCPUTime(data string) (uint64, uint64, error) {
+	const utimeField, stimeField = 14, 15
+
+	nameEnd := strings.LastIndex(data, ")")
+	if nameEnd < 0 {
+		return 0, 0, fmt.Errorf("Could not parse process stat")
+	}
+
+	fields := strings.Fields(data[nameEnd+1:])
+	if len(fields) < stimeField-2 {
+		return 0, 0, fmt.Errorf("Could not parse process CPU time, too few stat fields")
+	}
+
+	utime, err := strconv.ParseUint(fields[utimeField-3], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Could not parse process user time: %s", err)
+	}
+
+	stime, err := strconv.ParseUint(fields[stimeField-3], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Could not parse process system time: %s", err)
+	}
+
+	startTime, err := parseStatStartTime(data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return utime + stime, startTime, nil
+}
+
+// processCPUSample is the CPU time of a process in clock ticks and
+// its start time, read from /proc/<pid>/stat.
+type processCPUSample struct {
+	ticks     uint64
+	startTime uint64
+}
+
+// readProcessCPUSamples reads the CPU time of each of the processes.
+// A process that has exited is left out.
+func readProcessCPUSamples(svc processByNameHandlers, pids []int) (map[int]processCPUSample, error) {
+	samples := make(map[int]processCPUSample, len(pids))
+
+	for _, pid := range pids {
+		stat, err := svc.readFile(fmt.Sprintf("%s/%d/stat", svc.procDir(), pid))
+		if processExited(err) {
+			debugLog.Printf("Skipping process %d, it has exited", pid)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		ticks, startTime, err := parseStatCPUTime(string(stat))
+		if err != nil {
+			return nil, err
+		}
+
+		samples[pid] = processCPUSample{ticks: ticks, startTime: startTime}
+	}
+
+	return samples, nil
+}
+
+// sampleProcessCPUWithHandlers reads the CPU time of each process
+// matching name from /proc/<pid>/stat, waits for interval with sleep
+// and reads it again. The CPU time is in clock ticks, so the
+// percentage of one CPU a process used is
+//
+//	(ticks after - ticks before) / clockTicksPerSecond / elapsed seconds * 100
+//
+// where the elapsed time is measured with now rather than taken to be
+// the interval, as sleep may wait longer. A process exiting or
+// replaced by another with the same PID during the interval is left
+// out, and a process started during the interval is not sampled.
+func sampleProcessCPUWithHandlers(svc processByNameHandlers, name string, interval time.Duration,
+	sleep func(time.Duration), now func() time.Time) ([]processCPU, error) {
+	processEntries, err := getProcessesByNameWithHandlers(svc, name)
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make([]int, 0, len(processEntries))
+	for _, processEntry := range processEntries {
+		pid, _ := strconv.Atoi(processEntry.Name())
+		pids = append(pids, pid)
+	}
+
+	start := now()
+	before, err := readProcessCPUSamples(svc, pids)
+	if err != nil {
+		return nil, err
+	}
+
+	sleep(interval)
+
+	after, err := readProcessCPUSamples(svc, pids)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := now().Sub(start).Seconds()
+
+	if elapsed <= 0 {
+		return nil, fmt.Errorf("No time elapsed between the CPU samples")
+	}
+
+	usage := make([]processCPU, 0, len(pids))
+
+	for _, pid := range pids {
+		first, ok := before[pid]
+		if !ok {
+			continue
+		}
+
+		second, ok := after[pid]
+		if !ok || second.startTime != first.startTime || second.ticks < first.ticks {
+			debugLog.Printf("Skipping process %d, it has exited", pid)
+			continue
+		}
+
+		usage = append(usage, processCPU{
+			pid:     pid,
+			percent: float64(second.ticks-first.ticks) / clockTicksPerSecond / elapsed * 100,
+		})
+	}
+
+	if len(usage) == 0 {
+		return nil, errProcessNotRunning
+	}
+
+	return usage, nil
+}
+
+// checkProcessCPU samples the CPU used by the named processes over
+// options.Interval, defaulting to a second, on the CPUs of the host.
+//
+// Returns are those of checkProcessCPUWithCPUs()
+func checkProcessCPU(processCheck ProcessCheck, options ProcessCheckOptions) CheckResult {
+	return checkProcessCPUWithCPUs(processCheck, options, runtime.NumCPU())
+}
+
+// checkProcessCPUWithCPUs samples the CPU used by the named processes
+// and compares the total percentage against the options.Warning and
+// options.Critical thresholds, or with options.PerProcess the
+// percentage of each process, to catch a process pegging a core. The
+// percentage is of one CPU, as in top, unless options.OfCPUs divides
+// it by the number of CPUs so that 100% is every CPU busy. A process
+// that is not running is UNKNOWN, as there is no CPU to measure.
+func checkProcessCPUWithCPUs(processCheck ProcessCheck, options ProcessCheckOptions, cpus int) CheckResult {
+	cpuService, ok := processCheck.ProcessCheckHandler.(processCPUService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Process CPU usage is not available from the process service")
+	}
+
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkProcessName, err.Error())
+	}
+
+	interval := options.Interval
+	if interval == 0 {
+		interval = defaultCPUInterval
+	}
+
+	processes, err := cpuService.ProcessCPU(processCheck.ProcessName, interval)
+
+	switch {
+	case err == errProcessNotRunning:
+		return processCheck.notRunningResult(StateUnknown, fmt.Sprintf("Process %s is not running", processCheck.ProcessName))
+	case err != nil:
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not read the CPU usage of process %s: %s", processCheck.ProcessName, err), err)
+	}
+
+	metric := PerfData{Label: options.MetricName, UOM: "%", Min: "0"}
+	scale := 1.0
+	of := ""
+
+	if options.OfCPUs && cpus > 0 {
+		scale = 1 / float64(cpus)
+		metric.Max = "100"
+		of = fmt.Sprintf(" of %d CPUs", cpus)
+	}
+
+	if !options.PerProcess {
+		total := 0.0
+		for _, process := range processes {
+			total += process.percent * scale
+		}
+		total = math.Round(total*10) / 10
+
+		state, tripped := thresholds.Status(total)
+
+		checkInfo := fmt.Sprintf("%.1f%% CPU%s over %s in %d instances of %s", total, of, interval, len(processes), processCheck.ProcessName)
+		if state != StateOK {
+			checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+		}
+
+		metric.Value = total
+
+		return NewCheckResult(checkProcessName, state, checkInfo, thresholds.Metric(metric))
+	}
+
+	// Each process is checked on its own, reporting the processes
+	// outside the thresholds and the largest percentage as perfdata,
+	// as the PIDs change with each restart.
+	state := StateOK
+	most := 0.0
+	var tripped []string
+
+	for _, process := range processes {
+		percent := math.Round(process.percent*scale*10) / 10
+		if percent > most {
+			most = percent
+		}
+
+		processState, processRange := thresholds.Status(percent)
+		if processState == StateOK {
+			continue
+		}
+
+		if processState == StateCritical || state == StateOK {
+			state = processState
+		}
+
+		tripped = append(tripped, fmt.Sprintf("process %d uses %.1f%% CPU (expected %s)",
+			process.pid, percent, processRange.Expected()))
+	}
+
+	checkInfo := fmt.Sprintf("The %d instances of %s use at most %.1f%% CPU%s over %s", len(processes), processCheck.ProcessName, most, of, interval)
+	if len(tripped) > 0 {
+		checkInfo = fmt.Sprintf("%d of %d instances of %s use too much CPU%s over %s: %s",
+			len(tripped), len(processes), processCheck.ProcessName, of, interval, strings.Join(tripped, ", "))
+	}
+
+	metric.Value = most
+
+	return NewCheckResult(checkProcessName, state, checkInfo, thresholds.Metric(metric))
+}
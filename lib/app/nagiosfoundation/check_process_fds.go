@@ -0,0 +1,240 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// processFds is the number of open file descriptors of a process and
+// its soft limit on open files, zero when not available or unlimited.
+type processFds struct {
+	pid   int
+	count int
+	limit uint64
+}
+
+// percent returns the open file descriptors as a percentage of the
+// limit.
+func (f processFds) percent() float64 {
+	return float64(f.count) * 100 / float64(f.limit)
+}
+
+// processFdsService is implemented by a ProcessService that can also
+// count the open file descriptors of each of the named processes.
+type processFdsService interface {
+	ProcessFds(string) ([]processFds, error)
+}
+
+// parseLimitsOpenFiles returns the soft limit on open files from the
+// "Max open files" line of /proc/<pid>/limits, or zero when the limit
+// is unlimited or the line is missing.
+func parseLimitsOpenFiles(data string) (uint64, error) {
+	const openFilesLimit = "Max open files"
+
+	for _, line := range strings.Split(data, "\n") {
+		if !strings.HasPrefix(line, openFilesLimit) {
+			continue
+		}
+
+		fields := strings.Fields(line[len(openFilesLimit):])
+		if len(fields) == 0 {
+			return 0, fmt.Errorf("Could not parse open files limit: %s", line)
+		}
+
+		if fields[0] == "unlimited" {
+			return 0, nil
+		}
+
+		limit, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Could not parse open files limit: %s", line)
+		}
+
+		return limit, nil
+	}
+
+	return 0, nil
+}
+
+// getProcessFdsWithHandlers counts the entries in /proc/<pid>/fd of
+// each process matching name and reads its soft limit on open files
+// from /proc/<pid>/limits. A process exiting while it is read is
+// skipped, while a process whose descriptors may not be listed, such
+// as one of another user, is an error as the count would be wrong.
+func getProcessFdsWithHandlers(svc processByNameHandlers, name string) ([]processFds, error) {
+	processEntries, err := getProcessesByNameWithHandlers(svc, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(processEntries) == 0 {
+		return nil, errProcessNotRunning
+	}
+
+	fds := make([]processFds, 0, len(processEntries))
+
+	for _, processEntry := range processEntries {
+		pid, _ := strconv.Atoi(processEntry.Name())
+
+		entries, err := svc.listDir(fmt.Sprintf("%s/%d/fd", svc.procDir(), pid))
+		if processExited(err) {
+			debugLog.Printf("Skipping process %d, it has exited", pid)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var limit uint64
+		if limits, err := svc.readFile(fmt.Sprintf("%s/%d/limits", svc.procDir(), pid)); err != nil {
+			debugLog.Printf("Could not read the limits of process %d: %s", pid, err)
+		} else if limit, err = parseLimitsOpenFiles(string(limits)); err != nil {
+			return nil, err
+		}
+
+		fds = append(fds, processFds{pid: pid, count: len(entries), limit: limit})
+	}
+
+	if len(fds) == 0 {
+		return nil, errProcessNotRunning
+	}
+
+	return fds, nil
+}
+
+// describeFds describes the open files of a process, or the total of
+// several, with the limit and the percentage of it used when known.
+func describeFds(fds processFds) string {
+	if fds.limit == 0 {
+		return fmt.Sprintf("%d open files", fds.count)
+	}
+
+	return fmt.Sprintf("%d open files of a limit of %d (%.1f%%)", fds.count, fds.limit, fds.percent())
+}
+
+// checkFds counts the open file descriptors of the named processes and
+// compares the total against the options.Warning and options.Critical
+// thresholds, or with options.PerProcess the count of each process,
+// to catch a process leaking file descriptors. With options.OfLimit
+// the thresholds are a percentage of the soft limit on open files,
+// the total of the limits when the processes are summed. A process
+// that is not running is UNKNOWN, as there are no files to count.
+func checkFds(processCheck ProcessCheck, options ProcessCheckOptions) CheckResult {
+	fdsService, ok := processCheck.ProcessCheckHandler.(processFdsService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Process file descriptors are not available from the process service")
+	}
+
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkProcessName, err.Error())
+	}
+
+	processes, err := fdsService.ProcessFds(processCheck.ProcessName)
+
+	switch {
+	case err == errProcessNotRunning:
+		return processCheck.notRunningResult(StateUnknown, fmt.Sprintf("Process %s is not running", processCheck.ProcessName))
+	case err != nil:
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not count file descriptors of process %s: %s", processCheck.ProcessName, err), err)
+	}
+
+	// The value checked is the count, or the percentage of the limit,
+	// which is UNKNOWN when the limit isn't known or is unlimited.
+	value := func(fds processFds) (float64, bool) {
+		if !options.OfLimit {
+			return float64(fds.count), true
+		}
+
+		if fds.limit == 0 {
+			return 0, false
+		}
+
+		return math.Round(fds.percent()*10) / 10, true
+	}
+
+	metric := func(fds processFds) PerfData {
+		v, _ := value(fds)
+		perfData := PerfData{Label: options.MetricName, Value: v, Min: "0"}
+
+		if options.OfLimit {
+			perfData.UOM, perfData.Max = "%", "100"
+		} else if fds.limit > 0 {
+			perfData.Max = strconv.FormatUint(fds.limit, 10)
+		}
+
+		return thresholds.Metric(perfData)
+	}
+
+	if !options.PerProcess {
+		total := processFds{}
+		limited := true
+
+		for _, process := range processes {
+			total.count += process.count
+			total.limit += process.limit
+			limited = limited && process.limit > 0
+		}
+
+		if !limited {
+			total.limit = 0
+		}
+
+		v, ok := value(total)
+		if !ok {
+			return UnknownResult(checkProcessName,
+				fmt.Sprintf("The open files limit of process %s is not available", processCheck.ProcessName))
+		}
+
+		state, tripped := thresholds.Status(v)
+
+		checkInfo := fmt.Sprintf("%s in %d instances of %s", describeFds(total), len(processes), processCheck.ProcessName)
+		if state != StateOK {
+			checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+		}
+
+		return NewCheckResult(checkProcessName, state, checkInfo, metric(total))
+	}
+
+	// Each process is checked on its own, reporting the processes
+	// outside the thresholds and the largest value as perfdata, as the
+	// PIDs change with each restart.
+	state := StateOK
+	var most processFds
+	mostValue := -1.0
+	var tripped []string
+
+	for _, process := range processes {
+		v, ok := value(process)
+		if !ok {
+			return UnknownResult(checkProcessName,
+				fmt.Sprintf("The open files limit of process %d of %s is not available", process.pid, processCheck.ProcessName))
+		}
+
+		if v > mostValue {
+			most, mostValue = process, v
+		}
+
+		processState, processRange := thresholds.Status(v)
+		if processState == StateOK {
+			continue
+		}
+
+		if processState == StateCritical || state == StateOK {
+			state = processState
+		}
+
+		tripped = append(tripped, fmt.Sprintf("process %d has %s (expected %s)",
+			process.pid, describeFds(process), processRange.Expected()))
+	}
+
+	checkInfo := fmt.Sprintf("The %d instances of %s have at most %s", len(processes), processCheck.ProcessName, describeFds(most))
+	if len(tripped) > 0 {
+		checkInfo = fmt.Sprintf("%d of %d instances of %s have too many open files: %s",
+			len(tripped), len(processes), processCheck.ProcessName, strings.Join(tripped, ", "))
+	}
+
+	return NewCheckResult(checkProcessName, state, checkInfo, metric(most))
+}
@@ -0,0 +1,120 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ProcessInfo describes a running process.
+type ProcessInfo struct {
+	PID  int
+	Name string
+
+	// The resident memory of the process in bytes. Zero when the
+	// process uses none of its own, such as a kernel thread, or when
+	// it is not available.
+	RSS uint64
+
+	// When the process started. The zero time when not available.
+	StartTime time.Time
+
+	// The number of threads of the process. Zero when not available.
+	Threads int
+}
+
+// ProcessInspector lists the running processes of the OS. It is the
+// one place the processes are read from, so a check built on it
+// works on each OS and can be tested on any OS with a test
+// inspector.
+type ProcessInspector interface {
+	// List returns the running processes matching name, up to
+	// limit of them, where a limit of 0 returns every process, so
+	// callers needing a single process can stop the scan early.
+	List(name string, limit int) ([]ProcessInfo, error)
+}
+
+// procfsInspector is the ProcessInspector reading the proc
+// filesystem on Linux.
+type procfsInspector struct {
+	svc processByNameHandlers
+	now func() time.Time
+}
+
+// List returns the processes matching name, scoped by the settings of
+// the handlers. The start time is the boot time, the time now less
+// the system uptime, plus the start time of the process after boot.
+// A process exiting while it is read is not listed.
+func (i procfsInspector) List(name string, limit int) ([]ProcessInfo, error) {
+	processEntries, err := findProcessesByNameWithHandlers(i.svc, name, limit)
+	if err != nil || len(processEntries) == 0 {
+		return nil, err
+	}
+
+	data, err := i.svc.readFile(i.svc.procDir() + "/uptime")
+	if err != nil {
+		return nil, err
+	}
+
+	uptime, err := parseUptime(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bootTime := i.now().Add(-time.Duration(uptime * float64(time.Second)))
+
+	processes := make([]ProcessInfo, 0, len(processEntries))
+
+	for _, processEntry := range processEntries {
+		pid, _ := strconv.Atoi(processEntry.Name())
+
+		procName, err := i.svc.getPidName(i.svc.readFile, i.svc.procDir(), pid)
+		if processExited(err) {
+			debugLog.Printf("Skipping process %d, it has exited", pid)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		stat, err := i.svc.readFile(fmt.Sprintf("%s/%d/stat", i.svc.procDir(), pid))
+		if processExited(err) {
+			debugLog.Printf("Skipping process %d, it has exited", pid)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		startTime, err := parseStatStartTime(string(stat))
+		if err != nil {
+			return nil, err
+		}
+
+		threads, err := parseStatThreads(string(stat))
+		if err != nil {
+			return nil, err
+		}
+
+		status, err := i.svc.readFile(fmt.Sprintf("%s/%d/status", i.svc.procDir(), pid))
+		if processExited(err) {
+			debugLog.Printf("Skipping process %d, it has exited", pid)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		rss, err := parseStatusRSS(string(status))
+		if err != nil {
+			return nil, err
+		}
+
+		processes = append(processes, ProcessInfo{
+			PID:       pid,
+			Name:      procName,
+			RSS:       rss,
+			StartTime: bootTime.Add(time.Duration(float64(startTime) / clockTicksPerSecond * float64(time.Second))),
+			Threads:   threads,
+		})
+	}
+
+	return processes, nil
+}
@@ -2,12 +2,62 @@
 
 package nagiosfoundation
 
-func isProcessRunningOsConstrained(name string) bool {
-	retVal := false
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
 
-	if processEntries, _ := getProcessesByName(name); len(processEntries) > 0 {
-		retVal = true
+func newProcessInspectorOsConstrained(p processHandler) ProcessInspector {
+	return procfsInspector{svc: p.procHandlers(), now: time.Now}
+}
+
+func getProcessesRunningOsConstrained(p processHandler, names []string) (map[string]bool, error) {
+	return getProcessesRunningWithHandlers(p.procHandlers(), names)
+}
+
+func getWritableExecutableMappingsOsConstrained(p processHandler, name string) ([]memoryMapping, error) {
+	return getWritableExecutableMappingsWithHandlers(p.procHandlers(), name)
+}
+
+func getLogAgeOsConstrained(p processHandler, name, logPath string) (time.Duration, error) {
+	return getLogAgeWithHandlers(p.procHandlers(), name, logPath)
+}
+
+func getCgroupCountsOsConstrained(p processHandler, name string) (map[string]int, error) {
+	return getCgroupCountsWithHandlers(p.procHandlers(), name)
+}
+
+func getProcessFdsOsConstrained(p processHandler, name string) ([]processFds, error) {
+	return getProcessFdsWithHandlers(p.procHandlers(), name)
+}
+
+func getProcessZombiesOsConstrained(p processHandler, name string) ([]processZombie, error) {
+	return getProcessZombiesWithHandlers(p.procHandlers(), name)
+}
+
+func getProcessListeningPortsOsConstrained(p processHandler, name string) ([]int, error) {
+	return getProcessListeningPortsWithHandlers(p.procHandlers(), name)
+}
+
+func getProcessCPUOsConstrained(p processHandler, name string, interval time.Duration) ([]processCPU, error) {
+	return sampleProcessCPUWithHandlers(p.procHandlers(), name, interval, time.Sleep, time.Now)
+}
+
+// getPidUIDWithHandler returns the UID of the owner of the process,
+// the owner of its /proc/<pid> directory.
+func getPidUIDWithHandler(stat func(string) (os.FileInfo, error), procRoot string, pid int) (string, error) {
+	info, err := stat(fmt.Sprintf("%s/%d", procRoot, pid))
+	if err != nil {
+		return "", err
+	}
+
+	sys, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", fmt.Errorf("No owner for process %d", pid)
 	}
 
-	return retVal
+	return strconv.FormatUint(uint64(sys.Uid), 10), nil
 }
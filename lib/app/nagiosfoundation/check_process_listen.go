@@ -0,0 +1,166 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tcpListenState is the state of a listening socket in /proc/net/tcp,
+// TCP_LISTEN.
+const tcpListenState = "0A"
+
+// processListeningService is implemented by a ProcessService that can
+// also list the TCP ports the named process is listening on.
+type processListeningService interface {
+	ProcessListeningPorts(string) ([]int, error)
+}
+
+// parseNetTCPListeners returns the port of each listening socket in
+// the /proc/net/tcp or /proc/net/tcp6 format, keyed by the inode of
+// the socket. The local address is the address and port in hex, such
+// as 0100007F:1F90 for 127.0.0.1:8080.
+func parseNetTCPListeners(data string) map[string]int {
+	listeners := make(map[string]int)
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[3] != tcpListenState {
+			continue
+		}
+
+		i := strings.LastIndex(fields[1], ":")
+		if i < 0 {
+			continue
+		}
+
+		port, err := strconv.ParseUint(fields[1][i+1:], 16, 16)
+		if err != nil {
+			continue
+		}
+
+		listeners[fields[9]] = int(port)
+	}
+
+	return listeners
+}
+
+// getProcessListeningPortsWithHandlers returns the TCP ports the
+// processes matching name are listening on, in order. The socket
+// inodes open by each process, the socket:[<inode>] links in
+// /proc/<pid>/fd, are looked up among the listening sockets of
+// /proc/<pid>/net/tcp and tcp6, which are those of the network
+// namespace of the process, so a process in a container is checked
+// in its own namespace. A process exiting while it is read is skipped,
+// and errProcessNotRunning is returned when every process has.
+func getProcessListeningPortsWithHandlers(svc processByNameHandlers, name string) ([]int, error) {
+	processEntries, err := getProcessesByNameWithHandlers(svc, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(processEntries) == 0 {
+		return nil, errProcessNotRunning
+	}
+
+	seen := make(map[int]bool)
+	ports := make([]int, 0)
+	read := 0
+
+	for _, processEntry := range processEntries {
+		pidDir := fmt.Sprintf("%s/%s", svc.procDir(), processEntry.Name())
+
+		fds, err := svc.listDir(pidDir + "/fd")
+		if processExited(err) {
+			debugLog.Printf("Skipping process %s, it has exited", processEntry.Name())
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		read++
+
+		listeners := make(map[string]int)
+		for _, netFile := range []string{"tcp", "tcp6"} {
+			// tcp6 is missing when IPv6 is disabled.
+			data, err := svc.readFile(pidDir + "/net/" + netFile)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			for inode, port := range parseNetTCPListeners(string(data)) {
+				listeners[inode] = port
+			}
+		}
+
+		for _, fd := range fds {
+			// The descriptor may be closed between listing and
+			// reading the link so errors are skipped.
+			target, err := svc.readLink(pidDir + "/fd/" + fd)
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+
+			inode := strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
+			if port, ok := listeners[inode]; ok && !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+
+	if read == 0 {
+		return nil, errProcessNotRunning
+	}
+
+	sort.Ints(ports)
+
+	return ports, nil
+}
+
+// checkListening checks the named process is listening on the TCP
+// port options.Port, emitting a critical response when the process is
+// running but the port is not bound, as a started but wedged service
+// would be, listing the ports the process is listening on instead.
+// A process that is not running is critical.
+func checkListening(processCheck ProcessCheck, options ProcessCheckOptions) CheckResult {
+	listeningService, ok := processCheck.ProcessCheckHandler.(processListeningService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Listening ports are not available from the process service")
+	}
+
+	ports, err := listeningService.ProcessListeningPorts(processCheck.ProcessName)
+
+	switch {
+	case err == errProcessNotRunning:
+		return processCheck.notRunningResult(StateCritical, fmt.Sprintf("Process %s is not running", processCheck.ProcessName),
+			PerfData{Label: options.MetricName, Value: statusCodeCritical})
+	case err != nil:
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not read the sockets of process %s: %s", processCheck.ProcessName, err), err)
+	}
+
+	for _, port := range ports {
+		if port == options.Port {
+			return OKResult(checkProcessName,
+				fmt.Sprintf("Process %s is listening on port %d", processCheck.ProcessName, options.Port),
+				PerfData{Label: options.MetricName, Value: statusCodeOK})
+		}
+	}
+
+	checkInfo := fmt.Sprintf("Process %s is running but not listening on port %d", processCheck.ProcessName, options.Port)
+	if len(ports) > 0 {
+		listening := make([]string, len(ports))
+		for i, port := range ports {
+			listening[i] = strconv.Itoa(port)
+		}
+
+		checkInfo += fmt.Sprintf(", it is listening on %s", strings.Join(listening, ", "))
+	}
+
+	return CriticalResult(checkProcessName, checkInfo, PerfData{Label: options.MetricName, Value: statusCodeCritical})
+}
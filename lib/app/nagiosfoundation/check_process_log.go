@@ -0,0 +1,149 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+var errLogNotOpen = errors.New("Log not open by process")
+
+// processLogService is implemented by a ProcessService that can
+// also find the age of a log file open by the named process.
+type processLogService interface {
+	LogAge(name, logPath string) (time.Duration, error)
+}
+
+// getLogAgeWithHandlers finds the processes matching name, then looks
+// through the file descriptors of each for one pointing at logPath.
+// Returns the time since the log file was last modified, or
+// errLogNotOpen if no matching process has the log open. A process
+// exiting while it is read is skipped, and errProcessNotRunning is
+// returned when every process has.
+func getLogAgeWithHandlers(svc processByNameHandlers, name, logPath string) (time.Duration, error) {
+	processEntries, err := getProcessesByNameWithHandlers(svc, name)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(processEntries) == 0 {
+		return 0, errProcessNotRunning
+	}
+
+	logPath = filepath.Clean(logPath)
+	logOpen := false
+	read := 0
+
+	for _, processEntry := range processEntries {
+		fdDir := fmt.Sprintf("%s/%s/fd", svc.procDir(), processEntry.Name())
+
+		fds, err := svc.listDir(fdDir)
+		if processExited(err) {
+			debugLog.Printf("Skipping process %s, it has exited", processEntry.Name())
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+
+		read++
+
+		for _, fd := range fds {
+			// The descriptor may be closed between listing and
+			// reading the link so errors are skipped.
+			if target, err := svc.readLink(fdDir + "/" + fd); err == nil && target == logPath {
+				logOpen = true
+				break
+			}
+		}
+
+		if logOpen {
+			break
+		}
+	}
+
+	if read == 0 {
+		return 0, errProcessNotRunning
+	} else if !logOpen {
+		return 0, errLogNotOpen
+	}
+
+	logInfo, err := svc.stat(logPath)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Since(logInfo.ModTime()), nil
+}
+
+const defaultLogWarningSeconds = "300"
+const defaultLogCriticalSeconds = "900"
+
+// logAgeThreshold returns the threshold range in seconds, parsing
+// defaultSeconds when the threshold is empty.
+func logAgeThreshold(threshold string, defaultSeconds string) (Range, error) {
+	if threshold == "" {
+		threshold = defaultSeconds
+	}
+
+	return ParseRange(threshold)
+}
+
+// checkLogActive checks the named process has the log at
+// options.LogPath open and that the log was modified within
+// options.Warning and options.Critical seconds.
+func checkLogActive(processCheck ProcessCheck, options ProcessCheckOptions) CheckResult {
+	logService, ok := processCheck.ProcessCheckHandler.(processLogService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Log files are not available from the process service")
+	}
+
+	warning, err := logAgeThreshold(options.Warning, defaultLogWarningSeconds)
+	if err != nil {
+		return UnknownResult(checkProcessName, err.Error())
+	}
+
+	critical, err := logAgeThreshold(options.Critical, defaultLogCriticalSeconds)
+	if err != nil {
+		return UnknownResult(checkProcessName, err.Error())
+	}
+
+	age, err := logService.LogAge(processCheck.ProcessName, options.LogPath)
+	ageSeconds := int(age.Seconds())
+
+	var state State
+
+	switch {
+	case err == errProcessNotRunning:
+		return processCheck.notRunningResult(StateCritical, fmt.Sprintf("Process %s is not running", processCheck.ProcessName))
+	case err == errLogNotOpen:
+		return CriticalResult(checkProcessName,
+			fmt.Sprintf("Log %s is not open by process %s", options.LogPath, processCheck.ProcessName))
+	case err != nil:
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not determine log %s age for process %s: %s", options.LogPath, processCheck.ProcessName, err), err)
+	case critical.Check(float64(ageSeconds)):
+		state = StateCritical
+	case warning.Check(float64(ageSeconds)):
+		state = StateWarning
+	default:
+		state = StateOK
+	}
+
+	var checkInfo string
+	if state == StateOK {
+		checkInfo = fmt.Sprintf("Log %s of process %s was written %ds ago",
+			options.LogPath, processCheck.ProcessName, ageSeconds)
+	} else {
+		checkInfo = fmt.Sprintf("Log %s of process %s is stale, last written %ds ago",
+			options.LogPath, processCheck.ProcessName, ageSeconds)
+	}
+
+	return NewCheckResult(checkProcessName, state, checkInfo, PerfData{
+		Label:    options.MetricName,
+		Value:    float64(ageSeconds),
+		UOM:      "s",
+		Warning:  warning.String(),
+		Critical: critical.String(),
+	})
+}
@@ -0,0 +1,105 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// processMemoryService is implemented by a ProcessService that can
+// also total the resident memory of the named processes.
+type processMemoryService interface {
+	ProcessMemory(string) (uint64, int, error)
+}
+
+// parseStatusRSS returns the resident set size in bytes from the
+// VmRSS line of /proc/<pid>/status, given in kB. Kernel threads
+// have no VmRSS line and use no resident memory of their own.
+func parseStatusRSS(data string) (uint64, error) {
+	for _, line := range strings.Split(data, "\n") {
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[2] != "kB" {
+			return 0, fmt.Errorf("Could not parse resident memory entry: %s", line)
+		}
+
+		rss, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Could not parse resident memory entry: %s", line)
+		}
+
+		return rss * 1024, nil
+	}
+
+	return 0, nil
+}
+
+// processMemory returns the total resident memory in bytes of the
+// processes matching name and the number of processes.
+func processMemory(inspector ProcessInspector, name string) (uint64, int, error) {
+	processes, err := inspector.List(name, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(processes) == 0 {
+		return 0, 0, errProcessNotRunning
+	}
+
+	var total uint64
+
+	for _, process := range processes {
+		total += process.RSS
+	}
+
+	return total, len(processes), nil
+}
+
+// checkMemory totals the resident memory of the named processes and
+// compares the total in megabytes against the options.Warning and
+// options.Critical thresholds.
+func checkMemory(processCheck ProcessCheck, options ProcessCheckOptions) CheckResult {
+	memoryService, ok := processCheck.ProcessCheckHandler.(processMemoryService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Process memory is not available from the process service")
+	}
+
+	rss, count, err := memoryService.ProcessMemory(processCheck.ProcessName)
+
+	switch {
+	case err == errProcessNotRunning:
+		return processCheck.notRunningResult(StateCritical, fmt.Sprintf("Process %s is not running", processCheck.ProcessName))
+	case err != nil:
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not read memory usage of process %s: %s", processCheck.ProcessName, err), err)
+	}
+
+	megabytes := float64(rss) / (1024 * 1024)
+
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkProcessName, err.Error())
+	}
+
+	state, _ := thresholds.Status(megabytes)
+
+	checkInfo := fmt.Sprintf("%d instances of %s using %.1fMB", count, processCheck.ProcessName, megabytes)
+
+	switch state {
+	case StateCritical:
+		checkInfo += fmt.Sprintf(", critical threshold %s tripped", options.Critical)
+	case StateWarning:
+		checkInfo += fmt.Sprintf(", warning threshold %s tripped", options.Warning)
+	}
+
+	return NewCheckResult(checkProcessName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: options.MetricName,
+		Value: math.Round(megabytes*10) / 10,
+		UOM:   "MB",
+		Min:   "0",
+	}))
+}
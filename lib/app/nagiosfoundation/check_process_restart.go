@@ -0,0 +1,148 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// processStartTolerance is how far apart the start times of a PID in
+// two runs may be for the process to be taken as the same. The start
+// time is computed from the time since boot, so it moves slightly
+// between runs as the clock is adjusted.
+const processStartTolerance = 2 * time.Second
+
+// processStart is a running instance of a process saved by the
+// restarted check, told apart from a later process reusing its PID by
+// its start time.
+type processStart struct {
+	PID       int       `json:"pid"`
+	StartTime time.Time `json:"start_time"`
+}
+
+// processStartsState is the state saved by the restarted check, the
+// instances of the process running at Time.
+type processStartsState struct {
+	Processes []processStart `json:"processes"`
+	Time      time.Time      `json:"time"`
+}
+
+// restartStateKey is the key the instances of a restarted check are
+// saved as, told apart by the process name and the metric name.
+func restartStateKey(processCheck ProcessCheck, options ProcessCheckOptions) string {
+	return fmt.Sprintf("check_process_restarted_%s_%s", processCheck.ProcessName, options.MetricName)
+}
+
+// sameProcessStart reports whether a and b are the same process, the
+// same PID started within processStartTolerance.
+func sameProcessStart(a, b processStart) bool {
+	delta := a.StartTime.Sub(b.StartTime)
+	if delta < 0 {
+		delta = -delta
+	}
+
+	return a.PID == b.PID && delta <= processStartTolerance
+}
+
+// missingProcessStarts returns the PIDs of the processes of starts
+// not in others.
+func missingProcessStarts(starts, others []processStart) []int {
+	var pids []int
+
+	for _, start := range starts {
+		found := false
+		for _, other := range others {
+			if sameProcessStart(start, other) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			pids = append(pids, start.PID)
+		}
+	}
+
+	return pids
+}
+
+// checkRestarted saves the PID and start time of each running
+// instance of the named process to the store and emits a warning
+// response when they differ from those saved by the previous run,
+// catching a process restarted between two polls that a running check
+// would miss, such as one restarted by its supervisor after a crash.
+// The first run has nothing to compare and emits a good response. The
+// instances started since the previous run are output as perfdata.
+func checkRestarted(processCheck ProcessCheck, options ProcessCheckOptions, store stateStore) CheckResult {
+	detailsService, ok := processCheck.ProcessCheckHandler.(processDetailsService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Process start times are not available from the process service")
+	}
+
+	processes, err := detailsService.ProcessDetails(processCheck.ProcessName)
+	if err != nil {
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not determine the start times of process %s: %s", processCheck.ProcessName, err), err)
+	}
+
+	// The instances of the previous run are kept while the process is
+	// not running, so the run after it has started again tells it
+	// restarted.
+	if len(processes) == 0 {
+		return processCheck.notRunningResult(StateCritical, fmt.Sprintf("Process %s is not running", processCheck.ProcessName))
+	}
+
+	current := processStartsState{Time: store.now()}
+	for _, process := range processes {
+		current.Processes = append(current.Processes, processStart{PID: process.PID, StartTime: process.StartTime})
+	}
+
+	sort.Slice(current.Processes, func(i, j int) bool { return current.Processes[i].PID < current.Processes[j].PID })
+
+	key := restartStateKey(processCheck, options)
+
+	var previous processStartsState
+	saved, err := store.loadJSON(key, &previous)
+	if err == nil {
+		err = store.saveJSON(key, current)
+	}
+
+	if err != nil {
+		return errorResult(checkProcessName, fmt.Sprintf("Could not save the process start times in %s: %s", store.dir, err), err)
+	}
+
+	checkInfo := fmt.Sprintf("%d instances of %s running", len(current.Processes), processCheck.ProcessName)
+
+	if !saved {
+		return OKResult(checkProcessName, checkInfo+", no previous start times to compare",
+			PerfData{Label: options.MetricName, Value: 0, Min: "0"})
+	}
+
+	started := missingProcessStarts(current.Processes, previous.Processes)
+	exited := missingProcessStarts(previous.Processes, current.Processes)
+	seconds := int(current.Time.Sub(previous.Time).Seconds())
+
+	state := StateOK
+	if len(started) == 0 && len(exited) == 0 {
+		checkInfo += fmt.Sprintf(", none restarted in %d seconds", seconds)
+	} else {
+		state = StateWarning
+		checkInfo = fmt.Sprintf("Process %s restarted in %d seconds", processCheck.ProcessName, seconds)
+
+		if len(started) > 0 {
+			checkInfo += fmt.Sprintf(", %d started", len(started)) + pidsText(started)
+		}
+
+		if len(exited) > 0 {
+			checkInfo += fmt.Sprintf(", %d exited", len(exited)) + pidsText(exited)
+		}
+	}
+
+	checkInfo += processCheck.verboseProcesses()
+
+	return NewCheckResult(checkProcessName, state, checkInfo, PerfData{
+		Label: options.MetricName,
+		Value: float64(len(started)),
+		Min:   "0",
+	})
+}
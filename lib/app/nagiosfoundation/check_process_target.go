@@ -0,0 +1,158 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const processTargetSeparator = ";"
+
+// processTargetFields sets the ProcessCheckOptions field for each
+// key accepted in a process target. The keys match the check_process
+// flag names.
+var processTargetFields = map[string]func(*ProcessCheckOptions, string) error{
+	"name":              func(o *ProcessCheckOptions, v string) error { o.Name, o.Names = v, nil; return nil },
+	"type":              func(o *ProcessCheckOptions, v string) error { o.CheckType = v; return nil },
+	"metric_name":       func(o *ProcessCheckOptions, v string) error { o.MetricName = v; return nil },
+	"log_path":          func(o *ProcessCheckOptions, v string) error { o.LogPath = v; return nil },
+	"pid_ns":            func(o *ProcessCheckOptions, v string) error { o.PidNamespace = v; return nil },
+	"match_cmdline":     func(o *ProcessCheckOptions, v string) error { o.MatchCmdline = v; return nil },
+	"exe_path":          func(o *ProcessCheckOptions, v string) error { o.ExePath = v; return nil },
+	"user":              func(o *ProcessCheckOptions, v string) error { o.User = v; return nil },
+	"select":            func(o *ProcessCheckOptions, v string) error { o.Select = v; return nil },
+	"procfs_root":       func(o *ProcessCheckOptions, v string) error { o.ProcfsRoot = v; return nil },
+	"negate_on_missing": func(o *ProcessCheckOptions, v string) error { return parseTargetState(v, &o.MissingState) },
+	"regex":             func(o *ProcessCheckOptions, v string) error { return parseTargetBool(v, &o.Regex) },
+	"per_process":       func(o *ProcessCheckOptions, v string) error { return parseTargetBool(v, &o.PerProcess) },
+	"show_pids":         func(o *ProcessCheckOptions, v string) error { return parseTargetBool(v, &o.ShowPids) },
+	"of_limit":          func(o *ProcessCheckOptions, v string) error { return parseTargetBool(v, &o.OfLimit) },
+	"of_cpus":           func(o *ProcessCheckOptions, v string) error { return parseTargetBool(v, &o.OfCPUs) },
+	"interval":          func(o *ProcessCheckOptions, v string) error { return parseTargetDuration(v, &o.Interval) },
+	"expect_single":     func(o *ProcessCheckOptions, v string) error { return parseTargetBool(v, &o.ExpectSingle) },
+	"delta":             func(o *ProcessCheckOptions, v string) error { return parseTargetBool(v, &o.Delta) },
+	"state_dir":         func(o *ProcessCheckOptions, v string) error { o.StateDir = v; return nil },
+	"warning":           func(o *ProcessCheckOptions, v string) error { o.Warning = v; return nil },
+	"critical":          func(o *ProcessCheckOptions, v string) error { o.Critical = v; return nil },
+	"min_count":         func(o *ProcessCheckOptions, v string) error { return parseTargetInt(v, &o.MinCount) },
+	"port":              func(o *ProcessCheckOptions, v string) error { return parseTargetInt(v, &o.Port) },
+	"ppid":              func(o *ProcessCheckOptions, v string) error { return parseTargetInt(v, &o.Ppid) },
+	"pgid":              func(o *ProcessCheckOptions, v string) error { return parseTargetInt(v, &o.Pgid) },
+	"container_pid":     func(o *ProcessCheckOptions, v string) error { return parseTargetInt(v, &o.ContainerPid) },
+	"concurrency":       func(o *ProcessCheckOptions, v string) error { return parseTargetInt(v, &o.Concurrency) },
+	"max_count":         func(o *ProcessCheckOptions, v string) error { return parseTargetInt(v, &o.MaxCount) },
+}
+
+// processTargetAliases are short forms accepted for target keys.
+var processTargetAliases = map[string]string{
+	"metric": "metric_name",
+	"warn":   "warning",
+	"crit":   "critical",
+}
+
+// parseTargetState sets the field to a state name such as "ok".
+func parseTargetState(value string, field *string) error {
+	if _, err := ParseState(value); err != nil {
+		return err
+	}
+
+	*field = value
+
+	return nil
+}
+
+func parseTargetInt(value string, field *int) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", value)
+	}
+
+	*field = n
+
+	return nil
+}
+
+func parseTargetDuration(value string, field *time.Duration) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration", value)
+	}
+
+	*field = d
+
+	return nil
+}
+
+func parseTargetBool(value string, field *bool) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%q is not true or false", value)
+	}
+
+	*field = b
+
+	return nil
+}
+
+// ParseProcessTarget parses a process target, a compact alternative
+// to the check_process flags in the form of
+// "name=java;type=logactive;log_path=/var/log/app.log;warn=60;crit=300".
+// Each key=value entry sets the option of the same name on top of the
+// given options, so options not in the target keep their values.
+func ParseProcessTarget(target string, options ProcessCheckOptions) (ProcessCheckOptions, error) {
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(target, processTargetSeparator) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		keyValue := strings.SplitN(entry, "=", 2)
+		if len(keyValue) != 2 || strings.TrimSpace(keyValue[0]) == "" {
+			return options, fmt.Errorf("Malformed target entry %q, expected key=value", entry)
+		}
+
+		key := strings.ToLower(strings.TrimSpace(keyValue[0]))
+		value := strings.TrimSpace(keyValue[1])
+
+		if alias, ok := processTargetAliases[key]; ok {
+			key = alias
+		}
+
+		setField, ok := processTargetFields[key]
+		if !ok {
+			return options, fmt.Errorf("Unknown target key %q", key)
+		}
+
+		if seen[key] {
+			return options, fmt.Errorf("Target key %q given more than once", key)
+		}
+		seen[key] = true
+
+		if err := setField(&options, value); err != nil {
+			return options, fmt.Errorf("Invalid value for target key %q: %s", key, err)
+		}
+	}
+
+	return options, nil
+}
+
+// RunProcessCheckWithTarget performs the process check described by the
+// target, applied on top of options, and returns its result as
+// RunProcessCheck() does.
+func RunProcessCheckWithTarget(target string, options ProcessCheckOptions) CheckResult {
+	options, err := ParseProcessTarget(target, options)
+	if err != nil {
+		return CriticalResult(checkProcessName, err.Error())
+	}
+
+	return RunProcessCheck(options)
+}
+
+// CheckProcessWithTarget performs the process check described by
+// the target, applied on top of options.
+func CheckProcessWithTarget(target string, options ProcessCheckOptions) (string, int) {
+	return RunProcessCheckWithTarget(target, options).Output()
+}
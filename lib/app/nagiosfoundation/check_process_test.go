@@ -3,7 +3,14 @@ package nagiosfoundation
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -42,59 +49,59 @@ func TestCheckProcess(t *testing.T) {
 
 	var retcode int
 	// Running check with running process
-	_, retcode = checkProcessWithService(testProcessGoodName, "running", "metric", new(testProcessHandler))
+	_, retcode = checkProcessWithService(ProcessCheckOptions{Name: testProcessGoodName, CheckType: "running", MetricName: "metric"}, new(testProcessHandler)).Output()
 	if retcode != statusCodeOK {
 		t.Errorf("Running check with running process failed with retcode %d", retcode)
 	}
 
 	// Not running check with running process
-	_, retcode = checkProcessWithService(testProcessGoodName, "notrunning", "metric", new(testProcessHandler))
+	_, retcode = checkProcessWithService(ProcessCheckOptions{Name: testProcessGoodName, CheckType: "notrunning", MetricName: "metric"}, new(testProcessHandler)).Output()
 	if retcode != statusCodeCritical {
 		t.Errorf("Not running check with running process failed with retcode %d", retcode)
 	}
 
 	// Running check with not running process
-	_, retcode = checkProcessWithService(testProcessBadName, "running", "metric", new(testProcessHandler))
+	_, retcode = checkProcessWithService(ProcessCheckOptions{Name: testProcessBadName, CheckType: "running", MetricName: "metric"}, new(testProcessHandler)).Output()
 	if retcode != statusCodeCritical {
 		t.Errorf("Running check with not running process failed with retcode %d", retcode)
 	}
 
 	// Not running check with not running process
-	_, retcode = checkProcessWithService(testProcessBadName, "notrunning", "metric", new(testProcessHandler))
+	_, retcode = checkProcessWithService(ProcessCheckOptions{Name: testProcessBadName, CheckType: "notrunning", MetricName: "metric"}, new(testProcessHandler)).Output()
 	if retcode != statusCodeOK {
 		t.Errorf("Not running check with not running process failed with retcode %d", retcode)
 	}
 
 	// Invalid check type
-	_, retcode = checkProcessWithService(testProcessGoodName, "", "metric", new(testProcessHandler))
+	_, retcode = checkProcessWithService(ProcessCheckOptions{Name: testProcessGoodName, CheckType: "", MetricName: "metric"}, new(testProcessHandler)).Output()
 	if retcode != statusCodeCritical {
 		t.Errorf("Invalid check type not detected with retcode %d", retcode)
 	}
 
 	testMsg := "Test Message"
-	testCheckProcess := func(name, checkType, metricName string, processService ProcessService) (string, int) {
-		return testMsg, statusCodeOK
+	testCheckProcess := func(options ProcessCheckOptions, processService ProcessService) CheckResult {
+		return OKResult(checkProcessName, testMsg)
 	}
 
-	_, retcode = checkProcessCmd("dummyprocess", "running", "metric", testCheckProcess, new(testProcessHandler))
+	_, retcode = checkProcessCmd(ProcessCheckOptions{Name: "dummyprocess", CheckType: "running", MetricName: "metric"}, testCheckProcess, new(testProcessHandler)).Output()
 
 	if retcode != statusCodeOK {
 		t.Error("valid check process test should have returned OK")
 	}
 
-	_, retcode = checkProcessCmd("", "dummytype", "metric", testCheckProcess, new(testProcessHandler))
+	_, retcode = checkProcessCmd(ProcessCheckOptions{Name: "", CheckType: "dummytype", MetricName: "metric"}, testCheckProcess, new(testProcessHandler)).Output()
 
 	if retcode != statusCodeCritical {
 		t.Error("check process with no -name should return CRITICAL")
 	}
 
-	_, retcode = checkProcessCmd("", "", "metric", testCheckProcess, new(testProcessHandler))
+	_, retcode = checkProcessCmd(ProcessCheckOptions{Name: "", CheckType: "", MetricName: "metric"}, testCheckProcess, new(testProcessHandler)).Output()
 
 	if retcode != statusCodeCritical {
 		t.Error("check process test with no parameters should have returned CRITICAL")
 	}
 
-	_, retcode = checkProcessCmd("dummyprocess", "badtype", "metric", testCheckProcess, new(testProcessHandler))
+	_, retcode = checkProcessCmd(ProcessCheckOptions{Name: "dummyprocess", CheckType: "badtype", MetricName: "metric"}, testCheckProcess, new(testProcessHandler)).Output()
 
 	if retcode != statusCodeCritical {
 		t.Error("check process test with invalid type should have returned CRITICAL")
@@ -169,7 +176,7 @@ func TestCheckProcessLinux(t *testing.T) {
 		return []byte("123 bash 1 1 1"), nil
 	}
 
-	procName, err := getPidNameWithHandler(goodOutput, 123)
+	procName, err := getPidNameWithHandler(goodOutput, defaultProcRoot, 123)
 
 	if err != nil {
 		t.Error("getPidNameWithHandler returned an error on valid data")
@@ -179,7 +186,7 @@ func TestCheckProcessLinux(t *testing.T) {
 		t.Error("getPidNameWithHandler did not return a valid name on valid data")
 	}
 
-	procName, err = getPidNameWithHandler(errorReturn, 123)
+	procName, err = getPidNameWithHandler(errorReturn, defaultProcRoot, 123)
 
 	if err == nil {
 		t.Error("getPidNameWithHandler did not return an error on a read data error")
@@ -189,7 +196,7 @@ func TestCheckProcessLinux(t *testing.T) {
 		t.Error("getPidNameWithHandler returned a name on a read data error")
 	}
 
-	procName, err = getPidNameWithHandler(badOutput, 123)
+	procName, err = getPidNameWithHandler(badOutput, defaultProcRoot, 123)
 
 	if err == nil {
 		t.Error("getPidNameWithHandler did not return an error when data parse should fail")
@@ -258,3 +265,2563 @@ func TestCheckProcessLinux(t *testing.T) {
 		t.Error("getProcessesByNameWithHandlers returned a file list but should have returned an error")
 	}
 }
+
+type testPidFileInfo struct {
+	testFileInfo
+	name string
+}
+
+func (fi testPidFileInfo) Name() string {
+	return fi.name
+}
+
+func (fi testPidFileInfo) IsDir() bool {
+	return true
+}
+
+// testProcHandlers returns handlers for a synthetic /proc where
+// files holds the contents of each file by path.
+func testProcHandlers(pids []string, files map[string]string) processByNameHandlers {
+	return processByNameHandlers{
+		open: func(string) (*os.File, error) {
+			return nil, nil
+		},
+		close: func(*os.File) error {
+			return nil
+		},
+		readDir: func(*os.File, int) ([]os.FileInfo, error) {
+			entries := make([]os.FileInfo, len(pids))
+			for i, pid := range pids {
+				entries[i] = testPidFileInfo{name: pid}
+			}
+
+			return entries, nil
+		},
+		getPidName: getPidNameWithHandler,
+		readFile: func(path string) ([]byte, error) {
+			if data, ok := files[path]; ok {
+				return []byte(data), nil
+			}
+
+			return nil, os.ErrNotExist
+		},
+	}
+}
+
+const testMapsClean = `55d0c0a00000-55d0c0a21000 r--p 00000000 08:01 1050 /usr/sbin/nginx
+55d0c0a21000-55d0c0b00000 r-xp 00021000 08:01 1050 /usr/sbin/nginx
+55d0c1c00000-55d0c1d00000 rw-p 00000000 00:00 0 [heap]
+7ffd5e1f0000-7ffd5e211000 rw-p 00000000 00:00 0 [stack]
+`
+
+const testMapsWx = `55d0c0a21000-55d0c0b00000 r-xp 00021000 08:01 1050 /usr/sbin/nginx
+7f1c2a000000-7f1c2a100000 rwxp 00000000 00:00 0
+7f1c2b000000-7f1c2b001000 rwxs 00000000 08:01 2040 /tmp/jit cache
+`
+
+func TestProcessesByPidNamespace(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat": "100 (nginx) S 1",
+		"/proc/200/stat": "200 (nginx) S 1",
+		"/proc/300/stat": "300 (nginx) S 1",
+
+		"/proc/200/cgroup": "0::/system.slice/docker-3f4e9a0c2b71.scope\n",
+		"/proc/300/cgroup": "0::/user.slice\n",
+	}
+
+	nsLinks := map[string]string{
+		"/proc/100/ns/pid":   "pid:[4026531836]",
+		"/proc/200/ns/pid":   "pid:[4026532201]",
+		"/run/netns/web/pid": "pid:[4026532201]",
+	}
+
+	svc := testProcHandlers([]string{"100", "200", "300"}, files)
+	svc.readLink = func(path string) (string, error) {
+		if target, ok := nsLinks[path]; ok {
+			return target, nil
+		}
+
+		return "", os.ErrPermission
+	}
+
+	type testItem struct {
+		description   string
+		pidNamespace  string
+		expectedCount int
+		expectError   bool
+	}
+
+	testList := []testItem{
+		{"No namespace matches all", "", 3, false},
+		{"Namespace by PID", "200", 1, false},
+		{"Namespace by link path", "/run/netns/web/pid", 1, false},
+		{"Host namespace by PID", "100", 1, false},
+		{"Namespace by container ID", "3f4e9a0c2b71", 1, false},
+		{"Unreadable namespace", "999", 0, true},
+		{"Unknown container ID", "deadbeef", 0, true},
+	}
+
+	for _, i := range testList {
+		svc.pidNamespace = i.pidNamespace
+		entries, err := getProcessesByNameWithHandlers(svc, "nginx")
+
+		if (err != nil) != i.expectError {
+			t.Errorf("%s: Expected error: %t, Actual error: %v", i.description, i.expectError, err)
+		}
+
+		if len(entries) != i.expectedCount {
+			t.Errorf("%s: Expected Count: %d, Actual Count: %d", i.description, i.expectedCount, len(entries))
+		}
+	}
+}
+
+func TestProcessesByProcfsRoot(t *testing.T) {
+	files := map[string]string{
+		"/host/proc/uptime":     "1000.50 3800.20\n",
+		"/host/proc/100/stat":   "100 (sshd) S 1 100 100 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 1 0 50000 10000 200 18446744073709551615",
+		"/host/proc/100/status": "Name:\tsshd\nVmRSS:\t  2048 kB\n",
+		"/proc/200/stat":        "200 (sshd) S 1",
+	}
+
+	svc := testProcHandlers([]string{"100", "200"}, files)
+
+	var openedDir string
+	svc.open = func(name string) (*os.File, error) {
+		openedDir = name
+		return nil, nil
+	}
+
+	entries, err := getProcessesByNameWithHandlers(svc, "sshd")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "200" || openedDir != "/proc" {
+		t.Errorf("getProcessesByNameWithHandlers() should default to /proc, opened %s, Error: %v", openedDir, err)
+	}
+
+	svc.procRoot = "/host/proc/"
+	entries, err = getProcessesByNameWithHandlers(svc, "sshd")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "100" || openedDir != "/host/proc" {
+		t.Errorf("getProcessesByNameWithHandlers() should read the procfs root, opened %s, Error: %v", openedDir, err)
+	}
+
+	if rss, count, err := processMemory(procfsInspector{svc: svc, now: time.Now}, "sshd"); err != nil || count != 1 || rss != 2048*1024 {
+		t.Errorf("processMemory() should read status under the procfs root, got %d bytes, Error: %v", rss, err)
+	}
+}
+
+func TestProcessesByCombinedFilters(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat":    "100 (java) S 1 100 100",
+		"/proc/100/cmdline": "java\x00com.acme.OrderWorker\x00",
+		"/proc/101/stat":    "101 (java) S 100 100 100",
+		"/proc/101/cmdline": "java\x00com.acme.OrderWorker\x00",
+		"/proc/200/stat":    "200 (java) S 1 200 200",
+		"/proc/200/cmdline": "java\x00com.acme.BillingWorker\x00",
+		"/proc/300/stat":    "300 (python) S 100 100 100",
+		"/proc/300/cmdline": "python\x00com.acme.OrderWorker\x00",
+	}
+
+	owners := map[int]string{100: "1001", 101: "1002", 200: "1001", 300: "1001"}
+
+	svc := testProcHandlers([]string{"100", "101", "200", "300"}, files)
+	svc.getPidUID = func(stat func(string) (os.FileInfo, error), procRoot string, pid int) (string, error) {
+		return owners[pid], nil
+	}
+	svc.lookupUID = func(name string) (string, error) {
+		return map[string]string{"appuser": "1001", "batchuser": "1002"}[name], nil
+	}
+
+	type testItem struct {
+		description  string
+		user         string
+		matchCmdline string
+		ppid         int
+		expectedPids []string
+	}
+
+	testList := []testItem{
+		{"Name and user", "appuser", "", 0, []string{"100", "200"}},
+		{"Name, user and command line", "appuser", "OrderWorker", 0, []string{"100"}},
+		{"Name and parent", "", "", 100, []string{"101"}},
+		{"Name, user and parent", "appuser", "", 100, []string{}},
+		{"Name, command line and parent", "", "OrderWorker", 100, []string{"101"}},
+		{"Every criteria", "batchuser", "OrderWorker", 100, []string{"101"}},
+	}
+
+	for _, i := range testList {
+		svc.user, svc.matchCmdline, svc.ppid = i.user, i.matchCmdline, i.ppid
+		entries, err := getProcessesByNameWithHandlers(svc, "java")
+
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %s", i.description, err)
+		}
+
+		pids := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			pids = append(pids, entry.Name())
+		}
+
+		if strings.Join(pids, ",") != strings.Join(i.expectedPids, ",") {
+			t.Errorf("%s: Expected Processes: %v, Actual Processes: %v", i.description, i.expectedPids, pids)
+		}
+	}
+
+	svc.user, svc.matchCmdline, svc.ppid = "", "", 0
+	filters, err := newProcessFilters(svc, nil)
+	if err != nil || len(filters) != 0 {
+		t.Errorf("newProcessFilters() should return no filters when only the name is given, got %d, Error: %v", len(filters), err)
+	}
+
+	if !matchesProcessFilters(filters, 100) {
+		t.Error("matchesProcessFilters() should match any process with no filters")
+	}
+
+	failed := func(int) bool { return false }
+	passed := func(int) bool { return true }
+	if matchesProcessFilters([]processFilter{passed, failed, passed}, 100) {
+		t.Error("matchesProcessFilters() should not match a process failing one of the filters")
+	}
+}
+
+func TestContainerProcRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerproc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	containerProc := filepath.Join(dir, "4127", "root", "proc")
+	if err := os.MkdirAll(containerProc, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(containerProc, "uptime"), []byte("350735.47 234388.90\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if root, err := containerProcRoot(dir+"/", 4127, os.Stat); err != nil || root != dir+"/4127/root/proc" {
+		t.Errorf("containerProcRoot() should return the proc filesystem of the container, got %s, Error: %v", root, err)
+	}
+
+	if _, err := containerProcRoot(dir, 4128, os.Stat); err == nil || !strings.Contains(err.Error(), "Could not access the processes of container process 4128") {
+		t.Errorf("containerProcRoot() should return an error for a container that cannot be read: %v", err)
+	}
+
+	if root, _ := containerProcRoot("", 1, func(string) (os.FileInfo, error) { return nil, nil }); root != "/proc/1/root/proc" {
+		t.Errorf("containerProcRoot() should default to /proc, got %s", root)
+	}
+
+	msg, code := CheckProcessWithOptions(ProcessCheckOptions{Name: "java", CheckType: "running", ProcfsRoot: dir, ContainerPid: 4128})
+	if code != statusCodeUnknown || !strings.Contains(msg, "Could not access the processes of container process 4128") {
+		t.Errorf("A container that cannot be read should return UNKNOWN. Code: %d, Message: %s", code, msg)
+	}
+
+	msg, code = checkProcessCmd(ProcessCheckOptions{Name: "java", CheckType: "running", ContainerPid: -1}, checkProcessWithService, new(testProcessHandler)).Output()
+	if code != statusCodeCritical || !strings.Contains(msg, "container PID may not be negative") {
+		t.Errorf("A negative container PID should have been rejected. Code: %d, Message: %s", code, msg)
+	}
+}
+
+func TestProcessesByCmdline(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat":    "100 (java) S 1",
+		"/proc/100/cmdline": "java\x00-Xmx512m\x00-cp\x00/opt/app.jar\x00com.acme.OrderWorker\x00",
+		"/proc/200/stat":    "200 (java) S 1",
+		"/proc/200/cmdline": "java\x00-cp\x00/opt/app.jar\x00com.acme.BillingWorker\x00",
+		"/proc/300/stat":    "300 (java) S 2",
+	}
+
+	svc := testProcHandlers([]string{"100", "200", "300"}, files)
+
+	if cmdline, _ := getPidCmdlineWithHandler(svc.readFile, defaultProcRoot, 100); cmdline != "java -Xmx512m -cp /opt/app.jar com.acme.OrderWorker" {
+		t.Errorf("getPidCmdlineWithHandler() did not join the NUL separated arguments: %q", cmdline)
+	}
+
+	type testItem struct {
+		description   string
+		matchCmdline  string
+		expectedCount int
+	}
+
+	testList := []testItem{
+		{"No filter matches by name", "", 3},
+		{"Filter on one worker", "OrderWorker", 1},
+		{"Filter across arguments", "-cp /opt/app.jar", 2},
+		{"Filter matching none", "ShippingWorker", 0},
+	}
+
+	for _, i := range testList {
+		svc.matchCmdline = i.matchCmdline
+		entries, err := getProcessesByNameWithHandlers(svc, "java")
+
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %s", i.description, err)
+		}
+
+		if len(entries) != i.expectedCount {
+			t.Errorf("%s: Expected Count: %d, Actual Count: %d", i.description, i.expectedCount, len(entries))
+		}
+	}
+}
+
+func TestProcessesByExePath(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat": "100 (server) S 1",
+		"/proc/200/stat": "200 (server) S 1",
+		"/proc/300/stat": "300 (server) S 1",
+		"/proc/400/stat": "400 (server) S 1",
+	}
+
+	exeLinks := map[string]string{
+		"/proc/100/exe": "/opt/app-blue/bin/server",
+		"/proc/200/exe": "/opt/app-green/bin/server",
+		"/proc/300/exe": "/opt/app-blue/bin/server (deleted)",
+	}
+
+	svc := testProcHandlers([]string{"100", "200", "300", "400"}, files)
+	svc.readLink = func(path string) (string, error) {
+		if target, ok := exeLinks[path]; ok {
+			return target, nil
+		}
+
+		return "", os.ErrPermission
+	}
+
+	type testItem struct {
+		description   string
+		exePath       string
+		regex         bool
+		expectedCount int
+	}
+
+	testList := []testItem{
+		{"No filter matches by name", "", false, 4},
+		{"Exact path", "/opt/app-green/bin/server", false, 1},
+		{"Deleted executable", "/opt/app-blue/bin/server", false, 2},
+		{"Path prefix", "/opt/app-blue/", false, 2},
+		{"Prefix without a slash is exact", "/opt/app-blue", false, 0},
+		{"Path pattern", `^/opt/app-[a-z]+/bin/server$`, true, 3},
+	}
+
+	for _, i := range testList {
+		svc.exePath = i.exePath
+		svc.regex = i.regex
+		entries, err := getProcessesByNameWithHandlers(svc, "server")
+
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %s", i.description, err)
+		}
+
+		if len(entries) != i.expectedCount {
+			t.Errorf("%s: Expected Count: %d, Actual Count: %d", i.description, i.expectedCount, len(entries))
+		}
+	}
+}
+
+func TestProcessesByRegex(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat":    "100 (myapp-1.2.3) S 1",
+		"/proc/100/cmdline": "/opt/myapp-1.2.3/bin/myapp-1.2.3\x00--port\x008080\x00",
+		"/proc/200/stat":    "200 (myapp-1.3.0) S 1",
+		"/proc/200/cmdline": "/opt/myapp-1.3.0/bin/myapp-1.3.0\x00--port\x009090\x00",
+		"/proc/300/stat":    "300 (myapp) S 1",
+	}
+
+	svc := testProcHandlers([]string{"100", "200", "300"}, files)
+
+	type testItem struct {
+		description   string
+		name          string
+		regex         bool
+		matchCmdline  string
+		expectedCount int
+		expectError   bool
+	}
+
+	testList := []testItem{
+		{"Exact match by default", "myapp", false, "", 1, false},
+		{"Pattern not used without regex", "myapp-.*", false, "", 0, false},
+		{"Versioned names", `^myapp-\d+\.\d+\.\d+$`, true, "", 2, false},
+		{"Unanchored pattern", "myapp", true, "", 3, false},
+		{"Command line pattern", "^myapp-", true, `--port 80\d\d`, 1, false},
+		{"Invalid name pattern", "myapp-(", true, "", 0, true},
+		{"Invalid command line pattern", "myapp", true, "[", 0, true},
+	}
+
+	for _, i := range testList {
+		svc.regex = i.regex
+		svc.matchCmdline = i.matchCmdline
+		entries, err := getProcessesByNameWithHandlers(svc, i.name)
+
+		if (err != nil) != i.expectError {
+			t.Errorf("%s: Expected error: %t, Actual error: %v", i.description, i.expectError, err)
+		}
+
+		if len(entries) != i.expectedCount {
+			t.Errorf("%s: Expected Count: %d, Actual Count: %d", i.description, i.expectedCount, len(entries))
+		}
+	}
+
+	msg, code := checkProcessCmd(ProcessCheckOptions{Name: "myapp-(", CheckType: "running", Regex: true}, checkProcessWithService, new(testProcessHandler)).Output()
+	if code != statusCodeUnknown || !strings.Contains(msg, "Invalid regular expression") {
+		t.Errorf("Invalid pattern should have returned UNKNOWN. Code: %d, Message: %s", code, msg)
+	}
+}
+
+func TestParseMemoryMaps(t *testing.T) {
+	mappings, err := parseMemoryMaps(123, testMapsWx)
+	if err != nil {
+		t.Fatalf("parseMemoryMaps() returned an error on valid data: %s", err)
+	}
+
+	if len(mappings) != 3 {
+		t.Fatalf("parseMemoryMaps() should have returned 3 mappings, returned %d", len(mappings))
+	}
+
+	if mappings[0].isWritableExecutable() {
+		t.Error("Read and execute mapping should not be writable and executable")
+	}
+
+	if !mappings[1].isWritableExecutable() || mappings[1].path != "" {
+		t.Errorf("Anonymous writable and executable mapping parsed incorrectly: %+v", mappings[1])
+	}
+
+	if mappings[2].path != "/tmp/jit cache" {
+		t.Errorf("Mapping path containing spaces parsed incorrectly: %s", mappings[2].path)
+	}
+
+	if _, err = parseMemoryMaps(123, "7f1c2a000000-7f1c2a100000 rwxp\n"); err == nil {
+		t.Error("parseMemoryMaps() should return an error on a short entry")
+	}
+}
+
+func TestWritableExecutableMappings(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat": "100 (nginx) S 1",
+		"/proc/100/maps": testMapsClean,
+		"/proc/200/stat": "200 (nginx) S 100",
+		"/proc/200/maps": testMapsWx,
+		"/proc/300/stat": "300 (bash) S 1",
+		"/proc/400/stat": "400 (nginx) S 100",
+	}
+
+	// Process 300 and 400 have exited and have no maps.
+	svc := testProcHandlers([]string{"100", "200", "300", "400"}, files)
+
+	mappings, err := getWritableExecutableMappingsWithHandlers(svc, "nginx")
+	if err != nil {
+		t.Fatalf("getWritableExecutableMappingsWithHandlers() returned an error on valid data: %s", err)
+	}
+
+	if len(mappings) != 2 || mappings[0].pid != 200 {
+		t.Errorf("getWritableExecutableMappingsWithHandlers() should have found 2 mappings in pid 200: %v", mappings)
+	}
+
+	if _, err = getWritableExecutableMappingsWithHandlers(svc, "missing"); err != errProcessNotRunning {
+		t.Error("getWritableExecutableMappingsWithHandlers() should return errProcessNotRunning when no process matches")
+	}
+
+	if _, err = getWritableExecutableMappingsWithHandlers(svc, "bash"); err != errProcessNotRunning {
+		t.Errorf("getWritableExecutableMappingsWithHandlers() should return errProcessNotRunning when every process exited, returned %v", err)
+	}
+
+	readFile := svc.readFile
+	svc.readFile = func(path string) ([]byte, error) {
+		if path == "/proc/300/maps" {
+			return nil, os.ErrPermission
+		}
+
+		return readFile(path)
+	}
+
+	if _, err = getWritableExecutableMappingsWithHandlers(svc, "bash"); err != os.ErrPermission {
+		t.Errorf("getWritableExecutableMappingsWithHandlers() should return the error when maps can't be read, returned %v", err)
+	}
+}
+
+type testMappingProcessHandler struct {
+	testProcessHandler
+	mappings []memoryMapping
+	err      error
+}
+
+func (p testMappingProcessHandler) WritableExecutableMappings(name string) ([]memoryMapping, error) {
+	return p.mappings, p.err
+}
+
+func TestCheckWritableExecutable(t *testing.T) {
+	wxMapping := memoryMapping{pid: 200, address: "7f1c2a000000-7f1c2a100000", permissions: "rwxp"}
+
+	type testItem struct {
+		description  string
+		service      ProcessService
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"No mappings", testMappingProcessHandler{}, statusCodeOK, "wx=0"},
+		{"Offending mapping", testMappingProcessHandler{mappings: []memoryMapping{wxMapping}}, statusCodeWarning, "200:7f1c2a000000-7f1c2a100000 rwxp [anonymous]"},
+		{"Process not running", testMappingProcessHandler{err: errProcessNotRunning}, statusCodeCritical, "not running"},
+		{"Read error", testMappingProcessHandler{err: errors.New("permission denied")}, statusCodeUnknown, "permission denied"},
+		{"Service without mappings", new(testProcessHandler), statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		msg, code := checkProcessWithService(ProcessCheckOptions{Name: testProcessGoodName, CheckType: "wxmappings", MetricName: "wx"}, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+func TestGetLogAge(t *testing.T) {
+	const logPath = "/var/log/app.log"
+
+	files := map[string]string{
+		"/proc/100/stat": "100 (app) S 1",
+		"/proc/200/stat": "200 (app) S 1",
+		"/proc/300/stat": "300 (cron) S 1",
+	}
+
+	fdDirs := map[string][]string{
+		"/proc/100/fd": {"0", "1", "2"},
+		"/proc/200/fd": {"0", "1", "2", "3", "4"},
+	}
+
+	fdLinks := map[string]string{
+		"/proc/100/fd/0": "/dev/null",
+		"/proc/200/fd/3": "socket:[123]",
+		"/proc/200/fd/4": logPath,
+	}
+
+	logModTime := time.Now().Add(-100 * time.Second)
+
+	svc := testProcHandlers([]string{"100", "200", "300"}, files)
+	svc.listDir = func(path string) ([]string, error) {
+		if fds, ok := fdDirs[path]; ok {
+			return fds, nil
+		} else if path == "/proc/300/fd" {
+			return nil, os.ErrNotExist
+		}
+
+		return nil, os.ErrPermission
+	}
+	svc.readLink = func(path string) (string, error) {
+		if target, ok := fdLinks[path]; ok {
+			return target, nil
+		}
+
+		return "", os.ErrNotExist
+	}
+	svc.stat = func(path string) (os.FileInfo, error) {
+		if path != logPath {
+			return nil, os.ErrNotExist
+		}
+
+		return testModTimeFileInfo{modTime: logModTime}, nil
+	}
+
+	age, err := getLogAgeWithHandlers(svc, "app", logPath)
+	if err != nil {
+		t.Fatalf("getLogAgeWithHandlers() returned an error on valid data: %s", err)
+	}
+
+	if age < 100*time.Second || age > 110*time.Second {
+		t.Errorf("getLogAgeWithHandlers() returned age %s, expected about 100s", age)
+	}
+
+	if _, err = getLogAgeWithHandlers(svc, "app", "/var/log/other.log"); err != errLogNotOpen {
+		t.Errorf("getLogAgeWithHandlers() should return errLogNotOpen for a log not open, returned %v", err)
+	}
+
+	if _, err = getLogAgeWithHandlers(svc, "missing", logPath); err != errProcessNotRunning {
+		t.Errorf("getLogAgeWithHandlers() should return errProcessNotRunning when no process matches, returned %v", err)
+	}
+
+	// Process 300 exited before its descriptors were listed.
+	if _, err = getLogAgeWithHandlers(svc, "cron", logPath); err != errProcessNotRunning {
+		t.Errorf("getLogAgeWithHandlers() should return errProcessNotRunning when every process exited, returned %v", err)
+	}
+
+	delete(fdDirs, "/proc/100/fd")
+	if _, err = getLogAgeWithHandlers(svc, "app", logPath); err != os.ErrPermission {
+		t.Errorf("getLogAgeWithHandlers() should return the error listing descriptors, returned %v", err)
+	}
+}
+
+type testModTimeFileInfo struct {
+	testFileInfo
+	modTime time.Time
+}
+
+func (fi testModTimeFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+type testLogProcessHandler struct {
+	testProcessHandler
+	age time.Duration
+	err error
+}
+
+func (p testLogProcessHandler) LogAge(name, logPath string) (time.Duration, error) {
+	return p.age, p.err
+}
+
+func TestCheckLogActive(t *testing.T) {
+	type testItem struct {
+		description  string
+		service      ProcessService
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Log recently written", testLogProcessHandler{age: 10 * time.Second}, statusCodeOK, "log_age=10s;60;300"},
+		{"Log older than warning", testLogProcessHandler{age: 120 * time.Second}, statusCodeWarning, "is stale, last written 120s ago"},
+		{"Log older than critical", testLogProcessHandler{age: 600 * time.Second}, statusCodeCritical, "is stale"},
+		{"Log not open", testLogProcessHandler{err: errLogNotOpen}, statusCodeCritical, "is not open by process"},
+		{"Process not running", testLogProcessHandler{err: errProcessNotRunning}, statusCodeCritical, "is not running"},
+		{"Read error", testLogProcessHandler{err: errors.New("permission denied")}, statusCodeUnknown, "permission denied"},
+		{"Service without logs", new(testProcessHandler), statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		options := ProcessCheckOptions{
+			Name:       testProcessGoodName,
+			CheckType:  "logactive",
+			MetricName: "log_age",
+			LogPath:    "/var/log/app.log",
+			Warning:    "60",
+			Critical:   "300",
+		}
+
+		msg, code := checkProcessWithService(options, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	_, code := checkProcessCmd(ProcessCheckOptions{Name: testProcessGoodName, CheckType: "logactive"}, checkProcessWithService, new(testProcessHandler)).Output()
+	if code != statusCodeCritical {
+		t.Error("logactive check without a log path should have returned CRITICAL")
+	}
+}
+
+func TestParseCgroup(t *testing.T) {
+	type testItem struct {
+		description string
+		data        string
+		expected    string
+		expectErr   bool
+	}
+
+	testList := []testItem{
+		{"Unified hierarchy", "0::/system.slice/docker-abc123.scope\n", "/system.slice/docker-abc123.scope", false},
+		{"Systemd hierarchy preferred", "12:cpu,cpuacct:/docker/abc\n1:name=systemd:/docker/def\n", "/docker/def", false},
+		{"First hierarchy", "12:cpu,cpuacct:/docker/abc\n11:memory:/docker/xyz\n", "/docker/abc", false},
+		{"Unified and legacy hierarchy", "1:name=systemd:/user.slice\n0::/init.scope\n", "/init.scope", false},
+		{"Malformed entry", "not a cgroup\n", "", true},
+		{"Empty", "", "", true},
+	}
+
+	for _, i := range testList {
+		actual, err := parseCgroup(i.data)
+
+		if (err != nil) != i.expectErr {
+			t.Errorf("%s: Expected error: %t, Actual error: %v", i.description, i.expectErr, err)
+		}
+
+		if actual != i.expected {
+			t.Errorf("%s: Expected: %s, Actual: %s", i.description, i.expected, actual)
+		}
+	}
+}
+
+func TestCgroupCounts(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat":   "100 (worker) S 1",
+		"/proc/100/cgroup": "0::/docker/aaa\n",
+		"/proc/200/stat":   "200 (worker) S 1",
+		"/proc/200/cgroup": "0::/docker/aaa\n",
+		"/proc/300/stat":   "300 (worker) S 1",
+		"/proc/300/cgroup": "0::/docker/bbb\n",
+		"/proc/400/stat":   "400 (bash) S 1",
+		"/proc/500/stat":   "500 (worker) S 1",
+	}
+
+	// Process 500 has exited and has no cgroup.
+	svc := testProcHandlers([]string{"100", "200", "300", "400", "500"}, files)
+
+	counts, err := getCgroupCountsWithHandlers(svc, "worker")
+	if err != nil {
+		t.Fatalf("getCgroupCountsWithHandlers() returned an error on valid data: %s", err)
+	}
+
+	if len(counts) != 2 || counts["/docker/aaa"] != 2 || counts["/docker/bbb"] != 1 {
+		t.Errorf("getCgroupCountsWithHandlers() returned incorrect counts: %v", counts)
+	}
+
+	if counts, err = getCgroupCountsWithHandlers(svc, "bash"); err != nil || len(counts) != 0 {
+		t.Errorf("getCgroupCountsWithHandlers() should count no process when every process exited: %v, Error: %v", counts, err)
+	}
+
+	readFile := svc.readFile
+	svc.readFile = func(path string) ([]byte, error) {
+		if path == "/proc/400/cgroup" {
+			return nil, os.ErrPermission
+		}
+
+		return readFile(path)
+	}
+
+	if _, err = getCgroupCountsWithHandlers(svc, "bash"); err != os.ErrPermission {
+		t.Errorf("getCgroupCountsWithHandlers() should return the error when the cgroup can't be read, returned %v", err)
+	}
+}
+
+func TestCgroupLabel(t *testing.T) {
+	testList := map[string]string{
+		"/":                                "root",
+		"/docker/abc123":                   "docker_abc123",
+		"/system.slice/docker-abc.scope":   "system.slice_docker-abc.scope",
+		"/kubepods/pod a=b;c/container 'x": "kubepods_pod_a_b_c_container_x",
+	}
+
+	for cgroup, expected := range testList {
+		if actual := cgroupLabel(cgroup); actual != expected {
+			t.Errorf("cgroupLabel(%s) Expected: %s, Actual: %s", cgroup, expected, actual)
+		}
+	}
+}
+
+type testCgroupProcessHandler struct {
+	testProcessHandler
+	counts map[string]int
+	err    error
+}
+
+func (p testCgroupProcessHandler) CgroupCounts(name string) (map[string]int, error) {
+	return p.counts, p.err
+}
+
+func TestCheckCgroupCount(t *testing.T) {
+	counts := map[string]int{"/docker/aaa": 2, "/docker/bbb": 1}
+
+	type testItem struct {
+		description  string
+		service      ProcessService
+		minCount     int
+		maxCount     int
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"All in range", testCgroupProcessHandler{counts: counts}, 1, 0, statusCodeOK, "procs_docker_aaa=2;;;0 procs_docker_bbb=1;;;0"},
+		{"Too few", testCgroupProcessHandler{counts: counts}, 2, 0, statusCodeCritical, "1 of 2 cgroups: /docker/bbb (1)"},
+		{"Too many", testCgroupProcessHandler{counts: counts}, 1, 1, statusCodeCritical, "/docker/aaa (2)"},
+		{"No processes", testCgroupProcessHandler{counts: map[string]int{}}, 1, 0, statusCodeCritical, "not running"},
+		{"Read error", testCgroupProcessHandler{err: errors.New("permission denied")}, 1, 0, statusCodeUnknown, "permission denied"},
+		{"Service without cgroups", new(testProcessHandler), 1, 0, statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		options := ProcessCheckOptions{
+			Name:       testProcessGoodName,
+			CheckType:  "cgroupcount",
+			MetricName: "procs",
+			MinCount:   i.minCount,
+			MaxCount:   i.maxCount,
+		}
+
+		msg, code := checkProcessWithService(options, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+func TestParseProcessTarget(t *testing.T) {
+	defaults := ProcessCheckOptions{
+		Names:      []string{"sshd", "cron"},
+		CheckType:  "running",
+		MetricName: "process_state",
+		Warning:    "300",
+		Critical:   "900",
+		MinCount:   1,
+	}
+
+	options, err := ParseProcessTarget("name=java; type=logactive;log_path=/var/log/app.log;warn=60;crit=120;", defaults)
+	if err != nil {
+		t.Fatalf("ParseProcessTarget() returned an error on a valid target: %s", err)
+	}
+
+	expected := ProcessCheckOptions{
+		Name:       "java",
+		CheckType:  "logactive",
+		MetricName: "process_state",
+		LogPath:    "/var/log/app.log",
+		Warning:    "60",
+		Critical:   "120",
+		MinCount:   1,
+	}
+
+	if !reflect.DeepEqual(options, expected) {
+		t.Errorf("ParseProcessTarget() Expected: %+v, Actual: %+v", expected, options)
+	}
+
+	type testItem struct {
+		description string
+		target      string
+		expectedErr string
+	}
+
+	testList := []testItem{
+		{"Missing value", "name=java;type", "Malformed target entry \"type\""},
+		{"Missing key", "name=java;=running", "Malformed target entry"},
+		{"Unknown key", "name=java;owner=app", "Unknown target key \"owner\""},
+		{"Duplicate key", "name=java;warn=1;warning=2", "\"warning\" given more than once"},
+		{"Non integer count", "name=java;min_count=high", "Invalid value for target key \"min_count\""},
+		{"Invalid missing state", "name=java;negate_on_missing=absent", "Invalid value for target key \"negate_on_missing\""},
+	}
+
+	for _, i := range testList {
+		_, err := ParseProcessTarget(i.target, defaults)
+
+		if err == nil || !strings.Contains(err.Error(), i.expectedErr) {
+			t.Errorf("%s: Expected Error: %s, Actual Error: %v", i.description, i.expectedErr, err)
+		}
+	}
+
+	msg, code := CheckProcessWithTarget("name=java;max_count=many", defaults)
+	if code != statusCodeCritical || !strings.Contains(msg, "max_count") {
+		t.Errorf("CheckProcessWithTarget() with an invalid target. Code: %d, Message: %s", code, msg)
+	}
+}
+
+type testCountProcessHandler struct {
+	testProcessHandler
+	count int
+	err   error
+}
+
+func (p testCountProcessHandler) ProcessCount(name string) (int, error) {
+	return p.count, p.err
+}
+
+func TestCheckCount(t *testing.T) {
+	type testItem struct {
+		description  string
+		service      ProcessService
+		warning      string
+		critical     string
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Count in range", testCountProcessHandler{count: 7}, "5:10", "2:20", statusCodeOK, "7 instances of goodName running | procs=7;5:10;2:20;0"},
+		{"Too few for warning", testCountProcessHandler{count: 3}, "5:10", "2:20", statusCodeWarning, "3 instances of goodName running (expected 5-10)"},
+		{"Too many for critical", testCountProcessHandler{count: 25}, "5:10", "2:20", statusCodeCritical, "(expected 2-20)"},
+		{"Minimum only", testCountProcessHandler{count: 0}, "", "1:", statusCodeCritical, "(expected at least 1)"},
+		{"Inside inverted range", testCountProcessHandler{count: 2}, "@1:3", "", statusCodeWarning, "(expected outside 1-3)"},
+		{"Maximum only", testCountProcessHandler{count: 4}, "3", "", statusCodeWarning, "(expected 0-3)"},
+		{"Perfdata thresholds", testCountProcessHandler{count: 3}, "5", "10", statusCodeOK, "3 instances of goodName running | procs=3;5;10"},
+		{"No thresholds", testCountProcessHandler{count: 0}, "", "", statusCodeOK, "procs=0;;;0"},
+		{"Invalid range", testCountProcessHandler{count: 1}, "many", "", statusCodeUnknown, "Invalid range"},
+		{"Count error", testCountProcessHandler{err: errors.New("permission denied")}, "", "", statusCodeUnknown, "permission denied"},
+		{"Service without counts", new(testProcessHandler), "", "", statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		options := ProcessCheckOptions{
+			Name:       testProcessGoodName,
+			CheckType:  "count",
+			MetricName: "procs",
+			Warning:    i.warning,
+			Critical:   i.critical,
+		}
+
+		msg, code := checkProcessWithService(options, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+func TestCheckCountDelta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "countdelta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := stateStore{dir: dir, now: func() time.Time { return start }}
+
+	options := ProcessCheckOptions{
+		Name:       testProcessGoodName,
+		CheckType:  "count",
+		MetricName: "procs",
+		Warning:    "2",
+		Critical:   "5",
+		Delta:      true,
+		StateDir:   dir,
+	}
+
+	thresholds, _ := ParseThresholds(options.Warning, options.Critical)
+	pc := ProcessCheck{ProcessName: testProcessGoodName}
+
+	type testItem struct {
+		description  string
+		count        int
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"First run", 10, statusCodeOK, "CheckProcess OK - 10 instances of goodName running, no previous count to compare | procs=10;;;0"},
+		{"Small change", 11, statusCodeOK, "11 instances of goodName running, changed by +1 in 60 seconds | procs=11;;;0 procs_delta=1;2;5"},
+		{"Spike", 15, statusCodeWarning, "changed by +4 in 60 seconds (expected a change of 0-2)"},
+		{"Drop", 8, statusCodeCritical, "8 instances of goodName running, changed by -7 in 60 seconds (expected a change of 0-5) | procs=8;;;0 procs_delta=-7;2;5"},
+	}
+
+	for _, i := range testList {
+		msg, code := checkCountDelta(pc, options, i.count, thresholds, store).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+
+		start = start.Add(time.Minute)
+	}
+
+	if msg, code := checkProcessWithService(options, testCountProcessHandler{count: 2}).Output(); code != statusCodeCritical || !strings.Contains(msg, "changed by -6") {
+		t.Errorf("checkProcessWithService() should compare the count against the saved count. Code: %d, Message: %s", code, msg)
+	}
+
+	options.StateDir = ""
+	if msg, code := checkProcessCmd(options, checkProcessWithService, testCountProcessHandler{count: 3}).Output(); code != statusCodeCritical || !strings.Contains(msg, "A state directory must be specified") {
+		t.Errorf("checkProcessCmd() should require a state directory for the delta mode. Code: %d, Message: %s", code, msg)
+	}
+
+	options.StateDir, options.CheckType = dir, "running"
+	if msg, code := checkProcessCmd(options, checkProcessWithService, testCountProcessHandler{count: 3}).Output(); code != statusCodeCritical || !strings.Contains(msg, "only supported by the \"count\" type") {
+		t.Errorf("checkProcessCmd() should only accept the delta mode for the count type. Code: %d, Message: %s", code, msg)
+	}
+}
+
+func TestCheckRestarted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restarted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := stateStore{dir: dir, now: func() time.Time { return start }}
+	options := ProcessCheckOptions{Name: "nginx", CheckType: "restarted", MetricName: "restarts", StateDir: dir}
+
+	started := start.Add(-time.Hour)
+	master := ProcessInfo{PID: 100, Name: "nginx", StartTime: started}
+	worker := ProcessInfo{PID: 101, Name: "nginx", StartTime: started}
+
+	type testItem struct {
+		description  string
+		processes    []ProcessInfo
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"First run", []ProcessInfo{worker, master}, statusCodeOK,
+			"CheckProcess OK - 2 instances of nginx running, no previous start times to compare | restarts=0;;;0"},
+		{"Unchanged", []ProcessInfo{master, worker}, statusCodeOK, "2 instances of nginx running, none restarted in 60 seconds | restarts=0;;;0"},
+		{"Start time moved by the clock", []ProcessInfo{master, {PID: 101, Name: "nginx", StartTime: started.Add(time.Second)}}, statusCodeOK, "none restarted"},
+		{"Worker restarted", []ProcessInfo{master, {PID: 102, Name: "nginx", StartTime: start}}, statusCodeWarning,
+			"CheckProcess WARNING - Process nginx restarted in 60 seconds, 1 started (pids: 102), 1 exited (pids: 101) | restarts=1;;;0"},
+		{"PID reused", []ProcessInfo{{PID: 100, Name: "nginx", StartTime: start}, {PID: 102, Name: "nginx", StartTime: start}}, statusCodeWarning,
+			"1 started (pids: 100), 1 exited (pids: 100)"},
+		{"Not running", nil, statusCodeCritical, "Process nginx is not running"},
+		{"Started again", []ProcessInfo{master, worker}, statusCodeWarning, "Process nginx restarted in 120 seconds, 2 started (pids: 100, 101), 2 exited (pids: 100, 102)"},
+		{"Worker exited", []ProcessInfo{master}, statusCodeWarning, "restarted in 60 seconds, 1 exited (pids: 101) | restarts=0;;;0"},
+	}
+
+	for _, i := range testList {
+		pc := ProcessCheck{ProcessName: "nginx", ProcessCheckHandler: processHandler{inspector: testProcessInspector{processes: i.processes}}}
+		msg, code := checkRestarted(pc, options, store).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+
+		start = start.Add(time.Minute)
+	}
+
+	pc := ProcessCheck{ProcessName: "nginx", ProcessCheckHandler: processHandler{inspector: testProcessInspector{err: errors.New("permission denied")}}}
+	if msg, code := checkRestarted(pc, options, store).Output(); code != statusCodeUnknown || !strings.Contains(msg, "Could not determine the start times of process nginx: permission denied") {
+		t.Errorf("checkRestarted() should return UNKNOWN when the processes cannot be read. Code: %d, Message: %s", code, msg)
+	}
+
+	options.StateDir = ""
+	if msg, code := checkProcessCmd(options, checkProcessWithService, testProcessHandler{}).Output(); code != statusCodeCritical || !strings.Contains(msg, "A state directory must be specified for the restarted check") {
+		t.Errorf("checkProcessCmd() should require a state directory for the restarted check. Code: %d, Message: %s", code, msg)
+	}
+}
+
+type testMemoryProcessHandler struct {
+	testProcessHandler
+	rss   uint64
+	count int
+	err   error
+}
+
+func (p testMemoryProcessHandler) ProcessMemory(name string) (uint64, int, error) {
+	return p.rss, p.count, p.err
+}
+
+func TestProcessMemory(t *testing.T) {
+	files := map[string]string{
+		"/proc/uptime":     "1000.50 3800.20\n",
+		"/proc/100/stat":   "100 (daemon) S 1 100 100 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 1 0 50000 10000 200 18446744073709551615",
+		"/proc/100/status": "Name:\tdaemon\nVmRSS:\t  307200 kB\nThreads:\t4\n",
+		"/proc/200/stat":   "200 (daemon) S 100 100 100 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 1 0 50000 10000 200 18446744073709551615",
+		"/proc/200/status": "Name:\tdaemon\nVmRSS:\t  102400 kB\n",
+		"/proc/300/stat":   "300 (kthreadd) S 0 100 100 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 1 0 50000 10000 200 18446744073709551615",
+		"/proc/300/status": "Name:\tkthreadd\nThreads:\t1\n",
+	}
+
+	inspector := procfsInspector{svc: testProcHandlers([]string{"100", "200", "300"}, files), now: time.Now}
+
+	rss, count, err := processMemory(inspector, "daemon")
+	if err != nil || rss != 409600*1024 || count != 2 {
+		t.Errorf("processMemory() Expected: %d bytes in 2 processes, Actual: %d bytes in %d processes, Error: %v", 409600*1024, rss, count, err)
+	}
+
+	if rss, _, err = processMemory(inspector, "kthreadd"); err != nil || rss != 0 {
+		t.Errorf("processMemory() should total 0 without VmRSS, got %d, Error: %v", rss, err)
+	}
+
+	if _, _, err = processMemory(inspector, "missing"); err != errProcessNotRunning {
+		t.Errorf("processMemory() should return errProcessNotRunning, returned %v", err)
+	}
+
+	if _, err = parseStatusRSS("VmRSS:\tlots kB\n"); err == nil {
+		t.Error("parseStatusRSS() should return an error on an invalid entry")
+	}
+}
+
+func TestCheckProcessMemory(t *testing.T) {
+	const megabyte = 1024 * 1024
+
+	type testItem struct {
+		description  string
+		service      ProcessService
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Below thresholds", testMemoryProcessHandler{rss: 256 * megabyte, count: 2}, statusCodeOK, "2 instances of goodName using 256.0MB | rss=256MB;512;1024;0"},
+		{"Above warning", testMemoryProcessHandler{rss: 600 * megabyte, count: 1}, statusCodeWarning, "warning threshold 512 tripped"},
+		{"Above critical", testMemoryProcessHandler{rss: 2048 * megabyte, count: 3}, statusCodeCritical, "critical threshold 1024 tripped"},
+		{"Not running", testMemoryProcessHandler{err: errProcessNotRunning}, statusCodeCritical, "is not running"},
+		{"Read error", testMemoryProcessHandler{err: errors.New("permission denied")}, statusCodeUnknown, "permission denied"},
+		{"Service without memory", new(testProcessHandler), statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		options := ProcessCheckOptions{
+			Name:       testProcessGoodName,
+			CheckType:  "memory",
+			MetricName: "rss",
+			Warning:    "512",
+			Critical:   "1024",
+		}
+
+		msg, code := checkProcessWithService(options, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+type testUptimeProcessHandler struct {
+	testProcessHandler
+	ages []time.Duration
+	err  error
+}
+
+func (p testUptimeProcessHandler) ProcessAges(name string) ([]time.Duration, error) {
+	return p.ages, p.err
+}
+
+func TestProcessAges(t *testing.T) {
+	// Start times in clock ticks, field 22, of 50000 (500s) and 90000 (900s)
+	files := map[string]string{
+		"/proc/uptime":     "1000.50 3800.20\n",
+		"/proc/100/stat":   "100 (watch) dog) S 1 100 100 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 1 0 50000 10000 200 18446744073709551615",
+		"/proc/200/stat":   "200 (watch) dog) S 1 200 200 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 1 0 90000 10000 200 18446744073709551615",
+		"/proc/100/status": "Name:\twatch) dog\n",
+		"/proc/200/status": "Name:\twatch) dog\n",
+	}
+
+	svc := testProcHandlers([]string{"100", "200"}, files)
+	svc.getPidName = func(readFile func(string) ([]byte, error), procRoot string, pid int) (string, error) {
+		return "watchdog", nil
+	}
+
+	now := time.Now()
+	inspector := procfsInspector{svc: svc, now: func() time.Time { return now }}
+
+	ages, err := processAges(inspector, "watchdog", now)
+	if err != nil {
+		t.Fatalf("processAges() returned an error on valid data: %s", err)
+	}
+
+	if len(ages) != 2 || int(ages[0].Seconds()) != 500 || int(ages[1].Seconds()) != 100 {
+		t.Errorf("processAges() Expected ages of 500s and 100s, Actual: %v", ages)
+	}
+
+	if _, err = processAges(inspector, "missing", now); err != errProcessNotRunning {
+		t.Errorf("processAges() should return errProcessNotRunning, returned %v", err)
+	}
+
+	noStartTimes := testProcessInspector{processes: []ProcessInfo{{PID: 4, Name: "System"}}}
+	if _, err = processAges(noStartTimes, "System", now); err == nil {
+		t.Error("processAges() should return an error without any start times")
+	}
+
+	if _, err = parseStatStartTime("100 (short) S 1 100"); err == nil {
+		t.Error("parseStatStartTime() should return an error on too few fields")
+	}
+
+	if _, err = parseUptime(""); err == nil {
+		t.Error("parseUptime() should return an error on empty data")
+	}
+}
+
+func TestCheckProcessUptime(t *testing.T) {
+	ages := []time.Duration{600 * time.Second, 120 * time.Second, 20000 * time.Second}
+
+	type testItem struct {
+		description  string
+		service      ProcessService
+		selection    string
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Oldest too old", testUptimeProcessHandler{ages: ages}, "", statusCodeCritical, "The oldest of 3 instances of goodName has been running 20000s (expected 60-14400)"},
+		{"Youngest too young", testUptimeProcessHandler{ages: ages}, "youngest", statusCodeWarning, "running 120s (expected 300-7200)"},
+		{"Within thresholds", testUptimeProcessHandler{ages: ages[:1]}, "oldest", statusCodeOK, "| uptime=600s;300:7200;60:14400;0"},
+		{"Invalid selection", testUptimeProcessHandler{ages: ages}, "middle", statusCodeUnknown, "Invalid selection"},
+		{"Not running", testUptimeProcessHandler{err: errProcessNotRunning}, "", statusCodeCritical, "is not running"},
+		{"Service without start times", new(testProcessHandler), "", statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		options := ProcessCheckOptions{
+			Name:       testProcessGoodName,
+			CheckType:  "uptime",
+			MetricName: "uptime",
+			Warning:    "300:7200",
+			Critical:   "60:14400",
+			Select:     i.selection,
+		}
+
+		msg, code := checkProcessWithService(options, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+// testLargeProcHandlers returns handlers for a synthetic /proc of
+// count processes where only the first is named name.
+func testLargeProcHandlers(count int, name string) processByNameHandlers {
+	pids := make([]string, count)
+	files := make(map[string]string, count)
+
+	for i := range pids {
+		pid := strconv.Itoa(i + 1)
+		procName := "kworker"
+		if i == 0 {
+			procName = name
+		}
+
+		pids[i] = pid
+		files["/proc/"+pid+"/stat"] = pid + " (" + procName + ") S 1"
+	}
+
+	return testProcHandlers(pids, files)
+}
+
+func TestFindProcessesByNameFirstMatch(t *testing.T) {
+	svc := testLargeProcHandlers(1000, "sshd")
+
+	reads := 0
+	readFile := svc.readFile
+	svc.readFile = func(path string) ([]byte, error) {
+		reads++
+		return readFile(path)
+	}
+
+	if entries, err := findProcessesByNameWithHandlers(svc, "sshd", 1); err != nil || len(entries) != 1 || reads != 1 {
+		t.Errorf("findProcessesByNameWithHandlers() should stop at the first match, found %d after %d reads, Error: %v", len(entries), reads, err)
+	}
+
+	reads = 0
+	if entries, err := getProcessesByNameWithHandlers(svc, "sshd"); err != nil || len(entries) != 1 || reads != 1000 {
+		t.Errorf("getProcessesByNameWithHandlers() should scan every process, found %d after %d reads, Error: %v", len(entries), reads, err)
+	}
+}
+
+func TestFindProcessesByNameConcurrency(t *testing.T) {
+	svc := testLargeProcHandlers(1000, "sshd")
+	files := make(map[string]string, 1000)
+	for i := 1; i <= 1000; i++ {
+		name := "kworker"
+		if i%7 == 0 {
+			name = "sshd"
+		}
+
+		files[fmt.Sprintf("/proc/%d/stat", i)] = fmt.Sprintf("%d (%s) S 1", i, name)
+	}
+
+	readFile := func(path string) ([]byte, error) {
+		data, ok := files[path]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+
+		return []byte(data), nil
+	}
+	svc.readFile = readFile
+
+	serial, err := getProcessesByNameWithHandlers(svc, "sshd")
+	if err != nil || len(serial) != 142 {
+		t.Fatalf("getProcessesByNameWithHandlers() found %d processes, Error: %v", len(serial), err)
+	}
+
+	for _, concurrency := range []int{2, 8, 2000} {
+		svc.concurrency = concurrency
+
+		entries, err := getProcessesByNameWithHandlers(svc, "sshd")
+		if err != nil || !reflect.DeepEqual(entries, serial) {
+			t.Errorf("getProcessesByNameWithHandlers() with concurrency %d should find the processes of a serial scan in order, found %d, Error: %v", concurrency, len(entries), err)
+		}
+
+		entries, err = findProcessesByNameWithHandlers(svc, "sshd", 3)
+		if err != nil || !reflect.DeepEqual(entries, serial[:3]) {
+			t.Errorf("findProcessesByNameWithHandlers() with concurrency %d should find the first processes, found %d, Error: %v", concurrency, len(entries), err)
+		}
+	}
+
+	// The first error in the order of the scan is returned, however
+	// the reads complete.
+	svc.readFile = func(path string) ([]byte, error) {
+		if path == "/proc/500/stat" || path == "/proc/900/stat" {
+			return nil, os.ErrPermission
+		}
+
+		return readFile(path)
+	}
+
+	if _, err := getProcessesByNameWithHandlers(svc, "sshd"); err == nil || !strings.Contains(err.Error(), "process 500") {
+		t.Errorf("getProcessesByNameWithHandlers() should return the error of the first process it could not read, returned %v", err)
+	}
+}
+
+// testProcfsDir creates a proc filesystem of count processes in a
+// temporary directory, for benchmarking scans reading real files.
+func testProcfsDir(b *testing.B, count int) string {
+	dir, err := ioutil.TempDir("", "procfs")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 1; i <= count; i++ {
+		pidDir := fmt.Sprintf("%s/%d", dir, i)
+		if err := os.Mkdir(pidDir, 0755); err != nil {
+			b.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(pidDir+"/stat", []byte(fmt.Sprintf("%d (kworker) S 1", i)), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func BenchmarkProcessesByNameConcurrency(b *testing.B) {
+	dir := testProcfsDir(b, 5000)
+	defer os.RemoveAll(dir)
+
+	// The reads block on the filesystem, so several reads at once may
+	// gain even with a single CPU.
+	concurrent := runtime.GOMAXPROCS(0)
+	if concurrent < 8 {
+		concurrent = 8
+	}
+
+	for _, concurrency := range []int{1, concurrent} {
+		svc := getProcessByNameHandlers()
+		svc.procRoot = dir
+		svc.concurrency = concurrency
+
+		b.Run(fmt.Sprintf("concurrency_%d", concurrency), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				getProcessesByNameWithHandlers(svc, "sshd")
+			}
+		})
+	}
+}
+
+func BenchmarkProcessesByNameFullScan(b *testing.B) {
+	svc := testLargeProcHandlers(5000, "sshd")
+
+	for i := 0; i < b.N; i++ {
+		getProcessesByNameWithHandlers(svc, "sshd")
+	}
+}
+
+func BenchmarkProcessesByNameFirstMatch(b *testing.B) {
+	svc := testLargeProcHandlers(5000, "sshd")
+
+	for i := 0; i < b.N; i++ {
+		findProcessesByNameWithHandlers(svc, "sshd", 1)
+	}
+}
+
+// testProcessInspector lists the same processes for any name.
+type testProcessInspector struct {
+	processes []ProcessInfo
+	err       error
+}
+
+func (i testProcessInspector) List(name string, limit int) ([]ProcessInfo, error) {
+	if limit > 0 && len(i.processes) > limit {
+		return i.processes[:limit], i.err
+	}
+
+	return i.processes, i.err
+}
+
+func TestProcessHandlerWithInspector(t *testing.T) {
+	started := time.Now().Add(-time.Hour)
+
+	p := processHandler{inspector: testProcessInspector{processes: []ProcessInfo{
+		{PID: 1200, Name: "java.exe", RSS: 300 * 1024 * 1024, StartTime: started},
+		{PID: 1300, Name: "java.exe", RSS: 100 * 1024 * 1024, StartTime: started.Add(30 * time.Minute)},
+	}}}
+
+	if !p.IsProcessRunning("java.exe") {
+		t.Error("IsProcessRunning() should be true when the inspector lists the process")
+	}
+
+	if count, err := p.ProcessCount("java.exe"); err != nil || count != 2 {
+		t.Errorf("ProcessCount() Expected: 2, Actual: %d, Error: %v", count, err)
+	}
+
+	if rss, count, err := p.ProcessMemory("java.exe"); err != nil || count != 2 || rss != 400*1024*1024 {
+		t.Errorf("ProcessMemory() Expected: %d bytes in 2 processes, Actual: %d bytes in %d, Error: %v", 400*1024*1024, rss, count, err)
+	}
+
+	if ages, err := p.ProcessAges("java.exe"); err != nil || len(ages) != 2 || int(ages[0].Minutes()) != 60 || int(ages[1].Minutes()) != 30 {
+		t.Errorf("ProcessAges() Expected ages of 60 and 30 minutes, Actual: %v, Error: %v", ages, err)
+	}
+
+	msg, code := checkProcessWithService(ProcessCheckOptions{
+		Name:       "java.exe",
+		CheckType:  "count",
+		MetricName: "count",
+		Critical:   "1:1",
+	}, p).Output()
+	if code != statusCodeCritical {
+		t.Errorf("checkProcessWithService() count should be critical with 2 instances: %s", msg)
+	}
+
+	p.inspector = testProcessInspector{err: errors.New("snapshot failed")}
+	if p.IsProcessRunning("java.exe") {
+		t.Error("IsProcessRunning() should be false when the inspector fails")
+	}
+
+	if _, err := p.ProcessCount("java.exe"); err == nil {
+		t.Error("ProcessCount() should return the error of the inspector")
+	}
+}
+
+func TestShowPids(t *testing.T) {
+	processes := make([]ProcessInfo, 12)
+	for i := range processes {
+		processes[i] = ProcessInfo{PID: 1300 - i*10, Name: "worker"}
+	}
+
+	type testItem struct {
+		description  string
+		options      ProcessCheckOptions
+		inspector    testProcessInspector
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Running", ProcessCheckOptions{Name: "worker", CheckType: "running", ShowPids: true},
+			testProcessInspector{processes: processes[10:]}, statusCodeOK, "CheckProcess OK - Process worker is running (pids: 1190, 1200) |"},
+		{"Not running", ProcessCheckOptions{Name: "worker", CheckType: "running", ShowPids: true},
+			testProcessInspector{}, statusCodeCritical, "CheckProcess CRITICAL - Process worker is not running |"},
+		{"Count cut short", ProcessCheckOptions{Name: "worker", CheckType: "count", Warning: "8", ShowPids: true},
+			testProcessInspector{processes: processes}, statusCodeWarning,
+			"12 instances of worker running (expected 0-8) (pids: 1190, 1200, 1210, 1220, 1230, 1240, 1250, 1260, 1270, 1280, ...) |"},
+		{"Count of none", ProcessCheckOptions{Name: "worker", CheckType: "count", ShowPids: true},
+			testProcessInspector{}, statusCodeOK, "CheckProcess OK - 0 instances of worker running |"},
+		{"Not shown", ProcessCheckOptions{Name: "worker", CheckType: "count"},
+			testProcessInspector{processes: processes[10:]}, statusCodeOK, "CheckProcess OK - 2 instances of worker running |"},
+		{"List error", ProcessCheckOptions{Name: "worker", CheckType: "running", ShowPids: true},
+			testProcessInspector{err: errors.New("permission denied")}, statusCodeUnknown, "Could not determine if process worker is running: permission denied"},
+	}
+
+	for _, i := range testList {
+		i.options.MetricName = "procs"
+		msg, code := checkProcessWithService(i.options, processHandler{inspector: i.inspector}).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	SetVerbose(true)
+	msg, _ := checkProcessWithService(ProcessCheckOptions{Name: "worker", CheckType: "running", MetricName: "procs"},
+		processHandler{inspector: testProcessInspector{processes: processes[11:]}}).Output()
+	SetVerbose(false)
+
+	if !strings.Contains(msg, "Process worker is running (pids: 1190)") {
+		t.Errorf("The PIDs should be listed with verbose messages enabled: %s", msg)
+	}
+
+	// From -vv each process is on a line of its own, with the values
+	// read for it from -vvv.
+	started := time.Date(2019, 6, 4, 15, 4, 5, 0, time.UTC)
+	described := []ProcessInfo{{PID: 1200, Name: "worker", RSS: 2048, Threads: 4, StartTime: started}, {PID: 1190, Name: "worker"}}
+	verboseList := []struct {
+		level       int
+		expectedMsg string
+	}{
+		{VerbosityBreakdown, "2 instances of worker running (pids: 1190, 1200)\npid 1190 worker\npid 1200 worker |"},
+		{VerbosityRaw, "\npid 1200 worker, rss=2048 threads=4 start=2019-06-04T15:04:05Z |"},
+	}
+
+	for _, i := range verboseList {
+		SetVerbosity(i.level)
+		msg, _ := checkProcessWithService(ProcessCheckOptions{Name: "worker", CheckType: "count", MetricName: "procs"},
+			processHandler{inspector: testProcessInspector{processes: described}}).Output()
+		SetVerbosity(0)
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("Verbosity %d: Expected Message: %q, Actual Message: %q", i.level, i.expectedMsg, msg)
+		}
+	}
+}
+
+func TestProcessScanErrors(t *testing.T) {
+	files := map[string]string{
+		"/proc/uptime":     "1000.50 3800.20\n",
+		"/proc/100/stat":   "100 (sshd) S 1 100 100 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 1 0 50000 10000 200 18446744073709551615",
+		"/proc/100/status": "Name:\tsshd\nVmRSS:\t  2048 kB\n",
+	}
+
+	type testItem struct {
+		description  string
+		open         func(string) (*os.File, error)
+		getPidName   func(readFile func(string) ([]byte, error), procRoot string, pid int) (string, error)
+		checkType    string
+		expectedCode int
+		expectedMsg  string
+	}
+
+	permissionDenied := func(name string) (*os.File, error) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+	}
+
+	// Process 200 exits during the scan while 300 cannot be read.
+	namePermissionDenied := func(readFile func(string) ([]byte, error), procRoot string, pid int) (string, error) {
+		switch pid {
+		case 200:
+			return "", os.ErrNotExist
+		case 300:
+			return "", os.ErrPermission
+		}
+
+		return getPidNameWithHandler(readFile, procRoot, pid)
+	}
+
+	testList := []testItem{
+		{"Running", nil, nil, "running", statusCodeOK, "is running"},
+		{"Not running", nil, nil, "notrunning", statusCodeCritical, "is running"},
+		{"Open running", permissionDenied, nil, "running", statusCodeUnknown, "permission denied"},
+		{"Open not running", permissionDenied, nil, "notrunning", statusCodeUnknown, "Could not determine if process sshd is running"},
+		{"Open count", permissionDenied, nil, "count", statusCodeUnknown, "permission denied"},
+		{"Open memory", permissionDenied, nil, "memory", statusCodeUnknown, "permission denied"},
+		{"Name count", nil, namePermissionDenied, "count", statusCodeUnknown, "Could not read the name of process 300"},
+	}
+
+	for _, i := range testList {
+		svc := testProcHandlers([]string{"100", "200", "300"}, files)
+		if i.open != nil {
+			svc.open = i.open
+		}
+
+		if i.getPidName != nil {
+			svc.getPidName = i.getPidName
+		}
+
+		p := processHandler{inspector: procfsInspector{svc: svc, now: time.Now}}
+
+		msg, code := checkProcessWithService(ProcessCheckOptions{Name: "sshd", CheckType: i.checkType, MetricName: "metric"}, p).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	// Without the process that cannot be read, the process exiting
+	// during the scan is skipped.
+	svc := testProcHandlers([]string{"100", "200"}, files)
+	svc.getPidName = namePermissionDenied
+
+	if entries, err := getProcessesByNameWithHandlers(svc, "sshd"); err != nil || len(entries) != 1 {
+		t.Errorf("getProcessesByNameWithHandlers() should skip an exited process, found %d, Error: %v", len(entries), err)
+	}
+}
+
+func TestProcessExitedDuringScan(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat": "100 (sshd) S 1 100 100 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 1 0 50000 10000 200 0",
+		"/proc/200/stat": "200 (sshd) S 1 200 200 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 1 0 50000 10000 200 0",
+		"/proc/300/stat": "300 (sshd) S 1 300 300 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 1 0 50000 10000 200 0",
+	}
+
+	type testItem struct {
+		description   string
+		readErr       error
+		expectedCount int
+		expectedErr   string
+	}
+
+	// Process 200 is listed by the scan but its stat cannot be read.
+	testList := []testItem{
+		{"Exited before its stat was opened", &os.PathError{Op: "open", Path: "/proc/200/stat", Err: syscall.ENOENT}, 2, ""},
+		{"Exited after its stat was opened", &os.PathError{Op: "read", Path: "/proc/200/stat", Err: syscall.ESRCH}, 2, ""},
+		{"Permission denied", &os.PathError{Op: "open", Path: "/proc/200/stat", Err: syscall.EACCES}, 0, "Could not read the name of process 200"},
+	}
+
+	for _, i := range testList {
+		svc := testProcHandlers([]string{"100", "200", "300"}, files)
+		readFile := svc.readFile
+		readErr := i.readErr
+		svc.readFile = func(path string) ([]byte, error) {
+			if path == "/proc/200/stat" {
+				return nil, readErr
+			}
+
+			return readFile(path)
+		}
+
+		entries, err := getProcessesByNameWithHandlers(svc, "sshd")
+
+		if len(entries) != i.expectedCount {
+			t.Errorf("%s: Expected Count: %d, Actual Count: %d", i.description, i.expectedCount, len(entries))
+		}
+
+		if i.expectedErr == "" && err != nil {
+			t.Errorf("%s: An exited process should not be an error: %s", i.description, err)
+		} else if i.expectedErr != "" && (err == nil || !strings.Contains(err.Error(), i.expectedErr)) {
+			t.Errorf("%s: Expected Error: %s, Actual Error: %v", i.description, i.expectedErr, err)
+		}
+	}
+}
+
+func TestProcessesByUser(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat": "100 (worker) S 1",
+		"/proc/200/stat": "200 (worker) S 1",
+		"/proc/300/stat": "300 (worker) S 1",
+	}
+
+	owners := map[int]string{100: "1001", 200: "1002", 300: "1001"}
+
+	svc := testProcHandlers([]string{"100", "200", "300"}, files)
+	svc.getPidUID = func(stat func(string) (os.FileInfo, error), procRoot string, pid int) (string, error) {
+		return owners[pid], nil
+	}
+	svc.lookupUID = func(name string) (string, error) {
+		if name == "appuser" {
+			return "1001", nil
+		}
+
+		return "", fmt.Errorf("Unknown user %s", name)
+	}
+
+	type testItem struct {
+		description   string
+		user          string
+		expectedCount int
+		expectedErr   string
+	}
+
+	testList := []testItem{
+		{"No filter matches by name", "", 3, ""},
+		{"Filter on a user", "appuser", 2, ""},
+		{"Unknown user", "nosuchuser", 0, "Unknown user nosuchuser"},
+	}
+
+	for _, i := range testList {
+		svc.user = i.user
+		entries, err := getProcessesByNameWithHandlers(svc, "worker")
+
+		if i.expectedErr != "" && (err == nil || err.Error() != i.expectedErr) {
+			t.Errorf("%s: Expected Error: %s, Actual Error: %v", i.description, i.expectedErr, err)
+		} else if i.expectedErr == "" && err != nil {
+			t.Errorf("%s: Unexpected error: %s", i.description, err)
+		}
+
+		if len(entries) != i.expectedCount {
+			t.Errorf("%s: Expected Count: %d, Actual Count: %d", i.description, i.expectedCount, len(entries))
+		}
+	}
+
+	if _, err := lookupUID("nagiosfoundation-no-such-user"); err == nil || !strings.Contains(err.Error(), "nagiosfoundation-no-such-user") {
+		t.Errorf("lookupUID() should return an error for an unknown user: %v", err)
+	}
+
+	if uid, err := lookupUID("4242"); err != nil || uid != "4242" {
+		t.Errorf("lookupUID() should accept a UID, got %s, Error: %v", uid, err)
+	}
+}
+
+func TestProcessesByParent(t *testing.T) {
+	files := map[string]string{
+		"/proc/812/stat": "812 (gunicorn) S 1 812 812",
+		"/proc/900/stat": "900 (gunicorn) S 812 812 812",
+		"/proc/901/stat": "901 (gunicorn) S 812 812 812",
+		"/proc/950/stat": "950 (gunicorn) S 1 950 950",
+		"/proc/951/stat": "951 (gunicorn) S 950 950 950",
+		"/proc/952/stat": "952 (gunicorn) S 1",
+	}
+
+	svc := testProcHandlers([]string{"812", "900", "901", "950", "951", "952"}, files)
+
+	type testItem struct {
+		description   string
+		ppid          int
+		pgid          int
+		expectedCount int
+	}
+
+	testList := []testItem{
+		{"No filter matches by name", 0, 0, 6},
+		{"Children of a supervisor", 812, 0, 2},
+		{"Process group", 0, 950, 2},
+		{"Children in a process group", 950, 950, 1},
+		{"Parent with no children", 900, 0, 0},
+	}
+
+	for _, i := range testList {
+		svc.ppid, svc.pgid = i.ppid, i.pgid
+		entries, err := getProcessesByNameWithHandlers(svc, "gunicorn")
+
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %s", i.description, err)
+		}
+
+		if len(entries) != i.expectedCount {
+			t.Errorf("%s: Expected Count: %d, Actual Count: %d", i.description, i.expectedCount, len(entries))
+		}
+	}
+
+	if ppid, pgid, err := parseStatParent("900 (my (odd) worker) S 812 811 810"); err != nil || ppid != 812 || pgid != 811 {
+		t.Errorf("parseStatParent() should count the fields from the end of the name, got %d %d, Error: %v", ppid, pgid, err)
+	}
+
+	msg, code := checkProcessCmd(ProcessCheckOptions{Name: "gunicorn", CheckType: "count", Ppid: -1}, checkProcessWithService, new(testProcessHandler)).Output()
+	if code != statusCodeCritical || !strings.Contains(msg, "may not be negative") {
+		t.Errorf("A negative parent PID should have been rejected. Code: %d, Message: %s", code, msg)
+	}
+}
+
+func TestRunProcessCheck(t *testing.T) {
+	result := RunProcessCheck(ProcessCheckOptions{Name: "nagiosfoundation-no-such-process", CheckType: "running", MetricName: "process_state"})
+
+	if result.Name != checkProcessName || result.State() != StateCritical ||
+		result.Message != "Process nagiosfoundation-no-such-process is not running" {
+		t.Errorf("RunProcessCheck() should return the structured result of the check: %+v", result)
+	}
+
+	if len(result.PerfData) != 1 || result.PerfData[0].Label != "process_state" || result.PerfData[0].Value != statusCodeCritical {
+		t.Errorf("RunProcessCheck() should return the perfdata of the check: %+v", result.PerfData)
+	}
+
+	if result = RunProcessCheck(ProcessCheckOptions{CheckType: "running"}); result.State() != StateCritical || result.Message != "A process name must be specified." {
+		t.Errorf("RunProcessCheck() should return invalid options as the result: %+v", result)
+	}
+}
+
+func TestProcessesByNames(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat": "100 (sshd) S 1",
+		"/proc/200/stat": "200 (cron) S 1",
+		"/proc/300/stat": "300 (sshd) S 100",
+	}
+
+	svc := testProcHandlers([]string{"100", "200", "300"}, files)
+
+	reads := 0
+	readFile := svc.readFile
+	svc.readFile = func(path string) ([]byte, error) {
+		reads++
+		return readFile(path)
+	}
+
+	entries, err := findProcessesByNamesWithHandlers(svc, []string{"sshd", "cron", "nginx"}, 0)
+	if err != nil || len(entries["sshd"]) != 2 || len(entries["cron"]) != 1 || entries["nginx"] == nil || len(entries["nginx"]) != 0 {
+		t.Errorf("findProcessesByNamesWithHandlers() should classify every process by name: %v, Error: %v", entries, err)
+	}
+
+	if reads != 3 {
+		t.Errorf("findProcessesByNamesWithHandlers() should read each process once for all names, read %d files", reads)
+	}
+
+	reads = 0
+	running, err := getProcessesRunningWithHandlers(svc, []string{"sshd", "cron"})
+	if err != nil || !running["sshd"] || !running["cron"] || reads != 2 {
+		t.Errorf("getProcessesRunningWithHandlers() should stop once every name is found: %v after %d reads, Error: %v", running, reads, err)
+	}
+
+	if running, _ = getProcessesRunningWithHandlers(svc, []string{"sshd", "nginx"}); !running["sshd"] || running["nginx"] {
+		t.Errorf("getProcessesRunningWithHandlers() should report nginx is not running: %v", running)
+	}
+}
+
+func TestProcessNames(t *testing.T) {
+	type testItem struct {
+		description string
+		options     ProcessCheckOptions
+		expected    []string
+	}
+
+	testList := []testItem{
+		{"Single name", ProcessCheckOptions{Name: "sshd"}, []string{"sshd"}},
+		{"Comma separated", ProcessCheckOptions{Name: "sshd, cron,"}, []string{"sshd", "cron"}},
+		{"Repeated", ProcessCheckOptions{Names: []string{"sshd", "cron,nginx"}}, []string{"sshd", "cron", "nginx"}},
+		{"Name and names", ProcessCheckOptions{Name: "sshd", Names: []string{"cron"}}, []string{"sshd", "cron"}},
+		{"Repeated name once", ProcessCheckOptions{Name: "sshd,cron", Names: []string{"sshd"}}, []string{"sshd", "cron"}},
+		{"Regex not split", ProcessCheckOptions{Name: "ngin.{1,3}", Regex: true}, []string{"ngin.{1,3}"}},
+		{"No names", ProcessCheckOptions{}, nil},
+	}
+
+	for _, i := range testList {
+		if actual := processNames(i.options); !reflect.DeepEqual(actual, i.expected) {
+			t.Errorf("%s: Expected: %v, Actual: %v", i.description, i.expected, actual)
+		}
+	}
+}
+
+func TestCheckRunningNames(t *testing.T) {
+	type testItem struct {
+		description  string
+		names        []string
+		checkType    string
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Perfdata for each process", []string{testProcessGoodName, testProcessGoodName + "2"}, "running", statusCodeCritical,
+			"CheckProcess CRITICAL - 1 of 2 processes are not running: " + testProcessGoodName + "2 | " +
+				"process_state_" + testProcessGoodName + "=0 process_state_" + testProcessGoodName + "2=2"},
+		{"One down", []string{testProcessGoodName, testProcessBadName}, "running", statusCodeCritical,
+			"1 of 2 processes are not running: " + testProcessBadName},
+		{"Single name", []string{testProcessGoodName}, "running", statusCodeOK, "Process " + testProcessGoodName + " is running"},
+		{"Other type", []string{testProcessGoodName, testProcessBadName}, "count", statusCodeCritical,
+			"Several process names are only supported by the \"running\" type, not count."},
+	}
+
+	for _, i := range testList {
+		msg, code := checkProcessCmd(ProcessCheckOptions{Names: i.names, CheckType: i.checkType, MetricName: "process_state"},
+			checkProcessWithService, testProcessHandler{}).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	svc := testProcHandlers([]string{"100"}, map[string]string{"/proc/100/stat": "100 (sshd) S 1"})
+	p := processHandler{inspector: procfsInspector{svc: svc, now: time.Now}}
+
+	msg, code := checkRunningNames(ProcessCheck{ProcessCheckHandler: testProcessesRunningHandler{p, svc}}, []string{"sshd", "cron"}, "process_state").Output()
+	if code != statusCodeCritical || msg != "CheckProcess CRITICAL - 1 of 2 processes are not running: cron | process_state_sshd=0 process_state_cron=2" {
+		t.Errorf("checkRunningNames() should list the processes not running, Code: %d, %s", code, msg)
+	}
+
+	msg, code = checkRunningNames(ProcessCheck{ProcessCheckHandler: testProcessesRunningHandler{p, svc}}, []string{"sshd", "sshd"}, "process_state").Output()
+	if code != statusCodeOK || !strings.Contains(msg, "All 2 processes are running: sshd, sshd") {
+		t.Errorf("checkRunningNames() should be OK when every process is running, Code: %d, %s", code, msg)
+	}
+}
+
+func TestCheckRunningSingle(t *testing.T) {
+	type testItem struct {
+		description  string
+		options      ProcessCheckOptions
+		inspector    testProcessInspector
+		expectedCode int
+		expectedMsg  string
+	}
+
+	single := []ProcessInfo{{PID: 812, Name: "crond"}}
+	duplicated := []ProcessInfo{{PID: 4127, Name: "crond"}, {PID: 812, Name: "crond"}}
+
+	testList := []testItem{
+		{"One instance", ProcessCheckOptions{Name: "crond", CheckType: "running", ExpectSingle: true},
+			testProcessInspector{processes: single}, statusCodeOK, "CheckProcess OK - Process crond is running (pids: 812) | process_state=0"},
+		{"Duplicated", ProcessCheckOptions{Name: "crond", CheckType: "running", ExpectSingle: true},
+			testProcessInspector{processes: duplicated}, statusCodeWarning,
+			"CheckProcess WARNING - 2 instances of crond running, expected one (pids: 812, 4127) | process_state=1"},
+		{"Not running", ProcessCheckOptions{Name: "crond", CheckType: "running", ExpectSingle: true},
+			testProcessInspector{}, statusCodeCritical, "CheckProcess CRITICAL - Process crond is not running | process_state=2"},
+		{"Optional", ProcessCheckOptions{Name: "crond", CheckType: "running", ExpectSingle: true, MissingState: "ok"},
+			testProcessInspector{}, statusCodeOK, "Process crond is not running | process_state=2"},
+		{"List error", ProcessCheckOptions{Name: "crond", CheckType: "running", ExpectSingle: true},
+			testProcessInspector{err: errors.New("permission denied")}, statusCodeUnknown, "Could not determine if process crond is running: permission denied"},
+		{"Duplicates allowed without the mode", ProcessCheckOptions{Name: "crond", CheckType: "running"},
+			testProcessInspector{processes: duplicated}, statusCodeOK, "Process crond is running |"},
+		{"Other type", ProcessCheckOptions{Name: "crond", CheckType: "count", ExpectSingle: true},
+			testProcessInspector{}, statusCodeCritical, "The expect single mode is only supported by the \"running\" type, not count."},
+		{"Several names", ProcessCheckOptions{Name: "crond,sshd", CheckType: "running", ExpectSingle: true},
+			testProcessInspector{}, statusCodeCritical, "The expect single mode takes a single process name."},
+	}
+
+	for _, i := range testList {
+		i.options.MetricName = "process_state"
+		msg, code := checkProcessCmd(i.options, checkProcessWithService, processHandler{inspector: i.inspector}).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	if msg, code := checkRunningSingle(ProcessCheck{ProcessName: "crond", ProcessCheckHandler: new(testProcessHandler)}, "process_state").Output(); code != statusCodeUnknown {
+		t.Errorf("checkRunningSingle() should return UNKNOWN when the service cannot list PIDs, Code: %d, %s", code, msg)
+	}
+}
+
+// testProcessesRunningHandler lists the processes of a synthetic /proc
+// for every name at once.
+type testProcessesRunningHandler struct {
+	processHandler
+	svc processByNameHandlers
+}
+
+func (p testProcessesRunningHandler) ProcessesRunning(names []string) (map[string]bool, error) {
+	return getProcessesRunningWithHandlers(p.svc, names)
+}
+
+func TestProcessThreads(t *testing.T) {
+	files := map[string]string{
+		"/proc/uptime":     "1000.50 3800.20\n",
+		"/proc/100/stat":   "100 (java) S 1 100 100 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 42 0 50000 10000 200 18446744073709551615",
+		"/proc/100/status": "Name:\tjava\nVmRSS:\t  307200 kB\n",
+		"/proc/200/stat":   "200 (java) S 1 100 100 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 7 0 50000 10000 200 18446744073709551615",
+		"/proc/200/status": "Name:\tjava\nVmRSS:\t  102400 kB\n",
+	}
+
+	inspector := procfsInspector{svc: testProcHandlers([]string{"100", "200"}, files), now: time.Now}
+
+	processes, err := processThreads(inspector, "java")
+	if err != nil || len(processes) != 2 || processes[0].Threads != 42 || processes[1].Threads != 7 {
+		t.Errorf("processThreads() should read the threads of each process from stat: %+v, Error: %v", processes, err)
+	}
+
+	if _, err = processThreads(inspector, "missing"); err != errProcessNotRunning {
+		t.Errorf("processThreads() should return errProcessNotRunning, returned %v", err)
+	}
+
+	if threads, err := parseStatThreads("100 (my (odd) app) S 1 100 100 0 -1 4194560 500 0 0 0 5 3 0 0 20 0 9 0 50000"); err != nil || threads != 9 {
+		t.Errorf("parseStatThreads() should count fields from the end of the name, got %d, Error: %v", threads, err)
+	}
+
+	if _, err = parseStatThreads("100 (java) S 1 100"); err == nil {
+		t.Error("parseStatThreads() should return an error on too few fields")
+	}
+}
+
+type testThreadsProcessHandler struct {
+	testProcessHandler
+	processes []ProcessInfo
+	err       error
+}
+
+func (p testThreadsProcessHandler) ProcessThreads(name string) ([]ProcessInfo, error) {
+	return p.processes, p.err
+}
+
+func TestCheckThreads(t *testing.T) {
+	workers := []ProcessInfo{{PID: 100, Threads: 120}, {PID: 200, Threads: 40}, {PID: 300, Threads: 600}}
+
+	type testItem struct {
+		description  string
+		service      ProcessService
+		perProcess   bool
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Total over critical", testThreadsProcessHandler{processes: workers}, false, statusCodeCritical,
+			"760 threads in 3 instances of goodName (expected 0-500) | threads=760;200;500;0"},
+		{"Total below thresholds", testThreadsProcessHandler{processes: workers[:2]}, false, statusCodeOK, "160 threads in 2 instances of goodName"},
+		{"Each below thresholds", testThreadsProcessHandler{processes: workers[:2]}, true, statusCodeOK,
+			"The 2 instances of goodName have at most 120 threads | threads=120;200;500;0"},
+		{"One leaking", testThreadsProcessHandler{processes: workers}, true, statusCodeCritical,
+			"1 of 3 instances of goodName have too many threads: process 300 has 600 threads (expected 0-500) | threads=600"},
+		{"One over warning", testThreadsProcessHandler{processes: []ProcessInfo{{PID: 100, Threads: 250}, {PID: 200, Threads: 40}}}, true, statusCodeWarning,
+			"process 100 has 250 threads (expected 0-200)"},
+		{"Not running", testThreadsProcessHandler{err: errProcessNotRunning}, false, statusCodeUnknown, "Process goodName is not running"},
+		{"Read error", testThreadsProcessHandler{err: errors.New("permission denied")}, false, statusCodeUnknown, "permission denied"},
+		{"Service without threads", new(testProcessHandler), false, statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		options := ProcessCheckOptions{
+			Name:       testProcessGoodName,
+			CheckType:  "threads",
+			MetricName: "threads",
+			Warning:    "200",
+			Critical:   "500",
+			PerProcess: i.perProcess,
+		}
+
+		msg, code := checkProcessWithService(options, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+const testLimits = `Limit                     Soft Limit           Hard Limit           Units
+Max cpu time              unlimited            unlimited            seconds
+Max open files            1024                 524288               files
+Max locked memory         8388608              8388608              bytes
+`
+
+func TestProcessFds(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat":   "100 (nginx) S 1",
+		"/proc/100/limits": testLimits,
+		"/proc/200/stat":   "200 (nginx) S 1",
+		"/proc/300/stat":   "300 (nginx) S 1",
+	}
+
+	fdDirs := map[string][]string{
+		"/proc/100/fd": {"0", "1", "2", "3", "4"},
+		"/proc/200/fd": {"0", "1", "2"},
+	}
+
+	svc := testProcHandlers([]string{"100", "200", "300"}, files)
+	svc.listDir = func(path string) ([]string, error) {
+		if fds, ok := fdDirs[path]; ok {
+			return fds, nil
+		}
+
+		return nil, os.ErrNotExist
+	}
+
+	// Process 300 has exited and is skipped, and process 200 has no
+	// readable limits.
+	fds, err := getProcessFdsWithHandlers(svc, "nginx")
+	expected := []processFds{{pid: 100, count: 5, limit: 1024}, {pid: 200, count: 3}}
+	if err != nil || !reflect.DeepEqual(fds, expected) {
+		t.Errorf("getProcessFdsWithHandlers() should count the fds and read the limit of each process: %+v, Error: %v", fds, err)
+	}
+
+	if _, err = getProcessFdsWithHandlers(svc, "missing"); err != errProcessNotRunning {
+		t.Errorf("getProcessFdsWithHandlers() should return errProcessNotRunning, returned %v", err)
+	}
+
+	svc.listDir = func(string) ([]string, error) {
+		return nil, os.ErrPermission
+	}
+
+	if _, err = getProcessFdsWithHandlers(svc, "nginx"); err == nil {
+		t.Error("getProcessFdsWithHandlers() should return an error when the fds cannot be listed")
+	}
+
+	limitTests := []struct {
+		data     string
+		expected uint64
+		err      bool
+	}{
+		{testLimits, 1024, false},
+		{"Max open files            unlimited            unlimited            files\n", 0, false},
+		{"Max cpu time              unlimited            unlimited            seconds\n", 0, false},
+		{"Max open files            many                 many                 files\n", 0, true},
+	}
+
+	for _, i := range limitTests {
+		limit, err := parseLimitsOpenFiles(i.data)
+		if limit != i.expected || (err != nil) != i.err {
+			t.Errorf("parseLimitsOpenFiles(%q) returned %d, Error: %v", i.data, limit, err)
+		}
+	}
+}
+
+type testFdsProcessHandler struct {
+	testProcessHandler
+	processes []processFds
+	err       error
+}
+
+func (p testFdsProcessHandler) ProcessFds(name string) ([]processFds, error) {
+	return p.processes, p.err
+}
+
+func TestCheckFds(t *testing.T) {
+	workers := []processFds{{pid: 100, count: 300, limit: 1024}, {pid: 200, count: 80, limit: 1024}, {pid: 300, count: 900, limit: 1024}}
+
+	type testItem struct {
+		description  string
+		service      ProcessService
+		warning      string
+		critical     string
+		perProcess   bool
+		ofLimit      bool
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Total over critical", testFdsProcessHandler{processes: workers}, "500", "1000", false, false, statusCodeCritical,
+			"1280 open files of a limit of 3072 (41.7%) in 3 instances of goodName (expected 0-1000) | fds=1280;500;1000;0;3072"},
+		{"Total below thresholds", testFdsProcessHandler{processes: workers[:2]}, "500", "1000", false, false, statusCodeOK,
+			"380 open files of a limit of 2048 (18.6%) in 2 instances of goodName"},
+		{"Total of limit", testFdsProcessHandler{processes: workers}, "40", "80", false, true, statusCodeWarning,
+			"(expected 0-40) | fds=41.7%;40;80;0;100"},
+		{"Each below thresholds", testFdsProcessHandler{processes: workers[:2]}, "500", "1000", true, false, statusCodeOK,
+			"The 2 instances of goodName have at most 300 open files of a limit of 1024 (29.3%) | fds=300;500;1000;0;1024"},
+		{"One of limit", testFdsProcessHandler{processes: workers}, "70", "85", true, true, statusCodeCritical,
+			"1 of 3 instances of goodName have too many open files: process 300 has 900 open files of a limit of 1024 (87.9%) (expected 0-85) | fds=87.9%;70;85;0;100"},
+		{"Limit unknown", testFdsProcessHandler{processes: []processFds{{pid: 100, count: 300}}}, "500", "1000", false, false, statusCodeOK,
+			"300 open files in 1 instances of goodName | fds=300;500;1000;0"},
+		{"Limit unknown of limit", testFdsProcessHandler{processes: []processFds{{pid: 100, count: 300, limit: 1024}, {pid: 200, count: 80}}}, "70", "85", false, true, statusCodeUnknown,
+			"The open files limit of process goodName is not available"},
+		{"Not running", testFdsProcessHandler{err: errProcessNotRunning}, "500", "1000", false, false, statusCodeUnknown, "Process goodName is not running"},
+		{"Read error", testFdsProcessHandler{err: errors.New("permission denied")}, "500", "1000", false, false, statusCodeUnknown, "permission denied"},
+		{"Service without fds", new(testProcessHandler), "500", "1000", false, false, statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		options := ProcessCheckOptions{
+			Name:       testProcessGoodName,
+			CheckType:  "fds",
+			MetricName: "fds",
+			Warning:    i.warning,
+			Critical:   i.critical,
+			PerProcess: i.perProcess,
+			OfLimit:    i.ofLimit,
+		}
+
+		msg, code := checkProcessWithService(options, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+func TestProcessZombies(t *testing.T) {
+	files := map[string]string{
+		"/proc/1/stat":   "1 (systemd) S 0",
+		"/proc/100/stat": "100 (supervisor) S 1",
+		"/proc/101/stat": "101 (worker) Z 100",
+		"/proc/102/stat": "102 (worker) Z 100",
+		"/proc/200/stat": "200 (cron) S 1",
+		"/proc/201/stat": "201 (sh (job)) Z 200",
+		"/proc/300/stat": "300 (worker) S 100",
+	}
+
+	// Process 400 has exited and is skipped.
+	svc := testProcHandlers([]string{"1", "100", "101", "102", "200", "201", "300", "400"}, files)
+
+	zombies, err := getProcessZombiesWithHandlers(svc, "")
+	expected := []processZombie{{pid: 101, ppid: 100}, {pid: 102, ppid: 100}, {pid: 201, ppid: 200}}
+	if err != nil || !reflect.DeepEqual(zombies, expected) {
+		t.Errorf("getProcessZombiesWithHandlers() should return every zombie: %+v, Error: %v", zombies, err)
+	}
+
+	zombies, err = getProcessZombiesWithHandlers(svc, "supervisor")
+	if err != nil || !reflect.DeepEqual(zombies, expected[:2]) {
+		t.Errorf("getProcessZombiesWithHandlers() should return the zombies of the parent: %+v, Error: %v", zombies, err)
+	}
+
+	zombies, err = getProcessZombiesWithHandlers(svc, "systemd")
+	if err != nil || len(zombies) != 0 {
+		t.Errorf("getProcessZombiesWithHandlers() should return no zombies for a parent without any: %+v, Error: %v", zombies, err)
+	}
+
+	if _, err = getProcessZombiesWithHandlers(svc, "missing"); err != errProcessNotRunning {
+		t.Errorf("getProcessZombiesWithHandlers() should return errProcessNotRunning, returned %v", err)
+	}
+
+	files["/proc/1/stat"] = "1 (systemd)"
+	if _, err = getProcessZombiesWithHandlers(svc, ""); err == nil {
+		t.Error("getProcessZombiesWithHandlers() should return an error when a state cannot be parsed")
+	}
+}
+
+type testZombiesProcessHandler struct {
+	testProcessHandler
+	zombies []processZombie
+	err     error
+}
+
+func (p testZombiesProcessHandler) ProcessZombies(name string) ([]processZombie, error) {
+	return p.zombies, p.err
+}
+
+func TestCheckZombies(t *testing.T) {
+	zombies := []processZombie{{pid: 101, ppid: 100}, {pid: 102, ppid: 100}}
+
+	type testItem struct {
+		description  string
+		name         string
+		service      ProcessService
+		warning      string
+		critical     string
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"No zombies", "", testZombiesProcessHandler{}, "", "", statusCodeOK, "CheckProcess OK - 0 zombie processes | zombies=0;0;;0"},
+		{"Any zombie warns", "", testZombiesProcessHandler{zombies: zombies}, "", "", statusCodeWarning,
+			"2 zombie processes (expected 0): 101 (parent 100), 102 (parent 100) | zombies=2;0;;0"},
+		{"Zombies of parent", testProcessGoodName, testZombiesProcessHandler{zombies: zombies}, "5", "1", statusCodeCritical,
+			"2 zombie processes of goodName (expected 0-1): 101 (parent 100), 102 (parent 100) | zombies=2;5;1;0"},
+		{"Below thresholds", "", testZombiesProcessHandler{zombies: zombies}, "5", "10", statusCodeOK, "2 zombie processes | zombies=2;5;10;0"},
+		{"Invalid threshold", "", testZombiesProcessHandler{}, "many", "", statusCodeUnknown, statusTextUnknown},
+		{"Parent not running", testProcessGoodName, testZombiesProcessHandler{err: errProcessNotRunning}, "", "", statusCodeUnknown, "Process goodName is not running"},
+		{"Read error", "", testZombiesProcessHandler{err: errors.New("permission denied")}, "", "", statusCodeUnknown,
+			"Could not read the process states: permission denied"},
+		{"Service without zombies", "", new(testProcessHandler), "", "", statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		options := ProcessCheckOptions{
+			Name:       i.name,
+			CheckType:  "zombie",
+			MetricName: "zombies",
+			Warning:    i.warning,
+			Critical:   i.critical,
+		}
+
+		msg, code := checkProcessCmd(options, checkProcessWithService, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+const testNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:0050 0100007F:D431 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+`
+
+const testNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:01BB 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2001 1 0000000000000000 100 0 0 10 0
+`
+
+func TestProcessListeningPorts(t *testing.T) {
+	listeners := parseNetTCPListeners(testNetTCP)
+	if !reflect.DeepEqual(listeners, map[string]int{"1001": 80, "1002": 8080}) {
+		t.Errorf("parseNetTCPListeners() should return the listening sockets: %v", listeners)
+	}
+
+	files := map[string]string{
+		"/proc/100/stat":     "100 (nginx) S 1",
+		"/proc/100/net/tcp":  testNetTCP,
+		"/proc/100/net/tcp6": testNetTCP6,
+		"/proc/200/stat":     "200 (nginx) S 100",
+		"/proc/200/net/tcp":  testNetTCP,
+		"/proc/300/stat":     "300 (cron) S 1",
+		"/proc/300/net/tcp":  testNetTCP,
+		"/proc/400/stat":     "400 (sshd) S 1",
+	}
+
+	fdDirs := map[string][]string{
+		"/proc/100/fd": {"0", "3", "4"},
+		"/proc/200/fd": {"0", "3", "5"},
+		"/proc/300/fd": {"0"},
+	}
+
+	fdLinks := map[string]string{
+		"/proc/100/fd/0": "/dev/null",
+		"/proc/100/fd/3": "socket:[2001]",
+		"/proc/100/fd/4": "socket:[1001]",
+		"/proc/200/fd/3": "socket:[1001]",
+		"/proc/200/fd/5": "socket:[1003]",
+	}
+
+	svc := testProcHandlers([]string{"100", "200", "300", "400"}, files)
+	svc.listDir = func(path string) ([]string, error) {
+		if fds, ok := fdDirs[path]; ok {
+			return fds, nil
+		} else if path == "/proc/400/fd" {
+			return nil, os.ErrNotExist
+		}
+
+		return nil, os.ErrPermission
+	}
+	svc.readLink = func(path string) (string, error) {
+		if target, ok := fdLinks[path]; ok {
+			return target, nil
+		}
+
+		return "", os.ErrNotExist
+	}
+
+	ports, err := getProcessListeningPortsWithHandlers(svc, "nginx")
+	if err != nil || !reflect.DeepEqual(ports, []int{80, 443}) {
+		t.Errorf("getProcessListeningPortsWithHandlers() Expected: [80 443], Actual: %v, Error: %v", ports, err)
+	}
+
+	ports, err = getProcessListeningPortsWithHandlers(svc, "cron")
+	if err != nil || len(ports) != 0 {
+		t.Errorf("getProcessListeningPortsWithHandlers() should return no ports for a process without sockets: %v, Error: %v", ports, err)
+	}
+
+	if _, err = getProcessListeningPortsWithHandlers(svc, "missing"); err != errProcessNotRunning {
+		t.Errorf("getProcessListeningPortsWithHandlers() should return errProcessNotRunning, returned %v", err)
+	}
+
+	// Process 400 exited before its descriptors were listed.
+	if _, err = getProcessListeningPortsWithHandlers(svc, "sshd"); err != errProcessNotRunning {
+		t.Errorf("getProcessListeningPortsWithHandlers() should return errProcessNotRunning when every process exited, returned %v", err)
+	}
+
+	delete(fdDirs, "/proc/200/fd")
+	if _, err = getProcessListeningPortsWithHandlers(svc, "nginx"); err != os.ErrPermission {
+		t.Errorf("getProcessListeningPortsWithHandlers() should return the error listing descriptors, returned %v", err)
+	}
+}
+
+type testListeningProcessHandler struct {
+	testProcessHandler
+	ports []int
+	err   error
+}
+
+func (p testListeningProcessHandler) ProcessListeningPorts(name string) ([]int, error) {
+	return p.ports, p.err
+}
+
+func TestCheckListening(t *testing.T) {
+	type testItem struct {
+		description  string
+		port         int
+		service      ProcessService
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Listening", 80, testListeningProcessHandler{ports: []int{80, 443}}, statusCodeOK, "CheckProcess OK - Process goodName is listening on port 80 | listen=0"},
+		{"Other ports", 8080, testListeningProcessHandler{ports: []int{80, 443}}, statusCodeCritical,
+			"CheckProcess CRITICAL - Process goodName is running but not listening on port 8080, it is listening on 80, 443 | listen=2"},
+		{"No ports", 8080, testListeningProcessHandler{}, statusCodeCritical, "Process goodName is running but not listening on port 8080 | listen=2"},
+		{"Not running", 80, testListeningProcessHandler{err: errProcessNotRunning}, statusCodeCritical, "Process goodName is not running | listen=2"},
+		{"Read error", 80, testListeningProcessHandler{err: errors.New("permission denied")}, statusCodeUnknown,
+			"Could not read the sockets of process goodName: permission denied"},
+		{"Service without ports", 80, new(testProcessHandler), statusCodeUnknown, statusTextUnknown},
+		{"No port", 0, testListeningProcessHandler{ports: []int{80}}, statusCodeCritical, "A port from 1 to 65535 must be specified"},
+		{"Port out of range", 70000, testListeningProcessHandler{ports: []int{80}}, statusCodeCritical, "not 70000"},
+	}
+
+	for _, i := range testList {
+		options := ProcessCheckOptions{
+			Name:       testProcessGoodName,
+			CheckType:  "listening",
+			MetricName: "listen",
+			Port:       i.port,
+		}
+
+		msg, code := checkProcessCmd(options, checkProcessWithService, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+func TestProcessMissingState(t *testing.T) {
+	type testItem struct {
+		description  string
+		options      ProcessCheckOptions
+		service      ProcessService
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Missing is critical", ProcessCheckOptions{Name: testProcessBadName, CheckType: "running", MetricName: "state"}, new(testProcessHandler),
+			statusCodeCritical, "CheckProcess CRITICAL - Process badName is not running | state=2"},
+		{"Missing is ok", ProcessCheckOptions{Name: testProcessBadName, CheckType: "running", MetricName: "state", MissingState: "ok"}, new(testProcessHandler),
+			statusCodeOK, "CheckProcess OK - Process badName is not running | state=2"},
+		{"Running is unchanged", ProcessCheckOptions{Name: testProcessGoodName, CheckType: "running", MetricName: "state", MissingState: "ok"}, new(testProcessHandler),
+			statusCodeOK, "CheckProcess OK - Process goodName is running | state=0"},
+		{"Missing of several", ProcessCheckOptions{Names: []string{testProcessGoodName, testProcessBadName}, CheckType: "running", MetricName: "state", MissingState: "warning"}, new(testProcessHandler),
+			statusCodeWarning, "1 of 2 processes are not running: badName"},
+		{"Missing inverted", ProcessCheckOptions{Name: testProcessBadName, CheckType: "notrunning", MetricName: "state", MissingState: "critical"}, new(testProcessHandler),
+			statusCodeOK, "is not running"},
+		{"Missing threads", ProcessCheckOptions{Name: testProcessGoodName, CheckType: "threads", MetricName: "threads", MissingState: "warning"}, testThreadsProcessHandler{err: errProcessNotRunning},
+			statusCodeWarning, "CheckProcess WARNING - Process goodName is not running"},
+		{"Invalid missing state", ProcessCheckOptions{Name: testProcessBadName, CheckType: "running", MetricName: "state", MissingState: "absent"}, new(testProcessHandler),
+			statusCodeUnknown, "Invalid missing state: Invalid state \"absent\""},
+	}
+
+	for _, i := range testList {
+		msg, code := checkProcessCmd(i.options, checkProcessWithService, i.service).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
+
+// testCPUStat returns the /proc/<pid>/stat of an nginx process with
+// the user and system times and the start time in clock ticks.
+func testCPUStat(pid int, utime, stime, startTime uint64) string {
+	return fmt.Sprintf("%d (nginx) S 1 %d %d 0 -1 4194560 0 0 0 0 %d %d 0 0 20 0 1 0 %d", pid, pid, pid, utime, stime, startTime)
+}
+
+func TestSampleProcessCPU(t *testing.T) {
+	files := map[string]string{
+		"/proc/100/stat": testCPUStat(100, 50, 50, 1000),
+		"/proc/200/stat": testCPUStat(200, 10, 0, 2000),
+		"/proc/300/stat": testCPUStat(300, 10, 10, 3000),
+		"/proc/400/stat": testCPUStat(400, 500, 500, 4000),
+	}
+
+	svc := testProcHandlers([]string{"100", "200", "300", "400"}, files)
+
+	clock := time.Unix(1500000000, 0)
+	now := func() time.Time { return clock }
+
+	// Over the 2 seconds of the interval, process 100 uses 200 ticks,
+	// 2 seconds of CPU or 100% of one CPU, and process 200 uses 50
+	// ticks, 25%. Process 300 exits and process 400 is replaced by a
+	// process started later with the same PID, so both are left out.
+	var slept time.Duration
+	sleep := func(d time.Duration) {
+		slept = d
+		clock = clock.Add(2 * time.Second)
+		files["/proc/100/stat"] = testCPUStat(100, 150, 150, 1000)
+		files["/proc/200/stat"] = testCPUStat(200, 60, 0, 2000)
+		delete(files, "/proc/300/stat")
+		files["/proc/400/stat"] = testCPUStat(400, 10, 10, 4100)
+	}
+
+	usage, err := sampleProcessCPUWithHandlers(svc, "nginx", time.Second, sleep, now)
+	expected := []processCPU{{pid: 100, percent: 100}, {pid: 200, percent: 25}}
+	if err != nil || !reflect.DeepEqual(usage, expected) {
+		t.Errorf("sampleProcessCPUWithHandlers() should return the CPU percentage of each process: %+v, Error: %v", usage, err)
+	}
+
+	if slept != time.Second {
+		t.Errorf("sampleProcessCPUWithHandlers() should sleep for the interval, slept %s", slept)
+	}
+
+	if _, err = sampleProcessCPUWithHandlers(svc, "missing", time.Second, sleep, now); err != errProcessNotRunning {
+		t.Errorf("sampleProcessCPUWithHandlers() should return errProcessNotRunning, returned %v", err)
+	}
+
+	files["/proc/100/stat"] = "100 (nginx) S 1"
+	if _, err = sampleProcessCPUWithHandlers(svc, "nginx", time.Second, func(time.Duration) {}, now); err == nil {
+		t.Error("sampleProcessCPUWithHandlers() should return an error when a stat cannot be parsed")
+	}
+
+	statTests := []struct {
+		data      string
+		ticks     uint64
+		startTime uint64
+		err       bool
+	}{
+		{testCPUStat(100, 12, 30, 900), 42, 900, false},
+		{"100 (my (nginx)) S 1 100 100 0 -1 4194560 0 0 0 0 7 3 0 0 20 0 1 0 55", 10, 55, false},
+		{"100 (nginx) S 1 100 100 0 -1 4194560 0 0 0 0 x 3 0 0 20 0 1 0 55", 0, 0, true},
+		{"100 (nginx) S 1 100", 0, 0, true},
+	}
+
+	for _, i := range statTests {
+		ticks, startTime, err := parseStatCPUTime(i.data)
+		if ticks != i.ticks || startTime != i.startTime || (err != nil) != i.err {
+			t.Errorf("parseStatCPUTime(%q) returned %d, %d, Error: %v", i.data, ticks, startTime, err)
+		}
+	}
+}
+
+type testCPUProcessHandler struct {
+	testProcessHandler
+	processes []processCPU
+	err       error
+}
+
+func (p testCPUProcessHandler) ProcessCPU(name string, interval time.Duration) ([]processCPU, error) {
+	return p.processes, p.err
+}
+
+func TestCheckProcessCPU(t *testing.T) {
+	workers := []processCPU{{pid: 100, percent: 95}, {pid: 200, percent: 12.34}, {pid: 300, percent: 40}}
+
+	type testItem struct {
+		description  string
+		service      ProcessService
+		warning      string
+		critical     string
+		perProcess   bool
+		ofCPUs       bool
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"Total over critical", testCPUProcessHandler{processes: workers}, "80", "120", false, false, statusCodeCritical,
+			"147.3% CPU over 1s in 3 instances of goodName (expected 0-120) | cpu=147.3%;80;120;0"},
+		{"Total below thresholds", testCPUProcessHandler{processes: workers[1:]}, "80", "120", false, false, statusCodeOK,
+			"52.3% CPU over 1s in 2 instances of goodName | cpu=52.3%;80;120;0"},
+		{"Total of CPUs", testCPUProcessHandler{processes: workers}, "30", "50", false, true, statusCodeWarning,
+			"36.8% CPU of 4 CPUs over 1s in 3 instances of goodName (expected 0-30) | cpu=36.8%;30;50;0;100"},
+		{"Each over critical", testCPUProcessHandler{processes: workers}, "50", "90", true, false, statusCodeCritical,
+			"1 of 3 instances of goodName use too much CPU over 1s: process 100 uses 95.0% CPU (expected 0-90) | cpu=95%;50;90;0"},
+		{"Each over warning", testCPUProcessHandler{processes: workers}, "30", "90", true, false, statusCodeCritical,
+			"2 of 3 instances of goodName use too much CPU over 1s: process 100 uses 95.0% CPU (expected 0-90), process 300 uses 40.0% CPU (expected 0-30)"},
+		{"Each below thresholds", testCPUProcessHandler{processes: workers[1:]}, "50", "90", true, false, statusCodeOK,
+			"The 2 instances of goodName use at most 40.0% CPU over 1s | cpu=40%;50;90;0"},
+		{"Not running", testCPUProcessHandler{err: errProcessNotRunning}, "80", "120", false, false, statusCodeUnknown, "Process goodName is not running"},
+		{"Read error", testCPUProcessHandler{err: errors.New("permission denied")}, "80", "120", false, false, statusCodeUnknown, "permission denied"},
+		{"Service without cpu", new(testProcessHandler), "80", "120", false, false, statusCodeUnknown, statusTextUnknown},
+	}
+
+	for _, i := range testList {
+		options := ProcessCheckOptions{
+			Name:       testProcessGoodName,
+			CheckType:  "cpu",
+			MetricName: "cpu",
+			Warning:    i.warning,
+			Critical:   i.critical,
+			PerProcess: i.perProcess,
+			OfCPUs:     i.ofCPUs,
+		}
+
+		pc := ProcessCheck{ProcessName: testProcessGoodName, ProcessCheckHandler: i.service}
+
+		msg, code := checkProcessCPUWithCPUs(pc, options, 4).Output()
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	options := ProcessCheckOptions{Name: testProcessGoodName, CheckType: "cpu", MetricName: "cpu", Warning: "80"}
+	if msg, code := checkProcessWithService(options, testCPUProcessHandler{processes: workers[1:]}).Output(); code != statusCodeOK {
+		t.Errorf("checkProcessWithService() should run the cpu check, returned %d: %s", code, msg)
+	}
+
+	options.Interval = -time.Second
+	if msg, code := checkProcessCmd(options, checkProcessWithService, testCPUProcessHandler{processes: workers}).Output(); code != statusCodeCritical || !strings.Contains(msg, "may not be negative") {
+		t.Errorf("checkProcessCmd() should not allow a negative interval, returned %d: %s", code, msg)
+	}
+}
@@ -0,0 +1,136 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// processThreadsService is implemented by a ProcessService that can
+// also count the threads of each of the named processes.
+type processThreadsService interface {
+	ProcessThreads(string) ([]ProcessInfo, error)
+}
+
+// parseStatThreads returns the number of threads of a process, field
+// 20 of /proc/<pid>/stat. As with the start time, the fields are
+// counted from the last closing parenthesis of the process name.
+func parseStatThreads(data string) (int, error) {
+	const threadsField = 20
+
+	nameEnd := strings.LastIndex(data, ")")
+	if nameEnd < 0 {
+		return 0, fmt.Errorf("Could not parse process stat")
+	}
+
+	fields := strings.Fields(data[nameEnd+1:])
+	if len(fields) < threadsField-2 {
+		return 0, fmt.Errorf("Could not parse process threads, too few stat fields")
+	}
+
+	threads, err := strconv.Atoi(fields[threadsField-3])
+	if err != nil {
+		return 0, fmt.Errorf("Could not parse process threads: %s", err)
+	}
+
+	return threads, nil
+}
+
+// processThreads returns the processes matching name with their
+// thread counts.
+func processThreads(inspector ProcessInspector, name string) ([]ProcessInfo, error) {
+	processes, err := inspector.List(name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(processes) == 0 {
+		return nil, errProcessNotRunning
+	}
+
+	return processes, nil
+}
+
+// checkThreads counts the threads of the named processes and compares
+// the total against the options.Warning and options.Critical
+// thresholds, or with options.PerProcess the count of each process,
+// to catch a process leaking threads. A process that is not running
+// is UNKNOWN, as there are no threads to count.
+func checkThreads(processCheck ProcessCheck, options ProcessCheckOptions) CheckResult {
+	threadsService, ok := processCheck.ProcessCheckHandler.(processThreadsService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Process threads are not available from the process service")
+	}
+
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkProcessName, err.Error())
+	}
+
+	processes, err := threadsService.ProcessThreads(processCheck.ProcessName)
+
+	switch {
+	case err == errProcessNotRunning:
+		return processCheck.notRunningResult(StateUnknown, fmt.Sprintf("Process %s is not running", processCheck.ProcessName))
+	case err != nil:
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not count threads of process %s: %s", processCheck.ProcessName, err), err)
+	}
+
+	if !options.PerProcess {
+		total := 0
+		for _, process := range processes {
+			total += process.Threads
+		}
+
+		state, tripped := thresholds.Status(float64(total))
+
+		checkInfo := fmt.Sprintf("%d threads in %d instances of %s", total, len(processes), processCheck.ProcessName)
+		if state != StateOK {
+			checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+		}
+
+		return NewCheckResult(checkProcessName, state, checkInfo, thresholds.Metric(PerfData{
+			Label: options.MetricName,
+			Value: float64(total),
+			Min:   "0",
+		}))
+	}
+
+	// Each process is checked on its own, reporting the processes
+	// outside the thresholds and the largest count as perfdata, as the
+	// PIDs change with each restart.
+	state := StateOK
+	most := 0
+	var tripped []string
+
+	for _, process := range processes {
+		if process.Threads > most {
+			most = process.Threads
+		}
+
+		processState, processRange := thresholds.Status(float64(process.Threads))
+		if processState == StateOK {
+			continue
+		}
+
+		if processState == StateCritical || state == StateOK {
+			state = processState
+		}
+
+		tripped = append(tripped, fmt.Sprintf("process %d has %d threads (expected %s)",
+			process.PID, process.Threads, processRange.Expected()))
+	}
+
+	checkInfo := fmt.Sprintf("The %d instances of %s have at most %d threads", len(processes), processCheck.ProcessName, most)
+	if len(tripped) > 0 {
+		checkInfo = fmt.Sprintf("%d of %d instances of %s have too many threads: %s",
+			len(tripped), len(processes), processCheck.ProcessName, strings.Join(tripped, ", "))
+	}
+
+	return NewCheckResult(checkProcessName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: options.MetricName,
+		Value: float64(most),
+		Min:   "0",
+	}))
+}
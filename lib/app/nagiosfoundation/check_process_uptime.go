@@ -0,0 +1,159 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicksPerSecond is the number of clock ticks per second used
+// by the process start time in /proc/<pid>/stat, the value returned
+// by sysconf(_SC_CLK_TCK). The kernel reports times to user space in
+// USER_HZ, which is 100 on every architecture Linux supports, and
+// reading it with sysconf would require cgo.
+const clockTicksPerSecond = 100
+
+// processUptimeService is implemented by a ProcessService that can
+// also determine how long each of the named processes has been
+// running.
+type processUptimeService interface {
+	ProcessAges(string) ([]time.Duration, error)
+}
+
+// parseStatStartTime returns the start time of a process in clock
+// ticks since boot, field 22 of /proc/<pid>/stat. The process name in
+// field 2 is in parentheses and may contain spaces and parentheses,
+// so the fields are counted from the last closing parenthesis, which
+// is followed by field 3.
+func parseStatStartTime(data string) (uint64, error) {
+	const startTimeField = 22
+
+	nameEnd := strings.LastIndex(data, ")")
+	if nameEnd < 0 {
+		return 0, fmt.Errorf("Could not parse process stat")
+	}
+
+	fields := strings.Fields(data[nameEnd+1:])
+	if len(fields) < startTimeField-2 {
+		return 0, fmt.Errorf("Could not parse process start time, too few stat fields")
+	}
+
+	startTime, err := strconv.ParseUint(fields[startTimeField-3], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Could not parse process start time: %s", err)
+	}
+
+	return startTime, nil
+}
+
+// parseUptime returns the seconds since boot, the first field of
+// /proc/uptime.
+func parseUptime(data string) (float64, error) {
+	fields := strings.Fields(data)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("Could not parse uptime")
+	}
+
+	uptime, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Could not parse uptime: %s", err)
+	}
+
+	return uptime, nil
+}
+
+// processAges returns how long each process matching name has been
+// running at now. Processes without a start time, such as those the
+// user may not inspect, are left out.
+func processAges(inspector ProcessInspector, name string, now time.Time) ([]time.Duration, error) {
+	processes, err := inspector.List(name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(processes) == 0 {
+		return nil, errProcessNotRunning
+	}
+
+	ages := make([]time.Duration, 0, len(processes))
+
+	for _, process := range processes {
+		if process.StartTime.IsZero() {
+			continue
+		}
+
+		age := now.Sub(process.StartTime)
+		if age < 0 {
+			age = 0
+		}
+
+		ages = append(ages, age)
+	}
+
+	if len(ages) == 0 {
+		return nil, fmt.Errorf("The start times of the %d processes are not available", len(processes))
+	}
+
+	return ages, nil
+}
+
+// checkProcessUptime selects the oldest or youngest of the named
+// processes, using options.Select, and compares how long it has been
+// running in seconds against the options.Warning and
+// options.Critical thresholds.
+func checkProcessUptime(processCheck ProcessCheck, options ProcessCheckOptions) CheckResult {
+	uptimeService, ok := processCheck.ProcessCheckHandler.(processUptimeService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Process start times are not available from the process service")
+	}
+
+	selection := strings.ToLower(options.Select)
+	if selection == "" {
+		selection = "oldest"
+	}
+
+	if selection != "oldest" && selection != "youngest" {
+		return UnknownResult(checkProcessName,
+			fmt.Sprintf("Invalid selection (%s). Only \"oldest\" and \"youngest\" are supported.", options.Select))
+	}
+
+	ages, err := uptimeService.ProcessAges(processCheck.ProcessName)
+
+	switch {
+	case err == errProcessNotRunning:
+		return processCheck.notRunningResult(StateCritical, fmt.Sprintf("Process %s is not running", processCheck.ProcessName))
+	case err != nil:
+		return errorResult(checkProcessName,
+			fmt.Sprintf("Could not determine uptime of process %s: %s", processCheck.ProcessName, err), err)
+	}
+
+	selected := ages[0]
+	for _, age := range ages[1:] {
+		if (selection == "oldest") == (age > selected) {
+			selected = age
+		}
+	}
+
+	seconds := int64(selected.Seconds())
+
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkProcessName, err.Error())
+	}
+
+	state, tripped := thresholds.Status(float64(seconds))
+
+	checkInfo := fmt.Sprintf("The %s of %d instances of %s has been running %ds",
+		selection, len(ages), processCheck.ProcessName, seconds)
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+	}
+
+	return NewCheckResult(checkProcessName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: options.MetricName,
+		Value: float64(seconds),
+		UOM:   "s",
+		Min:   "0",
+	}))
+}
@@ -3,8 +3,11 @@
 package nagiosfoundation
 
 import (
+	"errors"
+	"os"
 	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
@@ -19,26 +22,154 @@ func uint16SliceToString(array []uint16) string {
 	return syscall.UTF16ToString(array[:end])
 }
 
-func isProcessRunningOsConstrained(name string) bool {
-	retval := false
+// processQueryLimitedInformation is the access right needed to read
+// the times and memory of a process, PROCESS_QUERY_LIMITED_INFORMATION.
+const processQueryLimitedInformation = 0x1000
+
+var procGetProcessMemoryInfo = syscall.NewLazyDLL("psapi.dll").NewProc("GetProcessMemoryInfo")
+
+// processMemoryCounters is PROCESS_MEMORY_COUNTERS.
+type processMemoryCounters struct {
+	cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// toolhelpInspector is the ProcessInspector using a Toolhelp snapshot
+// of the processes on Windows. Process names match without regard to
+// case.
+type toolhelpInspector struct {
+	regex bool
+}
+
+// processDetails returns the working set size, the Windows resident
+// memory, and the start time of a process. Both are zero for a process
+// the user may not open, such as a system process when not elevated.
+func processDetails(pid uint32) (uint64, time.Time) {
+	handle, err := windows.OpenProcess(processQueryLimitedInformation, false, pid)
+	if err != nil {
+		debugLog.Printf("Could not open process %d for its memory and start time: %s", pid, err)
+		return 0, time.Time{}
+	}
+	defer windows.CloseHandle(handle)
+
+	var rss uint64
+
+	var counters processMemoryCounters
+	counters.cb = uint32(unsafe.Sizeof(counters))
+
+	if ret, _, _ := procGetProcessMemoryInfo.Call(uintptr(handle), uintptr(unsafe.Pointer(&counters)), uintptr(counters.cb)); ret != 0 {
+		rss = uint64(counters.WorkingSetSize)
+	}
+
+	var startTime time.Time
+
+	var creation, exit, kernel, user windows.Filetime
+	if windows.GetProcessTimes(handle, &creation, &exit, &kernel, &user) == nil {
+		startTime = time.Unix(0, creation.Nanoseconds())
+	}
+
+	return rss, startTime
+}
+
+func (i toolhelpInspector) List(name string, limit int) ([]ProcessInfo, error) {
+	matchName, err := newTextMatcher(name, i.regex, strings.EqualFold)
+	if err != nil {
+		return nil, err
+	}
 
 	handle, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
-	if err == nil {
-		defer windows.CloseHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(handle)
+
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+
+	var processes []ProcessInfo
 
-		var entry windows.ProcessEntry32
-		entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = windows.Process32First(handle, &entry); err == nil; err = windows.Process32Next(handle, &entry) {
+		exeName := uint16SliceToString(entry.ExeFile[0:len(entry.ExeFile)])
+		if !matchName(exeName) {
+			continue
+		}
 
-		err = windows.Process32First(handle, &entry)
+		rss, startTime := processDetails(entry.ProcessID)
 
-		for err == nil && retval == false {
-			exeName := uint16SliceToString(entry.ExeFile[0:len(entry.ExeFile)])
-			//fmt.Println("Entry:", exeName, "| Match:", name)
-			retval = strings.EqualFold(name, exeName)
+		processes = append(processes, ProcessInfo{
+			PID:       int(entry.ProcessID),
+			Name:      exeName,
+			RSS:       rss,
+			StartTime: startTime,
+			Threads:   int(entry.Threads),
+		})
 
-			err = windows.Process32Next(handle, &entry)
+		if limit > 0 && len(processes) >= limit {
+			break
 		}
 	}
 
-	return retval
+	return processes, nil
+}
+
+func newProcessInspectorOsConstrained(p processHandler) ProcessInspector {
+	return toolhelpInspector{regex: p.regex}
+}
+
+// getProcessesRunningOsConstrained lists each of the named processes
+// with the inspector, which takes a snapshot of the processes for each
+// name.
+func getProcessesRunningOsConstrained(p processHandler, names []string) (map[string]bool, error) {
+	running := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		processes, err := p.inspector.List(name, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		running[name] = len(processes) > 0
+	}
+
+	return running, nil
+}
+
+func getWritableExecutableMappingsOsConstrained(p processHandler, name string) ([]memoryMapping, error) {
+	return nil, errors.New("Memory mapping checks are not supported on Windows")
+}
+
+func getLogAgeOsConstrained(p processHandler, name, logPath string) (time.Duration, error) {
+	return 0, errors.New("Log file checks are not supported on Windows")
+}
+
+func getCgroupCountsOsConstrained(p processHandler, name string) (map[string]int, error) {
+	return nil, errors.New("Cgroup checks are not supported on Windows")
+}
+
+func getProcessFdsOsConstrained(p processHandler, name string) ([]processFds, error) {
+	return nil, errors.New("File descriptor checks are not supported on Windows")
+}
+
+func getProcessZombiesOsConstrained(p processHandler, name string) ([]processZombie, error) {
+	return nil, errors.New("Zombie process checks are not supported on Windows")
+}
+
+func getProcessListeningPortsOsConstrained(p processHandler, name string) ([]int, error) {
+	return nil, errors.New("Listening port checks are not supported on Windows")
+}
+
+func getProcessCPUOsConstrained(p processHandler, name string, interval time.Duration) ([]processCPU, error) {
+	return nil, errors.New("Process CPU checks are not supported on Windows")
+}
+
+func getPidUIDWithHandler(stat func(string) (os.FileInfo, error), procRoot string, pid int) (string, error) {
+	return "", errors.New("Process owner checks are not supported on Windows")
 }
@@ -0,0 +1,167 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// processZombie is a process in the zombie state, one that has exited
+// but has not been reaped by its parent.
+type processZombie struct {
+	pid  int
+	ppid int
+}
+
+// processZombiesService is implemented by a ProcessService that can
+// also list the zombie processes, those of the named parent processes
+// or every zombie when no name is given.
+type processZombiesService interface {
+	ProcessZombies(string) ([]processZombie, error)
+}
+
+// parseStatState returns the state character and the parent PID of a
+// process, fields 3 and 4 of /proc/<pid>/stat. As with the start time,
+// the fields are counted from the last closing parenthesis of the
+// process name.
+func parseStatState(data string) (string, int, error) {
+	nameEnd := strings.LastIndex(data, ")")
+	if nameEnd < 0 {
+		return "", 0, fmt.Errorf("Could not parse process stat")
+	}
+
+	fields := strings.Fields(data[nameEnd+1:])
+	if len(fields) < 2 {
+		return "", 0, fmt.Errorf("Could not parse process state, too few stat fields")
+	}
+
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("Could not parse process parent: %s", err)
+	}
+
+	return fields[0], ppid, nil
+}
+
+// getProcessZombiesWithHandlers reads the state of every process from
+// /proc/<pid>/stat and returns the zombies, the processes in the Z
+// state. When name is given only the zombies whose parent matches
+// name are returned, with the parent scoped by the settings of the
+// handlers, and a parent that is not running is errProcessNotRunning.
+// A process exiting while it is read is skipped.
+func getProcessZombiesWithHandlers(svc processByNameHandlers, name string) ([]processZombie, error) {
+	var parents map[int]bool
+
+	if name != "" {
+		parentEntries, err := getProcessesByNameWithHandlers(svc, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(parentEntries) == 0 {
+			return nil, errProcessNotRunning
+		}
+
+		parents = make(map[int]bool, len(parentEntries))
+		for _, parentEntry := range parentEntries {
+			pid, _ := strconv.Atoi(parentEntry.Name())
+			parents[pid] = true
+		}
+	}
+
+	dir, err := svc.open(svc.procDir())
+	if err != nil {
+		return nil, err
+	}
+	defer svc.close(dir)
+
+	procEntries, err := svc.readDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	zombies := make([]processZombie, 0)
+
+	for _, procEntry := range procEntries {
+		pid, err := strconv.Atoi(procEntry.Name())
+		if err != nil || !procEntry.IsDir() {
+			continue
+		}
+
+		data, err := svc.readFile(fmt.Sprintf("%s/%d/stat", svc.procDir(), pid))
+		if processExited(err) {
+			debugLog.Printf("Skipping process %d, it has exited", pid)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("Could not read the state of process %d: %s", pid, err)
+		}
+
+		state, ppid, err := parseStatState(string(data))
+		if err != nil {
+			return nil, err
+		}
+
+		if state == "Z" && (parents == nil || parents[ppid]) {
+			zombies = append(zombies, processZombie{pid: pid, ppid: ppid})
+		}
+	}
+
+	return zombies, nil
+}
+
+// defaultZombieWarning alerts on any zombie when no thresholds are
+// given.
+const defaultZombieWarning = "0"
+
+// checkZombies counts the zombie processes, those of the parent
+// processes matching the name or every zombie when no name is given,
+// and compares the count against the options.Warning threshold,
+// defaulting to 0, and the options.Critical threshold. When a
+// threshold trips the zombies are named with their parents, which
+// are the processes failing to reap them.
+func checkZombies(processCheck ProcessCheck, options ProcessCheckOptions) CheckResult {
+	zombiesService, ok := processCheck.ProcessCheckHandler.(processZombiesService)
+	if !ok {
+		return UnknownResult(checkProcessName, "Zombie processes are not available from the process service")
+	}
+
+	warning := options.Warning
+	if warning == "" {
+		warning = defaultZombieWarning
+	}
+
+	thresholds, err := ParseThresholds(warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkProcessName, err.Error())
+	}
+
+	zombies, err := zombiesService.ProcessZombies(processCheck.ProcessName)
+
+	switch {
+	case err == errProcessNotRunning:
+		return processCheck.notRunningResult(StateUnknown, fmt.Sprintf("Process %s is not running", processCheck.ProcessName))
+	case err != nil:
+		return errorResult(checkProcessName, fmt.Sprintf("Could not read the process states: %s", err), err)
+	}
+
+	checkInfo := fmt.Sprintf("%d zombie processes", len(zombies))
+	if processCheck.ProcessName != "" {
+		checkInfo += fmt.Sprintf(" of %s", processCheck.ProcessName)
+	}
+
+	state, tripped := thresholds.Status(float64(len(zombies)))
+	if state != StateOK {
+		pids := make([]string, len(zombies))
+		for i, zombie := range zombies {
+			pids[i] = fmt.Sprintf("%d (parent %d)", zombie.pid, zombie.ppid)
+		}
+
+		checkInfo += fmt.Sprintf(" (expected %s): %s", tripped.Expected(), strings.Join(pids, ", "))
+	}
+
+	return NewCheckResult(checkProcessName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: options.MetricName,
+		Value: float64(len(zombies)),
+		Min:   "0",
+	}))
+}
@@ -0,0 +1,87 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/memory"
+)
+
+const checkSwapName = "CheckSwap"
+
+// runSwapCheck performs the check of CheckSwapWithHandler() and returns
+// its result.
+func runSwapCheck(warning, critical, metricName string, usageHandler func() (uint64, uint64, error)) CheckResult {
+	if metricName == "" {
+		metricName = "swap"
+	}
+
+	relativeThresholds, err := ParseRelativeThresholds(warning, critical)
+	if err != nil {
+		return UnknownResult(checkSwapName, err.Error())
+	}
+
+	if usageHandler == nil {
+		return UnknownResult(checkSwapName, "No swap usage service")
+	}
+
+	used, total, err := usageHandler()
+	if err != nil {
+		return UnknownResult(checkSwapName, fmt.Sprintf("Could not determine swap used: %s", err))
+	}
+
+	if total == 0 {
+		return OKResult(checkSwapName, "No swap configured")
+	}
+
+	thresholds, percentThresholds := diskThresholds(relativeThresholds, total)
+	state, _ := thresholds.Status(float64(used))
+
+	usedPercentage := float64(used) / float64(total) * 100
+
+	return NewCheckResult(checkSwapName, state,
+		fmt.Sprintf("Swap used is %.2f%% (%d of %d bytes)", usedPercentage, used, total),
+		thresholds.Metric(PerfData{
+			Label: metricName + "_used",
+			Value: float64(used),
+			UOM:   "B",
+			Min:   "0",
+			Max:   strconv.FormatUint(total, 10),
+		}),
+		percentThresholds.Metric(PerfData{
+			Label: metricName + "_used_pct",
+			Value: math.Round(usedPercentage*100) / 100,
+			UOM:   "%",
+			Min:   "0",
+			Max:   "100",
+		}),
+		PerfData{Label: metricName + "_total", Value: float64(total), UOM: "B", Min: "0"},
+	)
+}
+
+// CheckSwapWithHandler determines the swap used with the handler,
+// returning the used and total swap in bytes, and emits a critical
+// response if it's over the critical threshold, a warning response if
+// it's over the warning threshold and a good response otherwise. As
+// with CheckDisk the thresholds are Nagios ranges of a percentage such
+// as "90%" or an amount such as "2G". A host without swap configured
+// emits a good response saying so.
+func CheckSwapWithHandler(warning, critical, metricName string, usageHandler func() (uint64, uint64, error)) (string, int) {
+	return runSwapCheck(warning, critical, metricName, usageHandler).Output()
+}
+
+// RunSwapCheck performs the swap check of CheckSwap() and returns its
+// result, without output.
+func RunSwapCheck(warning, critical, metricName string) CheckResult {
+	return runSwapCheck(warning, critical, metricName, memory.GetSwapUsage)
+}
+
+// CheckSwap executes CheckSwapWithHandler(), passing it a handler
+// reading the swap used from /proc/meminfo on Linux and the page file
+// on Windows.
+//
+// Returns are those of CheckSwapWithHandler()
+func CheckSwap(warning, critical, metricName string) (string, int) {
+	return RunSwapCheck(warning, critical, metricName).Output()
+}
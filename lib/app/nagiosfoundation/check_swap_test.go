@@ -0,0 +1,52 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckSwap(t *testing.T) {
+	usage := func(used, total uint64) func() (uint64, uint64, error) {
+		return func() (uint64, uint64, error) {
+			return used, total, nil
+		}
+	}
+
+	type testItem struct {
+		description  string
+		warning      string
+		critical     string
+		usage        func() (uint64, uint64, error)
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testList := []testItem{
+		{"OK by percent", "85%", "95%", usage(500, 1000), statusCodeOK,
+			"CheckSwap OK - Swap used is 50.00% (500 of 1000 bytes) | swap_used=500B;850;950;0;1000 swap_used_pct=50%;85;95;0;100 swap_total=1000B;;;0"},
+		{"Warning by percent", "85%", "95%", usage(900, 1000), statusCodeWarning, "swap_used_pct=90%;85;95;0;100"},
+		{"Critical by size", "1K", "2K", usage(3072, 4096), statusCodeCritical, "swap_used=3072B;1024;2048;0;4096 swap_used_pct=75%;25;50;0;100"},
+		{"No swap", "85%", "95%", usage(0, 0), statusCodeOK, "CheckSwap OK - No swap configured"},
+		{"Invalid threshold", "85%", "lots", usage(1, 2), statusCodeUnknown, "Invalid range \"lots\""},
+		{"Warning by size", "512M", "1G", usage(768<<20, 4<<30), statusCodeWarning, "swap_used=805306368B;536870912;1073741824;0;4294967296 swap_used_pct=18.75%;12.5;25;0;100"},
+		{"Usage error", "85%", "95%", func() (uint64, uint64, error) { return 0, 0, errors.New("permission denied") }, statusCodeUnknown, "Could not determine swap used: permission denied"},
+		{"No usage service", "85%", "95%", nil, statusCodeUnknown, "No swap usage service"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckSwapWithHandler(i.warning, i.critical, "", i.usage)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+
+	if msg, _ := CheckSwapWithHandler("85%", "95%", "pagefile", usage(1, 2)); !strings.Contains(msg, "| pagefile_used=") {
+		t.Errorf("CheckSwapWithHandler() should label the perfdata with the metric name: %s", msg)
+	}
+}
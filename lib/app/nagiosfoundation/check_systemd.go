@@ -0,0 +1,159 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const checkSystemdName = "CheckSystemd"
+
+// systemdUnitState is the state of a unit as shown by systemctl.
+type systemdUnitState struct {
+	loadState   string
+	activeState string
+	subState    string
+}
+
+// parseSystemdShow parses the key=value lines output by systemctl
+// show into the unit state. Keys other than the states are ignored.
+func parseSystemdShow(data string) systemdUnitState {
+	var state systemdUnitState
+
+	for _, line := range strings.Split(data, "\n") {
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+
+		value := strings.TrimSpace(line[i+1:])
+
+		switch line[:i] {
+		case "LoadState":
+			state.loadState = value
+		case "ActiveState":
+			state.activeState = value
+		case "SubState":
+			state.subState = value
+		}
+	}
+
+	return state
+}
+
+// parseSystemdUnitList returns the unit names, the first field of
+// each line, output by systemctl list-units with --plain and
+// --no-legend.
+func parseSystemdUnitList(data string) []string {
+	var units []string
+
+	for _, line := range strings.Split(data, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			units = append(units, fields[0])
+		}
+	}
+
+	return units
+}
+
+// runSystemctl runs systemctl with the arguments and returns its
+// standard output. When systemctl fails, the error is what it wrote
+// to standard error, such as the system not being booted with
+// systemd.
+func runSystemctl(args ...string) ([]byte, error) {
+	out, err := exec.Command("systemctl", args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		err = fmt.Errorf("%s", strings.Replace(strings.TrimSpace(string(exitErr.Stderr)), "\n", " ", -1))
+	}
+
+	return out, err
+}
+
+func checkSystemdFailedUnits(systemctl func(...string) ([]byte, error)) CheckResult {
+	out, err := systemctl("list-units", "--state=failed", "--plain", "--no-legend", "--no-pager")
+	if err != nil {
+		return UnknownResult(checkSystemdName, fmt.Sprintf("Could not list the failed units: %s", err))
+	}
+
+	units := parseSystemdUnitList(string(out))
+	metric := PerfData{Label: "failed_units", Value: float64(len(units)), Min: "0"}
+
+	if len(units) > 0 {
+		return CriticalResult(checkSystemdName,
+			fmt.Sprintf("%d units have failed: %s", len(units), strings.Join(units, ", ")), metric)
+	}
+
+	return OKResult(checkSystemdName, "No units have failed", metric)
+}
+
+// runSystemdCheck performs the check of CheckSystemdWithHandler() and
+// returns its result.
+func runSystemdCheck(name, expectedState string, systemctl func(...string) ([]byte, error)) CheckResult {
+	if name == "" {
+		return checkSystemdFailedUnits(systemctl)
+	}
+
+	if expectedState == "" {
+		expectedState = "active"
+	}
+
+	expected := strings.SplitN(strings.ToLower(expectedState), "/", 2)
+
+	out, err := systemctl("show", name, "--property=LoadState,ActiveState,SubState", "--no-pager")
+	if err != nil {
+		return UnknownResult(checkSystemdName, fmt.Sprintf("Could not read the state of unit %s: %s", name, err))
+	}
+
+	unit := parseSystemdShow(string(out))
+
+	switch {
+	case unit.activeState == "":
+		return UnknownResult(checkSystemdName, fmt.Sprintf("Could not read the state of unit %s", name))
+	case unit.loadState == "not-found":
+		return UnknownResult(checkSystemdName, fmt.Sprintf("Unit %s not found", name))
+	}
+
+	desc := fmt.Sprintf("Unit %s is %s/%s", name, unit.activeState, unit.subState)
+
+	if unit.activeState == expected[0] && (len(expected) == 1 || unit.subState == expected[1]) {
+		return OKResult(checkSystemdName, desc)
+	}
+
+	desc += fmt.Sprintf(" (expected %s)", strings.Join(expected, "/"))
+
+	if unit.activeState == "activating" && expected[0] == "active" {
+		return WarningResult(checkSystemdName, desc)
+	}
+
+	return CriticalResult(checkSystemdName, desc)
+}
+
+// CheckSystemdWithHandler checks the state of a systemd unit, read
+// with systemctl show, against the expected state. The expected
+// state is an ActiveState such as "active", or an ActiveState and
+// SubState such as "active/running". A unit in the expected state
+// emits a good response. A unit still "activating" towards an
+// expected "active" state emits a warning response, as it may yet
+// start. Any other state, such as "failed", emits a critical
+// response. A unit that is not found emits an unknown response.
+//
+// Without a name, the check emits a critical response listing the
+// failed units if there are any, otherwise a good response. The
+// number of failed units is output as perfdata.
+func CheckSystemdWithHandler(name, expectedState string, systemctl func(...string) ([]byte, error)) (string, int) {
+	return runSystemdCheck(name, expectedState, systemctl).Output()
+}
+
+// RunSystemdCheck performs the systemd check of CheckSystemd() and
+// returns its result, without output.
+func RunSystemdCheck(name, expectedState string) CheckResult {
+	return runSystemdCheck(name, expectedState, runSystemctl)
+}
+
+// CheckSystemd executes CheckSystemdWithHandler(), passing it a
+// handler running systemctl.
+//
+// Returns are those of CheckSystemdWithHandler()
+func CheckSystemd(name, expectedState string) (string, int) {
+	return RunSystemdCheck(name, expectedState).Output()
+}
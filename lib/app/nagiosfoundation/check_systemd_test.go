@@ -0,0 +1,67 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckSystemd(t *testing.T) {
+	show := func(activeState, subState string) func(...string) ([]byte, error) {
+		return func(args ...string) ([]byte, error) {
+			return []byte("LoadState=loaded\nActiveState=" + activeState + "\nSubState=" + subState + "\n"), nil
+		}
+	}
+
+	type testItem struct {
+		description   string
+		name          string
+		expectedState string
+		systemctl     func(...string) ([]byte, error)
+		expectedCode  int
+		expectedMsg   string
+	}
+
+	testList := []testItem{
+		{"Active", "nginx", "", show("active", "running"), statusCodeOK, "CheckSystemd OK - Unit nginx is active/running"},
+		{"Active and running", "nginx", "active/running", show("active", "running"), statusCodeOK, "is active/running"},
+		{"Active but exited", "nginx", "active/running", show("active", "exited"), statusCodeCritical, "is active/exited (expected active/running)"},
+		{"Activating", "nginx", "active", show("activating", "start"), statusCodeWarning, "is activating/start (expected active)"},
+		{"Failed", "nginx", "active", show("failed", "failed"), statusCodeCritical, "is failed/failed (expected active)"},
+		{"Inactive expected", "cups", "inactive", show("inactive", "dead"), statusCodeOK, "is inactive/dead"},
+		{"Not found", "nosuch", "active", func(...string) ([]byte, error) {
+			return []byte("LoadState=not-found\nActiveState=inactive\nSubState=dead\n"), nil
+		}, statusCodeUnknown, "Unit nosuch not found"},
+		{"No state", "nginx", "active", func(...string) ([]byte, error) {
+			return []byte(""), nil
+		}, statusCodeUnknown, "Could not read the state of unit nginx"},
+		{"No systemctl", "nginx", "active", func(...string) ([]byte, error) {
+			return nil, errors.New("executable file not found")
+		}, statusCodeUnknown, "Could not read the state of unit nginx: executable file not found"},
+		{"No failed units", "", "", func(args ...string) ([]byte, error) {
+			return []byte(""), nil
+		}, statusCodeOK, "No units have failed | failed_units=0;;;0"},
+		{"Failed units", "", "", func(args ...string) ([]byte, error) {
+			if args[0] != "list-units" {
+				t.Errorf("Failed units should be listed, not %s", args[0])
+			}
+
+			return []byte("backup.service loaded failed failed Nightly backup\nmnt-nfs.mount loaded failed failed /mnt/nfs\n"), nil
+		}, statusCodeCritical, "2 units have failed: backup.service, mnt-nfs.mount | failed_units=2;;;0"},
+		{"Failed units error", "", "", func(...string) ([]byte, error) {
+			return nil, errors.New("exit status 1")
+		}, statusCodeUnknown, "Could not list the failed units"},
+	}
+
+	for _, i := range testList {
+		msg, code := CheckSystemdWithHandler(i.name, i.expectedState, i.systemctl)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const checkTCPName = "CheckTcp"
+
+// maxTCPResponseLength is the most read from the response while
+// looking for the expected string.
+const maxTCPResponseLength = 64 * 1024
+
+// tcpSendEscapes are the escapes accepted in the string sent so that
+// line based protocols can be given their line endings.
+var tcpSendEscapes = strings.NewReplacer(`\r`, "\r", `\n`, "\n", `\t`, "\t", `\\`, `\`)
+
+// readTCPResponse reads from the connection until the response
+// contains expect, the connection is closed or the read fails, such
+// as when the deadline passes.
+func readTCPResponse(conn net.Conn, expect string) (string, error) {
+	var response []byte
+	buffer := make([]byte, 4096)
+
+	for len(response) < maxTCPResponseLength {
+		n, err := conn.Read(buffer)
+		response = append(response, buffer[:n]...)
+
+		if strings.Contains(string(response), expect) {
+			return string(response), nil
+		}
+
+		if err != nil {
+			return string(response), err
+		}
+	}
+
+	return string(response), nil
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+
+	return ok && netErr.Timeout()
+}
+
+// TCPCheckOptions are the options of CheckTCPWithOptions().
+type TCPCheckOptions struct {
+	// The host to connect to, a host name or an IPv4 or IPv6 address.
+	Host string
+
+	// The port to connect to.
+	Port int
+
+	// The seconds to wait for the connection and response.
+	Timeout int
+
+	// The string written once connected, which may contain \r, \n and
+	// \t escapes, and the string the response must contain.
+	Send   string
+	Expect string
+
+	// The IP version connected with, 4 or 6, or 0 for either.
+	IPVersion int
+
+	// The addresses of a host resolving to several that must accept
+	// the connection, "any" for one of them, the default, or "all".
+	Addresses string
+
+	// The state to issue when the connection or response does not
+	// complete in time: "unknown", "critical" or "warning". Defaults to
+	// critical, as for a connection that fails.
+	TimeoutExit string
+}
+
+// tcpAddress returns the address connected to for ip and port, such
+// as "[2001:db8::1]:22", named after the host when it is not the
+// address itself, such as "db01:22 (2001:db8::1)".
+func tcpAddress(host string, ip net.IP, port int) string {
+	address := net.JoinHostPort(ip.String(), strconv.Itoa(port))
+	if net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")) != nil {
+		return address
+	}
+
+	return fmt.Sprintf("%s (%s)", net.JoinHostPort(host, strconv.Itoa(port)), ip)
+}
+
+// probeTCP connects to the address, sends and expects the strings of
+// the options and returns the description of the response expected,
+// or the reason the address failed, of the kind ErrTimeout when it did
+// not complete in time.
+func probeTCP(network, address, desc string, options TCPCheckOptions, deadline time.Time,
+	dial func(string, string, time.Duration) (net.Conn, error)) (string, error) {
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return "", &CheckError{Kind: ErrTimeout, Err: fmt.Errorf("Connection to %s timed out after %ds", desc, options.Timeout)}
+	}
+
+	conn, err := dial(network, address, remaining)
+	if err != nil {
+		if isTimeout(err) {
+			return "", &CheckError{Kind: ErrTimeout, Err: fmt.Errorf("Connection to %s timed out after %ds", desc, options.Timeout)}
+		}
+
+		return "", fmt.Errorf("Connection to %s failed: %s", desc, err)
+	}
+	defer conn.Close()
+
+	if options.Send != "" || options.Expect != "" {
+		conn.SetDeadline(deadline)
+	}
+
+	if options.Send != "" {
+		if _, err := conn.Write([]byte(tcpSendEscapes.Replace(options.Send))); err != nil {
+			return "", fmt.Errorf("Sending to %s failed: %s", desc, err)
+		}
+	}
+
+	if options.Expect == "" {
+		return "", nil
+	}
+
+	response, err := readTCPResponse(conn, options.Expect)
+
+	switch {
+	case strings.Contains(response, options.Expect):
+		return fmt.Sprintf(" and the response contains %q", options.Expect), nil
+	case isTimeout(err):
+		return "", &CheckError{Kind: ErrTimeout, Err: fmt.Errorf("No response from %s containing %q within %ds", desc, options.Expect, options.Timeout)}
+	}
+
+	return "", fmt.Errorf("Response from %s does not contain %q", desc, options.Expect)
+}
+
+// runTCPCheck performs the check of CheckTCPWithHandlers() and returns
+// its result.
+func runTCPCheck(options TCPCheckOptions, lookup func(string) ([]net.IP, error),
+	dial func(string, string, time.Duration) (net.Conn, error)) CheckResult {
+	if options.Host == "" {
+		return UnknownResult(checkTCPName, "A host must be specified.")
+	}
+
+	if options.Port < 1 || options.Port > 65535 {
+		return UnknownResult(checkTCPName, fmt.Sprintf("Invalid port (%d). The port must be from 1 to 65535.", options.Port))
+	}
+
+	if options.Timeout < 1 {
+		return UnknownResult(checkTCPName, fmt.Sprintf("Invalid timeout (%d). The timeout must be at least 1 second.", options.Timeout))
+	}
+
+	if err := checkIPVersion(options.IPVersion); err != nil {
+		return UnknownResult(checkTCPName, err.Error())
+	}
+
+	mode, err := parseAddressesMode(options.Addresses)
+	if err != nil {
+		return UnknownResult(checkTCPName, err.Error())
+	}
+
+	if err := checkTimeoutExit(options.TimeoutExit); err != nil {
+		return UnknownResult(checkTCPName, err.Error())
+	}
+
+	// The lookup counts against the timeout, but not against the
+	// time taken to connect.
+	deadline := time.Now().Add(time.Duration(options.Timeout) * time.Second)
+
+	ips, dnsTime, err := resolveAddresses(options.Host, options.IPVersion, lookup)
+	if err != nil {
+		return UnknownResult(checkTCPName, fmt.Sprintf("Could not resolve host %s: %s", options.Host, err))
+	}
+
+	network := "tcp"
+	if options.IPVersion != 0 {
+		network = fmt.Sprintf("tcp%d", options.IPVersion)
+	}
+
+	start := time.Now()
+
+	var connected, failed []string
+	var checkInfo string
+	timedOut := 0
+
+	for _, ip := range ips {
+		desc := tcpAddress(options.Host, ip, options.Port)
+
+		expected, err := probeTCP(network, net.JoinHostPort(ip.String(), strconv.Itoa(options.Port)), desc, options, deadline, dial)
+		if err != nil {
+			debugLog.Printf("%s", err)
+			failed = append(failed, err.Error())
+			if IsErrorKind(err, ErrTimeout) {
+				timedOut++
+			}
+			continue
+		}
+
+		connected = append(connected, desc)
+		checkInfo = expected
+
+		if mode == addressesAny {
+			break
+		}
+	}
+
+	failure := func(message string) CheckResult {
+		if timedOut == len(failed) && options.TimeoutExit != "" {
+			return timeoutResult(checkTCPName, options.TimeoutExit, message)
+		}
+
+		return CriticalResult(checkTCPName, message)
+	}
+
+	switch {
+	case len(connected) == 0 && len(failed) == 1:
+		return failure(failed[0])
+	case len(connected) == 0:
+		return failure(fmt.Sprintf("None of the %d addresses of %s accepted the connection: %s",
+			len(failed), options.Host, strings.Join(failed, ", ")))
+	case len(failed) > 0 && mode == addressesAll:
+		return failure(fmt.Sprintf("%d of %d addresses of %s failed: %s",
+			len(failed), len(ips), options.Host, strings.Join(failed, ", ")))
+	}
+
+	elapsed := time.Since(start).Seconds()
+
+	return OKResult(checkTCPName, fmt.Sprintf("Connected to %s%s in %.3fs", strings.Join(connected, " and "), checkInfo, elapsed), PerfData{
+		Label: "time",
+		Value: math.Round(elapsed*1000) / 1000,
+		UOM:   "s",
+		Min:   "0",
+		Max:   strconv.Itoa(options.Timeout),
+	}, dnsTimeMetric(dnsTime))
+}
+
+// CheckTCPWithHandlers connects to the port on the host of the options
+// and emits a critical response if the connection fails or does not
+// complete within the timeout. When Send is given it is written once
+// connected. When Expect is given a critical response is emitted
+// unless the response contains it within the timeout. The host is
+// resolved with lookup to its addresses of the IPVersion, and a host
+// that cannot be resolved emits an unknown response. With several
+// addresses they are connected to in turn until one succeeds, or with
+// Addresses "all" each must succeed. When every address failed by not
+// completing within the timeout, the response is in the state of
+// TimeoutExit when it is given. Otherwise a good response is
+// emitted along with the time taken to connect, and the time taken
+// resolving the host as separate perfdata.
+func CheckTCPWithHandlers(options TCPCheckOptions, lookup func(string) ([]net.IP, error),
+	dial func(string, string, time.Duration) (net.Conn, error)) (string, int) {
+	return runTCPCheck(options, lookup, dial).Output()
+}
+
+// CheckTCPWithHandler executes CheckTCPWithHandlers() with the host,
+// port, timeout and strings given, resolving the host with
+// net.LookupIP() and connecting with dial.
+//
+// Returns are those of CheckTCPWithHandlers()
+func CheckTCPWithHandler(host string, port, timeout int, send, expect string,
+	dial func(string, string, time.Duration) (net.Conn, error)) (string, int) {
+	return CheckTCPWithHandlers(TCPCheckOptions{Host: host, Port: port, Timeout: timeout, Send: send, Expect: expect}, net.LookupIP, dial)
+}
+
+// RunTCPCheck performs the TCP check of CheckTCPWithOptions() and
+// returns its result, without output.
+func RunTCPCheck(options TCPCheckOptions) CheckResult {
+	return runTCPCheck(options, net.LookupIP, net.DialTimeout)
+}
+
+// CheckTCPWithOptions executes CheckTCPWithHandlers(), passing it
+// net.LookupIP() and net.DialTimeout().
+//
+// Returns are those of CheckTCPWithHandlers()
+func CheckTCPWithOptions(options TCPCheckOptions) (string, int) {
+	return RunTCPCheck(options).Output()
+}
+
+// CheckTCP executes CheckTCPWithOptions() with the host, port, timeout
+// and strings given.
+//
+// Returns are those of CheckTCPWithHandlers()
+func CheckTCP(host string, port, timeout int, send, expect string) (string, int) {
+	return CheckTCPWithOptions(TCPCheckOptions{Host: host, Port: port, Timeout: timeout, Send: send, Expect: expect})
+}
@@ -0,0 +1,206 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// tcpTestServer accepts connections on a local port, answering each
+// with the reply to what was read, until the listener is closed.
+func tcpTestServer(t *testing.T, reply func(string) string) (net.Listener, string, int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listening failed: %s", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+				buffer := make([]byte, 1024)
+				n, _ := conn.Read(buffer)
+
+				conn.Write([]byte(reply(string(buffer[:n]))))
+			}(conn)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	return listener, host, portNumber
+}
+
+func TestCheckTCP(t *testing.T) {
+	server, host, port := tcpTestServer(t, func(request string) string {
+		if request == "PING\r\n" {
+			return "+PONG\r\n"
+		}
+
+		return "SSH-2.0-OpenSSH_7.4\r\n"
+	})
+	defer server.Close()
+
+	msg, code := CheckTCP(host, port, 1, "", "")
+	if code != 0 || !strings.Contains(msg, "| time=") || !strings.HasSuffix(msg, "s;;;0;1 dns_time=0s;;;0") {
+		t.Errorf("CheckTCP() should be OK with time metrics when connected: %d %s", code, msg)
+	}
+
+	msg, code = CheckTCP(host, port, 1, "", "SSH-2.0")
+	if code != 0 || !strings.Contains(msg, `the response contains "SSH-2.0"`) {
+		t.Errorf("CheckTCP() should be OK when the banner contains the expected string: %d %s", code, msg)
+	}
+
+	msg, code = CheckTCP(host, port, 1, `PING\r\n`, "PONG")
+	if code != 0 {
+		t.Errorf("CheckTCP() should be OK when the reply to the string sent is expected: %d %s", code, msg)
+	}
+
+	msg, code = CheckTCP(host, port, 1, "", "HTTP/1.1")
+	if code != 2 || !strings.Contains(msg, "does not contain") {
+		t.Errorf("CheckTCP() should be CRITICAL when the response does not contain the expected string: %d %s", code, msg)
+	}
+
+	// A port that nothing listens on
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := listener.Addr().(*net.TCPAddr)
+	listener.Close()
+
+	msg, code = CheckTCP("127.0.0.1", address.Port, 1, "", "")
+	if code != 2 || !strings.Contains(msg, "failed") {
+		t.Errorf("CheckTCP() should be CRITICAL when the connection fails: %d %s", code, msg)
+	}
+
+	dialTimeout := func(network, address string, timeout time.Duration) (net.Conn, error) {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: timeoutError{}}
+	}
+
+	msg, code = CheckTCPWithHandler("192.0.2.1", 22, 3, "", "", dialTimeout)
+	if code != 2 || !strings.Contains(msg, "timed out after 3s") {
+		t.Errorf("CheckTCP() should be CRITICAL when the connection times out: %d %s", code, msg)
+	}
+
+	msg, code = CheckTCPWithHandlers(TCPCheckOptions{Host: "192.0.2.1", Port: 22, Timeout: 3, TimeoutExit: "unknown"}, net.LookupIP, dialTimeout)
+	if code != statusCodeUnknown || !strings.Contains(msg, "CheckTcp UNKNOWN - Connection to 192.0.2.1:22 timed out after 3s") {
+		t.Errorf("CheckTCPWithHandlers() should issue the timeout exit when the connection times out: %d %s", code, msg)
+	}
+
+	dialError := func(network, address string, timeout time.Duration) (net.Conn, error) {
+		return nil, errors.New("no route to host")
+	}
+
+	for _, test := range []struct {
+		host    string
+		port    int
+		timeout int
+	}{
+		{"", 22, 10},
+		{"localhost", 0, 10},
+		{"localhost", 65536, 10},
+		{"localhost", 22, 0},
+	} {
+		msg, code = CheckTCPWithHandler(test.host, test.port, test.timeout, "", "", dialError)
+		if code != 3 {
+			t.Errorf("CheckTCP() should be UNKNOWN for host %q, port %d and timeout %d: %d %s",
+				test.host, test.port, test.timeout, code, msg)
+		}
+	}
+}
+
+func TestCheckTCPAddresses(t *testing.T) {
+	lookup := func(host string) ([]net.IP, error) {
+		if host == "nosuchhost" {
+			return nil, &net.DNSError{Err: "no such host", Name: host}
+		}
+
+		return []net.IP{net.ParseIP("192.0.2.10"), net.ParseIP("2001:db8::10")}, nil
+	}
+
+	var dialed []string
+	dial := func(refused ...string) func(string, string, time.Duration) (net.Conn, error) {
+		return func(network, address string, timeout time.Duration) (net.Conn, error) {
+			dialed = append(dialed, network+" "+address)
+
+			for _, r := range refused {
+				if address == r {
+					return nil, errors.New("connection refused")
+				}
+			}
+
+			client, server := net.Pipe()
+			server.Close()
+
+			return client, nil
+		}
+	}
+
+	type testItem struct {
+		description    string
+		options        TCPCheckOptions
+		dial           func(string, string, time.Duration) (net.Conn, error)
+		expectedCode   int
+		expectedMsg    string
+		expectedDialed []string
+	}
+
+	testList := []testItem{
+		{"First address", TCPCheckOptions{Host: "db01", Port: 22, Timeout: 3}, dial(), statusCodeOK,
+			"Connected to db01:22 (192.0.2.10) in", []string{"tcp 192.0.2.10:22"}},
+		{"Second address", TCPCheckOptions{Host: "db01", Port: 22, Timeout: 3}, dial("192.0.2.10:22"), statusCodeOK,
+			"Connected to db01:22 (2001:db8::10) in", []string{"tcp 192.0.2.10:22", "tcp [2001:db8::10]:22"}},
+		{"No address", TCPCheckOptions{Host: "db01", Port: 22, Timeout: 3}, dial("192.0.2.10:22", "[2001:db8::10]:22"), statusCodeCritical,
+			"None of the 2 addresses of db01 accepted the connection: Connection to db01:22 (192.0.2.10) failed: connection refused, Connection to db01:22 (2001:db8::10) failed",
+			[]string{"tcp 192.0.2.10:22", "tcp [2001:db8::10]:22"}},
+		{"All addresses", TCPCheckOptions{Host: "db01", Port: 22, Timeout: 3, Addresses: "all"}, dial(), statusCodeOK,
+			"Connected to db01:22 (192.0.2.10) and db01:22 (2001:db8::10) in", []string{"tcp 192.0.2.10:22", "tcp [2001:db8::10]:22"}},
+		{"All addresses with one down", TCPCheckOptions{Host: "db01", Port: 22, Timeout: 3, Addresses: "all"}, dial("[2001:db8::10]:22"), statusCodeCritical,
+			"CheckTcp CRITICAL - 1 of 2 addresses of db01 failed: Connection to db01:22 (2001:db8::10) failed: connection refused", []string{"tcp 192.0.2.10:22", "tcp [2001:db8::10]:22"}},
+		{"IPv6 only", TCPCheckOptions{Host: "db01", Port: 22, Timeout: 3, IPVersion: 6}, dial(), statusCodeOK,
+			"Connected to db01:22 (2001:db8::10) in", []string{"tcp6 [2001:db8::10]:22"}},
+		{"IPv6 literal", TCPCheckOptions{Host: "2001:db8::20", Port: 22, Timeout: 3}, dial(), statusCodeOK,
+			"Connected to [2001:db8::20]:22 in", []string{"tcp [2001:db8::20]:22"}},
+		{"IPv6 literal in brackets", TCPCheckOptions{Host: "[2001:db8::20]", Port: 22, Timeout: 3}, dial(), statusCodeOK,
+			"Connected to [2001:db8::20]:22 in", []string{"tcp [2001:db8::20]:22"}},
+		{"IPv4 literal with IPv6", TCPCheckOptions{Host: "192.0.2.20", Port: 22, Timeout: 3, IPVersion: 6}, dial(), statusCodeUnknown,
+			"Could not resolve host 192.0.2.20: lookup 192.0.2.20: no IPv6 address", nil},
+		{"Unresolved host", TCPCheckOptions{Host: "nosuchhost", Port: 22, Timeout: 3}, dial(), statusCodeUnknown,
+			"CheckTcp UNKNOWN - Could not resolve host nosuchhost: lookup nosuchhost: no such host", nil},
+		{"Invalid IP version", TCPCheckOptions{Host: "db01", Port: 22, Timeout: 3, IPVersion: 5}, dial(), statusCodeUnknown, "Invalid IP version (5)", nil},
+		{"Invalid addresses", TCPCheckOptions{Host: "db01", Port: 22, Timeout: 3, Addresses: "some"}, dial(), statusCodeUnknown, "Invalid addresses (some)", nil},
+	}
+
+	for _, i := range testList {
+		dialed = nil
+		msg, code := CheckTCPWithHandlers(i.options, lookup, i.dial)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+
+		if !reflect.DeepEqual(dialed, i.expectedDialed) {
+			t.Errorf("%s: Expected Dialed: %v, Actual Dialed: %v", i.description, i.expectedDialed, dialed)
+		}
+	}
+}
@@ -2,28 +2,205 @@ package nagiosfoundation
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
-		
-	"github.com/shirou/gopsutil/host"
-	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/nagiosformatters"
 )
 
-// CheckUptime gathers information about the host uptime.
-func CheckUptime(checkType string, warning, critical time.Duration, metricName string) (string, int) {
-	
-	const checkName = "CheckUptime"
-	
-	var msg string
-	var retcode int
-	
-	uptime, err := host.Uptime()
-	
+const checkUptimeName = "CheckUptime"
+
+// parseUptimeBound parses a bound of an uptime threshold into seconds.
+// The bound is a duration such as "72h" or "15m", a number of days
+// such as "7d" or a number of seconds.
+func parseUptimeBound(bound string) (float64, error) {
+	if strings.HasSuffix(bound, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(bound, "d"), 64)
+		return days * 24 * 60 * 60, err
+	}
+
+	if seconds, err := strconv.ParseFloat(bound, 64); err == nil {
+		return seconds, nil
+	}
+
+	duration, err := time.ParseDuration(bound)
+
+	return duration.Seconds(), err
+}
+
+// parseUptimeThreshold converts an uptime threshold, a Nagios range
+// with durations as its bounds such as "15m:72h", into a Nagios range
+// in seconds. An empty threshold is not checked.
+func parseUptimeThreshold(threshold string) (string, error) {
+	text := strings.TrimSpace(threshold)
+	if text == "" {
+		return "", nil
+	}
+
+	prefix := ""
+	if strings.HasPrefix(text, "@") {
+		prefix, text = "@", text[1:]
+	}
+
+	bounds := strings.SplitN(text, ":", 2)
+	for i, bound := range bounds {
+		if bound == "" || bound == "~" {
+			continue
+		}
+
+		seconds, err := parseUptimeBound(bound)
+		if err != nil {
+			return "", fmt.Errorf("Invalid uptime threshold %q, expected a range of durations such as 15m:72h", threshold)
+		}
+
+		bounds[i] = strconv.FormatFloat(seconds, 'f', -1, 64)
+	}
+
+	return prefix + strings.Join(bounds, ":"), nil
+}
+
+// formatUptime renders the duration in days, hours and minutes, such
+// as "3d 4h 12m", from the largest to the smallest unit that is not
+// zero. A duration under a minute is rendered in seconds.
+func formatUptime(d time.Duration) string {
+	seconds := int64(d / time.Second)
+	values := []int64{seconds / 86400, seconds % 86400 / 3600, seconds % 3600 / 60}
+	units := []string{"d", "h", "m"}
+
+	first, last := -1, -1
+	for i, value := range values {
+		if value != 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	if first < 0 {
+		return fmt.Sprintf("%ds", seconds)
+	}
+
+	parts := make([]string, 0, len(values))
+	for i := first; i <= last; i++ {
+		parts = append(parts, fmt.Sprintf("%d%s", values[i], units[i]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// uptimeExpected describes the uptimes that do not raise an alert
+// with the range, as Range.Expected() does, with the bounds rendered
+// as durations. As the uptime is never negative a range from zero is
+// described by its end, such as "at most 3d" for "72h".
+func uptimeExpected(r Range) string {
+	bound := func(seconds float64) string {
+		return formatUptime(time.Duration(seconds * float64(time.Second)))
+	}
+
+	fromZero, endInf := math.IsInf(r.Start, -1) || r.Start <= 0, math.IsInf(r.End, 1)
+
+	switch {
+	case r.Inside && fromZero && endInf:
+		return "no uptime"
+	case r.Inside && fromZero:
+		return "above " + bound(r.End)
+	case r.Inside && endInf:
+		return "below " + bound(r.Start)
+	case r.Inside:
+		return "outside " + bound(r.Start) + " to " + bound(r.End)
+	case fromZero && endInf:
+		return "any uptime"
+	case endInf:
+		return "at least " + bound(r.Start)
+	case fromZero:
+		return "at most " + bound(r.End)
+	}
+
+	return bound(r.Start) + " to " + bound(r.End)
+}
+
+// runUptimeCheck performs the check of CheckUptimeWithHandler() and
+// returns its result.
+func runUptimeCheck(warning, critical, metricName string, uptimeHandler func() (time.Duration, error)) CheckResult {
+	if metricName == "" {
+		metricName = "uptime"
+	}
+
+	warningRange, err := parseUptimeThreshold(warning)
+	if err != nil {
+		return UnknownResult(checkUptimeName, err.Error())
+	}
+
+	criticalRange, err := parseUptimeThreshold(critical)
 	if err != nil {
-		msg, _ = resultMessage(checkName, statusTextCritical, fmt.Sprintf("Failed to determine uptime %s", err.Error()))
-		retcode = 2
-	} else {
-		msg, retcode = nagiosformatters.GreaterFormatNagiosCheck(checkName, float64(uptime), float64(warning.Seconds()), float64(critical.Seconds()), metricName)
-	}
-	
-	return msg, retcode
-}
\ No newline at end of file
+		return UnknownResult(checkUptimeName, err.Error())
+	}
+
+	thresholds, err := ParseThresholds(warningRange, criticalRange)
+	if err != nil {
+		return UnknownResult(checkUptimeName, err.Error())
+	}
+
+	if uptimeHandler == nil {
+		return UnknownResult(checkUptimeName, "No uptime service")
+	}
+
+	uptime, err := uptimeHandler()
+	if err != nil {
+		return CriticalResult(checkUptimeName, fmt.Sprintf("Could not read the uptime: %s", err))
+	}
+
+	seconds := math.Floor(uptime.Seconds())
+	state, tripped := thresholds.Status(seconds)
+
+	desc := fmt.Sprintf("System has been up %s", formatUptime(uptime))
+	if state != StateOK {
+		desc += fmt.Sprintf(" (expected %s)", uptimeExpected(tripped))
+	}
+
+	return NewCheckResult(checkUptimeName, state, desc, thresholds.Metric(PerfData{
+		Label: metricName,
+		Value: seconds,
+		UOM:   "s",
+		Min:   "0",
+	}))
+}
+
+// CheckUptimeWithHandler gets the time since the system booted with
+// the handler and compares it against the warning and critical
+// thresholds. The thresholds are Nagios ranges with durations as the
+// bounds, so "15m:" alerts when the system has been up less than 15
+// minutes, catching an unexpected reboot, "72h" when it has been up
+// more than 72 hours, such as a host overdue for patching, and
+// "15m:72h" on both. A critical response is emitted when the uptime
+// is outside the critical range, a warning response when outside the
+// warning range and a good response otherwise, while an uptime that
+// cannot be read emits a critical response. The uptime is output as
+// perfdata in seconds.
+func CheckUptimeWithHandler(warning, critical, metricName string, uptimeHandler func() (time.Duration, error)) (string, int) {
+	return runUptimeCheck(warning, critical, metricName, uptimeHandler).Output()
+}
+
+// RunUptimeCheck performs the uptime check of
+// CheckUptimeWithThresholds() and returns its result, without output.
+func RunUptimeCheck(warning, critical, metricName string) CheckResult {
+	return runUptimeCheck(warning, critical, metricName, getUptimeOsConstrained)
+}
+
+// CheckUptimeWithThresholds executes CheckUptimeWithHandler(), passing
+// it the OS constrained handler reading the uptime.
+//
+// Returns are those of CheckUptimeWithHandler()
+func CheckUptimeWithThresholds(warning, critical, metricName string) (string, int) {
+	return RunUptimeCheck(warning, critical, metricName).Output()
+}
+
+// CheckUptime alerts when the system has been up longer than the
+// warning or critical duration. The checkType is not used.
+func CheckUptime(checkType string, warning, critical time.Duration, metricName string) (string, int) {
+	return CheckUptimeWithThresholds(
+		strconv.FormatFloat(warning.Seconds(), 'f', -1, 64),
+		strconv.FormatFloat(critical.Seconds(), 'f', -1, 64),
+		metricName)
+}
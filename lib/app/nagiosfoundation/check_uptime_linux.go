@@ -0,0 +1,24 @@
+// +build !windows
+
+package nagiosfoundation
+
+import (
+	"io/ioutil"
+	"time"
+)
+
+// getUptimeOsConstrained returns the time since boot from
+// /proc/uptime.
+func getUptimeOsConstrained() (time.Duration, error) {
+	data, err := ioutil.ReadFile(defaultProcRoot + "/uptime")
+	if err != nil {
+		return 0, err
+	}
+
+	uptime, err := parseUptime(string(data))
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(uptime * float64(time.Second)), nil
+}
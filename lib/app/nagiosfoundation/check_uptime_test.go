@@ -1,36 +1,60 @@
 package nagiosfoundation
 
 import (
-	"os"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestCheckUptime(t *testing.T) {
-	forever := time.Duration(24000 * time.Hour)
+	upFor := func(d time.Duration) func() (time.Duration, error) {
+		return func() (time.Duration, error) {
+			return d, nil
+		}
+	}
 
-	// Deep down in the github.com/shirou/gopsutil/host package is
-	// is code to override the uptime retrieval location using the
-	// HOST_PROC environment variable. Set this to cause it to fail.
-	// Test for failure first because on success, the package caches
-	// the boot time used for uptime calculation.
-	os.Setenv("HOST_PROC", "nosuchfile")
-	msg, retcode := CheckUptime("", forever, forever, "uptime_name")
-	if len(msg) == 0 {
-		t.Error("Message not populated for uptime CRITICAL")
+	type testItem struct {
+		description   string
+		warning       string
+		critical      string
+		uptimeHandler func() (time.Duration, error)
+		expectedCode  int
+		expectedMsg   string
 	}
 
-	if retcode != 2 {
-		t.Error("Incorrect return code for uptime CRITICAL")
+	testList := []testItem{
+		{"Up too long", "15m:72h", "5m:168h", upFor(76*time.Hour + 12*time.Minute + 30*time.Second), statusCodeWarning,
+			"System has been up 3d 4h 12m (expected 15m to 3d) | uptime=274350s;900:259200;300:604800;0"},
+		{"Freshly booted", "15m:72h", "5m:168h", upFor(8 * time.Minute), statusCodeWarning, "System has been up 8m (expected 15m to 3d)"},
+		{"Just rebooted", "15m:72h", "5m:168h", upFor(42 * time.Second), statusCodeCritical, "System has been up 42s (expected 5m to 7d)"},
+		{"Up a day", "15m:72h", "5m:168h", upFor(25 * time.Hour), statusCodeOK, "System has been up 1d 1h | uptime=90000s"},
+		{"Overdue for patching", "", "30d", upFor(31 * 24 * time.Hour), statusCodeCritical, "(expected at most 30d)"},
+		{"Seconds thresholds", "900:", "", upFor(10 * time.Minute), statusCodeWarning, "(expected at least 15m)"},
+		{"Inside an unbounded range", "@15m:", "", upFor(time.Hour), statusCodeWarning, "(expected below 15m)"},
+		{"No thresholds", "", "", upFor(time.Hour), statusCodeOK, "System has been up 1h | uptime=3600s;;;0"},
+		{"Invalid threshold", "15 minutes:", "", upFor(time.Hour), statusCodeUnknown, "Invalid uptime threshold \"15 minutes:\""},
+		{"Invalid range", "72h:15m", "", upFor(time.Hour), statusCodeUnknown, "start is greater than end"},
+		{"Uptime error", "15m:", "", func() (time.Duration, error) {
+			return 0, errors.New("no such file")
+		}, statusCodeCritical, "CheckUptime CRITICAL - Could not read the uptime: no such file"},
+		{"No uptime service", "15m:", "", nil, statusCodeUnknown, "No uptime service"},
 	}
 
-	os.Unsetenv("HOST_PROC")
-	msg, retcode = CheckUptime("", forever, forever, "uptime_name")
-	if len(msg) == 0 {
-		t.Error("Message not populated for uptime OK")
+	for _, i := range testList {
+		msg, code := CheckUptimeWithHandler(i.warning, i.critical, "", i.uptimeHandler)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
 	}
 
-	if retcode != 0 {
-		t.Error("Incorrect return code for uptime OK")
+	forever := time.Duration(24000 * time.Hour)
+	if msg, code := CheckUptime("", forever, forever, "uptime_name"); code != statusCodeOK || !strings.Contains(msg, "uptime_name=") {
+		t.Errorf("CheckUptime() should be OK below the durations, Code: %d, %s", code, msg)
 	}
 }
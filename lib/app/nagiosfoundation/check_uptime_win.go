@@ -0,0 +1,19 @@
+// +build windows
+
+package nagiosfoundation
+
+import (
+	"time"
+
+	"github.com/shirou/gopsutil/host"
+)
+
+// getUptimeOsConstrained returns the time since boot.
+func getUptimeOsConstrained() (time.Duration, error) {
+	uptime, err := host.Uptime()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(uptime) * time.Second, nil
+}
@@ -65,6 +65,15 @@ type UserGroupCheck struct {
 	Service UserGroupService
 }
 
+// userResult returns the result of CheckUser().
+func (ugc UserGroupCheck) userResult() CheckResult {
+	if _, err := ugc.Service.Lookup(ugc.UserName); err != nil {
+		return CriticalResult(checkUserName, fmt.Sprintf("User %s does not exist", ugc.UserName))
+	}
+
+	return OKResult(checkUserName, fmt.Sprintf("User %s exists", ugc.UserName))
+}
+
 // CheckUser checks for the existence of a user on the host
 // operating system.
 //
@@ -72,23 +81,16 @@ type UserGroupCheck struct {
 // with the return code. 0 indicates the user exists, 2 indicates
 // the user does not exist.
 func (ugc UserGroupCheck) CheckUser() (string, int) {
-	var retCode int
-	var formatString, status string
-
-	_, err := ugc.Service.Lookup(ugc.UserName)
-
-	if err == nil {
-		formatString = "User %s exists"
-		status = statusTextOK
-		retCode = statusCodeOK
-	} else {
-		formatString = "User %s does not exist"
-		status = statusTextCritical
-		retCode = statusCodeCritical
+	return ugc.userResult().Output()
+}
+
+// groupResult returns the result of CheckGroup().
+func (ugc UserGroupCheck) groupResult() CheckResult {
+	if _, err := ugc.Service.LookupGroup(ugc.GroupName); err != nil {
+		return CriticalResult(checkGroupName, fmt.Sprintf("Group %s does not exist", ugc.GroupName))
 	}
 
-	msg, _ := resultMessage(checkUserName, status, fmt.Sprintf(formatString, ugc.UserName))
-	return msg, retCode
+	return OKResult(checkGroupName, fmt.Sprintf("Group %s exists", ugc.GroupName))
 }
 
 // CheckGroup checks for the existence of a group on the host
@@ -98,23 +100,31 @@ func (ugc UserGroupCheck) CheckUser() (string, int) {
 // with the return code. 0 indicates the group exists, 2 indicates
 // the group does not exist.
 func (ugc UserGroupCheck) CheckGroup() (string, int) {
-	var retCode int
-	var formatString, status string
-
-	_, err := ugc.Service.LookupGroup(ugc.GroupName)
-
-	if err == nil {
-		formatString = "Group %s exists"
-		status = statusTextOK
-		retCode = statusCodeOK
-	} else {
-		formatString = "Group %s does not exist"
-		status = statusTextCritical
-		retCode = statusCodeCritical
+	return ugc.groupResult().Output()
+}
+
+// userGroupResult returns the result of CheckUserGroup().
+func (ugc UserGroupCheck) userGroupResult() CheckResult {
+	userInfo, err := ugc.Service.Lookup(ugc.UserName)
+	if err != nil {
+		return CriticalResult(checkUserGroupName, fmt.Sprintf("User %s does not exist", ugc.UserName))
 	}
 
-	msg, _ := resultMessage(checkGroupName, status, fmt.Sprintf(formatString, ugc.GroupName))
-	return msg, retCode
+	groupIds, err := ugc.Service.GroupIds(userInfo)
+	if err != nil {
+		return CriticalResult(checkUserGroupName, fmt.Sprintf("Could not get Group IDs for user %s", ugc.UserName))
+	}
+
+	for i := range groupIds {
+		groupInfo, _ := ugc.Service.LookupGroupID(groupIds[i])
+		if groupInfo.Name == ugc.GroupName {
+			return OKResult(checkUserGroupName, fmt.Sprintf("User %s exists and is in Group %s",
+				ugc.UserName, ugc.GroupName))
+		}
+	}
+
+	return CriticalResult(checkUserGroupName, fmt.Sprintf("User %s exists but is not in Group %s",
+		ugc.UserName, ugc.GroupName))
 }
 
 // CheckUserGroup checks for the existence of a user and if it
@@ -124,37 +134,28 @@ func (ugc UserGroupCheck) CheckGroup() (string, int) {
 // with the return code. 0 indicates the user exists and is in
 // the group, 2 indicates otherwise.
 func (ugc UserGroupCheck) CheckUserGroup() (string, int) {
-	var msg, status string
-	retcode := statusCodeCritical
+	return ugc.userGroupResult().Output()
+}
 
-	userInfo, err := ugc.Service.Lookup(ugc.UserName)
-	if err != nil {
-		msg = fmt.Sprintf("User %s does not exist", ugc.UserName)
-		status = statusTextCritical
-	} else {
-		groupIds, err := ugc.Service.GroupIds(userInfo)
-		if err != nil {
-			msg = fmt.Sprintf("Could not get Group IDs for user %s", ugc.UserName)
-			status = statusTextCritical
-		} else {
-			msg = fmt.Sprintf("User %s exists but is not in Group %s",
-				ugc.UserName, ugc.GroupName)
-			status = statusTextCritical
-
-			for i := range groupIds {
-				groupInfo, _ := ugc.Service.LookupGroupID(groupIds[i])
-				if groupInfo.Name == ugc.GroupName {
-					msg = fmt.Sprintf("User %s exists and is in Group %s",
-						ugc.UserName, ugc.GroupName)
-					status = statusTextOK
-					retcode = statusCodeOK
-				}
-			}
-		}
+// runUserGroupCheck performs the check of CheckUserGroupWithHandler()
+// and returns its result.
+func runUserGroupCheck(user, group string, userGroupHandler UserGroupService) CheckResult {
+	userGroupCheck := UserGroupCheck{
+		UserName:  user,
+		GroupName: group,
+		Service:   userGroupHandler,
+	}
+
+	switch {
+	case user != "" && group != "":
+		return userGroupCheck.userGroupResult()
+	case user != "":
+		return userGroupCheck.userResult()
+	case group != "":
+		return userGroupCheck.groupResult()
 	}
 
-	msg, _ = resultMessage(checkUserGroupName, status, msg)
-	return msg, retcode
+	return CheckResult{}
 }
 
 // CheckUserGroupWithHandler checks for the existence of a user and
@@ -164,29 +165,18 @@ func (ugc UserGroupCheck) CheckUserGroup() (string, int) {
 //
 // Returns - result message and return code.
 func CheckUserGroupWithHandler(user, group string, userGroupHandler UserGroupService) (string, int) {
-	var msg string
-	var retCode int
-
-	userGroupCheck := UserGroupCheck{
-		UserName:  user,
-		GroupName: group,
-		Service:   userGroupHandler,
-	}
-
-	if user != "" && group != "" {
-		msg, retCode = userGroupCheck.CheckUserGroup()
-	} else if user != "" {
-		msg, retCode = userGroupCheck.CheckUser()
-	} else if group != "" {
-		msg, retCode = userGroupCheck.CheckGroup()
-	}
+	return runUserGroupCheck(user, group, userGroupHandler).Output()
+}
 
-	return msg, retCode
+// RunUserGroupCheck performs the user and group check of
+// CheckUserGroup() and returns its result, without output.
+func RunUserGroupCheck(user, group string) CheckResult {
+	return runUserGroupCheck(user, group, new(UserGroupHandler))
 }
 
 // CheckUserGroup executes the normal user/group
 // checks in CheckUserGroupFlags() then prints the results
 // and exits.
 func CheckUserGroup(user, group string) (string, int) {
-	return CheckUserGroupWithHandler(user, group, new(UserGroupHandler))
+	return RunUserGroupCheck(user, group).Output()
 }
@@ -0,0 +1,233 @@
+package nagiosfoundation
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+const checkUsersName = "CheckUsers"
+
+const defaultUtmp = "/var/run/utmp"
+
+// The layout of a record of utmp on Linux, struct utmp of glibc, of
+// which the type, the terminal line, the user and the host are read.
+const (
+	utmpRecordSize  = 384
+	utmpLineOffset  = 8
+	utmpLineSize    = 32
+	utmpUserOffset  = 44
+	utmpUserSize    = 32
+	utmpHostOffset  = 76
+	utmpHostSize    = 256
+	utmpUserProcess = 7
+)
+
+// maxListedSessions is the number of sessions listed in the result of
+// a users check.
+const maxListedSessions = 10
+
+// UsersCheckOptions contains the options for a logged in users check.
+type UsersCheckOptions struct {
+	// The warning and critical thresholds, Nagios ranges of the number
+	// of sessions logged in. An empty threshold is not checked.
+	Warning  string
+	Critical string
+
+	// The utmp file the sessions are read from. Defaults to
+	// /var/run/utmp.
+	Utmp string
+
+	// The name of the metric in the nagios output. Defaults to
+	// "users".
+	MetricName string
+}
+
+// userSession is a login session, a user logged in on a terminal line
+// such as "pts/0", from a remote host or locally when host is empty.
+type userSession struct {
+	user string
+	line string
+	host string
+}
+
+// String returns the session as listed in a result, such as
+// "alice (pts/0 from 10.0.0.5)".
+func (s userSession) String() string {
+	if s.host == "" {
+		return fmt.Sprintf("%s (%s)", s.user, s.line)
+	}
+
+	return fmt.Sprintf("%s (%s from %s)", s.user, s.line, s.host)
+}
+
+// utmpText returns a NUL padded text field of a utmp record.
+func utmpText(field []byte) string {
+	if i := bytes.IndexByte(field, 0); i >= 0 {
+		field = field[:i]
+	}
+
+	return string(field)
+}
+
+// parseUtmp returns the login sessions of the content of a utmp file,
+// the records of type USER_PROCESS. Content that is not a whole number
+// of records is not in the layout read, such as the utmp of a libc
+// other than glibc, and is an error.
+func parseUtmp(data []byte) ([]userSession, error) {
+	if len(data)%utmpRecordSize != 0 {
+		return nil, fmt.Errorf("%d bytes is not a whole number of %d byte records", len(data), utmpRecordSize)
+	}
+
+	var sessions []userSession
+
+	for offset := 0; offset < len(data); offset += utmpRecordSize {
+		record := data[offset : offset+utmpRecordSize]
+		if binary.LittleEndian.Uint16(record) != utmpUserProcess {
+			continue
+		}
+
+		session := userSession{
+			user: utmpText(record[utmpUserOffset : utmpUserOffset+utmpUserSize]),
+			line: utmpText(record[utmpLineOffset : utmpLineOffset+utmpLineSize]),
+			host: utmpText(record[utmpHostOffset : utmpHostOffset+utmpHostSize]),
+		}
+
+		if session.user != "" {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions, nil
+}
+
+// parseWho returns the login sessions of the output of who, a line for
+// each of the user, the terminal line, the login time and the host in
+// brackets, such as "alice    pts/0        2019-06-04 15:04 (10.0.0.5)".
+func parseWho(data string) []userSession {
+	var sessions []userSession
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		session := userSession{user: fields[0], line: fields[1]}
+
+		last := fields[len(fields)-1]
+		if len(fields) > 2 && strings.HasPrefix(last, "(") && strings.HasSuffix(last, ")") {
+			session.host = strings.TrimSuffix(strings.TrimPrefix(last, "("), ")")
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions
+}
+
+// sessionsText returns the sessions as listed in a result, such as
+// ": alice (pts/0 from 10.0.0.5), root (tty1)", with up to
+// maxListedSessions of them and "..." after a list cut short.
+func sessionsText(sessions []userSession) string {
+	listed := make([]string, 0, maxListedSessions+1)
+
+	for i, session := range sessions {
+		if i == maxListedSessions {
+			listed = append(listed, "...")
+			break
+		}
+
+		listed = append(listed, session.String())
+	}
+
+	return ": " + strings.Join(listed, ", ")
+}
+
+// runUsersCheck performs the check of CheckUsersWithHandlers() and
+// returns its result.
+func runUsersCheck(options UsersCheckOptions, readFile func(string) ([]byte, error),
+	run func(context.Context, string, ...string) ([]byte, error)) CheckResult {
+	thresholds, err := ParseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return UnknownResult(checkUsersName, err.Error())
+	}
+
+	utmp := options.Utmp
+	if utmp == "" {
+		utmp = defaultUtmp
+	}
+
+	metricName := options.MetricName
+	if metricName == "" {
+		metricName = "users"
+	}
+
+	// who reports no sessions for a utmp it cannot open, so it is not
+	// run for a missing or unreadable utmp.
+	data, err := readFile(utmp)
+	if err != nil {
+		return UnknownResult(checkUsersName, fmt.Sprintf("Could not read %s: %s", utmp, err))
+	}
+
+	sessions, err := parseUtmp(data)
+	if err != nil {
+		debugLog.Printf("Could not parse %s, running who: %s", utmp, err)
+
+		out, runErr := run(context.Background(), "who", utmp)
+		if runErr != nil {
+			return UnknownResult(checkUsersName, fmt.Sprintf("Could not parse %s: %s, and could not run who: %s", utmp, err, runErr))
+		}
+
+		sessions = parseWho(string(out))
+	}
+
+	count := len(sessions)
+	state, tripped := thresholds.Status(float64(count))
+
+	checkInfo := fmt.Sprintf("%d users logged in", count)
+	if state != StateOK {
+		checkInfo += fmt.Sprintf(" (expected %s)", tripped.Expected())
+	}
+
+	if count > 0 {
+		checkInfo += sessionsText(sessions)
+	}
+
+	return NewCheckResult(checkUsersName, state, checkInfo, thresholds.Metric(PerfData{
+		Label: metricName,
+		Value: float64(count),
+		Min:   "0",
+	}))
+}
+
+// CheckUsersWithHandlers counts the login sessions read from the utmp
+// file with readFile and compares the count against the
+// options.Warning and options.Critical ranges, catching an unexpected
+// interactive login on a production host. A utmp that cannot be read
+// emits an unknown response rather than a count of zero, as no
+// sessions could be counted. A utmp not in the layout of glibc is
+// handed to who, run with run, which reads it with the libc of the
+// host. The sessions are listed in the result and the count is output
+// as perfdata.
+func CheckUsersWithHandlers(options UsersCheckOptions, readFile func(string) ([]byte, error),
+	run func(context.Context, string, ...string) ([]byte, error)) (string, int) {
+	return runUsersCheck(options, readFile, run).Output()
+}
+
+// RunUsersCheck performs the users check of CheckUsers() and returns
+// its result, without output.
+func RunUsersCheck(options UsersCheckOptions) CheckResult {
+	return runUsersCheck(options, ioutil.ReadFile, runCommand)
+}
+
+// CheckUsers executes CheckUsersWithHandlers(), reading the utmp file
+// with ioutil.ReadFile() and running who with exec.CommandContext().
+//
+// Returns are those of CheckUsersWithHandlers()
+func CheckUsers(options UsersCheckOptions) (string, int) {
+	return RunUsersCheck(options).Output()
+}
@@ -0,0 +1,125 @@
+package nagiosfoundation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testUtmpRecord returns a utmp record of the type, user, line and host.
+func testUtmpRecord(recordType uint16, user, line, host string) []byte {
+	record := make([]byte, utmpRecordSize)
+	record[0] = byte(recordType)
+	copy(record[utmpLineOffset:], line)
+	copy(record[utmpUserOffset:], user)
+	copy(record[utmpHostOffset:], host)
+
+	return record
+}
+
+func testUtmp(records ...[]byte) []byte {
+	var data []byte
+	for _, record := range records {
+		data = append(data, record...)
+	}
+
+	return data
+}
+
+func TestParseUtmp(t *testing.T) {
+	data := testUtmp(
+		testUtmpRecord(2, "reboot", "~", "5.4.0"),
+		testUtmpRecord(utmpUserProcess, "alice", "pts/0", "10.0.0.5"),
+		testUtmpRecord(6, "LOGIN", "tty2", ""),
+		testUtmpRecord(utmpUserProcess, "root", "tty1", ""),
+		testUtmpRecord(8, "", "pts/1", ""),
+	)
+
+	sessions, err := parseUtmp(data)
+	expected := []userSession{{user: "alice", line: "pts/0", host: "10.0.0.5"}, {user: "root", line: "tty1"}}
+	if err != nil || !reflect.DeepEqual(sessions, expected) {
+		t.Errorf("parseUtmp() Expected: %+v, Actual: %+v, Error: %v", expected, sessions, err)
+	}
+
+	if _, err := parseUtmp(data[:utmpRecordSize+100]); err == nil || !strings.Contains(err.Error(), "not a whole number") {
+		t.Errorf("parseUtmp() should reject a partial record, Error: %v", err)
+	}
+}
+
+func TestParseWho(t *testing.T) {
+	data := "alice    pts/0        2019-06-04 15:04 (10.0.0.5)\nroot     tty1         2019-06-04 09:12\n\n"
+
+	expected := []userSession{{user: "alice", line: "pts/0", host: "10.0.0.5"}, {user: "root", line: "tty1"}}
+	if sessions := parseWho(data); !reflect.DeepEqual(sessions, expected) {
+		t.Errorf("parseWho() Expected: %+v, Actual: %+v", expected, sessions)
+	}
+}
+
+func TestCheckUsers(t *testing.T) {
+	type testItem struct {
+		description  string
+		options      UsersCheckOptions
+		data         []byte
+		readErr      error
+		whoOut       string
+		whoErr       error
+		expectedCode int
+		expectedMsg  string
+	}
+
+	twoUsers := testUtmp(testUtmpRecord(utmpUserProcess, "alice", "pts/0", "10.0.0.5"), testUtmpRecord(utmpUserProcess, "root", "tty1", ""))
+
+	testList := []testItem{
+		{"Under the thresholds", UsersCheckOptions{Warning: "2", Critical: "5"}, twoUsers, nil, "", nil, statusCodeOK,
+			"CheckUsers OK - 2 users logged in: alice (pts/0 from 10.0.0.5), root (tty1) | users=2;2;5;0"},
+		{"Over the warning", UsersCheckOptions{Warning: "0", MetricName: "logins"}, twoUsers, nil, "", nil, statusCodeWarning,
+			"CheckUsers WARNING - 2 users logged in (expected 0): alice (pts/0 from 10.0.0.5), root (tty1) | logins=2;0;;0"},
+		{"Nobody", UsersCheckOptions{Critical: "0"}, testUtmp(testUtmpRecord(2, "reboot", "~", "")), nil, "", nil, statusCodeOK,
+			"CheckUsers OK - 0 users logged in | users=0;;0;0"},
+		{"Missing utmp", UsersCheckOptions{}, nil, os.ErrNotExist, "", nil, statusCodeUnknown,
+			"CheckUsers UNKNOWN - Could not read /var/run/utmp: file does not exist"},
+		{"Other layout read with who", UsersCheckOptions{Critical: "0"}, []byte("short"), nil,
+			"bob      pts/3        2019-06-04 15:04 (192.0.2.7)\n", nil, statusCodeCritical, "1 users logged in (expected 0): bob (pts/3 from 192.0.2.7)"},
+		{"Who failing", UsersCheckOptions{}, []byte("short"), nil, "", errors.New(`exec: "who": executable file not found in $PATH`), statusCodeUnknown,
+			"Could not parse /var/run/utmp: 5 bytes is not a whole number of 384 byte records, and could not run who"},
+		{"Invalid threshold", UsersCheckOptions{Warning: "many"}, twoUsers, nil, "", nil, statusCodeUnknown, "Invalid range"},
+	}
+
+	for _, i := range testList {
+		readFile := func(string) ([]byte, error) { return i.data, i.readErr }
+
+		var ranArgs []string
+		run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			ranArgs = append([]string{name}, args...)
+			return []byte(i.whoOut), i.whoErr
+		}
+
+		msg, code := CheckUsersWithHandlers(i.options, readFile, run)
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d, %s", i.description, i.expectedCode, code, msg)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %q, Actual Message: %q", i.description, i.expectedMsg, msg)
+		}
+
+		if i.readErr != nil && ranArgs != nil {
+			t.Errorf("%s: who should not be run for a utmp that cannot be read: %v", i.description, ranArgs)
+		}
+	}
+
+	var readPath string
+	readFile := func(path string) ([]byte, error) {
+		readPath = path
+		return nil, nil
+	}
+
+	CheckUsersWithHandlers(UsersCheckOptions{Utmp: "/run/utmp"}, readFile, nil)
+	if readPath != "/run/utmp" {
+		t.Errorf("CheckUsersWithHandlers() should read the utmp given, Actual: %s", readPath)
+	}
+}
@@ -0,0 +1,399 @@
+package nagiosfoundation
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// State is the state of a check. The values are the Nagios plugin
+// exit codes.
+type State int
+
+// The check states.
+const (
+	StateOK       State = statusCodeOK
+	StateWarning  State = statusCodeWarning
+	StateCritical State = statusCodeCritical
+	StateUnknown  State = statusCodeUnknown
+)
+
+// String returns the status text of the state, such as "WARNING".
+func (s State) String() string {
+	return statusTextForCode(int(s))
+}
+
+// ExitCode returns the exit code of the state. A state outside of
+// the Nagios range exits as UNKNOWN.
+func (s State) ExitCode() int {
+	if s < StateOK || s > StateUnknown {
+		return statusCodeUnknown
+	}
+
+	return int(s)
+}
+
+// ParseState returns the state named by its status text in any case,
+// such as "warning".
+func ParseState(text string) (State, error) {
+	for code, statusText := range statusTexts {
+		if strings.EqualFold(text, statusText) {
+			return State(code), nil
+		}
+	}
+
+	return StateUnknown, fmt.Errorf("Invalid state %q. Valid states are \"ok\", \"warning\", \"critical\" and \"unknown\"", text)
+}
+
+// CheckResult is the structured result of a check. Checks returning
+// a CheckResult can be output in any of the supported formats
+// without parsing the plain text Nagios output.
+type CheckResult struct {
+	// The name of the check, such as "CheckProcess".
+	Name string `json:"check"`
+
+	// The status text and the matching exit code.
+	Status string `json:"status"`
+	Code   int    `json:"code"`
+
+	// The description of the result.
+	Message string `json:"message"`
+
+	PerfData PerfDataSet `json:"perfdata"`
+}
+
+// NewCheckResult returns the result of the named check in the state
+// with the message and perfdata describing it.
+func NewCheckResult(name string, state State, message string, perfData ...PerfData) CheckResult {
+	return CheckResult{
+		Name:     name,
+		Status:   state.String(),
+		Code:     state.ExitCode(),
+		Message:  message,
+		PerfData: perfData,
+	}
+}
+
+// OKResult returns an OK result of the named check.
+func OKResult(name, message string, perfData ...PerfData) CheckResult {
+	return NewCheckResult(name, StateOK, message, perfData...)
+}
+
+// WarningResult returns a WARNING result of the named check.
+func WarningResult(name, message string, perfData ...PerfData) CheckResult {
+	return NewCheckResult(name, StateWarning, message, perfData...)
+}
+
+// CriticalResult returns a CRITICAL result of the named check.
+func CriticalResult(name, message string, perfData ...PerfData) CheckResult {
+	return NewCheckResult(name, StateCritical, message, perfData...)
+}
+
+// UnknownResult returns an UNKNOWN result of the named check.
+func UnknownResult(name, message string, perfData ...PerfData) CheckResult {
+	return NewCheckResult(name, StateUnknown, message, perfData...)
+}
+
+// State returns the state of the result.
+func (r CheckResult) State() State {
+	return State(r.Code)
+}
+
+// Output returns the result in the plain text Nagios format and its
+// exit code, the values returned by the check functions.
+func (r CheckResult) Output() (string, int) {
+	return r.String(), r.Code
+}
+
+// Invert returns the result with OK and CRITICAL swapped, for
+// alerting when what the check looks for is found. WARNING and
+// UNKNOWN are unchanged, as a check that could not tell is no more
+// certain when inverted.
+func (r CheckResult) Invert() CheckResult {
+	switch r.State() {
+	case StateOK:
+		r.Code = int(StateCritical)
+	case StateCritical:
+		r.Code = int(StateOK)
+	default:
+		return r
+	}
+
+	r.Status = r.State().String()
+
+	return r
+}
+
+// Remap returns the result with its exit code replaced by code, such
+// as a CRITICAL reported as WARNING during maintenance. The status
+// becomes that of code, while a code outside of the Nagios range, as
+// expected by some other tooling, keeps the status of the check.
+func (r CheckResult) Remap(code int) CheckResult {
+	if code >= statusCodeOK && code <= statusCodeUnknown {
+		r.Status = statusTextForCode(code)
+	}
+
+	r.Code = code
+
+	return r
+}
+
+// InvertResult inverts the plain text output of a check and its exit
+// code as CheckResult.Invert() does. Only the status text of the
+// output is changed, so the description and perfdata are kept as the
+// check wrote them. Output not in the Nagios format has only the exit
+// code inverted.
+func InvertResult(msg string, code int) (string, int) {
+	return RemapResult(msg, code, ParseCheckResult(msg, code).Invert().Code)
+}
+
+// RemapResult returns the plain text output of a check with its exit
+// code replaced by newCode, such as a CRITICAL reported as WARNING
+// during maintenance. As with InvertResult(), only the status text of
+// the output is changed, to that of newCode. A code outside of the
+// Nagios range, as expected by some other tooling, keeps the status
+// text the check wrote.
+func RemapResult(msg string, code, newCode int) (string, int) {
+	result := ParseCheckResult(msg, code)
+
+	head := result.Name + " " + result.Status
+	if newCode >= statusCodeOK && newCode <= statusCodeUnknown && result.Name != "" && strings.HasPrefix(msg, head) {
+		msg = result.Name + " " + statusTextForCode(newCode) + msg[len(head):]
+	}
+
+	return msg, newCode
+}
+
+// RecoverCheck runs the check and returns its result, or when the
+// check panics an UNKNOWN result of the named check describing the
+// panic, so that a bug such as a malformed /proc entry parsed past its
+// end is reported to Nagios rather than crashing the plugin with exit
+// code 2, which would read as CRITICAL. The stack of the panic is
+// logged as a diagnostic message, written to stderr with --verbose.
+func RecoverCheck(name string, check func() (string, int)) (msg string, retcode int) {
+	defer func() {
+		if r := recover(); r != nil {
+			debugLog.Printf("Check panicked: %v\n%s", r, debug.Stack())
+			msg, retcode = UnknownResult(name, fmt.Sprintf("The check failed unexpectedly: %v", r)).Output()
+		}
+	}()
+
+	return check()
+}
+
+// RecoverCheckResult runs the check and returns its result as
+// RecoverCheck() does, for a check returning a CheckResult.
+func RecoverCheckResult(name string, check func() CheckResult) (result CheckResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			debugLog.Printf("Check panicked: %v\n%s", r, debug.Stack())
+			result = UnknownResult(name, fmt.Sprintf("The check failed unexpectedly: %v", r))
+		}
+	}()
+
+	return check()
+}
+
+// truncatedMarker ends the description of a result cut short by
+// TruncateResult().
+const truncatedMarker = "...(truncated)"
+
+// TruncateResult returns the plain text output of a check cut to at
+// most maxLength bytes, for output long enough that Nagios would cut
+// it where it sees fit, such as the lines matched by a log check.
+// The description is cut short and ends with "...(truncated)", while
+// the perfdata after the pipe is kept whole, so the metrics still
+// parse. The first line, with the state, is never cut, so output
+// whose first line and perfdata alone are over maxLength is returned
+// over the length. A maxLength of 0 or less returns the output as it
+// is.
+func TruncateResult(msg string, maxLength int) string {
+	if maxLength <= 0 || len(msg) <= maxLength {
+		return msg
+	}
+
+	text, perfData := msg, ""
+	if i := strings.Index(msg, perfDataSeparator); i >= 0 {
+		text, perfData = msg[:i], msg[i:]
+	}
+
+	keep := maxLength - len(perfData) - len(truncatedMarker)
+
+	firstLine := len(text)
+	if i := strings.Index(text, "\n"); i >= 0 {
+		firstLine = i
+	}
+
+	if keep < firstLine {
+		keep = firstLine
+	}
+
+	if keep >= len(text) {
+		return msg
+	}
+
+	// Cut on a character rather than within one.
+	for keep > 0 && !utf8.RuneStart(text[keep]) {
+		keep--
+	}
+
+	return text[:keep] + truncatedMarker + perfData
+}
+
+// statusTexts is the status text for each status code.
+var statusTexts = []string{statusTextOK, statusTextWarning, statusTextCritical, statusTextUnknown}
+
+// statusTextForCode returns the status text for a status code.
+// Codes outside of the Nagios range are UNKNOWN.
+func statusTextForCode(code int) string {
+	if code < 0 || code >= len(statusTexts) {
+		return statusTextUnknown
+	}
+
+	return statusTexts[code]
+}
+
+// resultTextPipes replaces the pipes of a message, which Nagios would
+// take as the start of the perfdata, in the plain text format.
+var resultTextPipes = strings.NewReplacer("|", "¦")
+
+// String renders the result in the plain text Nagios format. A pipe
+// in the message is rendered as "¦", so it does not start the
+// perfdata, while the JSON format keeps the message as it is.
+func (r CheckResult) String() string {
+	return perfDataResultMessage(r.Name, r.Status, resultTextPipes.Replace(r.Message), r.PerfData)
+}
+
+// JSON renders the result as a JSON object.
+func (r CheckResult) JSON() (string, error) {
+	if r.PerfData == nil {
+		r.PerfData = PerfDataSet{}
+	}
+
+	data, err := json.Marshal(r)
+
+	return string(data), err
+}
+
+// ParseCheckResult builds a CheckResult from the plain text output
+// of a check in the form of "Name STATUS - description | perfdata"
+// and its exit code. Output not in this form, such as an error from
+// the command line parser, is kept whole as the message.
+func ParseCheckResult(msg string, code int) CheckResult {
+	result := CheckResult{
+		Status:  statusTextForCode(code),
+		Code:    code,
+		Message: msg,
+	}
+
+	text := msg
+	if i := strings.Index(msg, perfDataSeparator); i >= 0 {
+		text = msg[:i]
+		result.PerfData = parsePerfData(msg[i+len(perfDataSeparator):])
+	}
+
+	head, desc := text, ""
+	if i := strings.Index(text, " - "); i >= 0 {
+		head, desc = text[:i], text[i+3:]
+	}
+
+	fields := strings.Fields(head)
+	if len(fields) == 2 && isStatusText(fields[1]) {
+		result.Name = fields[0]
+		result.Status = fields[1]
+		result.Message = desc
+	} else {
+		result.PerfData = nil
+	}
+
+	return result
+}
+
+func isStatusText(text string) bool {
+	for _, status := range statusTexts {
+		if text == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+var perfDataValuePattern = regexp.MustCompile(`^([-+]?[0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?)(.*)$`)
+
+// parsePerfData parses the perfdata section of the Nagios output
+// into metrics. Labels may be quoted with single quotes, with a
+// quote in the label doubled. Metrics without a numeric value are
+// skipped.
+func parsePerfData(perfData string) []PerfData {
+	var metrics []PerfData
+
+	for _, metricText := range splitPerfData(perfData) {
+		i := strings.LastIndex(metricText, "=")
+		if i <= 0 {
+			continue
+		}
+
+		label := metricText[:i]
+		if len(label) >= 2 && strings.HasPrefix(label, "'") && strings.HasSuffix(label, "'") {
+			label = strings.Replace(label[1:len(label)-1], "''", "'", -1)
+		}
+
+		fields := strings.Split(metricText[i+1:], ";")
+		match := perfDataValuePattern.FindStringSubmatch(fields[0])
+		if match == nil {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			continue
+		}
+
+		metric := PerfData{Label: label, Value: value, UOM: match[2]}
+
+		thresholds := []*string{&metric.Warning, &metric.Critical, &metric.Min, &metric.Max}
+		for j, field := range fields[1:] {
+			if j < len(thresholds) {
+				*thresholds[j] = field
+			}
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+// splitPerfData splits the perfdata section on the spaces between
+// metrics, keeping spaces within quoted labels.
+func splitPerfData(perfData string) []string {
+	var metrics []string
+	var current strings.Builder
+	quoted := false
+
+	for _, c := range perfData {
+		switch {
+		case c == '\'':
+			quoted = !quoted
+			current.WriteRune(c)
+		case c == ' ' && !quoted:
+			if current.Len() > 0 {
+				metrics = append(metrics, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(c)
+		}
+	}
+
+	if current.Len() > 0 {
+		metrics = append(metrics, current.String())
+	}
+
+	return metrics
+}
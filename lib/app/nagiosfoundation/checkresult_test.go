@@ -0,0 +1,276 @@
+package nagiosfoundation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCheckResult(t *testing.T) {
+	type testItem struct {
+		description string
+		msg         string
+		code        int
+		expected    CheckResult
+	}
+
+	testList := []testItem{
+		{
+			description: "Description and perfdata",
+			msg:         "CheckProcess WARNING - 3 instances of worker running (expected 5-10) | procs=3;5:10;;0 'worker rss'=512.5MB",
+			code:        statusCodeWarning,
+			expected: CheckResult{
+				Name:    "CheckProcess",
+				Status:  statusTextWarning,
+				Code:    statusCodeWarning,
+				Message: "3 instances of worker running (expected 5-10)",
+				PerfData: []PerfData{
+					{Label: "procs", Value: 3, Warning: "5:10", Min: "0"},
+					{Label: "worker rss", Value: 512.5, UOM: "MB"},
+				},
+			},
+		},
+		{
+			description: "Description only",
+			msg:         "CheckService CRITICAL - Service sshd is not running",
+			code:        statusCodeCritical,
+			expected:    CheckResult{Name: "CheckService", Status: statusTextCritical, Code: statusCodeCritical, Message: "Service sshd is not running"},
+		},
+		{
+			description: "Status only",
+			msg:         "CheckFileExists OK",
+			code:        statusCodeOK,
+			expected:    CheckResult{Name: "CheckFileExists", Status: statusTextOK, Code: statusCodeOK},
+		},
+		{
+			description: "Not in the Nagios format",
+			msg:         "unknown flag: --bogus",
+			code:        1,
+			expected:    CheckResult{Status: statusTextWarning, Code: 1, Message: "unknown flag: --bogus"},
+		},
+	}
+
+	for _, i := range testList {
+		if actual := ParseCheckResult(i.msg, i.code); !reflect.DeepEqual(actual, i.expected) {
+			t.Errorf("%s: Expected: %+v, Actual: %+v", i.description, i.expected, actual)
+		}
+	}
+}
+
+func TestCheckResultOutput(t *testing.T) {
+	result := CheckResult{
+		Name:     "CheckCPU",
+		Status:   statusTextOK,
+		Code:     statusCodeOK,
+		Message:  "value = 42",
+		PerfData: []PerfData{{Label: "cpu", Value: 42, UOM: "%", Warning: "80", Critical: "90"}},
+	}
+
+	if text := result.String(); text != "CheckCPU OK - value = 42 | cpu=42%;80;90" {
+		t.Errorf("CheckResult.String() malformed: %s", text)
+	}
+
+	expected := `{"check":"CheckCPU","status":"OK","code":0,"message":"value = 42","perfdata":[{"label":"cpu","value":42,"uom":"%","warning":"80","critical":"90"}]}`
+	if actual, err := result.JSON(); err != nil || actual != expected {
+		t.Errorf("CheckResult.JSON() Expected: %s, Actual: %s, Error: %v", expected, actual, err)
+	}
+
+	result.PerfData = nil
+	if actual, _ := result.JSON(); actual != `{"check":"CheckCPU","status":"OK","code":0,"message":"value = 42","perfdata":[]}` {
+		t.Errorf("CheckResult.JSON() without perfdata should have an empty list: %s", actual)
+	}
+
+	result = WarningResult("CheckLog", "1 lines matching ERROR\nERROR disk full | retrying", PerfData{Label: "matches", Value: 1})
+	if text := result.String(); text != "CheckLog WARNING - 1 lines matching ERROR\nERROR disk full ¦ retrying | matches=1" {
+		t.Errorf("CheckResult.String() should not start the perfdata with a pipe in the message: %s", text)
+	}
+
+	if actual, _ := result.JSON(); !strings.Contains(actual, `"message":"1 lines matching ERROR\nERROR disk full | retrying"`) {
+		t.Errorf("CheckResult.JSON() should keep the message as it is: %s", actual)
+	}
+}
+
+func TestNewCheckResult(t *testing.T) {
+	type testItem struct {
+		description  string
+		result       CheckResult
+		expectedMsg  string
+		expectedCode int
+	}
+
+	metric := PerfData{Label: "procs", Value: 3, Min: "0"}
+
+	testList := []testItem{
+		{"OK", OKResult("CheckProcess", "Process worker is running"), "CheckProcess OK - Process worker is running", 0},
+		{"Warning with perfdata", WarningResult("CheckProcess", "3 instances of worker running", metric), "CheckProcess WARNING - 3 instances of worker running | procs=3;;;0", 1},
+		{"Critical", CriticalResult("CheckProcess", "Process worker is not running"), "CheckProcess CRITICAL - Process worker is not running", 2},
+		{"Unknown", UnknownResult("CheckProcess", "Invalid range"), "CheckProcess UNKNOWN - Invalid range", 3},
+		{"State out of range", NewCheckResult("CheckProcess", State(7), "Odd state"), "CheckProcess UNKNOWN - Odd state", 3},
+	}
+
+	for _, i := range testList {
+		msg, code := i.result.Output()
+
+		if msg != i.expectedMsg {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
+
+		if code != i.expectedCode {
+			t.Errorf("%s: Expected Code: %d, Actual Code: %d", i.description, i.expectedCode, code)
+		}
+	}
+
+	if StateWarning.String() != statusTextWarning || StateWarning.ExitCode() != statusCodeWarning {
+		t.Errorf("StateWarning should be %s exiting with %d", statusTextWarning, statusCodeWarning)
+	}
+}
+
+func TestInvertResult(t *testing.T) {
+	type testItem struct {
+		msg          string
+		code         int
+		expectedMsg  string
+		expectedCode int
+	}
+
+	testList := []testItem{
+		{"CheckFileExists OK - /tmp/lock exists | matches=1;;;0", 0, "CheckFileExists CRITICAL - /tmp/lock exists | matches=1;;;0", 2},
+		{"CheckTcp CRITICAL - Connection to 127.0.0.1:23 failed", 2, "CheckTcp OK - Connection to 127.0.0.1:23 failed", 0},
+		{"CheckLoad WARNING - Load average is 4.00, 3.00, 2.00", 1, "CheckLoad WARNING - Load average is 4.00, 3.00, 2.00", 1},
+		{"CheckProcess UNKNOWN - Could not read the process list", 3, "CheckProcess UNKNOWN - Could not read the process list", 3},
+		{"unknown flag: --bogus", 0, "unknown flag: --bogus", 2},
+	}
+
+	for _, i := range testList {
+		msg, code := InvertResult(i.msg, i.code)
+
+		if msg != i.expectedMsg || code != i.expectedCode {
+			t.Errorf("InvertResult(%q, %d) Expected: %q %d, Actual: %q %d", i.msg, i.code, i.expectedMsg, i.expectedCode, msg, code)
+		}
+	}
+
+	if inverted := OKResult("CheckProcess", "Process worker is running").Invert(); inverted.Status != statusTextCritical || inverted.Code != statusCodeCritical {
+		t.Errorf("CheckResult.Invert() of OK should be CRITICAL: %+v", inverted)
+	}
+}
+
+func TestRemapResult(t *testing.T) {
+	type testItem struct {
+		msg          string
+		code         int
+		newCode      int
+		expectedMsg  string
+		expectedCode int
+	}
+
+	testList := []testItem{
+		{"CheckTcp CRITICAL - Connection to 127.0.0.1:23 failed", 2, 1, "CheckTcp WARNING - Connection to 127.0.0.1:23 failed", 1},
+		{"CheckProcess UNKNOWN - Could not read the process list | count=0", 3, 0, "CheckProcess OK - Could not read the process list | count=0", 0},
+		{"CheckLoad WARNING - Load average is 4.00, 3.00, 2.00", 1, 1, "CheckLoad WARNING - Load average is 4.00, 3.00, 2.00", 1},
+		{"CheckDisk CRITICAL - Disk used on / is 97.00%", 2, 20, "CheckDisk CRITICAL - Disk used on / is 97.00%", 20},
+		{"unknown flag: --bogus", 3, 1, "unknown flag: --bogus", 1},
+	}
+
+	for _, i := range testList {
+		msg, code := RemapResult(i.msg, i.code, i.newCode)
+
+		if msg != i.expectedMsg || code != i.expectedCode {
+			t.Errorf("RemapResult(%q, %d, %d) Expected: %q %d, Actual: %q %d", i.msg, i.code, i.newCode, i.expectedMsg, i.expectedCode, msg, code)
+		}
+	}
+}
+
+func TestCheckResultRemap(t *testing.T) {
+	result := CriticalResult("CheckTcp", "Connection to 127.0.0.1:23 failed", PerfData{Label: "time", Value: 0})
+
+	if remapped := result.Remap(statusCodeWarning); remapped.Status != statusTextWarning || remapped.Code != statusCodeWarning || remapped.Message != result.Message || len(remapped.PerfData) != 1 {
+		t.Errorf("CheckResult.Remap() should change the status and code only: %+v", remapped)
+	}
+
+	remapped := result.Remap(20)
+	if msg, code := remapped.Output(); remapped.Status != statusTextCritical || code != 20 || msg != result.String() {
+		t.Errorf("CheckResult.Remap() outside of the Nagios range should keep the status: %+v", remapped)
+	}
+}
+
+func TestTruncateResult(t *testing.T) {
+	type testItem struct {
+		description string
+		msg         string
+		maxLength   int
+		expected    string
+	}
+
+	lines := "CheckLog WARNING - 3 lines matching ERROR\nERROR one\nERROR two\nERROR three"
+
+	testList := []testItem{
+		{"Short enough", "CheckLoad OK - Load average is 0.50 | load1=0.5", 100, "CheckLoad OK - Load average is 0.50 | load1=0.5"},
+		{"No limit", lines, 0, lines},
+		{"Perfdata kept", lines + " | matches=3;0", 75, "CheckLog WARNING - 3 lines matching ERROR\nERROR...(truncated) | matches=3;0"},
+		{"No perfdata", lines, 61, "CheckLog WARNING - 3 lines matching ERROR\nERROR...(truncated)"},
+		{"First line kept", lines + " | matches=3;0", 20, "CheckLog WARNING - 3 lines matching ERROR...(truncated) | matches=3;0"},
+		{"First line alone", "CheckLoad OK - Load average is 0.50 | load1=0.5", 20, "CheckLoad OK - Load average is 0.50 | load1=0.5"},
+		{"Within a character", "CheckLog OK - 1 lines\nnaïve and a much longer line", 39, "CheckLog OK - 1 lines\nna...(truncated)"},
+	}
+
+	for _, i := range testList {
+		if truncated := TruncateResult(i.msg, i.maxLength); truncated != i.expected {
+			t.Errorf("%s: Expected: %q, Actual: %q", i.description, i.expected, truncated)
+		}
+	}
+}
+
+func TestRecoverCheck(t *testing.T) {
+	msg, code := RecoverCheck("CheckLoad", func() (string, int) {
+		return OKResult("CheckLoad", "Load average is 0.50, 0.40, 0.30").Output()
+	})
+
+	if code != statusCodeOK || msg != "CheckLoad OK - Load average is 0.50, 0.40, 0.30" {
+		t.Errorf("RecoverCheck() should return the result of a check that does not panic: %d %s", code, msg)
+	}
+
+	msg, code = RecoverCheck("CheckLoad", func() (string, int) {
+		var fields []string
+		return fields[3], statusCodeOK
+	})
+
+	expected := "CheckLoad UNKNOWN - The check failed unexpectedly: runtime error: index out of range"
+	if code != statusCodeUnknown || !strings.HasPrefix(msg, expected) {
+		t.Errorf("RecoverCheck() should return UNKNOWN for a check that panics. Expected: %s, Actual: %d %s", expected, code, msg)
+	}
+}
+
+func TestRecoverCheckResult(t *testing.T) {
+	result := RecoverCheckResult("CheckLoad", func() CheckResult {
+		return OKResult("CheckLoad", "Load average is 0.50, 0.40, 0.30")
+	})
+
+	if result.State() != StateOK || result.Message != "Load average is 0.50, 0.40, 0.30" {
+		t.Errorf("RecoverCheckResult() should return the result of a check that does not panic: %+v", result)
+	}
+
+	result = RecoverCheckResult("CheckLoad", func() CheckResult {
+		var fields []string
+		return OKResult("CheckLoad", fields[3])
+	})
+
+	expected := "The check failed unexpectedly: runtime error: index out of range"
+	if result.State() != StateUnknown || result.Name != "CheckLoad" || !strings.HasPrefix(result.Message, expected) {
+		t.Errorf("RecoverCheckResult() should return UNKNOWN for a check that panics. Expected: %s, Actual: %+v", expected, result)
+	}
+}
+
+func TestParseState(t *testing.T) {
+	states := map[string]State{"ok": StateOK, "Warning": StateWarning, "CRITICAL": StateCritical, "unknown": StateUnknown}
+	for text, expected := range states {
+		if state, err := ParseState(text); err != nil || state != expected {
+			t.Errorf("ParseState(%q) Expected: %s, Actual: %s, Error: %v", text, expected, state, err)
+		}
+	}
+
+	for _, text := range []string{"", "failed", "2"} {
+		if _, err := ParseState(text); err == nil {
+			t.Errorf("ParseState(%q) should return an error", text)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package nagiosfoundation
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// debugLog logs the diagnostic messages explaining a result, such as
+// the errors behind an UNKNOWN result or those the checks otherwise
+// pass over. The messages are discarded unless enabled with
+// SetVerbose().
+var debugLog = log.New(ioutil.Discard, "DEBUG ", log.LstdFlags)
+
+// The levels of detail added to the plugin output by SetVerbosity(),
+// following the -v, -vv and -vvv of the monitoring-plugins.
+const (
+	// VerbosityDiagnostic logs the diagnostic messages to stderr and
+	// adds a little detail to the result line, such as the PIDs of
+	// check_process.
+	VerbosityDiagnostic = 1
+
+	// VerbosityBreakdown adds a line to the result for each item
+	// matched, such as each process counted.
+	VerbosityBreakdown = 2
+
+	// VerbosityRaw adds the raw values read, such as the counters of
+	// a network interface.
+	VerbosityRaw = 3
+)
+
+// verbosity is the level of detail set by SetVerbosity().
+var verbosity int
+
+// verboseEnabled is set by SetVerbose(), for the checks adding detail
+// to their result under --verbose, such as the PIDs of check_process.
+var verboseEnabled bool
+
+// SetVerbose enables the diagnostic messages when verbose is set,
+// writing them to stderr so they are kept apart from the plugin
+// output on stdout.
+func SetVerbose(verbose bool) {
+	level := 0
+	if verbose {
+		level = VerbosityDiagnostic
+	}
+
+	SetVerbosity(level)
+}
+
+// SetVerbosity sets the level of detail of the checks, the number of
+// -v flags given. From VerbosityDiagnostic the diagnostic messages
+// are written to stderr as with SetVerbose(), and from
+// VerbosityBreakdown the checks add lines of detail to their result,
+// the long output kept by Nagios below the first line.
+func SetVerbosity(level int) {
+	var w io.Writer = ioutil.Discard
+	if level >= VerbosityDiagnostic {
+		w = os.Stderr
+	}
+
+	debugLog.SetOutput(w)
+	verbosity = level
+	verboseEnabled = level >= VerbosityDiagnostic
+}
+
+// verboseLines returns the lines to add to a result at the level of
+// detail, each starting on a line of its own, or empty below the level.
+func verboseLines(level int, lines ...string) string {
+	if verbosity < level || len(lines) == 0 {
+		return ""
+	}
+
+	return "\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,50 @@
+package nagiosfoundation
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDebugLog(t *testing.T) {
+	defer SetVerbose(false)
+
+	SetVerbose(true)
+	if debugLog.Writer() != os.Stderr {
+		t.Error("SetVerbose(true) should log to stderr")
+	}
+
+	SetVerbose(false)
+	if debugLog.Writer() != ioutil.Discard {
+		t.Error("SetVerbose(false) should discard the log")
+	}
+
+	SetVerbosity(VerbosityBreakdown)
+	if debugLog.Writer() != os.Stderr || !verboseEnabled {
+		t.Error("SetVerbosity() should log to stderr from the diagnostic level")
+	}
+
+	if lines := verboseLines(VerbosityBreakdown, "one", "two"); lines != "\none\ntwo" {
+		t.Errorf("verboseLines() should add the lines at the level, Actual: %q", lines)
+	}
+
+	if lines := verboseLines(VerbosityRaw, "raw"); lines != "" {
+		t.Errorf("verboseLines() should add nothing above the level, Actual: %q", lines)
+	}
+
+	SetVerbosity(0)
+
+	var logged bytes.Buffer
+	debugLog.SetOutput(&logged)
+
+	// The stat of a process exiting during the scan does not exist.
+	svc := testProcHandlers([]string{"100"}, map[string]string{})
+
+	getProcessesByNameWithHandlers(svc, "sshd")
+
+	if !strings.Contains(logged.String(), "Skipping process 100, it has exited") {
+		t.Errorf("The skipped process should be logged, logged: %s", logged.String())
+	}
+}
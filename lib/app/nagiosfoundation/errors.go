@@ -0,0 +1,132 @@
+package nagiosfoundation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+)
+
+// The kinds of the errors of the checks, for programs embedding the
+// checks to tell apart why a check failed, such as with errors.Is() or
+// IsErrorKind().
+var (
+	// ErrTargetNotFound is the kind of the errors of a check not
+	// finding what it checks, such as a process that is not running
+	// or a heartbeat file that does not exist.
+	ErrTargetNotFound = errors.New("target not found")
+
+	// ErrPermission is the kind of the errors of a check denied the
+	// access to what it checks, such as the processes of other users
+	// on a proc filesystem mounted with hidepid.
+	ErrPermission = errors.New("permission denied")
+
+	// ErrTimeout is the kind of the errors of a check not completing
+	// in time, such as a command killed at its deadline.
+	ErrTimeout = errors.New("timed out")
+)
+
+// CheckError is an error of a check of one of the kinds
+// ErrTargetNotFound, ErrPermission or ErrTimeout. It is the kind with
+// errors.Is(), and unwraps to the error it was made from.
+type CheckError struct {
+	Kind error
+	Err  error
+}
+
+func (e *CheckError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error the CheckError was made from.
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of the error.
+func (e *CheckError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// ErrorKind returns the kind of the error, ErrTargetNotFound,
+// ErrPermission or ErrTimeout, or nil for an error of none of them.
+func ErrorKind(err error) error {
+	if checkErr, ok := err.(*CheckError); ok {
+		return checkErr.Kind
+	}
+
+	return nil
+}
+
+// IsErrorKind reports whether the error is of the kind, as errors.Is()
+// does for a CheckError.
+func IsErrorKind(err error, kind error) bool {
+	return err != nil && ErrorKind(err) == kind
+}
+
+// classifyError returns the error as a CheckError of the kind it is
+// from the error itself, ErrPermission for a permission denied, such
+// as of a file or of connecting to a unix socket, and ErrTimeout for a
+// deadline exceeded or a network timeout. Other errors, such as a
+// file that does not exist, are returned as they are, as only the
+// caller knows whether the file is what the check looks for.
+func classifyError(err error) error {
+	switch {
+	case err == nil || ErrorKind(err) != nil:
+		return err
+	case os.IsPermission(err) || os.IsPermission(connectionCause(err)):
+		return &CheckError{Kind: ErrPermission, Err: err}
+	case err == context.DeadlineExceeded || isTimeout(err):
+		return &CheckError{Kind: ErrTimeout, Err: err}
+	}
+
+	return err
+}
+
+// connectionCause returns the error below the url.Error and
+// net.OpError of a failed connection, such as the permission denied of
+// a unix socket, which os.IsPermission() does not look through.
+func connectionCause(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+
+	return err
+}
+
+// wrapError returns an error with the message of the format, such as
+// one adding what the check was doing to the message of err, of the
+// kind of err, as fmt.Errorf() with %w does from Go 1.13.
+func wrapError(err error, format string, args ...interface{}) error {
+	wrapped := fmt.Errorf(format, args...)
+	if kind := ErrorKind(classifyError(err)); kind != nil {
+		return &CheckError{Kind: kind, Err: wrapped}
+	}
+
+	return wrapped
+}
+
+// ErrorState returns the state of a check failing with the error,
+// CRITICAL for an error of the kind ErrTargetNotFound, as what is
+// checked is missing, and UNKNOWN for any other error, as the check
+// could not tell.
+func ErrorState(err error) State {
+	if IsErrorKind(err, ErrTargetNotFound) {
+		return StateCritical
+	}
+
+	return StateUnknown
+}
+
+// errorResult returns the result of the named check failing with the
+// error, in the state returned by ErrorState(), with the message
+// describing it.
+func errorResult(name, message string, err error) CheckResult {
+	return NewCheckResult(name, ErrorState(err), message)
+}
@@ -0,0 +1,91 @@
+package nagiosfoundation
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestErrorKind(t *testing.T) {
+	permissionErr := &os.PathError{Op: "open", Path: "/proc/100/stat", Err: os.ErrPermission}
+	timeoutErr := &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{IsTimeout: true}}
+	socketErr := &url.Error{Op: "Get", URL: "http://docker/_ping",
+		Err: &net.OpError{Op: "dial", Net: "unix", Err: os.NewSyscallError("connect", syscall.EACCES)}}
+
+	type testItem struct {
+		description   string
+		err           error
+		expectedKind  error
+		expectedState State
+	}
+
+	testList := []testItem{
+		{"Permission denied", classifyError(permissionErr), ErrPermission, StateUnknown},
+		{"Deadline exceeded", classifyError(context.DeadlineExceeded), ErrTimeout, StateUnknown},
+		{"Network timeout", classifyError(timeoutErr), ErrTimeout, StateUnknown},
+		{"Socket permission denied", classifyError(socketErr), ErrPermission, StateUnknown},
+		{"Missing file left to the caller", classifyError(os.ErrNotExist), nil, StateUnknown},
+		{"Other error", classifyError(errors.New("bad stat")), nil, StateUnknown},
+		{"Wrapped permission denied", wrapError(permissionErr, "Could not read the name of process 100: %s", permissionErr), ErrPermission, StateUnknown},
+		{"Wrapped other error", wrapError(os.ErrClosed, "Could not read: %s", os.ErrClosed), nil, StateUnknown},
+		{"Target not found", errProcessNotRunning, ErrTargetNotFound, StateCritical},
+		{"Already classified", classifyError(errProcessNotRunning), ErrTargetNotFound, StateCritical},
+	}
+
+	for _, i := range testList {
+		if kind := ErrorKind(i.err); kind != i.expectedKind {
+			t.Errorf("%s: Expected Kind: %v, Actual Kind: %v", i.description, i.expectedKind, kind)
+		}
+
+		if i.expectedKind != nil && !IsErrorKind(i.err, i.expectedKind) {
+			t.Errorf("%s: IsErrorKind() should report the kind %v", i.description, i.expectedKind)
+		}
+
+		if checkErr, ok := i.err.(*CheckError); ok && !checkErr.Is(i.expectedKind) {
+			t.Errorf("%s: CheckError.Is() should report the kind %v", i.description, i.expectedKind)
+		}
+
+		if state := ErrorState(i.err); state != i.expectedState {
+			t.Errorf("%s: Expected State: %s, Actual State: %s", i.description, i.expectedState, state)
+		}
+	}
+
+	wrapped := wrapError(permissionErr, "Could not read the name of process 100: %s", permissionErr)
+	if msg := wrapped.Error(); msg != "Could not read the name of process 100: open /proc/100/stat: permission denied" {
+		t.Errorf("wrapError() message incorrect: %s", msg)
+	}
+
+	if IsErrorKind(nil, ErrPermission) {
+		t.Error("IsErrorKind() should not report a kind for no error")
+	}
+}
+
+func TestProcessScanErrorKind(t *testing.T) {
+	svc := testProcHandlers([]string{"100"}, map[string]string{})
+	svc.readFile = func(path string) ([]byte, error) {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrPermission}
+	}
+
+	_, err := getProcessesByNameWithHandlers(svc, "java")
+	if !IsErrorKind(err, ErrPermission) || !strings.Contains(err.Error(), "Could not read the name of process 100") {
+		t.Errorf("getProcessesByNameWithHandlers() should return an ErrPermission error for an unreadable process, returned %v", err)
+	}
+
+	svc.open = func(string) (*os.File, error) {
+		return nil, &os.PathError{Op: "open", Path: "/proc", Err: os.ErrPermission}
+	}
+
+	if _, err = getProcessesByNameWithHandlers(svc, "java"); !IsErrorKind(err, ErrPermission) {
+		t.Errorf("getProcessesByNameWithHandlers() should return an ErrPermission error for an unreadable proc filesystem, returned %v", err)
+	}
+
+	msg, code := errorResult(checkProcessName, "Could not determine if process java is running: "+err.Error(), err).Output()
+	if code != statusCodeUnknown || !strings.HasPrefix(msg, "CheckProcess UNKNOWN - Could not determine") {
+		t.Errorf("errorResult() should be UNKNOWN for a permission denied, returned %d: %s", code, msg)
+	}
+}
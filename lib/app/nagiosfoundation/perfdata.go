@@ -0,0 +1,149 @@
+package nagiosfoundation
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxPluginOutputLength is the length of plugin output Nagios reads
+// before truncating it. It matches MAX_PLUGIN_OUTPUT_LENGTH in Nagios
+// Core.
+const maxPluginOutputLength = 8192
+
+const perfDataSeparator = " | "
+
+// PerfData is a single Nagios performance data metric. Only the
+// label and value are required. The unit of measure, thresholds
+// and range are omitted from the output when empty.
+type PerfData struct {
+	Label    string  `json:"label"`
+	Value    float64 `json:"value"`
+	UOM      string  `json:"uom,omitempty"`
+	Warning  string  `json:"warning,omitempty"`
+	Critical string  `json:"critical,omitempty"`
+	Min      string  `json:"min,omitempty"`
+	Max      string  `json:"max,omitempty"`
+}
+
+// String renders the metric in the Nagios perfdata format of
+// label=value[UOM];[warn];[crit];[min];[max]. A value that is not a
+// number, such as NaN, is rendered as "U", the value Nagios takes as
+// undetermined, without a unit.
+func (p PerfData) String() string {
+	value := "U"
+	if !math.IsNaN(p.Value) && !math.IsInf(p.Value, 0) {
+		value = strconv.FormatFloat(p.Value, 'f', -1, 64) + p.UOM
+	}
+
+	metric := quotePerfDataLabel(p.Label) + "=" + value
+
+	// Trailing empty fields are dropped, empty fields between
+	// populated fields must be kept to preserve their positions.
+	fields := []string{p.Warning, p.Critical, p.Min, p.Max}
+	last := len(fields)
+	for last > 0 && fields[last-1] == "" {
+		last--
+	}
+
+	for _, field := range fields[:last] {
+		metric += ";" + field
+	}
+
+	return metric
+}
+
+// perfDataLabelNewlines replaces the line breaks of a label, which
+// would end the perfdata, with spaces.
+var perfDataLabelNewlines = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// quotePerfDataLabel quotes a label containing spaces, equals signs
+// or single quotes with single quotes, doubling any single quotes
+// within it as Nagios requires.
+func quotePerfDataLabel(label string) string {
+	label = perfDataLabelNewlines.Replace(label)
+	if !strings.ContainsAny(label, " \t='") {
+		return label
+	}
+
+	return "'" + strings.Replace(label, "'", "''", -1) + "'"
+}
+
+// FormatPerfData renders a single metric in the Nagios perfdata
+// format. The warn, crit, min and max fields may be empty.
+func FormatPerfData(label string, value float64, warn, crit, min, max string) string {
+	return PerfData{
+		Label:    label,
+		Value:    value,
+		Warning:  warn,
+		Critical: crit,
+		Min:      min,
+		Max:      max,
+	}.String()
+}
+
+// PerfDataSet is the metrics of a result, such as those of a check
+// emitting several metrics or of several checks run together, in the
+// order they are output.
+type PerfDataSet []PerfData
+
+// Append adds the metrics to the end of the set.
+func (s *PerfDataSet) Append(metrics ...PerfData) {
+	*s = append(*s, metrics...)
+}
+
+// String renders the metrics as the perfdata section of the Nagios
+// output, the part that follows the pipe character, each metric
+// separated by a single space.
+func (s PerfDataSet) String() string {
+	rendered := make([]string, 0, len(s))
+
+	for _, metric := range s {
+		rendered = append(rendered, metric.String())
+	}
+
+	return strings.Join(rendered, " ")
+}
+
+// fit renders the metrics that fit in the output after outputLength
+// bytes of message, within the limit. The metrics are given in
+// priority order and when they don't all fit, whole metrics are
+// dropped from the end so a metric is never cut part way through and
+// the perfdata remains well formed.
+func (s PerfDataSet) fit(outputLength int, limit int) string {
+	rendered := make([]string, 0, len(s))
+	length := outputLength + len(perfDataSeparator)
+
+	for _, metric := range s {
+		metricText := metric.String()
+
+		metricLength := len(metricText)
+		if len(rendered) > 0 {
+			metricLength++
+		}
+
+		if length+metricLength > limit {
+			break
+		}
+
+		rendered = append(rendered, metricText)
+		length += metricLength
+	}
+
+	return strings.Join(rendered, " ")
+}
+
+// perfDataResultMessage returns the result message with as many of
+// the metrics as fit within maxPluginOutputLength.
+func perfDataResultMessage(checkName, statusText, desc string, metrics PerfDataSet) string {
+	msg, err := resultMessage(checkName, statusText, desc)
+	if err != nil {
+		return msg
+	}
+
+	if perfData := metrics.fit(len(msg), maxPluginOutputLength); perfData != "" {
+		msg += perfDataSeparator + perfData
+	}
+
+	return msg
+}
@@ -0,0 +1,188 @@
+package nagiosfoundation
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPerfData(t *testing.T) {
+	type testItem struct {
+		description string
+		metric      PerfData
+		expected    string
+	}
+
+	testList := []testItem{
+		{
+			description: "Label and value only",
+			metric:      PerfData{Label: "procs", Value: 3},
+			expected:    "procs=3",
+		},
+		{
+			description: "All fields populated",
+			metric:      PerfData{Label: "rss", Value: 512.5, UOM: "MB", Warning: "800", Critical: "1000", Min: "0", Max: "2048"},
+			expected:    "rss=512.5MB;800;1000;0;2048",
+		},
+		{
+			description: "Empty warning kept but trailing fields dropped",
+			metric:      PerfData{Label: "cpu", Value: 42, UOM: "%", Critical: "90"},
+			expected:    "cpu=42%;;90",
+		},
+		{
+			description: "Label with spaces quoted",
+			metric:      PerfData{Label: "C: free", Value: 10, UOM: "GB"},
+			expected:    "'C: free'=10GB",
+		},
+		{
+			description: "Label with quote escaped",
+			metric:      PerfData{Label: "bob's procs", Value: 1},
+			expected:    "'bob''s procs'=1",
+		},
+		{
+			description: "Label with equals sign quoted",
+			metric:      PerfData{Label: "rate=1m", Value: 2},
+			expected:    "'rate=1m'=2",
+		},
+		{
+			description: "Label with line break and tab",
+			metric:      PerfData{Label: "line1\nline2\tcol", Value: 4},
+			expected:    "'line1 line2\tcol'=4",
+		},
+		{
+			description: "Undetermined value",
+			metric:      PerfData{Label: "ratio", Value: math.NaN(), UOM: "%", Min: "0", Max: "100"},
+			expected:    "ratio=U;;;0;100",
+		},
+		{
+			description: "Infinite value",
+			metric:      PerfData{Label: "ratio", Value: math.Inf(1), UOM: "%"},
+			expected:    "ratio=U",
+		},
+	}
+
+	for _, i := range testList {
+		if actual := i.metric.String(); actual != i.expected {
+			t.Errorf("%s: Expected: %s, Actual: %s", i.description, i.expected, actual)
+		}
+	}
+}
+
+func TestFormatPerfDataMetric(t *testing.T) {
+	type testItem struct {
+		description string
+		actual      string
+		expected    string
+	}
+
+	testList := []testItem{
+		{"All fields", FormatPerfData("procs", 3, "5", "10", "0", ""), "procs=3;5;10;0"},
+		{"Empty thresholds", FormatPerfData("procs", 3, "", "", "0", ""), "procs=3;;;0"},
+		{"Value only", FormatPerfData("procs", 3, "", "", "", ""), "procs=3"},
+		{"Quoted label", FormatPerfData("worker procs", 3, "5:10", "", "", ""), "'worker procs'=3;5:10"},
+	}
+
+	for _, i := range testList {
+		if i.actual != i.expected {
+			t.Errorf("%s: Expected: %s, Actual: %s", i.description, i.expected, i.actual)
+		}
+	}
+}
+
+func TestPerfDataSet(t *testing.T) {
+	metrics := PerfDataSet{
+		{Label: "procs", Value: 3, Warning: "5", Critical: "10", Min: "0"},
+		{Label: "rss", Value: 256, UOM: "MB", Warning: "512", Critical: "1024"},
+		{Label: "cpu", Value: 12.5, UOM: "%", Warning: "80", Critical: "90", Min: "0", Max: "100"},
+	}
+
+	expected := "procs=3;5;10;0 rss=256MB;512;1024 cpu=12.5%;80;90;0;100"
+	if actual := metrics.String(); actual != expected {
+		t.Errorf("PerfDataSet.String() with count, memory and cpu metrics. Expected: %s, Actual: %s", expected, actual)
+	}
+
+	if actual := PerfDataSet(nil).String(); actual != "" {
+		t.Errorf("PerfDataSet.String() with no metrics should be empty. Actual: %s", actual)
+	}
+
+	var set PerfDataSet
+	set.Append(metrics[0])
+	set.Append(metrics[1:]...)
+	set.Append(PerfData{Label: "worker procs", Value: 2})
+	if actual := set.String(); actual != expected+" 'worker procs'=2" {
+		t.Errorf("PerfDataSet.Append() should add the metrics in order. Expected: %s, Actual: %s", expected+" 'worker procs'=2", actual)
+	}
+
+	msg, _ := resultMessage(checkProcessName, statusTextOK, "Process worker is running", metrics.String())
+	expected = "CheckProcess OK - Process worker is running | " + expected
+	if msg != expected {
+		t.Errorf("resultMessage() with combined perfdata. Expected: %s, Actual: %s", expected, msg)
+	}
+}
+
+func TestPerfDataSetFit(t *testing.T) {
+	metrics := PerfDataSet{
+		{Label: "procs", Value: 3},
+		{Label: "rss", Value: 256, UOM: "MB"},
+		{Label: "cpu", Value: 12.5, UOM: "%"},
+	}
+
+	type testItem struct {
+		description  string
+		outputLength int
+		limit        int
+		expected     string
+	}
+
+	// " | " adds 3, "procs=3" is 7, " rss=256MB" is 10, " cpu=12.5%" is 10
+	testList := []testItem{
+		{"Everything fits", 10, 100, "procs=3 rss=256MB cpu=12.5%"},
+		{"Exactly fits", 10, 40, "procs=3 rss=256MB cpu=12.5%"},
+		{"Last metric dropped", 10, 39, "procs=3 rss=256MB"},
+		{"Only first metric fits", 10, 29, "procs=3"},
+		{"No metric fits", 10, 19, ""},
+		{"Message over limit", 50, 40, ""},
+	}
+
+	for _, i := range testList {
+		if actual := metrics.fit(i.outputLength, i.limit); actual != i.expected {
+			t.Errorf("%s: Expected: %s, Actual: %s", i.description, i.expected, actual)
+		}
+	}
+}
+
+func TestPerfDataResultMessage(t *testing.T) {
+	metrics := make([]PerfData, 2000)
+	for i := range metrics {
+		metrics[i] = PerfData{Label: "procs_docker_" + strconv.Itoa(i), Value: float64(i), Min: "0"}
+	}
+
+	msg := perfDataResultMessage(checkProcessName, statusTextOK, "Process worker count in range", metrics)
+
+	if len(msg) > maxPluginOutputLength {
+		t.Errorf("perfDataResultMessage() output of %d exceeds the limit of %d", len(msg), maxPluginOutputLength)
+	}
+
+	parts := strings.SplitN(msg, perfDataSeparator, 2)
+	if len(parts) != 2 || parts[0] != "CheckProcess OK - Process worker count in range" {
+		t.Fatalf("perfDataResultMessage() output malformed: %s", msg)
+	}
+
+	kept := strings.Split(parts[1], " ")
+	if len(kept) == 0 || len(kept) == len(metrics) {
+		t.Errorf("perfDataResultMessage() should have kept some but not all metrics, kept %d", len(kept))
+	}
+
+	for i, metric := range kept {
+		if metric != metrics[i].String() {
+			t.Errorf("perfDataResultMessage() kept metric %d malformed or out of order: %s", i, metric)
+			break
+		}
+	}
+
+	msg = perfDataResultMessage(checkProcessName, statusTextOK, "Nothing to report", nil)
+	if msg != "CheckProcess OK - Nothing to report" {
+		t.Errorf("perfDataResultMessage() without metrics should not have a perfdata section: %s", msg)
+	}
+}
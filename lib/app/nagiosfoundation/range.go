@@ -0,0 +1,425 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Range is a Nagios threshold range as described in the Nagios
+// plugin development guidelines. A value outside of Start to End,
+// inclusive, raises an alert. When Inside is set, the alert is
+// instead raised for a value inside of Start to End.
+type Range struct {
+	Start  float64
+	End    float64
+	Inside bool
+}
+
+// ParseRange parses a threshold in the Nagios range format of
+// [@]start:end. Only end is required:
+//
+//	10      alert when below 0 or above 10
+//	10:     alert when below 10
+//	~:10    alert when above 10, ~ meaning negative infinity
+//	10:20   alert when below 10 or above 20
+//	@10:20  alert when 10 or above and 20 or below
+func ParseRange(threshold string) (Range, error) {
+	r, err := parseRange(threshold, false)
+
+	return r.Range, err
+}
+
+// sizeSuffixes are the multipliers of the size suffixes accepted in
+// the amounts of a RelativeRange.
+var sizeSuffixes = map[string]float64{
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+	"P": 1 << 50,
+}
+
+// RelativeRange is a Nagios range whose bounds may be percentages of
+// a total, such as "80%" or "10%:90%", for a check knowing the total
+// the value is an amount of, such as the size of a filesystem. The
+// bounds that are percentages are resolved to amounts of the total
+// with Resolve() once the check knows the total, or the amounts to
+// percentages of it with Percentages().
+type RelativeRange struct {
+	Range
+
+	// Whether Start and End are percentages of the total rather than
+	// amounts.
+	StartPercent bool
+	EndPercent   bool
+}
+
+// ParseRelativeRange parses a threshold in the Nagios range format,
+// as ParseRange() does, in which start and end may also be a
+// percentage from 0% to 100% of a total, such as "80%" to alert above
+// 80% of the total or "10%:" to alert below 10% of it. A range may mix
+// the two, such as "100:50%". An amount may have a size suffix of K,
+// M, G, T or P, powers of 1024 with an optional B or iB, such as "2G"
+// or "512MiB", for a total that is a size.
+func ParseRelativeRange(threshold string) (RelativeRange, error) {
+	return parseRange(threshold, true)
+}
+
+// Resolve returns the range with the bounds that are percentages
+// resolved to amounts of the total.
+func (r RelativeRange) Resolve(total float64) Range {
+	resolved := r.Range
+
+	if r.StartPercent {
+		resolved.Start = r.Start / 100 * total
+	}
+
+	if r.EndPercent {
+		resolved.End = r.End / 100 * total
+	}
+
+	return resolved
+}
+
+// Percentages returns the range with the bounds that are amounts
+// converted to percentages of the total, for comparing a percentage
+// of the total such as the percentage of a filesystem used.
+func (r RelativeRange) Percentages(total float64) Range {
+	converted := r.Range
+
+	if !r.StartPercent {
+		converted.Start = r.Start / total * 100
+	}
+
+	if !r.EndPercent {
+		converted.End = r.End / total * 100
+	}
+
+	return converted
+}
+
+// parseRangeBound parses a start or end bound of a range, a number or,
+// when relative is set, a percentage such as "80%" or a size such as
+// "2G", reporting whether it is a percentage. The error completes a
+// sentence naming the bound.
+func parseRangeBound(text string, relative bool) (float64, bool, error) {
+	if !relative {
+		bound, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("is not a number")
+		}
+
+		return bound, false, nil
+	}
+
+	if strings.HasSuffix(text, "%") {
+		bound, err := strconv.ParseFloat(strings.TrimSuffix(text, "%"), 64)
+		if err != nil {
+			return 0, true, fmt.Errorf("is not a number")
+		} else if bound < 0 || bound > 100 {
+			return 0, true, fmt.Errorf("is not a percentage from 0%% to 100%%")
+		}
+
+		return bound, true, nil
+	}
+
+	amount := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(text), "B"), "I")
+
+	multiplier := 1.0
+	if n := len(amount); n > 0 && sizeSuffixes[amount[n-1:]] != 0 {
+		multiplier, amount = sizeSuffixes[amount[n-1:]], amount[:n-1]
+	}
+
+	bound, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("is not a number, a percentage such as 90%% or a size such as 2G")
+	}
+
+	return bound * multiplier, false, nil
+}
+
+func parseRange(threshold string, relative bool) (RelativeRange, error) {
+	r := RelativeRange{Range: Range{End: math.Inf(1)}}
+	text := strings.TrimSpace(threshold)
+
+	if strings.HasPrefix(text, "@") {
+		r.Inside = true
+		text = text[1:]
+	}
+
+	if text == "" {
+		return r, fmt.Errorf("Invalid range %q, no end given", threshold)
+	}
+
+	startText, endText := "", text
+	if i := strings.Index(text, ":"); i >= 0 {
+		startText, endText = text[:i], text[i+1:]
+	}
+
+	var err error
+
+	switch startText {
+	case "~":
+		r.Start = math.Inf(-1)
+	case "":
+		r.Start = 0
+	default:
+		if r.Start, r.StartPercent, err = parseRangeBound(startText, relative); err != nil {
+			return r, fmt.Errorf("Invalid range %q, start %s", threshold, err)
+		}
+	}
+
+	if endText != "" {
+		if r.End, r.EndPercent, err = parseRangeBound(endText, relative); err != nil {
+			return r, fmt.Errorf("Invalid range %q, end %s", threshold, err)
+		}
+	}
+
+	// A range mixing a percentage and an amount is only ordered once
+	// the total is known.
+	if r.StartPercent == r.EndPercent && r.Start > r.End {
+		return r, fmt.Errorf("Invalid range %q, start is greater than end", threshold)
+	}
+
+	return r, nil
+}
+
+// Check returns true when the value raises an alert.
+func (r Range) Check(value float64) bool {
+	outside := value < r.Start || value > r.End
+
+	return outside != r.Inside
+}
+
+// String renders the range in the Nagios range format.
+func (r Range) String() string {
+	var text string
+
+	switch {
+	case math.IsInf(r.End, 1):
+		text = formatRangeBound(r.Start) + ":"
+	case r.Start == 0:
+		text = formatRangeBound(r.End)
+	default:
+		text = formatRangeBound(r.Start) + ":" + formatRangeBound(r.End)
+	}
+
+	if r.Inside {
+		text = "@" + text
+	}
+
+	return text
+}
+
+// Expected describes the values that do not raise an alert, such as
+// "5-10", "at least 5" or "outside 5-10". An infinite end is described
+// rather than rendered, such as "below 5" for "@5:".
+func (r Range) Expected() string {
+	startInf, endInf := math.IsInf(r.Start, -1), math.IsInf(r.End, 1)
+
+	if r.Inside {
+		switch {
+		case startInf && endInf:
+			return "no value"
+		case startInf:
+			return "above " + formatRangeBound(r.End)
+		case endInf:
+			return "below " + formatRangeBound(r.Start)
+		}
+
+		if r.Start == r.End {
+			return "other than " + formatRangeBound(r.Start)
+		}
+
+		return "outside " + formatRangeBound(r.Start) + "-" + formatRangeBound(r.End)
+	}
+
+	switch {
+	case startInf && endInf:
+		return "any value"
+	case startInf:
+		return "at most " + formatRangeBound(r.End)
+	case endInf:
+		return "at least " + formatRangeBound(r.Start)
+	case r.Start == r.End:
+		return formatRangeBound(r.Start)
+	}
+
+	return formatRangeBound(r.Start) + "-" + formatRangeBound(r.End)
+}
+
+func formatRangeBound(bound float64) string {
+	switch {
+	case math.IsInf(bound, -1):
+		return "~"
+	case math.IsInf(bound, 1):
+		return ""
+	default:
+		return strconv.FormatFloat(bound, 'f', -1, 64)
+	}
+}
+
+// Thresholds are the parsed warning and critical ranges of a check.
+// A nil range is not checked.
+type Thresholds struct {
+	Warning  *Range
+	Critical *Range
+}
+
+// ParseThresholds parses the warning and critical thresholds in the
+// Nagios range format. An empty threshold is not checked.
+func ParseThresholds(warning, critical string) (Thresholds, error) {
+	warningRange, err := parseThreshold(warning)
+	if err != nil {
+		return Thresholds{}, err
+	}
+
+	criticalRange, err := parseThreshold(critical)
+	if err != nil {
+		return Thresholds{}, err
+	}
+
+	return Thresholds{Warning: warningRange, Critical: criticalRange}, nil
+}
+
+func parseThreshold(threshold string) (*Range, error) {
+	if threshold == "" {
+		return nil, nil
+	}
+
+	r, err := ParseRange(threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// RelativeThresholds are the parsed warning and critical ranges of a
+// check whose thresholds may be percentages of a total, resolved to
+// Thresholds of amounts with Resolve().
+type RelativeThresholds struct {
+	Warning  *RelativeRange
+	Critical *RelativeRange
+}
+
+// ParseRelativeThresholds parses the warning and critical thresholds
+// in the Nagios range format, in which the bounds may be percentages
+// of a total as parsed by ParseRelativeRange(). An empty threshold is
+// not checked.
+func ParseRelativeThresholds(warning, critical string) (RelativeThresholds, error) {
+	warningRange, err := parseRelativeThreshold(warning)
+	if err != nil {
+		return RelativeThresholds{}, err
+	}
+
+	criticalRange, err := parseRelativeThreshold(critical)
+	if err != nil {
+		return RelativeThresholds{}, err
+	}
+
+	return RelativeThresholds{Warning: warningRange, Critical: criticalRange}, nil
+}
+
+func parseRelativeThreshold(threshold string) (*RelativeRange, error) {
+	if threshold == "" {
+		return nil, nil
+	}
+
+	r, err := ParseRelativeRange(threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Resolve returns the thresholds with the percentages resolved to
+// amounts of the total, to compare a value against and to report in
+// the perfdata of the value.
+func (t RelativeThresholds) Resolve(total float64) Thresholds {
+	return t.convert(func(r RelativeRange) Range { return r.Resolve(total) })
+}
+
+// Percentages returns the thresholds with the amounts converted to
+// percentages of the total, to compare a percentage of the total
+// against and to report in its perfdata.
+func (t RelativeThresholds) Percentages(total float64) Thresholds {
+	return t.convert(func(r RelativeRange) Range { return r.Percentages(total) })
+}
+
+func (t RelativeThresholds) convert(convert func(RelativeRange) Range) Thresholds {
+	var converted Thresholds
+
+	if t.Warning != nil {
+		r := convert(*t.Warning)
+		converted.Warning = &r
+	}
+
+	if t.Critical != nil {
+		r := convert(*t.Critical)
+		converted.Critical = &r
+	}
+
+	return converted
+}
+
+// round returns the thresholds with their bounds rounded to the number
+// of decimal places, such as to whole bytes for the amounts resolved
+// from percentages of a size.
+func (t Thresholds) round(places int) Thresholds {
+	scale := math.Pow(10, float64(places))
+	round := func(r *Range) *Range {
+		if r == nil {
+			return nil
+		}
+
+		rounded := *r
+		rounded.Start = math.Round(r.Start*scale) / scale
+		rounded.End = math.Round(r.End*scale) / scale
+
+		return &rounded
+	}
+
+	return Thresholds{Warning: round(t.Warning), Critical: round(t.Critical)}
+}
+
+// Status compares the value against the thresholds, returning the
+// state and, when not OK, the range raising the alert.
+func (t Thresholds) Status(value float64) (State, Range) {
+	switch {
+	case t.Critical != nil && t.Critical.Check(value):
+		return StateCritical, *t.Critical
+	case t.Warning != nil && t.Warning.Check(value):
+		return StateWarning, *t.Warning
+	}
+
+	return StateOK, Range{}
+}
+
+func formatThreshold(r *Range) string {
+	if r == nil {
+		return ""
+	}
+
+	return r.String()
+}
+
+// FormatPerfData renders the metric with FormatPerfData(), passing it
+// the thresholds as the warn and crit fields so graphing tools draw
+// the alert lines the check alerts on.
+func (t Thresholds) FormatPerfData(label string, value float64, min, max string) string {
+	return FormatPerfData(label, value, formatThreshold(t.Warning), formatThreshold(t.Critical), min, max)
+}
+
+// Metric returns the metric with its Warning and Critical fields set
+// from the thresholds, so a check reports the thresholds it compared
+// the value against rather than passing them separately.
+func (t Thresholds) Metric(metric PerfData) PerfData {
+	metric.Warning = formatThreshold(t.Warning)
+	metric.Critical = formatThreshold(t.Critical)
+
+	return metric
+}
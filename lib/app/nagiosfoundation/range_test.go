@@ -0,0 +1,223 @@
+package nagiosfoundation
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	type testItem struct {
+		threshold string
+		expected  Range
+	}
+
+	testList := []testItem{
+		{"10", Range{Start: 0, End: 10}},
+		{"10:", Range{Start: 10, End: math.Inf(1)}},
+		{"~:10", Range{Start: math.Inf(-1), End: 10}},
+		{"10:20", Range{Start: 10, End: 20}},
+		{"@10:20", Range{Start: 10, End: 20, Inside: true}},
+		{"-5:2.5", Range{Start: -5, End: 2.5}},
+	}
+
+	for _, i := range testList {
+		r, err := ParseRange(i.threshold)
+		if err != nil {
+			t.Errorf("ParseRange(%q) returned an error: %s", i.threshold, err)
+		} else if r != i.expected {
+			t.Errorf("ParseRange(%q) Expected: %+v, Actual: %+v", i.threshold, i.expected, r)
+		}
+
+		if r.String() != i.threshold {
+			t.Errorf("Range.String() Expected: %s, Actual: %s", i.threshold, r.String())
+		}
+	}
+
+	for _, threshold := range []string{"", "@", "ten", "x:10", "10:x", "20:10"} {
+		if _, err := ParseRange(threshold); err == nil {
+			t.Errorf("ParseRange(%q) should have returned an error", threshold)
+		}
+	}
+}
+
+func TestParseRelativeRange(t *testing.T) {
+	type testItem struct {
+		threshold string
+		total     float64
+		expected  Range
+	}
+
+	testList := []testItem{
+		{"80%", 500, Range{Start: 0, End: 400}},
+		{"80", 500, Range{Start: 0, End: 80}},
+		{"10%:", 200, Range{Start: 20, End: math.Inf(1)}},
+		{"@10%:50%", 1000, Range{Start: 100, End: 500, Inside: true}},
+		{"100:50%", 1000, Range{Start: 100, End: 500}},
+		{"~:0.5%", 1000, Range{Start: math.Inf(-1), End: 5}},
+		{"2G", 1 << 40, Range{Start: 0, End: 2 << 30}},
+		{"2gb", 1 << 40, Range{Start: 0, End: 2 << 30}},
+		{"512MiB:90%", 1000 << 20, Range{Start: 512 << 20, End: 900 << 20}},
+		{"1.5K", 1 << 20, Range{Start: 0, End: 1536}},
+		{"4096B", 1 << 20, Range{Start: 0, End: 4096}},
+	}
+
+	for _, i := range testList {
+		r, err := ParseRelativeRange(i.threshold)
+		if err != nil {
+			t.Errorf("ParseRelativeRange(%q) returned an error: %s", i.threshold, err)
+		} else if resolved := r.Resolve(i.total); resolved != i.expected {
+			t.Errorf("ParseRelativeRange(%q) Resolve(%g) Expected: %+v, Actual: %+v", i.threshold, i.total, i.expected, resolved)
+		}
+	}
+
+	errorList := map[string]string{
+		"120%":    "end is not a percentage from 0% to 100%",
+		"-5%:10%": "start is not a percentage from 0% to 100%",
+		"80%%":    "end is not a number",
+		"50%:10%": "start is greater than end",
+		"2X":      "end is not a number, a percentage such as 90% or a size such as 2G",
+		"lots":    "end is not a number, a percentage such as 90% or a size such as 2G",
+		"2G:1G":   "start is greater than end",
+	}
+
+	for threshold, expected := range errorList {
+		if _, err := ParseRelativeRange(threshold); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("ParseRelativeRange(%q) Expected Error: %q, Actual Error: %v", threshold, expected, err)
+		}
+	}
+
+	if _, err := ParseRange("80%"); err == nil {
+		t.Error("ParseRange() should not accept a percentage")
+	}
+
+	thresholds, err := ParseRelativeThresholds("50%", "900")
+	if err != nil {
+		t.Fatalf("ParseRelativeThresholds() returned an error: %s", err)
+	}
+
+	resolved := thresholds.Resolve(1000)
+	if state, _ := resolved.Status(600); state != StateWarning {
+		t.Errorf("Resolved thresholds Expected State: %s, Actual State: %s", StateWarning, state)
+	}
+
+	if perf := resolved.Metric(PerfData{Label: "used", Value: 600}).Warning; perf != "500" {
+		t.Errorf("Resolved thresholds Expected Warning: 500, Actual Warning: %s", perf)
+	}
+
+	if resolved := (RelativeThresholds{}).Resolve(1000); resolved.Warning != nil || resolved.Critical != nil {
+		t.Error("Resolving no thresholds should not set any")
+	}
+
+	percentages := thresholds.Percentages(1200)
+	if perf := percentages.Metric(PerfData{Label: "used_pct", Value: 50}); perf.Warning != "50" || perf.Critical != "75" {
+		t.Errorf("Percentages of thresholds Expected Warning: 50, Critical: 75, Actual Warning: %s, Critical: %s", perf.Warning, perf.Critical)
+	}
+
+	if _, err := ParseRange("2G"); err == nil {
+		t.Error("ParseRange() should not accept a size")
+	}
+}
+
+func TestRangeCheck(t *testing.T) {
+	type testItem struct {
+		threshold string
+		value     float64
+		alert     bool
+	}
+
+	testList := []testItem{
+		{"10", -1, true},
+		{"10", 0, false},
+		{"10", 10, false},
+		{"10", 11, true},
+		{"10:", 9, true},
+		{"10:", 10, false},
+		{"10:", 1e9, false},
+		{"~:10", -1e9, false},
+		{"~:10", 11, true},
+		{"10:20", 9, true},
+		{"10:20", 15, false},
+		{"10:20", 21, true},
+		{"@10:20", 9, false},
+		{"@10:20", 10, true},
+		{"@10:20", 20, true},
+		{"@10:20", 21, false},
+	}
+
+	for _, i := range testList {
+		r, _ := ParseRange(i.threshold)
+
+		if alert := r.Check(i.value); alert != i.alert {
+			t.Errorf("Range %s Check(%g) Expected: %t, Actual: %t", i.threshold, i.value, i.alert, alert)
+		}
+	}
+}
+
+func TestRangeExpected(t *testing.T) {
+	type testItem struct {
+		threshold string
+		expected  string
+	}
+
+	testList := []testItem{
+		{"10", "0-10"},
+		{"0", "0"},
+		{"10:", "at least 10"},
+		{"~:10", "at most 10"},
+		{"-5:5", "-5-5"},
+		{"10:20", "10-20"},
+		{"~:", "any value"},
+		{"@10:20", "outside 10-20"},
+		{"@10", "outside 0-10"},
+		{"@5:5", "other than 5"},
+		{"@10:", "below 10"},
+		{"@~:10", "above 10"},
+		{"@~:", "no value"},
+	}
+
+	for _, i := range testList {
+		r, err := ParseRange(i.threshold)
+		if err != nil {
+			t.Fatalf("ParseRange(%q) returned an error: %s", i.threshold, err)
+		}
+
+		if expected := r.Expected(); expected != i.expected {
+			t.Errorf("Range %s Expected() Expected: %s, Actual: %s", i.threshold, i.expected, expected)
+		}
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	thresholds, err := ParseThresholds(" 0:10 ", "@20:30")
+	if err != nil {
+		t.Fatalf("ParseThresholds() returned an error on valid thresholds: %s", err)
+	}
+
+	type testItem struct {
+		value         float64
+		expectedState State
+	}
+
+	for _, i := range []testItem{{5, StateOK}, {15, StateWarning}, {25, StateCritical}} {
+		if state, _ := thresholds.Status(i.value); state != i.expectedState {
+			t.Errorf("Thresholds Status(%g) Expected: %s, Actual: %s", i.value, i.expectedState, state)
+		}
+	}
+
+	if metric := thresholds.FormatPerfData("procs", 3, "0", ""); metric != "procs=3;10;@20:30;0" {
+		t.Errorf("Thresholds FormatPerfData() should render the parsed thresholds: %s", metric)
+	}
+
+	if metric := thresholds.Metric(PerfData{Label: "procs", Value: 3, Warning: "1", Critical: "2"}); metric.String() != "procs=3;10;@20:30" {
+		t.Errorf("Thresholds Metric() should replace the thresholds of the metric: %s", metric)
+	}
+
+	if metric := (Thresholds{}).FormatPerfData("procs", 3, "0", ""); metric != "procs=3;;;0" {
+		t.Errorf("Empty thresholds should leave the perfdata thresholds empty: %s", metric)
+	}
+
+	if _, err := ParseThresholds("5", "ten"); err == nil || !strings.Contains(err.Error(), `"ten"`) {
+		t.Errorf("ParseThresholds() should return an error naming the invalid threshold: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package nagiosfoundation
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// The modes of a check of a host resolving to several addresses,
+// either one address being reachable or every address.
+const (
+	addressesAny = "any"
+	addressesAll = "all"
+)
+
+// checkIPVersion returns an error unless the IP version is 4, 6 or 0
+// for either.
+func checkIPVersion(ipVersion int) error {
+	if ipVersion != 0 && ipVersion != 4 && ipVersion != 6 {
+		return fmt.Errorf("Invalid IP version (%d). The IP version must be 4, 6 or 0 for either.", ipVersion)
+	}
+
+	return nil
+}
+
+// parseAddressesMode returns the mode of a check of the addresses of
+// a host, "any" or "all", defaulting to "any".
+func parseAddressesMode(mode string) (string, error) {
+	switch strings.ToLower(mode) {
+	case "", addressesAny:
+		return addressesAny, nil
+	case addressesAll:
+		return addressesAll, nil
+	}
+
+	return "", fmt.Errorf("Invalid addresses (%s). Only \"any\" and \"all\" are supported.", mode)
+}
+
+// isIPVersion reports whether the address is of the IP version, 4 or
+// 6, or of either for 0.
+func isIPVersion(ip net.IP, ipVersion int) bool {
+	switch ipVersion {
+	case 4:
+		return ip.To4() != nil
+	case 6:
+		return ip.To4() == nil
+	}
+
+	return true
+}
+
+// resolveAddresses returns the addresses of the host of the IP
+// version, 4 or 6, or of either for 0, in the order lookup returns
+// them, along with the time the lookup took. A host that is an IPv4
+// or IPv6 address, with or without the brackets of a URL such as
+// "[2001:db8::1]", is returned without a lookup. A host without an
+// address of the IP version fails as a *net.DNSError, as does a host
+// that cannot be resolved, so that both are told apart from a host
+// that cannot be reached.
+func resolveAddresses(host string, ipVersion int, lookup func(string) ([]net.IP, error)) ([]net.IP, time.Duration, error) {
+	var ips []net.IP
+	var elapsed time.Duration
+
+	if ip := net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		start := time.Now()
+
+		var err error
+		ips, err = lookup(host)
+		elapsed = time.Since(start)
+
+		if err != nil {
+			return nil, elapsed, err
+		}
+	}
+
+	var selected []net.IP
+	for _, ip := range ips {
+		if isIPVersion(ip, ipVersion) {
+			selected = append(selected, ip)
+		}
+	}
+
+	if len(selected) == 0 && ipVersion == 0 {
+		return nil, elapsed, &net.DNSError{Err: "no address", Name: host}
+	} else if len(selected) == 0 {
+		return nil, elapsed, &net.DNSError{Err: fmt.Sprintf("no IPv%d address", ipVersion), Name: host}
+	}
+
+	return selected, elapsed, nil
+}
+
+// dnsTimeMetric returns the perfdata of the time taken resolving a
+// host, in seconds.
+func dnsTimeMetric(elapsed time.Duration) PerfData {
+	return PerfData{
+		Label: "dns_time",
+		Value: elapsed.Round(time.Millisecond).Seconds(),
+		UOM:   "s",
+		Min:   "0",
+	}
+}
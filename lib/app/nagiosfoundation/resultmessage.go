@@ -3,6 +3,7 @@ package nagiosfoundation
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 const (
@@ -22,6 +23,45 @@ const (
 var errResultMsgNotEnoughArgs = errors.New("Not enough arguments")
 var errResultMsgTooManyArgs = errors.New("Too many arguments")
 var errResultMsgInvalidStatus = errors.New("Invalid status text")
+var errInvalidTimeoutExit = errors.New("Invalid timeout exit. Valid values are \"unknown\", \"critical\" and \"warning\"")
+
+// TimeoutStatus returns the status code and status text a check
+// reports when it times out. The timeoutExit parameter is the value
+// of the --timeout_exit flag and may be "unknown" (the default when
+// empty), "critical" or "warning".
+func TimeoutStatus(timeoutExit string) (int, string, error) {
+	switch strings.ToLower(timeoutExit) {
+	case "", "unknown":
+		return statusCodeUnknown, statusTextUnknown, nil
+	case "critical":
+		return statusCodeCritical, statusTextCritical, nil
+	case "warning":
+		return statusCodeWarning, statusTextWarning, nil
+	}
+
+	return statusCodeUnknown, statusTextUnknown, errInvalidTimeoutExit
+}
+
+// checkTimeoutExit returns an error describing a timeoutExit that is
+// not valid for TimeoutStatus(), or nil.
+func checkTimeoutExit(timeoutExit string) error {
+	if _, _, err := TimeoutStatus(timeoutExit); err != nil {
+		return fmt.Errorf("The timeout exit (--timeout_exit) \"%s\" is not valid. %s.", timeoutExit, err)
+	}
+
+	return nil
+}
+
+// timeoutResult returns the result of the named check timing out, in
+// the state selected by timeoutExit as with TimeoutStatus(), with the
+// message describing what did not complete in time. A timeoutExit
+// that is not valid is UNKNOWN, as the checks reject it before they
+// start.
+func timeoutResult(name, timeoutExit, message string) CheckResult {
+	code, _, _ := TimeoutStatus(timeoutExit)
+
+	return NewCheckResult(name, State(code), message)
+}
 
 func resultMessage(s ...string) (string, error) {
 	// s[0] - check name
@@ -73,3 +73,29 @@ func TestResultMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestTimeoutStatus(t *testing.T) {
+	type testItem struct {
+		timeoutExit  string
+		expectedCode int
+		expectedText string
+		expectedErr  error
+	}
+
+	testList := []testItem{
+		{"", statusCodeUnknown, statusTextUnknown, nil},
+		{"unknown", statusCodeUnknown, statusTextUnknown, nil},
+		{"Critical", statusCodeCritical, statusTextCritical, nil},
+		{"warning", statusCodeWarning, statusTextWarning, nil},
+		{"ok", statusCodeUnknown, statusTextUnknown, errInvalidTimeoutExit},
+	}
+
+	for _, i := range testList {
+		code, text, err := TimeoutStatus(i.timeoutExit)
+
+		if code != i.expectedCode || text != i.expectedText || err != i.expectedErr {
+			t.Errorf("TimeoutStatus(%s) returned %d, %s, %v. Expected %d, %s, %v",
+				i.timeoutExit, code, text, err, i.expectedCode, i.expectedText, i.expectedErr)
+		}
+	}
+}
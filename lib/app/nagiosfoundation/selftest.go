@@ -0,0 +1,81 @@
+package nagiosfoundation
+
+import (
+	"os/user"
+	"runtime"
+)
+
+// Capability is a capability of the host the checks rely on, such as
+// reading /proc, and the error testing it, nil when it is available.
+type Capability struct {
+	Name string
+	Err  error
+}
+
+// selfTestCheck is the test of a capability, returning an error when
+// the capability is not available.
+type selfTestCheck struct {
+	name string
+	test func() error
+}
+
+// selfTestServiceName is the service looked up to test the service
+// manager. It is not expected to be installed, only for the service
+// manager to answer.
+const selfTestServiceName = "nagiosfoundation-selftest"
+
+func selfTestProcessList() error {
+	_, err := newProcessHandler(ProcessCheckOptions{}).inspector.List(selfTestServiceName, 1)
+
+	return err
+}
+
+func selfTestCurrentUser() error {
+	_, err := user.Current()
+
+	return err
+}
+
+func selfTestServiceManager() error {
+	manager, err := newServiceManagerOsConstrained("")
+	if err != nil {
+		return err
+	}
+
+	_, err = manager.Status(selfTestServiceName)
+
+	return err
+}
+
+// selfTestChecks are the capabilities tested by SelfTest().
+func selfTestChecks() []selfTestCheck {
+	processList := "read /proc"
+	if runtime.GOOS == "windows" {
+		processList = "list the processes"
+	}
+
+	return []selfTestCheck{
+		{processList, selfTestProcessList},
+		{"resolve the current user", selfTestCurrentUser},
+		{"open the service manager", selfTestServiceManager},
+	}
+}
+
+func selfTestWithChecks(checks []selfTestCheck) []Capability {
+	capabilities := make([]Capability, len(checks))
+
+	for i, check := range checks {
+		capabilities[i] = Capability{Name: check.name, Err: check.test()}
+	}
+
+	return capabilities
+}
+
+// SelfTest tests the capabilities of the host the checks rely on,
+// listing the processes, resolving the user the checks run as and
+// querying the service manager, without a target to check, so that
+// a deployment lacking a permission or a part of the OS is found
+// before its checks return confusing UNKNOWN results.
+func SelfTest() []Capability {
+	return selfTestWithChecks(selfTestChecks())
+}
@@ -0,0 +1,25 @@
+package nagiosfoundation
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	capabilities := selfTestWithChecks([]selfTestCheck{
+		{"read /proc", func() error { return nil }},
+		{"open the service manager", func() error { return errors.New("permission denied") }},
+	})
+
+	if len(capabilities) != 2 || capabilities[0].Name != "read /proc" || capabilities[0].Err != nil {
+		t.Errorf("selfTestWithChecks() should report an available capability without an error: %+v", capabilities)
+	}
+
+	if len(capabilities) == 2 && (capabilities[1].Err == nil || capabilities[1].Err.Error() != "permission denied") {
+		t.Errorf("selfTestWithChecks() should report the error of an unavailable capability: %+v", capabilities)
+	}
+
+	if capabilities := SelfTest(); len(capabilities) != len(selfTestChecks()) {
+		t.Errorf("SelfTest() should test every capability: %+v", capabilities)
+	}
+}
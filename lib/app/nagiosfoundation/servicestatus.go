@@ -3,12 +3,143 @@ package nagiosfoundation
 import (
 	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 const serviceCheckName = "CheckService"
 
-type getServiceInfoFunc func(string) (string, string, string, int, error)
+// ServiceStatus is the status of a service reported by a
+// ServiceManager.
+type ServiceStatus struct {
+	// The name of the service, empty when the service is not
+	// installed.
+	Name string
+
+	// The state of the service, such as "Running" on Windows or
+	// "active" for systemd, and the number output as the
+	// service_state perfdata.
+	State    string
+	StateNbr int
+
+	// Whether the service is running.
+	Running bool
+
+	// The user the service runs as and its start type, such as
+	// "auto". Empty when the service manager does not report them.
+	User      string
+	StartType string
+}
+
+// Installed reports whether the service manager found the service.
+func (s ServiceStatus) Installed() bool {
+	return s.Name != ""
+}
+
+// ServiceManager reports the status of the services of a service
+// manager, such as systemd or the Windows Service Control Manager. A
+// service that is not installed is not an error but a status without
+// a name.
+type ServiceManager interface {
+	Status(name string) (ServiceStatus, error)
+}
+
+// systemdManager is the ServiceManager of systemd, reading the state
+// of a unit with systemctl show.
+type systemdManager struct {
+	systemctl func(...string) ([]byte, error)
+}
+
+func (m systemdManager) Status(name string) (ServiceStatus, error) {
+	out, err := m.systemctl("show", name, "--property=LoadState,ActiveState,SubState", "--no-pager")
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	unit := parseSystemdShow(string(out))
+
+	switch {
+	case unit.activeState == "":
+		return ServiceStatus{}, fmt.Errorf("Could not read the state of unit %s", name)
+	case unit.loadState == "not-found":
+		return ServiceStatus{State: unit.activeState}, nil
+	}
+
+	status := ServiceStatus{Name: name, State: unit.activeState, Running: unit.activeState == "active"}
+	if status.Running {
+		status.StateNbr = 1
+	}
+
+	return status, nil
+}
+
+// defaultInitDir is the directory of the SysV init scripts.
+const defaultInitDir = "/etc/init.d"
+
+// sysvinitManager is the ServiceManager of SysV init, where a service
+// is installed when it has a script in the init directory and its
+// state is the exit code of the status action of the script.
+type sysvinitManager struct {
+	initDir string
+	stat    func(string) (os.FileInfo, error)
+
+	// Runs the script with the arguments and returns its exit code.
+	run func(string, ...string) (int, error)
+}
+
+// runInitScript runs the init script with the arguments and returns
+// its exit code.
+func runInitScript(path string, args ...string) (int, error) {
+	err := exec.Command(path, args...).Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+
+	return 0, err
+}
+
+func newSysvinitManager() sysvinitManager {
+	return sysvinitManager{initDir: defaultInitDir, stat: os.Stat, run: runInitScript}
+}
+
+// lsbStatusStates are the states of the exit codes of the status
+// action of an LSB init script. Other codes are "unknown".
+var lsbStatusStates = map[int]string{
+	0: "running",
+	1: "dead",
+	2: "dead",
+	3: "stopped",
+}
+
+func (m sysvinitManager) Status(name string) (ServiceStatus, error) {
+	script := filepath.Join(m.initDir, name)
+
+	if _, err := m.stat(script); os.IsNotExist(err) {
+		return ServiceStatus{State: "not-found"}, nil
+	} else if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	code, err := m.run(script, "status")
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	state, ok := lsbStatusStates[code]
+	if !ok {
+		state = "unknown"
+	}
+
+	status := ServiceStatus{Name: name, State: state, Running: code == 0}
+	if status.Running {
+		status.StateNbr = 1
+	}
+
+	return status, nil
+}
 
 type serviceInfo struct {
 	// The name of the service to process.
@@ -20,15 +151,31 @@ type serviceInfo struct {
 	// The user of the service to match.
 	desiredUser string
 
+	// The start type of the service to match, such as "auto".
+	desiredStartType string
+
+	// Whether the service must be restarted on its first and second
+	// failure.
+	desiredRecovery bool
+
 	// User only wants current state
 	currentStateWanted bool
 
+	// The state reported when the service is not installed. Empty
+	// reports UNKNOWN.
+	missingState string
+
 	actualName      string
 	actualStateText string
 	actualStateNbr  int
 	actualUser      string
+	actualStartType string
+
+	// The actions taken on the first, second and later failures of
+	// the service, read when desiredRecovery is set.
+	actualRecoveryActions []string
 
-	getServiceInfo getServiceInfoFunc
+	manager ServiceManager
 }
 
 // Returns the actual name of the service resulting from the service query.
@@ -51,6 +198,44 @@ func (i *serviceInfo) ActualUser() string {
 	return i.actualUser
 }
 
+// Returns the actual start type of the service resulting from the service query.
+func (i *serviceInfo) ActualStartType() string {
+	return i.actualStartType
+}
+
+// FailureAction returns the action taken on the nth failure of the
+// service, counted from 1, such as "restart". A failure past the
+// actions listed takes the last action, and a service without actions
+// takes "none".
+func (i *serviceInfo) FailureAction(n int) string {
+	actions := i.actualRecoveryActions
+
+	switch {
+	case len(actions) == 0:
+		return serviceRecoveryNone
+	case n > len(actions):
+		return actions[len(actions)-1]
+	}
+
+	return actions[n-1]
+}
+
+// Checks the service is restarted on its first and second failure.
+func (i *serviceInfo) RestartsOnFailure() bool {
+	return i.FailureAction(1) == serviceRecoveryRestart && i.FailureAction(2) == serviceRecoveryRestart
+}
+
+// normalizeStartType returns the start type in the form used by WMI
+// in lower case, such as "auto", accepting "automatic" for "auto".
+func normalizeStartType(startType string) string {
+	startType = strings.ToLower(startType)
+	if startType == "automatic" {
+		startType = "auto"
+	}
+
+	return startType
+}
+
 // Checks for a match against the actual name of the service. The comparison
 // is case insensitive.
 func (i *serviceInfo) IsName(name string) bool {
@@ -69,41 +254,67 @@ func (i *serviceInfo) IsUser(user string) bool {
 	return strings.EqualFold(i.ActualUser(), user)
 }
 
-// Executes the OS constrained function to retrieve information about a service.
-// This information is derived differently in Windows and Linux and must execute
-// an OS constrained method named getInfoOsConstrained().
+// Checks for a match against the actual start type of the service. The
+// comparison is case insensitive and "automatic" matches "auto".
+func (i *serviceInfo) IsStartType(startType string) bool {
+	return normalizeStartType(i.ActualStartType()) == normalizeStartType(startType)
+}
+
+// Retrieves the status of the service from the service manager.
 func (i *serviceInfo) GetInfo() error {
-	var err error
+	if i.manager == nil {
+		return errors.New("No service manager declared")
+	}
 
-	if i.getServiceInfo == nil {
-		return errors.New("No get service info handler declared")
+	status, err := i.manager.Status(i.desiredName)
+	if err != nil {
+		return err
 	}
 
-	i.actualName, i.actualUser, i.actualStateText, i.actualStateNbr, err = i.getServiceInfo(i.desiredName)
+	i.actualName = status.Name
+	i.actualUser = status.User
+	i.actualStateText = status.State
+	i.actualStateNbr = status.StateNbr
+	i.actualStartType = status.StartType
+	i.actualRecoveryActions = nil
+
+	if i.desiredRecovery && status.Installed() && !i.currentStateWanted {
+		reader, ok := i.manager.(serviceRecoveryReader)
+		if !ok {
+			return errors.New("Reading the recovery actions is not supported by the service manager")
+		}
+
+		actions, err := reader.RecoveryActions(status.Name)
+		if err != nil {
+			return fmt.Errorf("Reading the recovery actions of %s failed: %s", status.Name, err)
+		}
+
+		i.actualRecoveryActions = actions
+	}
 
-	return err
+	return nil
 }
 
 // Process the desired service info against the actual service info and return
-// check text and a return code.
-func (i *serviceInfo) ProcessInfo() (string, int) {
+// the check result.
+func (i *serviceInfo) ProcessInfo() CheckResult {
 	var (
-		checkInfo, nagiosInfo string
-		retcode               int
+		checkInfo string
+		perfData  []PerfData
+		retcode   int
 	)
 
 	if !i.IsName(i.desiredName) {
 		if i.currentStateWanted == true {
 			checkInfo = fmt.Sprintf("%s does not exist", i.desiredName)
-			nagiosInfo = fmt.Sprintf("service_state=255 service_name=%s", i.desiredName)
+			perfData = []PerfData{{Label: "service_state", Value: 255}}
 			retcode = 0
 		} else {
-			checkInfo = fmt.Sprintf("%s does not exist", i.desiredName)
-			retcode = 2
+			return notInstalledResult(i.desiredName, i.missingState)
 		}
 	} else if i.currentStateWanted == true {
 		checkInfo = fmt.Sprintf("%s is in a %s state", i.desiredName, i.ActualStateText())
-		nagiosInfo = fmt.Sprintf("service_state=%d service_name=%s", i.ActualStateNbr(), i.desiredName)
+		perfData = []PerfData{{Label: "service_state", Value: float64(i.ActualStateNbr())}}
 		retcode = 0
 	} else if i.desiredState != "" && i.desiredUser != "" {
 		if i.IsState(i.desiredState) && i.IsUser(i.desiredUser) {
@@ -141,24 +352,297 @@ func (i *serviceInfo) ProcessInfo() (string, int) {
 		retcode = 0
 	}
 
-	var responseStateText, actualInfo string
+	// A service in the desired state but with the wrong start type
+	// is a warning, such as a service started by hand that will not
+	// start again after a reboot.
+	if retcode == 0 && !i.currentStateWanted && i.desiredStartType != "" &&
+		i.IsName(i.desiredName) && !i.IsStartType(i.desiredStartType) {
+		checkInfo = fmt.Sprintf("%s but start type is %s rather than %s",
+			checkInfo, i.ActualStartType(), i.desiredStartType)
+		retcode = 1
+	}
 
-	if retcode == 0 {
-		responseStateText = statusTextOK
-		actualInfo = ""
-	} else {
-		responseStateText = statusTextCritical
-		actualInfo = fmt.Sprintf(" (Name: %s, State: %s, User: %s)",
+	// A service in the desired state that is not restarted when it
+	// fails is a warning too, as a crash leaves it stopped.
+	if (retcode == 0 || retcode == 1) && !i.currentStateWanted && i.desiredRecovery &&
+		i.IsName(i.desiredName) && !i.RestartsOnFailure() {
+		conjunction := "but"
+		if retcode == 1 {
+			conjunction = "and"
+		}
+
+		checkInfo = fmt.Sprintf("%s %s recovery does not restart it (first failure: %s, second failure: %s)",
+			checkInfo, conjunction, i.FailureAction(1), i.FailureAction(2))
+		retcode = 1
+	}
+
+	if retcode == statusCodeCritical {
+		checkInfo += fmt.Sprintf(" (Name: %s, State: %s, User: %s)",
 			i.ActualName(), i.ActualStateText(), i.ActualUser())
 	}
 
-	msg, _ := resultMessage(serviceCheckName, responseStateText, checkInfo+actualInfo, nagiosInfo)
+	return NewCheckResult(serviceCheckName, State(retcode), checkInfo, perfData...)
+}
+
+// notInstalledResult returns the result of a check finding the service
+// is not installed. Not installed is told apart from stopped, which is
+// critical, as the check cannot say the service failed, so the result
+// is UNKNOWN unless another state was selected with missingState.
+func notInstalledResult(name, missingState string) CheckResult {
+	state := StateUnknown
+	if missing, err := ParseState(missingState); err == nil {
+		state = missing
+	}
+
+	return NewCheckResult(serviceCheckName, state, fmt.Sprintf("service %s is not installed", name))
+}
+
+// checkServiceWithManager checks the service described by options
+// against the status reported by the manager, the check of Windows,
+// where the state, user and start type of the service may be checked.
+func checkServiceWithManager(options ServiceCheckOptions, manager ServiceManager) CheckResult {
+	i := serviceInfo{
+		desiredName:        options.Name,
+		desiredState:       options.State,
+		desiredUser:        options.User,
+		desiredStartType:   options.StartType,
+		desiredRecovery:    options.Recovery,
+		currentStateWanted: options.CurrentStateWanted,
+		missingState:       options.MissingState,
+		manager:            manager,
+	}
+
+	if err := i.GetInfo(); err != nil {
+		return CriticalResult(serviceCheckName, err.Error())
+	}
+
+	return i.ProcessInfo()
+}
+
+// checkServiceRunningWithManager checks the service described by
+// options is running, as reported by the manager, the check of Linux.
+// A service that is not running is critical. With
+// options.CurrentStateWanted the check always returns OK with the
+// state of the service, 1 when running and 0 otherwise, as perfdata.
+func checkServiceRunningWithManager(options ServiceCheckOptions, manager ServiceManager) CheckResult {
+	status, err := manager.Status(options.Name)
+	if err != nil {
+		return CriticalResult(serviceCheckName, fmt.Sprintf("Failed to read the status of %s: %s", options.Name, err))
+	}
+
+	if !status.Installed() && !options.CurrentStateWanted {
+		return notInstalledResult(options.Name, options.MissingState)
+	}
+
+	state := StateOK
+	info := fmt.Sprintf("%s in a running state", options.Name)
+
+	if !status.Running {
+		state = StateCritical
+		info = fmt.Sprintf("%s not in a running state (State: %s)", options.Name, status.State)
+	}
+
+	if !options.CurrentStateWanted {
+		return NewCheckResult(serviceCheckName, state, info)
+	}
+
+	// The status text still tells whether the service is running.
+	result := NewCheckResult(serviceCheckName, state, info, PerfData{Label: "service_state", Value: float64(status.StateNbr)})
+	result.Code = statusCodeOK
+
+	return result
+}
+
+// The ways a service may be matched, set with ServiceCheckOptions.MatchBy.
+const (
+	serviceMatchByName    = "name"
+	serviceMatchByDisplay = "display"
+)
+
+// serviceDisplayNameFinder is implemented by a ServiceManager whose
+// services have a display name besides their name, such as those of
+// the Windows Service Control Manager. It returns the names of the
+// services with the display name, matched without regard to case.
+type serviceDisplayNameFinder interface {
+	FindByDisplayName(string) ([]string, error)
+}
+
+// The failure actions of a service reported by a serviceRecoveryReader.
+const (
+	serviceRecoveryNone    = "none"
+	serviceRecoveryRestart = "restart"
+	serviceRecoveryReboot  = "reboot"
+	serviceRecoveryCommand = "run command"
+)
+
+// serviceRecoveryReader is implemented by a ServiceManager that can
+// read the actions taken when a service fails, such as the Windows
+// Service Control Manager. It returns the actions taken on the first,
+// second and later failures, such as "restart" and "none".
+type serviceRecoveryReader interface {
+	RecoveryActions(string) ([]string, error)
+}
+
+// checkServiceMatching runs check on the service described by
+// options. With options.MatchBy "display", options.Name is the display
+// name of the service and is replaced by the name of the service with
+// that display name before running check. A display name of no
+// service is not installed, while a display name of several services
+// is UNKNOWN listing them rather than checking one of them.
+func checkServiceMatching(options ServiceCheckOptions, manager ServiceManager,
+	check func(ServiceCheckOptions, ServiceManager) CheckResult) CheckResult {
+	if strings.ToLower(options.MatchBy) != serviceMatchByDisplay {
+		return check(options, manager)
+	}
+
+	finder, ok := manager.(serviceDisplayNameFinder)
+	if !ok {
+		return UnknownResult(serviceCheckName, "Matching services by display name is not supported by the service manager")
+	}
+
+	names, err := finder.FindByDisplayName(options.Name)
+	if err != nil {
+		return CriticalResult(serviceCheckName, fmt.Sprintf("Failed to find the service with display name %s: %s", options.Name, err))
+	}
+
+	switch len(names) {
+	case 0:
+		return notInstalledResult(options.Name, options.MissingState)
+	case 1:
+		debugLog.Printf("Checking service %s with display name %s", names[0], options.Name)
+		options.Name = names[0]
 
-	return msg, retcode
+		return check(options, manager)
+	}
+
+	return UnknownResult(serviceCheckName, fmt.Sprintf("The display name %s matches %d services: %s",
+		options.Name, len(names), strings.Join(names, ", ")))
+}
+
+// ServiceCheckOptions contains the options for a service check.
+type ServiceCheckOptions struct {
+	// The name of the service to check, or with MatchBy "display" its
+	// display name.
+	Name string
+
+	// Whether Name is matched against the name of the service, "name"
+	// or empty, or its display name, "display", as shown by
+	// services.msc on Windows.
+	MatchBy string
+
+	// The state and user the service must have. Windows only.
+	State string
+	User  string
+
+	// The start type the service must have, such as "auto". A service
+	// with another start type is a warning. Windows only.
+	StartType string
+
+	// Checks the service is restarted by its recovery actions on its
+	// first and second failure. A service taking no action or another
+	// action, such as rebooting, is a warning. Windows only.
+	Recovery bool
+
+	// Reports the state of the service as perfdata rather than
+	// checking it.
+	CurrentStateWanted bool
+
+	// The state reported when the service is not installed, "ok",
+	// "warning", "critical" or "unknown", such as ok for an optional
+	// service. Empty reports UNKNOWN.
+	MissingState string
+
+	// The service manager, "systemd" or "sysvinit" on Linux and
+	// "wmi" or "svcmgr" on Windows. Defaults to the service manager
+	// the host was booted with on Linux and to "wmi" on Windows.
+	Manager string
+
+	// The time to wait before checking a stopped service once more,
+	// so a service restarting during a deployment is not reported as
+	// CRITICAL. Zero checks the service once.
+	Grace time.Duration
+
+	// The time the check must complete by, such as the end of the
+	// global --timeout, shortening the grace period to end in time.
+	// The zero time has no deadline.
+	Deadline time.Time
+}
+
+// checkServiceWithGrace runs the check and, when it is CRITICAL such
+// as for a stopped service, waits for the grace period and runs it
+// once more, returning the second result. The grace period is
+// shortened so the second check, taking as long as the first, ends
+// by the deadline, and when no time is left the first result is
+// returned.
+func checkServiceWithGrace(check func() CheckResult, grace time.Duration, deadline time.Time,
+	sleep func(time.Duration), now func() time.Time) CheckResult {
+	started := now()
+	result := check()
+
+	if grace <= 0 || result.Code != statusCodeCritical {
+		return result
+	}
+
+	wait := grace
+	if !deadline.IsZero() {
+		finished := now()
+		if remaining := deadline.Sub(finished) - finished.Sub(started); remaining < wait {
+			wait = remaining
+		}
+	}
+
+	if wait <= 0 {
+		debugLog.Printf("Not checking the service again, the timeout leaves no time for the grace period")
+		return result
+	}
+
+	sleep(wait)
+	result = check()
+	result.Message += fmt.Sprintf(" after a grace period of %s", wait)
+
+	return result
+}
+
+// CheckServiceWithOptions performs the service check described by
+// options.
+func CheckServiceWithOptions(options ServiceCheckOptions) (string, int) {
+	return RunServiceCheck(options).Output()
+}
+
+// RunServiceCheck performs the service check described by options
+// and returns the result, for programs embedding the check or
+// running several checks in one process. Nothing is output and the
+// program does not exit.
+func RunServiceCheck(options ServiceCheckOptions) CheckResult {
+	if options.MissingState != "" {
+		if _, err := ParseState(options.MissingState); err != nil {
+			return UnknownResult(serviceCheckName, fmt.Sprintf("Invalid missing state: %s", err))
+		}
+	}
+
+	switch strings.ToLower(options.MatchBy) {
+	case "", serviceMatchByName, serviceMatchByDisplay:
+	default:
+		return UnknownResult(serviceCheckName, fmt.Sprintf("Invalid match by %q. Valid values are \"name\" and \"display\"", options.MatchBy))
+	}
+
+	return checkServiceWithGrace(func() CheckResult {
+		return checkServiceOsConstrained(options)
+	}, options.Grace, options.Deadline, time.Sleep, time.Now)
 }
 
 // CheckService checks a service based on name, state,
 // user, and manager
 func CheckService(name, state, user string, currentStateWanted bool, manager string) (string, int) {
-	return checkServiceOsConstrained(name, state, user, currentStateWanted, manager)
+	return checkServiceOsConstrained(ServiceCheckOptions{Name: name, State: state, User: user,
+		CurrentStateWanted: currentStateWanted, Manager: manager}).Output()
+}
+
+// CheckServiceWithStartType checks a service as CheckService does and
+// when startType is given, issues a warning if the service's start
+// type, such as "auto", "manual" or "disabled", does not match. The
+// start type is only checked on Windows.
+func CheckServiceWithStartType(name, state, user, startType string, currentStateWanted bool, manager string) (string, int) {
+	return checkServiceOsConstrained(ServiceCheckOptions{Name: name, State: state, User: user, StartType: startType,
+		CurrentStateWanted: currentStateWanted, Manager: manager}).Output()
 }
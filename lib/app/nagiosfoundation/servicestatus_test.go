@@ -1,6 +1,58 @@
 package nagiosfoundation
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testServiceManager is a ServiceManager returning the status of its
+// services by name. A service not in its map is not installed.
+type testServiceManager struct {
+	services map[string]ServiceStatus
+	err      error
+}
+
+func (m testServiceManager) Status(name string) (ServiceStatus, error) {
+	return m.services[name], m.err
+}
+
+// testDisplayServiceManager is a testServiceManager whose services
+// are also found by their display names.
+type testDisplayServiceManager struct {
+	testServiceManager
+	displayNames map[string]string
+}
+
+func (m testDisplayServiceManager) FindByDisplayName(displayName string) ([]string, error) {
+	var names []string
+
+	for name, display := range m.displayNames {
+		if strings.EqualFold(display, displayName) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, m.err
+}
+
+// testRecoveryServiceManager is a testServiceManager whose services
+// also have failure actions.
+type testRecoveryServiceManager struct {
+	testServiceManager
+	actions map[string][]string
+	err     error
+}
+
+func (m testRecoveryServiceManager) RecoveryActions(name string) ([]string, error) {
+	return m.actions[name], m.err
+}
 
 func TestActualIs(t *testing.T) {
 	var goodName = "goodName"
@@ -17,9 +69,10 @@ func TestActualIs(t *testing.T) {
 	var matchUser = "GOODUSER"
 
 	si := serviceInfo{
-		getServiceInfo: func(n string) (string, string, string, int, error) {
-			return goodName, goodUser, goodState, 0, nil
-		},
+		desiredName: goodName,
+		manager: testServiceManager{services: map[string]ServiceStatus{
+			goodName: {Name: goodName, User: goodUser, State: goodState},
+		}},
 	}
 
 	var err error
@@ -83,22 +136,30 @@ func TestActualIs(t *testing.T) {
 	var retcode int
 
 	// All good check
-	msg, retcode = si.ProcessInfo()
+	msg, retcode = si.ProcessInfo().Output()
 	if retcode != 0 {
 		t.Errorf("ProcessInfo() failed on good data with retcode %d, msg %s", retcode, msg)
 	}
 
 	// Check all with bad name
 	si.desiredName = badName
-	msg, retcode = si.ProcessInfo()
-	if retcode != 2 {
+	msg, retcode = si.ProcessInfo().Output()
+	if retcode != 3 || msg != "CheckService UNKNOWN - service "+badName+" is not installed" {
 		t.Errorf("ProcessInfo() failed on bad name with retcode %d, msg %s", retcode, msg)
 	}
 
+	// Check a missing service reported as OK
+	si.missingState = "ok"
+	msg, retcode = si.ProcessInfo().Output()
+	if retcode != 0 || msg != "CheckService OK - service "+badName+" is not installed" {
+		t.Errorf("ProcessInfo() failed on bad name with a missing state of ok with retcode %d, msg %s", retcode, msg)
+	}
+	si.missingState = ""
+
 	// Check all with bad state
 	si.desiredName = goodName
 	si.desiredState = badState
-	msg, retcode = si.ProcessInfo()
+	msg, retcode = si.ProcessInfo().Output()
 	if retcode != 2 {
 		t.Errorf("ProcessInfo() failed on bad state with retcode %d, msg %s", retcode, msg)
 	}
@@ -106,21 +167,21 @@ func TestActualIs(t *testing.T) {
 	// Check all with bad user
 	si.desiredState = goodState
 	si.desiredUser = badUser
-	msg, retcode = si.ProcessInfo()
+	msg, retcode = si.ProcessInfo().Output()
 	if retcode != 2 {
 		t.Errorf("ProcessInfo() failed on bad user with retcode %d, msg %s", retcode, msg)
 	}
 
 	// Check good state only
 	si.desiredUser = ""
-	msg, retcode = si.ProcessInfo()
+	msg, retcode = si.ProcessInfo().Output()
 	if retcode != 0 {
 		t.Errorf("ProcessInfo() failed on blank user with retcode %d, msg %s", retcode, msg)
 	}
 
 	// Check bad state only
 	si.desiredState = badState
-	msg, retcode = si.ProcessInfo()
+	msg, retcode = si.ProcessInfo().Output()
 	if retcode != 2 {
 		t.Errorf("ProcessInfo() failed on bad state with retcode %d, msg %s", retcode, msg)
 	}
@@ -128,14 +189,14 @@ func TestActualIs(t *testing.T) {
 	// Check good user only
 	si.desiredUser = goodUser
 	si.desiredState = ""
-	msg, retcode = si.ProcessInfo()
+	msg, retcode = si.ProcessInfo().Output()
 	if retcode != 0 {
 		t.Errorf("ProcessInfo() failed on blank state with retcode %d, msg %s", retcode, msg)
 	}
 
 	// Check bad user only
 	si.desiredUser = badUser
-	msg, retcode = si.ProcessInfo()
+	msg, retcode = si.ProcessInfo().Output()
 	if retcode != 2 {
 		t.Errorf("ProcessInfo() failed on bad user with retcode %d, msg %s", retcode, msg)
 	}
@@ -143,26 +204,359 @@ func TestActualIs(t *testing.T) {
 	// Get service info only
 	si.desiredState = ""
 	si.desiredUser = ""
-	msg, retcode = si.ProcessInfo()
+	msg, retcode = si.ProcessInfo().Output()
 	if retcode != 0 {
 		t.Errorf("ProcessInfo() failed returning service info only retcode %d, msg %s", retcode, msg)
 	}
 
 	si.currentStateWanted = true
-	msg, retcode = si.ProcessInfo()
+	msg, retcode = si.ProcessInfo().Output()
 	if retcode != 0 {
 		t.Errorf("ProcessInfo() failed when fetching current state")
 	}
 
 	si.desiredName = badName
-	msg, retcode = si.ProcessInfo()
+	msg, retcode = si.ProcessInfo().Output()
 	if retcode != 0 {
 		t.Errorf("ProcessInfo() failed when fetching current state for unknown service")
 	}
 
-	si.getServiceInfo = nil
+	si.manager = nil
 	err = si.GetInfo()
 	if err == nil {
-		t.Errorf("GetInfo() returned no error but had a nil handler")
+		t.Errorf("GetInfo() returned no error but had a nil service manager")
+	}
+}
+
+func TestStartType(t *testing.T) {
+	manager := testServiceManager{services: map[string]ServiceStatus{
+		"goodName": {Name: "goodName", User: "goodUser", State: "Running", StartType: "Auto"},
+	}}
+
+	si := serviceInfo{
+		desiredName:      "goodName",
+		desiredState:     "Running",
+		desiredStartType: "automatic",
+		manager:          manager,
+	}
+
+	if err := si.GetInfo(); err != nil {
+		t.Errorf("GetInfo() returned error but was fed good data")
+	}
+
+	if !si.IsStartType("auto") {
+		t.Errorf("IsStartType(auto) does not match actualStartType (%s)", si.ActualStartType())
+	}
+
+	msg, retcode := si.ProcessInfo().Output()
+	if retcode != 0 {
+		t.Errorf("ProcessInfo() failed on good start type with retcode %d, msg %s", retcode, msg)
+	}
+
+	// Running but with the wrong start type
+	manager.services["goodName"] = ServiceStatus{Name: "goodName", User: "goodUser", State: "Running", StartType: "manual"}
+	si.GetInfo()
+	msg, retcode = si.ProcessInfo().Output()
+	if retcode != 1 || !strings.Contains(msg, "WARNING") {
+		t.Errorf("ProcessInfo() failed on bad start type with retcode %d, msg %s", retcode, msg)
+	}
+
+	// A bad state is still critical
+	si.desiredState = "Stopped"
+	msg, retcode = si.ProcessInfo().Output()
+	if retcode != 2 {
+		t.Errorf("ProcessInfo() failed on bad state and start type with retcode %d, msg %s", retcode, msg)
+	}
+}
+
+func TestCheckServiceWithManager(t *testing.T) {
+	manager := testServiceManager{services: map[string]ServiceStatus{
+		"spooler": {Name: "Spooler", State: "Running", User: "LocalSystem", StartType: "auto"},
+	}}
+
+	tests := []struct {
+		options ServiceCheckOptions
+		manager ServiceManager
+		retcode int
+		text    string
+	}{
+		{ServiceCheckOptions{Name: "spooler", State: "running"}, manager, 0, "OK"},
+		{ServiceCheckOptions{Name: "spooler", State: "stopped"}, manager, 2, "CRITICAL"},
+		{ServiceCheckOptions{Name: "spooler", State: "running", StartType: "manual"}, manager, 1, "WARNING"},
+		{ServiceCheckOptions{Name: "nosuchservice"}, manager, 3, "is not installed"},
+		{ServiceCheckOptions{Name: "spooler"}, testServiceManager{err: errors.New("access denied")}, 2, "access denied"},
+	}
+
+	for _, test := range tests {
+		msg, retcode := checkServiceWithManager(test.options, test.manager).Output()
+		if retcode != test.retcode || !strings.Contains(msg, test.text) {
+			t.Errorf("checkServiceWithManager(%+v) should return %d containing %q, got %d: %s", test.options, test.retcode, test.text, retcode, msg)
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	services := testServiceManager{services: map[string]ServiceStatus{
+		"Spooler": {Name: "Spooler", State: "Running", User: "LocalSystem", StartType: "auto"},
+	}}
+
+	withActions := func(actions ...string) testRecoveryServiceManager {
+		return testRecoveryServiceManager{testServiceManager: services, actions: map[string][]string{"Spooler": actions}}
+	}
+
+	tests := []struct {
+		description string
+		options     ServiceCheckOptions
+		manager     ServiceManager
+		retcode     int
+		msg         string
+	}{
+		{"Restarted", ServiceCheckOptions{Name: "Spooler", State: "running", Recovery: true},
+			withActions("restart", "restart", "none"), 0, "CheckService OK - Spooler in a Running state"},
+		{"Last action repeated", ServiceCheckOptions{Name: "Spooler", State: "running", Recovery: true},
+			withActions("restart"), 0, "CheckService OK"},
+		{"Take no action", ServiceCheckOptions{Name: "Spooler", State: "running", Recovery: true}, withActions(), 1,
+			"CheckService WARNING - Spooler in a Running state but recovery does not restart it (first failure: none, second failure: none)"},
+		{"Second failure not restarted", ServiceCheckOptions{Name: "Spooler", State: "running", Recovery: true},
+			withActions("restart", "reboot"), 1, "(first failure: restart, second failure: reboot)"},
+		{"Start type and recovery", ServiceCheckOptions{Name: "Spooler", State: "running", StartType: "manual", Recovery: true},
+			withActions("none"), 1, "but start type is auto rather than manual and recovery does not restart it"},
+		{"Stopped is still critical", ServiceCheckOptions{Name: "Spooler", State: "stopped", Recovery: true},
+			withActions(), 2, "CheckService CRITICAL - Spooler not in a stopped state"},
+		{"Not checked without the option", ServiceCheckOptions{Name: "Spooler", State: "running"}, withActions("none"), 0, "CheckService OK"},
+		{"Not installed", ServiceCheckOptions{Name: "nosuchservice", Recovery: true}, withActions(), 3, "is not installed"},
+		{"Read failing", ServiceCheckOptions{Name: "Spooler", Recovery: true},
+			testRecoveryServiceManager{testServiceManager: services, err: errors.New("access denied")}, 2,
+			"Reading the recovery actions of Spooler failed: access denied"},
+		{"Not supported", ServiceCheckOptions{Name: "Spooler", Recovery: true}, services, 2, "not supported by the service manager"},
+	}
+
+	for _, test := range tests {
+		msg, retcode := checkServiceWithManager(test.options, test.manager).Output()
+		if retcode != test.retcode || !strings.Contains(msg, test.msg) {
+			t.Errorf("%s: Expected %d containing %q, Actual %d: %s", test.description, test.retcode, test.msg, retcode, msg)
+		}
+	}
+}
+
+func TestCheckServiceRunningWithManager(t *testing.T) {
+	manager := testServiceManager{services: map[string]ServiceStatus{
+		"sshd": {Name: "sshd", State: "active", StateNbr: 1, Running: true},
+		"cron": {Name: "cron", State: "failed"},
+	}}
+
+	tests := []struct {
+		options ServiceCheckOptions
+		manager ServiceManager
+		retcode int
+		msg     string
+	}{
+		{ServiceCheckOptions{Name: "sshd"}, manager, 0, "CheckService OK - sshd in a running state"},
+		{ServiceCheckOptions{Name: "cron"}, manager, 2, "CheckService CRITICAL - cron not in a running state (State: failed)"},
+		{ServiceCheckOptions{Name: "cron", CurrentStateWanted: true}, manager, 0,
+			"CheckService CRITICAL - cron not in a running state (State: failed) | service_state=0"},
+		{ServiceCheckOptions{Name: "sshd", CurrentStateWanted: true}, manager, 0,
+			"CheckService OK - sshd in a running state | service_state=1"},
+		{ServiceCheckOptions{Name: "nosuchservice"}, manager, 3, "CheckService UNKNOWN - service nosuchservice is not installed"},
+		{ServiceCheckOptions{Name: "nosuchservice", MissingState: "ok"}, manager, 0, "CheckService OK - service nosuchservice is not installed"},
+		{ServiceCheckOptions{Name: "sshd"}, testServiceManager{err: errors.New("no systemctl")}, 2,
+			"CheckService CRITICAL - Failed to read the status of sshd: no systemctl"},
+	}
+
+	for _, test := range tests {
+		msg, retcode := checkServiceRunningWithManager(test.options, test.manager).Output()
+		if retcode != test.retcode || msg != test.msg {
+			t.Errorf("checkServiceRunningWithManager(%+v) should return %d: %s, got %d: %s", test.options, test.retcode, test.msg, retcode, msg)
+		}
+	}
+}
+
+func TestCheckServiceMatching(t *testing.T) {
+	manager := testDisplayServiceManager{
+		testServiceManager: testServiceManager{services: map[string]ServiceStatus{
+			"Spooler":    {Name: "Spooler", State: "Running"},
+			"AppWorker":  {Name: "AppWorker", State: "Stopped"},
+			"AppWorker2": {Name: "AppWorker2", State: "Running"},
+		}},
+		displayNames: map[string]string{
+			"Spooler":    "Print Spooler",
+			"AppWorker":  "App Worker",
+			"AppWorker2": "App Worker",
+		},
+	}
+
+	tests := []struct {
+		options ServiceCheckOptions
+		manager ServiceManager
+		retcode int
+		msg     string
+	}{
+		{ServiceCheckOptions{Name: "Spooler", State: "Running"}, manager, 0, "CheckService OK"},
+		{ServiceCheckOptions{Name: "print spooler", MatchBy: "display", State: "Running"}, manager, 0, "CheckService OK"},
+		{ServiceCheckOptions{Name: "Print Spooler", MatchBy: "name"}, manager, 3, "CheckService UNKNOWN - service Print Spooler is not installed"},
+		{ServiceCheckOptions{Name: "Fax", MatchBy: "display"}, manager, 3, "CheckService UNKNOWN - service Fax is not installed"},
+		{ServiceCheckOptions{Name: "Fax", MatchBy: "display", MissingState: "ok"}, manager, 0, "CheckService OK - service Fax is not installed"},
+		{ServiceCheckOptions{Name: "App Worker", MatchBy: "display"}, manager, 3,
+			"CheckService UNKNOWN - The display name App Worker matches 2 services: AppWorker, AppWorker2"},
+		{ServiceCheckOptions{Name: "Print Spooler", MatchBy: "display"}, manager.testServiceManager, 3,
+			"CheckService UNKNOWN - Matching services by display name is not supported by the service manager"},
+		{ServiceCheckOptions{Name: "Print Spooler", MatchBy: "display"},
+			testDisplayServiceManager{testServiceManager: testServiceManager{err: errors.New("access denied")}}, 2,
+			"CheckService CRITICAL - Failed to find the service with display name Print Spooler: access denied"},
+	}
+
+	for _, test := range tests {
+		msg, retcode := checkServiceMatching(test.options, test.manager, checkServiceWithManager).Output()
+		if retcode != test.retcode || !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("checkServiceMatching(%+v) should return %d: %s, got %d: %s", test.options, test.retcode, test.msg, retcode, msg)
+		}
+	}
+
+	result := RunServiceCheck(ServiceCheckOptions{Name: "sshd", MatchBy: "key"})
+	if result.State() != StateUnknown || !strings.Contains(result.Message, "Invalid match by") {
+		t.Errorf("RunServiceCheck() should be UNKNOWN with an invalid match by: %+v", result)
+	}
+}
+
+func TestSystemdManager(t *testing.T) {
+	tests := []struct {
+		out     string
+		err     error
+		status  ServiceStatus
+		wantErr bool
+	}{
+		{"LoadState=loaded\nActiveState=active\nSubState=running\n", nil,
+			ServiceStatus{Name: "sshd", State: "active", StateNbr: 1, Running: true}, false},
+		{"LoadState=loaded\nActiveState=failed\nSubState=failed\n", nil,
+			ServiceStatus{Name: "sshd", State: "failed"}, false},
+		{"LoadState=not-found\nActiveState=inactive\nSubState=dead\n", nil,
+			ServiceStatus{State: "inactive"}, false},
+		{"", nil, ServiceStatus{}, true},
+		{"", errors.New("System has not been booted with systemd"), ServiceStatus{}, true},
+	}
+
+	for _, test := range tests {
+		manager := systemdManager{systemctl: func(args ...string) ([]byte, error) {
+			return []byte(test.out), test.err
+		}}
+
+		status, err := manager.Status("sshd")
+		if (err != nil) != test.wantErr || status != test.status {
+			t.Errorf("Status() of %q should return %+v, got %+v, error %v", test.out, test.status, status, err)
+		}
+	}
+}
+
+func TestSysvinitManager(t *testing.T) {
+	code := 0
+	manager := sysvinitManager{
+		initDir: "/etc/init.d",
+		stat: func(path string) (os.FileInfo, error) {
+			if path != "/etc/init.d/cron" {
+				return nil, os.ErrNotExist
+			}
+
+			return nil, nil
+		},
+		run: func(path string, args ...string) (int, error) {
+			if path != "/etc/init.d/cron" || len(args) != 1 || args[0] != "status" {
+				return 0, fmt.Errorf("unexpected run of %s %v", path, args)
+			}
+
+			return code, nil
+		},
+	}
+
+	tests := []struct {
+		code   int
+		status ServiceStatus
+	}{
+		{0, ServiceStatus{Name: "cron", State: "running", StateNbr: 1, Running: true}},
+		{1, ServiceStatus{Name: "cron", State: "dead"}},
+		{3, ServiceStatus{Name: "cron", State: "stopped"}},
+		{4, ServiceStatus{Name: "cron", State: "unknown"}},
+	}
+
+	for _, test := range tests {
+		code = test.code
+		status, err := manager.Status("cron")
+		if err != nil || status != test.status {
+			t.Errorf("Status() with exit code %d should return %+v, got %+v, error %v", test.code, test.status, status, err)
+		}
+	}
+
+	status, err := manager.Status("nosuchservice")
+	if err != nil || status.Installed() {
+		t.Errorf("Status() of a service without an init script should be not installed, got %+v, error %v", status, err)
+	}
+}
+
+func TestRunServiceCheck(t *testing.T) {
+	result := RunServiceCheck(ServiceCheckOptions{Name: "sshd", Manager: "nosuchmanager"})
+
+	if result.Name != serviceCheckName || result.State() != StateCritical || !strings.Contains(result.Message, "nosuchmanager") {
+		t.Errorf("RunServiceCheck() should return the structured result of the check: %+v", result)
+	}
+
+	result = RunServiceCheck(ServiceCheckOptions{Name: "sshd", Manager: "nosuchmanager", MissingState: "absent"})
+	if result.State() != StateUnknown || !strings.Contains(result.Message, "Invalid missing state") {
+		t.Errorf("RunServiceCheck() should be UNKNOWN with an invalid missing state: %+v", result)
+	}
+}
+
+func TestCheckServiceWithGrace(t *testing.T) {
+	start := time.Date(2019, 6, 4, 15, 4, 5, 0, time.UTC)
+
+	type testItem struct {
+		description   string
+		results       []int
+		grace         time.Duration
+		deadline      time.Time
+		expectedCode  int
+		expectedWait  time.Duration
+		expectedCalls int
+		expectedMsg   string
+	}
+
+	testList := []testItem{
+		{"No grace", []int{2, 0}, 0, time.Time{}, statusCodeCritical, 0, 1, "CheckService CRITICAL - sshd check 1"},
+		{"Running", []int{0, 0}, 30 * time.Second, time.Time{}, statusCodeOK, 0, 1, "CheckService OK - sshd check 1"},
+		{"Not installed", []int{3, 0}, 30 * time.Second, time.Time{}, statusCodeUnknown, 0, 1, "sshd check 1"},
+		{"Back within the grace period", []int{2, 0}, 30 * time.Second, time.Time{}, statusCodeOK, 30 * time.Second, 2,
+			"CheckService OK - sshd check 2 after a grace period of 30s | service_state=1"},
+		{"Still stopped", []int{2, 2}, 30 * time.Second, time.Time{}, statusCodeCritical, 30 * time.Second, 2,
+			"CheckService CRITICAL - sshd check 2 after a grace period of 30s"},
+		{"Shortened by the timeout", []int{2, 0}, 30 * time.Second, start.Add(10 * time.Second), statusCodeOK, 8 * time.Second, 2,
+			"after a grace period of 8s"},
+		{"No time left", []int{2, 0}, 30 * time.Second, start.Add(2 * time.Second), statusCodeCritical, 0, 1, "sshd check 1"},
+	}
+
+	for _, i := range testList {
+		now := start
+		calls := 0
+		var waited time.Duration
+
+		check := func() CheckResult {
+			// Each check takes a second.
+			now = now.Add(time.Second)
+			calls++
+
+			return NewCheckResult(serviceCheckName, State(i.results[calls-1]), fmt.Sprintf("sshd check %d", calls),
+				PerfData{Label: "service_state", Value: 1})
+		}
+
+		msg, code := checkServiceWithGrace(check, i.grace, i.deadline, func(d time.Duration) {
+			waited += d
+			now = now.Add(d)
+		}, func() time.Time { return now }).Output()
+
+		if code != i.expectedCode || calls != i.expectedCalls || waited != i.expectedWait {
+			t.Errorf("%s: Expected Code: %d, Calls: %d, Wait: %s, Actual Code: %d, Calls: %d, Wait: %s",
+				i.description, i.expectedCode, i.expectedCalls, i.expectedWait, code, calls, waited)
+		}
+
+		if !strings.Contains(msg, i.expectedMsg) {
+			t.Errorf("%s: Expected Message: %s, Actual Message: %s", i.description, i.expectedMsg, msg)
+		}
 	}
 }
@@ -5,6 +5,7 @@ package nagiosfoundation
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/svc"
@@ -38,18 +39,20 @@ func getStateNbrFromText(state string) int {
 	return nbrState
 }
 
-func getInfoWmi(name string) (string, string, string, int, error) {
+// wmiManager is the ServiceManager reading the Win32_Service class
+// with WMI.
+type wmiManager struct{}
+
+func (wmiManager) Status(name string) (ServiceStatus, error) {
 	type win32_Service struct {
 		Name      string
 		State     string
 		StartName string
+		StartMode string
 	}
 
 	var dst []win32_Service
-	var actualName string
-	var actualUser string
-	var actualStateText string
-	var actualStateNbr int
+	var status ServiceStatus
 
 	w := fmt.Sprintf("where name = '%v'", name)
 
@@ -58,13 +61,64 @@ func getInfoWmi(name string) (string, string, string, int, error) {
 	err := wmi.Query(query, &dst)
 
 	if err == nil && len(dst) >= 1 {
-		actualName = dst[0].Name
-		actualUser = dst[0].StartName
-		actualStateText = dst[0].State
-		actualStateNbr = getStateNbrFromText(actualStateText)
+		status = ServiceStatus{
+			Name:      dst[0].Name,
+			State:     dst[0].State,
+			StateNbr:  getStateNbrFromText(dst[0].State),
+			Running:   dst[0].State == "Running",
+			User:      dst[0].StartName,
+			StartType: normalizeStartType(dst[0].StartMode),
+		}
+	}
+
+	return status, err
+}
+
+// FindByDisplayName returns the names of the services with the display
+// name, which WQL compares without regard to case.
+func (wmiManager) FindByDisplayName(displayName string) ([]string, error) {
+	type win32_Service struct {
+		Name string
+	}
+
+	var dst []win32_Service
+
+	escaped := strings.Replace(strings.Replace(displayName, `\`, `\\`, -1), `'`, `\'`, -1)
+	w := fmt.Sprintf("where DisplayName = '%v'", escaped)
+
+	query := wmi.CreateQuery(&dst, w)
+
+	if err := wmi.Query(query, &dst); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(dst))
+	for i, service := range dst {
+		names[i] = service.Name
 	}
 
-	return actualName, actualUser, actualStateText, actualStateNbr, err
+	return names, nil
+}
+
+func getStartTypeText(startType uint32) string {
+	var txtStartType string
+
+	switch startType {
+	case windows.SERVICE_BOOT_START:
+		txtStartType = "boot"
+	case windows.SERVICE_SYSTEM_START:
+		txtStartType = "system"
+	case windows.SERVICE_AUTO_START:
+		txtStartType = "auto"
+	case windows.SERVICE_DEMAND_START:
+		txtStartType = "manual"
+	case windows.SERVICE_DISABLED:
+		txtStartType = "disabled"
+	default:
+		txtStartType = "unknown"
+	}
+
+	return txtStartType
 }
 
 func getStateText(state svc.State) string {
@@ -117,98 +171,178 @@ func getStateNbr(state svc.State) int {
 	return nbrState
 }
 
-func getInfoSvcMgr(name string) (string, string, string, int, error) {
-	var serviceName string
-	var serviceStartName string
-	var serviceStateText string
-	var serviceStateNbr int
+func getRecoveryActionText(actionType int) string {
+	var txtAction string
+
+	switch actionType {
+	case mgr.NoAction:
+		txtAction = serviceRecoveryNone
+	case mgr.ServiceRestart:
+		txtAction = serviceRecoveryRestart
+	case mgr.ComputerReboot:
+		txtAction = serviceRecoveryReboot
+	case mgr.RunCommand:
+		txtAction = serviceRecoveryCommand
+	default:
+		txtAction = "unknown"
+	}
+
+	return txtAction
+}
 
+// queryRecoveryActions returns the failure actions of the service,
+// read from the Service Control Manager with QueryServiceConfig2.
+func queryRecoveryActions(name string) ([]string, error) {
 	mgrPtr, err := mgr.Connect()
 	if err != nil {
-		err = errors.New("Connect to Service Manager failed: " + err.Error())
-	}
-
-	var service *mgr.Service
-	if err == nil {
-		service, err = mgrPtr.OpenService(name)
-		if err == nil {
-			serviceName = service.Name
-		} else {
-			// Error is valid - service doesn't exist which is
-			// what is being checked.
-			err = nil
-		}
+		return nil, errors.New("Connect to Service Manager failed: " + err.Error())
 	}
+	defer mgrPtr.Disconnect()
+
+	service, err := mgrPtr.OpenService(name)
+	if err != nil {
+		return nil, errors.New("Open service failed: " + err.Error())
+	}
+	defer service.Close()
+
+	recoveryActions, err := service.RecoveryActions()
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]string, len(recoveryActions))
+	for i, action := range recoveryActions {
+		actions[i] = getRecoveryActionText(action.Type)
+	}
+
+	return actions, nil
+}
+
+// RecoveryActions returns the failure actions of the service. WMI does
+// not report them, so they are read from the Service Control Manager.
+func (wmiManager) RecoveryActions(name string) ([]string, error) {
+	return queryRecoveryActions(name)
+}
+
+// svcmgrManager is the ServiceManager querying the Windows Service
+// Control Manager.
+type svcmgrManager struct{}
 
-	if service != nil {
-		var config mgr.Config
-		if err == nil {
-			config, err = service.Config()
+func (svcmgrManager) Status(name string) (ServiceStatus, error) {
+	var status ServiceStatus
 
-			if err == nil {
-				serviceStartName = config.ServiceStartName
-			} else {
-				err = errors.New("Getting service configuration failed: " + err.Error())
-			}
+	mgrPtr, err := mgr.Connect()
+	if err != nil {
+		return status, errors.New("Connect to Service Manager failed: " + err.Error())
+	}
+	defer mgrPtr.Disconnect()
+
+	service, err := mgrPtr.OpenService(name)
+	if err != nil {
+		// Error is valid - service doesn't exist which is
+		// what is being checked.
+		return status, nil
+	}
+	defer service.Close()
+
+	config, err := service.Config()
+	if err != nil {
+		return status, errors.New("Getting service configuration failed: " + err.Error())
+	}
+
+	serviceStatus, err := service.Query()
+	if err != nil {
+		return status, errors.New("Query service failed: " + err.Error())
+	}
+
+	return ServiceStatus{
+		Name:      service.Name,
+		State:     getStateText(serviceStatus.State),
+		StateNbr:  getStateNbr(serviceStatus.State),
+		Running:   serviceStatus.State == windows.SERVICE_RUNNING,
+		User:      config.ServiceStartName,
+		StartType: getStartTypeText(config.StartType),
+	}, nil
+}
+
+// RecoveryActions returns the failure actions of the service.
+func (svcmgrManager) RecoveryActions(name string) ([]string, error) {
+	return queryRecoveryActions(name)
+}
+
+// FindByDisplayName returns the names of the services with the display
+// name, reading the configuration of every service. A service whose
+// configuration cannot be read is skipped.
+func (svcmgrManager) FindByDisplayName(displayName string) ([]string, error) {
+	mgrPtr, err := mgr.Connect()
+	if err != nil {
+		return nil, errors.New("Connect to Service Manager failed: " + err.Error())
+	}
+	defer mgrPtr.Disconnect()
+
+	serviceNames, err := mgrPtr.ListServices()
+	if err != nil {
+		return nil, errors.New("List services failed: " + err.Error())
+	}
+
+	var names []string
+
+	for _, serviceName := range serviceNames {
+		service, err := mgrPtr.OpenService(serviceName)
+		if err != nil {
+			debugLog.Printf("Skipping service %s: %s", serviceName, err)
+			continue
 		}
 
-		var status svc.Status
-		if err == nil {
-			status, err = service.Query()
+		config, err := service.Config()
+		service.Close()
+
+		if err != nil {
+			debugLog.Printf("Skipping service %s: %s", serviceName, err)
+			continue
+		}
 
-			if err == nil {
-				serviceStateText = getStateText(status.State)
-				serviceStateNbr = getStateNbr(status.State)
-			} else {
-				err = errors.New("Query service failed: " + err.Error())
-			}
+		if strings.EqualFold(config.DisplayName, displayName) {
+			names = append(names, serviceName)
 		}
 	}
 
-	return serviceName, serviceStartName, serviceStateText, serviceStateNbr, err
+	return names, nil
 }
 
-func checkServiceOsConstrained(name string, state string, user string, currentStateWanted bool, manager string) (string, int) {
-	managers := make(map[string]getServiceInfoFunc)
-	managers["wmi"] = getInfoWmi
-	managers["svcmgr"] = getInfoSvcMgr
+// serviceManagers are the service managers of Windows by name.
+var serviceManagers = map[string]ServiceManager{
+	"wmi":    wmiManager{},
+	"svcmgr": svcmgrManager{},
+}
 
+// newServiceManagerOsConstrained returns the service manager of the
+// name, "wmi" when no name is given.
+func newServiceManagerOsConstrained(manager string) (ServiceManager, error) {
 	if manager == "" {
 		manager = "wmi"
 	}
 
-	var msg string
-	var retcode int
-
-	if _, ok := managers[manager]; !ok {
-		managersList := ""
-		for key, _ := range managers {
-			if managersList != "" {
-				managersList = managersList + ", "
-			}
-			managersList = managersList + "\"" + key + "\""
-		}
+	if serviceManager, ok := serviceManagers[manager]; ok {
+		return serviceManager, nil
+	}
 
-		msg = fmt.Sprintf("%s CRITICAL - Service manager \"%s\" not valid. Valid managers are %s.", serviceCheckName, manager, managersList)
-		retcode = 2
-	} else {
-		i := serviceInfo{
-			desiredName:        name,
-			desiredState:       state,
-			desiredUser:        user,
-			currentStateWanted: currentStateWanted,
-			getServiceInfo:     managers[manager],
+	managersList := ""
+	for key := range serviceManagers {
+		if managersList != "" {
+			managersList = managersList + ", "
 		}
+		managersList = managersList + "\"" + key + "\""
+	}
 
-		err := i.GetInfo()
+	return nil, fmt.Errorf("Service manager \"%s\" not valid. Valid managers are %s.", manager, managersList)
+}
 
-		if err != nil {
-			msg = fmt.Sprintf("%s CRITICAL - %s", serviceCheckName, err)
-			retcode = 2
-		} else {
-			msg, retcode = i.ProcessInfo()
-		}
+func checkServiceOsConstrained(options ServiceCheckOptions) CheckResult {
+	manager, err := newServiceManagerOsConstrained(options.Manager)
+	if err != nil {
+		return CriticalResult(serviceCheckName, err.Error())
 	}
 
-	return msg, retcode
+	return checkServiceMatching(options, manager, checkServiceWithManager)
 }
@@ -0,0 +1,120 @@
+package nagiosfoundation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// checkState is the value a check saved on its previous run, with
+// the time it was saved, for checks comparing a value against the
+// value of the last poll.
+type checkState struct {
+	Value float64   `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+// stateStore saves the state of checks between runs, as a JSON file
+// for each key in a directory.
+type stateStore struct {
+	dir string
+	now func() time.Time
+}
+
+func newStateStore(dir string) stateStore {
+	return stateStore{dir: dir, now: time.Now}
+}
+
+// stateKeyExp matches the characters of a key not kept in the name of
+// its state file.
+var stateKeyExp = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+func (s stateStore) path(key string) string {
+	return filepath.Join(s.dir, stateKeyExp.ReplaceAllString(key, "_")+".json")
+}
+
+// load returns the state saved for key and whether there was one.
+func (s stateStore) load(key string) (checkState, bool, error) {
+	var state checkState
+
+	ok, err := s.loadJSON(key, &state)
+	if !ok {
+		state = checkState{}
+	}
+
+	return state, ok, err
+}
+
+// loadJSON reads the state saved for key into state, a pointer to the
+// state of a check, and returns whether there was one. A state that
+// cannot be parsed, such as one left by an older version, is treated
+// as no state.
+func (s stateStore) loadJSON(key string, state interface{}) (bool, error) {
+	data, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		debugLog.Printf("Ignoring the state in %s: %s", s.path(key), err)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// save saves value as the state of key at the current time.
+func (s stateStore) save(key string, value float64) error {
+	return s.saveJSON(key, checkState{Value: value, Time: s.now()})
+}
+
+// saveJSON saves state as the state of key, creating the directory if
+// needed. The state is written to a temporary file renamed over the
+// previous state, so a check running at the same time never reads a
+// partial state.
+func (s stateStore) saveJSON(key string, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(s.dir, ".state")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), s.path(key))
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+	}
+
+	return err
+}
+
+// swap saves value as the state of key and returns the state saved
+// before it, if there was one.
+func (s stateStore) swap(key string, value float64) (checkState, bool, error) {
+	previous, ok, err := s.load(key)
+	if err != nil {
+		return previous, false, err
+	}
+
+	return previous, ok, s.save(key, value)
+}
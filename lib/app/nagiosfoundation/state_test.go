@@ -0,0 +1,50 @@
+package nagiosfoundation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := stateStore{dir: filepath.Join(dir, "state"), now: func() time.Time { return now }}
+
+	if _, ok, err := store.load("check_process_count_java/worker"); ok || err != nil {
+		t.Errorf("load() without a saved state. Found: %t, Error: %v", ok, err)
+	}
+
+	if _, ok, err := store.swap("check_process_count_java/worker", 12); ok || err != nil {
+		t.Errorf("swap() without a saved state. Found: %t, Error: %v", ok, err)
+	}
+
+	if path := store.path("check_process_count_java/worker"); filepath.Base(path) != "check_process_count_java_worker.json" {
+		t.Errorf("path() should replace the characters not kept in a file name: %s", path)
+	}
+
+	state, ok, err := store.load("check_process_count_java/worker")
+	if !ok || err != nil || state.Value != 12 || !state.Time.Equal(now) {
+		t.Errorf("load() Expected: 12 at %s, Actual: %+v, Found: %t, Error: %v", now, state, ok, err)
+	}
+
+	if err := ioutil.WriteFile(store.path("corrupt"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := store.load("corrupt"); ok || err != nil {
+		t.Errorf("load() should treat a state that cannot be parsed as no state. Found: %t, Error: %v", ok, err)
+	}
+
+	files, _ := ioutil.ReadDir(store.dir)
+	if len(files) != 2 {
+		t.Errorf("save() should not leave temporary files, found %d files", len(files))
+	}
+}
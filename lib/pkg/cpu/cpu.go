@@ -39,8 +39,7 @@ func getStats(getStatsData func() (string, error)) ([]float64, error) {
 	return result, nil
 }
 
-func getCPULoadLinuxWithHandler(getStatsData func() (string, error)) (float64, error) {
-	var sleep = 1
+func getCPULoadLinuxWithHandler(getStatsData func() (string, error), interval int) (float64, error) {
 	var usage, totalDiff float64
 
 	beforeStats, err := getStats(getStatsData)
@@ -48,7 +47,7 @@ func getCPULoadLinuxWithHandler(getStatsData func() (string, error)) (float64, e
 		return usage, err
 	}
 
-	time.Sleep(time.Duration(sleep) * time.Second)
+	time.Sleep(time.Duration(interval) * time.Second)
 
 	afterStats, err := getStats(getStatsData)
 	if err != nil {
@@ -61,6 +60,10 @@ func getCPULoadLinuxWithHandler(getStatsData func() (string, error)) (float64, e
 		totalDiff += diffStats[i]
 	}
 
+	if totalDiff == 0 {
+		return usage, errors.New("No CPU time elapsed in the sampling interval")
+	}
+
 	usage = 100.0 * (totalDiff - diffStats[3]) / totalDiff
 	return usage, nil
 }
@@ -77,11 +80,22 @@ func getStatsDataService() (string, error) {
 	return statsData, err
 }
 
-func getCPULoadLinux() (float64, error) {
-	return getCPULoadLinuxWithHandler(getStatsDataService)
+func getCPULoadLinux(interval int) (float64, error) {
+	return getCPULoadLinuxWithHandler(getStatsDataService, interval)
 }
 
-// GetCPULoad returns the current CPU load as a percentage.
+// DefaultInterval is the number of seconds GetCPULoad samples the
+// CPU usage over.
+const DefaultInterval = 1
+
+// GetCPULoad returns the current CPU load as a percentage, sampled
+// over DefaultInterval seconds.
 func GetCPULoad() (float64, error) {
-	return getCPULoadOsConstrained()
+	return GetCPULoadWithInterval(DefaultInterval)
+}
+
+// GetCPULoadWithInterval returns the CPU load as a percentage,
+// sampled over interval seconds.
+func GetCPULoadWithInterval(interval int) (float64, error) {
+	return getCPULoadOsConstrained(interval)
 }
@@ -40,7 +40,7 @@ func TestCpu(t *testing.T) {
 	cpuData = goodData
 	testError = nil
 	counter = 0
-	usage, err := getCPULoadLinuxWithHandler(testCPUData)
+	usage, err := getCPULoadLinuxWithHandler(testCPUData, 0)
 
 	if err != nil {
 		t.Error("Good CPU data should not return an error")
@@ -53,7 +53,7 @@ func TestCpu(t *testing.T) {
 	cpuData = goodData
 	testError = errors.New("Test Error")
 	counter = 0
-	usage, err = getCPULoadLinuxWithHandler(testCPUData)
+	usage, err = getCPULoadLinuxWithHandler(testCPUData, 0)
 
 	if err == nil {
 		t.Error("Error getting CPU data should return an error")
@@ -66,7 +66,7 @@ func TestCpu(t *testing.T) {
 	cpuData = badAfterData
 	testError = nil
 	counter = 0
-	usage, err = getCPULoadLinuxWithHandler(testCPUData)
+	usage, err = getCPULoadLinuxWithHandler(testCPUData, 0)
 
 	if err == nil {
 		t.Error("Getting error on second CPU check should return an error")
@@ -79,7 +79,7 @@ func TestCpu(t *testing.T) {
 	cpuData = badBeforeData
 	testError = nil
 	counter = 0
-	usage, err = getCPULoadLinuxWithHandler(testCPUData)
+	usage, err = getCPULoadLinuxWithHandler(testCPUData, 0)
 
 	if err == nil {
 		t.Error("Unparseable CPU data should return an error")
@@ -92,7 +92,7 @@ func TestCpu(t *testing.T) {
 	cpuData = emptyData
 	testError = nil
 	counter = 0
-	usage, err = getCPULoadLinuxWithHandler(testCPUData)
+	usage, err = getCPULoadLinuxWithHandler(testCPUData, 0)
 
 	if err == nil {
 		t.Error("Empty CPU data should return an error")
@@ -105,7 +105,7 @@ func TestCpu(t *testing.T) {
 	cpuData = shortData
 	testError = nil
 	counter = 0
-	usage, err = getCPULoadLinuxWithHandler(testCPUData)
+	usage, err = getCPULoadLinuxWithHandler(testCPUData, 0)
 
 	if err == nil {
 		t.Error("Short line of CPU data should return an error")
@@ -119,7 +119,7 @@ func TestCpu(t *testing.T) {
 		t.Error("No stats data handler should return an error")
 	}
 
-	if _, err := getCPULoadLinuxWithHandler(nil); err == nil {
+	if _, err := getCPULoadLinuxWithHandler(nil, 0); err == nil {
 		t.Error("No stats data handler should return an error")
 	}
 	// Execute to at least make sure there's no panic
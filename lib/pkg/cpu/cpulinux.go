@@ -2,6 +2,6 @@
 
 package cpu
 
-func getCPULoadOsConstrained() (float64, error) {
-	return getCPULoadLinux()
+func getCPULoadOsConstrained(interval int) (float64, error) {
+	return getCPULoadLinux(interval)
 }
@@ -6,8 +6,8 @@ import (
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/perfcounters"
 )
 
-func getCPULoadOsConstrained() (float64, error) {
-	counter, err := perfcounters.ReadPerformanceCounter("\\Processor(_Total)\\% Processor Time", 2, 1)
+func getCPULoadOsConstrained(interval int) (float64, error) {
+	counter, err := perfcounters.ReadPerformanceCounter("\\Processor(_Total)\\% Processor Time", 2, interval)
 	if err == nil {
 		return counter.Value, nil
 	}
@@ -0,0 +1,143 @@
+package disk
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Usage is the amount of space, in bytes, or inodes used on a
+// filesystem out of the total available.
+type Usage struct {
+	Used  uint64
+	Total uint64
+}
+
+// Mount describes the mount a path is on, as listed in
+// /proc/self/mountinfo.
+type Mount struct {
+	// The major:minor number of the device holding the filesystem.
+	// Bind mounts of the same filesystem share the device.
+	Device string
+
+	// The directory within the filesystem mounted at MountPoint.
+	// Anything other than "/" is a bind mount.
+	Root string
+
+	MountPoint string
+	FSType     string
+	Source     string
+}
+
+// IsBind reports whether the mount is a bind mount of a directory
+// within a filesystem mounted elsewhere.
+func (m Mount) IsBind() bool {
+	return m.Root != "" && m.Root != "/"
+}
+
+// GetUsage returns the space used on the filesystem holding path.
+func GetUsage(path string) (Usage, error) {
+	return getUsageOsConstrained(path, false)
+}
+
+// GetInodeUsage returns the inodes used on the filesystem holding
+// path.
+func GetInodeUsage(path string) (Usage, error) {
+	return getUsageOsConstrained(path, true)
+}
+
+// GetMount returns the mount holding path.
+func GetMount(path string) (Mount, error) {
+	return getMountOsConstrained(path)
+}
+
+// parseMountInfo parses the contents of /proc/self/mountinfo. Each
+// line has the format of
+//
+//	36 35 98:0 /mnt1 /mnt/parent rw,noatime master:1 - ext3 /dev/root rw
+//
+// where a variable number of optional fields is ended by "-".
+func parseMountInfo(data string) []Mount {
+	var mounts []Mount
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+
+		separator := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				separator = i
+				break
+			}
+		}
+
+		if separator < 0 || separator+2 >= len(fields) {
+			continue
+		}
+
+		mounts = append(mounts, Mount{
+			Device:     fields[2],
+			Root:       unescapeMountInfo(fields[3]),
+			MountPoint: unescapeMountInfo(fields[4]),
+			FSType:     fields[separator+1],
+			Source:     unescapeMountInfo(fields[separator+2]),
+		})
+	}
+
+	return mounts
+}
+
+// unescapeMountInfo replaces the octal escapes used in mountinfo for
+// spaces, tabs, newlines and backslashes, such as \040 for a space.
+func unescapeMountInfo(field string) string {
+	if !strings.Contains(field, "\\") {
+		return field
+	}
+
+	var text strings.Builder
+
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) {
+			if c, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				text.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+
+		text.WriteByte(field[i])
+	}
+
+	return text.String()
+}
+
+// findMount returns the mount holding path, the mount with the
+// longest mount point containing it. A later mount over the same
+// mount point hides an earlier one so the last is used.
+func findMount(mounts []Mount, path string) (Mount, bool) {
+	var found Mount
+	ok := false
+
+	path = filepath.Clean(path)
+
+	for _, mount := range mounts {
+		if !isWithin(path, mount.MountPoint) {
+			continue
+		}
+
+		if !ok || len(mount.MountPoint) >= len(found.MountPoint) {
+			found = mount
+			ok = true
+		}
+	}
+
+	return found, ok
+}
+
+func isWithin(path, dir string) bool {
+	if dir == "/" || path == dir {
+		return true
+	}
+
+	return strings.HasPrefix(path, dir+"/")
+}
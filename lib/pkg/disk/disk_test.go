@@ -0,0 +1,63 @@
+package disk
+
+import "testing"
+
+const testMountInfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw,errors=remount-ro
+25 22 0:22 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+30 22 8:17 / /data rw,relatime shared:20 - xfs /dev/sdb1 rw,attr2
+31 22 8:17 /exports/web /srv/web rw,relatime shared:20 - xfs /dev/sdb1 rw,attr2
+40 22 0:45 / /var/lib/docker/overlay2/3f4e/merged rw,relatime - overlay overlay rw,lowerdir=/l,upperdir=/u,workdir=/w
+41 22 8:1 /home/shared\040files /mnt/shared\040files rw,relatime - ext4 /dev/sda1 rw
+malformed line
+`
+
+func TestParseMountInfo(t *testing.T) {
+	mounts := parseMountInfo(testMountInfo)
+
+	if len(mounts) != 6 {
+		t.Fatalf("parseMountInfo() should parse 6 mounts, got %d", len(mounts))
+	}
+
+	expected := Mount{Device: "8:17", Root: "/exports/web", MountPoint: "/srv/web", FSType: "xfs", Source: "/dev/sdb1"}
+	if mounts[3] != expected {
+		t.Errorf("parseMountInfo() bind mount. Expected: %+v, Actual: %+v", expected, mounts[3])
+	}
+
+	if mounts[5].MountPoint != "/mnt/shared files" || mounts[5].Root != "/home/shared files" {
+		t.Errorf("parseMountInfo() did not unescape the spaces in %+v", mounts[5])
+	}
+
+	if mounts[0].IsBind() || !mounts[3].IsBind() {
+		t.Error("IsBind() should only be true for a mount of a directory within a filesystem")
+	}
+}
+
+func TestFindMount(t *testing.T) {
+	mounts := parseMountInfo(testMountInfo)
+
+	type testItem struct {
+		path       string
+		mountPoint string
+	}
+
+	testList := []testItem{
+		{"/", "/"},
+		{"/etc/hosts", "/"},
+		{"/data", "/data"},
+		{"/data/db/", "/data"},
+		{"/database", "/"},
+		{"/srv/web/index.html", "/srv/web"},
+		{"/var/lib/docker/overlay2/3f4e/merged/etc", "/var/lib/docker/overlay2/3f4e/merged"},
+	}
+
+	for _, i := range testList {
+		mount, ok := findMount(mounts, i.path)
+		if !ok || mount.MountPoint != i.mountPoint {
+			t.Errorf("findMount(%s). Expected: %s, Actual: %s", i.path, i.mountPoint, mount.MountPoint)
+		}
+	}
+
+	if _, ok := findMount(nil, "/"); ok {
+		t.Error("findMount() should not find a mount when there are none")
+	}
+}
@@ -0,0 +1,72 @@
+// +build !windows
+
+package disk
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"syscall"
+)
+
+// DefaultPath is the path checked when none is given.
+const DefaultPath = "/"
+
+const mountInfoPath = "/proc/self/mountinfo"
+
+// statfsUsage returns the usage reported by statfs. As with df, the
+// total space is the space used plus the space available to
+// unprivileged users, so the blocks reserved for root are not counted
+// and a filesystem is 100% used when only reserved blocks are left.
+func statfsUsage(stat syscall.Statfs_t, inodes bool) Usage {
+	if inodes {
+		return Usage{Used: uint64(stat.Files - stat.Ffree), Total: uint64(stat.Files)}
+	}
+
+	blockSize := uint64(stat.Frsize)
+	if blockSize == 0 {
+		blockSize = uint64(stat.Bsize)
+	}
+
+	used := uint64(stat.Blocks-stat.Bfree) * blockSize
+
+	return Usage{Used: used, Total: used + uint64(stat.Bavail)*blockSize}
+}
+
+// getUsageOsConstrained uses statfs, which reports on the filesystem
+// holding path. For a bind mount this is the filesystem the bound
+// directory is in and for an overlay it is the filesystem of the
+// upper layer, where anything written to the overlay is stored.
+func getUsageOsConstrained(path string, inodes bool) (Usage, error) {
+	var stat syscall.Statfs_t
+
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return Usage{}, err
+	}
+
+	return statfsUsage(stat, inodes), nil
+}
+
+func getMountOsConstrained(path string) (Mount, error) {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return Mount{}, err
+	}
+
+	realPath, err = filepath.Abs(realPath)
+	if err != nil {
+		return Mount{}, err
+	}
+
+	data, err := ioutil.ReadFile(mountInfoPath)
+	if err != nil {
+		return Mount{}, err
+	}
+
+	mount, ok := findMount(parseMountInfo(string(data)), realPath)
+	if !ok {
+		return Mount{}, fmt.Errorf("No mount found for %s", path)
+	}
+
+	return mount, nil
+}
@@ -0,0 +1,47 @@
+// +build windows
+
+package disk
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+// DefaultPath is the path checked when none is given.
+const DefaultPath = "C:\\"
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// getUsageOsConstrained uses GetDiskFreeSpaceEx. As with statfs on
+// Linux, the total space is the space used plus the space available
+// to the caller, so quotas are taken into account.
+func getUsageOsConstrained(path string, inodes bool) (Usage, error) {
+	if inodes {
+		return Usage{}, errors.New("Inode usage is not supported on Windows")
+	}
+
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	var freeToCaller, total, totalFree uint64
+
+	ret, _, err := procGetDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&freeToCaller)),
+		uintptr(unsafe.Pointer(&total)),
+		uintptr(unsafe.Pointer(&totalFree)))
+	if ret == 0 {
+		return Usage{}, err
+	}
+
+	used := total - totalFree
+
+	return Usage{Used: used, Total: used + freeToCaller}, nil
+}
+
+func getMountOsConstrained(path string) (Mount, error) {
+	return Mount{}, errors.New("Mount information is not supported on Windows")
+}
@@ -0,0 +1,68 @@
+package load
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Average is the system load averaged over the last 1, 5 and 15
+// minutes.
+type Average struct {
+	One     float64
+	Five    float64
+	Fifteen float64
+}
+
+// GetLoadAverage returns the system load average.
+func GetLoadAverage() (Average, error) {
+	return getLoadAverageOsConstrained()
+}
+
+// GetCPUCount returns the number of CPUs online.
+func GetCPUCount() (int, error) {
+	return getCPUCountOsConstrained()
+}
+
+// parseLoadAvg parses the contents of /proc/loadavg, such as
+//
+//	0.20 0.18 0.12 1/80 11206
+//
+// where the first three fields are the load averages.
+func parseLoadAvg(data string) (Average, error) {
+	fields := strings.Fields(data)
+	if len(fields) < 3 {
+		return Average{}, fmt.Errorf("Could not parse load average %q", strings.TrimSpace(data))
+	}
+
+	var averages [3]float64
+	for i := range averages {
+		average, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return Average{}, fmt.Errorf("Could not parse load average %q", strings.TrimSpace(data))
+		}
+
+		averages[i] = average
+	}
+
+	return Average{One: averages[0], Five: averages[1], Fifteen: averages[2]}, nil
+}
+
+// parseCPUCount counts the processor entries in the contents of
+// /proc/cpuinfo.
+func parseCPUCount(data string) (int, error) {
+	count := 0
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) == 2 && strings.TrimSpace(fields[0]) == "processor" {
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0, fmt.Errorf("No processors found in cpuinfo")
+	}
+
+	return count, nil
+}
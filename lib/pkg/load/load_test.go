@@ -0,0 +1,28 @@
+package load
+
+import "testing"
+
+func TestParseLoadAvg(t *testing.T) {
+	average, err := parseLoadAvg("0.20 1.18 12.5 1/80 11206\n")
+	if err != nil || average != (Average{One: 0.2, Five: 1.18, Fifteen: 12.5}) {
+		t.Errorf("parseLoadAvg() returned %v, Error: %v", average, err)
+	}
+
+	for _, data := range []string{"", "0.20 0.18", "0.20 high 0.12 1/80 11206"} {
+		if _, err := parseLoadAvg(data); err == nil {
+			t.Errorf("parseLoadAvg(%q) should return an error", data)
+		}
+	}
+}
+
+func TestParseCPUCount(t *testing.T) {
+	cpuinfo := "processor\t: 0\nmodel name\t: Intel(R) Xeon(R)\n\nprocessor\t: 1\nmodel name\t: Intel(R) Xeon(R)\n"
+
+	if count, err := parseCPUCount(cpuinfo); err != nil || count != 2 {
+		t.Errorf("parseCPUCount() Expected: 2, Actual: %d, Error: %v", count, err)
+	}
+
+	if _, err := parseCPUCount("model name\t: Intel(R) Xeon(R)\n"); err == nil {
+		t.Error("parseCPUCount() should return an error without processors")
+	}
+}
@@ -0,0 +1,23 @@
+// +build !windows
+
+package load
+
+import "io/ioutil"
+
+func getLoadAverageOsConstrained() (Average, error) {
+	data, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return Average{}, err
+	}
+
+	return parseLoadAvg(string(data))
+}
+
+func getCPUCountOsConstrained() (int, error) {
+	data, err := ioutil.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return 0, err
+	}
+
+	return parseCPUCount(string(data))
+}
@@ -0,0 +1,13 @@
+// +build windows
+
+package load
+
+import "errors"
+
+func getLoadAverageOsConstrained() (Average, error) {
+	return Average{}, errors.New("Load averages are not supported on Windows")
+}
+
+func getCPUCountOsConstrained() (int, error) {
+	return 0, errors.New("CPU counts from cpuinfo are not supported on Windows")
+}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"errors"
 	"io"
 	"io/ioutil"
 	"os"
@@ -51,6 +52,41 @@ func getMemInfoEntryFromReader(reader io.Reader, memInfoEntry string) uint64 {
 	return memoryInfo * 1024
 }
 
+// getAvailableMemoryFromMemInfo returns the memory available for
+// starting new applications from the contents of /proc/meminfo. The
+// kernel estimate in MemAvailable accounts for the reclaimable
+// buffers and page cache, which a healthy Linux host fills. Kernels
+// older than 3.14 don't provide MemAvailable, so it is approximated
+// as the free memory plus the buffers, page cache and reclaimable
+// slab.
+func getAvailableMemoryFromMemInfo(memInfo string) uint64 {
+	entry := func(name string) uint64 {
+		return getMemInfoEntryFromReader(strings.NewReader(memInfo), name)
+	}
+
+	if available := entry("MemAvailable"); available != 0 {
+		return available
+	}
+
+	return entry("MemFree") + entry("Buffers") + entry("Cached") + entry("SReclaimable")
+}
+
+// getSwapUsageFromMemInfo returns the amount of used and total swap
+// from the SwapTotal and SwapFree entries of /proc/meminfo. Both are
+// zero when no swap is configured.
+func getSwapUsageFromMemInfo(memInfo string) (uint64, uint64) {
+	entry := func(name string) uint64 {
+		return getMemInfoEntryFromReader(strings.NewReader(memInfo), name)
+	}
+
+	total, free := entry("SwapTotal"), entry("SwapFree")
+	if free > total {
+		free = total
+	}
+
+	return total - free, total
+}
+
 func getFreeMemoryWithHandler(freeMemory func() uint64) uint64 {
 	return freeMemory()
 }
@@ -105,3 +141,24 @@ func getUsedMemoryPercentageWithHandlers(totalMemory func() uint64, freeMemory f
 func GetUsedMemoryPercentage() uint64 {
 	return getUsedMemoryPercentageWithHandlers(m.TotalMemory, getFreeMemoryOsConstrained)
 }
+
+// GetMemoryUsage returns the amount of used and total memory,
+// where the memory used excludes the memory available for
+// reclaiming, such as buffers and cache.
+func GetMemoryUsage() (uint64, uint64, error) {
+	used := GetUsedMemory()
+	total := GetTotalMemory()
+
+	if used == 0 || total == 0 {
+		return 0, 0, errors.New("Failed to determine used memory")
+	}
+
+	return used, total, nil
+}
+
+// GetSwapUsage returns the amount of used and total swap, read from
+// /proc/meminfo on Linux and the page file on Windows. Both are zero
+// when no swap is configured.
+func GetSwapUsage() (uint64, uint64, error) {
+	return getSwapUsageOsConstrained()
+}
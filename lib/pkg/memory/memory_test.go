@@ -144,3 +144,50 @@ to parse
 		t.Error("Invalid memory info file and match string failed to generate error")
 	}
 }
+
+func TestGetAvailableMemoryFromMemInfo(t *testing.T) {
+	memInfo := `MemTotal:        8000000 kB
+MemFree:          200000 kB
+MemAvailable:    5000000 kB
+Buffers:          300000 kB
+Cached:          4000000 kB
+SReclaimable:     500000 kB
+`
+
+	if available := getAvailableMemoryFromMemInfo(memInfo); available != 5000000*1024 {
+		t.Error(availableMemoryErrorText("MemAvailable should be used when present", 5000000*1024, available))
+	}
+
+	memInfo = `MemTotal:        8000000 kB
+MemFree:          200000 kB
+Buffers:          300000 kB
+Cached:          4000000 kB
+SReclaimable:     500000 kB
+`
+
+	expected := uint64(200000+300000+4000000+500000) * 1024
+	if available := getAvailableMemoryFromMemInfo(memInfo); available != expected {
+		t.Error(availableMemoryErrorText("Buffers and cache should be available without MemAvailable", expected, available))
+	}
+}
+
+func TestGetSwapUsageFromMemInfo(t *testing.T) {
+	memInfo := `MemTotal:        8000000 kB
+SwapTotal:       2000000 kB
+SwapFree:        1500000 kB
+`
+
+	used, total := getSwapUsageFromMemInfo(memInfo)
+	if used != 500000*1024 || total != 2000000*1024 {
+		t.Errorf("getSwapUsageFromMemInfo() Expected: %d of %d, Actual: %d of %d", 500000*1024, 2000000*1024, used, total)
+	}
+
+	memInfo = `MemTotal:        8000000 kB
+SwapTotal:             0 kB
+SwapFree:              0 kB
+`
+
+	if used, total = getSwapUsageFromMemInfo(memInfo); used != 0 || total != 0 {
+		t.Errorf("getSwapUsageFromMemInfo() should return no swap when none is configured: %d of %d", used, total)
+	}
+}
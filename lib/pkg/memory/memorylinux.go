@@ -2,7 +2,26 @@
 
 package memory
 
+import "io/ioutil"
+
+func getSwapUsageOsConstrained() (uint64, uint64, error) {
+	memInfo, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	used, total := getSwapUsageFromMemInfo(string(memInfo))
+
+	return used, total, nil
+}
+
 // GetFreeMemoryOsConstrained returns the amount of available memory.
 func getFreeMemoryOsConstrained() uint64 {
-	return getMemInfoEntryFromFile("/proc/meminfo", "MemAvailable")
+	var memoryAvailable uint64
+
+	if memInfo, err := ioutil.ReadFile("/proc/meminfo"); err == nil {
+		memoryAvailable = getAvailableMemoryFromMemInfo(string(memInfo))
+	}
+
+	return memoryAvailable
 }
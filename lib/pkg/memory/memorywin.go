@@ -4,8 +4,19 @@ package memory
 
 import (
 	"github.com/ncr-devops-platform/nagiosfoundation/lib/pkg/perfcounters"
+	"github.com/shirou/gopsutil/mem"
 )
 
+// getSwapUsageOsConstrained returns the use of the page file.
+func getSwapUsageOsConstrained() (uint64, uint64, error) {
+	swap, err := mem.SwapMemory()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return swap.Used, swap.Total, nil
+}
+
 func getFreeMemoryOsConstrained() uint64 {
 	counter, err := perfcounters.ReadPerformanceCounter("\\Memory\\Available Bytes", 2, 1)
 
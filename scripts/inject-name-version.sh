@@ -1,8 +1,11 @@
 #!/bin/bash
 
 PACKAGE="github.com/ncr-devops-platform/nagiosfoundation/cmd/initcmd"
+COMMIT=$(git rev-parse --short HEAD 2>/dev/null)
+BUILD_DATE=$(date -u +%Y-%m-%dT%H:%M:%SZ)
 
 echo "-ldflags"
 echo -n "-X $PACKAGE.cmdName=$PRODUCT "
-echo "-X $PACKAGE.cmdVersion=$VERSION"
-
+echo -n "-X $PACKAGE.cmdVersion=$VERSION "
+echo -n "-X $PACKAGE.cmdCommit=$COMMIT "
+echo "-X $PACKAGE.cmdBuildDate=$BUILD_DATE"